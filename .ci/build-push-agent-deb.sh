@@ -14,7 +14,7 @@ make build-host-agent-binary
 echo "started building deb package for byoh-agent"
 make build-host-agent-deb
 
-echo "created deb package under build/pf9-byohost/debsrc/ "
+echo "created deb package and its .sha256 checksum file under build/pf9-byohost/debsrc/ "
 
 echo "installing imgpkg"
 curl -LO https://github.com/carvel-dev/imgpkg/releases/download/v0.43.1/imgpkg-linux-amd64
@@ -4,7 +4,9 @@ source ~/.bashrc
 
 
 export BUILD_ONLY=${BUILD_ONLY:-1}
+# containerd and runc have to be the versions pinned for the k8s version in installer/runtime_versions.go
 export CONTAINERD_VERSION=${CONTAINERD_VERSION:-1.7.26}
+export RUNC_VERSION=${RUNC_VERSION:-1.2.5}
 export KUBERNETES_VERSION=${KUBERNETES_VERSION:-1.32.2-1.1}
 export KUBERNETES_MAJOR_VERSION=${KUBERNETES_MAJOR_VERSION:-v1.32}
 export BUNDLE_VERSION=${BUNDLE_VERSION:-v1.32.2}
@@ -25,7 +27,7 @@ docker build -t byoh-bundle .
 docker rm -f byoh-bundle-container
 
 echo "executing docker image"
-docker run -e CRITOOL_VERSION -e BUILD_ONLY -e CONTAINERD_VERSION -e KUBERNETES_VERSION -e KUBERNETES_MAJOR_VERSION -e ARCH -e UBUNTU_VERSION --name byoh-bundle-container -i byoh-bundle /bin/bash
+docker run -e CRITOOL_VERSION -e BUILD_ONLY -e CONTAINERD_VERSION -e RUNC_VERSION -e KUBERNETES_VERSION -e KUBERNETES_MAJOR_VERSION -e ARCH -e UBUNTU_VERSION --name byoh-bundle-container -i byoh-bundle /bin/bash
 
 echo "creating bundle dir to push k8s packages"
 mkdir -p ./bundle
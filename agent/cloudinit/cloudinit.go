@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cloudinit
@@ -22,6 +23,11 @@ type ScriptExecutor struct {
 	WriteFilesExecutor    IFileWriter
 	RunCmdExecutor        ICmdRunner
 	ParseTemplateExecutor ITemplateParser
+	// CRISocket, if set, is written into the kubeadm configurations that do not set a cri socket
+	CRISocket string
+	// NodeName, if set, is written into the kubeadm configurations that do not set the name of the
+	// node, so that the node is named after its ByoHost rather than the hostname of the host
+	NodeName string
 }
 
 type bootstrapConfig struct {
@@ -67,6 +73,14 @@ func (se ScriptExecutor) Execute(bootstrapScript string) error {
 			return errors.Wrap(err, fmt.Sprintf("error parse template content for %s", cloudInitData.FilesToWrite[i].Path))
 		}
 
+		if (se.CRISocket != "" || se.NodeName != "") && directoryToCreate == kubeadmConfigDir {
+			cloudInitData.FilesToWrite[i].Content, err = setKubeadmNodeRegistration(cloudInitData.FilesToWrite[i].Content,
+				map[string]string{"criSocket": se.CRISocket, "name": se.NodeName})
+			if err != nil {
+				return errors.Wrap(err, fmt.Sprintf("error setting the node registration for %s", cloudInitData.FilesToWrite[i].Path))
+			}
+		}
+
 		err = se.WriteFilesExecutor.WriteToFile(&cloudInitData.FilesToWrite[i])
 		if err != nil {
 			return errors.Wrap(err, fmt.Sprintf("Error writing the file %s", cloudInitData.FilesToWrite[i].Path))
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cloudinit_test
@@ -157,5 +158,83 @@ runCmd:
 
 			Expect(err.Error()).To(ContainSubstring("command execution failed"))
 		})
+
+		Context("When a cri socket is set", func() {
+			BeforeEach(func() {
+				scriptExecutor.CRISocket = "unix:///var/run/crio/crio.sock"
+				fakeTemplateParser.ParseTemplateStub = func(content string) (string, error) {
+					return content, nil
+				}
+			})
+
+			It("should set the cri socket in the kubeadm configurations that do not set one", func() {
+				bootstrapSecret := `write_files:
+- path: /run/kubeadm/kubeadm.yaml
+  content: |
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: ClusterConfiguration
+    clusterName: test
+    ---
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: InitConfiguration
+    nodeRegistration:
+      name: test-host
+- path: /run/kubeadm/kubeadm-join-config.yaml
+  content: |
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: JoinConfiguration
+    nodeRegistration:
+      criSocket: unix:///var/run/containerd/containerd.sock
+- path: /etc/kubernetes/other.yaml
+  content: |
+    kind: JoinConfiguration`
+
+				Expect(scriptExecutor.Execute(bootstrapSecret)).To(Succeed())
+				Expect(fakeFileWriter.WriteToFileCallCount()).To(Equal(3))
+
+				initConfig := fakeFileWriter.WriteToFileArgsForCall(0).Content
+				Expect(initConfig).To(ContainSubstring("kind: ClusterConfiguration\nclusterName: test\n---\n"))
+				Expect(initConfig).To(ContainSubstring("criSocket: unix:///var/run/crio/crio.sock"))
+				Expect(initConfig).To(ContainSubstring("name: test-host"))
+
+				Expect(fakeFileWriter.WriteToFileArgsForCall(1).Content).To(ContainSubstring("criSocket: unix:///var/run/containerd/containerd.sock"))
+				Expect(fakeFileWriter.WriteToFileArgsForCall(1).Content).NotTo(ContainSubstring("crio"))
+				Expect(fakeFileWriter.WriteToFileArgsForCall(2).Content).To(Equal("kind: JoinConfiguration"))
+			})
+		})
+
+		Context("When a node name is set", func() {
+			BeforeEach(func() {
+				scriptExecutor.NodeName = "stable-host"
+				fakeTemplateParser.ParseTemplateStub = func(content string) (string, error) {
+					return content, nil
+				}
+			})
+
+			It("should set the node name in the kubeadm configurations that do not set one", func() {
+				bootstrapSecret := `write_files:
+- path: /run/kubeadm/kubeadm-join-config.yaml
+  content: |
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: JoinConfiguration
+    nodeRegistration:
+      criSocket: unix:///var/run/containerd/containerd.sock
+- path: /run/kubeadm/kubeadm.yaml
+  content: |
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: InitConfiguration
+    nodeRegistration:
+      name: test-host`
+
+				Expect(scriptExecutor.Execute(bootstrapSecret)).To(Succeed())
+				Expect(fakeFileWriter.WriteToFileCallCount()).To(Equal(2))
+
+				joinConfig := fakeFileWriter.WriteToFileArgsForCall(0).Content
+				Expect(joinConfig).To(ContainSubstring("name: stable-host"))
+				Expect(joinConfig).To(ContainSubstring("criSocket: unix:///var/run/containerd/containerd.sock"))
+				Expect(fakeFileWriter.WriteToFileArgsForCall(1).Content).To(ContainSubstring("name: test-host"))
+				Expect(fakeFileWriter.WriteToFileArgsForCall(1).Content).NotTo(ContainSubstring("stable-host"))
+			})
+		})
 	})
 })
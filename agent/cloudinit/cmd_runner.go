@@ -5,8 +5,8 @@ package cloudinit
 
 import (
 	"context"
-	"os"
-	"os/exec"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec"
 )
 
 //counterfeiter:generate . ICmdRunner
@@ -14,18 +14,12 @@ type ICmdRunner interface {
 	RunCmd(context.Context, string) error
 }
 
-// CmdRunner default implementer of ICmdRunner
-// TODO reevaluate empty interface/struct
+// CmdRunner default implementer of ICmdRunner, running the commands with bash through its Exec
 type CmdRunner struct {
+	Exec hostexec.Exec
 }
 
 // RunCmd executes the command string
 func (r CmdRunner) RunCmd(ctx context.Context, cmd string) error {
-	command := exec.CommandContext(ctx, "/bin/bash", "-c", cmd) // #nosec G204 -- cmd is admin-authored install/bootstrap content from K8sInstallerConfig/cloud-init, not external/untrusted input
-	command.Stderr = os.Stderr
-	command.Stdout = os.Stdout
-	if err := command.Run(); err != nil {
-		return err
-	}
-	return nil
+	return r.Exec.Script(ctx, cmd)
 }
@@ -0,0 +1,64 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudinit
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeadmConfigDir is where the kubeadm bootstrap provider writes the kubeadm init and join configurations
+const kubeadmConfigDir = "/run/kubeadm"
+
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*\n`)
+
+// setKubeadmNodeRegistration sets the fields of nodeRegistration of the InitConfiguration and
+// JoinConfiguration documents of a kubeadm config to values, unless the documents set them, e.g.
+// criSocket so that kubeadm does not have to detect the container runtime. The content is returned
+// unchanged if there is nothing to set.
+func setKubeadmNodeRegistration(content string, values map[string]string) (string, error) {
+	docs := yamlDocumentSeparator.Split(content, -1)
+	changed := false
+	for i, doc := range docs {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return content, errors.Wrap(err, "error parsing kubeadm config")
+		}
+		if kind := obj["kind"]; kind != "InitConfiguration" && kind != "JoinConfiguration" {
+			continue
+		}
+
+		nodeRegistration, _ := obj["nodeRegistration"].(map[string]interface{})
+		if nodeRegistration == nil {
+			nodeRegistration = map[string]interface{}{}
+		}
+		docChanged := false
+		for field, value := range values {
+			if current, _ := nodeRegistration[field].(string); current != "" || value == "" {
+				continue
+			}
+			nodeRegistration[field] = value
+			docChanged = true
+		}
+		if !docChanged {
+			continue
+		}
+		obj["nodeRegistration"] = nodeRegistration
+
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return content, errors.Wrap(err, "error serializing kubeadm config")
+		}
+		docs[i] = string(out)
+		changed = true
+	}
+
+	if !changed {
+		return content, nil
+	}
+	return strings.Join(docs, "---\n"), nil
+}
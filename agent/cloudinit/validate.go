@@ -0,0 +1,200 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudinit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ValidationErrorType is the type of a ValidationError
+type ValidationErrorType string
+
+const (
+	// ErrorTypeInvalidSyntax is bootstrap data that is not a YAML cloud-config
+	ErrorTypeInvalidSyntax ValidationErrorType = "InvalidSyntax"
+	// ErrorTypeUnknownModule is a cloud-config module, or a field of one, the agent does not implement
+	ErrorTypeUnknownModule ValidationErrorType = "UnknownModule"
+	// ErrorTypeInvalidType is a field whose value is not of the type of the schema
+	ErrorTypeInvalidType ValidationErrorType = "InvalidType"
+	// ErrorTypeRequired is a required field that is missing
+	ErrorTypeRequired ValidationErrorType = "Required"
+	// ErrorTypeInvalidPermissions is a permissions value that is not an octal file mode
+	ErrorTypeInvalidPermissions ValidationErrorType = "InvalidPermissions"
+	// ErrorTypeInvalidOwner is an owner value that is not of the form user:group
+	ErrorTypeInvalidOwner ValidationErrorType = "InvalidOwner"
+	// ErrorTypeInvalidEncoding is an unknown encoding, or a content that cannot be decoded with its encoding
+	ErrorTypeInvalidEncoding ValidationErrorType = "InvalidEncoding"
+)
+
+// ValidationError is an error of the bootstrap data found by Validate
+type ValidationError struct {
+	// Field is the path of the invalid field, e.g. write_files[0].permissions
+	Field string
+	// Type is the type of the error
+	Type ValidationErrorType
+	// Detail describes the error
+	Detail string
+}
+
+func (e ValidationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s: %s", e.Type, e.Detail)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Field, e.Type, e.Detail)
+}
+
+// ValidationErrors are the errors of the bootstrap data found by Validate
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// the fields of the write_files entries the agent implements
+var fileFields = map[string]bool{
+	"path": true, "encoding": true, "owner": true, "permissions": true, "content": true, "append": true,
+}
+
+// Validate parses the bootstrap data against the schema of the cloud-config the agent executes,
+// the write_files and runcmd modules, and returns all its errors, or nil if it is valid. Unlike
+// Execute, which ignores them, the modules and fields the agent does not implement are errors.
+func Validate(bootstrapData string) ValidationErrors {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal([]byte(bootstrapData), &config); err != nil {
+		return ValidationErrors{{Type: ErrorTypeInvalidSyntax, Detail: err.Error()}}
+	}
+
+	var errs ValidationErrors
+	for _, module := range sortedKeys(config) {
+		switch strings.ToLower(module) {
+		case "write_files":
+			errs = append(errs, validateWriteFiles(module, config[module])...)
+		case "runcmd":
+			errs = append(errs, validateRunCmd(module, config[module])...)
+		default:
+			errs = append(errs, ValidationError{Field: module, Type: ErrorTypeUnknownModule,
+				Detail: "the module is not implemented by the agent"})
+		}
+	}
+	return errs
+}
+
+func validateWriteFiles(field string, value interface{}) ValidationErrors {
+	files, ok := value.([]interface{})
+	if !ok {
+		return ValidationErrors{{Field: field, Type: ErrorTypeInvalidType, Detail: "must be a list of files"}}
+	}
+	var errs ValidationErrors
+	for i, value := range files {
+		fileField := fmt.Sprintf("%s[%d]", field, i)
+		file, ok := value.(map[string]interface{})
+		if !ok {
+			errs = append(errs, ValidationError{Field: fileField, Type: ErrorTypeInvalidType, Detail: "must be a file"})
+			continue
+		}
+		errs = append(errs, validateFile(fileField, file)...)
+	}
+	return errs
+}
+
+func validateFile(field string, file map[string]interface{}) ValidationErrors {
+	var errs ValidationErrors
+	for _, name := range sortedKeys(file) {
+		if !fileFields[name] {
+			errs = append(errs, ValidationError{Field: field + "." + name, Type: ErrorTypeUnknownModule,
+				Detail: "the field is not implemented by the agent"})
+		}
+	}
+	strs := map[string]string{}
+	for _, name := range []string{"path", "encoding", "owner", "permissions", "content"} {
+		value, ok := file[name]
+		if !ok {
+			continue
+		}
+		str, ok := value.(string)
+		if !ok {
+			detail := "must be a string"
+			if name == "permissions" {
+				detail = "must be a quoted octal string, e.g. '0644'"
+			}
+			errs = append(errs, ValidationError{Field: field + "." + name, Type: ErrorTypeInvalidType, Detail: detail})
+			continue
+		}
+		strs[name] = str
+	}
+	if value, ok := file["append"]; ok {
+		if _, ok := value.(bool); !ok {
+			errs = append(errs, ValidationError{Field: field + ".append", Type: ErrorTypeInvalidType, Detail: "must be a boolean"})
+		}
+	}
+
+	if path, isString := file["path"].(string); file["path"] == nil || isString && strings.TrimSpace(path) == "" {
+		errs = append(errs, ValidationError{Field: field + ".path", Type: ErrorTypeRequired, Detail: "the path of the file is required"})
+	}
+	if permissions, ok := strs["permissions"]; ok && permissions != "" {
+		if mode, err := strconv.ParseUint(permissions, 8, 32); err != nil || mode > 07777 {
+			errs = append(errs, ValidationError{Field: field + ".permissions", Type: ErrorTypeInvalidPermissions,
+				Detail: fmt.Sprintf("%q is not an octal file mode", permissions)})
+		}
+	}
+	if owner, ok := strs["owner"]; ok && owner != "" {
+		if parts := strings.Split(owner, ":"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errs = append(errs, ValidationError{Field: field + ".owner", Type: ErrorTypeInvalidOwner,
+				Detail: fmt.Sprintf("%q is not of the form user:group", owner)})
+		}
+	}
+	if encoding, ok := strs["encoding"]; ok {
+		encodings := parseEncodingScheme(encoding)
+		if encodings[0] == "text/plain" && !isPlainEncoding(encoding) {
+			errs = append(errs, ValidationError{Field: field + ".encoding", Type: ErrorTypeInvalidEncoding,
+				Detail: fmt.Sprintf("unknown encoding %q", encoding)})
+		} else if _, err := decodeContent(strs["content"], encodings); err != nil {
+			errs = append(errs, ValidationError{Field: field + ".content", Type: ErrorTypeInvalidEncoding,
+				Detail: fmt.Sprintf("the content cannot be decoded as %s: %v", encoding, err)})
+		}
+	}
+	return errs
+}
+
+func validateRunCmd(field string, value interface{}) ValidationErrors {
+	commands, ok := value.([]interface{})
+	if !ok {
+		return ValidationErrors{{Field: field, Type: ErrorTypeInvalidType, Detail: "must be a list of commands"}}
+	}
+	var errs ValidationErrors
+	for i, command := range commands {
+		if _, ok := command.(string); !ok {
+			errs = append(errs, ValidationError{Field: fmt.Sprintf("%s[%d]", field, i), Type: ErrorTypeInvalidType,
+				Detail: "must be a command string, the list form is not implemented by the agent"})
+		}
+	}
+	return errs
+}
+
+// isPlainEncoding reports whether encoding, for which parseEncodingScheme falls back to text/plain, is plain text
+func isPlainEncoding(encoding string) bool {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "text/plain":
+		return true
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
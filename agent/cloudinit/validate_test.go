@@ -0,0 +1,77 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudinit_test
+
+import (
+	"encoding/base64"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common"
+)
+
+var _ = Describe("Validate", func() {
+	It("should accept the cloud-config of the kubeadm bootstrap provider", func() {
+		gzipped, err := common.GzipData([]byte("some-content"))
+		Expect(err).NotTo(HaveOccurred())
+		bootstrapData := `## template: jinja
+#cloud-config
+write_files:
+- path: /etc/kubernetes/pki/ca.crt
+  owner: root:root
+  permissions: '0640'
+  content: some-cert
+- path: /run/kubeadm/kubeadm.yaml
+  encoding: gzip+base64
+  content: ` + base64.StdEncoding.EncodeToString(gzipped) + `
+- path: /run/cluster-api/placeholder
+  encoding: b64
+  append: true
+  content: ` + base64.StdEncoding.EncodeToString([]byte("placeholder")) + `
+runcmd:
+- kubeadm init --config /run/kubeadm/kubeadm.yaml
+- echo success > /run/cluster-api/bootstrap-success.complete
+`
+		Expect(cloudinit.Validate(bootstrapData)).To(BeNil())
+	})
+
+	It("should report every error with its field and type", func() {
+		bootstrapData := `write_files:
+- path: /etc/a
+  permissions: '0999'
+  owner: root
+  defer: true
+- content: no path
+  encoding: base64
+- path: /etc/b
+  encoding: rot13
+  content: abc
+- path: /etc/c
+  permissions: 0644
+runcmd:
+- [kubeadm, init]
+users:
+- name: admin
+`
+		Expect(cloudinit.Validate(bootstrapData)).To(Equal(cloudinit.ValidationErrors{
+			{Field: "runcmd[0]", Type: cloudinit.ErrorTypeInvalidType, Detail: "must be a command string, the list form is not implemented by the agent"},
+			{Field: "users", Type: cloudinit.ErrorTypeUnknownModule, Detail: "the module is not implemented by the agent"},
+			{Field: "write_files[0].defer", Type: cloudinit.ErrorTypeUnknownModule, Detail: "the field is not implemented by the agent"},
+			{Field: "write_files[0].permissions", Type: cloudinit.ErrorTypeInvalidPermissions, Detail: `"0999" is not an octal file mode`},
+			{Field: "write_files[0].owner", Type: cloudinit.ErrorTypeInvalidOwner, Detail: `"root" is not of the form user:group`},
+			{Field: "write_files[1].path", Type: cloudinit.ErrorTypeRequired, Detail: "the path of the file is required"},
+			{Field: "write_files[1].content", Type: cloudinit.ErrorTypeInvalidEncoding, Detail: "the content cannot be decoded as base64: illegal base64 data at input byte 2"},
+			{Field: "write_files[2].encoding", Type: cloudinit.ErrorTypeInvalidEncoding, Detail: `unknown encoding "rot13"`},
+			{Field: "write_files[3].permissions", Type: cloudinit.ErrorTypeInvalidType, Detail: "must be a quoted octal string, e.g. '0644'"},
+		}))
+	})
+
+	It("should report bootstrap data that is not YAML", func() {
+		errs := cloudinit.Validate("write_files: [")
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(cloudinit.ErrorTypeInvalidSyntax))
+		Expect(errs.Error()).To(HavePrefix("InvalidSyntax: "))
+	})
+})
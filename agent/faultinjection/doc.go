@@ -0,0 +1,6 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package faultinjection makes the agent simulate failures on demand, so that the
+// remediation and alerting of the controllers can be tested without breaking real hosts
+package faultinjection
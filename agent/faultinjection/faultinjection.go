@@ -0,0 +1,92 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package faultinjection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
+)
+
+// Fault is a failure the agent can simulate
+type Fault string
+
+const (
+	// ScriptTimeout makes every script the agent runs fail as if it timed out
+	ScriptTimeout Fault = "script-timeout"
+	// RegistryUnreachable makes the scripts that pull the bundle from the registry fail
+	RegistryUnreachable Fault = "registry-unreachable"
+	// HeartbeatDrop stops the periodic health reports of the host
+	HeartbeatDrop Fault = "heartbeat-drop"
+)
+
+// ErrInjected is wrapped by the errors of the simulated failures
+var ErrInjected = errors.New("injected fault")
+
+var knownFaults = map[Fault]bool{ScriptTimeout: true, RegistryUnreachable: true, HeartbeatDrop: true}
+
+// Faults is a flag that holds the faults to inject. One or more
+// faults can be passed using the same flag, separated by commas:
+//
+//	--inject-faults script-timeout,heartbeat-drop
+type Faults map[Fault]bool
+
+// String implements flag.Value interface
+func (f *Faults) String() string {
+	var result []string
+	for fault := range *f {
+		result = append(result, string(fault))
+	}
+	sort.Strings(result)
+	return strings.Join(result, ",")
+}
+
+// Set implements flag.Value interface
+func (f *Faults) Set(value string) error {
+	if *f == nil {
+		*f = Faults{}
+	}
+	for _, s := range strings.Split(value, ",") {
+		fault := Fault(strings.TrimSpace(s))
+		if fault == "" {
+			continue
+		}
+		if !knownFaults[fault] {
+			return fmt.Errorf("unknown fault %q, expect one of %s, %s, %s", fault, ScriptTimeout, RegistryUnreachable, HeartbeatDrop)
+		}
+		(*f)[fault] = true
+	}
+	return nil
+}
+
+// Enabled reports whether the fault is injected
+func (f Faults) Enabled(fault Fault) bool {
+	return f[fault]
+}
+
+// CmdRunner runs the commands with the wrapped ICmdRunner unless a fault makes them fail
+type CmdRunner struct {
+	cloudinit.ICmdRunner
+	Faults Faults
+}
+
+// RunCmd fails cmd with the injected fault, if any applies to it, otherwise runs it
+func (r CmdRunner) RunCmd(ctx context.Context, cmd string) error {
+	if r.Faults.Enabled(RegistryUnreachable) && pullsFromRegistry(cmd) {
+		return fmt.Errorf("%w %s: dial tcp: connection refused", ErrInjected, RegistryUnreachable)
+	}
+	if r.Faults.Enabled(ScriptTimeout) {
+		return fmt.Errorf("%w %s: %w", ErrInjected, ScriptTimeout, context.DeadlineExceeded)
+	}
+	return r.ICmdRunner.RunCmd(ctx, cmd)
+}
+
+// pullsFromRegistry reports whether cmd downloads the bundle from the OCI registry
+func pullsFromRegistry(cmd string) bool {
+	return strings.Contains(cmd, "imgpkg pull")
+}
@@ -0,0 +1,16 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package faultinjection_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFaultinjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Faultinjection Suite")
+}
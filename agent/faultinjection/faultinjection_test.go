@@ -0,0 +1,63 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package faultinjection_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit/cloudinitfakes"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/faultinjection"
+)
+
+var _ = Describe("Faultinjection", func() {
+	Context("When the faults flag is set", func() {
+		It("should accept a comma separated list of faults", func() {
+			faults := faultinjection.Faults{}
+			Expect(faults.Set("script-timeout, heartbeat-drop")).To(Succeed())
+			Expect(faults.Set("registry-unreachable")).To(Succeed())
+			Expect(faults.String()).To(Equal("heartbeat-drop,registry-unreachable,script-timeout"))
+		})
+
+		It("should reject an unknown fault", func() {
+			faults := faultinjection.Faults{}
+			Expect(faults.Set("disk-full")).To(MatchError(ContainSubstring(`unknown fault "disk-full"`)))
+		})
+	})
+
+	Context("When running commands", func() {
+		var (
+			fakeCmdRunner *cloudinitfakes.FakeICmdRunner
+			runner        faultinjection.CmdRunner
+		)
+
+		BeforeEach(func() {
+			fakeCmdRunner = &cloudinitfakes.FakeICmdRunner{}
+			runner = faultinjection.CmdRunner{ICmdRunner: fakeCmdRunner, Faults: faultinjection.Faults{}}
+		})
+
+		It("should run the commands if no fault is injected", func() {
+			Expect(runner.RunCmd(context.TODO(), "imgpkg pull -i registry/bundle")).To(Succeed())
+			Expect(fakeCmdRunner.RunCmdCallCount()).To(Equal(1))
+		})
+
+		It("should fail every command as timed out if script-timeout is injected", func() {
+			runner.Faults[faultinjection.ScriptTimeout] = true
+			err := runner.RunCmd(context.TODO(), "kubeadm join")
+			Expect(errors.Is(err, faultinjection.ErrInjected)).To(BeTrue())
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			Expect(fakeCmdRunner.RunCmdCallCount()).To(Equal(0))
+		})
+
+		It("should fail only the registry pulls if registry-unreachable is injected", func() {
+			runner.Faults[faultinjection.RegistryUnreachable] = true
+			err := runner.RunCmd(context.TODO(), "imgpkg pull -i registry/bundle -o /var/lib/byoh/bundles")
+			Expect(err).To(MatchError(ContainSubstring("registry-unreachable")))
+			Expect(runner.RunCmd(context.TODO(), "kubeadm join")).To(Succeed())
+			Expect(fakeCmdRunner.RunCmdCallCount()).To(Equal(1))
+		})
+	})
+})
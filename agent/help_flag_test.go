@@ -20,11 +20,17 @@ var _ = Describe("Help flag for host agent", func() {
 				"--bootstrap-kubeconfig string",
 				"--certExpiryDuration int",
 				"--downloadpath string",
+				"--host-name string",
 				"--kubeconfig string",
 				"--label labelFlags",
 				"--metricsbindaddress string",
+				"--otlp-endpoint string",
 				"--namespace string",
+				"--network-check-interval duration",
 				"--skip-installation",
+				"--status-update-interval duration",
+				"--strict-cloud-init",
+				"--taint taintFlags",
 				"--version",
 				"-v, --v",
 				"--feature-gates mapStringBool",
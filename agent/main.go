@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package main
@@ -17,15 +18,20 @@ import (
 	"github.com/go-logr/logr"
 	pflag "github.com/spf13/pflag"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/faultinjection"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/reconciler"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/registration"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/version"
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/nodetaint"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/feature"
 	certv1 "k8s.io/api/certificates/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/rest"
 	klog "k8s.io/klog/v2"
 	"k8s.io/klog/v2/klogr"
@@ -82,6 +88,25 @@ func (l *labelFlags) Set(value string) error {
 	}
 }
 
+// taintFlags is a flag that holds the taints of the Node of the host, one or more comma-separated
+// taints in the form key=value:effect can be passed using the same flag
+type taintFlags []corev1.Taint
+
+// String implements flag.Value interface
+func (t *taintFlags) String() string {
+	return nodetaint.Format(*t)
+}
+
+// Set implements flag.Value interface
+func (t *taintFlags) Set(value string) error {
+	taints, err := nodetaint.Parse(value)
+	if err != nil {
+		return err
+	}
+	*t = append(*t, taints...)
+	return nil
+}
+
 func setupflags() {
 	klog.InitFlags(nil)
 	// clear any discard loggers set by dependecies
@@ -90,21 +115,39 @@ func setupflags() {
 	flag.StringVar(&namespace, "namespace", "default", "Namespace in the management cluster where you would like to register this host")
 	flag.Int64Var(&certExpiryDuration, "certExpiryDuration", registration.ExpirationSeconds, "Duration (in seconds) for the expiration of the host certificates")
 	flag.Var(&labels, "label", "labels to attach to the ByoHost CR in the form labelname=labelVal for e.g. '--label site=apac --label cores=2'")
+	flag.StringVar(&hostNameOverride, "host-name", "", "Name of the ByoHost and of the node of the host, the hostname of the host if empty")
+	flag.Var(&taints, "taint", "taints of the Node of the host when it joins a cluster in the form key=value:effect for e.g. '--taint dedicated=gpu:NoSchedule'")
 	flag.StringVar(&metricsbindaddress, "metricsbindaddress", ":8080", "metricsbindaddress is the TCP address that the controller should bind to for serving prometheus metrics.It can be set to \"0\" to disable the metrics serving")
 	flag.StringVar(&downloadpath, "downloadpath", "/var/lib/byoh/bundles", "File System path to keep the downloads")
 	flag.BoolVar(&skipInstallation, "skip-installation", false, "If you want to skip installation of the kubernetes component binaries")
+	flag.BoolVar(&strictCloudInit, "strict-cloud-init", false, "Validate the bootstrap data against the cloud-config directives the agent implements before bootstrapping, and fail on unknown modules, bad permissions or encodings instead of ignoring them")
 	flag.BoolVar(&printVersion, "version", false, "Print the version of the agent")
 	flag.StringVar(&bootstrapKubeConfig, "bootstrap-kubeconfig", "", "Provide bootstrap kubeconfig for bootstrap token workflow")
+	flag.DurationVar(&statusUpdateInterval, "status-update-interval", registration.DefaultStatusUpdateInterval, "Minimum interval between two status updates of the ByoHost; the health reports and other status changes in between are batched into a single patch")
+	flag.DurationVar(&networkCheckInterval, "network-check-interval", registration.DefaultNetworkCheckInterval, "Interval between two checks of the IP addresses of the host; changed addresses are re-advertised in the ByoHost status and, if the kubelet sets --node-ip, on the Node")
+	flag.Var(&injectedFaults, "inject-faults", "Faults to simulate for resilience testing, a comma-separated list of script-timeout, registry-unreachable and heartbeat-drop. Never set it on production hosts")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv(tracing.EndpointEnv), "Endpoint of the OpenTelemetry collector to export traces to with OTLP/HTTP, e.g. http://otel-collector:4318. Tracing is off if empty")
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	hiddenFlags := []string{"log-flush-frequency", "alsologtostderr", "log-backtrace-at", "log-dir", "logtostderr", "stderrthreshold", "vmodule", "azure-container-registry-config",
-		"log_backtrace_at", "log_dir", "log_file", "log_file_max_size", "add_dir_header", "skip_headers", "skip_log_headers", "one_output", "kubeconfig"}
+		"log_backtrace_at", "log_dir", "log_file", "log_file_max_size", "add_dir_header", "skip_headers", "skip_log_headers", "one_output", "kubeconfig", "inject-faults"}
 	for _, hiddenFlag := range hiddenFlags {
 		_ = pflag.CommandLine.MarkHidden(hiddenFlag)
 	}
 	feature.MutableGates.AddFlag(pflag.CommandLine)
 }
 
+// getHostName returns the name of the ByoHost of the host, --host-name or the hostname of the host
+func getHostName() (string, error) {
+	if hostNameOverride == "" {
+		return os.Hostname()
+	}
+	if errs := validation.IsDNS1123Subdomain(hostNameOverride); len(errs) > 0 {
+		return "", fmt.Errorf("invalid --host-name %q: %s", hostNameOverride, strings.Join(errs, "; "))
+	}
+	return hostNameOverride, nil
+}
+
 func setupTemplateParser() *cloudinit.TemplateParser {
 	var templateParser *cloudinit.TemplateParser
 	if registration.LocalHostRegistrar.ByoHostInfo.DefaultNetworkInterfaceName == "" {
@@ -120,15 +163,22 @@ func setupTemplateParser() *cloudinit.TemplateParser {
 }
 
 var (
-	namespace           string
-	scheme              *runtime.Scheme
-	labels              = make(labelFlags)
-	metricsbindaddress  string
-	downloadpath        string
-	skipInstallation    bool
-	printVersion        bool
-	bootstrapKubeConfig string
-	certExpiryDuration  int64
+	namespace            string
+	scheme               *runtime.Scheme
+	labels               = make(labelFlags)
+	taints               taintFlags
+	hostNameOverride     string
+	metricsbindaddress   string
+	downloadpath         string
+	skipInstallation     bool
+	strictCloudInit      bool
+	printVersion         bool
+	bootstrapKubeConfig  string
+	certExpiryDuration   int64
+	otlpEndpoint         string
+	injectedFaults       = make(faultinjection.Faults)
+	statusUpdateInterval time.Duration
+	networkCheckInterval time.Duration
 )
 
 // TODO - fix logging
@@ -148,30 +198,47 @@ func main() {
 
 	logger := klogr.New()
 	ctrl.SetLogger(logger)
-	hostName, err := os.Hostname()
+	hostName, err := getHostName()
 	if err != nil {
 		logger.Error(err, "could not determine hostname")
 		return
 	}
 
+	shutdownTracing := tracing.Setup("byoh-hostagent", otlpEndpoint)
+	defer flushTraces(logger, shutdownTracing)
+
+	onboarding := registration.NewOnboardingTimer()
+	// byohctl hands over its trace so that the onboarding of the host is a single trace
+	ctx, span := tracing.Start(tracing.ContextWithTraceParent(context.Background(), os.Getenv(tracing.TraceParentEnv)),
+		"agent.onboard", tracing.String(tracing.HostNameKey, hostName))
 	_, err = os.Stat(registration.GetBYOHConfigPath())
 	// Enable bootstrap flow if --bootstrap-kubeconfig is provided
 	// and config doesn't already exists in ~/.byoh/
 	if bootstrapKubeConfig != "" && errors.Is(err, os.ErrNotExist) {
-		if err = handleBootstrapFlow(logger, hostName); err != nil {
+		if err = handleBootstrapFlow(ctx, logger, hostName, onboarding); err != nil {
 			logger.Error(err, "bootstrap flow failed")
+			span.End(err)
+			flushTraces(logger, shutdownTracing)
 			os.Exit(1)
 		}
 	}
 	// Handle restart flow or if the ~/.byoh/config already exists
 	config := getConfig(logger)
 	k8sClient := getClient(logger, config)
-	registration.LocalHostRegistrar = &registration.HostRegistrar{K8sClient: k8sClient}
+	registration.LocalHostRegistrar = &registration.HostRegistrar{
+		K8sClient:    k8sClient,
+		AgentVersion: version.Get().GitVersion,
+		NodeTaints:   taints,
+	}
+	_, registerSpan := tracing.Start(ctx, "agent.register", tracing.String(tracing.HostNameKey, hostName))
 	err = registration.LocalHostRegistrar.Register(hostName, namespace, labels)
+	registerSpan.End(err)
+	span.End(err)
 	if err != nil {
 		logger.Error(err, "error registering host %s registration in namespace %s", hostName, namespace)
 		return
 	}
+	onboarding.Record(registration.OnboardingPhaseRegistration)
 
 	// Start certificate rotation goroutine.
 	// This is behind a feature flag for now. Set 'CERTIFICATE_ROTATION=true' to enable it.
@@ -208,26 +275,72 @@ func main() {
 	if skipInstallation {
 		logger.Info("skip-installation flag set, skipping installer initialisation")
 	}
+	var cmdRunner cloudinit.ICmdRunner = cloudinit.CmdRunner{}
+	if len(injectedFaults) > 0 {
+		logger.Info("WARNING: injecting faults, the host will fail on purpose", "faults", injectedFaults.String())
+		cmdRunner = faultinjection.CmdRunner{ICmdRunner: cmdRunner, Faults: injectedFaults}
+	}
 	hostReconciler := &reconciler.HostReconciler{
 		Client:              k8sClient,
-		CmdRunner:           cloudinit.CmdRunner{},
+		CmdRunner:           cmdRunner,
 		FileWriter:          cloudinit.FileWriter{},
 		TemplateParser:      setupTemplateParser(),
 		Recorder:            mgr.GetEventRecorderFor("hostagent-controller"),
 		SkipK8sInstallation: skipInstallation,
 		DownloadPath:        downloadpath,
+		StrictCloudInit:     strictCloudInit,
+		AgentVersion:        version.Get().GitVersion,
+		DefaultLogVerbosity: flag.Lookup("v").Value.(flag.Getter).Get().(klog.Level),
+		HostLockPath:        hostlock.DefaultPath,
+		NodeName:            hostNameOverride,
 	}
 	if err = hostReconciler.SetupWithManager(context.TODO(), mgr); err != nil {
 		logger.Error(err, "unable to create controller")
 		return
 	}
+	statusBatcher := &registration.StatusBatcher{
+		K8sClient:   k8sClient,
+		HostName:    hostName,
+		Namespace:   namespace,
+		MinInterval: statusUpdateInterval,
+	}
+	if err = mgr.Add(statusBatcher); err != nil {
+		logger.Error(err, "unable to add status batcher")
+		return
+	}
+	hostHealthChecker := &registration.HostHealthChecker{
+		K8sClient:      k8sClient,
+		HostName:       hostName,
+		Namespace:      namespace,
+		KubeconfigPath: registration.GetBYOHConfigPath(),
+		Onboarding:     onboarding,
+		DropHeartbeat:  injectedFaults.Enabled(faultinjection.HeartbeatDrop),
+		Batcher:        statusBatcher,
+	}
+	if err = mgr.Add(hostHealthChecker); err != nil {
+		logger.Error(err, "unable to add host health checker")
+		return
+	}
+	networkWatcher := &registration.NetworkWatcher{
+		K8sClient: k8sClient,
+		Registrar: registration.LocalHostRegistrar,
+		HostName:  hostName,
+		Namespace: namespace,
+		Recorder:  mgr.GetEventRecorderFor("hostagent-controller"),
+		Interval:  networkCheckInterval,
+		Batcher:   statusBatcher,
+	}
+	if err = mgr.Add(networkWatcher); err != nil {
+		logger.Error(err, "unable to add network watcher")
+		return
+	}
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		logger.Error(err, "problem running manager")
 		return
 	}
 }
 
-func handleBootstrapFlow(logger logr.Logger, hostName string) error {
+func handleBootstrapFlow(ctx context.Context, logger logr.Logger, hostName string, onboarding *registration.OnboardingTimer) error {
 	logger.Info("initiated bootstrap kubeconfig flow")
 	bootstrapClientConfig, err := registration.LoadRESTClientConfig(bootstrapKubeConfig)
 	if err != nil {
@@ -237,13 +350,23 @@ func handleBootstrapFlow(logger logr.Logger, hostName string) error {
 	if err != nil {
 		return fmt.Errorf("ByohCSR intialization failed: %v", err)
 	}
-	err = byohCSR.BootstrapKubeconfig(hostName)
+	byohCSR.Onboarding = onboarding
+	err = byohCSR.BootstrapKubeconfigWithContext(ctx, hostName)
 	if err != nil {
 		return fmt.Errorf("kubeconfig generation failed: %v", err)
 	}
 	return nil
 }
 
+// flushTraces sends the spans not exported yet to the collector
+func flushTraces(logger logr.Logger, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //nolint: mnd
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		logger.Error(err, "failed to export traces")
+	}
+}
+
 func certificateRotation(logger logr.Logger, hostName string, config *rest.Config) error {
 	var pollDuration = 5 * time.Second
 	for {
@@ -274,7 +397,8 @@ func certRotation(logger logr.Logger, hostName string, config *rest.Config) erro
 	// https://github.com/kubernetes-sigs/cluster-api/blob/main/docs/proposals/20210222-kubelet-authentication.md#kubelet-authenticator-flow
 	if time.Now().After(cert.NotAfter.Add(totalTimeCert / -5)) {
 		logger.Info("certificate expiration time left is less than 20%, renewing")
-		if err = handleBootstrapFlow(logger, hostName); err != nil {
+		// the renewal is not part of the onboarding of the host
+		if err = handleBootstrapFlow(context.Background(), logger, hostName, nil); err != nil {
 			logger.Error(err, "bootstrap flow failed")
 		}
 	} else {
@@ -1,20 +1,30 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package reconciler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/registration"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
@@ -36,18 +46,59 @@ type HostReconciler struct {
 	Recorder            record.EventRecorder
 	SkipK8sInstallation bool
 	DownloadPath        string
+	// StrictCloudInit makes the agent validate the bootstrap data before installing and
+	// bootstrapping the node, instead of ignoring what it does not implement
+	StrictCloudInit bool
+	// AgentVersion is the version of the running agent, reported in the ByoHost status
+	AgentVersion string
+	// DefaultLogVerbosity is the verbosity the agent was started with, restored when
+	// the ByoHost does not set an agent log verbosity
+	DefaultLogVerbosity klog.Level
+	// HostLockPath is the lock of the host the install and uninstall scripts are run under,
+	// so that they do not interleave with byohctl; the scripts are not locked if empty
+	HostLockPath string
+	// NodeName is the name of the node of the host written into the kubeadm configurations that do
+	// not set one, the hostname of the host is left to kubeadm if empty
+	NodeName string
+
+	// logVerbosity is the verbosity currently applied to the agent logs
+	logVerbosity *klog.Level
 }
 
 const (
 	bootstrapSentinelFile = "/run/cluster-api/bootstrap-success.complete"
+	// kubeletExtraArgsFile is the environment file the agent renders KUBELET_EXTRA_ARGS into
+	kubeletExtraArgsFile = "/var/lib/byoh/kubelet-extra-args"
+	// kubeletExtraArgsDropIn makes the kubelet service read kubeletExtraArgsFile. The kubeadm drop-in
+	// reads KUBELET_EXTRA_ARGS from /etc/default/kubelet on Debian, /etc/sysconfig/kubelet on RPM
+	// based OSes and neither on Flatcar; this drop-in is read after it, wherever the kubelet comes from.
+	kubeletExtraArgsDropIn = "/etc/systemd/system/kubelet.service.d/20-byoh-extra-args.conf"
+	// cgroupDriverFile is where the install script records the cgroup driver the container runtime uses
+	cgroupDriverFile = "/var/lib/byoh/cgroup-driver"
+	// cgroupDriverCgroupfs is the cgroup driver of hosts that do not run systemd as init system
+	cgroupDriverCgroupfs = "cgroupfs"
+	// swapPolicyFile is where the install script records that it kept the swap of the host enabled
+	swapPolicyFile = "/var/lib/byoh/swap-policy"
+	// swapPolicyKeep is the swap policy that leaves swap enabled, the kubelet then must not fail on it
+	swapPolicyKeep = "keep"
 	// KubeadmResetCommand is the command to run to force reset/remove nodes' local file system of the files created by kubeadm
 	KubeadmResetCommand = "kubeadm reset --force"
+	// KubeletStopCommand is the command to run to stop the kubelet of a node released without reset,
+	// so that it does not keep running against its former cluster
+	KubeletStopCommand = "systemctl disable --now kubelet"
+	// KubeletEnableCommand is the command to run to enable the kubelet again when the k8s components of
+	// a host released without reset are reused, kubeadm starts it on join
+	KubeletEnableCommand = "systemctl enable kubelet"
 )
 
 // Reconcile handles events for the ByoHost that is registered by this agent process
 func (r *HostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := ctrl.LoggerFrom(ctx)
 	logger.Info("Reconcile request received")
+	ctx, span := tracing.Start(ctx, "agent.reconcile", tracing.String(tracing.HostNameKey, req.Name))
+	defer func() {
+		span.End(reterr)
+	}()
 
 	// Fetch the ByoHost instance
 	byoHost := &infrastructurev1beta1.ByoHost{}
@@ -56,6 +107,14 @@ func (r *HostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctr
 		logger.Error(err, "error getting ByoHost")
 		return ctrl.Result{}, err
 	}
+	if byoHost.Status.MachineRef != nil {
+		span.SetAttributes(tracing.String(tracing.MachineNameKey, byoHost.Status.MachineRef.Name))
+	}
+	if byoHost.IsPaused() {
+		logger.Info("ByoHost is paused, not reconciling", "machineRef", byoHost.Status.MachineRef,
+			"conditions", byoHost.Status.Conditions)
+		return ctrl.Result{}, nil
+	}
 	helper, _ := patch.NewHelper(byoHost, r.Client)
 	defer func() {
 		err = helper.Patch(ctx, byoHost)
@@ -65,11 +124,21 @@ func (r *HostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctr
 		}
 	}()
 
+	if r.AgentVersion != "" {
+		byoHost.Status.AgentVersion = r.AgentVersion
+	}
+
+	if err = r.setLogVerbosity(byoHost); err != nil {
+		logger.Error(err, "error setting agent log verbosity")
+	}
+
 	// Check for host cleanup annotation
 	hostAnnotations := byoHost.GetAnnotations()
 	_, ok := hostAnnotations[infrastructurev1beta1.HostCleanupAnnotation]
 	if ok {
-		err = r.hostCleanUp(ctx, byoHost)
+		cleanupCtx, cleanupSpan := tracing.Start(ctx, "agent.cleanup")
+		err = r.hostCleanUp(cleanupCtx, byoHost)
+		cleanupSpan.End(err)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
@@ -107,6 +176,21 @@ func (r *HostReconciler) reconcileNormal(ctx context.Context, byoHost *infrastru
 			return ctrl.Result{}, err
 		}
 
+		if r.StrictCloudInit {
+			if errs := cloudinit.Validate(bootstrapScript); errs != nil {
+				logger.Error(errs, "invalid bootstrap data")
+				r.Recorder.Eventf(byoHost, corev1.EventTypeWarning, "BootstrapDataInvalid", "bootstrap data is invalid: %v", errs)
+				conditions.MarkFalse(byoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded, infrastructurev1beta1.BootstrapDataInvalidReason, clusterv1.ConditionSeverityError, errs.Error())
+				return ctrl.Result{}, errs
+			}
+		}
+
+		if installedVersion, ok := byoHost.Annotations[infrastructurev1beta1.InstalledK8sVersionAnnotation]; ok {
+			if err = r.reattachNode(ctx, byoHost, installedVersion); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		if r.SkipK8sInstallation {
 			logger.Info("Skipping installation of k8s components")
 		} else if !conditions.IsTrue(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded) {
@@ -115,10 +199,18 @@ func (r *HostReconciler) reconcileNormal(ctx context.Context, byoHost *infrastru
 				conditions.MarkFalse(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded, infrastructurev1beta1.K8sInstallationSecretUnavailableReason, clusterv1.ConditionSeverityInfo, "")
 				return ctrl.Result{}, nil
 			}
-			err = r.executeInstallerController(ctx, byoHost)
+			installStart := time.Now()
+			installCtx, span := tracing.Start(ctx, "agent.install")
+			err = r.executeInstallerController(installCtx, byoHost)
+			span.End(err)
 			if err != nil {
 				return ctrl.Result{}, err
 			}
+			if err = registration.SetOnboardingDurations(byoHost, map[string]time.Duration{
+				registration.OnboardingPhasePackageInstall: time.Since(installStart),
+			}); err != nil {
+				logger.Error(err, "error setting onboarding durations")
+			}
 			r.Recorder.Event(byoHost, corev1.EventTypeNormal, "InstallScriptExecutionSucceeded", "install script executed")
 			conditions.MarkTrue(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)
 		} else {
@@ -133,7 +225,16 @@ func (r *HostReconciler) reconcileNormal(ctx context.Context, byoHost *infrastru
 			return ctrl.Result{}, err
 		}
 
-		err = r.bootstrapK8sNode(ctx, bootstrapScript, byoHost)
+		err = r.writeKubeletExtraArgs(ctx, byoHost)
+		if err != nil {
+			logger.Error(err, "error writing kubelet extra args")
+			r.Recorder.Event(byoHost, corev1.EventTypeWarning, "WriteKubeletExtraArgsFailed", "writing kubelet extra args failed")
+			return ctrl.Result{}, err
+		}
+
+		bootstrapCtx, span := tracing.Start(ctx, "agent.bootstrap-node")
+		err = r.bootstrapK8sNode(bootstrapCtx, bootstrapScript, byoHost)
+		span.End(err)
 		if err != nil {
 			logger.Error(err, "error in bootstrapping k8s node")
 			r.Recorder.Event(byoHost, corev1.EventTypeWarning, "BootstrapK8sNodeFailed", "k8s Node Bootstrap failed")
@@ -174,7 +275,7 @@ func (r *HostReconciler) executeInstallerController(ctx context.Context, byoHost
 		return err
 	}
 	logger.Info("executing install script")
-	err = r.CmdRunner.RunCmd(ctx, installScript)
+	err = r.runLocked(ctx, "agent install", installScript)
 	if err != nil {
 		logger.Error(err, "error executing installation script")
 		r.Recorder.Event(byoHost, corev1.EventTypeWarning, "InstallScriptExecutionFailed", "install script execution failed")
@@ -251,6 +352,30 @@ func (r *HostReconciler) hostCleanUp(ctx context.Context, byoHost *infrastructur
 	logger := ctrl.LoggerFrom(ctx)
 	logger.Info("cleaning up host")
 
+	if _, ok := byoHost.Annotations[infrastructurev1beta1.SkipUninstallAnnotation]; ok {
+		logger.Info("skip-uninstall annotation set, releasing the host without resetting the node")
+		if err := r.CmdRunner.RunCmd(ctx, KubeletStopCommand); err != nil {
+			r.Recorder.Event(byoHost, corev1.EventTypeWarning, "StopKubeletFailed", "stopping the kubelet failed")
+			return errors.Wrapf(err, "failed to stop the kubelet")
+		}
+		// the components stay on the host, the next attach resets the node and reuses them if the new
+		// cluster runs their version, otherwise the install script of the new cluster runs again
+		installedVersion := byoHost.Annotations[infrastructurev1beta1.K8sVersionAnnotation]
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded, infrastructurev1beta1.K8sNodeReleasedWithoutResetReason, clusterv1.ConditionSeverityInfo, "")
+		if err := r.removeSentinelFile(ctx, byoHost); err != nil {
+			return err
+		}
+		if err := r.removeKubeletExtraArgs(ctx, byoHost); err != nil {
+			return err
+		}
+		r.Recorder.Event(byoHost, corev1.EventTypeNormal, "HostReleasedWithoutReset", "host released without kubeadm reset and uninstall")
+		byoHost.Spec.InstallationSecret = nil
+		r.removeAnnotations(ctx, byoHost)
+		byoHost.Annotations[infrastructurev1beta1.InstalledK8sVersionAnnotation] = installedVersion
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded, infrastructurev1beta1.K8sNodeReleasedWithoutResetReason, clusterv1.ConditionSeverityInfo, "")
+		return nil
+	}
+
 	// Guard kubeadm reset behind the installation condition — only run if k8s was installed.
 	// MarkFalse immediately after reset so retries skip reset and only retry the uninstall script.
 	k8sComponentsInstallationSucceeded := conditions.Get(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)
@@ -288,7 +413,7 @@ func (r *HostReconciler) hostCleanUp(ctx context.Context, byoHost *infrastructur
 			logger.Error(err, "error parsing Uninstallation script")
 			return err
 		}
-		err = r.CmdRunner.RunCmd(ctx, uninstallScript)
+		err = r.runLocked(ctx, "agent uninstall", uninstallScript)
 		if err != nil {
 			logger.Error(err, "error executing Uninstallation script")
 			r.Recorder.Event(byoHost, corev1.EventTypeWarning, "UninstallScriptExecutionFailed", "uninstall script execution failed")
@@ -305,6 +430,11 @@ func (r *HostReconciler) hostCleanUp(ctx context.Context, byoHost *infrastructur
 		return err
 	}
 
+	err = r.removeKubeletExtraArgs(ctx, byoHost)
+	if err != nil {
+		return err
+	}
+
 	err = r.deleteEndpointIP(ctx, byoHost)
 	if err != nil {
 		return err
@@ -316,11 +446,35 @@ func (r *HostReconciler) hostCleanUp(ctx context.Context, byoHost *infrastructur
 	return nil
 }
 
+// runLocked runs script under the host lock, waiting for the byohctl operation holding it
+func (r *HostReconciler) runLocked(ctx context.Context, operation, script string) error {
+	if r.HostLockPath == "" {
+		return r.CmdRunner.RunCmd(ctx, script)
+	}
+	logger := ctrl.LoggerFrom(ctx)
+	lock, err := hostlock.Acquire(ctx, r.HostLockPath, operation, func(holder string) {
+		logger.Info("waiting for the host lock", "operation", operation, "holder", holder)
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to lock the host")
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			logger.Error(err, "error releasing the host lock")
+		}
+	}()
+	return r.CmdRunner.RunCmd(ctx, script)
+}
+
 func (r *HostReconciler) resetNode(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
 	logger := ctrl.LoggerFrom(ctx)
 	logger.Info("Running kubeadm reset")
 
-	err := r.CmdRunner.RunCmd(ctx, KubeadmResetCommand)
+	resetCommand := KubeadmResetCommand
+	if criSocket := byoHost.Annotations[infrastructurev1beta1.CRISocketAnnotation]; criSocket != "" {
+		resetCommand = fmt.Sprintf("%s --cri-socket %s", KubeadmResetCommand, criSocket)
+	}
+	err := r.CmdRunner.RunCmd(ctx, resetCommand)
 	if err != nil {
 		r.Recorder.Event(byoHost, corev1.EventTypeWarning, "ResetK8sNodeFailed", "k8s Node Reset failed")
 		return errors.Wrapf(err, "failed to exec kubeadm reset")
@@ -330,13 +484,172 @@ func (r *HostReconciler) resetNode(ctx context.Context, byoHost *infrastructurev
 	return nil
 }
 
+// reattachNode resets the node of a host released without reset, so that it can join its new cluster.
+// The k8s components left installed are reused when they are of the version of the new cluster, the
+// install script is then skipped; otherwise it runs as on any attach.
+func (r *HostReconciler) reattachNode(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost, installedVersion string) error {
+	logger := ctrl.LoggerFrom(ctx)
+	if err := r.resetNode(ctx, byoHost); err != nil {
+		return err
+	}
+	delete(byoHost.Annotations, infrastructurev1beta1.InstalledK8sVersionAnnotation)
+
+	version := byoHost.Annotations[infrastructurev1beta1.K8sVersionAnnotation]
+	if r.SkipK8sInstallation || installedVersion == "" || installedVersion != version {
+		logger.Info("k8s components left installed can not be reused", "installedVersion", installedVersion, "version", version)
+		return nil
+	}
+	if err := r.CmdRunner.RunCmd(ctx, KubeletEnableCommand); err != nil {
+		r.Recorder.Event(byoHost, corev1.EventTypeWarning, "EnableKubeletFailed", "enabling the kubelet failed")
+		return errors.Wrapf(err, "failed to enable the kubelet")
+	}
+	logger.Info("reusing the k8s components left installed", "version", version)
+	r.Recorder.Eventf(byoHost, corev1.EventTypeNormal, "K8sComponentsReused", "k8s components %s already installed, install script skipped", version)
+	conditions.MarkTrue(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)
+	return nil
+}
+
 func (r *HostReconciler) bootstrapK8sNode(ctx context.Context, bootstrapScript string, byoHost *infrastructurev1beta1.ByoHost) error {
 	logger := ctrl.LoggerFrom(ctx)
 	logger.Info("Bootstraping k8s Node")
 	return cloudinit.ScriptExecutor{
 		WriteFilesExecutor:    r.FileWriter,
 		RunCmdExecutor:        r.CmdRunner,
-		ParseTemplateExecutor: r.TemplateParser}.Execute(bootstrapScript)
+		ParseTemplateExecutor: r.TemplateParser,
+		CRISocket:             byoHost.Annotations[infrastructurev1beta1.CRISocketAnnotation],
+		NodeName:              r.NodeName}.Execute(bootstrapScript)
+}
+
+// setLogVerbosity switches the verbosity of the agent logs to the one requested on the ByoHost,
+// or back to the startup verbosity if none is requested
+func (r *HostReconciler) setLogVerbosity(byoHost *infrastructurev1beta1.ByoHost) error {
+	verbosity := r.DefaultLogVerbosity
+	if byoHost.Spec.AgentLogVerbosity != nil {
+		verbosity = klog.Level(*byoHost.Spec.AgentLogVerbosity)
+	}
+	current := r.DefaultLogVerbosity
+	if r.logVerbosity != nil {
+		current = *r.logVerbosity
+	}
+	if verbosity == current {
+		return nil
+	}
+
+	// Set on any klog.Level updates the global verbosity of klog
+	if err := new(klog.Level).Set(strconv.Itoa(int(verbosity))); err != nil {
+		return err
+	}
+	r.logVerbosity = &verbosity
+	r.Recorder.Eventf(byoHost, corev1.EventTypeNormal, "AgentLogVerbosityChanged", "agent log verbosity set to %d", verbosity)
+	return nil
+}
+
+// writeKubeletExtraArgs renders the kubelet flags requested on the attached ByoMachine and on the
+// ByoHost into the environment file of the kubelet service, before kubeadm starts the kubelet
+func (r *HostReconciler) writeKubeletExtraArgs(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	args, err := kubeletExtraArgs(byoHost, readInstallerRecord(cgroupDriverFile), readInstallerRecord(swapPolicyFile))
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	logger.Info("Writing kubelet extra args", "file", kubeletExtraArgsFile)
+	files := []*cloudinit.Files{
+		{
+			Path:        kubeletExtraArgsFile,
+			Permissions: "0644",
+			Content:     renderKubeletExtraArgs(args),
+		},
+		{
+			Path:        kubeletExtraArgsDropIn,
+			Permissions: "0644",
+			Content:     fmt.Sprintf("[Service]\nEnvironmentFile=-%s\n", kubeletExtraArgsFile),
+		},
+	}
+	for _, file := range files {
+		if err := r.FileWriter.MkdirIfNotExists(filepath.Dir(file.Path)); err != nil {
+			return err
+		}
+		if err := r.FileWriter.WriteToFile(file); err != nil {
+			return err
+		}
+	}
+	// kubeadm restarts the kubelet without reloading its unit
+	return r.CmdRunner.RunCmd(ctx, "systemctl daemon-reload")
+}
+
+// kubeletExtraArgs merges the kubelet flags of the ByoMachine and of the ByoHost. kubeadm configures
+// the kubelet with the systemd cgroup driver, so the cgroup driver is added when the container
+// runtime was set up with cgroupfs, and failSwapOn is turned off when the installer kept swap enabled.
+func kubeletExtraArgs(byoHost *infrastructurev1beta1.ByoHost, cgroupDriver, swapPolicy string) (map[string]string, error) {
+	args := make(map[string]string)
+	if encodedArgs, ok := byoHost.Annotations[infrastructurev1beta1.KubeletExtraArgsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(encodedArgs), &args); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode the %s annotation", infrastructurev1beta1.KubeletExtraArgsAnnotation)
+		}
+	}
+	for name, value := range byoHost.Spec.KubeletExtraArgs {
+		args[name] = value
+	}
+	if _, ok := args["cgroup-driver"]; !ok && cgroupDriver == cgroupDriverCgroupfs {
+		args["cgroup-driver"] = cgroupDriver
+	}
+	if _, ok := args["fail-swap-on"]; !ok && swapPolicy == swapPolicyKeep {
+		args["fail-swap-on"] = "false"
+	}
+	return args, nil
+}
+
+// readInstallerRecord returns the value the install script recorded in path, or an empty
+// string if the k8s components were not installed by the agent
+func readInstallerRecord(path string) string {
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value))
+}
+
+// renderKubeletExtraArgs renders the kubelet flags, sorted by name, as the KUBELET_EXTRA_ARGS variable
+func renderKubeletExtraArgs(args map[string]string) string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	flags := make([]string, 0, len(names))
+	for _, name := range names {
+		flags = append(flags, fmt.Sprintf("--%s=%s", name, args[name]))
+	}
+	return fmt.Sprintf("KUBELET_EXTRA_ARGS=\"%s\"\n", strings.Join(flags, " "))
+}
+
+// removeKubeletExtraArgs removes the environment file of the kubelet service and its drop-in, if the
+// agent wrote them. The drop-in reads the environment file optionally, so the unit is not reloaded.
+func (r *HostReconciler) removeKubeletExtraArgs(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
+	cgroupDriver, swapPolicy := readInstallerRecord(cgroupDriverFile), readInstallerRecord(swapPolicyFile)
+	for _, file := range []string{cgroupDriverFile, swapPolicyFile} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to delete installer record %s", file)
+		}
+	}
+	if args, err := kubeletExtraArgs(byoHost, cgroupDriver, swapPolicy); err == nil && len(args) == 0 {
+		return nil
+	}
+
+	logger := ctrl.LoggerFrom(ctx)
+	logger.Info("Removing the kubelet extra args file")
+	for _, file := range []string{kubeletExtraArgsDropIn, kubeletExtraArgsFile} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to delete kubelet extra args file %s", file)
+		}
+	}
+	return nil
 }
 
 func (r *HostReconciler) removeSentinelFile(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
@@ -399,4 +712,16 @@ func (r *HostReconciler) removeAnnotations(ctx context.Context, byoHost *infrast
 
 	// Remove the bundle registry annotation
 	delete(byoHost.Annotations, infrastructurev1beta1.BundleLookupBaseRegistryAnnotation)
+
+	// Remove the cri socket annotation
+	delete(byoHost.Annotations, infrastructurev1beta1.CRISocketAnnotation)
+
+	// Remove the kubelet extra args annotation
+	delete(byoHost.Annotations, infrastructurev1beta1.KubeletExtraArgsAnnotation)
+
+	// Remove the heartbeat timeout annotation of the cluster
+	delete(byoHost.Annotations, infrastructurev1beta1.HeartbeatTimeoutAnnotation)
+
+	// Remove the skip uninstall annotation, it only applies to a single release
+	delete(byoHost.Annotations, infrastructurev1beta1.SkipUninstallAnnotation)
 }
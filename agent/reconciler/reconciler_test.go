@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package reconciler_test
@@ -10,6 +11,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit/cloudinitfakes"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/reconciler"
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
@@ -18,6 +20,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
@@ -107,6 +111,66 @@ var _ = Describe("Byohost Agent Tests", func() {
 			}))
 		})
 
+		It("should not reconcile a paused ByoHost", func() {
+			byoHost.Annotations = map[string]string{infrastructurev1beta1.HostPausedAnnotation: ""}
+			Expect(patchHelper.Patch(ctx, byoHost)).Should(Succeed())
+			hostReconciler.AgentVersion = "v0.5.0"
+
+			result, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+				NamespacedName: byoHostLookupKey,
+			})
+			Expect(result).To(Equal(controllerruntime.Result{}))
+			Expect(reconcilerErr).ToNot(HaveOccurred())
+
+			updatedByoHost := &infrastructurev1beta1.ByoHost{}
+			Expect(k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)).To(Succeed())
+			Expect(updatedByoHost.Status.AgentVersion).To(BeEmpty())
+			Expect(conditions.Has(updatedByoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded)).To(BeFalse())
+		})
+
+		It("should report the agent version in the ByoHost status", func() {
+			hostReconciler.AgentVersion = "v0.5.0"
+			_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+				NamespacedName: byoHostLookupKey,
+			})
+			Expect(reconcilerErr).ToNot(HaveOccurred())
+
+			updatedByoHost := &infrastructurev1beta1.ByoHost{}
+			err := k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedByoHost.Status.AgentVersion).To(Equal("v0.5.0"))
+		})
+
+		It("should apply the agent log verbosity of the ByoHost and restore the startup verbosity once it is unset", func() {
+			hostReconciler.DefaultLogVerbosity = 0
+			defer func() { _ = new(klog.Level).Set("0") }()
+
+			byoHost.Spec.AgentLogVerbosity = pointer.Int32(4)
+			Expect(patchHelper.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+			_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+				NamespacedName: byoHostLookupKey,
+			})
+			Expect(reconcilerErr).ToNot(HaveOccurred())
+			Expect(klog.V(4).Enabled()).To(BeTrue())
+
+			updatedByoHost := &infrastructurev1beta1.ByoHost{}
+			Expect(k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)).NotTo(HaveOccurred())
+			helper, err := patch.NewHelper(updatedByoHost, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedByoHost.Spec.AgentLogVerbosity = nil
+			Expect(helper.Patch(ctx, updatedByoHost)).NotTo(HaveOccurred())
+			_, reconcilerErr = hostReconciler.Reconcile(ctx, controllerruntime.Request{
+				NamespacedName: byoHostLookupKey,
+			})
+			Expect(reconcilerErr).ToNot(HaveOccurred())
+			Expect(klog.V(4).Enabled()).To(BeFalse())
+
+			Expect(eventutils.CollectEvents(recorder.Events)).To(ConsistOf(
+				"Normal AgentLogVerbosityChanged agent log verbosity set to 4",
+				"Normal AgentLogVerbosityChanged agent log verbosity set to 0",
+			))
+		})
+
 		Context("When MachineRef is set", func() {
 			BeforeEach(func() {
 				byoMachine = builder.ByoMachine(ns, "test-byomachine").Build()
@@ -214,6 +278,47 @@ runCmd:
 					))
 				})
 
+				It("should not install nor bootstrap the host if strict-cloud-init is set and the bootstrap data is invalid", func() {
+					hostReconciler.StrictCloudInit = true
+					bootstrapSecret.Data["value"] = []byte(`write_files:
+- path: fake/path
+  permissions: '0999'
+  content: blah
+users:
+- name: admin`)
+					Expect(k8sClient.Update(ctx, bootstrapSecret)).NotTo(HaveOccurred())
+
+					result, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+						NamespacedName: byoHostLookupKey,
+					})
+					Expect(result).To(Equal(controllerruntime.Result{}))
+					Expect(reconcilerErr).To(MatchError(cloudinit.ValidationErrors{
+						{Field: "users", Type: cloudinit.ErrorTypeUnknownModule, Detail: "the module is not implemented by the agent"},
+						{Field: "write_files[0].permissions", Type: cloudinit.ErrorTypeInvalidPermissions, Detail: `"0999" is not an octal file mode`},
+					}))
+					Expect(fakeCommandRunner.RunCmdCallCount()).To(Equal(0))
+					Expect(fakeFileWriter.WriteToFileCallCount()).To(Equal(0))
+
+					updatedByoHost := &infrastructurev1beta1.ByoHost{}
+					err := k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)
+					Expect(err).ToNot(HaveOccurred())
+
+					k8sNodeBootstrapSucceeded := conditions.Get(updatedByoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded)
+					Expect(*k8sNodeBootstrapSucceeded).To(conditions.MatchCondition(clusterv1.Condition{
+						Type:     infrastructurev1beta1.K8sNodeBootstrapSucceeded,
+						Status:   corev1.ConditionFalse,
+						Reason:   infrastructurev1beta1.BootstrapDataInvalidReason,
+						Severity: clusterv1.ConditionSeverityError,
+						Message:  reconcilerErr.Error(),
+					}))
+
+					// assert events
+					events := eventutils.CollectEvents(recorder.Events)
+					Expect(events).Should(ConsistOf([]string{
+						"Warning BootstrapDataInvalid bootstrap data is invalid: " + reconcilerErr.Error(),
+					}))
+				})
+
 				It("should set the Reason to InstallationSecretUnavailableReason", func() {
 					result, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
 						NamespacedName: byoHostLookupKey,
@@ -325,6 +430,115 @@ runCmd:
 						}))
 					})
 
+					// releaseAndReattach attaches the host, releases it without reset and re-attaches it to a
+					// cluster of k8sVersion
+					releaseAndReattach := func(k8sVersion string) *infrastructurev1beta1.ByoHost {
+						_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+							NamespacedName: byoHostLookupKey,
+						})
+						Expect(reconcilerErr).ToNot(HaveOccurred())
+
+						// detach
+						attachedByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClient.Get(ctx, byoHostLookupKey, attachedByoHost)).NotTo(HaveOccurred())
+						detachHelper, err := patch.NewHelper(attachedByoHost, k8sClient)
+						Expect(err).NotTo(HaveOccurred())
+						attachedByoHost.Annotations[infrastructurev1beta1.HostCleanupAnnotation] = ""
+						attachedByoHost.Annotations[infrastructurev1beta1.SkipUninstallAnnotation] = ""
+						Expect(detachHelper.Patch(ctx, attachedByoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+						_, reconcilerErr = hostReconciler.Reconcile(ctx, controllerruntime.Request{
+							NamespacedName: byoHostLookupKey,
+						})
+						Expect(reconcilerErr).ToNot(HaveOccurred())
+						Expect(fakeCommandRunner.RunCmdCallCount()).To(Equal(3))
+						_, stopCommand := fakeCommandRunner.RunCmdArgsForCall(2)
+						Expect(stopCommand).To(Equal(reconciler.KubeletStopCommand))
+
+						// re-attach
+						releasedByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClient.Get(ctx, byoHostLookupKey, releasedByoHost)).NotTo(HaveOccurred())
+						Expect(conditions.IsFalse(releasedByoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)).To(BeTrue())
+						Expect(releasedByoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.InstalledK8sVersionAnnotation, testK8sVersion))
+						reattachHelper, err := patch.NewHelper(releasedByoHost, k8sClient)
+						Expect(err).NotTo(HaveOccurred())
+						releasedByoHost.Status.MachineRef = byoHost.Status.MachineRef
+						releasedByoHost.Spec.BootstrapSecret = byoHost.Spec.BootstrapSecret
+						releasedByoHost.Spec.InstallationSecret = byoHost.Spec.InstallationSecret
+						releasedByoHost.Annotations[infrastructurev1beta1.K8sVersionAnnotation] = k8sVersion
+						releasedByoHost.Annotations[infrastructurev1beta1.BundleLookupBaseRegistryAnnotation] = testBundleLookupBaseRegistry
+						Expect(reattachHelper.Patch(ctx, releasedByoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+						_, reconcilerErr = hostReconciler.Reconcile(ctx, controllerruntime.Request{
+							NamespacedName: byoHostLookupKey,
+						})
+						Expect(reconcilerErr).ToNot(HaveOccurred())
+
+						reattachedByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClient.Get(ctx, byoHostLookupKey, reattachedByoHost)).NotTo(HaveOccurred())
+						Expect(reattachedByoHost.Annotations).NotTo(HaveKey(infrastructurev1beta1.InstalledK8sVersionAnnotation))
+						Expect(conditions.IsTrue(reattachedByoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)).To(BeTrue())
+						Expect(conditions.IsTrue(reattachedByoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded)).To(BeTrue())
+						return reattachedByoHost
+					}
+
+					It("should reset the node and reuse the k8s components when it is re-attached to a cluster of the same version after a release without reset", func() {
+						releaseAndReattach(testK8sVersion)
+
+						// kubeadm reset, kubelet enabled and bootstrap, the install script is skipped
+						Expect(fakeCommandRunner.RunCmdCallCount()).To(Equal(6))
+						_, resetCommand := fakeCommandRunner.RunCmdArgsForCall(3)
+						Expect(resetCommand).To(Equal(reconciler.KubeadmResetCommand))
+						_, enableCommand := fakeCommandRunner.RunCmdArgsForCall(4)
+						Expect(enableCommand).To(Equal(reconciler.KubeletEnableCommand))
+						Expect(eventutils.CollectEvents(recorder.Events)).Should(ConsistOf([]string{
+							eventInstallScriptExecutionSucceeded,
+							eventBootstrapK8sNodeSucceeded,
+							"Normal HostReleasedWithoutReset host released without kubeadm reset and uninstall",
+							"Normal ResetK8sNodeSucceeded k8s Node Reset completed",
+							fmt.Sprintf("Normal K8sComponentsReused k8s components %s already installed, install script skipped", testK8sVersion),
+							eventBootstrapK8sNodeSucceeded,
+						}))
+					})
+
+					It("should reset the node and install the k8s components again when it is re-attached to a cluster of another version after a release without reset", func() {
+						releaseAndReattach("1.23")
+
+						// kubeadm reset, install script and bootstrap
+						Expect(fakeCommandRunner.RunCmdCallCount()).To(Equal(6))
+						_, resetCommand := fakeCommandRunner.RunCmdArgsForCall(3)
+						Expect(resetCommand).To(Equal(reconciler.KubeadmResetCommand))
+						Expect(eventutils.CollectEvents(recorder.Events)).Should(ConsistOf([]string{
+							eventInstallScriptExecutionSucceeded,
+							eventBootstrapK8sNodeSucceeded,
+							"Normal HostReleasedWithoutReset host released without kubeadm reset and uninstall",
+							"Normal ResetK8sNodeSucceeded k8s Node Reset completed",
+							eventInstallScriptExecutionSucceeded,
+							eventBootstrapK8sNodeSucceeded,
+						}))
+					})
+
+					It("should write the kubelet extra args of the ByoMachine and the ByoHost before the bootstrap", func() {
+						byoHost.Annotations[infrastructurev1beta1.KubeletExtraArgsAnnotation] = `{"max-pods":"200","node-ip":"10.0.0.1"}`
+						byoHost.Spec.KubeletExtraArgs = map[string]string{"max-pods": "250", "reserved-cpus": "0-1"}
+						Expect(patchHelper.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+
+						_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+							NamespacedName: byoHostLookupKey,
+						})
+						Expect(reconcilerErr).ToNot(HaveOccurred())
+
+						Expect(fakeFileWriter.WriteToFileCallCount()).To(Equal(3))
+						kubeletExtraArgsFile := fakeFileWriter.WriteToFileArgsForCall(0)
+						Expect(kubeletExtraArgsFile.Path).To(Equal("/var/lib/byoh/kubelet-extra-args"))
+						Expect(kubeletExtraArgsFile.Content).To(Equal("KUBELET_EXTRA_ARGS=\"--max-pods=250 --node-ip=10.0.0.1 --reserved-cpus=0-1\"\n"))
+						// the drop-in does not depend on the environment file of the kubelet packaging,
+						// which Flatcar, with the kubelet of a systemd-sysext image, does not have
+						kubeletDropIn := fakeFileWriter.WriteToFileArgsForCall(1)
+						Expect(kubeletDropIn.Path).To(Equal("/etc/systemd/system/kubelet.service.d/20-byoh-extra-args.conf"))
+						Expect(kubeletDropIn.Content).To(Equal("[Service]\nEnvironmentFile=-/var/lib/byoh/kubelet-extra-args\n"))
+						_, reloadCommand := fakeCommandRunner.RunCmdArgsForCall(1)
+						Expect(reloadCommand).To(Equal("systemctl daemon-reload"))
+					})
+
 					It("should set K8sNodeBootstrapSucceeded to false with Reason CloudInitExecutionFailedReason if the bootstrap execution fails", func() {
 						conditions.MarkTrue(byoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)
 						Expect(patchHelper.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
@@ -626,6 +840,53 @@ runCmd:
 				Expect(events).To(ContainElement("Warning ReadUninstallationSecretFailed uninstallation secret " + missingSecretName + " not found"))
 			})
 
+			It("should release the host without resetting the node if the skip-uninstall annotation is set", func() {
+				byoHost.Annotations[infrastructurev1beta1.SkipUninstallAnnotation] = ""
+				Expect(patchHelper.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+
+				_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+					NamespacedName: byoHostLookupKey,
+				})
+				Expect(reconcilerErr).ToNot(HaveOccurred())
+
+				// assert neither kubeadm reset nor the uninstall script is called, only the kubelet is stopped
+				Expect(fakeCommandRunner.RunCmdCallCount()).To(Equal(1))
+				_, stopCommand := fakeCommandRunner.RunCmdArgsForCall(0)
+				Expect(stopCommand).To(Equal(reconciler.KubeletStopCommand))
+
+				updatedByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)).NotTo(HaveOccurred())
+				Expect(updatedByoHost.Status.MachineRef).To(BeNil())
+				Expect(updatedByoHost.Labels).NotTo(HaveKey(clusterv1.ClusterNameLabel))
+				Expect(updatedByoHost.Annotations).NotTo(HaveKey(infrastructurev1beta1.HostCleanupAnnotation))
+				Expect(updatedByoHost.Annotations).NotTo(HaveKey(infrastructurev1beta1.SkipUninstallAnnotation))
+				Expect(conditions.GetReason(updatedByoHost, infrastructurev1beta1.K8sComponentsInstallationSucceeded)).To(Equal(infrastructurev1beta1.K8sNodeReleasedWithoutResetReason))
+				Expect(conditions.GetReason(updatedByoHost, infrastructurev1beta1.K8sNodeBootstrapSucceeded)).To(Equal(infrastructurev1beta1.K8sNodeReleasedWithoutResetReason))
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf([]string{
+					"Normal HostReleasedWithoutReset host released without kubeadm reset and uninstall",
+				}))
+			})
+
+			It("should pass the cri socket to kubeadm reset when it is recorded on the host", func() {
+				criSocket := "unix:///var/run/crio/crio.sock"
+				byoHost.Annotations[infrastructurev1beta1.CRISocketAnnotation] = criSocket
+				Expect(patchHelper.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).NotTo(HaveOccurred())
+
+				_, reconcilerErr := hostReconciler.Reconcile(ctx, controllerruntime.Request{
+					NamespacedName: byoHostLookupKey,
+				})
+				Expect(reconcilerErr).ToNot(HaveOccurred())
+
+				_, resetCommand := fakeCommandRunner.RunCmdArgsForCall(0)
+				Expect(resetCommand).To(Equal(reconciler.KubeadmResetCommand + " --cri-socket " + criSocket))
+
+				updatedByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClient.Get(ctx, byoHostLookupKey, updatedByoHost)).NotTo(HaveOccurred())
+				Expect(updatedByoHost.Annotations).NotTo(HaveKey(infrastructurev1beta1.CRISocketAnnotation))
+			})
+
 			It("should not run kubeadm reset a second time when uninstall secret is absent", func() {
 				// First reconcile: kubeadm reset runs, uninstall is skipped (nil secret ref), reconcile returns nil
 				byoHost.Spec.UninstallationSecret = nil
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package registration
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 	certv1 "k8s.io/api/certificates/v1"
 	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
@@ -52,6 +54,8 @@ type ByohCSR struct {
 	configPath            string
 	logger                logr.Logger
 	expiryDuration        time.Duration
+	// Onboarding, if set, records the auth and kubeconfig phases of the bootstrap
+	Onboarding *OnboardingTimer
 }
 
 // NewByohCSR returns a ByohCSR instance
@@ -73,23 +77,26 @@ func NewByohCSR(bootstrapClientConfig *restclient.Config, logger logr.Logger, ex
 // its running on and once the CSR is approved it will fetch the Certificate
 // and create a kubeconfig which will be used then by the host reconciler
 func (bcsr *ByohCSR) BootstrapKubeconfig(hostName string) error {
-	reqName, reqUID, err := bcsr.RequestBYOHClientCert(hostName)
-	if err != nil {
-		return err
-	}
-	bcsr.logger.Info("CSR request created")
-	// wait for certificate to be issued
-	ctx, cancel := context.WithTimeout(context.TODO(), CSRApprovalTimeout)
-	defer cancel()
-	bcsr.logger.Info("waiting for client certificate to be issued")
-	certData, err := csr.WaitForCertificate(ctx, bcsr.bootstrapClient, reqName, reqUID)
+	return bcsr.BootstrapKubeconfigWithContext(context.TODO(), hostName)
+}
+
+// BootstrapKubeconfigWithContext is BootstrapKubeconfig with the wait for the certificate
+// bound to ctx, and its phases traced as children of the span of ctx
+func (bcsr *ByohCSR) BootstrapKubeconfigWithContext(ctx context.Context, hostName string) error {
+	authCtx, span := tracing.Start(ctx, "agent.auth", tracing.String(tracing.HostNameKey, hostName))
+	certData, err := bcsr.requestCertificate(authCtx, hostName)
+	span.End(err)
 	if err != nil {
 		return err
 	}
+	bcsr.Onboarding.Record(OnboardingPhaseAuth)
+	_, span = tracing.Start(ctx, "agent.kubeconfig", tracing.String(tracing.HostNameKey, hostName))
 	err = writeKubeconfigFromBootstrapping(bcsr.bootstrapClientConfig, bcsr.configPath, certData, bcsr.PrivateKey)
+	span.End(err)
 	if err != nil {
 		return err
 	}
+	bcsr.Onboarding.Record(OnboardingPhaseKubeconfig)
 	bcsr.logger.Info("kubeconfig created", "path", bcsr.configPath)
 	if err := os.Remove(TmpPrivateKey); err != nil && !os.IsNotExist(err) {
 		bcsr.logger.Error(err, "Failed cleaning up private key file")
@@ -97,6 +104,20 @@ func (bcsr *ByohCSR) BootstrapKubeconfig(hostName string) error {
 	return nil
 }
 
+// requestCertificate creates the CertificateSigningRequest of the host and waits for its certificate
+func (bcsr *ByohCSR) requestCertificate(ctx context.Context, hostName string) ([]byte, error) {
+	reqName, reqUID, err := bcsr.RequestBYOHClientCert(hostName)
+	if err != nil {
+		return nil, err
+	}
+	bcsr.logger.Info("CSR request created")
+	// wait for certificate to be issued
+	ctx, cancel := context.WithTimeout(ctx, CSRApprovalTimeout)
+	defer cancel()
+	bcsr.logger.Info("waiting for client certificate to be issued")
+	return csr.WaitForCertificate(ctx, bcsr.bootstrapClient, reqName, reqUID)
+}
+
 // RequestBYOHClientCert will generate Private Key and then will create a
 // CertificateSigningRequest in K8s
 func (bcsr *ByohCSR) RequestBYOHClientCert(hostname string) (string, types.UID, error) {
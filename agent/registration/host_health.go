@@ -0,0 +1,237 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultHealthCheckInterval is how often the host health conditions are refreshed
+	defaultHealthCheckInterval = time.Minute
+	// timeError is the clock state returned by adjtimex when the clock is not synchronized
+	timeError = 5
+)
+
+// criticalPaths are the paths whose filesystems are checked for disk pressure; the
+// root filesystem plus the ones kubelet, the container runtime and the logs live on
+var criticalPaths = []string{"/", "/var/lib", "/var/log"}
+
+// healthConditions are the conditions reported by the HostHealthChecker
+var healthConditions = []clusterv1.ConditionType{
+	infrastructurev1beta1.DiskSpaceAvailable,
+	infrastructurev1beta1.TimeSynchronized,
+	infrastructurev1beta1.AgentCertificateValid,
+	infrastructurev1beta1.AgentConnected,
+}
+
+// HostHealthChecker periodically reports the disk pressure, the clock synchronization
+// and the agent certificate expiry of the host as conditions of its ByoHost. Every report
+// is a heartbeat of the agent, recorded in the LastHeartbeatTime of the ByoHost.
+type HostHealthChecker struct {
+	K8sClient client.Client
+	HostName  string
+	Namespace string
+	// KubeconfigPath is the kubeconfig the agent authenticates with; it is re-read
+	// on every check so that rotated certificates are picked up
+	KubeconfigPath string
+	// Interval between two checks, defaults to one minute. It is shortened to a third of the
+	// HeartbeatTimeoutAnnotation of the host, so that a single missed check does not mark it disconnected.
+	Interval time.Duration
+	// Onboarding, if set, is reported in the OnboardingDurationsAnnotation by the first
+	// check of a host that does not have the OnboardingPhaseAgentHealthy duration yet
+	Onboarding *OnboardingTimer
+	// DropHeartbeat skips every check, as if the agent stopped reporting; it is
+	// set by the heartbeat-drop injected fault only
+	DropHeartbeat bool
+	// Batcher, if set, batches the health reports with the other status updates of the host
+	Batcher *StatusBatcher
+
+	// heartbeatTimeout is the HeartbeatTimeoutAnnotation of the host at the last check, in nanoseconds
+	heartbeatTimeout atomic.Int64
+}
+
+// Start implements manager.Runnable; it refreshes the health conditions until ctx is done
+func (hc *HostHealthChecker) Start(ctx context.Context) error {
+	for {
+		if hc.DropHeartbeat {
+			klog.Warningf("heartbeat-drop fault injected, not reporting the health of host %s", hc.HostName)
+		} else if err := hc.UpdateHealth(ctx); err != nil {
+			klog.Errorf("error updating health of host %s, err=%v", hc.HostName, err)
+		}
+		timer := time.NewTimer(hc.interval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// interval returns the interval until the next check
+func (hc *HostHealthChecker) interval() time.Duration {
+	interval := hc.Interval
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+	if timeout := time.Duration(hc.heartbeatTimeout.Load()); timeout > 0 && timeout/3 < interval {
+		interval = timeout / 3
+	}
+	return interval
+}
+
+// UpdateHealth sets the DiskSpaceAvailable, TimeSynchronized, AgentCertificateValid and AgentConnected
+// conditions and the heartbeat of the ByoHost, or queues them in the Batcher if it is set
+func (hc *HostHealthChecker) UpdateHealth(ctx context.Context) error {
+	if hc.Batcher != nil {
+		hc.Batcher.Enqueue(healthStatusSource, hc.setHealth, healthConditions...)
+		return nil
+	}
+	byoHost := &infrastructurev1beta1.ByoHost{}
+	if err := hc.K8sClient.Get(ctx, types.NamespacedName{Name: hc.HostName, Namespace: hc.Namespace}, byoHost); err != nil {
+		return err
+	}
+	if byoHost.IsPaused() {
+		hc.setHealth(byoHost)
+		klog.Infof("host %s is paused, not reporting its health: %s", hc.HostName, describeConditions(byoHost.Status.Conditions, healthConditions))
+		return nil
+	}
+	helper, err := patch.NewHelper(byoHost, hc.K8sClient)
+	if err != nil {
+		return err
+	}
+	hc.setHealth(byoHost)
+	return helper.Patch(ctx, byoHost, patch.WithOwnedConditions{Conditions: healthConditions})
+}
+
+func (hc *HostHealthChecker) setHealth(byoHost *infrastructurev1beta1.ByoHost) {
+	now := metav1.Now()
+	byoHost.Status.LastHeartbeatTime = &now
+	hc.heartbeatTimeout.Store(int64(byoHost.GetHeartbeatTimeout(0)))
+	conditions.MarkTrue(byoHost, infrastructurev1beta1.AgentConnected)
+	setDiskSpaceCondition(byoHost, getFreeSpacePercent, criticalPaths)
+	setTimeSynchronizedCondition(byoHost, isClockSynchronized)
+	if config, err := LoadRESTClientConfig(hc.KubeconfigPath); err == nil {
+		setAgentCertificateCondition(byoHost, config.CertData, time.Now())
+	} else {
+		klog.Errorf("error loading kubeconfig %s, err=%v", hc.KubeconfigPath, err)
+	}
+	if hc.Onboarding != nil && !HasOnboardingDuration(byoHost, OnboardingPhaseAgentHealthy) {
+		hc.Onboarding.Record(OnboardingPhaseAgentHealthy)
+		if err := SetOnboardingDurations(byoHost, hc.Onboarding.Durations()); err != nil {
+			klog.Errorf("error setting onboarding durations of host %s, err=%v", hc.HostName, err)
+		}
+	}
+}
+
+// setDiskSpaceCondition marks DiskSpaceAvailable false if the free space on the filesystem
+// of any of the paths is below the kubelet nodefs hard eviction threshold.
+// Paths that do not exist (yet) are skipped.
+func setDiskSpaceCondition(byoHost *infrastructurev1beta1.ByoHost, freeSpacePercent func(string) (int64, error), paths []string) {
+	var pressured []string
+	for _, path := range paths {
+		free, err := freeSpacePercent(path)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				klog.Errorf("error getting free space of %s, err=%v", path, err)
+			}
+			continue
+		}
+		if free < evictionHardNodefsAvailablePercent {
+			pressured = append(pressured, fmt.Sprintf("%s (%d%% free)", path, free))
+		}
+	}
+
+	if len(pressured) > 0 {
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.DiskSpaceAvailable, infrastructurev1beta1.DiskPressureReason,
+			clusterv1.ConditionSeverityWarning, "free space below %d%% on %s", evictionHardNodefsAvailablePercent, strings.Join(pressured, ", "))
+		return
+	}
+	conditions.MarkTrue(byoHost, infrastructurev1beta1.DiskSpaceAvailable)
+}
+
+// setTimeSynchronizedCondition marks TimeSynchronized according to the clock synchronization
+// status reported by the kernel, or unknown if it cannot be queried
+func setTimeSynchronizedCondition(byoHost *infrastructurev1beta1.ByoHost, clockSynchronized func() (bool, error)) {
+	synchronized, err := clockSynchronized()
+	switch {
+	case err != nil:
+		conditions.MarkUnknown(byoHost, infrastructurev1beta1.TimeSynchronized, infrastructurev1beta1.TimeSyncStatusUnknownReason,
+			"failed to query the clock synchronization status: %v", err)
+	case !synchronized:
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.TimeSynchronized, infrastructurev1beta1.TimeNotSynchronizedReason,
+			clusterv1.ConditionSeverityWarning, "the system clock is not synchronized")
+	default:
+		conditions.MarkTrue(byoHost, infrastructurev1beta1.TimeSynchronized)
+	}
+}
+
+// setAgentCertificateCondition marks AgentCertificateValid false once less than 20% of the
+// lifetime of the agent certificate is left, the point from which the agent rotates it
+// when certificate rotation is enabled.
+// Nothing is reported if the agent does not authenticate with a client certificate.
+func setAgentCertificateCondition(byoHost *infrastructurev1beta1.ByoHost, certData []byte, now time.Time) {
+	block, _ := pem.Decode(certData)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		klog.Errorf("error parsing agent certificate, err=%v", err)
+		return
+	}
+
+	expiry := cert.NotAfter.UTC().Format(time.RFC3339)
+	switch {
+	case now.After(cert.NotAfter):
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.AgentCertificateValid, infrastructurev1beta1.AgentCertificateExpiredReason,
+			clusterv1.ConditionSeverityError, "the agent certificate expired at %s", expiry)
+	case now.After(cert.NotAfter.Add(cert.NotAfter.Sub(cert.NotBefore) / -5)):
+		conditions.MarkFalse(byoHost, infrastructurev1beta1.AgentCertificateValid, infrastructurev1beta1.AgentCertificateExpiringReason,
+			clusterv1.ConditionSeverityWarning, "the agent certificate expires at %s", expiry)
+	default:
+		conditions.MarkTrue(byoHost, infrastructurev1beta1.AgentCertificateValid)
+	}
+}
+
+// getFreeSpacePercent gets the percentage of the filesystem of path available to unprivileged users
+func getFreeSpacePercent(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	if stat.Blocks == 0 {
+		return 100, nil //nolint: mnd
+	}
+	return int64(stat.Bavail * 100 / stat.Blocks), nil //nolint: gosec, mnd
+}
+
+// isClockSynchronized reports whether the kernel considers the system clock synchronized,
+// the same status timedatectl reports as "System clock synchronized"
+func isClockSynchronized() (bool, error) {
+	state, err := syscall.Adjtimex(&syscall.Timex{})
+	if err != nil {
+		return false, err
+	}
+	return state != timeError, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func getCertificate(notBefore, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ShouldNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "byoh:host:test-host"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ShouldNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+var _ = Describe("Host Health Tests", func() {
+	var byoHost *infrastructurev1beta1.ByoHost
+
+	BeforeEach(func() {
+		byoHost = &infrastructurev1beta1.ByoHost{}
+	})
+
+	Context("When the disk space is checked", func() {
+		It("Should mark DiskSpaceAvailable true if all paths have enough free space", func() {
+			setDiskSpaceCondition(byoHost, func(string) (int64, error) { return 50, nil }, []string{"/", "/var/lib"})
+			Expect(conditions.IsTrue(byoHost, infrastructurev1beta1.DiskSpaceAvailable)).To(BeTrue())
+		})
+
+		It("Should report the paths under disk pressure", func() {
+			freeSpace := map[string]int64{"/": 50, "/var/lib": 4, "/var/log": 9}
+			setDiskSpaceCondition(byoHost, func(path string) (int64, error) { return freeSpace[path], nil }, []string{"/", "/var/lib", "/var/log"})
+			Expect(*conditions.Get(byoHost, infrastructurev1beta1.DiskSpaceAvailable)).To(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrastructurev1beta1.DiskSpaceAvailable,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrastructurev1beta1.DiskPressureReason,
+				Severity: clusterv1.ConditionSeverityWarning,
+				Message:  "free space below 10% on /var/lib (4% free), /var/log (9% free)",
+			}))
+		})
+
+		It("Should skip paths that do not exist", func() {
+			setDiskSpaceCondition(byoHost, func(path string) (int64, error) {
+				if path == "/var/log" {
+					return 0, os.ErrNotExist
+				}
+				return 50, nil
+			}, []string{"/", "/var/log"})
+			Expect(conditions.IsTrue(byoHost, infrastructurev1beta1.DiskSpaceAvailable)).To(BeTrue())
+		})
+
+		It("Should return the free space of an existing path", func() {
+			free, err := getFreeSpacePercent("/")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(free).To(BeNumerically(">=", 0))
+			Expect(free).To(BeNumerically("<=", 100))
+		})
+
+		It("Should return a not exist error for a missing path", func() {
+			_, err := getFreeSpacePercent("/non-existent-path")
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+
+	Context("When the clock synchronization is checked", func() {
+		It("Should mark TimeSynchronized true if the clock is synchronized", func() {
+			setTimeSynchronizedCondition(byoHost, func() (bool, error) { return true, nil })
+			Expect(conditions.IsTrue(byoHost, infrastructurev1beta1.TimeSynchronized)).To(BeTrue())
+		})
+
+		It("Should mark TimeSynchronized false if the clock is not synchronized", func() {
+			setTimeSynchronizedCondition(byoHost, func() (bool, error) { return false, nil })
+			Expect(*conditions.Get(byoHost, infrastructurev1beta1.TimeSynchronized)).To(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrastructurev1beta1.TimeSynchronized,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrastructurev1beta1.TimeNotSynchronizedReason,
+				Severity: clusterv1.ConditionSeverityWarning,
+				Message:  "the system clock is not synchronized",
+			}))
+		})
+
+		It("Should mark TimeSynchronized unknown if the status cannot be queried", func() {
+			setTimeSynchronizedCondition(byoHost, func() (bool, error) { return false, errors.New("operation not permitted") })
+			Expect(conditions.IsUnknown(byoHost, infrastructurev1beta1.TimeSynchronized)).To(BeTrue())
+			Expect(conditions.GetReason(byoHost, infrastructurev1beta1.TimeSynchronized)).To(Equal(infrastructurev1beta1.TimeSyncStatusUnknownReason))
+		})
+	})
+
+	Context("When the agent certificate is checked", func() {
+		now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		It("Should mark AgentCertificateValid true if the certificate is far from its expiry", func() {
+			setAgentCertificateCondition(byoHost, getCertificate(now.Add(-24*time.Hour), now.Add(72*time.Hour)), now)
+			Expect(conditions.IsTrue(byoHost, infrastructurev1beta1.AgentCertificateValid)).To(BeTrue())
+		})
+
+		It("Should mark AgentCertificateValid false if less than 20% of the lifetime is left", func() {
+			setAgentCertificateCondition(byoHost, getCertificate(now.Add(-90*time.Hour), now.Add(10*time.Hour)), now)
+			Expect(*conditions.Get(byoHost, infrastructurev1beta1.AgentCertificateValid)).To(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrastructurev1beta1.AgentCertificateValid,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrastructurev1beta1.AgentCertificateExpiringReason,
+				Severity: clusterv1.ConditionSeverityWarning,
+				Message:  "the agent certificate expires at 2026-06-01T10:00:00Z",
+			}))
+		})
+
+		It("Should mark AgentCertificateValid false with severity error if the certificate expired", func() {
+			setAgentCertificateCondition(byoHost, getCertificate(now.Add(-48*time.Hour), now.Add(-time.Hour)), now)
+			Expect(conditions.GetReason(byoHost, infrastructurev1beta1.AgentCertificateValid)).To(Equal(infrastructurev1beta1.AgentCertificateExpiredReason))
+			Expect(*conditions.GetSeverity(byoHost, infrastructurev1beta1.AgentCertificateValid)).To(Equal(clusterv1.ConditionSeverityError))
+		})
+
+		It("Should not report anything without a client certificate", func() {
+			setAgentCertificateCondition(byoHost, nil, now)
+			Expect(conditions.Has(byoHost, infrastructurev1beta1.AgentCertificateValid)).To(BeFalse())
+		})
+	})
+
+	Context("When the health is reported", func() {
+		It("Should record the heartbeat and mark AgentConnected true", func() {
+			conditions.MarkFalse(byoHost, infrastructurev1beta1.AgentConnected, infrastructurev1beta1.AgentHeartbeatStaleReason,
+				clusterv1.ConditionSeverityWarning, "")
+			before := time.Now().Truncate(time.Second)
+			hc := &HostHealthChecker{KubeconfigPath: "/nonexistent/kubeconfig"}
+			hc.setHealth(byoHost)
+			Expect(byoHost.Status.LastHeartbeatTime).NotTo(BeNil())
+			Expect(byoHost.Status.LastHeartbeatTime.Time).NotTo(BeTemporally("<", before))
+			Expect(conditions.IsTrue(byoHost, infrastructurev1beta1.AgentConnected)).To(BeTrue())
+		})
+
+		It("Should report at least three times per heartbeat timeout of the host", func() {
+			hc := &HostHealthChecker{KubeconfigPath: "/nonexistent/kubeconfig"}
+			Expect(hc.interval()).To(Equal(defaultHealthCheckInterval))
+
+			byoHost.Annotations = map[string]string{infrastructurev1beta1.HeartbeatTimeoutAnnotation: "90s"}
+			hc.setHealth(byoHost)
+			Expect(hc.interval()).To(Equal(30 * time.Second))
+
+			byoHost.Annotations = map[string]string{infrastructurev1beta1.HeartbeatTimeoutAnnotation: "1h"}
+			hc.setHealth(byoHost)
+			Expect(hc.interval()).To(Equal(defaultHealthCheckInterval))
+		})
+	})
+})
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package registration
@@ -6,16 +7,20 @@ package registration
 import (
 	"context"
 	"fmt"
-	"net"
 	"os"
-	"regexp"
 	"runtime"
-	"strings"
+	"time"
 
 	"github.com/jackpal/gateway"
 	"github.com/pkg/errors"
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostfacts"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostoperation"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/nodetaint"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	klog "k8s.io/klog/v2"
@@ -23,9 +28,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var (
-	// LocalHostRegistrar is a HostRegistrar that registers the local host.
-	LocalHostRegistrar *HostRegistrar
+// LocalHostRegistrar is a HostRegistrar that registers the local host.
+var LocalHostRegistrar *HostRegistrar
+
+const (
+	// defaultMaxPods is the kubelet default for the maximum number of pods per node
+	defaultMaxPods = 110
+	// evictionHardMemoryAvailable is the kubelet default hard eviction threshold for memory
+	evictionHardMemoryAvailable = "100Mi"
+	// evictionHardNodefsAvailablePercent is the kubelet default hard eviction threshold for nodefs
+	evictionHardNodefsAvailablePercent = 10
 )
 
 // HostInfo contains information about the host network interface.
@@ -37,6 +49,11 @@ type HostInfo struct {
 type HostRegistrar struct {
 	K8sClient   client.Client
 	ByoHostInfo HostInfo
+	// AgentVersion is the version of the running agent, reported in the ByoHost status
+	AgentVersion string
+	// NodeTaints are the taints of the --taint flags of the agent, set on the ByoHost so that they
+	// are applied to the Node of the host
+	NodeTaints []corev1.Taint
 }
 
 // Register is called on agent startup
@@ -62,20 +79,68 @@ func (hr *HostRegistrar) Register(hostName, namespace string, hostLabels map[str
 				Namespace: namespace,
 				Labels:    hostLabels,
 			},
-			Spec:   infrastructurev1beta1.ByoHostSpec{},
+			Spec:   infrastructurev1beta1.ByoHostSpec{NodeTaints: hr.NodeTaints},
 			Status: infrastructurev1beta1.ByoHostStatus{},
 		}
+		onboardStart := time.Now()
 		err = hr.K8sClient.Create(ctx, byoHost)
+		hr.recordOnboard(ctx, hostName, namespace, onboardStart, err)
 		if err != nil {
 			klog.Errorf("error creating host %s in namespace %s, err=%v", hostName, namespace, err)
 			return err
 		}
+	} else if err := hr.updateLabelsAndTaints(ctx, byoHost, hostLabels); err != nil {
+		klog.Errorf("error updating the labels and taints of host %s in namespace %s, err=%v", hostName, namespace, err)
+		return err
 	}
 
 	// run it at startup or reboot
 	return hr.UpdateHost(ctx, byoHost)
 }
 
+// updateLabelsAndTaints sets hostLabels and the node taints of the agent on the existing byoHost,
+// e.g. those of byohctl onboard --label and --taint when the host is onboarded again. They replace
+// the labels of the same key and the taints of the same key and effect, and keep the others.
+func (hr *HostRegistrar) updateLabelsAndTaints(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost, hostLabels map[string]string) error {
+	nodeTaints := nodetaint.Merge(byoHost.Spec.NodeTaints, hr.NodeTaints)
+	changed := !equality.Semantic.DeepEqual(nodeTaints, byoHost.Spec.NodeTaints)
+	for key, value := range hostLabels {
+		if current, ok := byoHost.Labels[key]; !ok || current != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	helper, err := patch.NewHelper(byoHost, hr.K8sClient)
+	if err != nil {
+		return err
+	}
+	if byoHost.Labels == nil {
+		byoHost.Labels = map[string]string{}
+	}
+	for key, value := range hostLabels {
+		byoHost.Labels[key] = value
+	}
+	byoHost.Spec.NodeTaints = nodeTaints
+	return helper.Patch(ctx, byoHost)
+}
+
+// recordOnboard records the creation of the ByoHost as its Onboard ByoHostOperation, initiated by
+// the identity the agent authenticates with. A failure to record it is only logged.
+func (hr *HostRegistrar) recordOnboard(ctx context.Context, hostName, namespace string, start time.Time, onboardErr error) {
+	err := hostoperation.Record(ctx, hr.K8sClient, hostoperation.Operation{
+		Namespace: namespace,
+		HostName:  hostName,
+		Type:      infrastructurev1beta1.ByoHostOperationOnboard,
+		Initiator: fmt.Sprintf(ByohCSRCNFormat, hostName),
+		StartTime: start,
+	}, onboardErr)
+	if err != nil {
+		klog.Errorf("error recording the onboarding of host %s in namespace %s, err=%v", hostName, namespace, err)
+	}
+}
+
 // UpdateHost updates the network interface and host platform details status for the host
 func (hr *HostRegistrar) UpdateHost(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
 	klog.Info("Add Network Info")
@@ -85,12 +150,19 @@ func (hr *HostRegistrar) UpdateHost(ctx context.Context, byoHost *infrastructure
 	}
 
 	byoHost.Status.Network = hr.GetNetworkStatus()
+	byoHost.Status.AgentVersion = hr.AgentVersion
 
 	klog.Info("Attach Host Platform details")
 	if byoHost.Status.HostDetails, err = hr.getHostInfo(); err != nil {
 		return err
 	}
 
+	klog.Info("Attach Host capacity and allocatable resources")
+	if byoHost.Status.Capacity, err = getHostCapacity(os.ReadFile, hostfacts.EphemeralStoragePath); err != nil {
+		return err
+	}
+	byoHost.Status.Allocatable = getHostAllocatable(byoHost.Status.Capacity)
+
 	return helper.Patch(ctx, byoHost)
 }
 
@@ -102,41 +174,33 @@ func (hr *HostRegistrar) GetNetworkStatus() []infrastructurev1beta1.NetworkStatu
 	if err != nil {
 		return Network
 	}
+	route := hostfacts.DefaultRoute{IP: defaultIP}
+	if gatewayIP, err := gateway.DiscoverGateway(); err == nil {
+		route.Gateway = gatewayIP.String()
+	}
 
-	ifaces, err := net.Interfaces()
+	ifaces, err := hostfacts.Interfaces(os.ReadFile, route)
 	if err != nil {
 		return Network
 	}
 
 	for _, iface := range ifaces {
-		netStatus := infrastructurev1beta1.NetworkStatus{}
-
-		if iface.Flags&net.FlagUp > 0 {
-			netStatus.Connected = true
-		}
-
-		netStatus.MACAddr = iface.HardwareAddr.String()
-		addrs, err := iface.Addrs()
-		if err != nil {
-			continue
-		}
-
-		netStatus.NetworkInterfaceName = iface.Name
-		for _, addr := range addrs {
-			var ip net.IP
-			switch v := addr.(type) {
-			case *net.IPNet:
-				ip = v.IP
-			case *net.IPAddr:
-				ip = v.IP
-			}
-			if ip.String() == defaultIP.String() {
-				netStatus.IsDefault = true
-				hr.ByoHostInfo.DefaultNetworkInterfaceName = netStatus.NetworkInterfaceName
-			}
-			netStatus.IPAddrs = append(netStatus.IPAddrs, addr.String())
+		if iface.IsDefault {
+			hr.ByoHostInfo.DefaultNetworkInterfaceName = iface.Name
 		}
-		Network = append(Network, netStatus)
+		Network = append(Network, infrastructurev1beta1.NetworkStatus{
+			Connected:            iface.Connected,
+			IPAddrs:              iface.IPAddrs,
+			MACAddr:              iface.MACAddr,
+			NetworkInterfaceName: iface.Name,
+			IsDefault:            iface.IsDefault,
+			MTU:                  iface.MTU,
+			Gateway:              iface.Gateway,
+			LinkType:             infrastructurev1beta1.NetworkLinkType(iface.LinkType),
+			BondMembers:          iface.BondMembers,
+			VLANID:               iface.VLANID,
+			ParentInterfaceName:  iface.ParentInterfaceName,
+		})
 	}
 	return Network
 }
@@ -145,10 +209,16 @@ func (hr *HostRegistrar) GetNetworkStatus() []infrastructurev1beta1.NetworkStatu
 func (hr *HostRegistrar) getHostInfo() (infrastructurev1beta1.HostInfo, error) {
 	hostInfo := infrastructurev1beta1.HostInfo{}
 
-	hostInfo.Architecture = runtime.GOARCH
+	// the agent binary may be emulated on a host of another architecture, the bundle installed
+	// on the host must match the host
+	arch, err := hostfacts.Architecture(hostfacts.UnameMachine)
+	if err != nil {
+		klog.Errorf("error getting the machine name of the host, err=%v", err)
+	}
+	hostInfo.Architecture = arch
 	hostInfo.OSName = runtime.GOOS
 
-	if distribution, err := getOperatingSystem(os.ReadFile); err != nil {
+	if distribution, err := hostfacts.OSImage(os.ReadFile); err != nil {
 		return hostInfo, errors.Wrap(err, "failed to get host operating system image")
 	} else {
 		hostInfo.OSImage = distribution
@@ -156,21 +226,42 @@ func (hr *HostRegistrar) getHostInfo() (infrastructurev1beta1.HostInfo, error) {
 	return hostInfo, nil
 }
 
-// getOperatingSystem gets the name of the current operating system image.
-func getOperatingSystem(f func(string) ([]byte, error)) (string, error) {
-	rex := regexp.MustCompile("(PRETTY_NAME)=(.*)")
-
-	bytes, err := f("/etc/os-release")
-	if err != nil && os.IsNotExist(err) {
-		// /usr/lib/os-release in stateless systems like Clear Linux
-		bytes, err = f("/usr/lib/os-release")
+// getHostCapacity gets the cpu, memory, ephemeral-storage and pods capacity of the host.
+func getHostCapacity(f func(string) ([]byte, error), storagePath string) (corev1.ResourceList, error) {
+	memory, err := hostfacts.MemoryTotal(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get host memory capacity")
 	}
+
+	storage, err := hostfacts.StorageCapacity(storagePath)
 	if err != nil {
-		return "", fmt.Errorf("error opening file : %v", err)
+		return nil, err
+	}
+
+	return corev1.ResourceList{
+		corev1.ResourceCPU:              *resource.NewQuantity(int64(runtime.NumCPU()), resource.DecimalSI),
+		corev1.ResourceMemory:           *resource.NewQuantity(memory, resource.BinarySI),
+		corev1.ResourceEphemeralStorage: *resource.NewQuantity(storage, resource.BinarySI),
+		corev1.ResourcePods:             *resource.NewQuantity(defaultMaxPods, resource.DecimalSI),
+	}, nil
+}
+
+// getHostAllocatable derives the allocatable resources of the host from its capacity
+// by subtracting the default kubelet hard eviction thresholds.
+func getHostAllocatable(capacity corev1.ResourceList) corev1.ResourceList {
+	allocatable := capacity.DeepCopy()
+
+	if memory, ok := allocatable[corev1.ResourceMemory]; ok {
+		memory.Sub(resource.MustParse(evictionHardMemoryAvailable))
+		if memory.Sign() < 0 {
+			memory = *resource.NewQuantity(0, resource.BinarySI)
+		}
+		allocatable[corev1.ResourceMemory] = memory
 	}
-	line := rex.FindAllStringSubmatch(string(bytes), -1)
-	if len(line) > 0 {
-		return strings.Trim(line[0][2], "\""), nil
+
+	if storage, ok := allocatable[corev1.ResourceEphemeralStorage]; ok {
+		reserved := storage.Value() * evictionHardNodefsAvailablePercent / 100 //nolint: mnd
+		allocatable[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(storage.Value()-reserved, resource.BinarySI)
 	}
-	return "Unknown", nil
+	return allocatable
 }
@@ -1,73 +1,42 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package registration
 
 import (
-	"fmt"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-func getMockFile(targetOs string) ([]byte, error) {
-	out := fmt.Sprintf(`NAME="Ubuntu"
-VERSION="20.04.4 LTS (Focal Fossa)"
-ID=ubuntu
-ID_LIKE=debian
-PRETTY_NAME="%s"
-VERSION_ID="20.04"
-HOME_URL="https://www.ubuntu.com/"
-SUPPORT_URL="https://help.ubuntu.com/"
-BUG_REPORT_URL="https://bugs.launchpad.net/ubuntu/"
-PRIVACY_POLICY_URL="https://www.ubuntu.com/legal/terms-and-policies/privacy-policy"
-VERSION_CODENAME=focal
-UBUNTU_CODENAME=focal`, targetOs)
-	return []byte(out), nil
-}
-
 var _ = Describe("Host Registrar Tests", func() {
-	Context("When the OS is detected", func() {
-		It("Should return the operating system for os following /etc/os-release", func() {
-			targetOs := "Ubuntu 20.04.4 LTS"
-			detectedOS, err := getOperatingSystem(func(string) ([]byte, error) { return getMockFile(targetOs) })
-			Expect(err).ShouldNot(HaveOccurred())
-			Expect(detectedOS).To(Equal("Ubuntu 20.04.4 LTS"))
-		})
-
-		It("Should return the operating system for os following /usr/lib/os-release", func() {
-			targetOs := "Clear Linux Initramfs"
-			detectedOS, err := getOperatingSystem(func(releaseFile string) ([]byte, error) {
-				if releaseFile == "/etc/os-release" {
-					return nil, os.ErrNotExist
-				}
-				return getMockFile(targetOs)
-			})
-			Expect(err).ShouldNot(HaveOccurred())
-			Expect(detectedOS).To(Equal("Clear Linux Initramfs"))
-		})
-
-		It("Should not error with real hostnamectl", func() {
-			_, err := getOperatingSystem(os.ReadFile)
+	Context("When the host capacity is detected", func() {
+		It("Should report cpu, memory, ephemeral-storage and pods", func() {
+			capacity, err := getHostCapacity(func(string) ([]byte, error) { return []byte("MemTotal: 2048 kB"), nil }, os.TempDir())
 			Expect(err).ShouldNot(HaveOccurred())
+			Expect(capacity).To(HaveKey(corev1.ResourceCPU))
+			Expect(capacity).To(HaveKey(corev1.ResourceEphemeralStorage))
+			Expect(capacity.Memory().Value()).To(Equal(int64(2048 * 1024)))
+			Expect(capacity.Pods().Value()).To(Equal(int64(defaultMaxPods)))
 		})
-	})
-
-	Context("When the os-release file is missing", func() {
-		It("Should return error", func() {
-			_, err := getOperatingSystem(func(string) ([]byte, error) {
-				return nil, os.ErrNotExist
-			})
-			Expect(err.Error()).To(Equal("error opening file : file does not exist"))
-		})
-	})
 
-	Context("When the os-release does not contain PRETTY_NAME", func() {
-		It("Should return Unknown as operating system", func() {
-			detectedOS, err := getOperatingSystem(func(string) ([]byte, error) { return []byte("some_file_without_PRETTY_NAME"), nil })
-			Expect(err).ShouldNot(HaveOccurred())
-			Expect(detectedOS).To(Equal("Unknown"))
+		It("Should subtract the kubelet eviction thresholds from allocatable", func() {
+			capacity := corev1.ResourceList{
+				corev1.ResourceCPU:              resource.MustParse("4"),
+				corev1.ResourceMemory:           resource.MustParse("1Gi"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+				corev1.ResourcePods:             resource.MustParse("110"),
+			}
+			allocatable := getHostAllocatable(capacity)
+			Expect(allocatable.Cpu().Equal(resource.MustParse("4"))).To(BeTrue())
+			Expect(allocatable.Memory().Equal(resource.MustParse("924Mi"))).To(BeTrue())
+			Expect(allocatable.StorageEphemeral().Equal(resource.MustParse("90Gi"))).To(BeTrue())
+			Expect(allocatable.Pods().Equal(resource.MustParse("110"))).To(BeTrue())
+			Expect(capacity.Memory().Equal(resource.MustParse("1Gi"))).To(BeTrue())
 		})
 	})
 })
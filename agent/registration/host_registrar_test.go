@@ -11,6 +11,8 @@ import (
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/registration"
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("Host Registrar Tests", func() {
@@ -35,5 +37,34 @@ var _ = Describe("Host Registrar Tests", func() {
 		It("Should update the host details on the byohost successfully", func() {
 			Expect(hr.UpdateHost(ctx, byoHost)).ToNot(HaveOccurred())
 		})
+
+		It("Should set the labels of the agent on the byohost and keep its other labels", func() {
+			byoHost.Labels = map[string]string{"gpu": "false", "rack": "r1"}
+			Expect(k8sClient.Update(ctx, byoHost)).Should(Succeed())
+
+			Expect(hr.Register(byoHost.Name, defaultNamespace, map[string]string{"gpu": "true", "zone": "dc1"})).Should(Succeed())
+
+			updatedByoHost := &infrastructurev1beta1.ByoHost{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).Should(Succeed())
+			Expect(updatedByoHost.Labels).To(Equal(map[string]string{"gpu": "true", "rack": "r1", "zone": "dc1"}))
+		})
+
+		It("Should set the taints of the agent on the byohost and keep its other taints", func() {
+			byoHost.Spec.NodeTaints = []corev1.Taint{
+				{Key: "dedicated", Value: "cpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectNoExecute},
+			}
+			Expect(k8sClient.Update(ctx, byoHost)).Should(Succeed())
+			hr.NodeTaints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+
+			Expect(hr.Register(byoHost.Name, defaultNamespace, nil)).Should(Succeed())
+
+			updatedByoHost := &infrastructurev1beta1.ByoHost{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).Should(Succeed())
+			Expect(updatedByoHost.Spec.NodeTaints).To(Equal([]corev1.Taint{
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectNoExecute},
+			}))
+		})
 	})
 })
@@ -0,0 +1,229 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultNetworkCheckInterval is how often the NetworkWatcher checks the addresses of the host by default
+	DefaultNetworkCheckInterval = 30 * time.Second
+	// kubeadmFlagsPath is the environment file kubeadm renders the flags of the kubelet into
+	kubeadmFlagsPath = "/var/lib/kubelet/kubeadm-flags.env"
+)
+
+// nodeIPFlagRegex matches the --node-ip flag the kubelet advertises the address of the node with
+var nodeIPFlagRegex = regexp.MustCompile(`--node-ip=([^\s"]+)`)
+
+// NetworkWatcher watches the IP addresses of the host, which DHCP renewals and interface flaps change,
+// and re-advertises them: it updates the network status of the ByoHost, records a NetworkChanged event
+// and, if the kubelet advertises the previous address of the default interface with --node-ip, moves
+// the Node to the new one. Without --node-ip the kubelet detects the new address of the Node itself.
+type NetworkWatcher struct {
+	K8sClient client.Client
+	Registrar *HostRegistrar
+	HostName  string
+	Namespace string
+	Recorder  record.EventRecorder
+	// Interval between two checks, defaults to DefaultNetworkCheckInterval
+	Interval time.Duration
+	// Batcher, if set, batches the network status updates with the other status updates of the host
+	Batcher *StatusBatcher
+	// KubeletFlagsPath is the environment file of the kubelet flags, defaults to the one kubeadm renders
+	KubeletFlagsPath string
+	// RestartKubelet restarts the kubelet once its flags changed, defaults to systemctl restart kubelet
+	RestartKubelet func(ctx context.Context) error
+	// NetworkStatus returns the network status of the host, defaults to the one of the Registrar
+	NetworkStatus func() []infrastructurev1beta1.NetworkStatus
+
+	last []infrastructurev1beta1.NetworkStatus
+}
+
+// Start implements manager.Runnable; it checks the addresses of the host until ctx is done
+func (nw *NetworkWatcher) Start(ctx context.Context) error {
+	interval := nw.Interval
+	if interval == 0 {
+		interval = DefaultNetworkCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := nw.Check(ctx); err != nil {
+			klog.Errorf("error re-advertising the network of host %s, err=%v", nw.HostName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check compares the addresses of the host with the ones of the previous check and re-advertises
+// them if they changed. The first check only records them, the registration reported them already.
+func (nw *NetworkWatcher) Check(ctx context.Context) error {
+	current := nw.networkStatus()
+	if nw.last == nil {
+		nw.last = current
+		return nil
+	}
+	changes := addressChanges(nw.last, current)
+	if len(changes) == 0 {
+		return nil
+	}
+	klog.Infof("addresses of host %s changed: %s", nw.HostName, strings.Join(changes, ", "))
+
+	byoHost := &infrastructurev1beta1.ByoHost{}
+	if err := nw.K8sClient.Get(ctx, types.NamespacedName{Name: nw.HostName, Namespace: nw.Namespace}, byoHost); err != nil {
+		return err
+	}
+	setNetwork := func(byoHost *infrastructurev1beta1.ByoHost) { byoHost.Status.Network = current }
+	if nw.Batcher != nil {
+		nw.Batcher.Enqueue(networkStatusSource, setNetwork)
+	} else {
+		helper, err := patch.NewHelper(byoHost, nw.K8sClient)
+		if err != nil {
+			return err
+		}
+		setNetwork(byoHost)
+		if err = helper.Patch(ctx, byoHost); err != nil {
+			return err
+		}
+	}
+	nw.Recorder.Eventf(byoHost, corev1.EventTypeNormal, "NetworkChanged", "addresses of the host changed: %s", strings.Join(changes, ", "))
+
+	previousIP, currentIP := defaultIP(nw.last), defaultIP(current)
+	nw.last = current
+	if previousIP == "" || currentIP == "" || previousIP == currentIP {
+		return nil
+	}
+	moved, err := nw.moveNodeIP(ctx, previousIP, currentIP)
+	if err != nil {
+		nw.Recorder.Eventf(byoHost, corev1.EventTypeWarning, "NodeAddressChangeFailed", "failed to advertise the node address %s: %v", currentIP, err)
+		return err
+	}
+	if moved {
+		nw.Recorder.Eventf(byoHost, corev1.EventTypeNormal, "NodeAddressChanged", "node address changed from %s to %s", previousIP, currentIP)
+	}
+	return nil
+}
+
+// moveNodeIP replaces the previous address of the --node-ip flag of the kubelet with the current one
+// and restarts the kubelet. It reports whether the flag was set to the previous address.
+func (nw *NetworkWatcher) moveNodeIP(ctx context.Context, previousIP, currentIP string) (bool, error) {
+	path := nw.KubeletFlagsPath
+	if path == "" {
+		path = kubeadmFlagsPath
+	}
+	flags, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// the host is not a node of a cluster
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	match := nodeIPFlagRegex.FindSubmatch(flags)
+	if match == nil || string(match[1]) != previousIP {
+		return false, nil
+	}
+	flags = nodeIPFlagRegex.ReplaceAll(flags, []byte("--node-ip="+currentIP))
+	if err = os.WriteFile(path, flags, 0644); err != nil { //nolint: gosec
+		return false, err
+	}
+	klog.Infof("moving the node address of host %s from %s to %s", nw.HostName, previousIP, currentIP)
+	restart := nw.RestartKubelet
+	if restart == nil {
+		restart = restartKubelet
+	}
+	return true, restart(ctx)
+}
+
+func (nw *NetworkWatcher) networkStatus() []infrastructurev1beta1.NetworkStatus {
+	if nw.NetworkStatus != nil {
+		return nw.NetworkStatus()
+	}
+	return nw.Registrar.GetNetworkStatus()
+}
+
+// addressChanges describes the interfaces whose addresses differ between previous and current,
+// e.g. "eth0: 10.0.0.5/24 -> 10.0.0.7/24", sorted by interface
+func addressChanges(previous, current []infrastructurev1beta1.NetworkStatus) []string {
+	before, after := interfaceAddresses(previous), interfaceAddresses(current)
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []string
+	for _, name := range names {
+		if before[name] != after[name] {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", name, orNone(before[name]), orNone(after[name])))
+		}
+	}
+	return changes
+}
+
+// interfaceAddresses returns the sorted addresses of every interface, by interface name
+func interfaceAddresses(network []infrastructurev1beta1.NetworkStatus) map[string]string {
+	addresses := make(map[string]string, len(network))
+	for _, iface := range network {
+		addrs := append([]string(nil), iface.IPAddrs...)
+		sort.Strings(addrs)
+		addresses[iface.NetworkInterfaceName] = strings.Join(addrs, ",")
+	}
+	return addresses
+}
+
+// defaultIP returns the first address of the default interface, without its prefix length
+func defaultIP(network []infrastructurev1beta1.NetworkStatus) string {
+	for _, iface := range network {
+		if !iface.IsDefault || len(iface.IPAddrs) == 0 {
+			continue
+		}
+		if ip, _, err := net.ParseCIDR(iface.IPAddrs[0]); err == nil {
+			return ip.String()
+		}
+		return iface.IPAddrs[0]
+	}
+	return ""
+}
+
+func orNone(addresses string) string {
+	if addresses == "" {
+		return "none"
+	}
+	return addresses
+}
+
+func restartKubelet(ctx context.Context) error {
+	if output, err := (hostexec.Exec{}).CombinedOutput(ctx, "systemctl", "restart", "kubelet"); err != nil {
+		return fmt.Errorf("failed to restart the kubelet: %v: %s", err, output)
+	}
+	return nil
+}
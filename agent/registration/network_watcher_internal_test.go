@@ -0,0 +1,94 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+)
+
+var _ = Describe("Network Watcher Tests", func() {
+	previous := []infrastructurev1beta1.NetworkStatus{
+		{NetworkInterfaceName: "eth0", IPAddrs: []string{"10.0.0.5/24"}, IsDefault: true},
+		{NetworkInterfaceName: "eth1", IPAddrs: []string{"192.168.1.2/24", "fe80::1/64"}},
+	}
+
+	Context("When the addresses of the host are compared", func() {
+		It("Should not report a change for the same addresses in another order", func() {
+			current := []infrastructurev1beta1.NetworkStatus{
+				{NetworkInterfaceName: "eth1", IPAddrs: []string{"fe80::1/64", "192.168.1.2/24"}},
+				{NetworkInterfaceName: "eth0", IPAddrs: []string{"10.0.0.5/24"}, IsDefault: true},
+			}
+			Expect(addressChanges(previous, current)).To(BeEmpty())
+		})
+
+		It("Should report the changed, added and removed interfaces", func() {
+			current := []infrastructurev1beta1.NetworkStatus{
+				{NetworkInterfaceName: "eth0", IPAddrs: []string{"10.0.0.7/24"}, IsDefault: true},
+				{NetworkInterfaceName: "eth2", IPAddrs: []string{"172.16.0.3/16"}},
+			}
+			Expect(addressChanges(previous, current)).To(Equal([]string{
+				"eth0: 10.0.0.5/24 -> 10.0.0.7/24",
+				"eth1: 192.168.1.2/24,fe80::1/64 -> none",
+				"eth2: none -> 172.16.0.3/16",
+			}))
+		})
+
+		It("Should return the address of the default interface without its prefix length", func() {
+			Expect(defaultIP(previous)).To(Equal("10.0.0.5"))
+			Expect(defaultIP(previous[1:])).To(BeEmpty())
+		})
+	})
+
+	Context("When the address of the default interface changes", func() {
+		var (
+			watcher   *NetworkWatcher
+			flagsPath string
+			restarted bool
+		)
+
+		BeforeEach(func() {
+			flagsPath = filepath.Join(GinkgoT().TempDir(), "kubeadm-flags.env")
+			restarted = false
+			watcher = &NetworkWatcher{
+				HostName:         "test-host",
+				KubeletFlagsPath: flagsPath,
+				RestartKubelet: func(context.Context) error {
+					restarted = true
+					return nil
+				},
+			}
+		})
+
+		It("Should move the node address of the kubelet to the new address", func() {
+			Expect(os.WriteFile(flagsPath, []byte(`KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///run/containerd/containerd.sock --node-ip=10.0.0.5"`), 0644)).To(Succeed())
+			moved, err := watcher.moveNodeIP(context.TODO(), "10.0.0.5", "10.0.0.7")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(moved).To(BeTrue())
+			Expect(restarted).To(BeTrue())
+			flags, err := os.ReadFile(flagsPath)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(string(flags)).To(Equal(`KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///run/containerd/containerd.sock --node-ip=10.0.0.7"`))
+		})
+
+		It("Should not restart the kubelet if it does not advertise the previous address", func() {
+			Expect(os.WriteFile(flagsPath, []byte(`KUBELET_KUBEADM_ARGS="--node-ip=192.168.1.2"`), 0644)).To(Succeed())
+			moved, err := watcher.moveNodeIP(context.TODO(), "10.0.0.5", "10.0.0.7")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(moved).To(BeFalse())
+			Expect(restarted).To(BeFalse())
+		})
+
+		It("Should do nothing if the host is not a node", func() {
+			moved, err := watcher.moveNodeIP(context.TODO(), "10.0.0.5", "10.0.0.7")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(moved).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,136 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+)
+
+const (
+	// OnboardingPhaseAuth is the time taken to get the agent client certificate issued
+	OnboardingPhaseAuth = "auth"
+	// OnboardingPhaseKubeconfig is the time taken to write the agent kubeconfig
+	OnboardingPhaseKubeconfig = "kubeconfig"
+	// OnboardingPhaseRegistration is the time taken to create or update the ByoHost
+	OnboardingPhaseRegistration = "registration"
+	// OnboardingPhaseAgentHealthy is the time taken until the agent first reports the health of the host
+	OnboardingPhaseAgentHealthy = "agentHealthy"
+	// OnboardingPhasePackageInstall is the time taken by the install script once the host is attached
+	OnboardingPhasePackageInstall = "packageInstall"
+	// OnboardingTotal is the time from the start of the agent until it is healthy
+	OnboardingTotal = "total"
+)
+
+// OnboardingTimer measures the phases of the onboarding of a host, each phase lasting from the
+// end of the previous one, or the start of the agent, until it is recorded.
+// A nil OnboardingTimer records nothing.
+type OnboardingTimer struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	start     time.Time
+	last      time.Time
+	starts    map[string]time.Time
+	durations map[string]time.Duration
+}
+
+// NewOnboardingTimer returns an OnboardingTimer started now
+func NewOnboardingTimer() *OnboardingTimer {
+	return newOnboardingTimer(time.Now)
+}
+
+func newOnboardingTimer(now func() time.Time) *OnboardingTimer {
+	start := now()
+	return &OnboardingTimer{
+		now:       now,
+		start:     start,
+		last:      start,
+		starts:    make(map[string]time.Time),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+// Record ends phase now. Recording a phase again, e.g. after a failed attempt to report it,
+// measures it again from the same start.
+func (t *OnboardingTimer) Record(phase string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, ok := t.starts[phase]
+	if !ok {
+		start = t.last
+		t.starts[phase] = start
+	}
+	t.last = t.now()
+	t.durations[phase] = t.last.Sub(start)
+}
+
+// Durations returns the recorded phases and the OnboardingTotal up to the last of them
+func (t *OnboardingTimer) Durations() map[string]time.Duration {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(t.durations)+1)
+	for phase, d := range t.durations {
+		durations[phase] = d
+	}
+	durations[OnboardingTotal] = t.last.Sub(t.start)
+	return durations
+}
+
+// HasOnboardingDuration reports whether the OnboardingDurationsAnnotation of byoHost has phase
+func HasOnboardingDuration(byoHost *infrastructurev1beta1.ByoHost, phase string) bool {
+	seconds, err := getOnboardingDurations(byoHost)
+	if err != nil {
+		return false
+	}
+	_, ok := seconds[phase]
+	return ok
+}
+
+// SetOnboardingDurations merges durations into the OnboardingDurationsAnnotation of byoHost,
+// replacing the phases already there
+func SetOnboardingDurations(byoHost *infrastructurev1beta1.ByoHost, durations map[string]time.Duration) error {
+	seconds, err := getOnboardingDurations(byoHost)
+	if err != nil {
+		// the annotation is only informational, a broken one is replaced
+		seconds = make(map[string]float64)
+	}
+	for phase, d := range durations {
+		seconds[phase] = math.Round(d.Seconds()*1000) / 1000 //nolint: mnd
+	}
+	// json sorts the keys of a map, the annotation only changes with the durations
+	value, err := json.Marshal(seconds)
+	if err != nil {
+		return err
+	}
+	if byoHost.Annotations == nil {
+		byoHost.Annotations = make(map[string]string)
+	}
+	byoHost.Annotations[infrastructurev1beta1.OnboardingDurationsAnnotation] = string(value)
+	return nil
+}
+
+func getOnboardingDurations(byoHost *infrastructurev1beta1.ByoHost) (map[string]float64, error) {
+	seconds := make(map[string]float64)
+	value, ok := byoHost.Annotations[infrastructurev1beta1.OnboardingDurationsAnnotation]
+	if !ok {
+		return seconds, nil
+	}
+	if err := json.Unmarshal([]byte(value), &seconds); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", infrastructurev1beta1.OnboardingDurationsAnnotation, err)
+	}
+	return seconds, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+)
+
+var _ = Describe("Onboarding Tests", func() {
+	Context("When the onboarding phases are recorded", func() {
+		var (
+			now   time.Time
+			timer *OnboardingTimer
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+			timer = newOnboardingTimer(func() time.Time { return now })
+		})
+
+		It("Should measure each phase from the end of the previous one", func() {
+			now = now.Add(30 * time.Second)
+			timer.Record(OnboardingPhaseAuth)
+			now = now.Add(time.Second)
+			timer.Record(OnboardingPhaseKubeconfig)
+			now = now.Add(2 * time.Second)
+			timer.Record(OnboardingPhaseRegistration)
+
+			Expect(timer.Durations()).To(Equal(map[string]time.Duration{
+				OnboardingPhaseAuth:         30 * time.Second,
+				OnboardingPhaseKubeconfig:   time.Second,
+				OnboardingPhaseRegistration: 2 * time.Second,
+				OnboardingTotal:             33 * time.Second,
+			}))
+		})
+
+		It("Should measure a phase recorded again from the same start", func() {
+			now = now.Add(time.Second)
+			timer.Record(OnboardingPhaseRegistration)
+			now = now.Add(time.Second)
+			timer.Record(OnboardingPhaseAgentHealthy)
+			now = now.Add(time.Minute)
+			timer.Record(OnboardingPhaseAgentHealthy)
+
+			durations := timer.Durations()
+			Expect(durations).To(HaveKeyWithValue(OnboardingPhaseAgentHealthy, time.Minute+time.Second))
+			Expect(durations).To(HaveKeyWithValue(OnboardingTotal, time.Minute+2*time.Second))
+		})
+
+		It("Should record nothing with a nil timer", func() {
+			var nilTimer *OnboardingTimer
+			nilTimer.Record(OnboardingPhaseAuth)
+			Expect(nilTimer.Durations()).To(BeNil())
+		})
+	})
+
+	Context("When the onboarding durations are set on the ByoHost", func() {
+		var byoHost *infrastructurev1beta1.ByoHost
+
+		BeforeEach(func() {
+			byoHost = &infrastructurev1beta1.ByoHost{}
+		})
+
+		It("Should set the durations in seconds", func() {
+			Expect(SetOnboardingDurations(byoHost, map[string]time.Duration{
+				OnboardingPhaseAuth:       12500 * time.Millisecond,
+				OnboardingPhaseKubeconfig: 1234567 * time.Microsecond,
+			})).To(Succeed())
+			Expect(byoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.OnboardingDurationsAnnotation, `{"auth":12.5,"kubeconfig":1.235}`))
+			Expect(HasOnboardingDuration(byoHost, OnboardingPhaseAuth)).To(BeTrue())
+			Expect(HasOnboardingDuration(byoHost, OnboardingPhasePackageInstall)).To(BeFalse())
+		})
+
+		It("Should merge the durations into the annotation", func() {
+			byoHost.Annotations = map[string]string{infrastructurev1beta1.OnboardingDurationsAnnotation: `{"auth":12.5,"total":20}`}
+			Expect(SetOnboardingDurations(byoHost, map[string]time.Duration{
+				OnboardingPhasePackageInstall: 3 * time.Minute,
+			})).To(Succeed())
+			Expect(byoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.OnboardingDurationsAnnotation, `{"auth":12.5,"packageInstall":180,"total":20}`))
+		})
+
+		It("Should replace an invalid annotation", func() {
+			byoHost.Annotations = map[string]string{infrastructurev1beta1.OnboardingDurationsAnnotation: "not-json"}
+			Expect(HasOnboardingDuration(byoHost, OnboardingPhaseAuth)).To(BeFalse())
+			Expect(SetOnboardingDurations(byoHost, map[string]time.Duration{
+				OnboardingPhaseAuth: time.Second,
+			})).To(Succeed())
+			Expect(byoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.OnboardingDurationsAnnotation, `{"auth":1}`))
+		})
+	})
+})
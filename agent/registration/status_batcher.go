@@ -0,0 +1,203 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultStatusUpdateInterval is the default minimum interval between two status patches of a StatusBatcher
+const DefaultStatusUpdateInterval = 10 * time.Second
+
+// The sources of the status mutations of the agent, a StatusBatcher keeps the last mutation of each
+const (
+	healthStatusSource  = "host-health"
+	networkStatusSource = "network"
+)
+
+// StatusMutation changes the status of the ByoHost of the agent
+type StatusMutation func(byoHost *infrastructurev1beta1.ByoHost)
+
+// StatusBatcher coalesces the status mutations of the ByoHost of the agent, e.g. network
+// changes and condition flaps, into a single patch written at most once every MinInterval,
+// to limit the writes of large fleets to the management cluster API server.
+// The mutations are applied in order to the latest ByoHost, so a condition that flaps
+// back to its status within an interval is not written at all. Only the last mutation of
+// each source is kept, so the queue does not grow while the API server cannot be reached.
+type StatusBatcher struct {
+	K8sClient client.Client
+	HostName  string
+	Namespace string
+	// MinInterval between two patches, defaults to DefaultStatusUpdateInterval
+	MinInterval time.Duration
+
+	mu sync.Mutex
+	// pending is the last mutation queued by each source, sources the order they were first queued in
+	pending    map[string]StatusMutation
+	sources    []string
+	conditions []clusterv1.ConditionType
+}
+
+// Enqueue queues mutation of source for the next patch, replacing the mutation source queued
+// before: the mutations compute the status they set, so only the last one of a source matters.
+// ownedConditions are the conditions it sets, which the patch then owns over concurrent changes.
+func (b *StatusBatcher) Enqueue(source string, mutation StatusMutation, ownedConditions ...clusterv1.ConditionType) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending == nil {
+		b.pending = map[string]StatusMutation{}
+	}
+	if _, ok := b.pending[source]; !ok {
+		b.sources = append(b.sources, source)
+	}
+	b.pending[source] = mutation
+	b.own(ownedConditions)
+}
+
+// own adds conditions to the conditions owned by the next patch, b.mu must be held
+func (b *StatusBatcher) own(conditions []clusterv1.ConditionType) {
+	for _, condition := range conditions {
+		if !containsCondition(b.conditions, condition) {
+			b.conditions = append(b.conditions, condition)
+		}
+	}
+}
+
+// Pending returns the number of mutations queued for the next patch
+func (b *StatusBatcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.sources)
+}
+
+// Flush applies the queued mutations to the ByoHost in a single patch. The mutations
+// are queued again if the patch fails, to be retried by the next flush, unless their
+// source queued a newer one meanwhile.
+func (b *StatusBatcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	sources, pending, owned := b.sources, b.pending, b.conditions
+	b.sources, b.pending, b.conditions = nil, nil, nil
+	b.mu.Unlock()
+	if len(sources) == 0 {
+		return nil
+	}
+
+	mutations := make([]StatusMutation, 0, len(sources))
+	for _, source := range sources {
+		mutations = append(mutations, pending[source])
+	}
+	err := b.patch(ctx, mutations, owned)
+	if err != nil {
+		b.mu.Lock()
+		for _, source := range b.sources {
+			if _, ok := pending[source]; !ok {
+				sources = append(sources, source)
+			}
+			pending[source] = b.pending[source]
+		}
+		b.sources, b.pending = sources, pending
+		b.own(owned)
+		b.mu.Unlock()
+	}
+	return err
+}
+
+func (b *StatusBatcher) patch(ctx context.Context, mutations []StatusMutation, owned []clusterv1.ConditionType) error {
+	byoHost := &infrastructurev1beta1.ByoHost{}
+	if err := b.K8sClient.Get(ctx, types.NamespacedName{Name: b.HostName, Namespace: b.Namespace}, byoHost); err != nil {
+		return err
+	}
+	helper, err := patch.NewHelper(byoHost, b.K8sClient)
+	if err != nil {
+		return err
+	}
+	before := append(clusterv1.Conditions(nil), byoHost.Status.Conditions...)
+	for _, mutate := range mutations {
+		mutate(byoHost)
+	}
+	if byoHost.IsPaused() {
+		// the mutations are dropped, the next health check reports the host once it is resumed
+		klog.Infof("host %s is paused, not updating its status: %s", b.HostName, describeConditions(byoHost.Status.Conditions, owned))
+		return nil
+	}
+	keepTransitionTimes(before, byoHost.Status.Conditions)
+	return helper.Patch(ctx, byoHost, patch.WithOwnedConditions{Conditions: owned})
+}
+
+// Start implements manager.Runnable; it flushes the queued mutations every MinInterval
+// until ctx is done, and a last time then
+func (b *StatusBatcher) Start(ctx context.Context) error {
+	interval := b.MinInterval
+	if interval == 0 {
+		interval = DefaultStatusUpdateInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the manager context is done, flush with a fresh one
+			flushCtx, cancel := context.WithTimeout(context.Background(), interval)
+			defer cancel()
+			if err := b.Flush(flushCtx); err != nil {
+				klog.Errorf("error updating status of host %s, err=%v", b.HostName, err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := b.Flush(ctx); err != nil {
+				klog.Errorf("error updating status of host %s, err=%v", b.HostName, err)
+			}
+		}
+	}
+}
+
+// keepTransitionTimes restores the last transition time of the conditions that are back to
+// what they were before the mutations, so that their flaps are not written
+func keepTransitionTimes(before, after clusterv1.Conditions) {
+	for i := range after {
+		for _, condition := range before {
+			if condition.Type == after[i].Type && condition.Status == after[i].Status && condition.Reason == after[i].Reason &&
+				condition.Severity == after[i].Severity && condition.Message == after[i].Message {
+				after[i].LastTransitionTime = condition.LastTransitionTime
+			}
+		}
+	}
+}
+
+// describeConditions describes the status of the conditions of types, e.g. "TimeSynchronized=False (TimeNotSynchronized)"
+func describeConditions(all clusterv1.Conditions, types []clusterv1.ConditionType) string {
+	descriptions := make([]string, 0, len(types))
+	for _, condition := range all {
+		if !containsCondition(types, condition.Type) {
+			continue
+		}
+		description := fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+		if condition.Reason != "" {
+			description += fmt.Sprintf(" (%s)", condition.Reason)
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func containsCondition(conditions []clusterv1.ConditionType, condition clusterv1.ConditionType) bool {
+	for _, c := range conditions {
+		if c == condition {
+			return true
+		}
+	}
+	return false
+}
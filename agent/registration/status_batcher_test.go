@@ -0,0 +1,153 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/registration"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// patchCountingClient counts the patches of the status of the ByoHosts
+type patchCountingClient struct {
+	client.Client
+	statusPatches int
+}
+
+func (c *patchCountingClient) Status() client.StatusWriter {
+	return &patchCountingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type patchCountingStatusWriter struct {
+	client.StatusWriter
+	client *patchCountingClient
+}
+
+func (w *patchCountingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	w.client.statusPatches++
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Status Batcher Tests", func() {
+	var (
+		byoHost          *infrastructurev1beta1.ByoHost
+		countingClient   *patchCountingClient
+		batcher          *registration.StatusBatcher
+		defaultNamespace = "default"
+		ctx              = context.TODO()
+	)
+
+	BeforeEach(func() {
+		byoHost = builder.ByoHost(defaultNamespace, "batched-host").Build()
+		Expect(k8sClient.Create(ctx, byoHost)).Should(Succeed())
+		countingClient = &patchCountingClient{Client: k8sClient}
+		batcher = &registration.StatusBatcher{K8sClient: countingClient, HostName: byoHost.Name, Namespace: defaultNamespace}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, byoHost)).ToNot(HaveOccurred())
+	})
+
+	It("Should write the queued mutations in a single patch", func() {
+		batcher.Enqueue("agent-version", func(byoHost *infrastructurev1beta1.ByoHost) {
+			byoHost.Status.AgentVersion = "v1.0.0"
+		})
+		batcher.Enqueue("time-sync", func(byoHost *infrastructurev1beta1.ByoHost) {
+			conditions.MarkFalse(byoHost, infrastructurev1beta1.TimeSynchronized, infrastructurev1beta1.TimeNotSynchronizedReason, clusterv1.ConditionSeverityWarning, "")
+		}, infrastructurev1beta1.TimeSynchronized)
+		Expect(batcher.Pending()).To(Equal(2))
+
+		Expect(batcher.Flush(ctx)).To(Succeed())
+		Expect(countingClient.statusPatches).To(Equal(1))
+		Expect(batcher.Pending()).To(Equal(0))
+
+		updatedByoHost := &infrastructurev1beta1.ByoHost{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).To(Succeed())
+		Expect(updatedByoHost.Status.AgentVersion).To(Equal("v1.0.0"))
+		Expect(conditions.IsFalse(updatedByoHost, infrastructurev1beta1.TimeSynchronized)).To(BeTrue())
+	})
+
+	It("Should not write a condition that flapped back within the interval", func() {
+		batcher.Enqueue("time-sync", func(byoHost *infrastructurev1beta1.ByoHost) {
+			conditions.MarkTrue(byoHost, infrastructurev1beta1.TimeSynchronized)
+		}, infrastructurev1beta1.TimeSynchronized)
+		Expect(batcher.Flush(ctx)).To(Succeed())
+		Expect(countingClient.statusPatches).To(Equal(1))
+
+		batcher.Enqueue("time-sync", func(byoHost *infrastructurev1beta1.ByoHost) {
+			conditions.MarkFalse(byoHost, infrastructurev1beta1.TimeSynchronized, infrastructurev1beta1.TimeNotSynchronizedReason, clusterv1.ConditionSeverityWarning, "")
+		}, infrastructurev1beta1.TimeSynchronized)
+		batcher.Enqueue("time-sync", func(byoHost *infrastructurev1beta1.ByoHost) {
+			conditions.MarkTrue(byoHost, infrastructurev1beta1.TimeSynchronized)
+		}, infrastructurev1beta1.TimeSynchronized)
+		Expect(batcher.Flush(ctx)).To(Succeed())
+		Expect(countingClient.statusPatches).To(Equal(1))
+	})
+
+	It("Should not write the status of a paused host", func() {
+		ph, err := patch.NewHelper(byoHost, k8sClient)
+		Expect(err).ShouldNot(HaveOccurred())
+		byoHost.Annotations = map[string]string{infrastructurev1beta1.HostPausedAnnotation: ""}
+		Expect(ph.Patch(ctx, byoHost)).Should(Succeed())
+
+		batcher.Enqueue("agent-version", func(byoHost *infrastructurev1beta1.ByoHost) {
+			byoHost.Status.AgentVersion = "v1.0.0"
+		})
+		Expect(batcher.Flush(ctx)).To(Succeed())
+		Expect(countingClient.statusPatches).To(Equal(0))
+		Expect(batcher.Pending()).To(Equal(0))
+
+		updatedByoHost := &infrastructurev1beta1.ByoHost{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).To(Succeed())
+		Expect(updatedByoHost.Status.AgentVersion).To(BeEmpty())
+	})
+
+	It("Should keep the mutations queued if the patch fails", func() {
+		batcher.HostName = "unknown-host"
+		batcher.Enqueue("agent-version", func(byoHost *infrastructurev1beta1.ByoHost) {
+			byoHost.Status.AgentVersion = "v1.0.0"
+		})
+		Expect(batcher.Flush(ctx)).NotTo(Succeed())
+		Expect(batcher.Pending()).To(Equal(1))
+	})
+
+	It("Should keep only the last mutation of each source across failed patches", func() {
+		batcher.HostName = "unknown-host"
+		applied := map[string]int{}
+		enqueueVersion := func(version string) {
+			batcher.Enqueue("agent-version", func(byoHost *infrastructurev1beta1.ByoHost) {
+				applied["agent-version"]++
+				byoHost.Status.AgentVersion = version
+			})
+		}
+		batcher.Enqueue("time-sync", func(byoHost *infrastructurev1beta1.ByoHost) {
+			applied["time-sync"]++
+			conditions.MarkTrue(byoHost, infrastructurev1beta1.TimeSynchronized)
+		}, infrastructurev1beta1.TimeSynchronized)
+		for _, version := range []string{"v1.0.0", "v1.0.1", "v1.0.2"} {
+			enqueueVersion(version)
+			Expect(batcher.Flush(ctx)).NotTo(Succeed())
+			Expect(batcher.Pending()).To(Equal(2))
+		}
+
+		batcher.HostName = byoHost.Name
+		Expect(batcher.Flush(ctx)).To(Succeed())
+		Expect(applied).To(Equal(map[string]int{"agent-version": 1, "time-sync": 1}))
+		Expect(countingClient.statusPatches).To(Equal(1))
+		Expect(batcher.Pending()).To(Equal(0))
+
+		updatedByoHost := &infrastructurev1beta1.ByoHost{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).To(Succeed())
+		Expect(updatedByoHost.Status.AgentVersion).To(Equal("v1.0.2"))
+		Expect(conditions.IsTrue(updatedByoHost, infrastructurev1beta1.TimeSynchronized)).To(BeTrue())
+	})
+})
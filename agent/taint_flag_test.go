@@ -0,0 +1,42 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// nolint: nolintlint,testpackage
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Taint flag for host agent", func() {
+
+	Context("When the taint flag is provided", func() {
+		var (
+			taints taintFlags
+		)
+		BeforeEach(func() {
+			taints = nil
+		})
+		It("Should accept the multiple taint flags with comma separated taints", func() {
+			Expect(taints.Set("dedicated=gpu:NoSchedule,maintenance:NoExecute")).NotTo(HaveOccurred())
+			Expect(taints.Set("zone=dc1:PreferNoSchedule")).NotTo(HaveOccurred())
+			Expect(taints).Should(Equal(taintFlags{
+				{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "maintenance", Effect: corev1.TaintEffectNoExecute},
+				{Key: "zone", Value: "dc1", Effect: corev1.TaintEffectPreferNoSchedule},
+			}))
+			Expect(taints.String()).To(Equal("dedicated=gpu:NoSchedule,maintenance:NoExecute,zone=dc1:PreferNoSchedule"))
+		})
+
+		It("Should accept an empty taint flag", func() {
+			Expect(taints.Set("")).NotTo(HaveOccurred())
+			Expect(taints).To(BeEmpty())
+		})
+
+		It("Should not accept a taint without an effect", func() {
+			Expect(taints.Set("dedicated=gpu")).To(MatchError(`invalid taint "dedicated=gpu", expected key=value:effect`))
+		})
+	})
+})
@@ -1,10 +1,13 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 const (
@@ -47,6 +50,18 @@ type BootstrapKubeconfigStatus struct {
 	// for starting the host registration process
 	// +optional
 	BootstrapKubeconfigData *string `json:"bootstrapKubeconfigData,omitempty"`
+
+	// TokenSecretRef is a reference to the bootstrap token secret the bootstrap kubeconfig authenticates with
+	// +optional
+	TokenSecretRef *corev1.ObjectReference `json:"tokenSecretRef,omitempty"`
+
+	// TokenExpiration is the time after which the bootstrap token can no longer be used to register hosts
+	// +optional
+	TokenExpiration *metav1.Time `json:"tokenExpiration,omitempty"`
+
+	// Conditions defines current service state of the BootstrapKubeconfig.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -61,6 +76,16 @@ type BootstrapKubeconfig struct {
 	Status BootstrapKubeconfigStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the conditions of BootstrapKubeconfig status
+func (bootstrapKubeconfig *BootstrapKubeconfig) GetConditions() clusterv1.Conditions {
+	return bootstrapKubeconfig.Status.Conditions
+}
+
+// SetConditions sets the conditions of BootstrapKubeconfig status
+func (bootstrapKubeconfig *BootstrapKubeconfig) SetConditions(conditions clusterv1.Conditions) {
+	bootstrapKubeconfig.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // BootstrapKubeconfigList contains a list of BootstrapKubeconfig
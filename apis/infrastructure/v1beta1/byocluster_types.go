@@ -1,9 +1,12 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -23,8 +26,67 @@ type ByoClusterSpec struct {
 
 	// BundleLookupBaseRegistry is the base Registry URL that is used for pulling byoh bundle images,
 	// if not set, the default will be set to https://quay.io/platform9
+	// Deprecated: use BundleRegistry instead. It is only honoured when BundleRegistry is not set.
 	// +optional
 	BundleLookupBaseRegistry string `json:"bundleLookupBaseRegistry,omitempty"`
+
+	// BundleRegistry is the registry host (and optional path) that byoh bundle images are pulled from,
+	// e.g. quay.io/platform9. It is propagated to the K8sInstallerConfigs generated for the cluster.
+	// +optional
+	BundleRegistry string `json:"bundleRegistry,omitempty"`
+
+	// HostDefaults overrides, for the hosts attached to the machines of the cluster, the defaults the
+	// controller manager is started with
+	// +optional
+	HostDefaults *ByoClusterHostDefaults `json:"hostDefaults,omitempty"`
+}
+
+// ByoClusterHostDefaults are the cluster-scoped defaults of the hosts attached to the machines of a
+// ByoCluster. Unset fields fall back to the flags of the controller manager.
+type ByoClusterHostDefaults struct {
+	// HeartbeatTimeout is how long the agent of a host may not report a heartbeat before the host is
+	// marked disconnected and no longer attached to the machines of the cluster. The agents of the
+	// attached hosts report their heartbeat at least three times per timeout.
+	// +optional
+	HeartbeatTimeout *metav1.Duration `json:"heartbeatTimeout,omitempty"`
+
+	// QuarantineThreshold is the number of consecutive machines of the cluster failing to bootstrap
+	// on a host after which the host is quarantined. Hosts are never quarantined if 0.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	QuarantineThreshold *int32 `json:"quarantineThreshold,omitempty"`
+
+	// NodeDrainTimeout is the NodeDrainTimeout of the machines of the cluster that do not set one,
+	// how long Cluster API drains the node of a machine before deleting it.
+	// +optional
+	NodeDrainTimeout *metav1.Duration `json:"nodeDrainTimeout,omitempty"`
+}
+
+// GetBundleRegistry returns the registry used for pulling byoh bundle images,
+// falling back to the deprecated BundleLookupBaseRegistry field
+func (spec *ByoClusterSpec) GetBundleRegistry() string {
+	if spec.BundleRegistry != "" {
+		return spec.BundleRegistry
+	}
+	return spec.BundleLookupBaseRegistry
+}
+
+// GetHeartbeatTimeout returns the heartbeat timeout of the hosts of the cluster, defaultTimeout if
+// the cluster does not set one
+func (spec *ByoClusterSpec) GetHeartbeatTimeout(defaultTimeout time.Duration) time.Duration {
+	if spec.HostDefaults == nil || spec.HostDefaults.HeartbeatTimeout == nil {
+		return defaultTimeout
+	}
+	return spec.HostDefaults.HeartbeatTimeout.Duration
+}
+
+// GetQuarantineThreshold returns the quarantine threshold of the hosts of the cluster,
+// defaultThreshold if the cluster does not set one
+func (spec *ByoClusterSpec) GetQuarantineThreshold(defaultThreshold int) int {
+	if spec.HostDefaults == nil || spec.HostDefaults.QuarantineThreshold == nil {
+		return defaultThreshold
+	}
+	return int(*spec.HostDefaults.QuarantineThreshold)
 }
 
 // ByoClusterStatus defines the observed state of ByoCluster
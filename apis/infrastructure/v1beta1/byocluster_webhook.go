@@ -0,0 +1,78 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var byoclusterlog = logf.Log.WithName("byocluster-resource")
+
+// DefaultAPIEndpointPort default port for the API endpoint
+const DefaultAPIEndpointPort int32 = 6443
+
+func (r *ByoCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-byocluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byoclusters,verbs=create;update,versions=v1beta1,name=mbyocluster.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ByoCluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ByoCluster) Default() {
+	byoclusterlog.Info("default", "name", r.Name)
+
+	if r.Spec.ControlPlaneEndpoint.Port == 0 {
+		r.Spec.ControlPlaneEndpoint.Port = DefaultAPIEndpointPort
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-byocluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byoclusters,verbs=create;update,versions=v1beta1,name=vbyocluster.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ByoCluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoCluster) ValidateCreate() error {
+	byoclusterlog.Info("validate create", "name", r.Name)
+
+	return r.validateBundleRegistry()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoCluster) ValidateUpdate(old runtime.Object) error {
+	byoclusterlog.Info("validate update", "name", r.Name)
+
+	return r.validateBundleRegistry()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoCluster) ValidateDelete() error {
+	byoclusterlog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (r *ByoCluster) validateBundleRegistry() error {
+	specPath := field.NewPath("spec")
+	if err := validateBundleRegistry(specPath.Child("bundleRegistry"), r.Spec.BundleRegistry); err != nil {
+		return err
+	}
+
+	// the deprecated field is only kept for backward compatibility, it must not
+	// point somewhere else than the typed field when both are set
+	if r.Spec.BundleRegistry != "" && r.Spec.BundleLookupBaseRegistry != "" &&
+		r.Spec.BundleLookupBaseRegistry != r.Spec.BundleRegistry {
+		return field.Invalid(specPath.Child("bundleLookupBaseRegistry"), r.Spec.BundleLookupBaseRegistry,
+			"bundleLookupBaseRegistry is deprecated and must match bundleRegistry when both are set")
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	byohv1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ByoCluster Webhook", func() {
+
+	var (
+		defaultNamespace   = "default"
+		testByoClusterName = "test-byocluster"
+		testBundleRegistry = "quay.io/platform9"
+	)
+
+	Context("When ByoCluster gets a create request", func() {
+
+		It("should reject the request if bundleRegistry has a URL scheme", func() {
+			byoCluster := builder.ByoCluster(defaultNamespace, testByoClusterName).
+				WithBundleRegistry("https://" + testBundleRegistry).
+				Build()
+			err := k8sClient.Create(ctx, byoCluster)
+			Expect(err).To(MatchError("admission webhook \"vbyocluster.kb.io\" denied the request: spec.bundleRegistry: Invalid value: \"https://" + testBundleRegistry + "\": bundleRegistry must not contain a URL scheme"))
+		})
+
+		It("should reject the request if the deprecated bundleLookupBaseRegistry conflicts with bundleRegistry", func() {
+			byoCluster := builder.ByoCluster(defaultNamespace, testByoClusterName).
+				WithBundleRegistry(testBundleRegistry).
+				WithBundleBaseRegistry("projects.registry.vmware.com").
+				Build()
+			err := k8sClient.Create(ctx, byoCluster)
+			Expect(err).To(MatchError("admission webhook \"vbyocluster.kb.io\" denied the request: spec.bundleLookupBaseRegistry: Invalid value: \"projects.registry.vmware.com\": bundleLookupBaseRegistry is deprecated and must match bundleRegistry when both are set"))
+		})
+
+		It("should accept the request if only the deprecated bundleLookupBaseRegistry is set", func() {
+			byoCluster := builder.ByoCluster(defaultNamespace, testByoClusterName).
+				WithBundleBaseRegistry("https://" + testBundleRegistry).
+				Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, byoCluster)).Should(Succeed())
+		})
+
+		It("should default the control plane endpoint port", func() {
+			byoCluster := builder.ByoCluster(defaultNamespace, testByoClusterName).Build()
+			byoCluster.Spec.ControlPlaneEndpoint.Host = "10.0.0.1"
+			Expect(k8sClient.Create(ctx, byoCluster)).Should(Succeed())
+
+			createdByoCluster := &byohv1beta1.ByoCluster{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoCluster), createdByoCluster)).Should(Succeed())
+			Expect(createdByoCluster.Spec.ControlPlaneEndpoint.Port).To(Equal(byohv1beta1.DefaultAPIEndpointPort))
+			Expect(k8sClient.Delete(ctx, byoCluster)).Should(Succeed())
+		})
+
+		It("should accept the request if bundleRegistry is valid", func() {
+			byoCluster := builder.ByoCluster(defaultNamespace, testByoClusterName).
+				WithBundleRegistry(testBundleRegistry).
+				WithBundleBaseRegistry(testBundleRegistry).
+				Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, byoCluster)).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,82 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/cluster-api/util/topology"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var byoclustertemplatelog = logf.Log.WithName("byoclustertemplate-resource")
+
+func (r *ByoClusterTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&byoClusterTemplateValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-byoclustertemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byoclustertemplates,verbs=create;update,versions=v1beta1,name=vbyoclustertemplate.kb.io,admissionReviewVersions=v1
+
+// byoClusterTemplateValidator validates ByoClusterTemplates. It is a CustomValidator
+// since the immutability check needs the admission request to detect topology dry-runs
+type byoClusterTemplateValidator struct{}
+
+var _ webhook.CustomValidator = &byoClusterTemplateValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoClusterTemplateValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoClusterTemplateValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldTemplate, ok := oldObj.(*ByoClusterTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoClusterTemplate but got a %T", oldObj))
+	}
+	newTemplate, ok := newObj.(*ByoClusterTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoClusterTemplate but got a %T", newObj))
+	}
+	byoclustertemplatelog.Info("validate update", "name", newTemplate.Name)
+
+	return validateTemplateImmutability(ctx, newTemplate, &oldTemplate.Spec.Template.Spec, &newTemplate.Spec.Template.Spec)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoClusterTemplateValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateTemplateImmutability rejects any change to spec.template.spec of a template, following
+// the Cluster API contract for infrastructure templates. Dry-run requests issued by the topology
+// controller are let through, since it uses them to compute ClusterClass rollouts
+func validateTemplateImmutability(ctx context.Context, obj metav1.Object, oldSpec, newSpec interface{}) error {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected an admission.Request inside context: %v", err))
+	}
+	if topology.ShouldSkipImmutabilityChecks(req, obj) {
+		return nil
+	}
+
+	if !reflect.DeepEqual(oldSpec, newSpec) {
+		return field.Forbidden(field.NewPath("spec", "template", "spec"),
+			fmt.Sprintf("%s spec.template.spec field is immutable, please create a new resource instead", req.Kind.Kind))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	byohv1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ByoClusterTemplate Webhook", func() {
+
+	var (
+		defaultNamespace   = "default"
+		byoClusterTemplate *byohv1beta1.ByoClusterTemplate
+	)
+
+	Context("When ByoClusterTemplate gets an update request", func() {
+
+		BeforeEach(func() {
+			byoClusterTemplate = &byohv1beta1.ByoClusterTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-byoclustertemplate-",
+					Namespace:    defaultNamespace,
+				},
+				Spec: byohv1beta1.ByoClusterTemplateSpec{
+					Template: byohv1beta1.ByoClusterTemplateResource{
+						Spec: byohv1beta1.ByoClusterSpec{BundleRegistry: "quay.io/platform9"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, byoClusterTemplate)).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, byoClusterTemplate)).Should(Succeed())
+		})
+
+		It("should reject the request if spec.template.spec is changed", func() {
+			ph, err := patch.NewHelper(byoClusterTemplate, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoClusterTemplate.Spec.Template.Spec.BundleRegistry = "docker.io/platform9"
+			err = ph.Patch(ctx, byoClusterTemplate)
+			Expect(err).To(MatchError("admission webhook \"vbyoclustertemplate.kb.io\" denied the request: spec.template.spec: Forbidden: ByoClusterTemplate spec.template.spec field is immutable, please create a new resource instead"))
+		})
+
+		It("should accept the request if only the metadata is changed", func() {
+			ph, err := patch.NewHelper(byoClusterTemplate, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoClusterTemplate.Labels = map[string]string{"site": "edge"}
+			Expect(ph.Patch(ctx, byoClusterTemplate)).Should(Succeed())
+		})
+
+		It("should accept a dry-run request from the topology controller", func() {
+			updatedTemplate := byoClusterTemplate.DeepCopy()
+			updatedTemplate.Annotations = map[string]string{clusterv1.TopologyDryRunAnnotation: ""}
+			updatedTemplate.Spec.Template.Spec.BundleRegistry = "docker.io/platform9"
+			Expect(k8sClient.Update(ctx, updatedTemplate, client.DryRunAll)).Should(Succeed())
+		})
+
+		It("should reject a dry-run request without the topology dry-run annotation", func() {
+			updatedTemplate := byoClusterTemplate.DeepCopy()
+			updatedTemplate.Spec.Template.Spec.BundleRegistry = "docker.io/platform9"
+			err := k8sClient.Update(ctx, updatedTemplate, client.DryRunAll)
+			Expect(err).To(MatchError("admission webhook \"vbyoclustertemplate.kb.io\" denied the request: spec.template.spec: Forbidden: ByoClusterTemplate spec.template.spec field is immutable, please create a new resource instead"))
+		})
+	})
+})
@@ -1,11 +1,15 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
@@ -19,11 +23,70 @@ const (
 	// AttachedByoMachineLabel label used to mark a node name attached to a byo host
 	AttachedByoMachineLabel = "byoh.infrastructure.cluster.x-k8s.io/byomachine-name"
 	// BundleLookupBaseRegistryAnnotation annotation used to store the base registry for the bundle lookup
+	// Deprecated: the registry is carried by K8sInstallerConfigSpec.BundleRegistry. The annotation is
+	// still set for agents that predate the typed field.
 	BundleLookupBaseRegistryAnnotation = "byoh.infrastructure.cluster.x-k8s.io/bundle-registry"
+	// CRISocketAnnotation annotation used to store the socket of the container runtime installed on the host
+	CRISocketAnnotation = "byoh.infrastructure.cluster.x-k8s.io/cri-socket"
+	// KubeletExtraArgsAnnotation annotation used to pass the JSON encoded KubeletExtraArgs of the
+	// attached ByoMachine to the host agent
+	KubeletExtraArgsAnnotation = "byoh.infrastructure.cluster.x-k8s.io/kubelet-extra-args"
+	// SkipUninstallAnnotation annotation set on a ByoMachine (or directly on its ByoHost) to release the host
+	// from the cluster without running kubeadm reset or the uninstall script, for hosts that are re-attached
+	// right away or whose node lifecycle is managed externally. It only applies to the next release.
+	SkipUninstallAnnotation = "byoh.infrastructure.cluster.x-k8s.io/skip-uninstall"
+	// InstalledK8sVersionAnnotation annotation set by the host agent to the k8s version of the components left
+	// installed on a host released because of the SkipUninstallAnnotation. On the next attach the agent resets
+	// the node and, when the new cluster runs the same version, reuses the components instead of installing them.
+	InstalledK8sVersionAnnotation = "byoh.infrastructure.cluster.x-k8s.io/installed-k8s-version"
+	// HeartbeatTimeoutAnnotation annotation set by the controller manager on an attached host to the heartbeat
+	// timeout of the ByoCluster of its machine. The controller manager marks the host disconnected after it
+	// and the host agent reports its heartbeat often enough for it.
+	HeartbeatTimeoutAnnotation = "byoh.infrastructure.cluster.x-k8s.io/heartbeat-timeout"
+	// ForceDeleteAnnotation annotation used to allow the deletion of a ByoHost whose MachineRef is still set,
+	// for hosts that are permanently gone and whose machine teardown can never complete. Only users allowed
+	// the ForceDeleteVerb on byohosts can set it.
+	ForceDeleteAnnotation = "byoh.infrastructure.cluster.x-k8s.io/force-delete"
+	// ForceDeleteVerb is the RBAC verb on byohosts required to set the ForceDeleteAnnotation
+	ForceDeleteVerb = "force-delete"
+	// OnboardingDurationsAnnotation annotation set by the host agent to the JSON encoded durations, in seconds,
+	// of the onboarding phases of the host, e.g. {"auth":12.5,"kubeconfig":0.01,"total":14.2}
+	OnboardingDurationsAnnotation = "byoh.infrastructure.cluster.x-k8s.io/onboarding-durations"
 	// ClusterLabel label is used to mark a cluster where it is attached to
 	ClusterLabel = "kaapi.pf9.io/cluster-name"
 	// ClusterLabelCP label is used to mark a control-plane host attached to a cluster
 	ClusterLabelCP = "kaapi.pf9.io/cluster-name-cp"
+	// HostPriorityLabel label used to rank hosts during selection. The value is an
+	// integer; hosts with a higher priority are preferred. Hosts without the label
+	// (or with a non-integer value) have priority 0.
+	HostPriorityLabel = "byoh.infrastructure.cluster.x-k8s.io/priority"
+	// HostZoneLabel label used to mark the zone a host is located in. Zones are reported as
+	// failure domains of the ByoCluster and applied to the Node as topology.kubernetes.io/zone.
+	HostZoneLabel = "byoh.infrastructure.cluster.x-k8s.io/zone"
+	// HostRoomLabel label used to mark the room, within its zone, a host is located in.
+	// It is applied to the Node as is.
+	HostRoomLabel = "byoh.infrastructure.cluster.x-k8s.io/room"
+	// HostRackLabel label used to mark the rack, within its zone, a host is located in.
+	// It is applied to the Node as is.
+	HostRackLabel = "byoh.infrastructure.cluster.x-k8s.io/rack"
+	// BootstrapFailuresAnnotation annotation set by the controller manager to the number of consecutive
+	// ByoMachines released from the host without it having bootstrapped their node
+	BootstrapFailuresAnnotation = "byoh.infrastructure.cluster.x-k8s.io/bootstrap-failures"
+	// QuarantinedLabel label set by the controller manager on a host that failed to bootstrap the nodes of
+	// too many consecutive ByoMachines. Quarantined hosts are not attached to ByoMachines until an operator
+	// removes the label.
+	QuarantinedLabel = "byoh.infrastructure.cluster.x-k8s.io/quarantined"
+	// HostPausedAnnotation annotation set by an operator to pause the reconciliation of a single host,
+	// e.g. during manual surgery. The agent and the controller manager then only read the host, they
+	// neither report its status nor attach it until the annotation is removed.
+	HostPausedAnnotation = "byoh.infrastructure.cluster.x-k8s.io/paused"
+	// ClaimedByAnnotation annotation set by the controller manager to the UID of the ByoMachine about to
+	// attach the host. The claim is written with an optimistic lock so that, of the ByoMachines reconciled
+	// concurrently that selected the same free host, only one attaches it.
+	ClaimedByAnnotation = "byoh.infrastructure.cluster.x-k8s.io/claimed-by"
+	// ClaimedAtAnnotation annotation set by the controller manager to the RFC3339 time of the claim of the
+	// ClaimedByAnnotation. A claim not followed by the attach of the host expires.
+	ClaimedAtAnnotation = "byoh.infrastructure.cluster.x-k8s.io/claimed-at"
 	// Max k8s label value length
 	MaxK8sLabelValueLength = 63
 	LabelHashLength        = 8 // Using 8 chars of SHA256 hex
@@ -49,6 +112,51 @@ type ByoHostSpec struct {
 	// +optional
 	// UninstallationScript *string `json:"uninstallationScript,omitempty"`
 	UninstallationSecret *corev1.ObjectReference `json:"uninstallationSecret,omitempty"`
+
+	// NodeLabels is an optional set of labels to apply to the Kubernetes node
+	// when this host joins a cluster
+	// +optional
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// NodeTaints is an optional list of taints to apply to the Kubernetes node
+	// when this host joins a cluster
+	// +optional
+	NodeTaints []corev1.Taint `json:"nodeTaints,omitempty"`
+
+	// Schedulable controls whether the host can be attached to a ByoMachine.
+	// Defaults to true.
+	// +kubebuilder:default=true
+	// +optional
+	Schedulable *bool `json:"schedulable,omitempty"`
+
+	// MaintenanceWindow is an optional period of time during which the host
+	// must not be attached to a ByoMachine
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// KubeletExtraArgs is an optional set of extra flags passed to the kubelet
+	// of this host, keyed by flag name without the leading dashes, e.g.
+	// max-pods or reserved-cpus. They take precedence over the KubeletExtraArgs
+	// of the ByoMachine the host is attached to.
+	// +optional
+	KubeletExtraArgs map[string]string `json:"kubeletExtraArgs,omitempty"`
+
+	// AgentLogVerbosity is an optional klog verbosity the host agent switches to at
+	// runtime, e.g. 4 to debug a single host. The agent restores the verbosity it
+	// was started with when it is unset.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	AgentLogVerbosity *int32 `json:"agentLogVerbosity,omitempty"`
+}
+
+// MaintenanceWindow defines a period of time during which a host is under maintenance
+type MaintenanceWindow struct {
+	// Start is the beginning of the maintenance window
+	Start metav1.Time `json:"start"`
+
+	// End is the end of the maintenance window
+	End metav1.Time `json:"end"`
 }
 
 // HostInfo is a set of details about the host platform.
@@ -82,6 +190,25 @@ type ByoHostStatus struct {
 	// network interfaces.
 	// +optional
 	Network []NetworkStatus `json:"network,omitempty"`
+
+	// Capacity represents the total resources of the host
+	// (cpu, memory, ephemeral-storage and pods).
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// Allocatable represents the resources of the host that are available
+	// for scheduling once it joins a cluster.
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// AgentVersion is the version of the host agent running on the host,
+	// reported by the agent on startup and refreshed on every reconcile.
+	// +optional
+	AgentVersion string `json:"agentVersion,omitempty"`
+
+	// LastHeartbeatTime is the last time the host agent reported the health of the host.
+	// +optional
+	LastHeartbeatTime *metav1.Time `json:"lastHeartbeatTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -90,6 +217,13 @@ type ByoHostStatus struct {
 //+kubebuilder:printcolumn:name="OSName",type="string",JSONPath=`.status.hostinfo.osname`
 //+kubebuilder:printcolumn:name="OSImage",type="string",JSONPath=`.status.hostinfo.osimage`
 //+kubebuilder:printcolumn:name="Arch",type="string",JSONPath=`.status.hostinfo.architecture`
+//+kubebuilder:printcolumn:name="Schedulable",type="boolean",JSONPath=`.spec.schedulable`
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=`.metadata.labels.cluster\.x-k8s\.io/cluster-name`,description="Cluster the host is attached to"
+//+kubebuilder:printcolumn:name="Machine",type="string",JSONPath=`.status.machineRef.name`,description="ByoMachine the host is attached to"
+//+kubebuilder:printcolumn:name="Version",type="string",JSONPath=`.metadata.annotations.byoh\.infrastructure\.cluster\.x-k8s\.io/k8sversion`,description="Kubernetes version installed on the host"
+//+kubebuilder:printcolumn:name="AgentVersion",type="string",JSONPath=`.status.agentVersion`,priority=1
+//+kubebuilder:printcolumn:name="LastHeartbeat",type="date",JSONPath=`.status.lastHeartbeatTime`,priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
 // ByoHost is the Schema for the byohosts API
 type ByoHost struct {
@@ -122,3 +256,53 @@ func (byoHost *ByoHost) GetConditions() clusterv1.Conditions {
 func (byoHost *ByoHost) SetConditions(conditions clusterv1.Conditions) {
 	byoHost.Status.Conditions = conditions
 }
+
+// IsSchedulable returns true if the host can be attached to a ByoMachine at the given time
+func (byoHost *ByoHost) IsSchedulable(now time.Time) bool {
+	if byoHost.Spec.Schedulable != nil && !*byoHost.Spec.Schedulable {
+		return false
+	}
+	if window := byoHost.Spec.MaintenanceWindow; window != nil {
+		if !now.Before(window.Start.Time) && now.Before(window.End.Time) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsHeartbeatStale returns true if the agent of the host has not reported a heartbeat for longer
+// than timeout at the given time. Hosts whose agent never reported one, e.g. older agents, are not stale.
+func (byoHost *ByoHost) IsHeartbeatStale(now time.Time, timeout time.Duration) bool {
+	heartbeat := byoHost.Status.LastHeartbeatTime
+	return heartbeat != nil && now.Sub(heartbeat.Time) > timeout
+}
+
+// GetHeartbeatTimeout returns the heartbeat timeout set on the host by the HeartbeatTimeoutAnnotation,
+// defaultTimeout if the annotation is not set or invalid
+func (byoHost *ByoHost) GetHeartbeatTimeout(defaultTimeout time.Duration) time.Duration {
+	timeout, err := time.ParseDuration(byoHost.Annotations[HeartbeatTimeoutAnnotation])
+	if err != nil || timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// IsPaused returns true if the reconciliation of the host is paused by the HostPausedAnnotation
+func (byoHost *ByoHost) IsPaused() bool {
+	_, paused := byoHost.Annotations[HostPausedAnnotation]
+	return paused
+}
+
+// IsClaimedByOther returns true if the host is claimed by a ByoMachine other than the one of uid and the
+// claim, at the given time, is younger than ttl. Claims without a valid claim time are expired.
+func (byoHost *ByoHost) IsClaimedByOther(uid types.UID, now time.Time, ttl time.Duration) bool {
+	claimedBy, ok := byoHost.Annotations[ClaimedByAnnotation]
+	if !ok || claimedBy == string(uid) {
+		return false
+	}
+	claimedAt, err := time.Parse(time.RFC3339, byoHost.Annotations[ClaimedAtAnnotation])
+	if err != nil {
+		return false
+	}
+	return now.Sub(claimedAt) < ttl
+}
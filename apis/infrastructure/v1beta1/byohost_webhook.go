@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
@@ -8,21 +9,37 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 
 	v1 "k8s.io/api/admission/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 //+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-byohost,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byohosts,verbs=create;update;delete,versions=v1beta1,name=vbyohost.kb.io,admissionReviewVersions={v1,v1beta1}
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 // +k8s:deepcopy-gen=false
 // ByoHostValidator validates ByoHosts
 type ByoHostValidator struct {
-	Client  client.Client
-	decoder *admission.Decoder
+	Client client.Client
+	// AllowedUsers are the usernames allowed to create and update any ByoHost,
+	// DefaultAllowedUsers is used when nil
+	AllowedUsers []string
+	// AllowedUserPatterns match the usernames allowed to create and update any ByoHost,
+	// DefaultAllowedUserPatterns is used when nil
+	AllowedUserPatterns []*regexp.Regexp
+	// DenyUnknownUsers denies the principals that are neither allowed nor an agent identity.
+	// It is off by default: the agents onboarded with the token kubeconfig of their tenant
+	// share its identity, which does not name their host.
+	DenyUnknownUsers bool
+	decoder          *admission.Decoder
 }
 
 // The byoh-controller-manager's namespace differs by deployment: "byoh-system" is the OSS
@@ -33,13 +50,65 @@ const (
 	byohSystemManagerServiceAccount = "system:serviceaccount:byoh-system:byoh-controller-manager"
 )
 
-var managerServiceAccounts = map[string]struct{}{
-	kaapiManagerServiceAccount:      struct{}{},
-	byohSystemManagerServiceAccount: struct{}{},
+// agentUserPrefix is the prefix of the agent identities, the agent's client certificate
+// common name is byoh:host:<hostname>
+const agentUserPrefix = "byoh:host:"
+
+// DefaultAllowedUsers are the manager service accounts allowed to bypass the per-agent checks
+var DefaultAllowedUsers = []string{kaapiManagerServiceAccount, byohSystemManagerServiceAccount}
+
+// emailLikeUserPattern matches the email-like usernames of human users
+const emailLikeUserPattern = `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`
+
+// DefaultAllowedUserPatterns match the users allowed to bypass the per-agent checks
+var DefaultAllowedUserPatterns = MustCompileUserPatterns([]string{emailLikeUserPattern})
+
+// CompileUserPatterns compiles the given username patterns. A pattern has to match
+// the whole username, it is anchored at both ends.
+func CompileUserPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid username pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
 }
 
-// Precompile email-like regex for efficiency
-var emailLikeUserRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+// MustCompileUserPatterns is like CompileUserPatterns but panics if a pattern is invalid
+func MustCompileUserPatterns(patterns []string) []*regexp.Regexp {
+	compiled, err := CompileUserPatterns(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// isAllowedUser checks whether userName is one of the allowed users or matches one of the allowed patterns
+func (v *ByoHostValidator) isAllowedUser(userName string) bool {
+	allowedUsers := v.AllowedUsers
+	if allowedUsers == nil {
+		allowedUsers = DefaultAllowedUsers
+	}
+	for _, user := range allowedUsers {
+		if userName == user {
+			return true
+		}
+	}
+
+	allowedPatterns := v.AllowedUserPatterns
+	if allowedPatterns == nil {
+		allowedPatterns = DefaultAllowedUserPatterns
+	}
+	for _, pattern := range allowedPatterns {
+		if pattern.MatchString(userName) {
+			return true
+		}
+	}
+	return false
+}
 
 // nolint: gocritic
 // Handle handles all the requests for ByoHost resource
@@ -48,7 +117,7 @@ func (v *ByoHostValidator) Handle(ctx context.Context, req admission.Request) ad
 
 	switch req.Operation {
 	case v1.Create, v1.Update:
-		response = v.handleCreateUpdate(&req)
+		response = v.handleCreateUpdate(ctx, &req)
 	case v1.Delete:
 		response = v.handleDelete(ctx, &req)
 	default:
@@ -57,41 +126,153 @@ func (v *ByoHostValidator) Handle(ctx context.Context, req admission.Request) ad
 	return response
 }
 
-func (v *ByoHostValidator) handleCreateUpdate(req *admission.Request) admission.Response {
+func (v *ByoHostValidator) handleCreateUpdate(ctx context.Context, req *admission.Request) admission.Response {
 	byoHost := &ByoHost{}
 	err := v.decoder.Decode(*req, byoHost)
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	errs := validateByoHostSpec(&byoHost.Spec)
+	errs = append(errs, validateTopologyLabels(byoHost.Labels)...)
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
 	userName := req.UserInfo.Username
-	// allow manager service account to patch ByoHost
-	if _, ok := managerServiceAccounts[userName]; ok {
+
+	// the force-delete annotation bypasses the delete protection below, only users
+	// granted the force-delete verb on byohosts may set it
+	forceDelete, ok := byoHost.Annotations[ForceDeleteAnnotation]
+	if ok {
+		oldByoHost := &ByoHost{}
+		if req.Operation == v1.Update {
+			if err = v.decoder.DecodeRaw(req.OldObject, oldByoHost); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+		if oldForceDelete, found := oldByoHost.Annotations[ForceDeleteAnnotation]; !found || oldForceDelete != forceDelete {
+			allowed, err := v.isAllowedToForceDelete(ctx, req)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			if !allowed {
+				return admission.Denied(fmt.Sprintf("%s is not allowed to set the %s annotation", userName, ForceDeleteAnnotation))
+			}
+		}
+	}
+
+	// allow the manager service accounts and users with email-like usernames, or
+	// the configured principals, to patch ByoHost
+	if v.isAllowedUser(userName) {
 		return admission.Allowed("")
 	}
 
-	// allow users with email-like usernames
-	if emailLikeUserRegex.MatchString(userName) {
+	// An agent's username encodes the host it owns (format: byoh:host:<hostname>). Reject
+	// requests where the encoded host does not match the target ByoHost — an agent must not
+	// create or update another agent's host.
+	if hostName, ok := agentHostName(userName); ok {
+		if hostName == "" {
+			return admission.Denied(fmt.Sprintf("%s is not a valid agent username", userName))
+		}
+		if hostName != byoHost.Name {
+			return admission.Denied(fmt.Sprintf("%s cannot create/update resource %s", userName, byoHost.Name))
+		}
 		return admission.Allowed("")
 	}
 
+	// The agents onboarded with a token kubeconfig register with the shared identity of their
+	// tenant, which cannot be checked against the host until each host gets its own identity.
 	substrs := strings.Split(userName, ":")
-
-	if len(substrs) < 2 { //nolint: mnd
+	if v.DenyUnknownUsers || len(substrs) < 2 { //nolint: mnd
 		return admission.Denied(fmt.Sprintf("%s is not a valid agent username", userName))
 	}
 
-	// An agent's username encodes the host it owns as the third colon-separated segment
-	// (format: byoh:host:<hostname>). Reject requests where the encoded host does not
-	// match the target ByoHost — an agent must not create or update another agent's host.
+	return admission.Allowed("")
+}
+
+// agentHostName returns the host encoded in the username of an agent identity,
+// i.e. the common name of the agent's client certificate
+func agentHostName(userName string) (string, bool) {
+	if !strings.HasPrefix(userName, agentUserPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(userName, agentUserPrefix), true
+}
+
+// validateByoHostSpec validates the node labels, taints, maintenance window and kubelet flags requested on the ByoHost
+func validateByoHostSpec(spec *ByoHostSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
 
-	// FIXME: We only support token based kubeconfig for now. cert based flow needs a redesign. Disable it for now to allow host onboarding for the time being.
-	// NOTE: When you're fixing this, see the tests that were skipped as part of the commit that disabled this check.
-	//
-	// if len(substrs) >= 3 && !strings.Contains(byoHost.Name, substrs[2]) {
-	// 	return admission.Denied(fmt.Sprintf("%s cannot create/update resource %s", userName, byoHost.Name))
-	// }
+	labelsPath := specPath.Child("nodeLabels")
+	keys := make([]string, 0, len(spec.NodeLabels))
+	for key := range spec.NodeLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := spec.NodeLabels[key]
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(labelsPath, key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			allErrs = append(allErrs, field.Invalid(labelsPath.Key(key), value, msg))
+		}
+	}
 
-	return admission.Allowed("")
+	taintsPath := specPath.Child("nodeTaints")
+	seen := make(map[string]struct{}, len(spec.NodeTaints))
+	for i, taint := range spec.NodeTaints {
+		idxPath := taintsPath.Index(i)
+		for _, msg := range validation.IsQualifiedName(taint.Key) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("key"), taint.Key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(taint.Value) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("value"), taint.Value, msg))
+		}
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("effect"), taint.Effect,
+				[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+		}
+		id := taint.Key + ":" + string(taint.Effect)
+		if _, ok := seen[id]; ok {
+			allErrs = append(allErrs, field.Duplicate(idxPath, id))
+		}
+		seen[id] = struct{}{}
+	}
+
+	if window := spec.MaintenanceWindow; window != nil && !window.End.After(window.Start.Time) {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maintenanceWindow", "end"), window.End, "end must be after start"))
+	}
+
+	allErrs = append(allErrs, validateKubeletExtraArgs(specPath.Child("kubeletExtraArgs"), spec.KubeletExtraArgs)...)
+	return allErrs
+}
+
+// validateTopologyLabels validates the zone, room and rack labels of the ByoHost. Rooms and
+// racks are only meaningful within a zone, so they require the zone label to be set as well.
+func validateTopologyLabels(hostLabels map[string]string) field.ErrorList {
+	var allErrs field.ErrorList
+	labelsPath := field.NewPath("metadata", "labels")
+
+	for _, key := range []string{HostZoneLabel, HostRoomLabel, HostRackLabel} {
+		value, ok := hostLabels[key]
+		if !ok {
+			continue
+		}
+		if value == "" {
+			allErrs = append(allErrs, field.Invalid(labelsPath.Key(key), value, "must not be empty"))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Label(value) {
+			allErrs = append(allErrs, field.Invalid(labelsPath.Key(key), value, msg))
+		}
+		if key != HostZoneLabel && hostLabels[HostZoneLabel] == "" {
+			allErrs = append(allErrs, field.Required(labelsPath.Key(HostZoneLabel), fmt.Sprintf("must be set when %s is set", key)))
+		}
+	}
+	return allErrs
 }
 
 func (v *ByoHostValidator) handleDelete(ctx context.Context, req *admission.Request) admission.Response {
@@ -100,7 +281,16 @@ func (v *ByoHostValidator) handleDelete(ctx context.Context, req *admission.Requ
 	if err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	// an agent must not delete another agent's host
+	if hostName, ok := agentHostName(req.UserInfo.Username); ok && hostName != byoHost.Name {
+		return admission.Denied(fmt.Sprintf("%s cannot delete resource %s", req.UserInfo.Username, byoHost.Name))
+	}
 	if byoHost.Status.MachineRef != nil {
+		// allow an admin to delete a ByoHost that is permanently gone, its machine teardown can never complete
+		if byoHost.Annotations[ForceDeleteAnnotation] == "true" {
+			return admission.Allowed(fmt.Sprintf("%s annotation is set", ForceDeleteAnnotation))
+		}
+
 		// allow webhook to delete ByoHost when MachineRef is assigned but respective byoMachine doesn't exist
 		byoMachine := byoHost.Status.MachineRef.Name
 
@@ -122,6 +312,33 @@ func (v *ByoHostValidator) handleDelete(ctx context.Context, req *admission.Requ
 	return admission.Allowed("")
 }
 
+// isAllowedToForceDelete checks whether the requesting user is granted the force-delete verb on the ByoHost
+func (v *ByoHostValidator) isAllowedToForceDelete(ctx context.Context, req *admission.Request) (bool, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: req.Namespace,
+				Verb:      ForceDeleteVerb,
+				Group:     GroupVersion.Group,
+				Resource:  "byohosts",
+				Name:      req.Name,
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
 // InjectDecoder injects the decoder.
 func (v *ByoHostValidator) InjectDecoder(d *admission.Decoder) error {
 	v.decoder = d
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
@@ -7,7 +8,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -28,6 +31,8 @@ const (
 	unauthorizedUser = "unauthorized-user"
 	byohHostTwoUser  = "byoh:host:host2"
 	byohHostOneUser  = "byoh:host:host1"
+	// tenantTokenUser is the identity of the token kubeconfig byohctl onboards the agents of a tenant with
+	tenantTokenUser = "system:serviceaccount:tenant1:byoh-bootstrap"
 )
 
 var _ = Describe("ByohostWebhook/Unit", func() {
@@ -66,7 +71,6 @@ var _ = Describe("ByohostWebhook/Unit", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("Should reject create request from invalid user", func() {
-			Skip("feature not implemented yet")
 			admissionRequest := admissionv1.AdmissionRequest{
 				Operation: admissionv1.Create,
 				UserInfo:  v1.UserInfo{Username: unauthorizedUser},
@@ -80,7 +84,6 @@ var _ = Describe("ByohostWebhook/Unit", func() {
 			Expect(string(resp.AdmissionResponse.Result.Reason)).To(Equal(fmt.Sprintf("%s is not a valid agent username", unauthorizedUser)))
 		})
 		It("Should reject request from another agent user in the group", func() {
-			Skip("feature not implemented yet")
 			admissionRequest := admissionv1.AdmissionRequest{
 				Operation: admissionv1.Create,
 				UserInfo:  v1.UserInfo{Username: byohHostTwoUser},
@@ -181,7 +184,6 @@ var _ = Describe("ByohostWebhook/Unit", func() {
 		})
 
 		It("Should reject request from another agent user in the group", func() {
-			Skip("feature not implemented yet")
 			admissionRequest := admissionv1.AdmissionRequest{
 				Operation: admissionv1.Update,
 				UserInfo:  v1.UserInfo{Username: byohHostTwoUser},
@@ -241,6 +243,19 @@ var _ = Describe("ByohostWebhook/Unit", func() {
 			resp := v.Handle(ctx, admission.Request{AdmissionRequest: admissionRequest})
 			Expect(resp.AdmissionResponse.Allowed).To(Equal(true))
 		})
+		It("Should reject delete request from another agent user in the group", func() {
+			admissionRequest := admissionv1.AdmissionRequest{
+				Operation: admissionv1.Delete,
+				UserInfo:  v1.UserInfo{Username: byohHostTwoUser},
+				OldObject: runtime.RawExtension{
+					Raw:    byoHostRaw,
+					Object: byoHost,
+				},
+			}
+			resp := v.Handle(ctx, admission.Request{AdmissionRequest: admissionRequest})
+			Expect(resp.AdmissionResponse.Allowed).To(Equal(false))
+			Expect(string(resp.AdmissionResponse.Result.Reason)).To(Equal(fmt.Sprintf("%s cannot delete resource %s", byohHostTwoUser, defaultHostName)))
+		})
 		It("Should reject delete request if status.MachineRef is not nil", func() {
 			byoHost.Status.MachineRef = &corev1.ObjectReference{
 				Kind:       "ByoMachine",
@@ -272,14 +287,13 @@ func TestByoHostValidator_handleCreateUpdate(t *testing.T) {
 	decoder, err := admission.NewDecoder(scheme)
 	require.NoError(t, err)
 
-	v := &ByoHostValidator{decoder: decoder}
-
 	testCases := []struct {
-		name      string
-		userName  string
-		hostName  string // ByoHost.Name; defaults to "host1" when empty
-		wantAllow bool
-		wantMsg   string
+		name             string
+		userName         string
+		hostName         string // ByoHost.Name; defaults to "host1" when empty
+		denyUnknownUsers bool
+		wantAllow        bool
+		wantMsg          string
 	}{
 		{
 			name:      "byoh-system manager service account bypasses the ownership check",
@@ -297,20 +311,46 @@ func TestByoHostValidator_handleCreateUpdate(t *testing.T) {
 			wantAllow: true,
 		},
 		{
-			name:      "username with fewer than 2 segments is rejected before the ownership check runs",
+			name:      "username with fewer than 2 segments is denied",
 			userName:  unauthorizedUser,
 			wantAllow: false,
 			wantMsg:   "unauthorized-user is not a valid agent username",
 		},
 		{
-			name:      "username with no host segment skips the ownership check",
-			userName:  "byoh:host",
+			name:      "agent username without a host is denied",
+			userName:  "byoh:host:",
+			wantAllow: false,
+			wantMsg:   "byoh:host: is not a valid agent username",
+		},
+		{
+			name:      "token kubeconfig identity of the tenant is allowed",
+			userName:  tenantTokenUser,
 			wantAllow: true,
 		},
+		{
+			name:             "token kubeconfig identity of the tenant is denied with DenyUnknownUsers",
+			userName:         tenantTokenUser,
+			denyUnknownUsers: true,
+			wantAllow:        false,
+			wantMsg:          tenantTokenUser + " is not a valid agent username",
+		},
+		{
+			name:             "node identity is denied with DenyUnknownUsers",
+			userName:         "system:node:host1",
+			denyUnknownUsers: true,
+			wantAllow:        false,
+			wantMsg:          "system:node:host1 is not a valid agent username",
+		},
+		{
+			name:             "agent encoding the target host is allowed with DenyUnknownUsers",
+			userName:         byohHostOneUser,
+			denyUnknownUsers: true,
+			wantAllow:        true,
+		},
 		{
 			name:      "agent encoding a different host is denied",
 			userName:  byohHostTwoUser,
-			wantAllow: true, // FIXME: This test should fail when we fix the check.
+			wantAllow: false,
 			wantMsg:   "byoh:host:host2 cannot create/update resource host1",
 		},
 		{
@@ -319,10 +359,11 @@ func TestByoHostValidator_handleCreateUpdate(t *testing.T) {
 			wantAllow: true,
 		},
 		{
-			name:      "ownership check matches by substring containment, not exact equality",
+			name:      "ownership check requires the exact host name",
 			userName:  byohHostOneUser,
 			hostName:  "host12",
-			wantAllow: true,
+			wantAllow: false,
+			wantMsg:   "byoh:host:host1 cannot create/update resource host12",
 		},
 	}
 
@@ -351,7 +392,8 @@ func TestByoHostValidator_handleCreateUpdate(t *testing.T) {
 				},
 			}
 
-			resp := v.handleCreateUpdate(req)
+			v := &ByoHostValidator{decoder: decoder, DenyUnknownUsers: tc.denyUnknownUsers}
+			resp := v.handleCreateUpdate(context.Background(), req)
 
 			require.Equal(t, tc.wantAllow, resp.Allowed)
 			if !tc.wantAllow {
@@ -360,3 +402,276 @@ func TestByoHostValidator_handleCreateUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestByoHostValidator_isAllowedUser(t *testing.T) {
+	customPatterns, err := CompileUserPatterns([]string{`system:serviceaccount:byoh:.*`, `admin-[0-9]+`})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		validator *ByoHostValidator
+		userName  string
+		wantAllow bool
+	}{
+		{
+			name:      "default manager service account is allowed by default",
+			validator: &ByoHostValidator{},
+			userName:  kaapiManagerServiceAccount,
+			wantAllow: true,
+		},
+		{
+			name:      "email-like username is allowed by default",
+			validator: &ByoHostValidator{},
+			userName:  "user@example.com",
+			wantAllow: true,
+		},
+		{
+			name:      "configured user is allowed",
+			validator: &ByoHostValidator{AllowedUsers: []string{"system:serviceaccount:capi:byoh-manager"}},
+			userName:  "system:serviceaccount:capi:byoh-manager",
+			wantAllow: true,
+		},
+		{
+			name:      "configured users replace the default manager service accounts",
+			validator: &ByoHostValidator{AllowedUsers: []string{"system:serviceaccount:capi:byoh-manager"}},
+			userName:  byohSystemManagerServiceAccount,
+			wantAllow: false,
+		},
+		{
+			name:      "configured pattern matches the username",
+			validator: &ByoHostValidator{AllowedUserPatterns: customPatterns},
+			userName:  "system:serviceaccount:byoh:controller",
+			wantAllow: true,
+		},
+		{
+			name:      "configured pattern has to match the whole username",
+			validator: &ByoHostValidator{AllowedUserPatterns: customPatterns},
+			userName:  "admin-42@example.com",
+			wantAllow: false,
+		},
+		{
+			name:      "empty configured patterns disable the email-like default",
+			validator: &ByoHostValidator{AllowedUserPatterns: []*regexp.Regexp{}},
+			userName:  "user@example.com",
+			wantAllow: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.wantAllow, tc.validator.isAllowedUser(tc.userName))
+		})
+	}
+}
+
+func TestCompileUserPatterns(t *testing.T) {
+	_, err := CompileUserPatterns([]string{`admin-[0-9+`})
+	require.Error(t, err)
+}
+
+func TestValidateByoHostSpec(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     ByoHostSpec
+		wantErrs int
+	}{
+		{
+			name: "empty spec is valid",
+			spec: ByoHostSpec{},
+		},
+		{
+			name: "valid node labels and taints",
+			spec: ByoHostSpec{
+				NodeLabels: map[string]string{"node.example.com/pool": "gpu"},
+				NodeTaints: []corev1.Taint{{Key: "node.example.com/dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			name:     "invalid node label key",
+			spec:     ByoHostSpec{NodeLabels: map[string]string{"not a valid key": "gpu"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "invalid node label value",
+			spec:     ByoHostSpec{NodeLabels: map[string]string{"pool": "not a valid value"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "unsupported taint effect",
+			spec:     ByoHostSpec{NodeTaints: []corev1.Taint{{Key: "dedicated", Effect: "Sometimes"}}},
+			wantErrs: 1,
+		},
+		{
+			name: "duplicate taint key and effect",
+			spec: ByoHostSpec{NodeTaints: []corev1.Taint{
+				{Key: "dedicated", Value: "a", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "dedicated", Value: "b", Effect: corev1.TaintEffectNoSchedule},
+			}},
+			wantErrs: 1,
+		},
+		{
+			name: "maintenance window ending after it starts",
+			spec: ByoHostSpec{MaintenanceWindow: &MaintenanceWindow{
+				Start: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+				End:   metav1.NewTime(time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)),
+			}},
+		},
+		{
+			name: "maintenance window ending before it starts",
+			spec: ByoHostSpec{MaintenanceWindow: &MaintenanceWindow{
+				Start: metav1.NewTime(time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)),
+				End:   metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			}},
+			wantErrs: 1,
+		},
+		{
+			name: "valid kubelet extra args",
+			spec: ByoHostSpec{KubeletExtraArgs: map[string]string{"max-pods": "250", "node-labels": "pool=gpu,tier=web"}},
+		},
+		{
+			name:     "kubelet extra arg with leading dashes",
+			spec:     ByoHostSpec{KubeletExtraArgs: map[string]string{"--max-pods": "250"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "kubelet extra arg managed by kubeadm",
+			spec:     ByoHostSpec{KubeletExtraArgs: map[string]string{"kubeconfig": "/tmp/kubeconfig"}},
+			wantErrs: 1,
+		},
+		{
+			name:     "kubelet extra arg value with whitespace",
+			spec:     ByoHostSpec{KubeletExtraArgs: map[string]string{"max-pods": "250 --v=10"}},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := validateByoHostSpec(&tc.spec)
+			require.Len(t, errs, tc.wantErrs)
+		})
+	}
+}
+
+func TestValidateTopologyLabels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		labels   map[string]string
+		wantErrs int
+	}{
+		{
+			name: "no topology labels are valid",
+		},
+		{
+			name:   "zone, room and rack are valid",
+			labels: map[string]string{HostZoneLabel: "zone-a", HostRoomLabel: "room-1", HostRackLabel: "rack-12"},
+		},
+		{
+			name:     "empty zone",
+			labels:   map[string]string{HostZoneLabel: ""},
+			wantErrs: 1,
+		},
+		{
+			name:     "zone that is not a DNS label",
+			labels:   map[string]string{HostZoneLabel: "Zone_A"},
+			wantErrs: 1,
+		},
+		{
+			name:     "rack without zone",
+			labels:   map[string]string{HostRackLabel: "rack-12"},
+			wantErrs: 1,
+		},
+		{
+			name:     "room and rack without zone",
+			labels:   map[string]string{HostRoomLabel: "room-1", HostRackLabel: "rack-12"},
+			wantErrs: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := validateTopologyLabels(tc.labels)
+			require.Len(t, errs, tc.wantErrs)
+		})
+	}
+}
+
+func TestByoHost_IsSchedulable(t *testing.T) {
+	now := time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)
+	notSchedulable := false
+
+	testCases := []struct {
+		name string
+		spec ByoHostSpec
+		want bool
+	}{
+		{
+			name: "hosts are schedulable by default",
+			want: true,
+		},
+		{
+			name: "host marked unschedulable",
+			spec: ByoHostSpec{Schedulable: &notSchedulable},
+			want: false,
+		},
+		{
+			name: "host inside its maintenance window",
+			spec: ByoHostSpec{MaintenanceWindow: &MaintenanceWindow{
+				Start: metav1.NewTime(now.Add(-time.Hour)),
+				End:   metav1.NewTime(now.Add(time.Hour)),
+			}},
+			want: false,
+		},
+		{
+			name: "host outside its maintenance window",
+			spec: ByoHostSpec{MaintenanceWindow: &MaintenanceWindow{
+				Start: metav1.NewTime(now.Add(time.Hour)),
+				End:   metav1.NewTime(now.Add(2 * time.Hour)),
+			}},
+			want: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			byoHost := &ByoHost{Spec: tc.spec}
+			require.Equal(t, tc.want, byoHost.IsSchedulable(now))
+		})
+	}
+}
+
+func TestByoHost_IsHeartbeatStale(t *testing.T) {
+	now := time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)
+	heartbeat := func(age time.Duration) *metav1.Time {
+		t := metav1.NewTime(now.Add(-age))
+		return &t
+	}
+
+	testCases := []struct {
+		name      string
+		heartbeat *metav1.Time
+		want      bool
+	}{
+		{
+			name: "hosts without a heartbeat are not stale",
+			want: false,
+		},
+		{
+			name:      "recent heartbeat",
+			heartbeat: heartbeat(time.Minute),
+			want:      false,
+		},
+		{
+			name:      "heartbeat older than the timeout",
+			heartbeat: heartbeat(10 * time.Minute),
+			want:      true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			byoHost := &ByoHost{Status: ByoHostStatus{LastHeartbeatTime: tc.heartbeat}}
+			require.Equal(t, tc.want, byoHost.IsHeartbeatStale(now, 5*time.Minute))
+		})
+	}
+}
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1_test
@@ -101,6 +102,81 @@ var _ = Describe("ByohostWebhook", func() {
 			})
 		})
 	})
+	Context("When ByoHost has the force-delete annotation", func() {
+		var (
+			byoHost    *byohv1beta1.ByoHost
+			byoMachine *byohv1beta1.ByoMachine
+		)
+		BeforeEach(func() {
+			ctx = context.Background()
+			byoHost = &byohv1beta1.ByoHost{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       testByoHostKind,
+					APIVersion: testAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      defaultHostName,
+					Namespace: defaultNamespace,
+				},
+				Spec: byohv1beta1.ByoHostSpec{},
+			}
+			Expect(ValidUserK8sClient.Create(ctx, byoHost)).Should(Succeed())
+
+			byoMachine = &byohv1beta1.ByoMachine{
+				TypeMeta: metav1.TypeMeta{
+					Kind:       "ByoMachine",
+					APIVersion: testAPIVersion,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "byomachine-",
+					Namespace:    defaultNamespace,
+				},
+				Spec: byohv1beta1.ByoMachineSpec{},
+			}
+			Expect(k8sClient.Create(ctx, byoMachine)).Should(Succeed())
+
+			ph, err := patch.NewHelper(byoHost, ValidUserK8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoHost.Status.MachineRef = &corev1.ObjectReference{
+				Kind:       "ByoMachine",
+				Namespace:  byoMachine.Namespace,
+				Name:       byoMachine.Name,
+				UID:        byoMachine.UID,
+				APIVersion: byoHost.APIVersion,
+			}
+			Expect(ph.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, byoMachine)).Should(Succeed())
+		})
+
+		It("should reject setting the annotation by a user without the force-delete verb", func() {
+			ph, err := patch.NewHelper(byoHost, ValidUserK8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoHost.Annotations = map[string]string{byohv1beta1.ForceDeleteAnnotation: "true"}
+			err = ph.Patch(ctx, byoHost)
+			Expect(err).To(MatchError(ContainSubstring("byoh:host:host1 is not allowed to set the byoh.infrastructure.cluster.x-k8s.io/force-delete annotation")))
+
+			// cleanup
+			updatedByoHost := &byohv1beta1.ByoHost{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoHost), updatedByoHost)).Should(Succeed())
+			ph, err = patch.NewHelper(updatedByoHost, ValidUserK8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedByoHost.Status.MachineRef = nil
+			Expect(ph.Patch(ctx, updatedByoHost, patch.WithStatusObservedGeneration{})).Should(Succeed())
+			Expect(ValidUserK8sClient.Delete(ctx, updatedByoHost)).Should(Succeed())
+		})
+
+		It("should allow the deletion once an admin has set the annotation", func() {
+			ph, err := patch.NewHelper(byoHost, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoHost.Annotations = map[string]string{byohv1beta1.ForceDeleteAnnotation: "true"}
+			Expect(ph.Patch(ctx, byoHost)).Should(Succeed())
+
+			Expect(ValidUserK8sClient.Delete(ctx, byoHost)).Should(Succeed())
+		})
+	})
 	Context("When ByoHost gets a create request", func() {
 		var (
 			byoHost *byohv1beta1.ByoHost
@@ -0,0 +1,120 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestByoHostAdmissionPolicy_AppliesTo(t *testing.T) {
+	testCases := []struct {
+		name string
+		spec ByoHostAdmissionPolicySpec
+		want bool
+	}{
+		{
+			name: "policies apply to all clusters by default",
+			want: true,
+		},
+		{
+			name: "cluster in one of the namespaces",
+			spec: ByoHostAdmissionPolicySpec{Namespaces: []string{"team-b", "team-a"}},
+			want: true,
+		},
+		{
+			name: "cluster in another namespace",
+			spec: ByoHostAdmissionPolicySpec{Namespaces: []string{"team-b"}},
+			want: false,
+		},
+		{
+			name: "cluster matching the cluster selector",
+			spec: ByoHostAdmissionPolicySpec{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}},
+			want: true,
+		},
+		{
+			name: "cluster not matching the cluster selector",
+			spec: ByoHostAdmissionPolicySpec{ClusterSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+			want: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := &ByoHostAdmissionPolicy{Spec: tc.spec}
+			applies, err := policy.AppliesTo("team-a", map[string]string{"env": "prod"})
+			require.NoError(t, err)
+			require.Equal(t, tc.want, applies)
+		})
+	}
+}
+
+func TestByoHostAdmissionPolicy_Admits(t *testing.T) {
+	byoHost := &ByoHost{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "team-a"}},
+		Status: ByoHostStatus{
+			HostDetails: HostInfo{OSName: "Ubuntu 22.04.4 LTS"},
+			Capacity:    corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("8")},
+		},
+	}
+
+	testCases := []struct {
+		name       string
+		spec       ByoHostAdmissionPolicySpec
+		wantReason string
+	}{
+		{
+			name: "policies without restrictions admit all hosts",
+		},
+		{
+			name: "host admitted by all the restrictions",
+			spec: ByoHostAdmissionPolicySpec{
+				HostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "team-a"}},
+				OSNames:      []string{"Ubuntu 22.04.4 LTS"},
+				MinCPU:       4,
+				MaxCPU:       8,
+			},
+		},
+		{
+			name:       "host not matching the host selector",
+			spec:       ByoHostAdmissionPolicySpec{HostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "team-b"}}},
+			wantReason: "labels do not match the host selector",
+		},
+		{
+			name:       "host running another os",
+			spec:       ByoHostAdmissionPolicySpec{OSNames: []string{"Flatcar Container Linux"}},
+			wantReason: "os Ubuntu 22.04.4 LTS is not allowed",
+		},
+		{
+			name:       "host with too few cpus",
+			spec:       ByoHostAdmissionPolicySpec{MinCPU: 16},
+			wantReason: "cpu count is below the minimum",
+		},
+		{
+			name:       "host with too many cpus",
+			spec:       ByoHostAdmissionPolicySpec{MaxCPU: 4},
+			wantReason: "cpu count is above the maximum",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := &ByoHostAdmissionPolicy{Spec: tc.spec}
+			reason, err := policy.Admits(byoHost)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantReason, reason)
+		})
+	}
+
+	t.Run("host without a reported capacity", func(t *testing.T) {
+		policy := &ByoHostAdmissionPolicy{Spec: ByoHostAdmissionPolicySpec{MinCPU: 1}}
+		reason, err := policy.Admits(&ByoHost{})
+		require.NoError(t, err)
+		require.Equal(t, "cpu capacity is not reported", reason)
+	})
+}
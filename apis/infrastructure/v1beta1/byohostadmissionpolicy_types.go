@@ -0,0 +1,125 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ByoHostAdmissionPolicySpec restricts the hosts the clusters it applies to may attach
+type ByoHostAdmissionPolicySpec struct {
+	// Namespaces are the namespaces of the clusters the policy applies to.
+	// The policy applies to the clusters of all namespaces if empty.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ClusterSelector selects the clusters, by the labels of their Cluster, the policy applies to.
+	// The policy applies to all clusters if not set.
+	// +optional
+	ClusterSelector *metav1.LabelSelector `json:"clusterSelector,omitempty"`
+
+	// HostSelector is the labels the hosts must have to be attached.
+	// +optional
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// OSNames are the operating systems, as reported in the hostinfo of the ByoHost,
+	// e.g. "Ubuntu 22.04.4 LTS", the hosts must run to be attached. All are admitted if empty.
+	// +optional
+	OSNames []string `json:"osNames,omitempty"`
+
+	// MinCPU is the minimum number of CPUs the hosts must have to be attached.
+	// Hosts that have not reported a capacity are not admitted when set.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinCPU int32 `json:"minCPU,omitempty"`
+
+	// MaxCPU is the maximum number of CPUs the hosts may have to be attached.
+	// Hosts that have not reported a capacity are not admitted when set.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxCPU int32 `json:"maxCPU,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=byohostadmissionpolicies,scope=Cluster,shortName=byohap
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// ByoHostAdmissionPolicy restricts which ByoHosts the ByoMachines of a set of clusters may attach.
+// A host is attached to a ByoMachine only if it is admitted by all the policies that apply to its
+// cluster, giving the administrators of a shared host inventory guardrails over the clusters of the teams.
+type ByoHostAdmissionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ByoHostAdmissionPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ByoHostAdmissionPolicyList contains a list of ByoHostAdmissionPolicy
+type ByoHostAdmissionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ByoHostAdmissionPolicy `json:"items"`
+}
+
+// AppliesTo reports whether the policy applies to the cluster of namespace with clusterLabels
+func (policy *ByoHostAdmissionPolicy) AppliesTo(namespace string, clusterLabels map[string]string) (bool, error) {
+	if len(policy.Spec.Namespaces) > 0 && !containsString(policy.Spec.Namespaces, namespace) {
+		return false, nil
+	}
+	if policy.Spec.ClusterSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.ClusterSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(clusterLabels)), nil
+}
+
+// Admits reports whether the policy admits host to be attached. It returns the reason a host is
+// not admitted, e.g. "os Flatcar Container Linux is not allowed", or an empty string if it is.
+func (policy *ByoHostAdmissionPolicy) Admits(byoHost *ByoHost) (string, error) {
+	if policy.Spec.HostSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(policy.Spec.HostSelector)
+		if err != nil {
+			return "", err
+		}
+		if !selector.Matches(labels.Set(byoHost.Labels)) {
+			return "labels do not match the host selector", nil
+		}
+	}
+	if len(policy.Spec.OSNames) > 0 && !containsString(policy.Spec.OSNames, byoHost.Status.HostDetails.OSName) {
+		return "os " + byoHost.Status.HostDetails.OSName + " is not allowed", nil
+	}
+	if policy.Spec.MinCPU > 0 || policy.Spec.MaxCPU > 0 {
+		cpu, ok := byoHost.Status.Capacity[corev1.ResourceCPU]
+		if !ok {
+			return "cpu capacity is not reported", nil
+		}
+		if policy.Spec.MinCPU > 0 && cpu.Value() < int64(policy.Spec.MinCPU) {
+			return "cpu count is below the minimum", nil
+		}
+		if policy.Spec.MaxCPU > 0 && cpu.Value() > int64(policy.Spec.MaxCPU) {
+			return "cpu count is above the maximum", nil
+		}
+	}
+	return "", nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&ByoHostAdmissionPolicy{}, &ByoHostAdmissionPolicyList{})
+}
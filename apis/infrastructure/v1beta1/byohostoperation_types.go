@@ -0,0 +1,104 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ByoHostOperationHostLabel is the label of a ByoHostOperation set to the name of its ByoHost,
+	// to list the operations of a host
+	ByoHostOperationHostLabel = "byoh.infrastructure.cluster.x-k8s.io/byohost"
+	// ByoHostOperationNotifiedAnnotation is set on a ByoHostOperation once its lifecycle event was
+	// notified, so that it is notified only once
+	ByoHostOperationNotifiedAnnotation = "byoh.infrastructure.cluster.x-k8s.io/notified"
+)
+
+// ByoHostOperationType is a lifecycle operation of a ByoHost
+// +kubebuilder:validation:Enum=Onboard;Attach;Detach;Decommission
+type ByoHostOperationType string
+
+const (
+	// ByoHostOperationOnboard is the registration of a new host by its agent
+	ByoHostOperationOnboard ByoHostOperationType = "Onboard"
+	// ByoHostOperationAttach is the attachment of a host to a ByoMachine
+	ByoHostOperationAttach ByoHostOperationType = "Attach"
+	// ByoHostOperationDetach is the release of a host by its ByoMachine
+	ByoHostOperationDetach ByoHostOperationType = "Detach"
+	// ByoHostOperationDecommission is the removal of a host with byohctl
+	ByoHostOperationDecommission ByoHostOperationType = "Decommission"
+)
+
+// ByoHostOperationOutcome is the result of a ByoHostOperation
+// +kubebuilder:validation:Enum=Succeeded;Failed
+type ByoHostOperationOutcome string
+
+const (
+	// ByoHostOperationSucceeded is the outcome of a completed operation
+	ByoHostOperationSucceeded ByoHostOperationOutcome = "Succeeded"
+	// ByoHostOperationFailed is the outcome of an operation that returned an error
+	ByoHostOperationFailed ByoHostOperationOutcome = "Failed"
+)
+
+// ByoHostOperationSpec records an operation done on a ByoHost
+type ByoHostOperationSpec struct {
+	// HostName is the name of the ByoHost the operation was done on
+	HostName string `json:"hostName"`
+
+	// Operation is the lifecycle operation done on the host
+	Operation ByoHostOperationType `json:"operation"`
+
+	// Initiator is the user, or the agent or controller acting on its behalf, that did the operation
+	Initiator string `json:"initiator"`
+
+	// MachineRef is the ByoMachine the host was attached to or detached from
+	// +optional
+	MachineRef *corev1.ObjectReference `json:"machineRef,omitempty"`
+
+	// StartTime is the time the operation started
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is the time the operation succeeded or failed
+	CompletionTime metav1.Time `json:"completionTime"`
+
+	// Outcome is the result of the operation
+	Outcome ByoHostOperationOutcome `json:"outcome"`
+
+	// Message is the error the operation failed with
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=byohostoperations,scope=Namespaced,shortName=byoho
+//+kubebuilder:printcolumn:name="Host",type="string",JSONPath=`.spec.hostName`
+//+kubebuilder:printcolumn:name="Operation",type="string",JSONPath=`.spec.operation`
+//+kubebuilder:printcolumn:name="Outcome",type="string",JSONPath=`.spec.outcome`
+//+kubebuilder:printcolumn:name="Initiator",type="string",JSONPath=`.spec.initiator`
+//+kubebuilder:printcolumn:name="Machine",type="string",JSONPath=`.spec.machineRef.name`,priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
+
+// ByoHostOperation is the audit record of an onboard, attach, detach or decommission of a ByoHost.
+// Records are not updated once created and are deleted after the retention period of the manager.
+type ByoHostOperation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ByoHostOperationSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ByoHostOperationList contains a list of ByoHostOperation
+type ByoHostOperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ByoHostOperation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ByoHostOperation{}, &ByoHostOperationList{})
+}
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
@@ -14,6 +15,11 @@ const (
 	// resources associated with ByoMachine before removing it from the
 	// API Server.
 	MachineFinalizer = "byomachine.infrastructure.cluster.x-k8s.io"
+
+	// BootstrapTokenRefreshAnnotation annotation set by the controller manager on the bootstrap config of
+	// a Machine, to the time it was set at, to have the bootstrap provider refresh the bootstrap token of
+	// the bootstrap data when the token expires too soon for the host to join with it
+	BootstrapTokenRefreshAnnotation = "byoh.infrastructure.cluster.x-k8s.io/bootstrap-token-refresh"
 )
 
 // ByoMachineSpec defines the desired state of ByoMachine
@@ -21,14 +27,60 @@ type ByoMachineSpec struct {
 	// Label Selector to choose the byohost
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 
+	// HostRef is an optional reference to the ByoHost, in the namespace of the ByoMachine, the
+	// machine is pinned to. The named host is attached instead of one chosen by the Selector,
+	// for workloads that must run on specific hardware. It cannot be set along with the Selector.
+	// +optional
+	HostRef *corev1.LocalObjectReference `json:"hostRef,omitempty"`
+
 	ProviderID string `json:"providerID,omitempty"`
 
 	// InstallerRef is an optional reference to a installer-specific resource that holds
 	// the details of InstallationSecret to be used to install BYOH Bundle.
 	// +optional
 	InstallerRef *corev1.ObjectReference `json:"installerRef,omitempty"`
+
+	// MinCPU is the minimum number of CPUs a ByoHost must report
+	// to be selected for this machine
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinCPU int32 `json:"minCPU,omitempty"`
+
+	// MinMemoryMiB is the minimum amount of memory, in MiB, a ByoHost must
+	// report to be selected for this machine
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinMemoryMiB int64 `json:"minMemoryMiB,omitempty"`
+
+	// MinDiskGiB is the minimum amount of ephemeral storage, in GiB, a ByoHost
+	// must report to be selected for this machine
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinDiskGiB int64 `json:"minDiskGiB,omitempty"`
+
+	// KubeletExtraArgs is an optional set of extra flags passed to the kubelet
+	// of the host attached to this machine, keyed by flag name without the
+	// leading dashes, e.g. max-pods or node-ip. The KubeletExtraArgs of the
+	// ByoHost take precedence.
+	// +optional
+	KubeletExtraArgs map[string]string `json:"kubeletExtraArgs,omitempty"`
 }
 
+// NetworkLinkType is the kind of link backing a network interface.
+// +kubebuilder:validation:Enum=physical;bond;vlan;virtual
+type NetworkLinkType string
+
+const (
+	// NetworkLinkTypePhysical is an interface backed by a network device
+	NetworkLinkTypePhysical NetworkLinkType = "physical"
+	// NetworkLinkTypeBond is a bonding interface aggregating other interfaces
+	NetworkLinkTypeBond NetworkLinkType = "bond"
+	// NetworkLinkTypeVLAN is an 802.1Q VLAN interface on top of a parent interface
+	NetworkLinkTypeVLAN NetworkLinkType = "vlan"
+	// NetworkLinkTypeVirtual is any other software interface, e.g. loopback, bridge or veth
+	NetworkLinkTypeVirtual NetworkLinkType = "virtual"
+)
+
 // NetworkStatus provides information about one of a VM's networks.
 type NetworkStatus struct {
 	// Connected is a flag that indicates whether this network is currently
@@ -49,6 +101,32 @@ type NetworkStatus struct {
 	// IsDefault is a flag that indicates whether this interface name is where
 	// the default gateway sit on.
 	IsDefault bool `json:"isDefault,omitempty"`
+
+	// MTU is the maximum transmission unit of the network interface.
+	// +optional
+	MTU int32 `json:"mtu,omitempty"`
+
+	// Gateway is the address of the default gateway. It is only set on the
+	// default interface.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+
+	// LinkType is the kind of link backing the network interface.
+	// +optional
+	LinkType NetworkLinkType `json:"linkType,omitempty"`
+
+	// BondMembers are the names of the interfaces aggregated by a bond interface.
+	// +optional
+	BondMembers []string `json:"bondMembers,omitempty"`
+
+	// VLANID is the 802.1Q VLAN id of a vlan interface.
+	// +optional
+	VLANID int32 `json:"vlanID,omitempty"`
+
+	// ParentInterfaceName is the name of the interface a vlan interface is
+	// stacked on.
+	// +optional
+	ParentInterfaceName string `json:"parentInterfaceName,omitempty"`
 }
 
 // ByoMachineStatus defines the observed state of ByoMachine
@@ -63,6 +141,14 @@ type ByoMachineStatus struct {
 	// +optional
 	Ready bool `json:"ready"`
 
+	// NodeRef is the reference to the Node of the workload cluster the attached host joined as.
+	// +optional
+	NodeRef *corev1.ObjectReference `json:"nodeRef,omitempty"`
+
+	// NodeReady reports whether the Node of NodeRef is Ready.
+	// +optional
+	NodeReady bool `json:"nodeReady,omitempty"`
+
 	// Conditions defines current service state of the BYOMachine.
 	// +optional
 	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
@@ -71,6 +157,12 @@ type ByoMachineStatus struct {
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:path=byomachines,scope=Namespaced,shortName=byom
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=`.metadata.labels.cluster\.x-k8s\.io/cluster-name`,description="Cluster the ByoMachine belongs to"
+//+kubebuilder:printcolumn:name="ProviderID",type="string",JSONPath=`.spec.providerID`,description="Provider ID of the attached host"
+//+kubebuilder:printcolumn:name="OSImage",type="string",JSONPath=`.status.hostinfo.osimage`,priority=1
+//+kubebuilder:printcolumn:name="Node",type="string",JSONPath=`.status.nodeRef.name`,description="Node of the workload cluster the host joined as",priority=1
+//+kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=`.status.ready`,description="Indicates if the ByoMachine is ready"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
 // ByoMachine is the Schema for the byomachines API
 type ByoMachine struct {
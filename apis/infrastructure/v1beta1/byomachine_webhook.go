@@ -0,0 +1,279 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var byomachinelog = logf.Log.WithName("byomachine-resource")
+
+func (r *ByoMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-byomachine,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byomachines,verbs=create;update,versions=v1beta1,name=mbyomachine.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ByoMachine{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ByoMachine) Default() {
+	byomachinelog.Info("default", "name", r.Name)
+
+	r.Spec.setDefaults(r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-byomachine,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byomachines,verbs=create;update,versions=v1beta1,name=vbyomachine.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ByoMachine{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoMachine) ValidateCreate() error {
+	byomachinelog.Info("validate create", "name", r.Name)
+
+	return r.Spec.validate(field.NewPath("spec")).ToAggregate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoMachine) ValidateUpdate(old runtime.Object) error {
+	byomachinelog.Info("validate update", "name", r.Name)
+
+	oldMachine, ok := old.(*ByoMachine)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoMachine but got a %T", old))
+	}
+	allErrs := r.Spec.validate(field.NewPath("spec"))
+	if !reflect.DeepEqual(oldMachine.Spec.HostRef, r.Spec.HostRef) {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "hostRef"), "field is immutable"))
+	}
+	return allErrs.ToAggregate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ByoMachine) ValidateDelete() error {
+	byomachinelog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (r *ByoMachineTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&byoMachineTemplateValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-byomachinetemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byomachinetemplates,verbs=create;update,versions=v1beta1,name=mbyomachinetemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ByoMachineTemplate{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ByoMachineTemplate) Default() {
+	byomachinelog.Info("default", "name", r.Name)
+
+	r.Spec.Template.Spec.setDefaults(r.Namespace)
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-byomachinetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=byomachinetemplates,verbs=create;update,versions=v1beta1,name=vbyomachinetemplate.kb.io,admissionReviewVersions=v1
+
+// byoMachineTemplateValidator validates ByoMachineTemplates. It is a CustomValidator
+// since the immutability check needs the admission request to detect topology dry-runs
+type byoMachineTemplateValidator struct{}
+
+var _ webhook.CustomValidator = &byoMachineTemplateValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoMachineTemplateValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	template, ok := obj.(*ByoMachineTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoMachineTemplate but got a %T", obj))
+	}
+	byomachinelog.Info("validate create", "name", template.Name)
+
+	return template.Spec.Template.Spec.validate(field.NewPath("spec", "template", "spec")).ToAggregate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoMachineTemplateValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldTemplate, ok := oldObj.(*ByoMachineTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoMachineTemplate but got a %T", oldObj))
+	}
+	newTemplate, ok := newObj.(*ByoMachineTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a ByoMachineTemplate but got a %T", newObj))
+	}
+	byomachinelog.Info("validate update", "name", newTemplate.Name)
+
+	// templates created before the defaulting webhook was installed are compared in
+	// their defaulted form, so that metadata only updates are not rejected
+	oldSpec := oldTemplate.Spec.Template.Spec.DeepCopy()
+	oldSpec.setDefaults(oldTemplate.Namespace)
+
+	return validateTemplateImmutability(ctx, newTemplate, oldSpec, &newTemplate.Spec.Template.Spec)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *byoMachineTemplateValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// setDefaults normalizes the host selector and resolves the installer reference
+// against the namespace of the object
+func (spec *ByoMachineSpec) setDefaults(namespace string) {
+	spec.Selector = NormalizeLabelSelector(spec.Selector)
+
+	if spec.InstallerRef != nil && spec.InstallerRef.Namespace == "" {
+		spec.InstallerRef.Namespace = namespace
+	}
+}
+
+// validate validates the host reference and the extra kubelet flags of the spec
+func (spec *ByoMachineSpec) validate(specPath *field.Path) field.ErrorList {
+	allErrs := validateKubeletExtraArgs(specPath.Child("kubeletExtraArgs"), spec.KubeletExtraArgs)
+	if spec.HostRef != nil {
+		hostRefPath := specPath.Child("hostRef")
+		if spec.HostRef.Name == "" {
+			allErrs = append(allErrs, field.Required(hostRefPath.Child("name"), "the name of the ByoHost is required"))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(spec.HostRef.Name) {
+				allErrs = append(allErrs, field.Invalid(hostRefPath.Child("name"), spec.HostRef.Name, msg))
+			}
+		}
+		if spec.Selector != nil {
+			allErrs = append(allErrs, field.Forbidden(hostRefPath, "cannot be set along with the selector"))
+		}
+	}
+	return allErrs
+}
+
+var (
+	// kubeletFlagNameRegexp matches a kubelet flag name without its leading dashes
+	kubeletFlagNameRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// kubeletFlagValueRegexp rejects the characters that would break the quoting of the
+	// environment file the flags are rendered into on the host
+	kubeletFlagValueRegexp = regexp.MustCompile("^[^\\s\"'`$\\\\]*$")
+	// reservedKubeletFlags are set by kubeadm when the host joins and must not be overridden
+	reservedKubeletFlags = []string{"bootstrap-kubeconfig", "config", "kubeconfig"}
+)
+
+// validateKubeletExtraArgs validates the extra kubelet flags requested on a ByoHost or a ByoMachine
+func validateKubeletExtraArgs(argsPath *field.Path, args map[string]string) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch {
+		case !kubeletFlagNameRegexp.MatchString(name):
+			allErrs = append(allErrs, field.Invalid(argsPath, name,
+				"must be a kubelet flag name without the leading dashes, e.g. max-pods"))
+		case isReservedKubeletFlag(name):
+			allErrs = append(allErrs, field.Forbidden(argsPath.Key(name), "the flag is managed by kubeadm"))
+		}
+		if value := args[name]; !kubeletFlagValueRegexp.MatchString(value) {
+			allErrs = append(allErrs, field.Invalid(argsPath.Key(name), value,
+				"must not contain whitespace, quotes, backslashes or $"))
+		}
+	}
+	return allErrs
+}
+
+func isReservedKubeletFlag(name string) bool {
+	for _, reserved := range reservedKubeletFlags {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLabelSelector returns an equivalent label selector in canonical form:
+// values of set based requirements are sorted and deduplicated, values are dropped from
+// Exists/DoesNotExist requirements, single valued In requirements are folded into
+// MatchLabels and a selector without any requirement is returned as nil
+func NormalizeLabelSelector(selector *metav1.LabelSelector) *metav1.LabelSelector {
+	if selector == nil {
+		return nil
+	}
+
+	matchLabels := make(map[string]string, len(selector.MatchLabels))
+	for key, value := range selector.MatchLabels {
+		matchLabels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	var expressions []metav1.LabelSelectorRequirement
+	seen := make(map[string]bool)
+	for _, expression := range selector.MatchExpressions {
+		expression.Key = strings.TrimSpace(expression.Key)
+		switch expression.Operator {
+		case metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist:
+			expression.Values = nil
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn:
+			expression.Values = sortedUniqueValues(expression.Values)
+		}
+
+		if expression.Operator == metav1.LabelSelectorOpIn && len(expression.Values) == 1 {
+			if value, ok := matchLabels[expression.Key]; !ok || value == expression.Values[0] {
+				matchLabels[expression.Key] = expression.Values[0]
+				continue
+			}
+		}
+
+		id := expression.Key + "|" + string(expression.Operator) + "|" + strings.Join(expression.Values, ",")
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		expressions = append(expressions, expression)
+	}
+
+	if len(matchLabels) == 0 && len(expressions) == 0 {
+		return nil
+	}
+
+	normalized := &metav1.LabelSelector{MatchExpressions: expressions}
+	if len(matchLabels) > 0 {
+		normalized.MatchLabels = matchLabels
+	}
+	return normalized
+}
+
+func sortedUniqueValues(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	unique := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,236 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	byohv1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("ByoMachine Webhook", func() {
+
+	var (
+		defaultNamespace   = "default"
+		testByoMachineName = "test-byomachine-"
+		installerRef       = func() *corev1.ObjectReference {
+			return &corev1.ObjectReference{
+				Kind:       "K8sInstallerConfigTemplate",
+				Name:       "test-installer-config-template",
+				APIVersion: byohv1beta1.GroupVersion.String(),
+			}
+		}
+	)
+
+	Context("When ByoMachine gets a create request", func() {
+		var byoMachine *byohv1beta1.ByoMachine
+
+		BeforeEach(func() {
+			byoMachine = builder.ByoMachine(defaultNamespace, testByoMachineName).Build()
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, byoMachine)).Should(Succeed())
+		})
+
+		It("should default the installer ref namespace to the namespace of the ByoMachine", func() {
+			byoMachine.Spec.InstallerRef = installerRef()
+			Expect(k8sClient.Create(ctx, byoMachine)).Should(Succeed())
+
+			createdByoMachine := &byohv1beta1.ByoMachine{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachine), createdByoMachine)).Should(Succeed())
+			Expect(createdByoMachine.Spec.InstallerRef.Namespace).To(Equal(defaultNamespace))
+		})
+
+		It("should normalize the label selector", func() {
+			byoMachine.Spec.Selector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "site", Operator: metav1.LabelSelectorOpIn, Values: []string{"edge"}},
+					{Key: "zone", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"b", "a", "b"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, byoMachine)).Should(Succeed())
+
+			createdByoMachine := &byohv1beta1.ByoMachine{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachine), createdByoMachine)).Should(Succeed())
+			Expect(createdByoMachine.Spec.Selector).To(Equal(&metav1.LabelSelector{
+				MatchLabels: map[string]string{"site": "edge"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "zone", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a", "b"}},
+				},
+			}))
+		})
+
+		It("should drop an empty label selector", func() {
+			byoMachine.Spec.Selector = &metav1.LabelSelector{}
+			Expect(k8sClient.Create(ctx, byoMachine)).Should(Succeed())
+
+			createdByoMachine := &byohv1beta1.ByoMachine{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachine), createdByoMachine)).Should(Succeed())
+			Expect(createdByoMachine.Spec.Selector).To(BeNil())
+		})
+	})
+
+	Context("When ByoMachine has invalid kubelet extra args", func() {
+		It("should reject a flag passed with the leading dashes", func() {
+			byoMachine := builder.ByoMachine(defaultNamespace, testByoMachineName).Build()
+			byoMachine.Spec.KubeletExtraArgs = map[string]string{"--max-pods": "250"}
+
+			err := k8sClient.Create(ctx, byoMachine)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.kubeletExtraArgs: Invalid value: \"--max-pods\": must be a kubelet flag name without the leading dashes"))
+		})
+
+		It("should reject a flag managed by kubeadm", func() {
+			byoMachine := builder.ByoMachine(defaultNamespace, testByoMachineName).Build()
+			byoMachine.Spec.KubeletExtraArgs = map[string]string{"kubeconfig": "/tmp/kubeconfig"}
+
+			err := k8sClient.Create(ctx, byoMachine)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.kubeletExtraArgs[kubeconfig]: Forbidden: the flag is managed by kubeadm"))
+		})
+	})
+
+	Context("When ByoMachine has a host ref", func() {
+		It("should reject a host ref set along with the selector", func() {
+			byoMachine := builder.ByoMachine(defaultNamespace, testByoMachineName).
+				WithHostRef("host-1").
+				WithLabelSelector(map[string]string{"site": "edge"}).
+				Build()
+
+			err := k8sClient.Create(ctx, byoMachine)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.hostRef: Forbidden: cannot be set along with the selector"))
+		})
+
+		It("should reject a host ref that is not a valid name", func() {
+			byoMachine := builder.ByoMachine(defaultNamespace, testByoMachineName).WithHostRef("Host_1").Build()
+
+			err := k8sClient.Create(ctx, byoMachine)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.hostRef.name: Invalid value: \"Host_1\""))
+		})
+
+		It("should reject a change of the host ref", func() {
+			byoMachine := builder.ByoMachine(defaultNamespace, testByoMachineName).WithHostRef("host-1").Build()
+			Expect(k8sClient.Create(ctx, byoMachine)).Should(Succeed())
+
+			ph, err := patch.NewHelper(byoMachine, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoMachine.Spec.HostRef.Name = "host-2"
+			err = ph.Patch(ctx, byoMachine)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.hostRef: Forbidden: field is immutable"))
+			Expect(k8sClient.Delete(ctx, byoMachine)).Should(Succeed())
+		})
+	})
+
+	Context("When ByoMachineTemplate gets a create request", func() {
+		It("should default the installer ref namespace to the namespace of the ByoMachineTemplate", func() {
+			byoMachineTemplate := &byohv1beta1.ByoMachineTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-byomachinetemplate-",
+					Namespace:    defaultNamespace,
+				},
+				Spec: byohv1beta1.ByoMachineTemplateSpec{
+					Template: byohv1beta1.ByoMachineTemplateResource{
+						Spec: byohv1beta1.ByoMachineSpec{InstallerRef: installerRef()},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, byoMachineTemplate)).Should(Succeed())
+
+			createdByoMachineTemplate := &byohv1beta1.ByoMachineTemplate{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachineTemplate), createdByoMachineTemplate)).Should(Succeed())
+			Expect(createdByoMachineTemplate.Spec.Template.Spec.InstallerRef.Namespace).To(Equal(defaultNamespace))
+			Expect(k8sClient.Delete(ctx, byoMachineTemplate)).Should(Succeed())
+		})
+	})
+
+	Context("When ByoMachineTemplate gets an update request", func() {
+		var byoMachineTemplate *byohv1beta1.ByoMachineTemplate
+
+		BeforeEach(func() {
+			byoMachineTemplate = &byohv1beta1.ByoMachineTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test-byomachinetemplate-",
+					Namespace:    defaultNamespace,
+				},
+				Spec: byohv1beta1.ByoMachineTemplateSpec{
+					Template: byohv1beta1.ByoMachineTemplateResource{
+						Spec: byohv1beta1.ByoMachineSpec{InstallerRef: installerRef()},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, byoMachineTemplate)).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(k8sClient.Delete(ctx, byoMachineTemplate)).Should(Succeed())
+		})
+
+		It("should reject the request if spec.template.spec is changed", func() {
+			ph, err := patch.NewHelper(byoMachineTemplate, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoMachineTemplate.Spec.Template.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{"site": "edge"},
+			}
+			err = ph.Patch(ctx, byoMachineTemplate)
+			Expect(err).To(MatchError("admission webhook \"vbyomachinetemplate.kb.io\" denied the request: spec.template.spec: Forbidden: ByoMachineTemplate spec.template.spec field is immutable, please create a new resource instead"))
+		})
+
+		It("should accept the request if the change is removed by defaulting", func() {
+			ph, err := patch.NewHelper(byoMachineTemplate, k8sClient)
+			Expect(err).ShouldNot(HaveOccurred())
+			byoMachineTemplate.Spec.Template.Spec.Selector = &metav1.LabelSelector{}
+			byoMachineTemplate.Labels = map[string]string{"site": "edge"}
+			Expect(ph.Patch(ctx, byoMachineTemplate)).Should(Succeed())
+		})
+
+		It("should accept a dry-run request from the topology controller", func() {
+			updatedTemplate := byoMachineTemplate.DeepCopy()
+			updatedTemplate.Annotations = map[string]string{clusterv1.TopologyDryRunAnnotation: ""}
+			updatedTemplate.Spec.Template.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{"site": "edge"},
+			}
+			Expect(k8sClient.Update(ctx, updatedTemplate, client.DryRunAll)).Should(Succeed())
+		})
+	})
+})
+
+var _ = Describe("NormalizeLabelSelector", func() {
+	It("should return nil for a nil selector", func() {
+		Expect(byohv1beta1.NormalizeLabelSelector(nil)).To(BeNil())
+	})
+
+	It("should drop values from Exists requirements and deduplicate requirements", func() {
+		selector := &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "gpu", Operator: metav1.LabelSelectorOpExists, Values: []string{"true"}},
+				{Key: " gpu ", Operator: metav1.LabelSelectorOpExists},
+			},
+		}
+		Expect(byohv1beta1.NormalizeLabelSelector(selector)).To(Equal(&metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "gpu", Operator: metav1.LabelSelectorOpExists},
+			},
+		}))
+	})
+
+	It("should keep a single valued In requirement that conflicts with MatchLabels", func() {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"site": "edge"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "site", Operator: metav1.LabelSelectorOpIn, Values: []string{"core"}},
+			},
+		}
+		Expect(byohv1beta1.NormalizeLabelSelector(selector)).To(Equal(selector))
+	})
+})
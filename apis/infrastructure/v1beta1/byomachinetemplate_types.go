@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
@@ -20,6 +21,7 @@ type ByoMachineTemplateStatus struct {
 }
 
 //+kubebuilder:object:root=true
+//+kubebuilder:resource:path=byomachinetemplates,scope=Namespaced,shortName=byomt
 //+kubebuilder:subresource:status
 
 // ByoMachineTemplate is the Schema for the byomachinetemplates API
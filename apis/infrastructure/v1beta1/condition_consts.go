@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
@@ -40,10 +41,19 @@ const (
 	// that are part of the cloud-config file
 	CloudInitExecutionFailedReason = "CloudInitExecutionFailed"
 
+	// BootstrapDataInvalidReason indicates that the agent validates the bootstrap data strictly and
+	// the cloud-config file does not match the schema of the directives the agent implements
+	BootstrapDataInvalidReason = "BootstrapDataInvalid"
+
 	// K8sNodeAbsentReason indicates that the node is not a Kubernetes node
 	// This is usually set after executing kubeadm reset on the node
 	K8sNodeAbsentReason = "K8sNodeAbsent"
 
+	// K8sNodeReleasedWithoutResetReason indicates that the host was released from its cluster
+	// without kubeadm reset nor uninstall because of the SkipUninstallAnnotation, its kubelet is
+	// stopped and its node is reset on the next attach
+	K8sNodeReleasedWithoutResetReason = "K8sNodeReleasedWithoutReset"
+
 	// K8sComponentsInstallingReason indicates that the k8s components are being
 	// downloaded and installed
 	// TODO unused, remove it
@@ -52,6 +62,54 @@ const (
 	// K8sComponentsInstallationFailedReason indicates that the installer failed to install all the
 	// k8s components on this host
 	K8sComponentsInstallationFailedReason = "K8sComponentsInstallationFailed"
+
+	// DiskSpaceAvailable documents if the filesystems of the paths critical to the host
+	// have enough free space. This condition is managed by the host agent.
+	DiskSpaceAvailable clusterv1.ConditionType = "DiskSpaceAvailable"
+
+	// TimeSynchronized documents if the system clock of the host is synchronized,
+	// e.g. by NTP. This condition is managed by the host agent.
+	TimeSynchronized clusterv1.ConditionType = "TimeSynchronized"
+
+	// AgentCertificateValid documents if the client certificate the host agent uses to
+	// authenticate against the management cluster is far enough from its expiry.
+	// This condition is managed by the host agent.
+	AgentCertificateValid clusterv1.ConditionType = "AgentCertificateValid"
+
+	// AgentConnected documents if the host agent is reporting heartbeats. The host agent
+	// marks it true with every heartbeat, the controller manager marks it false once the
+	// heartbeats are stale.
+	AgentConnected clusterv1.ConditionType = "AgentConnected"
+
+	// Quarantined documents if the host is quarantined after failing to bootstrap the nodes of too many
+	// consecutive ByoMachines. This condition is managed by the controller manager, it is removed once
+	// an operator removes the QuarantinedLabel of the host.
+	Quarantined clusterv1.ConditionType = "Quarantined"
+
+	// DiskPressureReason indicates that the free space on the filesystem of at least one
+	// critical path of the host is below the threshold
+	DiskPressureReason = "DiskPressure"
+
+	// TimeNotSynchronizedReason indicates that the kernel reports the system clock as not synchronized
+	TimeNotSynchronizedReason = "TimeNotSynchronized"
+
+	// TimeSyncStatusUnknownReason indicates that the host agent failed to query the clock synchronization status
+	TimeSyncStatusUnknownReason = "TimeSyncStatusUnknown"
+
+	// AgentCertificateExpiringReason indicates that less than 20% of the lifetime of the agent
+	// certificate is left and it is due for rotation
+	AgentCertificateExpiringReason = "AgentCertificateExpiring"
+
+	// AgentCertificateExpiredReason indicates that the agent certificate has expired
+	AgentCertificateExpiredReason = "AgentCertificateExpired"
+
+	// AgentHeartbeatStaleReason indicates that the host agent has not reported a heartbeat
+	// for longer than the heartbeat timeout of the controller manager
+	AgentHeartbeatStaleReason = "AgentHeartbeatStale"
+
+	// RepeatedBootstrapFailuresReason indicates that the host was released by too many consecutive
+	// ByoMachines without having bootstrapped their node
+	RepeatedBootstrapFailuresReason = "RepeatedBootstrapFailures"
 )
 
 // Conditions and Reasons defined on BYOMachine
@@ -60,6 +118,10 @@ const (
 	// BYOHostReady documents the k8s node is ready and can take on workloads
 	BYOHostReady clusterv1.ConditionType = "BYOHostReady"
 
+	// HostRefResolved documents if the ByoHost named by the hostRef of the ByoMachine has been
+	// attached to it. The condition is only set on ByoMachines with a hostRef.
+	HostRefResolved clusterv1.ConditionType = "HostRefResolved"
+
 	// WaitingForClusterInfrastructureReason indicates the cluster that the ByoMachine belongs to
 	// is waiting to be owned by the corresponding CAPI Cluster
 	WaitingForClusterInfrastructureReason = "WaitingForClusterInfrastructure"
@@ -72,11 +134,107 @@ const (
 	// BYOHostsUnavailableReason indicates that no byohosts are available in the capacity pool
 	BYOHostsUnavailableReason = "BYOHostsUnavailable"
 
+	// InsufficientHostResourcesReason indicates that byohosts are available in the capacity pool
+	// but none of them reports enough capacity to satisfy the resource requirements of the ByoMachine
+	InsufficientHostResourcesReason = "InsufficientHostResources"
+
+	// HostsNotAdmittedReason indicates that byohosts are available in the capacity pool
+	// but none of them is admitted by the ByoHostAdmissionPolicies of the cluster of the ByoMachine
+	HostsNotAdmittedReason = "HostsNotAdmitted"
+
+	// HostRefNotFoundReason indicates that the ByoHost named by the hostRef of the ByoMachine
+	// does not exist
+	HostRefNotFoundReason = "HostRefNotFound"
+
+	// HostRefUnavailableReason indicates that the ByoHost named by the hostRef of the ByoMachine
+	// cannot be attached, e.g. because it is attached to another machine or is unschedulable
+	HostRefUnavailableReason = "HostRefUnavailable"
+
+	// HostRefNotAdmittedReason indicates that the ByoHost named by the hostRef of the ByoMachine
+	// is not admitted by the ByoHostAdmissionPolicies of the cluster of the ByoMachine
+	HostRefNotAdmittedReason = "HostRefNotAdmitted"
+
+	// BootstrapTokenExpiringReason indicates that the bootstrap token of the bootstrap data of the
+	// Machine expires too soon for a host to join with it, and that its refresh has been requested
+	BootstrapTokenExpiringReason = "BootstrapTokenExpiring"
+
 	// InstallationSecretNotAvailableReason indicates that the installation secret is not yet
 	// generated for a given BYOMachine
 	InstallationSecretNotAvailableReason = "InstallationSecretNotAvailable"
 )
 
+// Conditions and Reasons defined on K8sInstallerConfig
+const (
+
+	// BundleResolved documents if an installer supporting the OS, architecture and Kubernetes
+	// version of the host has been resolved for the bundle
+	BundleResolved clusterv1.ConditionType = "BundleResolved"
+
+	// SecretGenerated documents if the installation and uninstallation secrets have been generated
+	SecretGenerated clusterv1.ConditionType = "SecretGenerated"
+
+	// InstallationSecretUpToDate documents if the installation secret was generated from the
+	// current spec of the K8sInstallerConfig. It is false when the spec changed since.
+	InstallationSecretUpToDate clusterv1.ConditionType = "InstallationSecretUpToDate"
+
+	// InstallerDryRunSucceeded documents if the scripts requested by the InstallerDryRunAnnotation
+	// have been rendered into the dry-run secret. It is removed along with the annotation.
+	InstallerDryRunSucceeded clusterv1.ConditionType = "InstallerDryRunSucceeded"
+
+	// WaitingForOwnerByoMachineReason indicates that the ByoMachine controller is yet to set
+	// itself as owner of the K8sInstallerConfig
+	WaitingForOwnerByoMachineReason = "WaitingForOwnerByoMachine"
+
+	// WaitingForInstallationRequestReason indicates that the owner ByoMachine is not waiting
+	// for an installation secret yet, e.g. because no ByoHost is attached to it
+	WaitingForInstallationRequestReason = "WaitingForInstallationRequest"
+
+	// BundleResolutionFailedReason indicates that no installer supports the OS, architecture
+	// and Kubernetes version of the host
+	BundleResolutionFailedReason = "BundleResolutionFailed"
+
+	// VersionSkewNotSupportedReason indicates that the Kubernetes version of the host is newer
+	// than the control plane, or older than the version skew policy allows
+	VersionSkewNotSupportedReason = "VersionSkewNotSupported"
+
+	// SecretGenerationFailedReason indicates that creating or updating the installation or
+	// uninstallation secret failed
+	SecretGenerationFailedReason = "SecretGenerationFailed"
+
+	// TemplatesUnavailableReason indicates that the ConfigMap overriding the installer templates
+	// could not be read or holds keys that are neither a template nor a hook
+	TemplatesUnavailableReason = "TemplatesUnavailable"
+
+	// SpecChangedAfterGenerationReason indicates that the spec of the K8sInstallerConfig changed
+	// after the installation secret was generated
+	SpecChangedAfterGenerationReason = "SpecChangedAfterGeneration"
+
+	// InstallerDryRunFailedReason indicates that the InstallerDryRunAnnotation could not be decoded,
+	// or that rendering the scripts or writing the dry-run secret failed
+	InstallerDryRunFailedReason = "InstallerDryRunFailed"
+)
+
+// Conditions and Reasons defined on BootstrapKubeconfig
+const (
+
+	// TokenGenerated documents if a bootstrap token has been generated and the bootstrap
+	// kubeconfig authenticating with it is available in the status
+	TokenGenerated clusterv1.ConditionType = "TokenGenerated"
+
+	// TokenValid documents if the bootstrap token can still be used to register hosts
+	TokenValid clusterv1.ConditionType = "TokenValid"
+
+	// TokenExpiredReason indicates that the bootstrap token passed its expiration
+	TokenExpiredReason = "Expired"
+
+	// TokenRevokedReason indicates that the bootstrap token secret was deleted before the token expired
+	TokenRevokedReason = "Revoked"
+
+	// TokenGenerationFailedReason indicates that generating the bootstrap token, its secret or
+	// the bootstrap kubeconfig failed
+	TokenGenerationFailedReason = "TokenGenerationFailed"
+)
+
 // Reasons common to all Byo Resources
 const (
 
@@ -1,11 +1,15 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1beta1
 
 import (
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 const (
@@ -13,15 +17,117 @@ const (
 	// resources associated with K8sInstallerConfig before removing it from the
 	// API Server.
 	K8sInstallerConfigFinalizer = "k8sinstallerconfig.infrastructure.cluster.x-k8s.io"
+
+	// InstallerDryRunAnnotation asks for the install and uninstall scripts of a K8sInstallerConfig to be
+	// rendered, without running them, into the byoh-dry-run-<name> secret. Its value is a JSON object
+	// with the osimage and architecture of the target host, and optionally the k8sversion of the bundle.
+	InstallerDryRunAnnotation = "byoh.infrastructure.cluster.x-k8s.io/installer-dry-run"
 )
 
 // K8sInstallerConfigSpec defines the desired state of K8sInstallerConfig
 type K8sInstallerConfigSpec struct {
-	// BundleRepo is the OCI registry from which the carvel imgpkg bundle will be downloaded
+	// BundleRepo is the OCI registry from which the carvel imgpkg bundle will be downloaded.
+	// When it does not include a registry host, it is resolved relative to BundleRegistry.
 	BundleRepo string `json:"bundleRepo"`
 
 	// BundleType is the type of bundle (e.g. k8s) that needs to be downloaded
 	BundleType string `json:"bundleType"`
+
+	// BundleRegistry is the registry host (and optional path) a relative BundleRepo is resolved against.
+	// When unset, it is filled in from the ByoCluster the config is generated for.
+	// +optional
+	BundleRegistry string `json:"bundleRegistry,omitempty"`
+
+	// BundlePath is the path on the host of a pre-seeded bundle, either a directory or a tarball
+	// of the bundle contents, for hosts without access to a registry. When set, the bundle is
+	// copied from there instead of being pulled from BundleRepo, which then only names it.
+	// +optional
+	BundlePath string `json:"bundlePath,omitempty"`
+
+	// CRI is the container runtime installed on the host, either containerd or cri-o
+	// +kubebuilder:validation:Enum=containerd;cri-o
+	// +kubebuilder:default=containerd
+	// +optional
+	CRI string `json:"cri,omitempty"`
+
+	// ContainerdVersion overrides the containerd version pinned for the k8s version, e.g. 1.7.22.
+	// The install script fails if the bundle ships another version.
+	// +kubebuilder:validation:Pattern=`^[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	ContainerdVersion string `json:"containerdVersion,omitempty"`
+
+	// HTTPProxy is the proxy used for HTTP requests made by the installer and the container runtime
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+
+	// HTTPSProxy is the proxy used for HTTPS requests made by the installer and the container runtime
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+
+	// NoProxy is a comma separated list of hosts, domains and CIDRs that must bypass the proxy
+	// +optional
+	NoProxy string `json:"noProxy,omitempty"`
+
+	// RegistryMirrors configures the mirrors and TLS settings the container runtime uses
+	// when pulling images from the listed registries
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+
+	// RuncVersion overrides the runc version pinned for the k8s version, e.g. 1.1.14.
+	// The install script fails if the bundle ships another version.
+	// +kubebuilder:validation:Pattern=`^[0-9]+\.[0-9]+\.[0-9]+$`
+	// +optional
+	RuncVersion string `json:"runcVersion,omitempty"`
+
+	// SwapPolicy is what the installer does with the swap of the host: disable turns it off,
+	// keep leaves it enabled and starts the kubelet with failSwapOn set to false
+	// +kubebuilder:validation:Enum=disable;keep
+	// +kubebuilder:default=disable
+	// +optional
+	SwapPolicy string `json:"swapPolicy,omitempty"`
+
+	// TemplatesConfigMapRef references a ConfigMap, in the namespace of the K8sInstallerConfig, that
+	// overrides the install and uninstall templates. Its install and uninstall keys replace a template
+	// fully, its pre-install, post-install, pre-uninstall and post-uninstall keys are snippets run at
+	// these points of the scripts. They are Go templates given the same data as the embedded ones.
+	// +optional
+	TemplatesConfigMapRef *corev1.LocalObjectReference `json:"templatesConfigMapRef,omitempty"`
+}
+
+// RegistryMirror configures how the container runtime pulls images from a registry
+type RegistryMirror struct {
+	// Registry is the host (and optional port) of the registry being configured, e.g. docker.io
+	// +kubebuilder:validation:MinLength=1
+	Registry string `json:"registry"`
+
+	// Endpoints are the URLs of the mirrors to pull images of the registry from, tried in order
+	// +kubebuilder:validation:items:Pattern=`^https?://`
+	// +optional
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Insecure skips TLS verification when pulling from the registry and its mirrors
+	// +optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// BundleRepoAddr returns the address the bundle is downloaded from. BundleRepo is used as is
+// when it already names a registry host, otherwise it is prefixed with BundleRegistry.
+func (spec *K8sInstallerConfigSpec) BundleRepoAddr() string {
+	if spec.BundleRegistry == "" || hasRegistryHost(spec.BundleRepo) {
+		return spec.BundleRepo
+	}
+	return strings.TrimSuffix(spec.BundleRegistry, "/") + "/" + strings.TrimPrefix(spec.BundleRepo, "/")
+}
+
+// hasRegistryHost reports whether the first component of an image repository is a
+// registry host, following the same rules as docker image references
+func hasRegistryHost(repo string) bool {
+	i := strings.Index(repo, "/")
+	if i < 0 {
+		return false
+	}
+	host := repo[:i]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
 }
 
 // K8sInstallerConfigStatus defines the observed state of K8sInstallerConfig
@@ -37,10 +143,21 @@ type K8sInstallerConfigStatus struct {
 	// UninstallationSecret is an optional reference to a generated uninstallation secret by K8sInstallerConfig controller
 	// +optional
 	UninstallationSecret *corev1.ObjectReference `json:"uninstallationSecret,omitempty"`
+
+	// ObservedGeneration is the generation of the K8sInstallerConfig the installation secret was generated from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions documents the progress of the generation of the installation secret
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="BundleType",type="string",JSONPath=`.spec.bundleType`
+//+kubebuilder:printcolumn:name="Ready",type="boolean",JSONPath=`.status.ready`,description="Indicates if the installation secret is ready"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=`.metadata.creationTimestamp`
 
 // K8sInstallerConfig is the Schema for the k8sinstallerconfigs API
 type K8sInstallerConfig struct {
@@ -51,6 +168,16 @@ type K8sInstallerConfig struct {
 	Status K8sInstallerConfigStatus `json:"status,omitempty"`
 }
 
+// GetConditions returns the conditions of K8sInstallerConfig status
+func (config *K8sInstallerConfig) GetConditions() clusterv1.Conditions {
+	return config.Status.Conditions
+}
+
+// SetConditions sets the conditions of K8sInstallerConfig status
+func (config *K8sInstallerConfig) SetConditions(conditions clusterv1.Conditions) {
+	config.Status.Conditions = conditions
+}
+
 //+kubebuilder:object:root=true
 
 // K8sInstallerConfigList contains a list of K8sInstallerConfig
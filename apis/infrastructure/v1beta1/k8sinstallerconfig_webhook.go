@@ -0,0 +1,211 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1beta1
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var k8sinstallerconfiglog = logf.Log.WithName("k8sinstallerconfig-resource")
+
+// BundleTypeK8s is the bundle type of the k8s installation bundle
+const BundleTypeK8s = "k8s"
+
+// bundlePathRegex matches the absolute paths accepted for a pre-seeded bundle
+var bundlePathRegex = regexp.MustCompile(`^/[A-Za-z0-9._/-]*$`)
+
+// proxyURLRegex matches the characters accepted in a proxy URL, leaving out quotes, whitespace
+// and the characters the shell expands
+var proxyURLRegex = regexp.MustCompile(`^[A-Za-z0-9.:/@%_~+=-]+$`)
+
+// noProxyRegex matches a comma separated list of hosts, domains, IPs and CIDRs
+var noProxyRegex = regexp.MustCompile(`^[A-Za-z0-9.,:/*_\[\]-]*$`)
+
+// repositoryNameRegex matches an OCI repository name, an optional registry host followed by path
+// components, as parsed by the distribution reference grammar
+var repositoryNameRegex = regexp.MustCompile(`^` + reference.NameRegexp.String() + `$`)
+
+// registryHostRegex matches a registry host with an optional port, e.g. registry.example.com:5000
+var registryHostRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]{1,5})?$`)
+
+// mirrorEndpointRegex matches the characters accepted in a mirror endpoint URL, leaving out
+// credentials, queries, quotes, whitespace and the characters the shell expands
+var mirrorEndpointRegex = regexp.MustCompile(`^[A-Za-z0-9.:/_~%-]+$`)
+
+// supportedBundleTypes lists the bundle types the installer knows how to download
+var supportedBundleTypes = []string{BundleTypeK8s}
+
+func (r *K8sInstallerConfig) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-k8sinstallerconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=k8sinstallerconfigs,verbs=create;update,versions=v1beta1,name=vk8sinstallerconfig.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &K8sInstallerConfig{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfig) ValidateCreate() error {
+	k8sinstallerconfiglog.Info("validate create", "name", r.Name)
+
+	return r.Spec.validate(field.NewPath("spec"))
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfig) ValidateUpdate(old runtime.Object) error {
+	k8sinstallerconfiglog.Info("validate update", "name", r.Name)
+
+	return r.Spec.validate(field.NewPath("spec"))
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfig) ValidateDelete() error {
+	k8sinstallerconfiglog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (r *K8sInstallerConfigTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-k8sinstallerconfigtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=k8sinstallerconfigtemplates,verbs=create;update,versions=v1beta1,name=vk8sinstallerconfigtemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &K8sInstallerConfigTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfigTemplate) ValidateCreate() error {
+	k8sinstallerconfiglog.Info("validate create", "name", r.Name)
+
+	return r.Spec.Template.Spec.validate(field.NewPath("spec", "template", "spec"))
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfigTemplate) ValidateUpdate(old runtime.Object) error {
+	k8sinstallerconfiglog.Info("validate update", "name", r.Name)
+
+	return r.Spec.Template.Spec.validate(field.NewPath("spec", "template", "spec"))
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *K8sInstallerConfigTemplate) ValidateDelete() error {
+	k8sinstallerconfiglog.Info("validate delete", "name", r.Name)
+
+	return nil
+}
+
+func (spec *K8sInstallerConfigSpec) validate(specPath *field.Path) error {
+	if err := spec.validateBundle(specPath); err != nil {
+		return err
+	}
+	if err := spec.validateProxy(specPath); err != nil {
+		return err
+	}
+	return validateRegistryMirrors(specPath.Child("registryMirrors"), spec.RegistryMirrors)
+}
+
+func (spec *K8sInstallerConfigSpec) validateBundle(specPath *field.Path) error {
+	if spec.BundleRepo == "" {
+		return field.Required(specPath.Child("bundleRepo"), "bundleRepo field cannot be empty")
+	}
+	if !repositoryNameRegex.MatchString(spec.BundleRepo) {
+		return field.Invalid(specPath.Child("bundleRepo"), spec.BundleRepo, "bundleRepo must be an image repository without a URL scheme")
+	}
+
+	if !isSupportedBundleType(spec.BundleType) {
+		return field.NotSupported(specPath.Child("bundleType"), spec.BundleType, supportedBundleTypes)
+	}
+
+	if err := validateBundlePath(specPath.Child("bundlePath"), spec.BundlePath); err != nil {
+		return err
+	}
+
+	return validateBundleRegistry(specPath.Child("bundleRegistry"), spec.BundleRegistry)
+}
+
+// validateBundlePath checks that path is an absolute path made of characters that are safe
+// to render into the install script. An empty path is valid.
+func validateBundlePath(fldPath *field.Path, path string) error {
+	if path == "" {
+		return nil
+	}
+	if !bundlePathRegex.MatchString(path) {
+		return field.Invalid(fldPath, path, "bundlePath must be an absolute path made of letters, digits, '.', '_', '-' and '/'")
+	}
+	return nil
+}
+
+// validateProxy checks that the proxy settings, which are rendered into the install and
+// uninstall scripts, are http(s) URLs and a plain list of hosts, domains and CIDRs
+func (spec *K8sInstallerConfigSpec) validateProxy(specPath *field.Path) error {
+	proxies := []struct{ name, value string }{{"httpProxy", spec.HTTPProxy}, {"httpsProxy", spec.HTTPSProxy}}
+	for _, proxy := range proxies {
+		if proxy.value == "" {
+			continue
+		}
+		u, err := url.Parse(proxy.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || !proxyURLRegex.MatchString(proxy.value) {
+			return field.Invalid(specPath.Child(proxy.name), proxy.value, proxy.name+" must be an http or https URL, e.g. http://proxy.example.com:3128")
+		}
+	}
+	if !noProxyRegex.MatchString(spec.NoProxy) {
+		return field.Invalid(specPath.Child("noProxy"), spec.NoProxy, "noProxy must be a comma separated list of hosts, domains and CIDRs")
+	}
+	return nil
+}
+
+// validateRegistryMirrors checks that the registries of the mirrors, which are rendered into the
+// install script, are hosts with an optional port and that their endpoints are http(s) URLs
+func validateRegistryMirrors(fldPath *field.Path, mirrors []RegistryMirror) error {
+	for i, mirror := range mirrors {
+		mirrorPath := fldPath.Index(i)
+		if !registryHostRegex.MatchString(mirror.Registry) {
+			return field.Invalid(mirrorPath.Child("registry"), mirror.Registry, "registry must be a registry host with an optional port, e.g. registry.example.com:5000")
+		}
+		for j, endpoint := range mirror.Endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || !mirrorEndpointRegex.MatchString(endpoint) {
+				return field.Invalid(mirrorPath.Child("endpoints").Index(j), endpoint, "endpoint must be an http or https URL, e.g. https://mirror.example.com:5000")
+			}
+		}
+	}
+	return nil
+}
+
+func isSupportedBundleType(bundleType string) bool {
+	for _, t := range supportedBundleTypes {
+		if bundleType == t {
+			return true
+		}
+	}
+	return false
+}
+
+// validateBundleRegistry checks that registry is a registry host with an optional path that
+// the bundle repository can be appended to, e.g. quay.io/platform9. An empty registry is valid.
+func validateBundleRegistry(fldPath *field.Path, registry string) error {
+	if registry == "" {
+		return nil
+	}
+	if strings.Contains(registry, "://") {
+		return field.Invalid(fldPath, registry, "bundleRegistry must not contain a URL scheme")
+	}
+	if !repositoryNameRegex.MatchString(registry) {
+		return field.Invalid(fldPath, registry, "bundleRegistry must be a registry host with an optional path, e.g. quay.io/platform9")
+	}
+	return nil
+}
@@ -2,37 +2,173 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 )
 
+// Transport carries the requests to dex and the oidc-proxy of the management plane,
+// tests replace it to reach a fake management plane
+var Transport http.RoundTripper = http.DefaultTransport
+
+// loggingTransport logs the requests to the management plane to the debug log, redacted like all the logs
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+func (t loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		utils.LogDebug("%s %s failed after %s: %v", req.Method, req.URL, time.Since(start), err)
+		return nil, err
+	}
+	utils.LogDebug("%s %s returned %s in %s", req.Method, req.URL, resp.Status, time.Since(start))
+	return resp, nil
+}
+
+// retryTransport retries the idempotent requests to the management plane that fail on the network
+// or with a status a retry can fix, with the backoff of utils.NetworkBackoff
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		// the management plane may have applied the request even when its answer was lost
+		return t.next.RoundTrip(req)
+	}
+	var resp *http.Response
+	attempt := 0
+	err := utils.Retry(req.Context(), utils.NetworkBackoff, req.Method+" "+req.URL.Redacted(), func() error {
+		if resp != nil {
+			// the response of the previous attempt is not returned
+			io.Copy(io.Discard, resp.Body) //nolint:errcheck
+			resp.Body.Close()
+			resp = nil
+		}
+		attempt++
+		r := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return utils.Permanent(fmt.Errorf("the body of %s %s cannot be sent again", req.Method, req.URL.Redacted()))
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return utils.Permanent(err)
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		var err error
+		if resp, err = t.next.RoundTrip(r); err != nil {
+			return err
+		}
+		if retriableStatus(resp.StatusCode) {
+			return fmt.Errorf("%s %s returned %s", req.Method, req.URL.Redacted(), resp.Status)
+		}
+		return nil
+	})
+	if resp != nil {
+		// the response of the last attempt, even with a status to retry
+		return resp, nil
+	}
+	return nil, err
+}
+
+// idempotent returns whether req may be sent again: its method is idempotent or, like net/http
+// does, it carries an Idempotency-Key or X-Idempotency-Key header
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	_, ok := req.Header["X-Idempotency-Key"]
+	return ok
+}
+
+// markIdempotent lets retryTransport retry req, a request without side effects whose method is
+// not idempotent. The nil Idempotency-Key header is not sent.
+func markIdempotent(req *http.Request) {
+	req.Header["Idempotency-Key"] = nil
+}
+
+// retriableStatus returns whether a request that failed with the status code may succeed when retried
+func retriableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
 type AuthClient struct {
 	client      *http.Client
 	fqdn        string
 	clientToken string
+	// totp is the TOTP code answering the second factor challenge of the password grant
+	totp string
+	// totpPrompt asks for the TOTP code if the password grant is challenged and totp is empty,
+	// nil if it can not be asked for
+	totpPrompt func() (string, error)
 }
 
 func NewAuthClient(fqdn, clientToken string) *AuthClient {
 	return &AuthClient{
-		client:      &http.Client{Timeout: 30 * time.Second},
+		client:      &http.Client{Timeout: HTTPTimeout, Transport: retryTransport{loggingTransport{Transport}}},
 		fqdn:        fqdn,
 		clientToken: clientToken,
 	}
 }
 
+// mfaRequiredError is the OAuth error dex answers the password grant of an account with a second
+// factor with. The grant is then repeated with the TOTP code of the account in the totp field and
+// the mfa_token of the answer.
+const mfaRequiredError = "mfa_required"
+
+// mfaChallenge is the second factor challenge dex answered a password grant with
+type mfaChallenge struct {
+	mfaToken    string
+	description string
+}
+
+func (c *mfaChallenge) Error() string {
+	return fmt.Sprintf("%s: %s", mfaRequiredError, c.description)
+}
+
+// Unwrap classifies a challenge that is not answered as an authentication failure
+func (c *mfaChallenge) Unwrap() error {
+	return types.ErrAuth
+}
+
+// SetTOTP sets the TOTP code answering the second factor challenge of the password grant, or the
+// prompt asking the user for it when code is empty
+func (c *AuthClient) SetTOTP(code string, prompt func() (string, error)) {
+	c.totp, c.totpPrompt = code, prompt
+}
+
+// tokenRefreshMargin is how long before their expiry the tokens of a TokenSource are requested again
+const tokenRefreshMargin = time.Minute
+
+// TokenSource returns the bearer token of the requests to the management plane
+type TokenSource func() (string, error)
+
 func (c *AuthClient) GetToken(username, password string) (string, error) {
 	start := time.Now()
 	defer utils.TrackTime(start, "Token retrieval")
 
 	utils.LogDebug("Getting authentication token for user %s", username)
-	tokenEndpoint := fmt.Sprintf("https://%s/dex/token", c.fqdn)
 	formData := url.Values{
 		"grant_type":    {"password"},
 		"client_id":     {"kubernetes"},
@@ -41,33 +177,138 @@ func (c *AuthClient) GetToken(username, password string) (string, error) {
 		"password":      {password},
 		"scope":         {"openid offline_access groups federated:id email"},
 	}
+	tokenResp, err := c.requestToken(formData)
+	var challenge *mfaChallenge
+	if errors.As(err, &challenge) {
+		utils.LogDebug("User %s is challenged for a second factor: %s", username, challenge.description)
+		code := c.totp
+		if code == "" && c.totpPrompt != nil {
+			if code, err = c.totpPrompt(); err != nil {
+				return "", err
+			}
+		}
+		if code == "" {
+			return "", utils.LogErrorf("%w: %w for user %s, pass the TOTP code with --totp", types.ErrAuth, types.ErrMFARequired, username)
+		}
+		formData.Set("totp", code)
+		if challenge.mfaToken != "" {
+			formData.Set("mfa_token", challenge.mfaToken)
+		}
+		tokenResp, err = c.requestToken(formData)
+		if errors.As(err, &challenge) {
+			return "", utils.LogErrorf("%w: the TOTP code of user %s was rejected", types.ErrAuth, username)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	utils.LogInfo("Successfully obtained authentication token")
+	return tokenResp.IDToken, nil
+}
+
+// GetClientCredentialsToken returns a token of the client credentials grant of the client clientID
+// of dex, for service accounts and application credentials authenticating without a user, and when
+// it expires
+func (c *AuthClient) GetClientCredentialsToken(clientID, clientSecret string) (string, time.Time, error) {
+	start := time.Now()
+	defer utils.TrackTime(start, "Token retrieval")
+
+	utils.LogDebug("Getting authentication token for client %s", clientID)
+	tokenResp, err := c.requestToken(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"scope":         {"openid groups federated:id email"},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
 
+	// the oidc-proxy authenticates id tokens, dex issues access tokens only without the openid scope
+	token := tokenResp.IDToken
+	if token == "" {
+		token = tokenResp.AccessToken
+	}
+	if token == "" {
+		return "", time.Time{}, utils.LogErrorf("%w: no token in the response of the client credentials grant", types.ErrAuth)
+	}
+	// the expiry is zero if neither the response nor the token tell it
+	expiry, _ := tokenExpiry(token)
+	if tokenResp.ExpiresIn > 0 {
+		expiry = start.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+
+	utils.LogInfo("Successfully obtained authentication token for client %s", clientID)
+	return token, expiry, nil
+}
+
+// ClientCredentialsSource returns a TokenSource of the tokens of the client credentials grant of
+// the client clientID, a token is requested again shortly before it expires
+func (c *AuthClient) ClientCredentialsSource(clientID, clientSecret string) TokenSource {
+	var (
+		mu     sync.Mutex
+		token  string
+		expiry time.Time
+	)
+	return func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// a token without expiry is kept for the whole command
+		if token != "" && (expiry.IsZero() || time.Until(expiry) > tokenRefreshMargin) {
+			return token, nil
+		}
+		if token != "" {
+			utils.LogDebug("Refreshing the authentication token of client %s expiring at %s", clientID, expiry.Format(time.RFC3339))
+		}
+		newToken, newExpiry, err := c.GetClientCredentialsToken(clientID, clientSecret)
+		if err != nil {
+			return "", err
+		}
+		token, expiry = newToken, newExpiry
+		return token, nil
+	}
+}
+
+// requestToken requests a token of the grant of formData from dex
+func (c *AuthClient) requestToken(formData url.Values) (types.TokenResponse, error) {
+	var tokenResp types.TokenResponse
+	tokenEndpoint := fmt.Sprintf("https://%s/dex/token", c.fqdn)
 	req, err := http.NewRequest("POST", tokenEndpoint, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return "", utils.LogErrorf("failed to create authentication request: %v", err)
+		return tokenResp, utils.LogErrorf("failed to create authentication request: %w", err)
 	}
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	// a TOTP code is accepted once, the grants without one can be requested again
+	if formData.Get("totp") == "" {
+		markIdempotent(req)
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", utils.LogErrorf("failed to authenticate: %v", err)
+		return tokenResp, utils.LogErrorf("failed to authenticate: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", utils.LogErrorf("failed to read authentication response: %v", err)
+		return tokenResp, utils.LogErrorf("failed to read authentication response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", utils.LogErrorf("authentication failed with status %d: %s", resp.StatusCode, string(body))
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			MFAToken    string `json:"mfa_token"`
+		}
+		if json.Unmarshal(body, &oauthErr) == nil && oauthErr.Error == mfaRequiredError {
+			return tokenResp, &mfaChallenge{mfaToken: oauthErr.MFAToken, description: oauthErr.Description}
+		}
+		return tokenResp, utils.LogErrorf("%w with status %d: %s", types.ErrAuth, resp.StatusCode, string(body))
 	}
 
-	var tokenResp types.TokenResponse
 	if err := json.Unmarshal(body, &tokenResp); err != nil {
-		return "", utils.LogErrorf("failed to parse authentication response: %v", err)
+		return tokenResp, utils.LogErrorf("failed to parse authentication response: %w", err)
 	}
-
-	utils.LogSuccess("Successfully obtained authentication token")
-	return tokenResp.IDToken, nil
+	return tokenResp, nil
 }
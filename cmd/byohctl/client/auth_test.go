@@ -4,6 +4,7 @@ package client
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 )
 
 func TestNewAuthClient(t *testing.T) {
@@ -95,3 +97,213 @@ func TestNewAuthClient(t *testing.T) {
 		t.Errorf("Unexpected token: expected test-id-token, got %s", tokenResp.IDToken)
 	}
 }
+
+// Test the tokens of the client credentials grant are kept until they are about to expire
+func TestClientCredentialsSource(t *testing.T) {
+	requests, expiresIn := 0, 3600
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "automation" ||
+			r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id_token": "token-%d", "expires_in": %d}`, requests, expiresIn)
+	}))
+	defer server.Close()
+
+	authClient := NewAuthClient(strings.TrimPrefix(server.URL, "https://"), "")
+	authClient.client = server.Client()
+
+	source := authClient.ClientCredentialsSource("automation", "secret")
+	for i := 0; i < 2; i++ {
+		token, err := source()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if token != "token-1" {
+			t.Errorf("Expected the token to be kept, got %s", token)
+		}
+	}
+
+	// a token expiring within the refresh margin is requested again
+	expiresIn = 30
+	source = authClient.ClientCredentialsSource("automation", "secret")
+	for _, expected := range []string{"token-2", "token-3"} {
+		token, err := source()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if token != expected {
+			t.Errorf("Expected token %s, got %s", expected, token)
+		}
+	}
+
+	_, err := authClient.ClientCredentialsSource("automation", "wrong-secret")()
+	if !errors.Is(err, types.ErrAuth) {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
+
+// Test the password grant of an account with a second factor is repeated with its TOTP code
+func TestGetTokenMFA(t *testing.T) {
+	var grants []url.Values
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		grants = append(grants, r.PostForm)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.FormValue("totp") == "":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "mfa_required", "error_description": "TOTP required", "mfa_token": "challenge"}`))
+		case r.FormValue("totp") == "123456" && r.FormValue("mfa_token") == "challenge":
+			w.Write([]byte(`{"id_token": "test-id-token"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+		}
+	}))
+	defer server.Close()
+
+	authClient := NewAuthClient(strings.TrimPrefix(server.URL, "https://"), "test-client-token")
+	authClient.client = server.Client()
+
+	// without a code nor a prompt
+	_, err := authClient.GetToken("testuser", "testpass")
+	if !errors.Is(err, types.ErrMFARequired) || !errors.Is(err, types.ErrAuth) {
+		t.Errorf("Expected a second factor to be required, got %v", err)
+	}
+
+	grants = nil
+	authClient.SetTOTP("", func() (string, error) { return "123456", nil })
+	token, err := authClient.GetToken("testuser", "testpass")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "test-id-token" {
+		t.Errorf("Unexpected token: expected test-id-token, got %s", token)
+	}
+	if len(grants) != 2 || grants[1].Get("password") != "testpass" {
+		t.Errorf("Expected the password grant to be repeated with the code, got %v", grants)
+	}
+
+	authClient.SetTOTP("654321", nil)
+	_, err = authClient.GetToken("testuser", "testpass")
+	if !errors.Is(err, types.ErrAuth) || errors.Is(err, types.ErrMFARequired) {
+		t.Errorf("Expected the code to be rejected, got %v", err)
+	}
+}
+
+// Test the requests to the management plane are retried while it is unavailable
+func TestRetryTransport(t *testing.T) {
+	origBackoff := utils.NetworkBackoff
+	utils.NetworkBackoff = utils.Backoff{Attempts: 3}
+	defer func() { utils.NetworkBackoff = origBackoff }()
+
+	requests, unavailable := 0, 2
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		if requests <= unavailable {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.FormValue("username") != "testuser" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id_token": "test-id-token"}`))
+	}))
+	defer server.Close()
+
+	authClient := NewAuthClient(strings.TrimPrefix(server.URL, "https://"), "")
+	authClient.client.Transport = retryTransport{server.Client().Transport}
+
+	// the form of the token request is sent again with each attempt
+	token, err := authClient.GetToken("testuser", "testpass")
+	if err != nil || token != "test-id-token" {
+		t.Fatalf("Expected the token after the retries, got %q, %v", token, err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+
+	// the last response is returned once the attempts run out
+	requests, unavailable = 0, 3
+	if _, err := authClient.GetToken("testuser", "testpass"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the service unavailable status, got %v", err)
+	}
+
+	// rejected credentials are not retried
+	requests, unavailable = 0, 0
+	if _, err := authClient.GetToken("wronguser", "testpass"); !errors.Is(err, types.ErrAuth) || requests != 1 {
+		t.Errorf("Expected a single request rejecting the credentials, got %d requests and %v", requests, err)
+	}
+}
+
+// failingTransport fails every request like a connection reset after the request was sent
+type failingTransport struct {
+	requests int
+}
+
+func (t *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	t.requests++
+	return nil, errors.New("connection reset by peer")
+}
+
+// Test only the idempotent requests are sent again after a network error
+func TestRetryTransportIdempotent(t *testing.T) {
+	origBackoff := utils.NetworkBackoff
+	utils.NetworkBackoff = utils.Backoff{Attempts: 3}
+	defer func() { utils.NetworkBackoff = origBackoff }()
+
+	testCases := []struct {
+		name         string
+		method       string
+		header       http.Header
+		markIdem     bool
+		wantRequests int
+	}{
+		{name: "get", method: http.MethodGet, wantRequests: 3},
+		{name: "delete", method: http.MethodDelete, wantRequests: 3},
+		{name: "post", method: http.MethodPost, wantRequests: 1},
+		{name: "patch", method: http.MethodPatch, wantRequests: 1},
+		{name: "post marked idempotent", method: http.MethodPost, markIdem: true, wantRequests: 3},
+		{name: "post with an idempotency key", method: http.MethodPost, header: http.Header{"X-Idempotency-Key": {"key"}}, wantRequests: 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := &failingTransport{}
+			req, err := http.NewRequest(tc.method, "https://example.com/resource", strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, values := range tc.header {
+				req.Header[key] = values
+			}
+			if tc.markIdem {
+				markIdempotent(req)
+			}
+			if _, err := (retryTransport{next}).RoundTrip(req); err == nil {
+				t.Errorf("Expected the network error")
+			}
+			if next.requests != tc.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tc.wantRequests, next.requests)
+			}
+		})
+	}
+
+	// the password grant with a TOTP code is sent once, the code is accepted only once
+	next := &failingTransport{}
+	authClient := NewAuthClient("example.com", "")
+	authClient.client.Transport = retryTransport{next}
+	if _, err := authClient.requestToken(url.Values{"grant_type": {"password"}, "totp": {"123456"}}); err == nil {
+		t.Errorf("Expected the network error")
+	}
+	if next.requests != 1 {
+		t.Errorf("Expected a single request with the TOTP code, got %d", next.requests)
+	}
+}
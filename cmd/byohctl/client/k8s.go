@@ -2,14 +2,19 @@ package client
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,12 +23,14 @@ import (
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
 	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -37,6 +44,10 @@ const (
 	DefaultDirPerms = 0755
 )
 
+// HTTPTimeout is the timeout of a request to the management plane, its retries included, set by
+// the --http-timeout flag
+var HTTPTimeout = DefaultTimeout
+
 // K8sClient handles Kubernetes API operations
 type K8sClient struct {
 	client      *http.Client
@@ -44,7 +55,13 @@ type K8sClient struct {
 	domain      string
 	tenant      string
 	bearerToken string
+	// tokenSource refreshes bearerToken before the requests, nil if the token is never refreshed
+	tokenSource TokenSource
 	regionName  string
+	// namespace is the tenant namespace, set explicitly or discovered, derived from the FQDN if empty
+	namespace string
+	// namespaceSet is whether namespace was set explicitly, it is then never discovered
+	namespaceSet bool
 }
 
 // Client wraps the Kubernetes clientset and dynamic client.
@@ -56,7 +73,7 @@ type Client struct {
 // NewK8sClient creates a new Kubernetes client with provided credentials
 func NewK8sClient(fqdn, domain, tenant, token, regionName string) *K8sClient {
 	client := &K8sClient{
-		client:      &http.Client{Timeout: DefaultTimeout},
+		client:      &http.Client{Timeout: HTTPTimeout, Transport: retryTransport{loggingTransport{Transport}}},
 		fqdn:        fqdn,
 		domain:      domain,
 		tenant:      tenant,
@@ -70,13 +87,16 @@ func NewK8sClient(fqdn, domain, tenant, token, regionName string) *K8sClient {
 func GetNamespaceFromConfig(kubeconfigPath string) (string, error) {
 	// Read the kubeconfig file and get the namespace
 	data, err := os.ReadFile(kubeconfigPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: error reading kubeconfig: %w", types.ErrNotOnboarded, err)
+	}
 	if err != nil {
-		return "", fmt.Errorf("error reading kubeconfig: %v", err)
+		return "", fmt.Errorf("error reading kubeconfig: %w", err)
 	}
 
 	var config service.Config
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return "", fmt.Errorf("error parsing kubeconfig: %v", err)
+		return "", fmt.Errorf("error parsing kubeconfig: %w", err)
 	}
 	for _, context := range config.Contexts {
 		if context.Name == config.CurrentContext {
@@ -86,109 +106,356 @@ func GetNamespaceFromConfig(kubeconfigPath string) (string, error) {
 	return "", fmt.Errorf("namespace not found in kubeconfig")
 }
 
+// SetNamespace makes the client use namespace as the tenant namespace, instead of deriving it from
+// the FQDN, domain and tenant or discovering it
+func (c *K8sClient) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.namespaceSet = namespace != ""
+}
+
+// SetTokenSource makes the client take the bearer token of its requests from source, so tokens that
+// expire during a long command are refreshed
+func (c *K8sClient) SetTokenSource(source TokenSource) {
+	c.tokenSource = source
+}
+
+// authorize sets the bearer token of req, refreshed by the token source of the client if it has one
+func (c *K8sClient) authorize(req *http.Request) error {
+	if c.tokenSource != nil {
+		token, err := c.tokenSource()
+		if err != nil {
+			return err
+		}
+		c.bearerToken = token
+	}
+	req.Header.Add("Authorization", "Bearer "+c.bearerToken)
+	return nil
+}
+
+// Namespace returns the tenant namespace the client uses
+func (c *K8sClient) Namespace() string {
+	return c.getNamespace()
+}
+
 // getNamespace returns the namespace for the client
 func (c *K8sClient) getNamespace() string {
+	if c.namespace != "" {
+		return c.namespace
+	}
+	return c.derivedNamespace()
+}
+
+// derivedNamespace returns the namespace of the tenant by the convention of the management plane
+func (c *K8sClient) derivedNamespace() string {
 	fqdnPrefix := strings.Split(c.fqdn, ".")[0]
 	tenant := strings.ReplaceAll(c.tenant, "_", "-")
 	return fmt.Sprintf("%s-%s-%s", fqdnPrefix, c.domain, tenant)
 }
 
-// GetSecret retrieves a secret from the Kubernetes API
-func (c *K8sClient) GetSecret(secretName string) (*types.Secret, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+// discoverNamespace queries the management plane for the namespace labeled with the domain and the
+// tenant of the client, for the deployments whose namespaces do not follow the convention
+func (c *K8sClient) discoverNamespace() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), HTTPTimeout)
 	defer cancel()
 
-	utils.LogInfo("Fetching secret '%s'", secretName)
+	selector := fmt.Sprintf("%s=%s,%s=%s", service.PcdKaapiDomainKey, c.domain, service.PcdKaapiTenantKey, c.tenant)
+	utils.LogInfo("Discovering the namespace of the tenant labeled %s", selector)
+	namespacesEndpoint := fmt.Sprintf("https://%s/oidc-proxy/%s/%s/api/v1/namespaces?labelSelector=%s",
+		c.fqdn, c.derivedNamespace(), c.regionName, url.QueryEscape(selector))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", namespacesEndpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return "", err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error listing namespaces (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var namespaces struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &namespaces); err != nil {
+		return "", fmt.Errorf("error parsing namespaces: %w", err)
+	}
+	switch len(namespaces.Items) {
+	case 0:
+		return "", fmt.Errorf("no namespace is labeled %s", selector)
+	case 1:
+		return namespaces.Items[0].Metadata.Name, nil
+	}
+	return "", fmt.Errorf("%d namespaces are labeled %s, set the namespace of the tenant explicitly", len(namespaces.Items), selector)
+}
+
+// ValidateToken checks the bearer token of the client with the management plane before it is used.
+// A token past its expiry is rejected without a request, otherwise the management plane is asked
+// whether the token may read the secret secretName of the tenant namespace.
+func (c *K8sClient) ValidateToken(secretName string) error {
+	if expiry, ok := tokenExpiry(c.bearerToken); ok && time.Now().After(expiry) {
+		return utils.LogErrorf("%w: the token expired at %s", types.ErrAuth, expiry.UTC().Format(time.RFC3339))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HTTPTimeout)
+	defer cancel()
 
 	namespace := c.getNamespace()
+	review, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       "SelfSubjectAccessReview",
+		"spec": map[string]interface{}{
+			"resourceAttributes": map[string]string{
+				"namespace": namespace,
+				"verb":      "get",
+				"resource":  "secrets",
+				"name":      secretName,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding the access review: %w", err)
+	}
+	reviewEndpoint := fmt.Sprintf("https://%s/oidc-proxy/%s/%s/apis/authorization.k8s.io/v1/selfsubjectaccessreviews",
+		c.fqdn, namespace, c.regionName)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reviewEndpoint, strings.NewReader(string(review)))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	// the access review is answered without being stored
+	markIdempotent(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return utils.LogErrorf("error validating the token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return utils.LogErrorf("%w: the management plane rejected the token", types.ErrAuth)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return utils.LogErrorf("error validating the token (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Status struct {
+			Allowed bool `json:"allowed"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("error parsing the access review: %w", err)
+	}
+	if !result.Status.Allowed {
+		if c.namespaceSet {
+			return utils.LogErrorf("%w: the token may not read the secret %s of namespace %s", types.ErrAuth, secretName, namespace)
+		}
+		// the tenant namespace may not be the derived one, GetSecret discovers it
+		utils.LogDebug("The token may not read the secret %s of the derived namespace %s", secretName, namespace)
+	}
+	utils.LogInfo("Validated the authentication token")
+	return nil
+}
+
+// tokenExpiry returns the expiry of token if it is a JWT with an exp claim
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+// KubeconfigExpiry returns when the credentials of the current context of the kubeconfig at
+// kubeconfigPath expire, the expiry of its client certificate or of its JWT token, false if they
+// do not expire or their expiry is unknown
+func KubeconfigExpiry(kubeconfigPath string) (time.Time, bool, error) {
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, false, fmt.Errorf("%w: error reading kubeconfig: %w", types.ErrNotOnboarded, err)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("error reading kubeconfig: %w", err)
+	}
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("current context %q not found in kubeconfig", config.CurrentContext)
+	}
+	authInfo, ok := config.AuthInfos[context.AuthInfo]
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("user %q not found in kubeconfig", context.AuthInfo)
+	}
+	if len(authInfo.ClientCertificateData) > 0 {
+		block, _ := pem.Decode(authInfo.ClientCertificateData)
+		if block == nil {
+			return time.Time{}, false, fmt.Errorf("invalid client certificate of user %q in kubeconfig", context.AuthInfo)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid client certificate of user %q in kubeconfig: %w", context.AuthInfo, err)
+		}
+		return cert.NotAfter, true, nil
+	}
+	expiry, ok := tokenExpiry(authInfo.Token)
+	return expiry, ok, nil
+}
+
+// GetSecret retrieves a secret from the Kubernetes API. If the secret is not found in the derived
+// namespace of the tenant, the namespace is discovered and the secret retrieved from there.
+func (c *K8sClient) GetSecret(secretName string) (*types.Secret, error) {
+	utils.LogInfo("Fetching secret '%s'", secretName)
+
+	secret, status, err := c.getSecret(c.getNamespace(), secretName)
+	if err != nil && !c.namespaceSet && (status == http.StatusNotFound || status == http.StatusForbidden) {
+		utils.LogDebug("Secret %s not found in namespace %s: %v", secretName, c.getNamespace(), err)
+		namespace, discoverErr := c.discoverNamespace()
+		if discoverErr != nil {
+			utils.LogDebug("Failed to discover the namespace of the tenant: %v", discoverErr)
+		} else if namespace != c.getNamespace() {
+			utils.LogInfo("Using the discovered namespace %s of the tenant", namespace)
+			c.namespace = namespace
+			secret, _, err = c.getSecret(namespace, secretName)
+		}
+	}
+	if err != nil {
+		return nil, utils.LogErrorf("%w", err)
+	}
+
+	utils.LogInfo("Successfully retrieved secret")
+	return secret, nil
+}
+
+// getSecret retrieves the secret secretName of namespace, with the status code of the response
+func (c *K8sClient) getSecret(namespace, secretName string) (*types.Secret, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), HTTPTimeout)
+	defer cancel()
+
 	secretEndpoint := fmt.Sprintf("https://%s/oidc-proxy/%s/%s/api/v1/namespaces/%s/secrets/%s",
 		c.fqdn, namespace, c.regionName, namespace, secretName)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", secretEndpoint, nil)
 	if err != nil {
-		return nil, utils.LogErrorf("error creating request: %v", err)
+		return nil, 0, fmt.Errorf("error creating request: %w", err)
 	}
 
-	req.Header.Add("Authorization", "Bearer "+c.bearerToken)
+	if err := c.authorize(req); err != nil {
+		return nil, 0, err
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, utils.LogErrorf("error making request: %v", err)
+		return nil, 0, fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, utils.LogErrorf("error reading response: %v", err)
+		return nil, resp.StatusCode, fmt.Errorf("error reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, utils.LogErrorf("error getting secret (status %d): %s", resp.StatusCode, string(body))
+		return nil, resp.StatusCode, fmt.Errorf("error getting secret (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var secret types.Secret
 	err = json.Unmarshal(body, &secret)
 	if err != nil {
-		return nil, utils.LogErrorf("error parsing secret: %v", err)
+		return nil, resp.StatusCode, fmt.Errorf("error parsing secret: %w", err)
 	}
-
-	utils.LogSuccess("Successfully retrieved secret")
-	return &secret, nil
+	return &secret, resp.StatusCode, nil
 }
 
-// SaveKubeConfig saves the kubeconfig from the secret to the user's BYOH directory
-func (c *K8sClient) SaveKubeConfig(secretName string) error {
+// GetKubeConfig returns the kubeconfig of the agent from the secret, without saving it
+func (c *K8sClient) GetKubeConfig(secretName string) ([]byte, error) {
 	// Step 1: Get secret
 	secret, err := c.GetSecret(secretName)
 	if err != nil {
-		return fmt.Errorf("failed to get secret: %v", err)
+		return nil, fmt.Errorf("failed to get secret: %w", err)
 	}
 
 	// Step 2: Get kubeconfig from secret
 	kubeconfigString, ok := secret.Data["config"]
 	if !ok {
-		return fmt.Errorf("kubeconfig not found in secret")
+		return nil, fmt.Errorf("kubeconfig not found in secret")
 	}
 
 	// Step 3: Decode kubeconfig
 	kubeconfig, err := base64.StdEncoding.DecodeString(string(kubeconfigString))
 	if err != nil {
-		return fmt.Errorf("failed to decode kubeconfig: %v", err)
+		return nil, fmt.Errorf("failed to decode kubeconfig: %w", err)
+	}
+
+	// The agent trusts the CA of the management plane byohctl was told to trust
+	if len(caBundle) > 0 {
+		if kubeconfig, err = addCertificateAuthority(kubeconfig, caBundle); err != nil {
+			return nil, fmt.Errorf("failed to add the CA certificate to the kubeconfig: %w", err)
+		}
+	}
+	return kubeconfig, nil
+}
+
+// SaveKubeConfig saves the kubeconfig from the secret to the user's BYOH directory
+func (c *K8sClient) SaveKubeConfig(secretName string) error {
+	kubeconfig, err := c.GetKubeConfig(secretName)
+	if err != nil {
+		return err
 	}
 
 	// Step 4: Create byohDir if it doesn't exist
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return fmt.Errorf("failed to get home directory: %v", err)
+		return fmt.Errorf("failed to get home directory: %w", err)
 	}
 
 	byohDir := filepath.Join(homeDir, service.ByohConfigDir)
 
 	// Step 4: Create byohDir if it doesn't exist
 	if err = os.MkdirAll(byohDir, DefaultDirPerms); err != nil {
-		return fmt.Errorf("failed to create byoh directory: %v", err)
+		return fmt.Errorf("failed to create byoh directory: %w", err)
 	}
 
 	// Step 5: Write kubeconfig to byohDir
 	kubeconfigPath := filepath.Join(byohDir, "config")
 
 	if err = os.WriteFile(kubeconfigPath, kubeconfig, service.DefaultFilePerms); err != nil {
-		return fmt.Errorf("failed to write kubeconfig: %v", err)
-	}
-
-	// Success
-	utils.LogSuccess("Successfully wrote kubeconfig to %s", kubeconfigPath)
-	return nil
-}
-
-// DeleteSavedKubeconfig deletes the kubeconfig from the user's BYOH directory
-func (c *K8sClient) DeleteSavedKubeconfig() error {
-
-	if err := os.RemoveAll(service.ByohDir); err != nil {
-		return fmt.Errorf("failed to delete kubeconfig: %v", err)
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
 	}
 
-	utils.LogSuccess("Successfully deleted saved kubeconfig from %s", service.KubeconfigFilePath)
+	utils.RecordStep(utils.HostChanged, "Wrote the kubeconfig of the agent to %s", kubeconfigPath)
 	return nil
 }
 
@@ -201,14 +468,14 @@ func (c *K8sClient) CheckDNSResolution() ([]string, error) {
 	var r net.Resolver
 	addrs, err := r.LookupHost(ctx, c.fqdn)
 	if err != nil {
-		return nil, fmt.Errorf("DNS resolution failed for %s: %v", c.fqdn, err)
+		return nil, fmt.Errorf("DNS resolution failed for %s: %w", c.fqdn, err)
 	}
 
 	if len(addrs) == 0 {
 		return nil, fmt.Errorf("DNS resolution returned empty result for %s", c.fqdn)
 	}
 
-	utils.LogSuccess("DNS resolution successful: %v", addrs)
+	utils.LogInfo("DNS resolution successful: %v", addrs)
 	return addrs, nil
 }
 
@@ -218,19 +485,26 @@ func GetK8sClient(kubeconfigPath string) (*Client, error) {
 	// Build the config from the kubeconfig file.
 	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("error building kubeconfig: %v", err)
+		return nil, fmt.Errorf("error building kubeconfig: %w", err)
 	}
+	return newClient(config)
+}
+
+// newClient returns a new Kubernetes client from config
+func newClient(config *rest.Config) (*Client, error) {
+	config = rest.CopyConfig(config)
+	config.Timeout = HTTPTimeout
 
 	// Create a new Kubernetes client that can be used to interact with Kubernetes resources.
 	client, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Kubernetes client: %v", err)
+		return nil, fmt.Errorf("error creating Kubernetes client: %w", err)
 	}
 
 	// Create a new dynamic client that can be used to interact with custom resources.
 	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
-		return nil, fmt.Errorf("error creating dynamic client: %v", err)
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
 	}
 
 	return &Client{
@@ -247,27 +521,57 @@ func (client *Client) GetByoHostObject(namespace string) (*infrastructurev1beta1
 		Resource: "byohosts",
 	}
 
-	hostName, err := os.Hostname()
+	hostName, err := service.HostName()
 	if err != nil {
-		return nil, fmt.Errorf("error getting hostname: %v", err)
+		return nil, fmt.Errorf("error getting hostname: %w", err)
 	}
 
 	// Get the byohost object
 	unstructuredObj, err := client.DynamicClient.Resource(byohostGVR).Namespace(namespace).Get(context.Background(), hostName, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("error getting ByoHosts: %v", err)
+		return nil, fmt.Errorf("error getting ByoHosts: %w", err)
 	}
 
 	// Convert the unstructured object to ByoHost
 	byoHost := &infrastructurev1beta1.ByoHost{}
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredObj.UnstructuredContent(), byoHost)
 	if err != nil {
-		return nil, fmt.Errorf("error converting ByoHosts: %v", err)
+		return nil, fmt.Errorf("error converting ByoHosts: %w", err)
 	}
 
 	return byoHost, nil
 }
 
+// GetByoHostEvents returns the events of the ByoHost name of namespace that last occurred from
+// since on, the oldest first
+func (client *Client) GetByoHostEvents(namespace, name string, since time.Time) ([]corev1.Event, error) {
+	events, err := client.Clientset.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=ByoHost,involvedObject.name=" + name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the events of ByoHost %s: %w", name, err)
+	}
+	var items []corev1.Event
+	for _, event := range events.Items {
+		if !EventTime(event).Before(since) {
+			items = append(items, event)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return EventTime(items[i]).Before(EventTime(items[j])) })
+	return items, nil
+}
+
+// EventTime returns the last time event occurred
+func EventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
 // DeleteByoHostObject deletes the ByoHost object in the given namespace.
 func (client *Client) DeleteByoHostObject(namespace string) error {
 	byohostGVR := schema.GroupVersionResource{
@@ -276,9 +580,9 @@ func (client *Client) DeleteByoHostObject(namespace string) error {
 		Resource: "byohosts",
 	}
 
-	hostName, err := os.Hostname()
+	hostName, err := service.HostName()
 	if err != nil {
-		return fmt.Errorf("error getting hostname: %v", err)
+		return fmt.Errorf("error getting hostname: %w", err)
 	}
 
 	// Delete the byohost object
@@ -290,6 +594,30 @@ func (client *Client) DeleteByoHostObject(namespace string) error {
 	return nil
 }
 
+// CreateByoHostOperation creates the ByoHostOperation record of an operation on the host in the given namespace.
+func (client *Client) CreateByoHostOperation(namespace string, operation *infrastructurev1beta1.ByoHostOperation) error {
+	byohostOperationGVR := schema.GroupVersionResource{
+		Group:    "infrastructure.cluster.x-k8s.io",
+		Version:  "v1beta1",
+		Resource: "byohostoperations",
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(operation)
+	if err != nil {
+		return fmt.Errorf("error converting ByoHostOperation: %w", err)
+	}
+	unstructuredObj := &unstructured.Unstructured{Object: content}
+	unstructuredObj.SetAPIVersion(infrastructurev1beta1.GroupVersion.String())
+	unstructuredObj.SetKind("ByoHostOperation")
+
+	_, err = client.DynamicClient.Resource(byohostOperationGVR).Namespace(namespace).Create(context.Background(), unstructuredObj, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error creating ByoHostOperation: %w", err)
+	}
+
+	return nil
+}
+
 // AnnotateMachineObject annotates the machine object with the given annotation
 func (client *Client) AnnotateMachineObject(machineObj *unstructured.Unstructured, namespace, annotationKey, annotationValue string) error {
 	machineGVR := schema.GroupVersionResource{
@@ -309,7 +637,7 @@ func (client *Client) AnnotateMachineObject(machineObj *unstructured.Unstructure
 	// Update the machine object
 	_, err := client.DynamicClient.Resource(machineGVR).Namespace(namespace).Update(context.TODO(), machineObj, metav1.UpdateOptions{})
 	if err != nil {
-		return fmt.Errorf("error updating machine object: %v", err)
+		return fmt.Errorf("error updating machine object: %w", err)
 	}
 
 	return nil
@@ -333,19 +661,19 @@ func (client *Client) ScaleDownMachineDeployment(machineObj *unstructured.Unstru
 	// Get the machine deployment object
 	unstructuredDeploymentObj, err := client.DynamicClient.Resource(deploymentGVR).Namespace(namespace).Get(context.TODO(), machineDeploymentName, metav1.GetOptions{})
 	if err != nil {
-		return fmt.Errorf("error getting machine deployment object: %v", err)
+		return fmt.Errorf("error getting machine deployment object: %w", err)
 	}
 	machineDeploymentObj := &capiv1beta1.MachineDeployment{}
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredDeploymentObj.UnstructuredContent(), machineDeploymentObj)
 	if err != nil {
-		return fmt.Errorf("error converting machine deployment object: %v", err)
+		return fmt.Errorf("error converting machine deployment object: %w", err)
 	}
 
 	*machineDeploymentObj.Spec.Replicas = *machineDeploymentObj.Spec.Replicas - 1
 
 	updatedUnstructuredDeploymentObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(machineDeploymentObj)
 	if err != nil {
-		return fmt.Errorf("error converting machine deployment object: %v", err)
+		return fmt.Errorf("error converting machine deployment object: %w", err)
 	}
 
 	updatedUnstructured := &unstructured.Unstructured{
@@ -355,7 +683,7 @@ func (client *Client) ScaleDownMachineDeployment(machineObj *unstructured.Unstru
 	// Update the machine deployment object
 	_, err = client.DynamicClient.Resource(deploymentGVR).Namespace(namespace).Update(context.TODO(), updatedUnstructured, metav1.UpdateOptions{})
 	if err != nil {
-		return fmt.Errorf("error updating machine deployment object: %v", err)
+		return fmt.Errorf("error updating machine deployment object: %w", err)
 	}
 
 	return nil
@@ -372,7 +700,7 @@ func (client *Client) GetUnstructuredMachineObject(namespace, machineName string
 	// Get the machine object
 	unstructuredMachineObj, err := client.DynamicClient.Resource(machineGVR).Namespace(namespace).Get(context.TODO(), machineName, metav1.GetOptions{})
 	if err != nil {
-		return nil, fmt.Errorf("error getting machine object: %v", err)
+		return nil, fmt.Errorf("error getting machine object: %w", err)
 	}
 
 	return unstructuredMachineObj, nil
@@ -396,12 +724,12 @@ func (client *Client) GetMachineDeploymentReplicaCount(machineObj *unstructured.
 	// Get the machine deployment object
 	unstructuredDeploymentObj, err := client.DynamicClient.Resource(deploymentGVR).Namespace(namespace).Get(context.TODO(), machineDeploymentName, metav1.GetOptions{})
 	if err != nil {
-		return 0, fmt.Errorf("error getting machine deployment object: %v", err)
+		return 0, fmt.Errorf("error getting machine deployment object: %w", err)
 	}
 	machineDeploymentObj := &capiv1beta1.MachineDeployment{}
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredDeploymentObj.UnstructuredContent(), machineDeploymentObj)
 	if err != nil {
-		return 0, fmt.Errorf("error converting machine deployment object: %v", err)
+		return 0, fmt.Errorf("error converting machine deployment object: %w", err)
 	}
 
 	return *machineDeploymentObj.Spec.Replicas, nil
@@ -413,19 +741,19 @@ func (client *Client) WaitForMachineRefToBeUnset(byoHost *infrastructurev1beta1.
 
 	for {
 		// Check if we've exceeded the timeout
-		if time.Since(startTime) > service.WaitForMachineRefToBeUnsetTimeout {
-			return fmt.Errorf("timeout waiting for machineRef to be unset")
+		if time.Since(startTime) > service.WaitTimeout {
+			return fmt.Errorf("timeout waiting for machineRef to be unset after %s, raise it with --wait-timeout", service.WaitTimeout)
 		}
 
 		// Get the current byohost object
 		byoHost, err := client.GetByoHostObject(namespace)
 		if err != nil {
-			return fmt.Errorf("error getting byohost object: %v", err)
+			return fmt.Errorf("error getting byohost object: %w", err)
 		}
 
 		// Check if machineRef is nil or no longer references the machine
 		if byoHost.Status.MachineRef == nil {
-			utils.LogSuccess("MachineRef unset")
+			utils.LogInfo("MachineRef unset")
 			return nil
 		}
 
@@ -435,26 +763,67 @@ func (client *Client) WaitForMachineRefToBeUnset(byoHost *infrastructurev1beta1.
 	}
 }
 
+// WaitForHeartbeat waits for the agent of the host to send a heartbeat after since and to be
+// connected again, e.g. after the agent restarted
+func (client *Client) WaitForHeartbeat(namespace string, since time.Time) error {
+	startTime := time.Now()
+
+	for {
+		if time.Since(startTime) > service.WaitTimeout {
+			return fmt.Errorf("timeout waiting for a heartbeat of the agent after %s, see sudo journalctl -u %s", service.WaitTimeout, service.ByohAgentServiceName)
+		}
+
+		byoHost, err := client.GetByoHostObject(namespace)
+		if err != nil {
+			return fmt.Errorf("error getting byohost object: %w", err)
+		}
+
+		heartbeat := byoHost.Status.LastHeartbeatTime
+		if heartbeat != nil && heartbeat.Time.After(since) {
+			for _, condition := range byoHost.Status.Conditions {
+				if condition.Type == infrastructurev1beta1.AgentConnected && condition.Status == corev1.ConditionTrue {
+					utils.LogInfo("The agent sent a heartbeat at %s", heartbeat.Time.Format(time.RFC3339))
+					return nil
+				}
+			}
+		}
+
+		utils.LogInfo("Waiting for a heartbeat of the agent...")
+		time.Sleep(5 * time.Second)
+	}
+}
+
 // CheckRegionAvailability checks if the region is available for the tenant
 func (c *K8sClient) CheckRegionAvailability(regionName string) (bool, []string, error) {
 	// Create a client from the kubeconfig
 	client, err := GetK8sClient(service.KubeconfigFilePath)
 	if err != nil {
-		return false, nil, fmt.Errorf("error creating Kubernetes client: %v", err)
+		return false, nil, fmt.Errorf("error creating Kubernetes client: %w", err)
 	}
+	return c.checkRegionAvailability(client, regionName)
+}
 
-	// Get the region configmap from the management cluster from the tenant namespace
-	regionConfigMap, err := client.Clientset.CoreV1().ConfigMaps(c.getNamespace()).Get(context.TODO(), "region-config", metav1.GetOptions{})
+// CheckRegionAvailabilityWith checks if the region is available for the tenant with kubeconfig,
+// the kubeconfig of GetKubeConfig that is not saved
+func (c *K8sClient) CheckRegionAvailabilityWith(kubeconfig []byte, regionName string) (bool, []string, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return false, nil, fmt.Errorf("error building kubeconfig: %w", err)
+	}
+	client, err := newClient(config)
 	if err != nil {
-		return false, nil, fmt.Errorf("error getting region configmap: %v", err)
+		return false, nil, err
 	}
+	return c.checkRegionAvailability(client, regionName)
+}
 
+// checkRegionAvailability checks if the region is available for the tenant with client
+func (c *K8sClient) checkRegionAvailability(client *Client, regionName string) (bool, []string, error) {
 	// Check if the given region is available for the tenant
-	regionsStr, ok := regionConfigMap.Data["regions"]
-	if !ok {
-		return false, nil, fmt.Errorf("region configmap does not have regions key")
+	regions, err := client.getRegions(c.getNamespace())
+	if err != nil {
+		return false, nil, err
 	}
-	regions := strings.Split(regionsStr, "\n")
 	for _, region := range regions {
 		if strings.TrimSpace(region) == regionName {
 			return true, nil, nil
@@ -463,3 +832,143 @@ func (c *K8sClient) CheckRegionAvailability(regionName string) (bool, []string,
 
 	return false, regions, nil
 }
+
+// ListRegions returns the regions available to the tenant of the kubeconfig
+func ListRegions(kubeconfigPath string) ([]string, error) {
+	namespace, err := GetNamespaceFromConfig(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	client, err := GetK8sClient(kubeconfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Kubernetes client: %w", err)
+	}
+	regions, err := client.getRegions(namespace)
+	if err != nil {
+		return nil, err
+	}
+	return regionNames(regions), nil
+}
+
+// TenantRegions returns the regions available to the tenant of the client, read with the
+// kubeconfig of its bootstrap secret without saving it, so that a host that is not onboarded yet
+// can list them
+func (c *K8sClient) TenantRegions() ([]string, error) {
+	kubeconfig, err := c.GetKubeConfig("byoh-bootstrap-kc")
+	if err != nil {
+		return nil, err
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("error building kubeconfig: %w", err)
+	}
+	client, err := newClient(config)
+	if err != nil {
+		return nil, err
+	}
+	regions, err := client.getRegions(c.getNamespace())
+	if err != nil {
+		return nil, err
+	}
+	return regionNames(regions), nil
+}
+
+// regionNames returns the names of the lines of the region configmap, without the blank lines
+func regionNames(regions []string) []string {
+	var names []string
+	for _, region := range regions {
+		if region = strings.TrimSpace(region); region != "" {
+			names = append(names, region)
+		}
+	}
+	return names
+}
+
+// getRegions returns the regions of the region configmap of the tenant namespace, one per line
+func (client *Client) getRegions(namespace string) ([]string, error) {
+	// Get the region configmap from the management cluster from the tenant namespace
+	regionConfigMap, err := client.Clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), "region-config", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting region configmap: %w", err)
+	}
+	regionsStr, ok := regionConfigMap.Data["regions"]
+	if !ok {
+		return nil, fmt.Errorf("region configmap does not have regions key")
+	}
+	return strings.Split(regionsStr, "\n"), nil
+}
+
+// GetClusterRequirements returns the requirements of the cluster clusterName of the given namespace on its
+// hosts: its Kubernetes version, the largest resource requirements of the ByoMachineTemplates of its
+// MachineDeployments and the pre-seeded bundles of their K8sInstallerConfigTemplates.
+func (client *Client) GetClusterRequirements(namespace, clusterName string) (*service.HostRequirements, error) {
+	clusterGVR := capiv1beta1.GroupVersion.WithResource("clusters")
+	unstructuredCluster, err := client.DynamicClient.Resource(clusterGVR).Namespace(namespace).Get(context.TODO(), clusterName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting cluster %s: %w", clusterName, err)
+	}
+	cluster := &capiv1beta1.Cluster{}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredCluster.UnstructuredContent(), cluster); err != nil {
+		return nil, fmt.Errorf("error converting cluster object: %w", err)
+	}
+	requirements := &service.HostRequirements{Cluster: clusterName}
+	if cluster.Spec.Topology != nil {
+		requirements.K8sVersion = cluster.Spec.Topology.Version
+	} else if ref := cluster.Spec.ControlPlaneRef; ref != nil {
+		// the resource of the control plane is the plural of its kind, e.g. kubeadmcontrolplanes
+		controlPlaneGVR := schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind).GroupVersion().WithResource(strings.ToLower(ref.Kind) + "s")
+		controlPlane, err := client.DynamicClient.Resource(controlPlaneGVR).Namespace(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting the control plane of cluster %s: %w", clusterName, err)
+		}
+		requirements.K8sVersion, _, _ = unstructured.NestedString(controlPlane.Object, "spec", "version")
+	}
+	if requirements.K8sVersion == "" {
+		return nil, fmt.Errorf("the Kubernetes version of cluster %s is not set", clusterName)
+	}
+
+	deploymentGVR := capiv1beta1.GroupVersion.WithResource("machinedeployments")
+	deployments, err := client.DynamicClient.Resource(deploymentGVR).Namespace(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: capiv1beta1.ClusterNameLabel + "=" + clusterName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the machine deployments of cluster %s: %w", clusterName, err)
+	}
+	machineTemplateGVR := infrastructurev1beta1.GroupVersion.WithResource("byomachinetemplates")
+	installerTemplateGVR := infrastructurev1beta1.GroupVersion.WithResource("k8sinstallerconfigtemplates")
+	for i := range deployments.Items {
+		deployment := &capiv1beta1.MachineDeployment{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(deployments.Items[i].UnstructuredContent(), deployment); err != nil {
+			return nil, fmt.Errorf("error converting machine deployment object: %w", err)
+		}
+		infraRef := deployment.Spec.Template.Spec.InfrastructureRef
+		if infraRef.Kind != "ByoMachineTemplate" {
+			continue
+		}
+		unstructuredTemplate, err := client.DynamicClient.Resource(machineTemplateGVR).Namespace(namespace).Get(context.TODO(), infraRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting ByoMachineTemplate %s: %w", infraRef.Name, err)
+		}
+		machineTemplate := &infrastructurev1beta1.ByoMachineTemplate{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredTemplate.UnstructuredContent(), machineTemplate); err != nil {
+			return nil, fmt.Errorf("error converting ByoMachineTemplate: %w", err)
+		}
+		spec := machineTemplate.Spec.Template.Spec
+		requirements.MinCPU = max(requirements.MinCPU, spec.MinCPU)
+		requirements.MinMemoryMiB = max(requirements.MinMemoryMiB, spec.MinMemoryMiB)
+		requirements.MinDiskGiB = max(requirements.MinDiskGiB, spec.MinDiskGiB)
+
+		if spec.InstallerRef == nil || spec.InstallerRef.Kind != "K8sInstallerConfigTemplate" {
+			continue
+		}
+		installerTemplate, err := client.DynamicClient.Resource(installerTemplateGVR).Namespace(namespace).Get(context.TODO(), spec.InstallerRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting K8sInstallerConfigTemplate %s: %w", spec.InstallerRef.Name, err)
+		}
+		bundlePath, _, _ := unstructured.NestedString(installerTemplate.Object, "spec", "template", "spec", "bundlePath")
+		if bundlePath != "" {
+			requirements.BundlePaths = append(requirements.BundlePaths, bundlePath)
+		}
+	}
+	return requirements, nil
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -59,6 +60,11 @@ func TestGetNamespace(t *testing.T) {
 	if !strings.Contains(namespace, "test-tenant") {
 		t.Errorf("Namespace %s does not contain tenant", namespace)
 	}
+
+	client.SetNamespace("explicit-namespace")
+	if namespace := client.getNamespace(); namespace != "explicit-namespace" {
+		t.Errorf("Expected the namespace set explicitly, got %s", namespace)
+	}
 }
 
 // Test GetSecret method
@@ -124,6 +130,89 @@ func TestGetSecret(t *testing.T) {
 	}
 }
 
+// Test GetSecret discovers the namespace of the tenant when the secret is not in the derived namespace
+func TestGetSecretDiscoveredNamespace(t *testing.T) {
+	var paths []string
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oidc-proxy/127-test-domain-test-tenant/region/api/v1/namespaces":
+			assert.Equal(t, "pcd-kaapi.pf9.io/domain=test-domain,pcd-kaapi.pf9.io/tenant=test-tenant", r.URL.Query().Get("labelSelector"))
+			fmt.Fprint(w, `{"kind":"NamespaceList","items":[{"metadata":{"name":"tenant-1234"}}]}`)
+		case "/oidc-proxy/tenant-1234/region/api/v1/namespaces/tenant-1234/secrets/kubeconfig":
+			json.NewEncoder(w).Encode(types.Secret{Data: map[string]string{"config": "a3ViZWNvbmZpZw=="}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := NewK8sClient(strings.TrimPrefix(ts.URL, "https://"), "test-domain", "test-tenant", "test-token", "region")
+	client.client = ts.Client()
+
+	secret, err := client.GetSecret("kubeconfig")
+	require.NoError(t, err)
+	assert.Equal(t, "a3ViZWNvbmZpZw==", secret.Data["config"])
+	assert.Equal(t, "tenant-1234", client.Namespace())
+	assert.Len(t, paths, 3)
+
+	// a namespace set explicitly is never discovered
+	paths = nil
+	client.SetNamespace("explicit")
+	_, err = client.GetSecret("kubeconfig")
+	require.Error(t, err)
+	assert.Equal(t, []string{"/oidc-proxy/explicit/region/api/v1/namespaces/explicit/secrets/kubeconfig"}, paths)
+}
+
+// Test ValidateToken asks the management plane about the token, unless it already expired
+func TestValidateToken(t *testing.T) {
+	var reviews []map[string]interface{}
+	allowed := true
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "/oidc-proxy/explicit/region/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", r.URL.Path)
+		var review map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&review))
+		reviews = append(reviews, review)
+		review["status"] = map[string]interface{}{"allowed": allowed}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(review)
+	}))
+	defer ts.Close()
+
+	newClient := func(token string) *K8sClient {
+		client := NewK8sClient(strings.TrimPrefix(ts.URL, "https://"), "test-domain", "test-tenant", token, "region")
+		client.client = ts.Client()
+		client.SetNamespace("explicit")
+		return client
+	}
+
+	require.NoError(t, newClient("test-token").ValidateToken("kubeconfig"))
+	require.Len(t, reviews, 1)
+	assert.Equal(t, map[string]interface{}{
+		"namespace": "explicit",
+		"verb":      "get",
+		"resource":  "secrets",
+		"name":      "kubeconfig",
+	}, reviews[0]["spec"].(map[string]interface{})["resourceAttributes"])
+
+	allowed = false
+	assert.ErrorIs(t, newClient("test-token").ValidateToken("kubeconfig"), types.ErrAuth)
+	assert.ErrorIs(t, newClient("other-token").ValidateToken("kubeconfig"), types.ErrAuth)
+
+	// an expired JWT is rejected without asking the management plane
+	reviews = nil
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"automation","exp":1600000000}`))
+	err := newClient("eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl").ValidateToken("kubeconfig")
+	assert.ErrorIs(t, err, types.ErrAuth)
+	assert.Contains(t, err.Error(), "expired")
+	assert.Empty(t, reviews)
+}
+
 func TestSaveKubeConfig(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -306,3 +395,53 @@ func TestAgentLogOutput(t *testing.T) {
 		t.Errorf("Agent log file doesn't exist at expected path: %s", agentLogPath)
 	}
 }
+
+func TestKubeconfigExpiry(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	dir := t.TempDir()
+	writeKubeconfig := func(name, user string) string {
+		path := filepath.Join(dir, name)
+		kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: management
+  cluster:
+    server: %s
+contexts:
+- name: byoh
+  context:
+    cluster: management
+    user: byoh
+current-context: byoh
+users:
+- name: byoh
+  user:
+%s
+`, ts.URL, user)
+		require.NoError(t, os.WriteFile(path, []byte(kubeconfig), DefaultFilePerms))
+		return path
+	}
+
+	// the client certificate expires
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	expiry, expires, err := KubeconfigExpiry(writeKubeconfig("cert", "    client-certificate-data: "+base64.StdEncoding.EncodeToString(cert)))
+	require.NoError(t, err)
+	assert.True(t, expires)
+	assert.True(t, ts.Certificate().NotAfter.Equal(expiry))
+
+	// a JWT expires, an opaque token does not
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"byoh","exp":1600000000}`))
+	expiry, expires, err = KubeconfigExpiry(writeKubeconfig("jwt", "    token: eyJhbGciOiJSUzI1NiJ9."+payload+".c2lnbmF0dXJl"))
+	require.NoError(t, err)
+	assert.True(t, expires)
+	assert.Equal(t, int64(1600000000), expiry.Unix())
+	_, expires, err = KubeconfigExpiry(writeKubeconfig("token", "    token: test-token"))
+	require.NoError(t, err)
+	assert.False(t, expires)
+
+	_, _, err = KubeconfigExpiry(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, types.ErrNotOnboarded)
+	_, _, err = KubeconfigExpiry(writeKubeconfig("invalid", "    client-certificate-data: "+base64.StdEncoding.EncodeToString([]byte("not a certificate"))))
+	assert.ErrorContains(t, err, "invalid client certificate")
+}
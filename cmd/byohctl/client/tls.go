@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// caBundle is the PEM of the CA of the management plane set with UseCACert, empty if the system
+// CAs are trusted only
+var caBundle []byte
+
+// UseCACert makes the clients of the management plane trust the CA caCert on top of the system CAs,
+// for management planes behind an internal CA. caCert is either the path of a PEM file or the PEM
+// itself; the kubeconfig saved for the agent trusts the CA too. An empty caCert trusts the system
+// CAs only again.
+func UseCACert(caCert string) error {
+	if caCert == "" {
+		Transport, caBundle = http.DefaultTransport, nil
+		return nil
+	}
+
+	pemData := []byte(caCert)
+	if !strings.Contains(caCert, "-----BEGIN") {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA certificate: %w", err)
+		}
+		pemData = data
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return fmt.Errorf("no PEM certificate found in the CA certificate %s", caCertSource(caCert))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	Transport = transport
+	caBundle = pemData
+	return nil
+}
+
+// caCertSource describes caCert for error messages, without printing an inline PEM
+func caCertSource(caCert string) string {
+	if strings.Contains(caCert, "-----BEGIN") {
+		return "passed inline"
+	}
+	return caCert
+}
+
+// addCertificateAuthority makes the clusters of kubeconfig trust the CA of the PEM ca on top of
+// their own certificate authority
+func addCertificateAuthority(kubeconfig, ca []byte) ([]byte, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.InsecureSkipTLSVerify || bytes.Contains(cluster.CertificateAuthorityData, bytes.TrimSpace(ca)) {
+			continue
+		}
+		if cluster.CertificateAuthority != "" {
+			data, err := os.ReadFile(cluster.CertificateAuthority)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the certificate authority of cluster %s: %w", name, err)
+			}
+			cluster.CertificateAuthorityData, cluster.CertificateAuthority = data, ""
+		}
+		if len(cluster.CertificateAuthorityData) > 0 && !bytes.HasSuffix(cluster.CertificateAuthorityData, []byte("\n")) {
+			cluster.CertificateAuthorityData = append(cluster.CertificateAuthorityData, '\n')
+		}
+		cluster.CertificateAuthorityData = append(cluster.CertificateAuthorityData, ca...)
+	}
+	return clientcmd.Write(*config)
+}
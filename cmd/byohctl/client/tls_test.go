@@ -0,0 +1,76 @@
+package client
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// useCACert runs UseCACert and restores the transport and the CA at the end of the test
+func useCACert(t *testing.T, caCert string) error {
+	origTransport, origCABundle := Transport, caBundle
+	t.Cleanup(func() { Transport, caBundle = origTransport, origCABundle })
+	return UseCACert(caCert)
+}
+
+func TestUseCACert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, ca, DefaultFilePerms))
+
+	for name, caCert := range map[string]string{"file": caFile, "inline PEM": string(ca)} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, useCACert(t, caCert))
+			resp, err := (&http.Client{Transport: Transport}).Get(ts.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, ca, caBundle)
+		})
+	}
+
+	t.Run("not a certificate", func(t *testing.T) {
+		notCA := filepath.Join(t.TempDir(), "ca.crt")
+		require.NoError(t, os.WriteFile(notCA, []byte("not a certificate"), DefaultFilePerms))
+		assert.ErrorContains(t, useCACert(t, notCA), "no PEM certificate found")
+		assert.ErrorContains(t, useCACert(t, filepath.Join(t.TempDir(), "missing.crt")), "failed to read the CA certificate")
+	})
+}
+
+func TestAddCertificateAuthority(t *testing.T) {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: management
+  cluster:
+    server: https://mgmt.example.com
+    certificate-authority-data: Y2x1c3Rlci1jYQ==
+contexts:
+- name: byoh
+  context:
+    cluster: management
+    namespace: tenant
+current-context: byoh
+`
+	ca := []byte("-----BEGIN CERTIFICATE-----\naW50ZXJuYWwtY2E=\n-----END CERTIFICATE-----\n")
+
+	updated, err := addCertificateAuthority([]byte(kubeconfig), ca)
+	require.NoError(t, err)
+	config, err := clientcmd.Load(updated)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster-ca\n"+string(ca), string(config.Clusters["management"].CertificateAuthorityData))
+	assert.Equal(t, "tenant", config.Contexts["byoh"].Namespace)
+
+	// the CA is added once
+	again, err := addCertificateAuthority(updated, ca)
+	require.NoError(t, err)
+	assert.Equal(t, string(updated), string(again))
+}
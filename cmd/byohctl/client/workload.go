@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// hostnameLabel is the node label the local persistent volumes are pinned to their node with
+const hostnameLabel = "kubernetes.io/hostname"
+
+// DoNotEvictAnnotations are the annotations marking the pods that must not be evicted from their
+// node, with the value that marks them
+var DoNotEvictAnnotations = map[string]string{
+	"byoh.infrastructure.cluster.x-k8s.io/do-not-evict": "true",
+	"karpenter.sh/do-not-evict":                         "true",
+	"karpenter.sh/do-not-disrupt":                       "true",
+	"cluster-autoscaler.kubernetes.io/safe-to-evict":    "false",
+}
+
+// GetWorkloadClient returns a clientset of the workload cluster clusterName of namespace, built
+// from the kubeconfig secret Cluster API keeps for the cluster
+func (client *Client) GetWorkloadClient(namespace, clusterName string) (kubernetes.Interface, error) {
+	secretName := clusterName + "-kubeconfig"
+	secret, err := client.Clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting the kubeconfig of cluster %s: %w", clusterName, err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data["value"])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the kubeconfig of cluster %s: %w", clusterName, err)
+	}
+	workloadClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the client of cluster %s: %w", clusterName, err)
+	}
+	return workloadClient, nil
+}
+
+// CriticalWorkloads returns the workloads of the node nodeName that are lost or disrupted with the
+// host: its pods annotated not to be evicted and the local persistent volumes pinned to it
+func CriticalWorkloads(clientset kubernetes.Interface, nodeName string) ([]string, error) {
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "spec.nodeName=" + nodeName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the pods of node %s: %w", nodeName, err)
+	}
+	var workloads []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		for annotation, value := range DoNotEvictAnnotations {
+			if pod.Annotations[annotation] == value {
+				workloads = append(workloads, fmt.Sprintf("pod %s/%s (%s=%s)", pod.Namespace, pod.Name, annotation, value))
+				break
+			}
+		}
+	}
+
+	volumes, err := clientset.CoreV1().PersistentVolumes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the persistent volumes: %w", err)
+	}
+	for i := range volumes.Items {
+		volume := &volumes.Items[i]
+		if !isLocalVolumeOf(volume, nodeName) {
+			continue
+		}
+		workload := "local persistent volume " + volume.Name
+		if claim := volume.Spec.ClaimRef; claim != nil {
+			workload += fmt.Sprintf(" (claimed by %s/%s)", claim.Namespace, claim.Name)
+		}
+		workloads = append(workloads, workload)
+	}
+	sort.Strings(workloads)
+	return workloads, nil
+}
+
+// isLocalVolumeOf reports whether volume is a local or host path volume pinned to the node nodeName
+func isLocalVolumeOf(volume *corev1.PersistentVolume, nodeName string) bool {
+	if volume.Spec.Local == nil && volume.Spec.HostPath == nil {
+		return false
+	}
+	if volume.Spec.NodeAffinity == nil || volume.Spec.NodeAffinity.Required == nil {
+		return false
+	}
+	for _, term := range volume.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, expression := range term.MatchExpressions {
+			if expression.Key != hostnameLabel || expression.Operator != corev1.NodeSelectorOpIn {
+				continue
+			}
+			for _, value := range expression.Values {
+				if value == nodeName {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
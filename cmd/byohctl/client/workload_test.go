@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// localVolume returns a local persistent volume pinned to the node nodeName
+func localVolume(name, nodeName string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{Local: &corev1.LocalVolumeSource{Path: "/mnt/disks/" + name}},
+			ClaimRef:               &corev1.ObjectReference{Namespace: "db", Name: "data-" + name},
+			NodeAffinity: &corev1.VolumeNodeAffinity{Required: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{
+					Key:      hostnameLabel,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{nodeName},
+				}}}},
+			}},
+		},
+	}
+}
+
+func TestCriticalWorkloads(t *testing.T) {
+	pod := func(name string, annotations map[string]string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "db", Name: name, Annotations: annotations},
+			Spec:       corev1.PodSpec{NodeName: "node-1"},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		pod("postgres-0", map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "false"}, corev1.PodRunning),
+		pod("web", map[string]string{"cluster-autoscaler.kubernetes.io/safe-to-evict": "true"}, corev1.PodRunning),
+		pod("migration", map[string]string{"karpenter.sh/do-not-evict": "true"}, corev1.PodSucceeded),
+		localVolume("pv-1", "node-1"),
+		localVolume("pv-2", "node-2"),
+		&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "nfs"}},
+	)
+
+	workloads, err := CriticalWorkloads(clientset, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"local persistent volume pv-1 (claimed by db/data-pv-1)",
+		"pod db/postgres-0 (cluster-autoscaler.kubernetes.io/safe-to-evict=false)",
+	}, workloads)
+
+	workloads, err = CriticalWorkloads(fake.NewSimpleClientset(), "node-1")
+	require.NoError(t, err)
+	assert.Empty(t, workloads)
+}
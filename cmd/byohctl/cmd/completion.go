@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/spf13/cobra"
+)
+
+// verbosityLevels are the values of the --verbosity flags
+var verbosityLevels = []string{"all", "important", "minimal", "critical", "none"}
+
+// defaultTenant is the tenant onboarded to when --tenant is not set
+const defaultTenant = "service"
+
+// registerOnboardCompletions adds the dynamic completions of the flags added by AddOnboardFlags
+func registerOnboardCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("region", completeRegions)
+	_ = cmd.RegisterFlagCompletionFunc("tenant", completeTenants)
+	_ = cmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
+	_ = cmd.MarkFlagFilename("config", "yaml", "yml")
+}
+
+// completeRegions completes the regions available to the tenant. They are read from the management
+// plane with the kubeconfig of the host, so they are only completed on a host that is onboarded.
+func completeRegions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	regions, err := client.ListRegions(service.KubeconfigFilePath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return withPrefix(regions, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTenants completes the tenant of the onboarding config file, the profile passed with
+// --config, and the default tenant
+func completeTenants(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	tenants := []string{defaultTenant}
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		if cfg, err := LoadOnboardConfig(path); err == nil && cfg.Tenant != "" && cfg.Tenant != defaultTenant {
+			tenants = append(tenants, cfg.Tenant)
+		}
+	}
+	return withPrefix(tenants, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeVerbosity(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return withPrefix(verbosityLevels, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// withPrefix returns the values starting with prefix
+func withPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompleteVerbosity(t *testing.T) {
+	values, directive := completeVerbosity(onboardCmd, nil, "m")
+	assert.Equal(t, []string{"minimal"}, values)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
+
+func TestCompleteTenants(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("config", "", "")
+	values, _ := completeTenants(cmd, nil, "")
+	assert.Equal(t, []string{"service"}, values)
+
+	config := filepath.Join(t.TempDir(), "onboard.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("tenant: engineering\n"), 0644))
+	require.NoError(t, cmd.Flags().Set("config", config))
+	values, _ = completeTenants(cmd, nil, "")
+	assert.Equal(t, []string{"service", "engineering"}, values)
+	values, _ = completeTenants(cmd, nil, "eng")
+	assert.Equal(t, []string{"engineering"}, values)
+}
+
+func TestCompleteRegions(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	values, _ := completeRegions(onboardCmd, nil, "")
+	assert.Empty(t, values, "no regions are completed on a host that is not onboarded")
+
+	plane.AddRegions(onboardedHost(t, plane), "region-one", "region-two")
+
+	values, directive := completeRegions(onboardCmd, nil, "region-t")
+	assert.Equal(t, []string{"region-two"}, values)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
@@ -7,10 +7,14 @@ import (
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/pkg"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	"github.com/spf13/cobra"
 )
 
+// forceHostOperation makes deauthorise and decommission remove the host from its cluster even if it runs critical workloads
+var forceHostOperation bool
+
 var deauthoriseCmd = &cobra.Command{
 	Use:   "deauthorise",
 	Short: "Deauthorise a host from the respective byo cluster",
@@ -18,7 +22,10 @@ var deauthoriseCmd = &cobra.Command{
 This command will:
 1. Authenticate with Platform9
 2. Deauthorise the host from the byo cluster
-3. Host must have been part of some cluster before deauthorisation`,
+3. Host must have been part of some cluster before deauthorisation
+
+The host is not deauthorised while its node runs pods annotated not to be evicted, e.g.
+cluster-autoscaler.kubernetes.io/safe-to-evict=false, or has local persistent volumes, unless --force is set.`,
 	Example: `  byohctl deauthorise -v all`,
 	Run:     runDeauthorise,
 }
@@ -26,6 +33,10 @@ This command will:
 func init() {
 	rootCmd.AddCommand(deauthoriseCmd)
 	deauthoriseCmd.Flags().StringVarP(&verbosity, "verbosity", "v", "minimal", "Log verbosity level (all, important, minimal, critical, none)")
+	deauthoriseCmd.Flags().BoolVar(&forceHostOperation, "force", false, "Continue even if the host runs pods annotated not to be evicted or has local persistent volumes")
+	deauthoriseCmd.Flags().StringVar(&service.HostNameOverride, "host-name", "",
+		"Name of the ByoHost of the host, by default the --host-name it was onboarded with or its hostname")
+	_ = deauthoriseCmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
 }
 
 func runDeauthorise(cmd *cobra.Command, args []string) {
@@ -35,15 +46,18 @@ func runDeauthorise(cmd *cobra.Command, args []string) {
 	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
 	if err != nil {
 		fmt.Println("Failed to get namespace from kubeconfig: " + err.Error())
-		os.Exit(1)
+		os.Exit(types.ExitCode(err))
 	}
 
-	err = pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace)
+	err = pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, forceHostOperation)
 	if err != nil {
 		fmt.Println("Failed to deauthorise host. " + err.Error())
-		os.Exit(1)
+		utils.PrintSummary("deauthorise", false)
+		os.Exit(types.ExitCode(err))
 	}
 
-	utils.LogSuccess("Successfully deauthorised host from the byo cluster")
+	utils.RecordStep(utils.LogLocation, "Agent service logs, with the cleanup of the host: %s", service.ByohAgentLogPath)
+	utils.RecordStep(utils.NextStep, "The host can be attached to another machine, byohctl decommission removes it from the management plane")
+	utils.PrintSummary("deauthorise", true)
 
 }
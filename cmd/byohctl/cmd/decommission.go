@@ -7,6 +7,7 @@ import (
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/pkg"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	"github.com/spf13/cobra"
 )
@@ -18,7 +19,10 @@ var decommissionCmd = &cobra.Command{
 This command will:
 1. Authenticate with Platform9
 2. Decommission the host from the pf9 kaapi management cluster
-3. If host is part of some cluster, decommission will deauthorise the host first and then decommission`,
+3. If host is part of some cluster, decommission will deauthorise the host first and then decommission
+
+The host is not removed from its cluster while its node runs pods annotated not to be evicted, e.g.
+cluster-autoscaler.kubernetes.io/safe-to-evict=false, or has local persistent volumes, unless --force is set.`,
 	Example: `  byohctl decommission -v all`,
 	Run:     runDecommission,
 }
@@ -26,6 +30,10 @@ This command will:
 func init() {
 	rootCmd.AddCommand(decommissionCmd)
 	decommissionCmd.Flags().StringVarP(&verbosity, "verbosity", "v", "minimal", "Log verbosity level (all, important, minimal, critical, none)")
+	decommissionCmd.Flags().BoolVar(&forceHostOperation, "force", false, "Continue even if the host runs pods annotated not to be evicted or has local persistent volumes")
+	decommissionCmd.Flags().StringVar(&service.HostNameOverride, "host-name", "",
+		"Name of the ByoHost of the host, by default the --host-name it was onboarded with or its hostname")
+	_ = decommissionCmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
 }
 
 func runDecommission(cmd *cobra.Command, args []string) {
@@ -35,14 +43,16 @@ func runDecommission(cmd *cobra.Command, args []string) {
 	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
 	if err != nil {
 		fmt.Println("Failed to get namespace from kubeconfig: " + err.Error())
-		os.Exit(1)
+		os.Exit(types.ExitCode(err))
 	}
 
-	err = pkg.PerformHostOperation(pkg.OperationDecommission, namespace)
+	err = pkg.PerformHostOperation(pkg.OperationDecommission, namespace, forceHostOperation)
 	if err != nil {
 		fmt.Println("Failed to decommission host. " + err.Error())
-		os.Exit(1)
+		utils.PrintSummary("decommission", false)
+		os.Exit(types.ExitCode(err))
 	}
 
-	utils.LogSuccess("Successfully decommissioned host from the pf9 kaapi management cluster")
+	utils.RecordStep(utils.NextStep, "byohctl onboard to onboard the host again")
+	utils.PrintSummary("decommission", true)
 }
@@ -0,0 +1,842 @@
+package cmd
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/internal/fakeplane"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/pkg"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// useFakePlane points byohctl at a fake management plane and runs its commands on a fake host,
+// with the home directory in a temporary directory
+func useFakePlane(t *testing.T) (*fakeplane.Plane, *fakeplane.Runner) {
+	plane := fakeplane.New(t)
+	runner := fakeplane.NewRunner()
+	runner.On("imgpkg pull", fakeplane.PullFile(service.ByohAgentDebPackageFilename))
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	origTransport, origRunner := client.Transport, service.CommandRunner
+	origByohDir, origKubeconfigFilePath := service.ByohDir, service.KubeconfigFilePath
+	client.Transport = plane.Transport()
+	service.CommandRunner = runner
+	service.ByohDir = filepath.Join(home, service.ByohConfigDir)
+	service.KubeconfigFilePath = filepath.Join(service.ByohDir, "config")
+	origHostLockPath, origOSReleasePath := service.HostLockPath, service.OSReleasePath
+	service.HostLockPath = filepath.Join(home, "host.lock")
+	service.OSReleasePath = filepath.Join(home, "os-release")
+	require.NoError(t, os.WriteFile(service.OSReleasePath, []byte(fakeplane.UbuntuOSRelease), service.DefaultFilePerms))
+	// the failed network operations are retried without delay
+	origBackoff := utils.NetworkBackoff
+	utils.NetworkBackoff = utils.Backoff{Attempts: 3}
+	t.Cleanup(func() {
+		client.Transport, service.CommandRunner = origTransport, origRunner
+		service.ByohDir, service.KubeconfigFilePath = origByohDir, origKubeconfigFilePath
+		service.HostLockPath, service.OSReleasePath = origHostLockPath, origOSReleasePath
+		utils.NetworkBackoff = origBackoff
+		resetOnboardGlobals()
+	})
+	return plane, runner
+}
+
+// setOnboardFlags sets the flags of an onboarding of the fake management plane to region
+func setOnboardFlags(plane *fakeplane.Plane, region string) {
+	fqdn = plane.FQDN()
+	username = fakeplane.Username
+	password = fakeplane.Password
+	clientToken = fakeplane.ClientToken
+	domain = "default"
+	tenant = "service"
+	regionName = region
+}
+
+// onboardedHost returns the namespace of a host onboarded to the fake management plane
+func onboardedHost(t *testing.T, plane *fakeplane.Plane) string {
+	namespace := plane.Namespace("default", "service")
+	require.NoError(t, os.MkdirAll(service.ByohDir, service.DefaultDirPerms))
+	require.NoError(t, os.WriteFile(service.KubeconfigFilePath, plane.Kubeconfig(namespace), service.DefaultFilePerms))
+	configNamespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	require.NoError(t, err)
+	require.Equal(t, namespace, configNamespace)
+	return namespace
+}
+
+func TestOnboardHost(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one", "region-two")
+	setOnboardFlags(plane, "region-two")
+
+	require.NoError(t, onboardHost(nil))
+
+	kubeconfig, err := os.ReadFile(service.KubeconfigFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(plane.Kubeconfig(namespace)), string(kubeconfig))
+	region, err := os.ReadFile(filepath.Join(service.ByohDir, "region"))
+	require.NoError(t, err)
+	assert.Equal(t, service.PcdKaapiRegionKey+"=region-two", string(region))
+	packagePath := filepath.Join(service.ByohDir, "packages", service.ByohAgentDebPackageFilename)
+	assert.True(t, runner.Ran("dpkg -i "+packagePath), "commands: %v", runner.Commands())
+}
+
+func TestOnboardHostLabels(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	hostLabels = []string{"topology.kubernetes.io/zone=dc1", "gpu=true"}
+
+	require.NoError(t, onboardHost(nil))
+
+	// the agent-after-install script passes the labels to the --label flag of the agent
+	labels, err := os.ReadFile(filepath.Join(service.ByohDir, "region"))
+	require.NoError(t, err)
+	assert.Equal(t, service.PcdKaapiRegionKey+"=region-one,topology.kubernetes.io/zone=dc1,gpu=true", string(labels))
+}
+
+func TestOnboardHostTaints(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	nodeTaints = []string{"dedicated=gpu:NoSchedule", "maintenance:NoExecute"}
+
+	require.NoError(t, onboardHost(nil))
+
+	// the agent-after-install script passes the taints to the --taint flag of the agent
+	taints, err := os.ReadFile(filepath.Join(service.ByohDir, service.TaintsFilename))
+	require.NoError(t, err)
+	assert.Equal(t, "dedicated=gpu:NoSchedule,maintenance:NoExecute", string(taints))
+}
+
+func TestOnboardHostNamespace(t *testing.T) {
+	t.Run("discovered by the labels of the tenant", func(t *testing.T) {
+		plane, _ := useFakePlane(t)
+		plane.AddNamespace("tenant-service", map[string]string{
+			service.PcdKaapiDomainKey: "default",
+			service.PcdKaapiTenantKey: "service",
+		})
+		plane.AddBootstrapKubeconfig("tenant-service")
+		plane.AddRegions("tenant-service", "region-one")
+		setOnboardFlags(plane, "region-one")
+
+		require.NoError(t, onboardHost(nil))
+		namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, "tenant-service", namespace)
+	})
+
+	t.Run("set explicitly", func(t *testing.T) {
+		plane, _ := useFakePlane(t)
+		plane.AddBootstrapKubeconfig("explicit-namespace")
+		plane.AddRegions("explicit-namespace", "region-one")
+		setOnboardFlags(plane, "region-one")
+		tenantNamespace = "explicit-namespace"
+
+		require.NoError(t, onboardHost(nil))
+		for _, request := range plane.Requests() {
+			assert.NotContains(t, request, plane.Namespace("default", "service"))
+		}
+	})
+}
+
+func TestOnboardHostCACert(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	t.Cleanup(func() { _ = client.UseCACert("") })
+
+	// the system CAs do not trust the management plane
+	client.Transport = http.DefaultTransport
+	require.Error(t, onboardHost(nil))
+
+	caCert = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: plane.Server.Certificate().Raw}))
+	require.NoError(t, onboardHost(nil))
+	kubeconfig, err := clientcmd.LoadFromFile(service.KubeconfigFilePath)
+	require.NoError(t, err)
+	for _, cluster := range kubeconfig.Clusters {
+		assert.Contains(t, string(cluster.CertificateAuthorityData), caCert, "the agent should trust the CA")
+	}
+}
+
+func TestOnboardHostFlakyNetwork(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	// the token request and the first pull of the agent package fail
+	plane.Unavailable = 2
+	pulls := 0
+	runner.On("imgpkg pull", func(args []string) error {
+		pulls++
+		if pulls == 1 {
+			return errors.New("dial tcp: i/o timeout")
+		}
+		return fakeplane.PullFile(service.ByohAgentDebPackageFilename)(args)
+	})
+
+	require.NoError(t, onboardHost(nil))
+	assert.FileExists(t, service.KubeconfigFilePath)
+	assert.Equal(t, 2, pulls)
+	tokenRequests := 0
+	for _, request := range plane.Requests() {
+		if request == "POST /dex/token" {
+			tokenRequests++
+		}
+	}
+	assert.Equal(t, 3, tokenRequests, "the token request should be retried while the plane is unavailable")
+}
+
+func TestOnboardHostWrongPassword(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	password = "wrong-password"
+
+	err := onboardHost(nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrAuth)
+	assert.NoFileExists(t, service.KubeconfigFilePath)
+	assert.Empty(t, runner.Commands())
+}
+
+func TestLoginOnboardHost(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	origKeyring := service.CredentialKeyring
+	service.CredentialKeyring = fakeplane.NewKeyring()
+	t.Cleanup(func() { service.CredentialKeyring = origKeyring })
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+
+	setOnboardFlags(plane, "region-one")
+	password = "wrong-password"
+	assert.ErrorIs(t, login(), types.ErrAuth)
+	_, err := service.LoadCredentials()
+	assert.ErrorIs(t, err, types.ErrNoCredentials)
+
+	password = fakeplane.Password
+	require.NoError(t, login())
+
+	// the onboarding of another user does not use the stored password
+	resetOnboardGlobals()
+	username, domain, tenant = "someone-else@example.com", "default", "service"
+	creds, err := service.LoadCredentials()
+	require.NoError(t, err)
+	assert.False(t, mergeStoredCredentials(creds))
+	assert.Empty(t, password)
+
+	resetOnboardGlobals()
+	domain, tenant, regionName = "default", "service", "region-one"
+	assert.True(t, mergeStoredCredentials(creds))
+	assert.Equal(t, plane.FQDN(), fqdn)
+	assert.Equal(t, fakeplane.Username, username)
+	assert.Equal(t, fakeplane.ClientToken, clientToken)
+	require.NoError(t, onboardHost(nil))
+	assert.FileExists(t, service.KubeconfigFilePath)
+}
+
+func TestOnboardHostAuthToken(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		plane, _ := useFakePlane(t)
+		namespace := plane.Namespace("default", "service")
+		plane.AddBootstrapKubeconfig(namespace)
+		plane.AddRegions(namespace, "region-one")
+		setOnboardFlags(plane, "region-one")
+		username, password, clientToken = "", "", ""
+		authToken = fakeplane.Token
+
+		require.NoError(t, onboardHost(nil))
+		assert.FileExists(t, service.KubeconfigFilePath)
+		assert.NotContains(t, plane.Requests(), "POST /dex/token", "the password grant should be skipped")
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		plane, runner := useFakePlane(t)
+		namespace := plane.Namespace("default", "service")
+		plane.AddBootstrapKubeconfig(namespace)
+		plane.AddRegions(namespace, "region-one")
+		setOnboardFlags(plane, "region-one")
+		authToken = "revoked-token"
+
+		err := onboardHost(nil)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, types.ErrAuth)
+		assert.NoFileExists(t, service.KubeconfigFilePath)
+		assert.Empty(t, runner.Commands())
+	})
+}
+
+func TestOnboardHostClientCredentials(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	username, password, clientToken = "", "", ""
+	clientID, clientSecret = fakeplane.ServiceClientID, fakeplane.ServiceClientSecret
+
+	require.NoError(t, onboardHost(nil))
+	assert.FileExists(t, service.KubeconfigFilePath)
+
+	clientSecret = "wrong-secret"
+	assert.ErrorIs(t, onboardHost(nil), types.ErrAuth)
+}
+
+func TestOnboardHostTOTP(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	plane.TOTP = "123456"
+	setOnboardFlags(plane, "region-one")
+
+	// the code can not be prompted for without a terminal
+	assert.ErrorIs(t, onboardHost(nil), types.ErrMFARequired)
+	assert.NoFileExists(t, service.KubeconfigFilePath)
+
+	totpCode = plane.TOTP
+	require.NoError(t, onboardHost(nil))
+	assert.FileExists(t, service.KubeconfigFilePath)
+}
+
+func TestPlanOnboarding(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	runner.Set("apt-cache policy", "  Installed: (none)\n  Candidate: 1.0\n", nil)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+
+	actions, err := planOnboarding(nil)
+	require.NoError(t, err)
+	assert.Contains(t, actions, "Write the kubeconfig of the agent in namespace "+namespace+" to "+service.KubeconfigFilePath)
+	assert.Contains(t, actions, "Write the labels "+service.PcdKaapiRegionKey+"=region-one of the ByoHost to "+filepath.Join(service.ByohDir, "region"))
+	assert.Contains(t, actions, "Install the package socat")
+	assert.NoDirExists(t, service.ByohDir)
+	assert.NoFileExists(t, service.HostLockPath)
+	for _, command := range runner.Commands() {
+		assert.NotRegexp(t, `^(apt-get|imgpkg|dpkg -i)`, command)
+	}
+
+	agentVersion = "0.1.500"
+	actions, err = planOnboarding(nil)
+	require.NoError(t, err)
+	pkgDir := filepath.Join(service.ByohDir, "packages")
+	assert.Contains(t, actions, "Pull the agent package "+service.ByohAgentDebPackageRepository+":0.1.500 into "+pkgDir)
+
+	regionName = "region-two"
+	_, err = planOnboarding(nil)
+	assert.ErrorIs(t, err, types.ErrRegionUnavailable)
+	assert.NoDirExists(t, service.ByohDir)
+}
+
+func TestOnboardHostUnavailableRegion(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-two")
+
+	err := onboardHost(nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrRegionUnavailable)
+	assert.Contains(t, err.Error(), "region-two")
+	assert.NoDirExists(t, service.ByohDir, "the onboarding should be rolled back")
+	assert.False(t, runner.Ran("dpkg -i"), "commands: %v", runner.Commands())
+}
+
+func TestOnboardHostRollback(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	nodeTaints = []string{"dedicated=gpu:NoSchedule"}
+	runner.Set("dpkg -l dpkg", "ii  dpkg  1.21  amd64", nil)
+	runner.Set("dpkg -i", "dpkg: error processing archive", errors.New("exit status 1"))
+	// the log of byohctl is in the BYOH directory before the onboarding
+	logFile := filepath.Join(service.ByohDir, "byoh-agent-debug.log")
+	require.NoError(t, os.MkdirAll(service.ByohDir, service.DefaultDirPerms))
+	require.NoError(t, os.WriteFile(logFile, []byte("onboarding"), service.DefaultFilePerms))
+
+	err := onboardHost(nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrPackageInstall)
+	assert.FileExists(t, logFile)
+	for _, name := range []string{"config", "region", service.TaintsFilename} {
+		assert.NoFileExists(t, filepath.Join(service.ByohDir, name), "the onboarding should be rolled back")
+	}
+	assert.NoDirExists(t, filepath.Join(service.ByohDir, "packages"))
+	for _, name := range []string{service.ByohAgentServiceName, "socat", "conntrack"} {
+		assert.True(t, runner.Ran("dpkg --purge "+name), "commands: %v", runner.Commands())
+	}
+	assert.False(t, runner.Ran("dpkg --purge dpkg"), "the packages installed before should be kept")
+}
+
+func TestOnboardHostResume(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	// the interrupted onboarding saved the kubeconfig and wrote the files of the agent
+	require.NoError(t, os.MkdirAll(service.ByohDir, service.DefaultDirPerms))
+	checkpoint, err := service.LoadCheckpoint(service.ByohDir, onboardSettings())
+	require.NoError(t, err)
+	require.NoError(t, checkpoint.Complete(stepSaveKubeconfig))
+	require.NoError(t, checkpoint.Complete(stepWriteAgentFiles))
+
+	require.NoError(t, onboardHost(nil))
+
+	for _, request := range plane.Requests() {
+		assert.NotContains(t, request, "byoh-bootstrap-kc", "the saved kubeconfig should not be saved again")
+	}
+	assert.True(t, runner.Ran("dpkg -i"), "commands: %v", runner.Commands())
+	assert.NoFileExists(t, filepath.Join(service.ByohDir, service.CheckpointFilename))
+}
+
+func TestOnboardHostResumeAfterFailedPull(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	// the network is down during every pull of the agent package
+	runner.On("imgpkg pull", func(args []string) error { return errors.New("dial tcp: i/o timeout") })
+
+	err := onboardHost(nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrDownload)
+	// only the failed step is rolled back
+	assert.FileExists(t, service.KubeconfigFilePath)
+	assert.FileExists(t, filepath.Join(service.ByohDir, "region"))
+	assert.FileExists(t, filepath.Join(service.ByohDir, service.CheckpointFilename))
+	assert.NoDirExists(t, filepath.Join(service.ByohDir, "packages"))
+
+	// the rerun resumes after the saved kubeconfig and the written files of the agent
+	requests := len(plane.Requests())
+	runner.On("imgpkg pull", fakeplane.PullFile(service.ByohAgentDebPackageFilename))
+	require.NoError(t, onboardHost(nil))
+	for _, request := range plane.Requests()[requests:] {
+		assert.NotContains(t, request, "byoh-bootstrap-kc", "the saved kubeconfig should not be saved again")
+	}
+	assert.True(t, runner.Ran("dpkg -i"), "commands: %v", runner.Commands())
+	assert.NoFileExists(t, filepath.Join(service.ByohDir, service.CheckpointFilename))
+}
+
+func TestOnboardHostAgentChecksumMismatch(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	agentChecksum = strings.Repeat("0", 64)
+
+	err := onboardHost(nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrVerify)
+	assert.Equal(t, types.ExitVerify, types.ExitCode(err))
+	assert.False(t, runner.Ran("dpkg -i"), "commands: %v", runner.Commands())
+}
+
+func TestDeauthoriseHost(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.AddMachineDeployment(namespace, "md-0", 2)
+	plane.AddMachine(namespace, "md-0-abcde", "md-0")
+	plane.AddByoHost(namespace, hostName, "md-0-abcde")
+
+	require.NoError(t, pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, false))
+
+	assert.Nil(t, plane.Get("machines", namespace, "md-0-abcde"), "the machine of the host should be deleted")
+	deployment := plane.Get("machinedeployments", namespace, "md-0")
+	require.NotNil(t, deployment)
+	assert.EqualValues(t, 1, deployment["spec"].(map[string]interface{})["replicas"])
+	byoHost := plane.Get("byohosts", namespace, hostName)
+	require.NotNil(t, byoHost, "deauthorise should keep the host")
+	assert.Nil(t, byoHost["status"].(map[string]interface{})["machineRef"])
+	assert.False(t, runner.Ran("dpkg --purge"), "commands: %v", runner.Commands())
+}
+
+func TestDeauthoriseLastHostUnattended(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.AddMachineDeployment(namespace, "md-0", 1)
+	plane.AddMachine(namespace, "md-0-abcde", "md-0")
+	plane.AddByoHost(namespace, hostName, "md-0-abcde")
+	defer func() { utils.AssumeYes, utils.NonInteractive = false, false }()
+
+	// the confirmation to remove the last node of the cluster cannot be asked
+	utils.NonInteractive = true
+	err = pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, false)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrCancelled)
+	assert.NotNil(t, plane.Get("machines", namespace, "md-0-abcde"), "the machine of the host should be kept")
+
+	utils.AssumeYes = true
+	require.NoError(t, pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, false))
+	assert.Nil(t, plane.Get("machines", namespace, "md-0-abcde"), "the machine of the host should be deleted")
+}
+
+func TestDeauthoriseDetachedHost(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.AddByoHost(namespace, hostName, "")
+
+	err = pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, false)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrHostNotAttached)
+	assert.NotNil(t, plane.Get("byohosts", namespace, hostName))
+}
+
+func TestDecommissionHost(t *testing.T) {
+	for _, attached := range []bool{true, false} {
+		name := "detached host"
+		if attached {
+			name = "attached host"
+		}
+		t.Run(name, func(t *testing.T) {
+			plane, runner := useFakePlane(t)
+			namespace := onboardedHost(t, plane)
+			hostName, err := os.Hostname()
+			require.NoError(t, err)
+			machineName := ""
+			if attached {
+				machineName = "md-0-abcde"
+				plane.AddMachineDeployment(namespace, "md-0", 2)
+				plane.AddMachine(namespace, machineName, "md-0")
+			}
+			plane.AddByoHost(namespace, hostName, machineName)
+
+			require.NoError(t, pkg.PerformHostOperation(pkg.OperationDecommission, namespace, false))
+
+			assert.Nil(t, plane.Get("byohosts", namespace, hostName), "the host should be deleted")
+			assert.Empty(t, plane.List("machines", namespace))
+			operations := plane.List("byohostoperations", namespace)
+			require.Len(t, operations, 1)
+			spec := operations[0]["spec"].(map[string]interface{})
+			assert.Equal(t, "Decommission", spec["operation"])
+			assert.Equal(t, "Succeeded", spec["outcome"])
+			assert.True(t, strings.HasSuffix(spec["initiator"].(string), "@"+hostName))
+			assert.True(t, runner.Ran("dpkg --purge "+service.ByohAgentServiceName), "commands: %v", runner.Commands())
+		})
+	}
+}
+
+func TestDecommissionHostName(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one")
+	setOnboardFlags(plane, "region-one")
+	byoHostName = "rack1-node3"
+	service.HostNameOverride = byoHostName
+
+	require.NoError(t, onboardHost(nil))
+	name, err := os.ReadFile(filepath.Join(service.ByohDir, service.HostNameFilename))
+	require.NoError(t, err)
+	assert.Equal(t, "rack1-node3", string(name))
+
+	// decommission finds the ByoHost by the --host-name of onboard, not by the hostname
+	service.HostNameOverride = ""
+	plane.AddByoHost(namespace, "rack1-node3", "")
+	require.NoError(t, pkg.PerformHostOperation(pkg.OperationDecommission, namespace, false))
+	assert.Nil(t, plane.Get("byohosts", namespace, "rack1-node3"), "the host should be deleted")
+}
+
+func TestDeauthoriseHostCriticalWorkloads(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.AddMachineDeployment(namespace, "md-0", 2)
+	plane.AddMachine(namespace, "md-0-abcde", "md-0")
+	plane.AddByoHost(namespace, hostName, "md-0-abcde")
+	plane.AddWorkloadNode(namespace, "workload", "md-0-abcde", "node-1")
+	plane.AddPod("db", "postgres-0", "node-1", map[string]string{"karpenter.sh/do-not-evict": "true"})
+	plane.AddPod("web", "nginx", "node-1", nil)
+
+	err = pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, false)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, types.ErrCriticalWorkloads)
+	assert.NotNil(t, plane.Get("machines", namespace, "md-0-abcde"), "the machine of the host should be kept")
+
+	require.NoError(t, pkg.PerformHostOperation(pkg.OperationDeauthorise, namespace, true))
+	assert.Nil(t, plane.Get("machines", namespace, "md-0-abcde"), "the machine of the host should be deleted")
+}
+
+func TestHostStatus(t *testing.T) {
+	plane, runner := useFakePlane(t)
+
+	// a host that is not onboarded
+	status := collectHostStatus(time.Now())
+	assert.Equal(t, service.AgentServiceNotInstalled, status.AgentService)
+	assert.Equal(t, "missing", status.Kubeconfig)
+	assert.Len(t, status.Problems, 2)
+
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	heartbeat := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	plane.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", namespace, hostName, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"cluster.x-k8s.io/cluster-name": "workload"},
+		},
+		"status": map[string]interface{}{
+			"machineRef":        map[string]interface{}{"kind": "Machine", "namespace": namespace, "name": "md-0-abcde"},
+			"agentVersion":      "0.1.500",
+			"lastHeartbeatTime": heartbeat.Format(time.RFC3339),
+			"conditions": []interface{}{map[string]interface{}{
+				"type": "AgentConnected", "status": "True", "lastTransitionTime": heartbeat.Format(time.RFC3339),
+			}},
+		},
+	})
+	runner.Set("systemctl list-unit-files", "pf9-byohost-agent.service enabled enabled\n", nil)
+	runner.Set("systemctl is-active", "active\n", nil)
+	runner.Set("dpkg-query -W", "0.1.500", nil)
+
+	status = collectHostStatus(time.Now())
+	assert.Empty(t, status.Problems)
+	assert.Equal(t, "active", status.AgentService)
+	assert.Equal(t, "0.1.500", status.AgentVersion)
+	assert.Equal(t, "valid", status.Kubeconfig)
+	assert.Equal(t, hostName, status.ByoHost)
+	assert.Equal(t, "True", status.Connected)
+	require.NotNil(t, status.LastHeartbeat)
+	assert.True(t, heartbeat.Equal(*status.LastHeartbeat))
+	assert.Equal(t, "md-0-abcde", status.Machine)
+	assert.Equal(t, "workload", status.Cluster)
+
+	// a stopped agent whose heartbeats are stale
+	runner.Set("systemctl is-active", "failed\n", errors.New("exit status 3"))
+	plane.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", namespace, hostName, map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{
+				"type": "AgentConnected", "status": "False", "reason": "HeartbeatStale", "message": "no heartbeat for 5m",
+				"lastTransitionTime": heartbeat.Format(time.RFC3339),
+			}},
+		},
+	})
+	status = collectHostStatus(time.Now())
+	assert.Equal(t, "False (HeartbeatStale: no heartbeat for 5m)", status.Connected)
+	assert.Len(t, status.Problems, 2)
+	var out strings.Builder
+	require.NoError(t, printHostStatusTable(&out, status))
+	assert.Contains(t, out.String(), "The host is not healthy")
+}
+
+func TestLogs(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	origAgentLogPath := agentLogPath
+	agentLogPath = filepath.Join(t.TempDir(), "byoh-agent.log")
+	defer func() { agentLogPath, logsLines = origAgentLogPath, 100 }()
+	require.NoError(t, os.WriteFile(agentLogPath, []byte("I0105 09:00:00.000000    1 main.go:1] first\nI0105 10:00:00.000000    1 main.go:1] second\n"), service.DefaultFilePerms))
+	require.NoError(t, os.WriteFile(filepath.Join(service.ByohDir, utils.DebugLogFilename), []byte("[2026-01-05 09:30:00] [INFO] Setting up BYOH agent\n"), service.DefaultFilePerms))
+
+	logsLines = 1
+	var out strings.Builder
+	offsets, err := tailLogs(&out, logNames, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "agent   | I0105 10:00:00.000000    1 main.go:1] second\nbyohctl | [2026-01-05 09:30:00] [INFO] Setting up BYOH agent\n", out.String())
+	assert.Len(t, offsets, 2)
+
+	// the only log printed is not prefixed, a missing log is skipped unless it is the only one
+	out.Reset()
+	_, err = tailLogs(&out, []string{"agent"}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "I0105 10:00:00.000000    1 main.go:1] second\n", out.String())
+	require.NoError(t, os.Remove(agentLogPath))
+	_, err = tailLogs(&out, []string{"agent"}, time.Time{})
+	assert.ErrorIs(t, err, types.ErrNotOnboarded)
+
+	now := time.Now().UTC()
+	for name, occurred := range map[string]time.Time{"recent": now.Add(-10 * time.Minute), "old": now.Add(-2 * time.Hour)} {
+		plane.Add("v1", "Event", namespace, hostName+"."+name, map[string]interface{}{
+			"involvedObject": map[string]interface{}{"kind": "ByoHost", "namespace": namespace, "name": hostName},
+			"type":           "Warning",
+			"reason":         "HeartbeatStale",
+			"message":        name + " heartbeat",
+			"count":          1,
+			"lastTimestamp":  occurred.Format(time.RFC3339),
+		})
+	}
+	out.Reset()
+	require.NoError(t, printByoHostEvents(&out, now.Add(-time.Hour)))
+	assert.Contains(t, out.String(), "recent heartbeat")
+	assert.NotContains(t, out.String(), "old heartbeat", "the events before --since should not be printed")
+}
+
+func TestUpgradeAgent(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	namespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	packagePath := filepath.Join(service.ByohDir, "packages", service.ByohAgentDebPackageFilename)
+	require.NoError(t, os.MkdirAll(filepath.Dir(packagePath), service.DefaultDirPerms))
+	require.NoError(t, os.WriteFile(packagePath, []byte("previous package"), service.DefaultFilePerms))
+	runner.Set("dpkg-query -W", "0.1.450", nil)
+	addByoHost := func(heartbeat time.Time) {
+		plane.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", namespace, hostName, map[string]interface{}{
+			"status": map[string]interface{}{
+				"lastHeartbeatTime": heartbeat.UTC().Format(time.RFC3339),
+				"conditions": []interface{}{map[string]interface{}{
+					"type": "AgentConnected", "status": "True", "lastTransitionTime": heartbeat.UTC().Format(time.RFC3339),
+				}},
+			},
+		})
+	}
+
+	// the upgraded agent sends a heartbeat
+	addByoHost(time.Now().Add(time.Minute))
+	agentVersion = "0.1.500"
+	require.NoError(t, upgradeAgent(namespace, agentPackageSource()))
+	assert.True(t, runner.Ran("dpkg -i "+packagePath), "commands: %v", runner.Commands())
+	content, err := os.ReadFile(packagePath)
+	require.NoError(t, err)
+	assert.Equal(t, fakeplane.FakePackage, string(content))
+
+	// no heartbeat arrives, the previous package is reinstalled
+	require.NoError(t, os.WriteFile(packagePath, []byte("previous package"), service.DefaultFilePerms))
+	addByoHost(time.Now().Add(-time.Hour))
+	origWaitTimeout := service.WaitTimeout
+	service.WaitTimeout = time.Nanosecond
+	defer func() { service.WaitTimeout = origWaitTimeout }()
+	err = upgradeAgent(namespace, agentPackageSource())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout waiting for a heartbeat")
+	assert.True(t, runner.Ran("dpkg -i "+filepath.Join(service.ByohDir, "packages", "previous-"+service.ByohAgentDebPackageFilename)), "commands: %v", runner.Commands())
+	content, err = os.ReadFile(packagePath)
+	require.NoError(t, err)
+	assert.Equal(t, "previous package", string(content))
+}
+
+func TestReauthorizeHost(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	oldNamespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", oldNamespace, hostName, map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{service.PcdKaapiRegionKey: "region-one"}},
+	})
+	regionFile := filepath.Join(service.ByohDir, service.RegionFilename)
+	require.NoError(t, os.WriteFile(regionFile, []byte(service.PcdKaapiRegionKey+"=region-one,gpu=true"), service.DefaultFilePerms))
+	origAgentServiceEnvPath := service.AgentServiceEnvPath
+	service.AgentServiceEnvPath = filepath.Join(t.TempDir(), "pf9-byohost-agent.conf")
+	defer func() { service.AgentServiceEnvPath = origAgentServiceEnvPath }()
+	env := "NAMESPACE=" + oldNamespace + "\nREGION=" + service.PcdKaapiRegionKey + "=region-one,gpu=true\n"
+	require.NoError(t, os.WriteFile(service.AgentServiceEnvPath, []byte(env), service.DefaultFilePerms))
+
+	newNamespace := plane.Namespace("default", "other")
+	plane.AddBootstrapKubeconfig(newNamespace)
+	plane.AddRegions(newNamespace, "region-two")
+	setOnboardFlags(plane, "region-three")
+	tenant = "other"
+	// the restarted agent registers the host in the namespace of its kubeconfig
+	runner.On("systemctl restart", func(args []string) error {
+		namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+		if err != nil {
+			return err
+		}
+		heartbeat := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+		plane.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", namespace, hostName, map[string]interface{}{
+			"status": map[string]interface{}{
+				"lastHeartbeatTime": heartbeat,
+				"conditions": []interface{}{map[string]interface{}{
+					"type": "AgentConnected", "status": "True", "lastTransitionTime": heartbeat,
+				}},
+			},
+		})
+		return nil
+	})
+
+	// the region is not available for the new tenant, the host is moved back
+	err = reauthorizeHost(oldNamespace)
+	assert.ErrorIs(t, err, types.ErrRegionUnavailable)
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, oldNamespace, namespace)
+	content, err := os.ReadFile(service.AgentServiceEnvPath)
+	require.NoError(t, err)
+	assert.Equal(t, env, string(content))
+	assert.True(t, runner.Ran("systemctl restart"), "commands: %v", runner.Commands())
+
+	regionName = "region-two"
+	require.NoError(t, reauthorizeHost(oldNamespace))
+	namespace, err = client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, newNamespace, namespace)
+	labels, err := os.ReadFile(regionFile)
+	require.NoError(t, err)
+	assert.Equal(t, service.PcdKaapiRegionKey+"=region-two,gpu=true", string(labels))
+	content, err = os.ReadFile(service.AgentServiceEnvPath)
+	require.NoError(t, err)
+	assert.Equal(t, "NAMESPACE="+newNamespace+"\nREGION="+service.PcdKaapiRegionKey+"=region-two,gpu=true\n", string(content))
+	assert.NotNil(t, plane.Get("byohosts", newNamespace, hostName))
+	assert.Nil(t, plane.Get("byohosts", oldNamespace, hostName), "the ByoHost of the old namespace should be deleted")
+}
+
+func TestReauthorizeAttachedHost(t *testing.T) {
+	plane, runner := useFakePlane(t)
+	oldNamespace := onboardedHost(t, plane)
+	hostName, err := os.Hostname()
+	require.NoError(t, err)
+	plane.AddByoHost(oldNamespace, hostName, "md-0-abcde")
+	setOnboardFlags(plane, "region-two")
+	tenant = "other"
+
+	err = reauthorizeHost(oldNamespace)
+	assert.ErrorIs(t, err, types.ErrHostAttached)
+	assert.False(t, runner.Ran("systemctl stop"), "commands: %v", runner.Commands())
+}
+
+func TestRegions(t *testing.T) {
+	plane, _ := useFakePlane(t)
+	namespace := plane.Namespace("default", "service")
+	plane.AddBootstrapKubeconfig(namespace)
+	plane.AddRegions(namespace, "region-one", "", "region-two")
+	setOnboardFlags(plane, "")
+
+	// a host that is not onboarded lists the regions with the kubeconfig of the bootstrap secret
+	regions, err := tenantRegions()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"region-one", "region-two"}, regions)
+	_, err = os.Stat(service.KubeconfigFilePath)
+	assert.True(t, os.IsNotExist(err), "the kubeconfig should not be saved")
+
+	var out strings.Builder
+	require.NoError(t, printRegions(&out, regions, "region-two"))
+	assert.Equal(t, "region-one\nregion-two\n", out.String())
+	assert.ErrorIs(t, printRegions(&out, regions, "region-three"), types.ErrRegionUnavailable)
+	assert.Error(t, printRegions(&out, nil, ""))
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var manDir string
+
+var genManCmd = &cobra.Command{
+	Use:   "gen-man",
+	Short: "Generate the man pages of byohctl",
+	Long: `Generate a man page, in section 1, for byohctl and each of its commands from the command tree,
+e.g. byohctl-onboard.1 for byohctl onboard. Install them under a man path, e.g. /usr/local/share/man/man1.`,
+	Example: `  byohctl gen-man --dir /usr/local/share/man/man1`,
+	Args:    cobra.NoArgs,
+	Run:     runGenMan,
+}
+
+func init() {
+	genManCmd.Flags().StringVar(&manDir, "dir", ".", "Directory to write the man pages to")
+	_ = genManCmd.MarkFlagDirname("dir")
+	rootCmd.AddCommand(genManCmd)
+}
+
+func runGenMan(cmd *cobra.Command, args []string) {
+	pages, err := genManPages(rootCmd, manDir, time.Now())
+	if err != nil {
+		fmt.Println("Failed to generate man pages: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+	utils.LogSuccess("Generated %d man pages in %s", len(pages), manDir)
+}
+
+// genManPages writes the man page of cmd and of each of its available subcommands to dir,
+// and returns their paths
+func genManPages(cmd *cobra.Command, dir string, date time.Time) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var pages []string
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		subPages, err := genManPages(sub, dir, date)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, subPages...)
+	}
+
+	path := filepath.Join(dir, manPageName(cmd)+".1")
+	if err := os.WriteFile(path, renderManPage(cmd, date), 0644); err != nil {
+		return nil, err
+	}
+	return append([]string{path}, pages...), nil
+}
+
+// manPageName is the name of the man page of cmd, its command path joined with dashes
+func manPageName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// renderManPage renders the man page of cmd in roff
+func renderManPage(cmd *cobra.Command, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, ".TH %q 1 %q \"byohctl\" \"BYOH Manual\"\n", strings.ToUpper(manPageName(cmd)), date.Format("Jan 2006"))
+	fmt.Fprintf(&buf, ".SH NAME\n%s \\- %s\n", manPageName(cmd), roffEscape(cmd.Short))
+	fmt.Fprintf(&buf, ".SH SYNOPSIS\n.B %s\n", roffEscape(cmd.UseLine()))
+
+	description := cmd.Long
+	if description == "" {
+		description = cmd.Short
+	}
+	fmt.Fprintf(&buf, ".SH DESCRIPTION\n%s\n", roffParagraphs(description))
+
+	writeManFlags(&buf, "OPTIONS", cmd.NonInheritedFlags())
+	writeManFlags(&buf, "OPTIONS INHERITED FROM PARENT COMMANDS", cmd.InheritedFlags())
+
+	if cmd.Example != "" {
+		fmt.Fprintf(&buf, ".SH EXAMPLE\n.nf\n%s\n.fi\n", roffEscape(cmd.Example))
+	}
+
+	var seeAlso []string
+	if cmd.HasParent() {
+		seeAlso = append(seeAlso, manPageName(cmd.Parent()))
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() && !sub.IsAdditionalHelpTopicCommand() {
+			seeAlso = append(seeAlso, manPageName(sub))
+		}
+	}
+	if len(seeAlso) > 0 {
+		references := make([]string, 0, len(seeAlso))
+		for _, page := range seeAlso {
+			references = append(references, fmt.Sprintf("\\fB%s\\fP(1)", page))
+		}
+		fmt.Fprintf(&buf, ".SH SEE ALSO\n%s\n", strings.Join(references, ", "))
+	}
+	return buf.Bytes()
+}
+
+// writeManFlags writes the section of the visible flags of the set, if any
+func writeManFlags(buf *bytes.Buffer, section string, flags *pflag.FlagSet) {
+	var entries []string
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+		name := "\\-\\-" + flag.Name
+		if flag.Shorthand != "" {
+			name = "\\-" + flag.Shorthand + ", " + name
+		}
+		if varname, _ := pflag.UnquoteUsage(flag); varname != "" {
+			name += " " + varname
+		}
+		usage := flag.Usage
+		if flag.DefValue != "" && flag.DefValue != "false" && flag.DefValue != "[]" {
+			usage += fmt.Sprintf(" (default %q)", flag.DefValue)
+		}
+		entries = append(entries, fmt.Sprintf(".TP\n\\fB%s\\fP\n%s", name, roffEscape(usage)))
+	})
+	if len(entries) > 0 {
+		fmt.Fprintf(buf, ".SH %s\n%s\n", section, strings.Join(entries, "\n"))
+	}
+}
+
+// roffParagraphs escapes text and separates its paragraphs and lines for roff
+func roffParagraphs(text string) string {
+	lines := strings.Split(strings.TrimSpace(roffEscape(text)), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ".PP"
+		} else if i > 0 && lines[i-1] != ".PP" {
+			lines[i] = ".br\n" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// roffEscape escapes the backslashes of text, and the dots and quotes that would start a roff request
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\e")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = "\\&" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenManPages(t *testing.T) {
+	dir := t.TempDir()
+	pages, err := genManPages(rootCmd, dir, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Contains(t, pages, filepath.Join(dir, "byohctl.1"))
+	assert.Contains(t, pages, filepath.Join(dir, "byohctl-onboard.1"))
+	assert.Contains(t, pages, filepath.Join(dir, "byohctl-gen-man.1"))
+	assert.NotContains(t, pages, filepath.Join(dir, "byohctl-help.1"))
+
+	page, err := os.ReadFile(filepath.Join(dir, "byohctl-onboard.1"))
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `.TH "BYOHCTL-ONBOARD" 1 "Jan 2026" "byohctl" "BYOH Manual"`)
+	assert.Contains(t, string(page), "byohctl-onboard \\- Onboard a host to Platform9")
+	assert.Contains(t, string(page), "\\fB\\-r, \\-\\-region string\\fP")
+	assert.Contains(t, string(page), "Platform9 tenant (default \"service\")")
+	assert.Contains(t, string(page), ".SH EXAMPLE")
+	assert.Contains(t, string(page), "\\fBbyohctl\\fP(1)")
+}
+
+func TestRoffEscape(t *testing.T) {
+	assert.Equal(t, "\\&.dot\nC:\\eDir", roffEscape(".dot\nC:\\Dir"))
+	assert.Equal(t, "first\n.br\nsecond\n.PP\nthird", roffParagraphs("first\nsecond\n\nthird"))
+}
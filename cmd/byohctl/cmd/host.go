@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostfacts"
+)
+
+var hostFactsOutput string
+
+var hostCmd = &cobra.Command{
+	Use:   "host",
+	Short: "Inspect this host",
+}
+
+var hostFactsCmd = &cobra.Command{
+	Use:   "facts",
+	Short: "Print the facts of this host",
+	Long: `Print the facts of this host collected by the same code as the agent: its OS, architecture, kernel,
+CPUs, memory, disks, network interfaces and virtualization. The host does not need to be onboarded,
+so the facts can be used as an inventory before onboarding.
+
+The OS, the architecture, the CPUs, the memory, the ephemeral storage of ` + hostfacts.EphemeralStoragePath + ` and the network
+interfaces are what the agent reports in the status of the ByoHost of the host; the kernel, the disks
+and the virtualization are printed for the inventory only.`,
+	Example: `  byohctl host facts
+  byohctl host facts -o json`,
+	Run: runHostFacts,
+}
+
+func init() {
+	hostFactsCmd.Flags().StringVarP(&hostFactsOutput, "output", "o", "table", "Output format (table, json)")
+	_ = hostFactsCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	hostCmd.AddCommand(hostFactsCmd)
+	rootCmd.AddCommand(hostCmd)
+}
+
+func runHostFacts(cmd *cobra.Command, args []string) {
+	if hostFactsOutput != "table" && hostFactsOutput != "json" {
+		fmt.Printf("Error: unknown output format %q, use table or json\n", hostFactsOutput)
+		os.Exit(types.ExitUsage)
+	}
+
+	// the agent discovers the default route the same way, a host without one has no default interface
+	route, err := hostfacts.ReadDefaultRoute(os.ReadFile, hostfacts.InterfaceAddrs)
+	if err != nil {
+		utils.LogDebug("No default interface: %v", err)
+	}
+	facts, err := hostfacts.Collect(route)
+	if err != nil {
+		fmt.Println("Failed to get the facts of the host: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+
+	if hostFactsOutput == "json" {
+		err = printHostFactsJSON(os.Stdout, facts)
+	} else {
+		err = printHostFactsTable(os.Stdout, facts)
+	}
+	if err != nil {
+		fmt.Println("Failed to print the facts of the host: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+}
+
+// printHostFactsJSON prints facts to w as indented JSON
+func printHostFactsJSON(w io.Writer, facts *hostfacts.Facts) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(facts)
+}
+
+// printHostFactsTable prints facts to w as a summary of the host followed by tables of its
+// disks and network interfaces
+func printHostFactsTable(w io.Writer, facts *hostfacts.Facts) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "OS:\t%s (%s)\n", facts.OSImage, facts.OSName)
+	fmt.Fprintf(tw, "Architecture:\t%s\n", facts.Architecture)
+	fmt.Fprintf(tw, "Kernel:\t%s\n", facts.KernelVersion)
+	fmt.Fprintf(tw, "CPUs:\t%d\n", facts.CPUs)
+	fmt.Fprintf(tw, "Memory:\t%s\n", formatBytes(facts.MemoryBytes))
+	fmt.Fprintf(tw, "Ephemeral storage:\t%s (%s)\n", formatBytes(facts.EphemeralStorageBytes), hostfacts.EphemeralStoragePath)
+	fmt.Fprintf(tw, "Virtualization:\t%s\n", facts.Virtualization)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISK\tSIZE\tTYPE\tMODEL")
+	for _, disk := range facts.Disks {
+		diskType := "ssd"
+		if disk.Rotational {
+			diskType = "hdd"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", disk.Name, formatBytes(disk.SizeBytes), diskType, disk.Model)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INTERFACE\tMAC\tMTU\tSTATE\tLINK\tADDRESSES\tDEFAULT")
+	for _, iface := range facts.Interfaces {
+		state := "down"
+		if iface.Connected {
+			state = "up"
+		}
+		link := iface.LinkType
+		switch {
+		case len(iface.BondMembers) > 0:
+			link += " (" + strings.Join(iface.BondMembers, ",") + ")"
+		case iface.ParentInterfaceName != "":
+			link += fmt.Sprintf(" (%d on %s)", iface.VLANID, iface.ParentInterfaceName)
+		}
+		defaultRoute := ""
+		if iface.IsDefault {
+			defaultRoute = "via " + iface.Gateway
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", iface.Name, iface.MACAddr, iface.MTU, state, link,
+			strings.Join(iface.IPAddrs, ","), defaultRoute)
+	}
+	return tw.Flush()
+}
+
+// formatBytes formats the size bytes in GiB, or MiB below a GiB
+func formatBytes(bytes int64) string {
+	if bytes < 1<<30 {
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f GiB", float64(bytes)/(1<<30))
+}
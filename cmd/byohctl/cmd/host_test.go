@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostfacts"
+)
+
+func testHostFacts() *hostfacts.Facts {
+	return &hostfacts.Facts{
+		OSName:                "linux",
+		OSImage:               "Ubuntu 22.04.4 LTS",
+		Architecture:          "amd64",
+		KernelVersion:         "5.15.0-91-generic",
+		CPUs:                  8,
+		MemoryBytes:           16 << 30,
+		EphemeralStorageBytes: 100 << 30,
+		Disks: []hostfacts.Disk{
+			{Name: "sda", SizeBytes: 512 << 30, Model: "Samsung SSD 870"},
+			{Name: "sdb", SizeBytes: 2 << 40, Rotational: true},
+		},
+		Interfaces: []hostfacts.Interface{
+			{
+				Name: "bond0", MACAddr: "52:54:00:12:34:56", MTU: 9000, Connected: true,
+				IPAddrs: []string{"192.168.1.10/24"}, IsDefault: true, Gateway: "192.168.1.1",
+				LinkInfo: hostfacts.LinkInfo{LinkType: hostfacts.LinkTypeBond, BondMembers: []string{"eth0", "eth1"}},
+			},
+			{Name: "lo", MTU: 65536, Connected: true, IPAddrs: []string{"127.0.0.1/8"}, LinkInfo: hostfacts.LinkInfo{LinkType: hostfacts.LinkTypeVirtual}},
+		},
+		Virtualization: "kvm",
+	}
+}
+
+func TestPrintHostFactsTable(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printHostFactsTable(&out, testHostFacts()))
+
+	assert.Contains(t, out.String(), "OS:                 Ubuntu 22.04.4 LTS (linux)\n")
+	assert.Contains(t, out.String(), "Memory:             16.0 GiB\n")
+	assert.Contains(t, out.String(), "Virtualization:     kvm\n")
+	assert.Contains(t, out.String(), "sda   512.0 GiB   ssd   Samsung SSD 870\n")
+	assert.Contains(t, out.String(), "sdb   2048.0 GiB  hdd")
+	assert.Regexp(t, `bond0 +52:54:00:12:34:56 +9000 +up +bond \(eth0,eth1\) +192\.168\.1\.10/24 +via 192\.168\.1\.1\n`, out.String())
+}
+
+func TestPrintHostFactsJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printHostFactsJSON(&out, testHostFacts()))
+
+	var facts hostfacts.Facts
+	require.NoError(t, json.Unmarshal(out.Bytes(), &facts))
+	assert.Equal(t, *testHostFacts(), facts)
+	assert.Contains(t, out.String(), `"linkType": "bond"`)
+}
+
+func TestHostFactsOfThisHost(t *testing.T) {
+	facts, err := hostfacts.Collect(hostfacts.DefaultRoute{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	assert.NotEmpty(t, facts.Architecture)
+	assert.Positive(t, facts.CPUs)
+	assert.Positive(t, facts.MemoryBytes)
+}
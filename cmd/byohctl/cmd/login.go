@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Store the credentials of a Platform9 user in the keyring",
+	Long: `Log in to the Platform9 management plane and store the credentials of the user in the kernel
+keyring of the user running byohctl, so that onboard and the later commands authenticate again
+without the password on the command line or in a config file.
+
+The keyring never reaches the disk: it outlives the sessions of the user but not a reboot of the
+host, and the kernel drops it after it is unused for a few days. Log in with sudo, as the other
+commands of byohctl run. byohctl logout removes the credentials.`,
+	Example: `  sudo byohctl login -u your-fqdn.platform9.com -e admin@platform9.com -c client-token --password-interactive
+  sudo BYOHCTL_PASSWORD="$PF9_PASSWORD" byohctl login -u your-fqdn.platform9.com -e admin@platform9.com -c client-token
+  sudo byohctl onboard -r region`,
+	Run: runLogin,
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the credentials stored by byohctl login",
+	Run:   runLogout,
+}
+
+func init() {
+	loginCmd.Flags().StringVarP(&fqdn, "url", "u", "", "Platform9 FQDN")
+	loginCmd.Flags().StringVarP(&username, "username", "e", "", "Platform9 username")
+	loginCmd.Flags().StringVarP(&password, "password", "p", "", "Platform9 password, prefer the "+PasswordEnv+" environment variable")
+	loginCmd.Flags().BoolVar(&passwordInteractive, "password-interactive", false, "Enter password interactively")
+	loginCmd.Flags().StringVar(&passwordFile, "password-file", "", "Path of a file with the password of the user")
+	loginCmd.Flags().StringVarP(&clientToken, "client-token", "c", "", "Client token for authentication")
+	loginCmd.Flags().StringVarP(&domain, "domain", "d", "default", "Platform9 domain")
+	loginCmd.Flags().StringVarP(&tenant, "tenant", "t", "service", "Platform9 tenant")
+	loginCmd.Flags().StringVar(&caCert, "ca-cert", "",
+		"CA certificate of the management plane, as the path of a PEM file or the PEM itself, trusted on top of the system CAs")
+	loginCmd.Flags().StringVarP(&verbosity, "verbosity", "v", "minimal", "Log verbosity level (all, important, minimal, critical, none)")
+	loginCmd.MarkFlagsMutuallyExclusive("password", "password-interactive", "password-file")
+	_ = loginCmd.MarkFlagFilename("password-file")
+	_ = loginCmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
+	rootCmd.AddCommand(loginCmd)
+
+	logoutCmd.Flags().StringVarP(&verbosity, "verbosity", "v", "minimal", "Log verbosity level (all, important, minimal, critical, none)")
+	_ = logoutCmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
+	rootCmd.AddCommand(logoutCmd)
+}
+
+func runLogin(cmd *cobra.Command, args []string) {
+	utils.SetConsoleOutputLevel(verbosity)
+
+	passwordSource, err := resolvePassword(passwordFromEnv())
+	if err != nil {
+		utils.LogError("%v", err)
+		os.Exit(types.ExitUsage)
+	}
+	missing := []string{}
+	if fqdn == "" {
+		missing = append(missing, "--url")
+	}
+	if username == "" {
+		missing = append(missing, "--username")
+	}
+	if clientToken == "" {
+		missing = append(missing, "--client-token")
+	}
+	if password == "" {
+		missing = append(missing, "--password-interactive, --password-file or "+PasswordEnv)
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Error: missing required flags: %s\n", strings.Join(missing, ", "))
+		os.Exit(types.ExitUsage)
+	}
+	utils.LogDebug("Logging in as %s with the password from %s", username, passwordSource)
+
+	if err := login(); err != nil {
+		fmt.Println("Failed to log in: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	utils.LogSuccess("Logged in to %s as %s, the credentials are stored in the keyring", fqdn, username)
+}
+
+// login checks the credentials of the flags with the management plane and stores them in the keyring
+func login() error {
+	if caCert != "" {
+		if err := client.UseCACert(caCert); err != nil {
+			return err
+		}
+	}
+	authClient := client.NewAuthClient(fqdn, clientToken)
+	authClient.SetTOTP("", promptTOTP)
+	if _, err := authClient.GetToken(username, password); err != nil {
+		return err
+	}
+	return service.SaveCredentials(service.Credentials{
+		URL:         fqdn,
+		Username:    username,
+		Password:    password,
+		ClientToken: clientToken,
+		Domain:      domain,
+		Tenant:      tenant,
+		CACert:      caCert,
+	})
+}
+
+func runLogout(cmd *cobra.Command, args []string) {
+	utils.SetConsoleOutputLevel(verbosity)
+
+	err := service.DeleteCredentials()
+	if errors.Is(err, types.ErrNoCredentials) {
+		utils.LogInfo("No credentials are stored")
+		return
+	}
+	if err != nil {
+		fmt.Println("Failed to log out: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	utils.LogSuccess("Removed the stored credentials")
+}
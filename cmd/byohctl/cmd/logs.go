@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsSince  time.Duration
+	logsLines  int
+	logsEvents bool
+
+	// agentLogPath is the log of the agent service byohctl logs reads
+	agentLogPath = service.ByohAgentLogPath
+)
+
+// logNames are the logs byohctl logs prints, the agent log and the debug log of byohctl
+var logNames = []string{"agent", "byohctl"}
+
+// logFollowInterval is how often byohctl logs --follow checks the logs for new lines
+const logFollowInterval = 500 * time.Millisecond
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [agent|byohctl]...",
+	Short: "Print the logs of the agent and of byohctl on this host",
+	Long: `Print the last lines of the log of the agent service, ` + service.ByohAgentLogPath + `, and of the
+debug log of the last byohctl command, ` + filepath.Join("~", service.ByohConfigDir, utils.DebugLogFilename) + `. Without arguments both
+logs are printed, their lines prefixed with the name of their log.
+
+--follow keeps printing the lines appended to the logs until interrupted, --events also prints the
+events of the ByoHost of the host from the management plane.`,
+	Example: `  byohctl logs agent --follow
+  byohctl logs --since 1h --lines 0
+  byohctl logs agent --events`,
+	Args:        cobra.OnlyValidArgs,
+	ValidArgs:   logNames,
+	Annotations: map[string]string{noLogFileAnnotation: "true"},
+	Run:         runLogs,
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing the lines appended to the logs until interrupted")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "Only print the lines logged within this duration, e.g. 30m or 2h")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 100, "Number of last lines of each log to print, 0 prints all of them")
+	logsCmd.Flags().BoolVar(&logsEvents, "events", false, "Also print the events of the ByoHost of the host from the management plane")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) {
+	if logsSince < 0 || logsLines < 0 {
+		fmt.Printf("Error: invalid --since %s or --lines %d, they must not be negative\n", logsSince, logsLines)
+		os.Exit(types.ExitUsage)
+	}
+	names := args
+	if len(names) == 0 {
+		names = logNames
+	}
+	var since time.Time
+	if logsSince > 0 {
+		since = time.Now().Add(-logsSince)
+	}
+
+	offsets, err := tailLogs(os.Stdout, names, since)
+	if err != nil {
+		fmt.Println("Failed to print the logs: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	if logsEvents {
+		if err := printByoHostEvents(os.Stdout, since); err != nil {
+			fmt.Println("Failed to print the events of the ByoHost: " + err.Error())
+			os.Exit(types.ExitCode(err))
+		}
+	}
+	if !logsFollow {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := followLogs(ctx, os.Stdout, names, offsets); err != nil {
+		fmt.Println("Failed to follow the logs: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+}
+
+// logPath returns the path of the log name
+func logPath(name string) string {
+	if name == "agent" {
+		return agentLogPath
+	}
+	return filepath.Join(service.ByohDir, utils.DebugLogFilename)
+}
+
+// logPrefix returns the prefix of the lines of the log name, none if it is the only log printed
+func logPrefix(name string, names []string) string {
+	if len(names) == 1 {
+		return ""
+	}
+	return fmt.Sprintf("%-7s | ", name)
+}
+
+// tailLogs prints the last lines of the logs names from since on to w, and returns the offsets
+// they end at to follow them from. A missing log is skipped, unless all of them are.
+func tailLogs(w io.Writer, names []string, since time.Time) (map[string]int64, error) {
+	offsets := map[string]int64{}
+	var missing []string
+	for _, name := range names {
+		lines, offset, err := service.TailLog(logPath(name), logsLines, since)
+		if errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, logPath(name))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		offsets[name] = offset
+		for _, line := range lines {
+			fmt.Fprintln(w, logPrefix(name, names)+line)
+		}
+	}
+	if len(missing) == len(names) {
+		return nil, fmt.Errorf("%w: no log at %v, the host may not be onboarded", types.ErrNotOnboarded, missing)
+	}
+	for _, path := range missing {
+		utils.LogWarn("No log at %s", path)
+	}
+	return offsets, nil
+}
+
+// followLogs prints the lines appended to the logs names after their offsets to w until ctx is done
+func followLogs(ctx context.Context, w io.Writer, names []string, offsets map[string]int64) error {
+	var mu sync.Mutex
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		go func(name string) {
+			errs <- service.FollowLog(ctx, logPath(name), offsets[name], logFollowInterval, func(line string) {
+				mu.Lock()
+				defer mu.Unlock()
+				fmt.Fprintln(w, logPrefix(name, names)+line)
+			})
+		}(name)
+	}
+	var err error
+	for range names {
+		if followErr := <-errs; followErr != nil && err == nil {
+			err = followErr
+		}
+	}
+	return err
+}
+
+// printByoHostEvents prints the events of the ByoHost of the host from since on to w
+func printByoHostEvents(w io.Writer, since time.Time) error {
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+	hostName, err := service.HostName()
+	if err != nil {
+		return fmt.Errorf("error getting hostname: %w", err)
+	}
+	k8sClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+	events, err := k8sClient.GetByoHostEvents(namespace, hostName, since)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nEvents of ByoHost %s/%s:\n", namespace, hostName)
+	if len(events) == 0 {
+		fmt.Fprintln(w, "No events")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LAST SEEN\tTYPE\tREASON\tCOUNT\tMESSAGE")
+	for _, event := range events {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", client.EventTime(event).Local().Format(time.RFC3339), event.Type, event.Reason, event.Count, event.Message)
+	}
+	return tw.Flush()
+}
@@ -2,15 +2,17 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -28,8 +30,32 @@ var (
 	verbosity           string
 	regionName          string
 	configFile          string
+	otlpEndpoint        string
+	skipPreflight       bool
+	packageFile         string
+	artifactDir         string
+	tenantNamespace     string
+	caCert              string
+	authToken           string
+	clientID            string
+	clientSecret        string
+	totpCode            string
+	passwordFile        string
+	dryRun              bool
+	hostLabels          []string
+	nodeTaints          []string
+	byoHostName         string
+	agentPackage        string
+	agentVersion        string
+	agentChecksum       string
+	agentPublicKey      string
+	skipVerify          bool
 )
 
+// PasswordEnv is the environment variable with the password of the user, it keeps the password out
+// of the shell history and the ps output where --password lands
+const PasswordEnv = "BYOHCTL_PASSWORD"
+
 var onboardCmd = &cobra.Command{
 	Use:   "onboard",
 	Short: "Onboard a host to Platform9",
@@ -44,7 +70,19 @@ CLI flags take precedence over config file values.`,
 	Example: `  byohctl onboard -u your-fqdn.platform9.com -e admin@platform9.com -c client-token
   byohctl onboard -u your-fqdn.platform9.com -e admin@platform9.com -c client-token -d custom-domain -t custom-tenant
   byohctl onboard --config onboard-config.yaml
-  byohctl onboard --config onboard-config.yaml --username overrideuser`,
+  byohctl onboard --config onboard-config.yaml --username overrideuser
+  BYOHCTL_PASSWORD="$PF9_PASSWORD" byohctl onboard --config onboard-config.yaml
+  byohctl onboard --config onboard-config.yaml --password-file /run/secrets/pf9-password
+  byohctl onboard --config onboard-config.yaml --totp 123456
+  byohctl onboard --config onboard-config.yaml --artifact-dir /opt/byoh-artifacts
+  byohctl onboard --config onboard-config.yaml --dry-run
+  byohctl onboard --config onboard-config.yaml --label topology.kubernetes.io/zone=dc1 --label gpu=true
+  byohctl onboard --config onboard-config.yaml --taint dedicated=gpu:NoSchedule
+  byohctl onboard --config onboard-config.yaml --host-name rack1-node3
+  byohctl onboard --config onboard-config.yaml --agent-package registry.internal/platform9/byoh-agent-deb --agent-version 0.1.441
+  byohctl onboard --config onboard-config.yaml --agent-public-key /etc/pf9/byoh-agent-cosign.pub
+  byohctl onboard -u your-fqdn.platform9.com --auth-token "$PF9_TOKEN" -r region
+  byohctl onboard -u your-fqdn.platform9.com --client-id byoh-automation --client-secret "$CLIENT_SECRET" -r region`,
 	Run: runOnboard,
 }
 
@@ -54,6 +92,22 @@ func init() {
 		&fqdn, &username, &password, &passwordInteractive,
 		&clientToken, &domain, &tenant, &verbosity, &regionName, &configFile,
 	)
+	onboardCmd.Flags().StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv(utils.OTLPEndpointEnv),
+		"Endpoint of the OpenTelemetry collector to export the onboarding trace to, e.g. http://otel-collector:4318")
+	onboardCmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Skip the preflight checks of the host, see byohctl preflight")
+	onboardCmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"Authenticate and check the host, the region and the packages, then print the actions of the onboarding without changing the host")
+	onboardCmd.MarkFlagsMutuallyExclusive("dry-run", "skip-preflight")
+	onboardCmd.Flags().StringArrayVar(&hostLabels, "label", nil,
+		"Label key=value of the ByoHost of the host on top of its region label, repeat it for several labels")
+	onboardCmd.Flags().StringArrayVar(&nodeTaints, "taint", nil,
+		"Taint key=value:effect or key:effect of the node of the host when it joins a cluster, repeat it for several taints")
+	onboardCmd.Flags().StringVar(&byoHostName, "host-name", "",
+		"Name of the ByoHost and of the node of the host, instead of its hostname, e.g. a stable name for a DHCP hostname")
+	addAgentPackageFlags(onboardCmd)
+	addAuthFlags(onboardCmd)
+	_ = onboardCmd.MarkFlagFilename("package-file", "deb", "rpm")
+	_ = onboardCmd.MarkFlagDirname("artifact-dir")
 	rootCmd.AddCommand(onboardCmd)
 }
 
@@ -64,7 +118,7 @@ func AddOnboardFlags(cmd *cobra.Command,
 ) {
 	cmd.Flags().StringVarP(fqdn, "url", "u", "", "Platform9 FQDN")
 	cmd.Flags().StringVarP(username, "username", "e", "", "Platform9 username")
-	cmd.Flags().StringVarP(password, "password", "p", "", "Platform9 password")
+	cmd.Flags().StringVarP(password, "password", "p", "", "Platform9 password, prefer the "+PasswordEnv+" environment variable")
 	cmd.Flags().BoolVar(passwordInteractive, "password-interactive", false, "Enter password interactively")
 	cmd.Flags().StringVarP(clientToken, "client-token", "c", "", "Client token for authentication")
 	cmd.Flags().StringVarP(domain, "domain", "d", "default", "Platform9 domain")
@@ -73,29 +127,36 @@ func AddOnboardFlags(cmd *cobra.Command,
 	cmd.MarkFlagsMutuallyExclusive("password", "password-interactive")
 	cmd.Flags().StringVarP(regionName, "region", "r", "", "Platform9 region where you want to onboard this host")
 	cmd.Flags().StringVarP(configFile, "config", "f", "", "Path to onboarding config YAML file")
-}
-
-// Check if running on Ubuntu
-func isUbuntuSystem() bool {
-	if runtime.GOOS != "linux" {
-		return false
-	}
-	data, err := os.ReadFile("/etc/os-release")
-	if err != nil {
-		return false
-	}
-	return strings.Contains(string(data), "Ubuntu")
+	registerOnboardCompletions(cmd)
 }
 
 type OnboardConfig struct {
-	URL         string `yaml:"url"`
-	Username    string `yaml:"username"`
-	Password    string `yaml:"password"`
-	ClientToken string `yaml:"client-token"`
-	Domain      string `yaml:"domain"`
-	Tenant      string `yaml:"tenant"`
-	Verbosity   string `yaml:"verbosity"`
-	Region      string `yaml:"region"`
+	URL            string        `yaml:"url"`
+	Username       string        `yaml:"username"`
+	Password       string        `yaml:"password"`
+	ClientToken    string        `yaml:"client-token"`
+	Domain         string        `yaml:"domain"`
+	Tenant         string        `yaml:"tenant"`
+	Verbosity      string        `yaml:"verbosity"`
+	Region         string        `yaml:"region"`
+	OTLPEndpoint   string        `yaml:"otlp-endpoint"`
+	PackageFile    string        `yaml:"package-file"`
+	ArtifactDir    string        `yaml:"artifact-dir"`
+	Namespace      string        `yaml:"namespace"`
+	CACert         string        `yaml:"ca-cert"`
+	AuthToken      string        `yaml:"auth-token"`
+	ClientID       string        `yaml:"client-id"`
+	ClientSecret   string        `yaml:"client-secret"`
+	PasswordFile   string        `yaml:"password-file"`
+	Labels         []string      `yaml:"labels"`
+	Taints         []string      `yaml:"taints"`
+	HostName       string        `yaml:"host-name"`
+	AgentPackage   string        `yaml:"agent-package"`
+	AgentVersion   string        `yaml:"agent-version"`
+	AgentChecksum  string        `yaml:"agent-checksum"`
+	AgentPublicKey string        `yaml:"agent-public-key"`
+	SkipVerify     bool          `yaml:"skip-verify"`
+	HTTPTimeout    time.Duration `yaml:"http-timeout"`
 }
 
 func LoadOnboardConfig(path string) (*OnboardConfig, error) {
@@ -136,84 +197,297 @@ func mergeConfigWithFlags(cfg *OnboardConfig) {
 	if regionName == "" {
 		regionName = cfg.Region
 	}
+	if otlpEndpoint == "" {
+		otlpEndpoint = cfg.OTLPEndpoint
+	}
+	if packageFile == "" {
+		packageFile = cfg.PackageFile
+	}
+	if artifactDir == "" {
+		artifactDir = cfg.ArtifactDir
+	}
+	if tenantNamespace == "" {
+		tenantNamespace = cfg.Namespace
+	}
+	if caCert == "" {
+		caCert = cfg.CACert
+	}
+	if authToken == "" {
+		authToken = cfg.AuthToken
+	}
+	if clientID == "" {
+		clientID = cfg.ClientID
+	}
+	if clientSecret == "" {
+		clientSecret = cfg.ClientSecret
+	}
+	// the password file of the config does not replace a password of the flags or the environment
+	if passwordFile == "" && password == "" {
+		passwordFile = cfg.PasswordFile
+	}
+	if len(hostLabels) == 0 {
+		hostLabels = cfg.Labels
+	}
+	if len(nodeTaints) == 0 {
+		nodeTaints = cfg.Taints
+	}
+	if byoHostName == "" {
+		byoHostName = cfg.HostName
+	}
+	if agentPackage == "" {
+		agentPackage = cfg.AgentPackage
+	}
+	if agentVersion == "" {
+		agentVersion = cfg.AgentVersion
+	}
+	if agentChecksum == "" {
+		agentChecksum = cfg.AgentChecksum
+	}
+	if agentPublicKey == "" {
+		agentPublicKey = cfg.AgentPublicKey
+	}
+	if !skipVerify {
+		skipVerify = cfg.SkipVerify
+	}
+	if client.HTTPTimeout == client.DefaultTimeout && cfg.HTTPTimeout > 0 {
+		client.HTTPTimeout = cfg.HTTPTimeout
+	}
 }
 
-func runOnboard(cmd *cobra.Command, args []string) {
+// passwordFromEnv sets the password from BYOHCTL_PASSWORD unless --password is set, the password of
+// the config file only applies without both. It returns where the password comes from, for the
+// debug log that must not print the password itself.
+func passwordFromEnv() string {
+	if password != "" {
+		return "--password"
+	}
+	if password = os.Getenv(PasswordEnv); password != "" {
+		return PasswordEnv
+	}
+	return "config file"
+}
+
+// readPasswordFile returns the password in the file path, without the whitespace and the trailing
+// newline around it
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the password file: %w", err)
+	}
+	pw := strings.TrimSpace(string(data))
+	if pw == "" {
+		return "", fmt.Errorf("the password file %s is empty", path)
+	}
+	return pw, nil
+}
+
+// resolvePassword reads the password of --password-file or asks for it with --password-interactive.
+// source is where the password came from so far, it returns where the password comes from now.
+func resolvePassword(source string) (string, error) {
+	if passwordFile != "" {
+		pw, err := readPasswordFile(passwordFile)
+		if err != nil {
+			return "", err
+		}
+		password, source = pw, passwordFile
+	}
+	if passwordInteractive {
+		fmt.Print("Enter Password: ")
+		pwBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		fmt.Println() // Add newline after password input
+		if len(pwBytes) == 0 {
+			return "", errors.New("password cannot be empty")
+		}
+		password, source = string(pwBytes), "prompt"
+	}
+	if password == "" {
+		source = "none"
+	}
+	return source, nil
+}
+
+// mergeStoredCredentials fills in the credentials the flags, the environment and the config file
+// did not set with the credentials byohctl login stored for the same management plane and user.
+// It returns whether the password is the stored one.
+func mergeStoredCredentials(creds *service.Credentials) bool {
+	if (fqdn != "" && fqdn != creds.URL) || (username != "" && username != creds.Username) {
+		return false
+	}
+	fqdn, username = creds.URL, creds.Username
+	if clientToken == "" {
+		clientToken = creds.ClientToken
+	}
+	if domain == "default" && creds.Domain != "" {
+		domain = creds.Domain
+	}
+	if tenant == "service" && creds.Tenant != "" {
+		tenant = creds.Tenant
+	}
+	if caCert == "" {
+		caCert = creds.CACert
+	}
+	if password != "" {
+		return false
+	}
+	password = creds.Password
+	return true
+}
+
+// failOnboarding ends the onboarding span failed with err, exports the trace and exits
+func failOnboarding(onboardSpan *utils.Span, err error) {
+	onboardSpan.End(err)
+	flushTraces()
+	// a failed download keeps the completed steps of the onboarding for a rerun to resume after them
+	if checkpoint, loadErr := service.LoadCheckpoint(service.ByohDir, onboardSettings()); loadErr == nil && checkpoint.Resumed() {
+		utils.RecordStep(utils.NextStep, "byohctl onboard again with the same flags to resume after the steps %s", strings.Join(checkpoint.Steps, ", "))
+	} else if len(utils.StepEvents()) > 0 {
+		utils.RecordStep(utils.NextStep, "byohctl decommission to undo the changes above, then onboard again")
+	}
+	if errors.Is(err, types.ErrRegionUnavailable) {
+		utils.RecordStep(utils.NextStep, "byohctl regions to list the regions available to the tenant")
+	}
+	utils.PrintSummary("onboard", false)
+	os.Exit(types.ExitCode(err))
+}
+
+// flushTraces exports the onboarding trace, a collector that cannot be reached does not fail the onboarding
+func flushTraces() {
+	if err := utils.FlushTraces(); err != nil {
+		utils.LogWarn("%v", err)
+	}
+}
+
+// loadOnboardInputs merges the config file and the credentials of byohctl login into the flags they
+// did not set, and exits if a required one is missing, the region only if requireRegion. It returns
+// where the password comes from.
+func loadOnboardInputs(requireRegion bool) string {
+	passwordSource := passwordFromEnv()
 	// If config file is provided, load it and use values as defaults for unset flags
 	if configFile != "" {
 		cfg, err := LoadOnboardConfig(configFile)
 		if err != nil {
 			fmt.Printf("Error loading config file: %v\n", err)
-			os.Exit(1)
+			os.Exit(types.ExitUsage)
 		}
 		mergeConfigWithFlags(cfg)
 	}
 
+	// the credentials of byohctl login fill in what the flags, the environment and the config file did not set
+	if authToken == "" && clientID == "" && passwordFile == "" && !passwordInteractive {
+		if creds, err := service.LoadCredentials(); err == nil {
+			if mergeStoredCredentials(creds) {
+				passwordSource = "keyring"
+			}
+		} else if !errors.Is(err, types.ErrNoCredentials) {
+			utils.LogWarn("Failed to load the credentials of byohctl login: %v", err)
+		}
+	}
+
 	missing := []string{}
 	if fqdn == "" {
 		missing = append(missing, "--url (or config file 'url")
 	}
-	if username == "" {
+	// a pre-obtained token or client credentials replace the username, password and client token
+	userCredentials := authToken == "" && clientID == ""
+	if username == "" && userCredentials {
         missing = append(missing, "--username (or config file 'username')")
 	}
-	if clientToken == "" {
+	if clientToken == "" && userCredentials {
         missing = append(missing, "--client-token (or config file 'client-token')")
 	}
-	if regionName == "" {
+	if clientID != "" && clientSecret == "" {
+        missing = append(missing, "--client-secret (or config file 'client-secret')")
+	}
+	if regionName == "" && requireRegion {
         missing = append(missing, "--region (or config file 'region')")
 	}
 	if len(missing) > 0 {
 		fmt.Printf("Error: missing required flags: %s\n", strings.Join(missing, ", "))
-		os.Exit(1)
+		os.Exit(types.ExitUsage)
 	}
+	return passwordSource
+}
+
+func runOnboard(cmd *cobra.Command, args []string) {
+	passwordSource := loadOnboardInputs(true)
 
 	utils.LogDebug("Final onboarding values: url=%s, username=%s, domain=%s, tenant=%s, region=%s, verbosity=%s",
 		fqdn, username, domain, tenant, regionName, verbosity)
 
-	// Check if running on Ubuntu system
-	if !isUbuntuSystem() {
-		fmt.Println("Error: This command requires an Ubuntu system")
-		os.Exit(1)
+	// Check the host is ready before changing it, rather than failing halfway
+	if !skipPreflight && !checkPreflight(fqdn) {
+		os.Exit(types.ExitPreflight)
 	}
 
-	// Continue with interactive password if needed
-	if passwordInteractive {
-		fmt.Print("Enter Password: ")
-		pwBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
-		if err != nil {
-			utils.LogError("Failed to read password: %v", err)
-			os.Exit(1)
-		}
-		if len(pwBytes) == 0 {
-			utils.LogError("Password cannot be empty")
-			os.Exit(1)
+	// Check if running on a supported distribution
+	if _, err := service.HostOSFamily(); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitPreflight)
+	}
+
+	// Check the local packages of an air-gapped onboarding
+	if err := agentPackageSource().Check(); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitUsage)
+	}
+
+	// Check the labels of the ByoHost and the taints of its node, the agent only reads them once the host is changed
+	if _, err := service.AgentLabels(regionName, hostLabels); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitUsage)
+	}
+	if _, err := service.AgentTaints(nodeTaints); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitUsage)
+	}
+	if byoHostName != "" {
+		if err := service.ValidateHostName(byoHostName); err != nil {
+			fmt.Println("Error: " + err.Error())
+			os.Exit(types.ExitUsage)
 		}
-		fmt.Println() // Add newline after password input
-		password = string(pwBytes)
 	}
+	// the ByoHost is named after --host-name, not after a host name of a previous onboarding
+	service.HostNameOverride = byoHostName
+
+	passwordSource, err := resolvePassword(passwordSource)
+	if err != nil {
+		utils.LogError("%v", err)
+		os.Exit(types.ExitUsage)
+	}
+	utils.LogDebug("Using the password from %s", passwordSource)
 
 	// Check if service present
 	out, err := service.RunWithStdout(service.Systemctl, service.SystemctlServiceExists...)
 	if err != nil {
 		utils.LogSuccess("Byoh service is not installed, proceeding with onboarding")
 	} else if strings.Contains(out, service.ByohAgentServiceName) {
-		utils.LogError("pf9-byohost-agent service is already installed on this host. Host already onboarded in some tenant.")
-		os.Exit(1)
+		// the service of an onboarding that died before its last step is set up again
+		checkpoint, err := service.LoadCheckpoint(service.ByohDir, onboardSettings())
+		if err != nil || !checkpoint.Resumed() {
+			utils.LogError("pf9-byohost-agent service is already installed on this host. Host already onboarded in some tenant.")
+			os.Exit(types.ExitAlreadyOnboarded)
+		}
+		utils.LogInfo("pf9-byohost-agent service is installed by an interrupted onboarding, resuming it")
 	}
 
-	// Initialize loggers
+	// Initialize loggers, a dry run does not even write its log file
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		fmt.Printf("Error getting user home directory: %v\n", err)
-		os.Exit(1)
+		os.Exit(types.ExitFailure)
 	}
 	byohDir := filepath.Join(homeDir, ".byoh")
-	// Initialize loggers with debug enabled for file logs
-	if err = utils.InitLoggers(byohDir, true); err != nil {
-		fmt.Printf("Error initializing loggers: %v\n", err)
-		os.Exit(1)
+	if !dryRun {
+		// Initialize loggers with debug enabled for file logs
+		if err = utils.InitLoggers(byohDir, true); err != nil {
+			fmt.Printf("Error initializing loggers: %v\n", err)
+			os.Exit(types.ExitFailure)
+		}
+		defer utils.CloseLoggers()
 	}
-	defer utils.CloseLoggers()
 
 	// Set console output level based on verbosity flag
 	utils.SetConsoleOutputLevel(verbosity)
@@ -221,97 +495,494 @@ func runOnboard(cmd *cobra.Command, args []string) {
 	start := time.Now()
 	defer utils.TrackTime(start, "Total onboarding process")
 
+	// Trace the onboarding steps, the trace is continued by the agent
+	utils.InitTracing("byohctl", otlpEndpoint)
+	onboardSpan := utils.StartSpan("byohctl.onboard", nil)
+	if hostName, err := onboardHostName(); err == nil {
+		onboardSpan.SetAttribute(utils.HostNameKey, hostName)
+	}
+	onboardSpan.SetAttribute("byoh.region", regionName)
+
 	utils.LogDebug("Starting host onboarding process")
-	utils.LogDebug("Using FQDN: %s, Domain: %s, Tenant: %s", fqdn, domain, tenant)
+	utils.LogDebug("Using FQDN: %s, Domain: %s, Tenant: %s, Namespace: %s", fqdn, domain, tenant, tenantNamespace)
 	utils.LogDebug("Verbosity level set to: %s", verbosity)
 
-	// Get authentication token
-	utils.LogDebug("Getting authentication token for user %s", username)
-	authClient := client.NewAuthClient(fqdn, clientToken)
-	token, err := authClient.GetToken(username, password)
-	if err != nil {
-		utils.LogError("Failed to get authentication token: %v", err)
-		os.Exit(1)
+	if dryRun {
+		actions, err := planOnboarding(onboardSpan)
+		if err != nil {
+			failOnboarding(onboardSpan, err)
+		}
+		onboardSpan.End(nil)
+		flushTraces()
+		fmt.Println("Dry run, the host was not changed. byohctl onboard would:")
+		for i, action := range actions {
+			fmt.Printf("  %d. %s\n", i+1, action)
+		}
+		return
+	}
+
+	if err := onboardHost(onboardSpan); err != nil {
+		failOnboarding(onboardSpan, err)
+	}
+	onboardSpan.End(nil)
+	flushTraces()
+
+	timeElapsed := time.Since(start)
+	utils.LogDebug("Time elapsed: %s", timeElapsed)
+
+	recordOnboardedHost()
+	utils.PrintSummary("onboard", true)
+}
+
+// addAuthFlags adds the flags of the tenant and of the credentials byohctl authenticates with, on
+// top of those of AddOnboardFlags, to cmd
+func addAuthFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&tenantNamespace, "namespace", "",
+		"Namespace of the tenant in the management cluster, by default derived from the FQDN, domain and tenant, or discovered by its labels")
+	cmd.Flags().StringVar(&caCert, "ca-cert", "",
+		"CA certificate of the management plane, as the path of a PEM file or the PEM itself, trusted on top of the system CAs")
+	cmd.Flags().StringVar(&authToken, "auth-token", "",
+		"Pre-obtained OIDC bearer token of the management plane, used instead of the username and password")
+	cmd.Flags().StringVar(&clientID, "client-id", "",
+		"Client of the management plane authenticating with the client credentials grant, for unattended onboarding without a user")
+	cmd.Flags().StringVar(&clientSecret, "client-secret", "", "Client secret of --client-id")
+	cmd.MarkFlagsRequiredTogether("client-id", "client-secret")
+	cmd.Flags().StringVar(&totpCode, "totp", "",
+		"TOTP code of the second factor of the user, prompted for when the account requires one and it is not set")
+	cmd.Flags().StringVar(&passwordFile, "password-file", "",
+		"Path of a file with the password of the user, e.g. a secret delivered by config management")
+	cmd.MarkFlagsMutuallyExclusive("password-file", "password")
+	cmd.MarkFlagsMutuallyExclusive("password-file", "password-interactive")
+	for _, userFlag := range []string{"password", "password-interactive", "password-file", "auth-token", "totp"} {
+		cmd.MarkFlagsMutuallyExclusive("client-id", userFlag)
+	}
+	cmd.MarkFlagsMutuallyExclusive("auth-token", "totp")
+	cmd.MarkFlagsMutuallyExclusive("auth-token", "password")
+	cmd.MarkFlagsMutuallyExclusive("auth-token", "password-interactive")
+	cmd.MarkFlagsMutuallyExclusive("auth-token", "password-file")
+	_ = cmd.MarkFlagFilename("password-file")
+}
+
+// addAgentPackageFlags adds the flags of where the agent package is installed from to cmd
+func addAgentPackageFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&packageFile, "package-file", "",
+		"Path to the agent .deb or .rpm package on local disk, it is not downloaded from quay.io")
+	cmd.Flags().StringVar(&artifactDir, "artifact-dir", "",
+		"Directory of the .deb or .rpm files of the required packages, and of the agent package unless --package-file is set, for hosts without internet access")
+	cmd.Flags().StringVar(&agentPackage, "agent-package", "",
+		"Image the agent package is pulled from, e.g. of an internal registry, with or without a tag; by default the image on quay.io")
+	cmd.Flags().StringVar(&agentVersion, "agent-version", "",
+		"Version of the agent package, the tag of its image, by default "+service.ByohAgentVersion)
+	for _, localFlag := range []string{"package-file", "artifact-dir"} {
+		cmd.MarkFlagsMutuallyExclusive("agent-package", localFlag)
+		cmd.MarkFlagsMutuallyExclusive("agent-version", localFlag)
+	}
+	cmd.Flags().StringVar(&agentChecksum, "agent-checksum", "",
+		"SHA256 checksum the agent package must match, by default the checksum file pulled with the agent package, if any. Protects against a compromised registry, unlike the checksum file")
+	cmd.Flags().StringVar(&agentPublicKey, "agent-public-key", "",
+		"Path of the cosign public key the signature of the agent package is verified with, the signature is not verified without it")
+	cmd.Flags().BoolVar(&skipVerify, "skip-verify", false,
+		"Install the agent package without verifying its checksum and its signature")
+	cmd.MarkFlagsMutuallyExclusive("skip-verify", "agent-checksum")
+	cmd.MarkFlagsMutuallyExclusive("skip-verify", "agent-public-key")
+}
+
+// agentPackageSource returns where the flags install the agent package and the required packages from
+func agentPackageSource() service.PackageSource {
+	return service.PackageSource{
+		PackageFile: packageFile,
+		ArtifactDir: artifactDir,
+		Image:       agentPackage,
+		Version:     agentVersion,
+		Checksum:    agentChecksum,
+		PublicKey:   agentPublicKey,
+		SkipVerify:  skipVerify,
+	}
+}
+
+// onboardHostName returns the name the agent registers the ByoHost of the host with, --host-name
+// or the hostname of the host
+func onboardHostName() (string, error) {
+	if byoHostName != "" {
+		return byoHostName, nil
+	}
+	return os.Hostname()
+}
+
+// recordOnboardedHost records the ByoHost the agent registers and what to do next for the summary of the onboarding
+func recordOnboardedHost() {
+	hostName, _ := onboardHostName()
+	if namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath); err == nil {
+		utils.RecordStep(utils.PlaneObject, "ByoHost %s/%s, registered by the agent once it starts", namespace, hostName)
+	}
+	utils.RecordStep(utils.LogLocation, "Agent service logs: %s", service.ByohAgentLogPath)
+	utils.RecordStep(utils.NextStep, "sudo systemctl status %s.service to check the agent is running", service.ByohAgentServiceName)
+	utils.RecordStep(utils.NextStep, "Create a cluster with the host in region %s, or byohctl decommission to remove it", regionName)
+}
+
+// promptTOTP asks the user for the TOTP code of their second factor, it returns an empty code
+// without a terminal to ask on or with --non-interactive
+func promptTOTP() (string, error) {
+	if utils.NonInteractive || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", nil
+	}
+	fmt.Print("Enter TOTP code: ")
+	var code string
+	if _, err := fmt.Scanln(&code); err != nil {
+		return "", fmt.Errorf("failed to read the TOTP code: %w", err)
+	}
+	return strings.TrimSpace(code), nil
+}
+
+// authenticate authenticates with the management plane and returns the client of the tenant of
+// the onboarding, the steps are traced as children of onboardSpan
+func authenticate(onboardSpan *utils.Span) (*client.K8sClient, error) {
+	// Trust the internal CA of the management plane
+	if caCert != "" {
+		if err := client.UseCACert(caCert); err != nil {
+			utils.LogError("Failed to load the CA certificate: %v", err)
+			return nil, err
+		}
+	}
+
+	// Get authentication token, unless it was obtained beforehand
+	token := authToken
+	var tokenSource client.TokenSource
+	if token == "" {
+		span := utils.StartSpan("byohctl.authenticate", onboardSpan)
+		authClient := client.NewAuthClient(fqdn, clientToken)
+		var err error
+		if clientID != "" {
+			// the tokens of the client credentials grant are refreshed, the onboarding may outlive them
+			tokenSource = authClient.ClientCredentialsSource(clientID, clientSecret)
+			token, err = tokenSource()
+		} else {
+			utils.LogDebug("Getting authentication token for user %s", username)
+			authClient.SetTOTP(totpCode, promptTOTP)
+			token, err = authClient.GetToken(username, password)
+		}
+		span.End(err)
+		if err != nil {
+			utils.LogError("Failed to get authentication token: %v", err)
+			return nil, err
+		}
 	}
 
 	// Create Kubernetes client
 	k8sClient := client.NewK8sClient(fqdn, domain, tenant, token, regionName)
+	k8sClient.SetNamespace(tenantNamespace)
+	if tokenSource != nil {
+		k8sClient.SetTokenSource(tokenSource)
+	}
+
+	// Check the pre-obtained token before the host is changed
+	if authToken != "" {
+		span := utils.StartSpan("byohctl.validate-token", onboardSpan)
+		err := k8sClient.ValidateToken("byoh-bootstrap-kc")
+		span.End(err)
+		if err != nil {
+			utils.LogError("Failed to validate the authentication token: %v", err)
+			return nil, err
+		}
+	}
+	return k8sClient, nil
+}
+
+// planOnboarding authenticates and checks the bootstrap kubeconfig, the region and the packages
+// like onboardHost, without changing the host. It returns the actions onboardHost would take.
+func planOnboarding(onboardSpan *utils.Span) ([]string, error) {
+	k8sClient, err := authenticate(onboardSpan)
+	if err != nil {
+		return nil, err
+	}
+
+	span := utils.StartSpan("byohctl.get-kubeconfig", onboardSpan)
+	kubeconfig, err := k8sClient.GetKubeConfig("byoh-bootstrap-kc")
+	span.End(err)
+	if err != nil {
+		utils.LogError("Failed to get kubeconfig: %v", err)
+		return nil, err
+	}
+
+	span = utils.StartSpan("byohctl.check-region", onboardSpan)
+	available, regions, err := k8sClient.CheckRegionAvailabilityWith(kubeconfig, regionName)
+	if err == nil && !available {
+		err = fmt.Errorf("%w: %s", types.ErrRegionUnavailable, regionName)
+		if len(regions) > 0 {
+			utils.LogInfo("Available regions: %v", regions)
+		}
+	}
+	span.End(err)
+	if err != nil {
+		utils.LogError("Failed to check region availability: %v", err)
+		return nil, err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		utils.LogError("Error getting home directory: %v", err)
+		return nil, err
+	}
+	byohDir := filepath.Join(homeDir, service.ByohConfigDir)
+	labels, err := service.AgentLabels(regionName, hostLabels)
+	if err != nil {
+		return nil, err
+	}
+	pkgDir := filepath.Join(byohDir, "packages")
+	actions := []string{
+		fmt.Sprintf("Lock the host with %s", service.HostLockPath),
+		fmt.Sprintf("Create the directory %s", byohDir),
+		fmt.Sprintf("Write the kubeconfig of the agent in namespace %s to %s", k8sClient.Namespace(), filepath.Join(byohDir, "config")),
+		fmt.Sprintf("Write the labels %s of the ByoHost to %s", labels, filepath.Join(byohDir, "region")),
+		fmt.Sprintf("Create the directory %s", pkgDir),
+	}
+	if taints, err := service.AgentTaints(nodeTaints); err != nil {
+		return nil, err
+	} else if taints != "" {
+		actions = append(actions, fmt.Sprintf("Write the taints %s of the node to %s", taints, filepath.Join(byohDir, service.TaintsFilename)))
+	}
+	if byoHostName != "" {
+		actions = append(actions, fmt.Sprintf("Write the name %s of the ByoHost and the node to %s", byoHostName, filepath.Join(byohDir, service.HostNameFilename)))
+	}
+	if otlpEndpoint != "" {
+		actions = append(actions, fmt.Sprintf("Write the collector %s of the agent to %s", otlpEndpoint, filepath.Join(byohDir, service.TracingEnvFilename)))
+	}
+
+	span = utils.StartSpan("byohctl.check-packages", onboardSpan)
+	setup, err := service.PlanAgentSetup(pkgDir, agentPackageSource())
+	span.End(err)
+	if err != nil {
+		utils.LogError("Failed to check the packages of the agent: %v", err)
+		return nil, err
+	}
+	return append(actions, setup...), nil
+}
+
+// onboardHost authenticates with the management plane, saves the kubeconfig of the host and
+// sets up the agent, the steps are traced as children of onboardSpan. If a step fails, the changes
+// of the host of the completed steps are rolled back, unless it fails with an error a rerun may
+// not hit: only the failed step is then rolled back, a rerun resumes after the completed steps.
+func onboardHost(onboardSpan *utils.Span) (err error) {
+	k8sClient, err := authenticate(onboardSpan)
+	if err != nil {
+		return err
+	}
+
+	// Lock the host, so the agent is not set up while another operation writes its packages
+	lock, err := service.LockHost("byohctl onboard")
+	if err != nil {
+		utils.LogError("%v", err)
+		return err
+	}
+	defer service.UnlockHost(lock)
+
+	// the rollback runs before the host is unlocked
+	rollback := &utils.Rollback{}
+	resumable := false
+	defer func() {
+		if err == nil || resumable {
+			return
+		}
+		utils.LogInfo("Rolling back the onboarding of the host")
+		if rollbackErr := rollback.Run(); rollbackErr != nil {
+			utils.LogError("Failed to roll back the onboarding, clean up the host with byohctl decommission: %v", rollbackErr)
+		}
+	}()
 
 	// Prepare directories
 	utils.LogInfo("Preparing directory structure for BYOH agent")
-	homeDir, err = os.UserHomeDir()
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		utils.LogError("Error getting home directory: %v", err)
-		os.Exit(1)
+		return err
 	}
-	byohDir = filepath.Join(homeDir, service.ByohConfigDir)
+	byohDir := filepath.Join(homeDir, service.ByohConfigDir)
+	rollback.RemoveDir(byohDir)
 	if err := service.PrepareAgentDirectory(byohDir); err != nil {
 		utils.LogError("Failed to prepare agent directory: %v", err)
-		os.Exit(1)
+		return err
+	}
+
+	// An onboarding that died midway resumes after its completed steps, a failed one is rolled back
+	// to the checkpoint of the interrupted onboarding
+	checkpoint, err := service.LoadCheckpoint(byohDir, onboardSettings())
+	if err != nil {
+		utils.LogError("%v", err)
+		return err
+	}
+	if checkpoint.Resumed() {
+		utils.LogInfo("Resuming the interrupted onboarding of the host after the steps %s", strings.Join(checkpoint.Steps, ", "))
+	}
+	if err := rollback.RestoreFile(filepath.Join(byohDir, service.CheckpointFilename)); err != nil {
+		return err
+	}
+	steps := []struct {
+		name string
+		run  func(rollback *utils.Rollback) error
+	}{
+		{stepSaveKubeconfig, func(rollback *utils.Rollback) error {
+			return saveKubeconfig(k8sClient, byohDir, onboardSpan, rollback)
+		}},
+		{stepWriteAgentFiles, func(rollback *utils.Rollback) error { return writeAgentFiles(byohDir, rollback) }},
+		{stepSetupAgent, func(rollback *utils.Rollback) error { return setupAgent(byohDir, onboardSpan, rollback) }},
+	}
+	for _, step := range steps {
+		if checkpoint.Done(step.name) {
+			utils.LogInfo("Skipping the step %s, completed by the interrupted onboarding", step.name)
+			continue
+		}
+		stepRollback := &utils.Rollback{}
+		if err := step.run(stepRollback); err != nil {
+			if resumableError(err) && checkpoint.Resumed() {
+				resumable = true
+				utils.LogInfo("Rolling back the step %s, run byohctl onboard again to resume after the steps %s", step.name, strings.Join(checkpoint.Steps, ", "))
+				if rollbackErr := stepRollback.Run(); rollbackErr != nil {
+					utils.LogError("Failed to roll back the step %s: %v", step.name, rollbackErr)
+				}
+				return err
+			}
+			rollback.Include(stepRollback)
+			return err
+		}
+		rollback.Include(stepRollback)
+		if err := checkpoint.Complete(step.name); err != nil {
+			utils.LogError("%v", err)
+			return err
+		}
+	}
+	if err := checkpoint.Remove(); err != nil {
+		utils.LogWarn("%v", err)
+	}
+	return nil
+}
+
+// The steps of onboardHost, recorded in the checkpoint of the onboarding as they complete
+const (
+	stepSaveKubeconfig  = "save-kubeconfig"
+	stepWriteAgentFiles = "write-agent-files"
+	stepSetupAgent      = "setup-agent"
+)
+
+// resumableError returns whether a step of the onboarding failing with err may succeed when the
+// onboarding is run again, like a failed download of the agent package
+func resumableError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, types.ErrDownload) || errors.As(err, &netErr)
+}
+
+// onboardSettings returns the settings of the flags identifying the onboarding, an interrupted
+// onboarding only resumes with the same settings
+func onboardSettings() service.OnboardSettings {
+	return service.OnboardSettings{
+		FQDN:      fqdn,
+		Domain:    domain,
+		Tenant:    tenant,
+		Namespace: tenantNamespace,
+		Region:    regionName,
+		HostName:  byoHostName,
 	}
+}
 
+// saveKubeconfig saves the kubeconfig of the host from its bootstrap secret into byohDir, and checks
+// the region of the host is available for the tenant
+func saveKubeconfig(k8sClient *client.K8sClient, byohDir string, onboardSpan *utils.Span, rollback *utils.Rollback) error {
 	// Save kubeconfig
 	utils.LogInfo("Saving kubeconfig from bootstrap secret")
-	if err := k8sClient.SaveKubeConfig("byoh-bootstrap-kc"); err != nil {
+	if err := rollback.RestoreFile(filepath.Join(byohDir, "config")); err != nil {
+		return err
+	}
+	span := utils.StartSpan("byohctl.save-kubeconfig", onboardSpan)
+	err := k8sClient.SaveKubeConfig("byoh-bootstrap-kc")
+	span.End(err)
+	if err != nil {
 		utils.LogError("Failed to save kubeconfig: %v", err)
-		os.Exit(1)
+		return err
 	}
 
 	// Check if region where user wants to onboard to is available for this tenant or not
 	// If not available, roll back the onboarding process
+	span = utils.StartSpan("byohctl.check-region", onboardSpan)
 	available, regions, err := k8sClient.CheckRegionAvailability(regionName)
-	if err != nil {
-		utils.LogError("Failed to check region availability, rolling back onboarding process: %v", err)
-		if err := k8sClient.DeleteSavedKubeconfig(); err != nil {
-			utils.LogError("Failed to delete saved kubeconfig while rolling back onboarding process: %v", err)
-		}
-		os.Exit(1)
-	}
-	if !available {
+	if err == nil && !available {
+		err = fmt.Errorf("%w: %s", types.ErrRegionUnavailable, regionName)
+		span.End(err)
 		utils.LogError("Region %s is not available for the tenant, rolling back onboarding process", regionName)
 		if len(regions) > 0 {
 			utils.LogInfo("Available regions: %v", regions)
 		}
-		if err := k8sClient.DeleteSavedKubeconfig(); err != nil {
-			utils.LogError("Failed to delete saved kubeconfig while rolling back onboarding process: %v", err)
-		}
-		os.Exit(1)
+		return err
 	}
+	span.End(err)
+	if err != nil {
+		utils.LogError("Failed to check region availability, rolling back onboarding process: %v", err)
+		return err
+	}
+	return nil
+}
 
-	// Save region name in a temp file in byohDir
+// writeAgentFiles writes the files of byohDir the agent-after-install script passes to the agent:
+// the region and the labels of the ByoHost, the taints of its node and its name
+func writeAgentFiles(byohDir string, rollback *utils.Rollback) error {
+	// Save region name and the labels of the ByoHost in a temp file in byohDir
 	/*
 		Agent deb will read this file in a agent-after-install script, export the region label variable,
 		then it will be passed as a label flag to the pf9-byohost-agent binary.
 		This file will be removed as a part of agent-before-remove script.
 	*/
 	regionFile := filepath.Join(byohDir, "region")
-	regionLabel := service.PcdKaapiRegionKey + "=" + regionName
+	regionLabel, err := service.AgentLabels(regionName, hostLabels)
+	if err != nil {
+		utils.LogError("Invalid labels: %v", err)
+		return err
+	}
+	if err := rollback.RestoreFile(regionFile); err != nil {
+		return err
+	}
 	if err := os.WriteFile(regionFile, []byte(regionLabel), service.DefaultFilePerms); err != nil {
 		utils.LogError("Failed to save region name: %v", err)
-		os.Exit(1)
+		return err
+	}
+	utils.RecordStep(utils.HostChanged, "Wrote the region %s and the labels of the agent to %s", regionName, regionFile)
+
+	// Like the region, the agent-after-install script passes the taints of the node and the name of the host to the agent
+	for _, name := range []string{service.TaintsFilename, service.HostNameFilename, service.TracingEnvFilename} {
+		if err := rollback.RestoreFile(filepath.Join(byohDir, name)); err != nil {
+			return err
+		}
+	}
+	if err := service.WriteAgentTaints(byohDir, nodeTaints); err != nil {
+		utils.LogError("Failed to save the taints of the node: %v", err)
+		return err
+	}
+	if err := service.WriteHostName(byohDir, byoHostName); err != nil {
+		utils.LogError("Failed to save the name of the host: %v", err)
+		return err
 	}
+	return nil
+}
 
+// setupAgent downloads and installs the agent package and its required packages
+func setupAgent(byohDir string, onboardSpan *utils.Span, rollback *utils.Rollback) error {
 	// Create packages directory for downloads
 	pkgDir := filepath.Join(byohDir, "packages")
+	rollback.RemoveDir(pkgDir)
 	if err := os.MkdirAll(pkgDir, service.DefaultDirPerms); err != nil {
 		utils.LogError("Failed to create packages directory: %v", err)
-		os.Exit(1)
+		return err
 	}
 
 	// Setup agent (download and install)
 	utils.LogInfo("Setting up BYOH agent")
-	err = service.SetupAgent(pkgDir)
+	span := utils.StartSpan("byohctl.setup-agent", onboardSpan)
+	// Like the region, the agent-after-install script passes the collector and the trace to the agent
+	if err := service.WriteTracingEnv(byohDir, otlpEndpoint, span.TraceParent()); err != nil {
+		utils.LogWarn("Failed to hand over the trace to the agent: %v", err)
+	}
+	err := service.SetupAgent(pkgDir, agentPackageSource(), rollback)
+	span.End(err)
 	if err != nil {
 		utils.LogError("Failed to setup agent: %v", err)
-		os.Exit(1)
+		return err
 	}
-
-	utils.LogSuccess("Successfully onboarded the host")
-
-	timeElapsed := time.Since(start)
-	utils.LogDebug("Time elapsed: %s", timeElapsed)
-
-	utils.LogSuccess("BYOH Agent Service logs are available at:")
-	utils.LogSuccess("   - Agent service logs: %s", service.ByohAgentLogPath)
-	utils.LogSuccess("   - Check service status: sudo systemctl status pf9-byohost-agent.service")
+	return nil
 }
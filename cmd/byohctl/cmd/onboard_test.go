@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -229,6 +232,26 @@ func resetOnboardGlobals() {
 	verbosity = ""
 	regionName = ""
 	configFile = ""
+	packageFile = ""
+	artifactDir = ""
+	tenantNamespace = ""
+	caCert = ""
+	authToken = ""
+	clientID = ""
+	clientSecret = ""
+	totpCode = ""
+	passwordFile = ""
+	dryRun = false
+	hostLabels = nil
+	nodeTaints = nil
+	byoHostName = ""
+	agentPackage = ""
+	agentVersion = ""
+	agentChecksum = ""
+	agentPublicKey = ""
+	skipVerify = false
+	client.HTTPTimeout = client.DefaultTimeout
+	service.HostNameOverride = ""
 }
 
 func TestConfigFilePrecedence(t *testing.T) {
@@ -241,6 +264,9 @@ domain: "config-domain"
 tenant: "config-tenant"
 verbosity: "important"
 region: "config-region"
+artifact-dir: "/opt/byoh-artifacts"
+namespace: "config-namespace"
+http-timeout: "2m"
 `
 	tests := []struct {
 		name string
@@ -259,6 +285,9 @@ region: "config-region"
 				"tenant":      "config-tenant",
 				"verbosity":   "important",
 				"regionName":  "config-region",
+				"artifactDir": "/opt/byoh-artifacts",
+				"namespace":   "config-namespace",
+				"httpTimeout": "2m0s",
 			},
 		},
 		{
@@ -324,6 +353,12 @@ region: "config-region"
 					got = verbosity
 				case "regionName":
 					got = regionName
+				case "artifactDir":
+					got = artifactDir
+				case "namespace":
+					got = tenantNamespace
+				case "httpTimeout":
+					got = client.HTTPTimeout.String()
 				}
 				if got != v {
 					t.Errorf("Expected %s = '%s', got '%s'", k, v, got)
@@ -333,6 +368,65 @@ region: "config-region"
 	}
 }
 
+func TestPasswordEnv(t *testing.T) {
+	const configYAML = `
+url: "config.platform9.com"
+username: "configuser"
+password: "configpass"
+client-token: "config-token"
+region: "config-region"
+`
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "Environment overrides config", env: "envpass", want: "envpass"},
+		{name: "Flag overrides environment", env: "envpass", args: []string{"--password", "clipass"}, want: "clipass"},
+		{name: "Config without environment", want: "configpass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetOnboardGlobals()
+			t.Setenv(PasswordEnv, tt.env)
+			testCmd := createTestCommand()
+			testCmd.SetArgs(append([]string{"--config", createTempConfigFile(t, configYAML)}, tt.args...))
+			if err := testCmd.Execute(); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if password != tt.want {
+				t.Errorf("Expected password '%s', got '%s'", tt.want, password)
+			}
+		})
+	}
+}
+
+func TestReadPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("  filepass \n"), 0600); err != nil {
+		t.Fatalf("Failed to write the password file: %v", err)
+	}
+	pw, err := readPasswordFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if pw != "filepass" {
+		t.Errorf("Expected password 'filepass', got '%s'", pw)
+	}
+
+	if err := os.WriteFile(path, []byte("\n"), 0600); err != nil {
+		t.Fatalf("Failed to write the password file: %v", err)
+	}
+	if _, err := readPasswordFile(path); err == nil || !strings.Contains(err.Error(), "is empty") {
+		t.Errorf("Expected an empty password file error, got: %v", err)
+	}
+	if _, err := readPasswordFile(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing password file")
+	}
+}
+
 func TestConfigFileAndCLIDefaultFallback(t *testing.T) {
 	// No CLI or config, should use default for domain, tenant, verbosity.
 	// Args are passed as literals rather than globals because AddOnboardFlags
@@ -371,6 +465,7 @@ func createTestCommand() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Mirror runOnboard: load config first, then validate required fields.
 			// Returns an error instead of os.Exit so tests can inspect the result.
+			passwordFromEnv()
 			if configFile != "" {
 				cfg, err := LoadOnboardConfig(configFile)
 				if err == nil {
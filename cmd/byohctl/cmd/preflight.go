@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var preflightFQDN string
+
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check this host is ready to be onboarded",
+	Long: `Check this host is ready to be onboarded to the Platform9 management plane, so that onboard does
+not fail halfway and leave the host in a partial state. The checks are:
+1. The host runs ` + service.SupportedOS + ` on a kernel of at least ` + service.MinKernelVersion + `,
+   ` + service.MinRHELKernelVersion + ` on RHEL and Rocky Linux
+2. The host has the CPUs, memory and disk kubeadm requires
+3. The FQDN of the management plane resolves
+4. The management plane is reachable over HTTPS
+5. No conflicting agent, e.g. the BYOH agent of a previous onboarding or a kubelet, is installed
+
+Every check is reported PASS or FAIL; the command fails if any check does. onboard runs the same
+checks first unless --skip-preflight is set.`,
+	Example: `  byohctl preflight -u your-fqdn.platform9.com`,
+	Run:     runPreflight,
+}
+
+func init() {
+	preflightCmd.Flags().StringVarP(&preflightFQDN, "url", "u", "", "Platform9 FQDN")
+	_ = preflightCmd.MarkFlagRequired("url")
+	rootCmd.AddCommand(preflightCmd)
+}
+
+func runPreflight(cmd *cobra.Command, args []string) {
+	if !checkPreflight(preflightFQDN) {
+		utils.RecordStep(utils.NextStep, "byohctl preflight -u %s once the failed checks are fixed", preflightFQDN)
+		utils.PrintSummary("preflight", false)
+		os.Exit(types.ExitPreflight)
+	}
+	utils.RecordStep(utils.NextStep, "byohctl onboard -u %s to onboard the host", preflightFQDN)
+	utils.PrintSummary("preflight", true)
+}
+
+// checkPreflight runs the preflight checks of the host against the management plane fqdn,
+// prints their report and returns whether they all passed
+func checkPreflight(fqdn string) bool {
+	facts, err := service.GetHostFacts()
+	if err != nil {
+		fmt.Println("Failed to get the facts of the host: " + err.Error())
+		return false
+	}
+	results := (&service.Preflight{}).Run(facts, fqdn)
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("FAIL  %-20s %v\n", result.Name, result.Err)
+		} else {
+			fmt.Printf("PASS  %-20s %s\n", result.Name, result.Detail)
+		}
+	}
+	if service.PreflightFailed(results) {
+		fmt.Println("Host is not ready to be onboarded, fix the failed checks and run byohctl preflight again")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var reauthorizeCmd = &cobra.Command{
+	Use:   "reauthorize",
+	Short: "Move this host to another tenant or region",
+	Long: `Move this onboarded host to another tenant or region of the management plane, keeping the
+installed agent, instead of decommissioning it and onboarding it again.
+This command will:
+1. Authenticate with Platform9 as the new tenant
+2. Replace the kubeconfig of the agent and its region label, and restart the agent
+3. Wait for the agent to register the ByoHost of the host in the namespace of the new tenant
+4. Delete the ByoHost of the host from the namespace of the old tenant
+
+The host must not be attached to a cluster, deauthorise it first. If a step fails, the host is
+moved back to its old tenant and region.
+
+The credentials are given like for byohctl onboard, with flags, a config file or byohctl login.`,
+	Example: `  byohctl reauthorize -u your-fqdn.platform9.com -e admin@platform9.com -c client-token -t other-tenant -r region
+  byohctl reauthorize --config onboard-config.yaml --region other-region`,
+	Run: runReauthorize,
+}
+
+func init() {
+	AddOnboardFlags(
+		reauthorizeCmd,
+		&fqdn, &username, &password, &passwordInteractive,
+		&clientToken, &domain, &tenant, &verbosity, &regionName, &configFile,
+	)
+	addAuthFlags(reauthorizeCmd)
+	rootCmd.AddCommand(reauthorizeCmd)
+}
+
+func runReauthorize(cmd *cobra.Command, args []string) {
+	passwordSource := loadOnboardInputs(true)
+	utils.SetConsoleOutputLevel(verbosity)
+
+	oldNamespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		fmt.Println("Failed to get namespace from kubeconfig: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	passwordSource, err = resolvePassword(passwordSource)
+	if err != nil {
+		utils.LogError("%v", err)
+		os.Exit(types.ExitUsage)
+	}
+	utils.LogDebug("Using the password from %s", passwordSource)
+
+	if err := reauthorizeHost(oldNamespace); err != nil {
+		fmt.Println("Failed to reauthorize the host. " + err.Error())
+		utils.PrintSummary("reauthorize", false)
+		os.Exit(types.ExitCode(err))
+	}
+	utils.RecordStep(utils.NextStep, "byohctl status to check the health of the host")
+	utils.PrintSummary("reauthorize", true)
+}
+
+// reauthorizeHost moves the host from oldNamespace to the tenant and the region of the flags: it
+// saves the kubeconfig of the new tenant and the new region label for the installed agent, restarts
+// it and waits for its heartbeat, then deletes the ByoHost of oldNamespace. If a step fails, the
+// host is moved back.
+func reauthorizeHost(oldNamespace string) (err error) {
+	oldClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+	byoHost, err := oldClient.GetByoHostObject(oldNamespace)
+	if err != nil {
+		return err
+	}
+	if byoHost.Status.MachineRef != nil {
+		return fmt.Errorf("%w: it is the host of machine %s, deauthorise it first", types.ErrHostAttached, byoHost.Status.MachineRef.Name)
+	}
+	labels, err := service.RegionAgentLabels(service.ByohDir, regionName)
+	if err != nil {
+		return fmt.Errorf("%w: %w", types.ErrNotOnboarded, err)
+	}
+
+	k8sClient, err := authenticate(nil)
+	if err != nil {
+		return err
+	}
+	if k8sClient.Namespace() == oldNamespace && byoHost.Labels[service.PcdKaapiRegionKey] == regionName {
+		return fmt.Errorf("%w: the host is already in namespace %s and region %s", types.ErrUsage, oldNamespace, regionName)
+	}
+
+	// Lock the host, so the agent is not changed while another operation changes it
+	lock, err := service.LockHost("byohctl reauthorize")
+	if err != nil {
+		utils.LogError("%v", err)
+		return err
+	}
+	defer service.UnlockHost(lock)
+
+	// the rollback runs before the host is unlocked, the agent restarts once its files are restored
+	rollback := &utils.Rollback{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		utils.LogInfo("Moving the host back to namespace %s", oldNamespace)
+		if rollbackErr := rollback.Run(); rollbackErr != nil {
+			utils.LogError("Failed to move the host back, decommission it and onboard it again: %v", rollbackErr)
+		}
+	}()
+	rollback.Add("restart the agent service "+service.ByohAgentServiceName, service.RestartAgentService)
+
+	start := time.Now()
+	if err := service.StopAgentService(); err != nil {
+		return err
+	}
+	if err := saveKubeconfig(k8sClient, service.ByohDir, nil, rollback); err != nil {
+		return err
+	}
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+	newClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+
+	// the agent-after-install script passed the labels and the namespace to the agent service
+	regionFile := filepath.Join(service.ByohDir, service.RegionFilename)
+	if err := rollback.RestoreFile(regionFile); err != nil {
+		return err
+	}
+	if err := os.WriteFile(regionFile, []byte(labels), service.DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to save the region: %w", err)
+	}
+	if err := rollback.RestoreFile(service.AgentServiceEnvPath); err != nil {
+		return err
+	}
+	if err := service.SetAgentServiceEnv(map[string]string{"NAMESPACE": namespace, "REGION": labels}); err != nil {
+		return err
+	}
+	utils.RecordStep(utils.HostChanged, "Moved the agent to namespace %s and region %s", namespace, regionName)
+
+	if namespace != oldNamespace {
+		rollback.Add("delete the ByoHost of the host from namespace "+namespace, func() error {
+			return newClient.DeleteByoHostObject(namespace)
+		})
+	}
+	if err := service.RestartAgentService(); err != nil {
+		return err
+	}
+	utils.LogInfo("Waiting for the agent to register the host in namespace %s", namespace)
+	if err := newClient.WaitForHeartbeat(namespace, start); err != nil {
+		return err
+	}
+
+	// the host is moved, a ByoHost left behind in the old namespace is only reported
+	if namespace != oldNamespace {
+		if err := oldClient.DeleteByoHostObject(oldNamespace); err != nil {
+			utils.LogWarn("Failed to delete the ByoHost of the host from namespace %s, delete it from the management plane: %v", oldNamespace, err)
+		} else {
+			utils.RecordStep(utils.PlaneObject, "Deleted the ByoHost of the host from namespace %s", oldNamespace)
+		}
+	}
+	utils.RecordStep(utils.PlaneObject, "ByoHost %s/%s, registered by the agent", namespace, byoHost.Name)
+	utils.LogSuccess("Moved the host to namespace %s and region %s", namespace, regionName)
+	return nil
+}
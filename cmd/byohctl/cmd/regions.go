@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var regionsCmd = &cobra.Command{
+	Use:   "regions",
+	Short: "List the regions a host can be onboarded to",
+	Long: `List the regions available to a tenant, the values of the --region flag of byohctl onboard, one
+per line. The host is not changed.
+
+The credentials are given like for byohctl onboard, with flags, a config file or byohctl login. On
+an onboarded host without --url and --config, the regions of the tenant of the host are listed.
+
+With --region, regions exits with code 4 if the region is not available to the tenant.`,
+	Example: `  byohctl regions -u your-fqdn.platform9.com -e admin@platform9.com -c client-token -t tenant
+  byohctl regions --config onboard-config.yaml
+  byohctl regions --config onboard-config.yaml --region region-two`,
+	Run: runRegions,
+}
+
+func init() {
+	AddOnboardFlags(
+		regionsCmd,
+		&fqdn, &username, &password, &passwordInteractive,
+		&clientToken, &domain, &tenant, &verbosity, &regionName, &configFile,
+	)
+	addAuthFlags(regionsCmd)
+	rootCmd.AddCommand(regionsCmd)
+}
+
+func runRegions(cmd *cobra.Command, args []string) {
+	var regions []string
+	var err error
+	if _, statErr := os.Stat(service.KubeconfigFilePath); statErr == nil && fqdn == "" && configFile == "" {
+		utils.SetConsoleOutputLevel(verbosity)
+		regions, err = client.ListRegions(service.KubeconfigFilePath)
+	} else {
+		passwordSource := loadOnboardInputs(false)
+		utils.SetConsoleOutputLevel(verbosity)
+		if _, err := resolvePassword(passwordSource); err != nil {
+			utils.LogError("%v", err)
+			os.Exit(types.ExitUsage)
+		}
+		regions, err = tenantRegions()
+	}
+	if err != nil {
+		fmt.Println("Failed to list the regions: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+
+	if err := printRegions(os.Stdout, regions, regionName); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+}
+
+// tenantRegions authenticates with the management plane and returns the regions of the tenant of the flags
+func tenantRegions() ([]string, error) {
+	k8sClient, err := authenticate(nil)
+	if err != nil {
+		return nil, err
+	}
+	return k8sClient.TenantRegions()
+}
+
+// printRegions prints regions to w one per line, it fails with ErrRegionUnavailable if region is
+// set and not one of them
+func printRegions(w io.Writer, regions []string, region string) error {
+	if len(regions) == 0 {
+		return errors.New("no region is available to the tenant")
+	}
+	for _, name := range regions {
+		fmt.Fprintln(w, name)
+	}
+	if region != "" && !slices.Contains(regions, region) {
+		return fmt.Errorf("%w: %s", types.ErrRegionUnavailable, region)
+	}
+	return nil
+}
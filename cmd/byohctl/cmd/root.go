@@ -1,28 +1,71 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	"github.com/spf13/cobra"
 )
 
+// errInit marks the failures to initialize the commands, the other errors of Execute are invalid
+// flags and arguments
+var errInit = errors.New("failed to initialize")
+
+// noLogFileAnnotation marks the commands that do not start a new debug log of byohctl, which
+// would truncate it, e.g. logs that reads it
+const noLogFileAnnotation = "byohctl/no-log-file"
+
 var rootCmd = &cobra.Command{
 	Use:   "byohctl",
 	Short: "BYOH control tool for Platform9",
 	Long: `BYOH (Bring Your Own Host) control tool for Platform9.
 This tool helps onboard hosts to your Platform9 deployment.`,
-	CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if utils.NetworkBackoff.Attempts < 1 {
+			return fmt.Errorf("invalid --retries %d, it must be at least 1", utils.NetworkBackoff.Attempts)
+		}
+		if client.HTTPTimeout <= 0 || service.WaitTimeout <= 0 {
+			return fmt.Errorf("invalid --http-timeout %s or --wait-timeout %s, they must be positive", client.HTTPTimeout, service.WaitTimeout)
+		}
+		// a dry run does not change the host, not even with its log file
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun || cmd.Annotations[noLogFileAnnotation] != "" {
+			return nil
+		}
 		// Initialize loggers
 		if err := utils.InitLoggers(service.ByohDir, true); err != nil {
-			return fmt.Errorf("failed to initialize loggers: %v", err)
+			return fmt.Errorf("%w loggers: %v", errInit, err)
 		}
 		return nil
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().IntVar(&utils.NetworkBackoff.Attempts, "retries", utils.NetworkBackoff.Attempts,
+		"Attempts of the requests to the management plane and of the downloads that fail on the network, 1 does not retry them")
+	rootCmd.PersistentFlags().DurationVar(&utils.NetworkBackoff.BaseDelay, "retry-delay", utils.NetworkBackoff.BaseDelay,
+		"Delay before the first retry of a failed network operation, doubled before each next retry")
+	rootCmd.PersistentFlags().DurationVar(&utils.NetworkBackoff.MaxDelay, "retry-max-delay", utils.NetworkBackoff.MaxDelay,
+		"Maximum delay between two attempts of a failed network operation")
+	rootCmd.PersistentFlags().DurationVar(&client.HTTPTimeout, "http-timeout", client.HTTPTimeout,
+		"Timeout of a request to the management plane, its retries included, raise it on slow links")
+	rootCmd.PersistentFlags().DurationVar(&service.WaitTimeout, "wait-timeout", service.WaitTimeout,
+		"How long decommission waits for the machine of the host to release it, and upgrade for a heartbeat of the agent")
+	rootCmd.PersistentFlags().BoolVarP(&utils.AssumeYes, "yes", "y", false,
+		"Answer yes to the confirmations of deauthorise and decommission, for unattended runs")
+	rootCmd.PersistentFlags().BoolVar(&utils.NonInteractive, "non-interactive", false,
+		"Fail instead of asking for a confirmation or a TOTP code, for automation; --yes answers the confirmations")
+}
+
+// Execute runs the command of the command line, its errors wrap types.ErrUsage but for the
+// failures to initialize the command
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err != nil && !errors.Is(err, errInit) {
+		return fmt.Errorf("%w: %w", types.ErrUsage, err)
+	}
+	return err
 }
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var statusOutput string
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether this host is healthy",
+	Long: `Show whether this host is healthy: the state of the agent service, the version of the agent package,
+whether the kubeconfig of the host is valid, and from the ByoHost of the host on the management plane
+the last heartbeat of the agent, its AgentConnected condition, the version the agent reports, and the
+machine and the cluster the host is part of.
+
+status exits with code 1 if the host is not healthy, the problems are listed after the status.`,
+	Example: `  byohctl status
+  byohctl status -o json`,
+	Run: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "Output format (table, json)")
+	_ = statusCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(statusCmd)
+}
+
+// hostStatus is the health of the host byohctl status prints
+type hostStatus struct {
+	AgentService     string     `json:"agentService"`
+	AgentVersion     string     `json:"agentVersion,omitempty"`
+	Kubeconfig       string     `json:"kubeconfig"`
+	KubeconfigExpiry *time.Time `json:"kubeconfigExpiry,omitempty"`
+	Namespace        string     `json:"namespace,omitempty"`
+	ByoHost          string     `json:"byoHost,omitempty"`
+	// ReportedAgentVersion is the version the agent reports in the status of the ByoHost
+	ReportedAgentVersion string     `json:"reportedAgentVersion,omitempty"`
+	LastHeartbeat        *time.Time `json:"lastHeartbeatTime,omitempty"`
+	Connected            string     `json:"connected,omitempty"`
+	Machine              string     `json:"machine,omitempty"`
+	Cluster              string     `json:"cluster,omitempty"`
+	// Problems are why the host is not healthy, none for a healthy host
+	Problems []string `json:"problems,omitempty"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	if statusOutput != "table" && statusOutput != "json" {
+		fmt.Printf("Error: unknown output format %q, use table or json\n", statusOutput)
+		os.Exit(types.ExitUsage)
+	}
+
+	status := collectHostStatus(time.Now())
+	var err error
+	if statusOutput == "json" {
+		err = printHostStatusJSON(os.Stdout, status)
+	} else {
+		err = printHostStatusTable(os.Stdout, status)
+	}
+	if err != nil {
+		fmt.Println("Failed to print the status of the host: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+	if len(status.Problems) > 0 {
+		os.Exit(types.ExitFailure)
+	}
+}
+
+// collectHostStatus collects the status of the host from the host and from its ByoHost, now is
+// the time the expiry of the kubeconfig is checked against
+func collectHostStatus(now time.Time) *hostStatus {
+	status := &hostStatus{AgentService: service.AgentServiceState()}
+	switch status.AgentService {
+	case "active":
+	case service.AgentServiceNotInstalled:
+		status.Problems = append(status.Problems, "the agent service is not installed, onboard the host with byohctl onboard")
+	default:
+		status.Problems = append(status.Problems, fmt.Sprintf("the agent service is %s, see sudo journalctl -u %s", status.AgentService, service.ByohAgentServiceName))
+	}
+	if version, err := service.InstalledAgentVersion(); err == nil {
+		status.AgentVersion = version
+	} else {
+		utils.LogDebug("No agent package: %v", err)
+	}
+
+	expiry, expires, err := client.KubeconfigExpiry(service.KubeconfigFilePath)
+	switch {
+	case errors.Is(err, types.ErrNotOnboarded):
+		status.Kubeconfig = "missing"
+		status.Problems = append(status.Problems, fmt.Sprintf("the kubeconfig %s is missing, the host is not onboarded", service.KubeconfigFilePath))
+		return status
+	case err != nil:
+		status.Kubeconfig = "invalid"
+		status.Problems = append(status.Problems, fmt.Sprintf("the kubeconfig %s is invalid: %v", service.KubeconfigFilePath, err))
+		return status
+	case expires && !now.Before(expiry):
+		status.Kubeconfig = "expired"
+		status.Problems = append(status.Problems, fmt.Sprintf("the credentials of the kubeconfig expired at %s, onboard the host again", expiry.Format(time.RFC3339)))
+	default:
+		status.Kubeconfig = "valid"
+	}
+	if expires {
+		status.KubeconfigExpiry = &expiry
+	}
+
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("failed to get the namespace of the kubeconfig: %v", err))
+		return status
+	}
+	status.Namespace = namespace
+	if status.ByoHost, err = service.HostName(); err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("failed to get the name of the host: %v", err))
+		return status
+	}
+	k8sClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("failed to get Kubernetes client: %v", err))
+		return status
+	}
+	byoHost, err := k8sClient.GetByoHostObject(namespace)
+	if err != nil {
+		status.Problems = append(status.Problems, fmt.Sprintf("failed to get the ByoHost from the management plane: %v", err))
+		return status
+	}
+
+	status.ReportedAgentVersion = byoHost.Status.AgentVersion
+	if byoHost.Status.LastHeartbeatTime != nil {
+		status.LastHeartbeat = &byoHost.Status.LastHeartbeatTime.Time
+	}
+	if byoHost.Status.MachineRef != nil {
+		status.Machine = byoHost.Status.MachineRef.Name
+	}
+	status.Cluster = byoHost.Labels[capiv1beta1.ClusterNameLabel]
+	status.Connected = string(corev1.ConditionUnknown)
+	for _, condition := range byoHost.Status.Conditions {
+		switch {
+		case condition.Type == infrastructurev1beta1.AgentConnected:
+			status.Connected = string(condition.Status)
+			if condition.Status != corev1.ConditionTrue && condition.Reason != "" {
+				status.Connected += fmt.Sprintf(" (%s: %s)", condition.Reason, condition.Message)
+			}
+		case condition.Type == infrastructurev1beta1.Quarantined && condition.Status == corev1.ConditionTrue:
+			status.Problems = append(status.Problems, fmt.Sprintf("the host is quarantined: %s", condition.Message))
+		}
+	}
+	if status.Connected != string(corev1.ConditionTrue) {
+		status.Problems = append(status.Problems, "the agent is not connected to the management plane, its heartbeats are stale or missing")
+	}
+	return status
+}
+
+// printHostStatusJSON prints status to w as indented JSON
+func printHostStatusJSON(w io.Writer, status *hostStatus) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(status)
+}
+
+// printHostStatusTable prints status to w as a summary of the host followed by its problems
+func printHostStatusTable(w io.Writer, status *hostStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Agent service:\t%s\n", status.AgentService)
+	fmt.Fprintf(tw, "Agent version:\t%s\n", valueOrNone(status.AgentVersion))
+	kubeconfig := status.Kubeconfig
+	if status.KubeconfigExpiry != nil {
+		kubeconfig += fmt.Sprintf(" (expires %s)", status.KubeconfigExpiry.Format(time.RFC3339))
+	}
+	fmt.Fprintf(tw, "Kubeconfig:\t%s\n", kubeconfig)
+	if status.Namespace != "" {
+		fmt.Fprintf(tw, "ByoHost:\t%s/%s\n", status.Namespace, valueOrNone(status.ByoHost))
+	}
+	if status.Connected != "" {
+		fmt.Fprintf(tw, "Connected:\t%s\n", status.Connected)
+		heartbeat := "none"
+		if status.LastHeartbeat != nil {
+			heartbeat = status.LastHeartbeat.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "Last heartbeat:\t%s\n", heartbeat)
+		fmt.Fprintf(tw, "Reported agent version:\t%s\n", valueOrNone(status.ReportedAgentVersion))
+		fmt.Fprintf(tw, "Machine:\t%s\n", valueOrNone(status.Machine))
+		fmt.Fprintf(tw, "Cluster:\t%s\n", valueOrNone(status.Cluster))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(status.Problems) == 0 {
+		fmt.Fprintln(w, "\nThe host is healthy")
+		return nil
+	}
+	fmt.Fprintln(w, "\nThe host is not healthy:")
+	for _, problem := range status.Problems {
+		fmt.Fprintf(w, "- %s\n", problem)
+	}
+	return nil
+}
+
+// valueOrNone returns value, or none if it is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+// previousPackageFile is the agent package a failed upgrade reinstalls
+var previousPackageFile string
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the agent of this host in place",
+	Long: `Upgrade the agent package of this host in place, keeping the host onboarded.
+This command will:
+1. Download the new agent package, or take it from local disk, and verify it
+2. Stop the agent service, install the new agent package and restart the agent service
+3. Wait for the agent to send a heartbeat to the management plane again
+
+If a step fails, or no heartbeat arrives within --wait-timeout, the previous agent package is
+reinstalled and the agent restarted. The previous agent package is the one the onboarding or the
+last upgrade saved in ` + filepath.Join("~", service.ByohConfigDir, "packages") + `, or --previous-package-file.`,
+	Example: `  byohctl upgrade --agent-version 0.1.500
+  byohctl upgrade --package-file ./pf9-byohost-agent.deb --agent-checksum <sha256>`,
+	Run: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+	upgradeCmd.Flags().StringVarP(&verbosity, "verbosity", "v", "minimal", "Log verbosity level (all, important, minimal, critical, none)")
+	addAgentPackageFlags(upgradeCmd)
+	upgradeCmd.Flags().StringVar(&previousPackageFile, "previous-package-file", "",
+		"Path of the installed agent package to reinstall if the upgrade fails, by default the one saved in "+filepath.Join("~", service.ByohConfigDir, "packages"))
+	upgradeCmd.Flags().StringVar(&service.HostNameOverride, "host-name", "",
+		"Name of the ByoHost of the host, by default the --host-name it was onboarded with or its hostname")
+	_ = upgradeCmd.RegisterFlagCompletionFunc("verbosity", completeVerbosity)
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) {
+	utils.SetConsoleOutputLevel(verbosity)
+
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		fmt.Println("Failed to get namespace from kubeconfig: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	source := agentPackageSource()
+	if err := source.Check(); err != nil {
+		fmt.Println("Error: " + err.Error())
+		os.Exit(types.ExitUsage)
+	}
+
+	if err := upgradeAgent(namespace, source); err != nil {
+		fmt.Println("Failed to upgrade the agent. " + err.Error())
+		utils.PrintSummary("upgrade", false)
+		os.Exit(types.ExitCode(err))
+	}
+	utils.RecordStep(utils.NextStep, "byohctl status to check the health of the host")
+	utils.PrintSummary("upgrade", true)
+}
+
+// upgradeAgent upgrades the agent package from source and waits for the upgraded agent to send a
+// heartbeat to the ByoHost of namespace. If it fails, the previous agent package is reinstalled.
+func upgradeAgent(namespace string, source service.PackageSource) (err error) {
+	k8sClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		return err
+	}
+
+	// Lock the host, so the agent package is not upgraded while another operation changes it
+	lock, err := service.LockHost("byohctl upgrade")
+	if err != nil {
+		utils.LogError("%v", err)
+		return err
+	}
+	defer service.UnlockHost(lock)
+
+	// the rollback runs before the host is unlocked
+	rollback := &utils.Rollback{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		utils.LogInfo("Rolling back the upgrade of the agent")
+		if rollbackErr := rollback.Run(); rollbackErr != nil {
+			utils.LogError("Failed to roll back the upgrade, reinstall the agent package with byohctl upgrade --package-file: %v", rollbackErr)
+		}
+	}()
+
+	start := time.Now()
+	version, err := service.UpgradeAgent(filepath.Join(service.ByohDir, "packages"), source, previousPackageFile, rollback)
+	if err != nil {
+		return err
+	}
+	utils.LogInfo("Waiting for the agent %s to send a heartbeat", version)
+	if err := k8sClient.WaitForHeartbeat(namespace, start); err != nil {
+		return err
+	}
+	utils.LogSuccess("Upgraded the agent to %s", version)
+	return nil
+}
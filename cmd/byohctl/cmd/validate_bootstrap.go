@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
+)
+
+var validateBootstrapCmd = &cobra.Command{
+	Use:   "validate-bootstrap FILE",
+	Short: "Validate the bootstrap data of a host",
+	Long: `Validate bootstrap data, the cloud-config the bootstrap provider renders for a host,
+against the write_files and runcmd directives the BYOH agent implements.
+This is the validation the agent runs before bootstrapping when started with --strict-cloud-init.
+Every error is printed with the field it is found at. Use - as FILE to read the bootstrap data from stdin.`,
+	Example: `  byohctl validate-bootstrap bootstrap-data.yaml
+  kubectl get secret my-machine -o jsonpath='{.data.value}' | base64 -d | byohctl validate-bootstrap -`,
+	Args: cobra.ExactArgs(1),
+	Run:  runValidateBootstrap,
+}
+
+func init() {
+	rootCmd.AddCommand(validateBootstrapCmd)
+}
+
+func runValidateBootstrap(cmd *cobra.Command, args []string) {
+	errs, err := validateBootstrap(args[0])
+	if err != nil {
+		fmt.Println("Failed to read bootstrap data: " + err.Error())
+		os.Exit(types.ExitUsage)
+	}
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println(err.Error())
+		}
+		fmt.Printf("Bootstrap data is invalid: %d errors\n", len(errs))
+		os.Exit(types.ExitFailure)
+	}
+	utils.LogSuccess("Bootstrap data is valid")
+}
+
+// validateBootstrap validates the bootstrap data of the file path, or of stdin if path is -
+func validateBootstrap(path string) (cloudinit.ValidationErrors, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cloudinit.Validate(string(data)), nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/agent/cloudinit"
+)
+
+func TestValidateBootstrap(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte(`write_files:
+- path: /run/kubeadm/kubeadm.yaml
+  permissions: '0640'
+  content: some-config
+runcmd:
+- kubeadm join --config /run/kubeadm/kubeadm.yaml
+`), 0644))
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte(`write_files:
+- path: /run/kubeadm/kubeadm.yaml
+  encoding: base64
+  content: not base64
+ntp:
+  enabled: true
+`), 0644))
+
+	errs, err := validateBootstrap(valid)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	errs, err = validateBootstrap(invalid)
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "ntp", errs[0].Field)
+	assert.Equal(t, cloudinit.ErrorTypeUnknownModule, errs[0].Type)
+	assert.Equal(t, "write_files[0].content", errs[1].Field)
+	assert.Equal(t, cloudinit.ErrorTypeInvalidEncoding, errs[1].Type)
+
+	_, err = validateBootstrap(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/spf13/cobra"
+)
+
+var validateHostCluster string
+
+var validateHostCmd = &cobra.Command{
+	Use:   "validate-host",
+	Short: "Validate this host against the requirements of a cluster",
+	Long: `Validate this host against the requirements of a cluster of the management plane before it joins
+the pool of hosts of the cluster. The Kubernetes version of the cluster, the resource requirements of
+its ByoMachineTemplates and the pre-seeded bundles of its K8sInstallerConfigTemplates are fetched with
+the kubeconfig saved by onboard, then checked against this host:
+1. The OS and the architecture of the host are supported for the Kubernetes version
+2. The kernel of the host is supported
+3. The host has the CPUs, memory and disk the machines of the cluster require
+4. The bundles the cluster installs from are pre-seeded on the host`,
+	Example: `  byohctl validate-host --cluster my-cluster`,
+	Run:     runValidateHost,
+}
+
+func init() {
+	validateHostCmd.Flags().StringVar(&validateHostCluster, "cluster", "", "Name of the cluster to validate the host against")
+	_ = validateHostCmd.MarkFlagRequired("cluster")
+	rootCmd.AddCommand(validateHostCmd)
+}
+
+func runValidateHost(cmd *cobra.Command, args []string) {
+	namespace, err := client.GetNamespaceFromConfig(service.KubeconfigFilePath)
+	if err != nil {
+		fmt.Println("Failed to get namespace from kubeconfig: " + err.Error())
+		os.Exit(types.ExitCode(err))
+	}
+	k8sClient, err := client.GetK8sClient(service.KubeconfigFilePath)
+	if err != nil {
+		fmt.Println("Failed to create Kubernetes client: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+	requirements, err := k8sClient.GetClusterRequirements(namespace, validateHostCluster)
+	if err != nil {
+		fmt.Println("Failed to get the requirements of the cluster: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+	facts, err := service.GetHostFacts()
+	if err != nil {
+		fmt.Println("Failed to get the facts of the host: " + err.Error())
+		os.Exit(types.ExitFailure)
+	}
+
+	if errs := service.ValidateHost(facts, requirements); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Println(err.Error())
+		}
+		fmt.Printf("Host does not satisfy the requirements of cluster %s: %d errors\n", validateHostCluster, len(errs))
+		os.Exit(types.ExitPreflight)
+	}
+	utils.LogSuccess("Host satisfies the requirements of cluster %s (Kubernetes %s)", validateHostCluster, requirements.K8sVersion)
+}
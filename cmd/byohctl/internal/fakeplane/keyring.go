@@ -0,0 +1,48 @@
+package fakeplane
+
+import (
+	"sync"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+)
+
+// Keyring is a fake keyring of the OS keeping its secrets in memory
+type Keyring struct {
+	mu      sync.Mutex
+	secrets map[string][]byte
+}
+
+// NewKeyring returns an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{secrets: map[string][]byte{}}
+}
+
+// Get returns the secret of key, types.ErrNoCredentials if the keyring has none
+func (k *Keyring) Get(key string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	secret, ok := k.secrets[key]
+	if !ok {
+		return nil, types.ErrNoCredentials
+	}
+	return secret, nil
+}
+
+// Set stores secret as the secret of key
+func (k *Keyring) Set(key string, secret []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secrets[key] = secret
+	return nil
+}
+
+// Delete removes the secret of key, types.ErrNoCredentials if the keyring has none
+func (k *Keyring) Delete(key string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.secrets[key]; !ok {
+		return types.ErrNoCredentials
+	}
+	delete(k.secrets, key)
+	return nil
+}
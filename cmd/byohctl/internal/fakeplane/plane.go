@@ -0,0 +1,564 @@
+// Package fakeplane fakes the Platform9 management plane byohctl talks to, dex, the oidc-proxy
+// and the API server of the management cluster, and the host byohctl runs its commands on, so
+// that the onboarding, deauthorise and decommission flows can run end to end in unit tests.
+package fakeplane
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// Username is the user dex authenticates
+	Username = "admin@platform9.com"
+	// Password is the password of Username
+	Password = "password"
+	// ClientToken is the client secret of the kubernetes client of dex
+	ClientToken = "client-token"
+	// ServiceClientID is the client of dex granted tokens with its client credentials
+	ServiceClientID = "byoh-automation"
+	// ServiceClientSecret is the client secret of ServiceClientID
+	ServiceClientSecret = "automation-secret"
+	// MFAToken is the token of the second factor challenge of dex, to send back with the TOTP code
+	MFAToken = "fake-mfa-token"
+	// Token is the id token dex issues, the only bearer token the API server accepts
+	Token = "fake-id-token"
+
+	// BootstrapKubeconfigSecret is the secret onboarding saves the kubeconfig of the host from
+	BootstrapKubeconfigSecret = "byoh-bootstrap-kc"
+	// RegionConfigMap is the configmap listing the regions of a tenant
+	RegionConfigMap = "region-config"
+
+	deploymentNameLabel     = "cluster.x-k8s.io/deployment-name"
+	clusterNameLabel        = "cluster.x-k8s.io/cluster-name"
+	deleteMachineAnnotation = "cluster.x-k8s.io/delete-machine"
+)
+
+// Plane is a fake management plane serving dex, the oidc-proxy and the API server of the
+// management cluster over TLS. Like the controllers of the management cluster, scaling down
+// a machine deployment deletes its machine annotated for deletion and releases its host.
+type Plane struct {
+	// Server serves the management plane
+	Server *httptest.Server
+	// TOTP is the TOTP code of the second factor of Username, the password grant requires it if set
+	TOTP string
+	// Unavailable is the number of the next requests answered with 503 Service Unavailable, like
+	// a management plane behind a flaky network
+	Unavailable int
+
+	mu       sync.Mutex
+	objects  map[string]map[string]interface{}
+	requests []string
+	names    int
+}
+
+// New starts a fake management plane, stopped at the end of the test
+func New(t *testing.T) *Plane {
+	p := &Plane{objects: map[string]map[string]interface{}{}}
+	p.Server = httptest.NewTLSServer(http.HandlerFunc(p.serve))
+	t.Cleanup(p.Server.Close)
+	return p
+}
+
+// FQDN returns the FQDN byohctl reaches the management plane at
+func (p *Plane) FQDN() string {
+	return strings.TrimPrefix(p.Server.URL, "https://")
+}
+
+// Namespace returns the namespace of the tenant of domain, like byohctl derives it from the FQDN
+func (p *Plane) Namespace(domain, tenant string) string {
+	return fmt.Sprintf("%s-%s-%s", strings.Split(p.FQDN(), ".")[0], domain, strings.ReplaceAll(tenant, "_", "-"))
+}
+
+// Transport returns a transport trusting the certificate of the management plane
+func (p *Plane) Transport() http.RoundTripper {
+	return p.Server.Client().Transport
+}
+
+// Kubeconfig returns a kubeconfig of the API server with namespace as the namespace of its context
+func (p *Plane) Kubeconfig(namespace string) []byte {
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: p.Server.Certificate().Raw})
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: management
+  cluster:
+    server: %s
+    certificate-authority-data: %s
+contexts:
+- name: byoh
+  context:
+    cluster: management
+    namespace: %s
+    user: byoh
+current-context: byoh
+users:
+- name: byoh
+  user:
+    token: %s
+`, p.Server.URL, base64.StdEncoding.EncodeToString(ca), namespace, Token))
+}
+
+// Requests returns the method and path of the requests served so far
+func (p *Plane) Requests() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.requests...)
+}
+
+// AddBootstrapKubeconfig adds the bootstrap kubeconfig secret of namespace onboarding saves
+func (p *Plane) AddBootstrapKubeconfig(namespace string) {
+	p.Add("v1", "Secret", namespace, BootstrapKubeconfigSecret, map[string]interface{}{
+		"data": map[string]interface{}{
+			"config": base64.StdEncoding.EncodeToString(p.Kubeconfig(namespace)),
+		},
+	})
+}
+
+// AddNamespace adds the namespace name with labels, byohctl discovers the namespace of a tenant by its labels
+func (p *Plane) AddNamespace(name string, labels map[string]string) {
+	metadataLabels := map[string]interface{}{}
+	for label, value := range labels {
+		metadataLabels[label] = value
+	}
+	p.Add("v1", "Namespace", "", name, map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": metadataLabels},
+	})
+}
+
+// AddRegions adds the region configmap of namespace listing regions
+func (p *Plane) AddRegions(namespace string, regions ...string) {
+	p.Add("v1", "ConfigMap", namespace, RegionConfigMap, map[string]interface{}{
+		"data": map[string]interface{}{"regions": strings.Join(regions, "\n")},
+	})
+}
+
+// AddByoHost adds the ByoHost name of namespace, attached to the machine machineName unless empty
+func (p *Plane) AddByoHost(namespace, name, machineName string) {
+	fields := map[string]interface{}{}
+	if machineName != "" {
+		fields["status"] = map[string]interface{}{
+			"machineRef": map[string]interface{}{
+				"apiVersion": "cluster.x-k8s.io/v1beta1",
+				"kind":       "Machine",
+				"namespace":  namespace,
+				"name":       machineName,
+			},
+		}
+	}
+	p.Add("infrastructure.cluster.x-k8s.io/v1beta1", "ByoHost", namespace, name, fields)
+}
+
+// AddMachineDeployment adds the MachineDeployment name of namespace with replicas
+func (p *Plane) AddMachineDeployment(namespace, name string, replicas int64) {
+	p.Add("cluster.x-k8s.io/v1beta1", "MachineDeployment", namespace, name, map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": replicas},
+	})
+}
+
+// AddMachine adds the Machine name of namespace, owned by the machine deployment deploymentName
+func (p *Plane) AddMachine(namespace, name, deploymentName string) {
+	p.Add("cluster.x-k8s.io/v1beta1", "Machine", namespace, name, map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{deploymentNameLabel: deploymentName},
+		},
+	})
+}
+
+// AddWorkloadNode makes the Machine machineName of namespace the node nodeName of the workload
+// cluster clusterName, whose kubeconfig secret points back to the plane: the pods and the persistent
+// volumes of the workload cluster are served by the plane too
+func (p *Plane) AddWorkloadNode(namespace, clusterName, machineName, nodeName string) {
+	p.Add("v1", "Secret", namespace, clusterName+"-kubeconfig", map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": base64.StdEncoding.EncodeToString(p.Kubeconfig(namespace)),
+		},
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	machine := p.objects[key("machines", namespace, machineName)]
+	metadata, _ := machine["metadata"].(map[string]interface{})
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+		metadata["labels"] = labels
+	}
+	labels[clusterNameLabel] = clusterName
+	machine["status"] = map[string]interface{}{
+		"nodeRef": map[string]interface{}{"apiVersion": "v1", "kind": "Node", "name": nodeName},
+	}
+}
+
+// AddPod adds the Pod name of namespace running on the node nodeName of the workload cluster with annotations
+func (p *Plane) AddPod(namespace, name, nodeName string, annotations map[string]string) {
+	metadataAnnotations := map[string]interface{}{}
+	for annotation, value := range annotations {
+		metadataAnnotations[annotation] = value
+	}
+	p.Add("v1", "Pod", namespace, name, map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": metadataAnnotations},
+		"spec":     map[string]interface{}{"nodeName": nodeName},
+		"status":   map[string]interface{}{"phase": "Running"},
+	})
+}
+
+// Add adds the object kind name of namespace with the given fields
+func (p *Plane) Add(apiVersion, kind, namespace, name string, fields map[string]interface{}) {
+	obj := map[string]interface{}{"apiVersion": apiVersion, "kind": kind}
+	for field, value := range fields {
+		obj[field] = value
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadata["namespace"], metadata["name"] = namespace, name
+	obj["metadata"] = metadata
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.objects[key(resourceOf(kind), namespace, name)] = obj
+}
+
+// Get returns the object name of namespace of the resource, e.g. byohosts, or nil if not found
+func (p *Plane) Get(resource, namespace, name string) map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.objects[key(resource, namespace, name)]
+}
+
+// List returns the objects of namespace of the resource, sorted by name
+func (p *Plane) List(resource, namespace string) []map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefix := key(resource, namespace, "")
+	var keys []string
+	for k := range p.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	objs := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		objs = append(objs, p.objects[k])
+	}
+	return objs
+}
+
+func (p *Plane) serve(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.requests = append(p.requests, r.Method+" "+r.URL.Path)
+	unavailable := p.Unavailable > 0
+	if unavailable {
+		p.Unavailable--
+	}
+	p.mu.Unlock()
+	if unavailable {
+		http.Error(w, "upstream connect error", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.URL.Path == "/dex/token" {
+		p.serveToken(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+Token {
+		writeStatus(w, http.StatusUnauthorized, "Unauthorized", "invalid bearer token")
+		return
+	}
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/oidc-proxy/") {
+		// the oidc-proxy forwards /oidc-proxy/<namespace>/<region>/<path> to the API server
+		parts := strings.SplitN(path, "/", 5)
+		if len(parts) < 5 {
+			writeStatus(w, http.StatusNotFound, "NotFound", "unknown path "+path)
+			return
+		}
+		path = "/" + parts[4]
+	}
+	p.serveAPI(w, r, path)
+}
+
+func (p *Plane) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	switch form.Get("grant_type") {
+	case "password":
+		if form.Get("client_secret") != ClientToken || form.Get("username") != Username || form.Get("password") != Password {
+			break
+		}
+		if p.TOTP != "" && form.Get("totp") == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+				"error": "mfa_required", "error_description": "Multifactor authentication required", "mfa_token": MFAToken,
+			})
+			return
+		}
+		if p.TOTP != "" && (form.Get("totp") != p.TOTP || form.Get("mfa_token") != MFAToken) {
+			break
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id_token": Token, "token_type": "bearer"})
+		return
+	case "client_credentials":
+		if form.Get("client_id") == ServiceClientID && form.Get("client_secret") == ServiceClientSecret {
+			writeJSON(w, http.StatusOK, map[string]interface{}{"id_token": Token, "token_type": "bearer", "expires_in": 3600})
+			return
+		}
+	}
+	http.Error(w, `{"error":"invalid_grant"}`, http.StatusUnauthorized)
+}
+
+// serveAPI serves /api/v1/namespaces/<namespace>/<resource>[/<name>],
+// /apis/<group>/<version>/namespaces/<namespace>/<resource>[/<name>], the lists of the objects
+// of a namespace or of every namespace, e.g. /api/v1/pods, matching their labelSelector and
+// fieldSelector, and the self subject access reviews
+func (p *Plane) serveAPI(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		parts = nil
+	}
+	if len(parts) == 1 && r.Method == http.MethodGet {
+		p.serveList(w, parts[0], "", r.URL.Query().Get("labelSelector"), r.URL.Query().Get("fieldSelector"))
+		return
+	}
+	if len(parts) == 3 && parts[0] == "namespaces" && r.Method == http.MethodGet {
+		p.serveList(w, parts[2], parts[1], r.URL.Query().Get("labelSelector"), r.URL.Query().Get("fieldSelector"))
+		return
+	}
+	if len(parts) == 1 && r.Method == http.MethodPost && parts[0] == "selfsubjectaccessreviews" {
+		// the id token of dex is allowed everything
+		review, err := readObject(r)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		review["status"] = map[string]interface{}{"allowed": true}
+		writeJSON(w, http.StatusCreated, review)
+		return
+	}
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != "namespaces" {
+		writeStatus(w, http.StatusNotFound, "NotFound", "unknown path "+path)
+		return
+	}
+	namespace, resource, name := parts[1], parts[2], ""
+	if len(parts) == 4 {
+		name = parts[3]
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && name != "":
+		obj, ok := p.objects[key(resource, namespace, name)]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", resource, name))
+			return
+		}
+		writeJSON(w, http.StatusOK, obj)
+	case r.Method == http.MethodPost && name == "":
+		obj, err := readObject(r)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			obj["metadata"] = metadata
+		}
+		name, _ = metadata["name"].(string)
+		if generateName, _ := metadata["generateName"].(string); name == "" && generateName != "" {
+			p.names++
+			name = fmt.Sprintf("%s%05d", generateName, p.names)
+		}
+		if _, ok := p.objects[key(resource, namespace, name)]; ok {
+			writeStatus(w, http.StatusConflict, "AlreadyExists", fmt.Sprintf("%s %q already exists", resource, name))
+			return
+		}
+		metadata["namespace"], metadata["name"] = namespace, name
+		p.objects[key(resource, namespace, name)] = obj
+		writeJSON(w, http.StatusCreated, obj)
+	case r.Method == http.MethodPut && name != "":
+		old, ok := p.objects[key(resource, namespace, name)]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", resource, name))
+			return
+		}
+		obj, err := readObject(r)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		p.objects[key(resource, namespace, name)] = obj
+		if resource == "machinedeployments" && replicas(obj) < replicas(old) {
+			p.scaleDown(namespace, name)
+		}
+		writeJSON(w, http.StatusOK, obj)
+	case r.Method == http.MethodDelete && name != "":
+		if _, ok := p.objects[key(resource, namespace, name)]; !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", resource, name))
+			return
+		}
+		delete(p.objects, key(resource, namespace, name))
+		writeStatus(w, http.StatusOK, "", "")
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method+" is not supported on "+path)
+	}
+}
+
+// listKinds are the kinds of the lists serveList serves, by their resource
+var listKinds = map[string]string{
+	"namespaces":        "NamespaceList",
+	"pods":              "PodList",
+	"events":            "EventList",
+	"persistentvolumes": "PersistentVolumeList",
+}
+
+// serveList serves the list of the objects of resource of namespace, of every namespace if it is
+// empty, with every label=value of labelSelector and every field=value of fieldSelector, e.g.
+// spec.nodeName=node-1
+func (p *Plane) serveList(w http.ResponseWriter, resource, namespace, labelSelector, fieldSelector string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	prefix := resource + "/"
+	if namespace != "" {
+		prefix = key(resource, namespace, "")
+	}
+	var keys []string
+	for k := range p.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	items := []interface{}{}
+	for _, k := range keys {
+		obj := p.objects[k]
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		labels, _ := metadata["labels"].(map[string]interface{})
+		if matches(labelSelector, func(label string) interface{} { return labels[label] }) &&
+			matches(fieldSelector, func(field string) interface{} { return fieldOf(obj, field) }) {
+			items = append(items, obj)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"apiVersion": "v1", "kind": listKinds[resource], "items": items})
+}
+
+// matches reports whether every key=value requirement of selector is met by the values of valueOf
+func matches(selector string, valueOf func(key string) interface{}) bool {
+	for _, requirement := range strings.Split(selector, ",") {
+		k, value, _ := strings.Cut(requirement, "=")
+		if requirement != "" && valueOf(k) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldOf returns the field of obj at the dotted path field, e.g. spec.nodeName, nil if not set
+func fieldOf(obj map[string]interface{}, field string) interface{} {
+	var value interface{} = obj
+	for _, name := range strings.Split(field, ".") {
+		fields, _ := value.(map[string]interface{})
+		value = fields[name]
+	}
+	return value
+}
+
+// scaleDown deletes the machines of the machine deployment annotated for deletion and releases
+// their hosts, as the controllers of the management cluster do
+func (p *Plane) scaleDown(namespace, deploymentName string) {
+	for k, machine := range p.objects {
+		if !strings.HasPrefix(k, key("machines", namespace, "")) {
+			continue
+		}
+		metadata, _ := machine["metadata"].(map[string]interface{})
+		labels, _ := metadata["labels"].(map[string]interface{})
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if labels[deploymentNameLabel] != deploymentName || annotations[deleteMachineAnnotation] == nil {
+			continue
+		}
+		delete(p.objects, k)
+		for hostKey, host := range p.objects {
+			if !strings.HasPrefix(hostKey, key("byohosts", namespace, "")) {
+				continue
+			}
+			status, _ := host["status"].(map[string]interface{})
+			machineRef, _ := status["machineRef"].(map[string]interface{})
+			if machineRef != nil && machineRef["name"] == metadata["name"] {
+				delete(status, "machineRef")
+			}
+		}
+	}
+}
+
+func replicas(obj map[string]interface{}) int64 {
+	spec, _ := obj["spec"].(map[string]interface{})
+	switch replicas := spec["replicas"].(type) {
+	case int64:
+		return replicas
+	case float64:
+		return int64(replicas)
+	}
+	return 0
+}
+
+func readObject(r *http.Request) (map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	obj := map[string]interface{}{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(obj)
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, message string) {
+	status := "Success"
+	if code >= http.StatusBadRequest {
+		status = "Failure"
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Status",
+		"status":     status,
+		"reason":     reason,
+		"message":    message,
+		"code":       code,
+	})
+}
+
+func key(resource, namespace, name string) string {
+	return resource + "/" + namespace + "/" + name
+}
+
+// resourceOf returns the resource of kind, e.g. byohosts for ByoHost
+func resourceOf(kind string) string {
+	return strings.ToLower(kind) + "s"
+}
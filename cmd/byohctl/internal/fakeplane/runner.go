@@ -0,0 +1,68 @@
+package fakeplane
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec/hostexectest"
+)
+
+// Runner is a fake host running the commands of byohctl. A command line succeeds without
+// output unless a result is set for it, and LookPath finds every executable in /usr/bin
+// unless it is missing.
+type Runner struct {
+	*hostexectest.Fake
+}
+
+const (
+	// UbuntuOSRelease is the os-release of an Ubuntu host
+	UbuntuOSRelease = "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\nID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"22.04\"\n"
+	// RockyOSRelease is the os-release of a Rocky Linux host
+	RockyOSRelease = "NAME=\"Rocky Linux\"\nPRETTY_NAME=\"Rocky Linux 9.3 (Blue Onyx)\"\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n"
+)
+
+// NewRunner returns a fake Ubuntu host on which the BYOH agent is not installed yet and apt is not locked
+func NewRunner() *Runner {
+	r := &Runner{Fake: hostexectest.NewFake()}
+	// lsof exits with code 1 if the apt lock is not held
+	r.Set("lsof /var/lib/apt/lists/lock", "", fmt.Errorf("exit status 1"))
+	return r
+}
+
+// FakePackage is the content of the packages imgpkg pulls on the fake host
+const FakePackage = "fake package"
+
+// PullFile returns the effect of an imgpkg pull writing the file name into its output directory,
+// like the images of the agent package published without a checksum file
+func PullFile(name string) func(args []string) error {
+	return pullFile(name, false)
+}
+
+// PullFileWithChecksum is like PullFile, with the SHA256 checksum file of the file next to it
+func PullFileWithChecksum(name string) func(args []string) error {
+	return pullFile(name, true)
+}
+
+func pullFile(name string, withChecksum bool) func(args []string) error {
+	return func(args []string) error {
+		for i, arg := range args {
+			if arg == "-o" && i+1 < len(args) {
+				if err := os.MkdirAll(args[i+1], 0755); err != nil {
+					return err
+				}
+				path := filepath.Join(args[i+1], name)
+				if err := os.WriteFile(path, []byte(FakePackage), 0644); err != nil {
+					return err
+				}
+				if !withChecksum {
+					return nil
+				}
+				checksum := fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(FakePackage)), name)
+				return os.WriteFile(path+".sha256", []byte(checksum), 0644)
+			}
+		}
+		return fmt.Errorf("imgpkg pull without an output directory")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/cmd"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/version"
 )
@@ -22,6 +23,6 @@ func init() {
 func main() {
 	if err := cmd.Execute(); err != nil {
 		utils.LogError("Command execution failed: %s", err.Error())
-		os.Exit(1)
+		os.Exit(types.ExitCode(err))
 	}
 }
@@ -1,12 +1,20 @@
 package pkg
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/user"
+	"time"
 
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/client"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/service"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostoperation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
 
 type HostOperationType string
@@ -16,8 +24,9 @@ const (
 	OperationDecommission HostOperationType = "decommission"
 )
 
-// PerformHostOperation performs the common steps for host deauthorisation or decommissioning
-func PerformHostOperation(operationType HostOperationType, namespace string) error {
+// PerformHostOperation performs the common steps for host deauthorisation or decommissioning. Unless
+// force is set, it refuses to remove a host from its cluster while the host runs critical workloads.
+func PerformHostOperation(operationType HostOperationType, namespace string, force bool) error {
 
 	// Deauthorise and decommission host steps -
 	// 1. Authenticate with Platform9 with the kubeconfig present in the agent directory ( kubeconfig )
@@ -27,65 +36,69 @@ func PerformHostOperation(operationType HostOperationType, namespace string) err
 	// 5. Scale down the machine deployment by 1
 	// 6. Wait for machineRef to be unset from the byohost object status field
 	// Once the machienRef is unset, host is deauthorised
-	// If the request is to decommission, delete the byohost object and run dpkg purge
+	// If the request is to decommission, delete the byohost object and purge the agent package
 	// 7. Delete the byohost object
-	// 8. Run dpkg --purge byohost-agent
+	// 8. Purge the pf9-byohost-agent package
 
 	utils.LogInfo("Performing %s operation for host in namespace %s", operationType, namespace)
 
 	// 1. Check if kubeconfig file exists
 	if _, err := os.Stat(service.KubeconfigFilePath); os.IsNotExist(err) {
-		return fmt.Errorf("kubeconfig file not found at %s. Please onboard the host first.", service.KubeconfigFilePath)
+		return fmt.Errorf("%w: kubeconfig file not found at %s, please onboard the host first", types.ErrNotOnboarded, service.KubeconfigFilePath)
 	}
 
 	// 2. Get Kubernetes client
 	client, err := client.GetK8sClient(service.KubeconfigFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to get Kubernetes client: %v", err)
+		return fmt.Errorf("failed to get Kubernetes client: %w", err)
 	}
 
-	utils.LogSuccess("Successfully retrieved Kubernetes client")
+	utils.LogInfo("Successfully retrieved Kubernetes client")
 
 	// 3. Check if byohost object exists
 	byoHost, err := client.GetByoHostObject(namespace)
 	if err != nil {
 		fmt.Println("failed to get ByoHosts object from the management plane: " + err.Error())
 		// There might be a chance that the byohost object is not present in the management cluster
-		// If decommission, ask user to proceed with host cleanup or not, run dpkg purge if yes
+		// If decommission, ask user to proceed with host cleanup or not, purge the agent package if yes
 		if operationType == OperationDecommission {
 			// Ask user to proceed with host cleanup or not
-			continueDecommission, err := utils.AskBool("Do you want to proceed with host cleanup? (y/n)")
+			continueDecommission, err := confirm("Do you want to proceed with host cleanup? (y/n)")
 			if err != nil {
-				return fmt.Errorf("failed to get user input: %v", err)
+				return err
 			}
 			if !continueDecommission {
 				return nil
 			}
-			err = service.PurgeDebianPackage()
+			lock, err := service.LockHost("byohctl decommission")
 			if err != nil {
-				return fmt.Errorf("failed to run dpkg purge: %v", err)
+				return err
+			}
+			defer service.UnlockHost(lock)
+			err = service.PurgeAgentPackage()
+			if err != nil {
+				return fmt.Errorf("failed to purge the agent package: %w", err)
 			}
-			utils.LogSuccess("Successfully ran dpkg purge")
 			return nil
 		}
 
 		// If its here, the operationType is deauthorise
 		// For deathorise byoHost object must be present in the management cluster
-		return fmt.Errorf("Cannot proceed ahead with the deauthorisation. Either restart the pf9-byohost-agent service or decommission and re-onboard.")
+		return fmt.Errorf("%w: cannot proceed ahead with the deauthorisation, either restart the pf9-byohost-agent service or decommission and re-onboard: %w", types.ErrNotOnboarded, err)
 	}
 
-	utils.LogSuccess("Successfully retrieved ByoHosts object from the management plane")
+	utils.LogInfo("Successfully retrieved ByoHosts object from the management plane")
 
 	// 4. Check if machineRef is set to the byohost object
 	if byoHost.Status.MachineRef == nil {
 		// Host is not attached to any cluster
-		// Delete the byohost object and run dpkg purge if decommission
+		// Delete the byohost object and purge the agent package if decommission
 		// If deauthorise, just return
 		if operationType == OperationDecommission {
-			utils.LogInfo("MachineRef is not set to the byohost object. Host is not part of any cluster. Deleting the byohost object and running dpkg purge.")
+			utils.LogInfo("MachineRef is not set to the byohost object. Host is not part of any cluster. Deleting the byohost object and purging the agent package.")
 			return performHostDecommissionWithNoMachineRef(client, namespace)
 		}
-		return fmt.Errorf("machineRef is not set for the byohost object. This host is not part of the cluster. Cannot proceed ahead with de-auth")
+		return fmt.Errorf("%w: machineRef is not set for the byohost object, cannot proceed ahead with de-auth", types.ErrHostNotAttached)
 
 		// We should return from here even if deauth or decommission
 	}
@@ -95,12 +108,17 @@ func PerformHostOperation(operationType HostOperationType, namespace string) err
 	// Get the machine object ( unstructured )
 	unstructuredMachineObj, err := client.GetUnstructuredMachineObject(namespace, machineName)
 	if err != nil {
-		return fmt.Errorf("failed to get machine object: %v", err)
+		return fmt.Errorf("failed to get machine object: %w", err)
 	}
 
 	// At this point, we know that the host is part of some cluster since the machineRef is set.
 	// There must be respctive machine object in the cluster and the machine deployment must have replicas set and greater than or equal to 1
 
+	// Refuse to destroy the stateful workloads pinned to the host
+	if err := checkCriticalWorkloads(client, unstructuredMachineObj, namespace, force); err != nil {
+		return err
+	}
+
 	// TODO: Right now considering there is only one machine deployment is associated with the cluster.
 	// There might be a multiple machine deployments associated with the cluster.
 	// So when doing de-auth, check if the node count in the workload cluster and stop the de-auth if that is last node.
@@ -108,59 +126,59 @@ func PerformHostOperation(operationType HostOperationType, namespace string) err
 	// Check machine deployment replica count. If it is 1, then warn and ask the user to continue de-uth or not.
 	replicaCount, err := client.GetMachineDeploymentReplicaCount(unstructuredMachineObj, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to get machine deployment replica count: %v", err)
+		return fmt.Errorf("failed to get machine deployment replica count: %w", err)
 	}
 
 	if replicaCount == 1 {
 		fmt.Println("Info: Machine deployment replica count is 1. This is the last node in the cluster.")
 
 		// Ask user to continue de-auth or not
-		continueDeauth, err := utils.AskBool("Do you want to continue with de-auth? (y/n)")
+		continueDeauth, err := confirm("Do you want to continue with de-auth? (y/n)")
 		if err != nil {
-			return fmt.Errorf("failed to get user input: %v", err)
+			return err
 		}
 		if !continueDeauth {
-			return fmt.Errorf("Info: De-auth cancelled by user.")
+			return fmt.Errorf("de-auth %w", types.ErrCancelled)
 		}
 
 		// Since this is the last machine in the cluster, annotate machine objects to exclude the node drain
 		err = client.AnnotateMachineObject(unstructuredMachineObj, namespace, "machine.cluster.x-k8s.io/exclude-node-draining", "")
 		if err != nil {
-			return fmt.Errorf("failed to annotate the last machine object to be deauth: %v", err)
+			return fmt.Errorf("failed to annotate the last machine object to be deauth: %w", err)
 		}
 	}
 
 	// Get the fresh machine object from the server to get the updated machine object
 	unstructuredMachineObj, err = client.GetUnstructuredMachineObject(namespace, machineName)
 	if err != nil {
-		return fmt.Errorf("failed to get machine object: %v", err)
+		return fmt.Errorf("failed to get machine object: %w", err)
 	}
 
 	// 5. Annonate the respective machine object with "cluster.x-k8s.io/delete-machine"="yes"
 	err = client.AnnotateMachineObject(unstructuredMachineObj, namespace, "cluster.x-k8s.io/delete-machine", "yes")
 	if err != nil {
-		return fmt.Errorf("failed to annotate machine object: %v", err)
+		return fmt.Errorf("failed to annotate machine object: %w", err)
 	}
 
-	utils.LogSuccess("Successfully annotated machine object that needs to be removed from the cluster")
+	utils.RecordStep(utils.PlaneObject, "Annotated Machine %s/%s to be removed from its cluster", namespace, machineName)
 
 	// 6. Scale down the machine deployment by 1
 	err = client.ScaleDownMachineDeployment(unstructuredMachineObj, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to scale down machine deployment: %v", err)
+		return fmt.Errorf("failed to scale down machine deployment: %w", err)
 	}
 
-	utils.LogSuccess("Successfully scaled down machine deployment by 1")
+	utils.RecordStep(utils.PlaneObject, "Scaled down the MachineDeployment of Machine %s/%s by 1", namespace, machineName)
 
 	// 7. Wait for machineRef to be unset from the byohost object status field
 	err = client.WaitForMachineRefToBeUnset(byoHost, namespace)
 	if err != nil {
-		return fmt.Errorf("failed to wait for machineRef to be unset: %v", err)
+		return fmt.Errorf("failed to wait for machineRef to be unset: %w", err)
 	}
 
-	utils.LogSuccess("MachineRef successfully unset for the host")
+	utils.RecordStep(utils.PlaneObject, "ByoHost %s/%s released by Machine %s", namespace, byoHost.Name, machineName)
 
-	// If operation is decommission, delete the byohost object and run dpkg purge
+	// If operation is decommission, delete the byohost object and purge the agent package
 	if operationType == OperationDecommission {
 		return performHostDecommissionWithNoMachineRef(client, namespace)
 	}
@@ -168,28 +186,125 @@ func PerformHostOperation(operationType HostOperationType, namespace string) err
 	return nil
 }
 
+// checkCriticalWorkloads fails if the node of the machine machineObj runs pods annotated not to be
+// evicted or has local persistent volumes, which are lost with the host. With force, the workloads
+// are only warned about, and a workload cluster that cannot be queried does not fail the check.
+func checkCriticalWorkloads(managementClient *client.Client, machineObj *unstructured.Unstructured, namespace string, force bool) error {
+	nodeName, _, _ := unstructured.NestedString(machineObj.Object, "status", "nodeRef", "name")
+	if nodeName == "" {
+		// the machine never became a node, nothing runs on the host
+		return nil
+	}
+	clusterName := machineObj.GetLabels()[capiv1beta1.ClusterNameLabel]
+
+	utils.LogInfo("Checking the critical workloads of node %s of cluster %s", nodeName, clusterName)
+	workloads, err := criticalWorkloads(managementClient, namespace, clusterName, nodeName)
+	if err != nil {
+		if force {
+			utils.LogWarn("Failed to check the critical workloads of node %s, continuing as --force is set: %v", nodeName, err)
+			return nil
+		}
+		return fmt.Errorf("failed to check the critical workloads of node %s, use --force to continue without the check: %w", nodeName, err)
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	for _, workload := range workloads {
+		fmt.Println("  - " + workload)
+	}
+	if force {
+		utils.LogWarn("Node %s runs %d critical workloads, continuing as --force is set", nodeName, len(workloads))
+		return nil
+	}
+	return fmt.Errorf("%w: node %s runs the %d workloads above, move them or use --force to continue", types.ErrCriticalWorkloads, nodeName, len(workloads))
+}
+
+// criticalWorkloads returns the critical workloads of the node nodeName of the cluster clusterName of namespace
+func criticalWorkloads(managementClient *client.Client, namespace, clusterName, nodeName string) ([]string, error) {
+	if clusterName == "" {
+		return nil, fmt.Errorf("the machine of node %s has no %s label", nodeName, capiv1beta1.ClusterNameLabel)
+	}
+	workloadClient, err := managementClient.GetWorkloadClient(namespace, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return client.CriticalWorkloads(workloadClient, nodeName)
+}
+
 // Helper function to consolidate decommissioning logic when no machineRef is set
 func performHostDecommissionWithNoMachineRef(client *client.Client, namespace string) error {
 	// 1. Delete the byohost object
-	// 2. Run dpkg purge
+	// 2. Purge the agent package
 	// 3. Return success
 
-	utils.LogInfo("Deleting ByoHosts object and running dpkg purge")
+	utils.LogInfo("Deleting ByoHosts object and purging the agent package")
+	// The lock is taken after the machineRef is unset, as the agent takes it to uninstall the host before
+	lock, err := service.LockHost("byohctl decommission")
+	if err != nil {
+		return err
+	}
+	defer service.UnlockHost(lock)
+
 	// 1. Delete the byohost object
-	err := client.DeleteByoHostObject(namespace)
+	decommissionStart := time.Now()
+	err = client.DeleteByoHostObject(namespace)
+	recordDecommission(client, namespace, decommissionStart, err)
 	if err != nil {
-		return fmt.Errorf("failed to delete ByoHosts object: %v", err)
+		return fmt.Errorf("failed to delete ByoHosts object: %w", err)
 	}
 
-	utils.LogSuccess("Successfully deleted ByoHosts object")
+	utils.RecordStep(utils.PlaneObject, "Deleted the ByoHost of the host from namespace %s", namespace)
 
-	// 2. Run dpkg purge
-	err = service.PurgeDebianPackage()
+	// 2. Purge the agent package
+	err = service.PurgeAgentPackage()
 	if err != nil {
-		return fmt.Errorf("failed to run dpkg purge: %v", err)
+		return fmt.Errorf("failed to purge the agent package: %w", err)
 	}
 
-	utils.LogSuccess("Successfully ran dpkg purge")
-
 	return nil
 }
+
+// recordDecommission records the deletion of the byohost object as its Decommission ByoHostOperation,
+// initiated by the local user running byohctl. A failure to record it is only logged.
+func recordDecommission(client *client.Client, namespace string, start time.Time, decommissionErr error) {
+	hostName, err := service.HostName()
+	if err != nil {
+		utils.LogWarn("Failed to record the decommission of the host: %v", err)
+		return
+	}
+	operation := hostoperation.New(hostoperation.Operation{
+		Namespace: namespace,
+		HostName:  hostName,
+		Type:      infrastructurev1beta1.ByoHostOperationDecommission,
+		Initiator: fmt.Sprintf("%s@%s", localUser(), hostName),
+		StartTime: start,
+	}, decommissionErr)
+	if err := client.CreateByoHostOperation(namespace, operation); err != nil {
+		utils.LogWarn("Failed to record the decommission of the host: %v", err)
+	}
+}
+
+// localUser returns the user that ran byohctl, through sudo if need be
+func localUser() string {
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		return sudoUser
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// confirm asks the user the question msg, a question that cannot be asked with --non-interactive
+// cancels the operation
+func confirm(msg string) (bool, error) {
+	ok, err := utils.AskBool(msg)
+	if errors.Is(err, utils.ErrNonInteractive) {
+		return false, fmt.Errorf("%w: %w", types.ErrCancelled, err)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get user input: %w", err)
+	}
+	return ok, nil
+}
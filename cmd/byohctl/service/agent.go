@@ -2,38 +2,48 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
 	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/nodetaint"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
-// execCommand is a variable so tests can replace it with a mock.
-var execCommand = exec.Command
+// CommandRunner runs the commands of byohctl, tests replace it with a fake host
+var CommandRunner hostexec.Runner = hostexec.Exec{Redact: utils.Redact, Hooks: []hostexec.Hook{logCommand}}
+
+// logCommand logs the commands byohctl ran to the debug log
+func logCommand(event hostexec.Event) {
+	if event.Err != nil {
+		utils.LogDebug("%s failed after %s: %v", event.Command, event.Duration, event.Err)
+		return
+	}
+	utils.LogDebug("%s ran in %s", event.Command, event.Duration)
+}
 
 // Package represents a required package and its installation details
 type Package struct {
 	Name            string
-	InstallCommand  string
-	InstallArgs     []string
 	VerifyCommand   string
 	PackageName     string // Debian package name for dpkg verification
+	RPMPackageName  string // RPM package name, or file it provides, for the RHEL family, empty if not required
 	CustomInstaller func() error
-}
-
-func isPackageInstalled(packageName string) bool {
-	cmd := exec.Command("dpkg", "-l", packageName)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return false
-	}
-	// dpkg -l output has "ii" at the start of the line for installed packages
-	return bytes.Contains(output, []byte("ii  "+packageName))
+	PullOnly        bool   // only needed to pull the agent package, not when it is on local disk
+	InstallPath     string // file CustomInstaller installs, removed when the onboarding is rolled back
 }
 
 var requiredPackages = []Package{
@@ -41,220 +51,647 @@ var requiredPackages = []Package{
 		Name:          "imgpkg",
 		VerifyCommand: "imgpkg",
 		CustomInstaller: func() error {
-			resp, err := http.Get(ImgPkgURL)
-			if err != nil {
-				return fmt.Errorf("failed to download imgpkg: %v", err)
-			}
-			defer resp.Body.Close()
-
-			out, err := os.Create(ImgPkgPath)
+			err := utils.Retry(context.TODO(), utils.NetworkBackoff, "download of imgpkg", downloadImgPkg)
 			if err != nil {
-				return fmt.Errorf("failed to create file: %v", err)
-			}
-			defer out.Close()
-
-			if _, err = io.Copy(out, resp.Body); err != nil {
-				return fmt.Errorf("failed to write file: %v", err)
+				return err
 			}
 
 			if err := os.Chmod(ImgPkgPath, 0755); err != nil {
-				return fmt.Errorf("failed to make file executable: %v", err)
+				return fmt.Errorf("failed to make file executable: %w", err)
 			}
 
-			utils.LogSuccess("Installed imgpkg " + ImgPkgVersion)
+			utils.RecordStep(utils.HostChanged, "Installed imgpkg %s to %s", ImgPkgVersion, ImgPkgPath)
 			return nil
 		},
+		PullOnly:    true,
+		InstallPath: ImgPkgPath,
 	},
 	{
-		Name:           "dpkg",
-		VerifyCommand:  "dpkg",
-		PackageName:    "dpkg",
-		InstallCommand: "apt-get",
-		InstallArgs:    []string{"install", "-y", "dpkg"},
+		Name:          "dpkg",
+		VerifyCommand: "dpkg",
+		PackageName:   "dpkg",
 	},
 	{
-		Name:           "ebtables",
-		VerifyCommand:  "ebtables",
-		PackageName:    "ebtables",
-		InstallCommand: "apt-get",
-		InstallArgs:    []string{"install", "-y", "ebtables"},
+		Name:          "ebtables",
+		VerifyCommand: "ebtables",
+		PackageName:   "ebtables",
+		// ebtables is packaged as iptables-ebtables on RHEL 8 and iptables-nft on RHEL 9
+		RPMPackageName: "/usr/sbin/ebtables",
 	},
 	{
 		Name:           "conntrack",
 		VerifyCommand:  "conntrack",
 		PackageName:    "conntrack",
-		InstallCommand: "apt-get",
-		InstallArgs:    []string{"install", "-y", "conntrack"},
+		RPMPackageName: "conntrack-tools",
 	},
 	{
 		Name:           "socat",
 		VerifyCommand:  "socat",
 		PackageName:    "socat",
-		InstallCommand: "apt-get",
-		InstallArgs:    []string{"install", "-y", "socat"},
+		RPMPackageName: "socat",
 	},
 	{
 		Name:           "libseccomp2",
 		VerifyCommand:  "libseccomp2",
 		PackageName:    "libseccomp2",
-		InstallCommand: "apt-get",
-		InstallArgs:    []string{"install", "-y", "libseccomp2"},
+		RPMPackageName: "libseccomp",
 	},
+	{
+		// installed by default but on the releases of debPackageMatrix
+		Name:          "iptables",
+		VerifyCommand: "iptables",
+	},
+}
+
+// downloadImgPkg downloads imgpkg into ImgPkgPath
+func downloadImgPkg() error {
+	resp, err := http.Get(ImgPkgURL)
+	if err != nil {
+		return fmt.Errorf("%w imgpkg: %w", types.ErrDownload, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("%w imgpkg: %s returned %s", types.ErrDownload, ImgPkgURL, resp.Status)
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return utils.Permanent(err)
+		}
+		return err
+	}
+
+	out, err := os.Create(ImgPkgPath)
+	if err != nil {
+		return utils.Permanent(fmt.Errorf("failed to create file: %w", err))
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("%w imgpkg: failed to write file: %w", types.ErrDownload, err)
+	}
+	return nil
+}
+
+// PackageSource is where SetupAgent installs the packages from, the zero value pulls the agent
+// package with imgpkg and installs the required packages from the repositories of the host
+type PackageSource struct {
+	// PackageFile is the agent package on local disk, it is not pulled if set
+	PackageFile string
+	// ArtifactDir is a directory of the package files of the required packages, and of the agent
+	// package if PackageFile is not set, they are installed without reaching the repositories
+	ArtifactDir string
+	// Image is the image the agent package is pulled from, e.g. of an internal registry, with or
+	// without a tag; the image of the distribution of the host on quay.io if empty
+	Image string
+	// Version is the version of the agent package, it replaces the tag of Image; the tag of Image,
+	// else ByohAgentVersion, if empty
+	Version string
+	// Checksum is the SHA256 checksum of the agent package in hex; the checksum file pulled with
+	// the agent package if empty, the agent package on local disk is not checked without it
+	Checksum string
+	// PublicKey is the cosign public key the signature file of the agent package is verified with,
+	// the signature is not verified if empty
+	PublicKey string
+	// SkipVerify installs the agent package without verifying its checksum and its signature
+	SkipVerify bool
+}
+
+// Check fails if the agent package of the source is not on local disk or has no image to pull it
+// from, or is not a package the host can install, so that onboard fails before changing the host
+func (s PackageSource) Check() error {
+	pm, err := HostPackageManager()
+	if err != nil {
+		return err
+	}
+	packagePath, err := s.agentPackagePath(pm)
+	if err != nil {
+		return err
+	}
+	if packagePath == "" {
+		if _, err = s.agentImage(pm); err != nil {
+			return err
+		}
+	}
+	if s.Checksum != "" && !sha256Pattern.MatchString(s.Checksum) {
+		return fmt.Errorf("invalid agent package checksum %q, expected the 64 hex digits of a SHA256 checksum", s.Checksum)
+	}
+	if s.PublicKey != "" {
+		if _, err := os.Stat(s.PublicKey); err != nil {
+			return fmt.Errorf("could not find the public key of the agent package: %w", err)
+		}
+		if _, err := CommandRunner.LookPath("cosign"); err != nil {
+			return fmt.Errorf("cosign is required to verify the signature of the agent package: %w", err)
+		}
+	}
+	return nil
 }
 
-// SetupAgent installs the BYOH agent in the host
-func SetupAgent(byohDirPath string) error {
+// sha256Pattern matches a SHA256 checksum in hex
+var sha256Pattern = regexp.MustCompile(`^[A-Fa-f0-9]{64}$`)
+
+// verifyAgentPackage fails with ErrVerify unless the agent package packagePath matches the checksum
+// of the source, or the checksum file pulled with it, and the signature file next to it. Only the
+// checksum and the public key of the source protect against a compromised registry, the checksum
+// file pulled with the package only against a corrupted pull.
+func (s PackageSource) verifyAgentPackage(packagePath string, pulled bool) error {
+	if s.SkipVerify {
+		utils.LogWarn("Skipping the verification of the agent package %s", packagePath)
+		return nil
+	}
+
+	checksum := s.Checksum
+	if checksum == "" && pulled {
+		// the checksum file is in the format of sha256sum, the checksum then the file name
+		data, err := os.ReadFile(packagePath + ChecksumFileExt)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			// the images of the agent package published before the checksum files have none
+			utils.LogWarn("No checksum was pulled with the agent package %s, its checksum is not verified", packagePath)
+		case err != nil:
+			return fmt.Errorf("%w the agent package %s: %w", types.ErrVerify, packagePath, err)
+		default:
+			if fields := strings.Fields(string(data)); len(fields) > 0 {
+				checksum = fields[0]
+			}
+		}
+	}
+	if checksum != "" {
+		sum, err := fileSHA256(packagePath)
+		if err != nil {
+			return fmt.Errorf("%w the agent package %s: %w", types.ErrVerify, packagePath, err)
+		}
+		if !strings.EqualFold(sum, checksum) {
+			return fmt.Errorf("%w the agent package %s: its SHA256 checksum is %s, expected %s", types.ErrVerify, packagePath, sum, checksum)
+		}
+		utils.LogInfo("Verified the SHA256 checksum of the agent package %s", packagePath)
+	}
+
+	if s.PublicKey != "" {
+		cosignPath, err := CommandRunner.LookPath("cosign")
+		if err != nil {
+			return fmt.Errorf("%w the signature of the agent package %s: %w", types.ErrVerify, packagePath, err)
+		}
+		output, err := CommandRunner.CombinedOutput(context.TODO(), cosignPath, "verify-blob",
+			"--key", s.PublicKey, "--signature", packagePath+SignatureFileExt, packagePath)
+		if err != nil {
+			return fmt.Errorf("%w the signature of the agent package %s: %w\nOutput: %s", types.ErrVerify, packagePath, err, string(output))
+		}
+		utils.LogInfo("Verified the signature of the agent package %s", packagePath)
+	}
+	return nil
+}
+
+// fileSHA256 returns the SHA256 checksum of the file path in hex
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// agentImage returns the image the agent package of the source is pulled from
+func (s PackageSource) agentImage(pm PackageManager) (string, error) {
+	image, filename := pm.AgentPackage()
+	if image == "" && s.Image == "" {
+		return "", fmt.Errorf("%w: no image of the agent package %s is published for the distribution of the host, set the image of one with --agent-package, or its file with --package-file or --artifact-dir", types.ErrUsage, filename)
+	}
+	if s.Image == "" && s.Version == "" {
+		return image, nil
+	}
+	repository, tag := splitImageTag(image)
+	if s.Image != "" {
+		if strings.Contains(s.Image, "@") {
+			if s.Version != "" {
+				return "", fmt.Errorf("the agent package %s is pinned by its digest, it cannot have the version %s", s.Image, s.Version)
+			}
+			return s.Image, nil
+		}
+		repository, tag = splitImageTag(s.Image)
+		if tag == "" {
+			tag = ByohAgentVersion
+		}
+	}
+	if s.Version != "" {
+		if !imageTagPattern.MatchString(s.Version) {
+			return "", fmt.Errorf("invalid agent version %q, it must be the tag of an image", s.Version)
+		}
+		tag = s.Version
+	}
+	return repository + ":" + tag, nil
+}
+
+// imageTagPattern matches the tags of an image
+var imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// splitImageTag splits image into its repository and its tag, empty if it has none. The port of
+// a registry is not a tag.
+func splitImageTag(image string) (string, string) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+// agentPackagePath returns the agent package of the source on local disk, empty if it is pulled
+func (s PackageSource) agentPackagePath(pm PackageManager) (string, error) {
+	_, filename := pm.AgentPackage()
+	packagePath := s.PackageFile
+	if packagePath == "" {
+		if s.ArtifactDir == "" {
+			return "", nil
+		}
+		packagePath = filepath.Join(s.ArtifactDir, filename)
+	}
+	if _, err := os.Stat(packagePath); err != nil {
+		return "", fmt.Errorf("could not find the agent package %s: %w", packagePath, err)
+	}
+	if filepath.Ext(packagePath) != filepath.Ext(filename) {
+		return "", fmt.Errorf("%s is not a %s package the host can install", packagePath, filepath.Ext(filename))
+	}
+	return packagePath, nil
+}
+
+// SetupAgent installs the BYOH agent in the host from source. It adds the removal of the packages
+// and the tools it installs to rollback, so that a failed onboarding purges them again.
+func SetupAgent(byohDirPath string, source PackageSource, rollback *utils.Rollback) error {
 	utils.LogInfo("Setting up BYOH agent")
 
+	pm, err := HostPackageManager()
+	if err != nil {
+		return err
+	}
+	packagePath, err := source.agentPackagePath(pm)
+	if err != nil {
+		return err
+	}
+	if packagePath == "" {
+		// a package without an image fails before the required packages are installed
+		if _, err := source.agentImage(pm); err != nil {
+			return err
+		}
+	}
+
 	// Install all pre-requisite packages first
 	utils.LogInfo("Checking and installing required packages...")
-	if err := ensureRequiredPackages(); err != nil {
+	if source.ArtifactDir != "" {
+		err = installArtifacts(pm, source.ArtifactDir, packagePath, rollback)
+	} else {
+		err = ensureRequiredPackages(pm, packagePath == "", rollback)
+	}
+	if err != nil {
 		// Since all packages are important, return an error here
-		return fmt.Errorf("failed to install required packages: %v", err)
+		return fmt.Errorf("failed to install required packages: %w", err)
 	}
 
-	// Proceed with downloading the agent package
-	utils.LogInfo("Downloading agent package...")
-	packagePath, err := downloadDebianPackage(byohDirPath)
-	if err != nil {
-		return fmt.Errorf("failed to download Debian package: %v", err)
+	// Proceed with downloading the agent package, unless it is on local disk
+	pulled := packagePath == ""
+	if pulled {
+		utils.LogInfo("Downloading agent package...")
+		packagePath, err = downloadAgentPackage(pm, source, byohDirPath)
+		if err != nil {
+			return fmt.Errorf("%w agent package: %w", types.ErrDownload, err)
+		}
+	}
+
+	if err = source.verifyAgentPackage(packagePath, pulled); err != nil {
+		return err
 	}
 
-	// Install the agent package
+	// Install the agent package, a failed install may leave it half installed
 	utils.LogInfo("Installing BYOH agent package...")
-	if err = installDebianPackage(packagePath); err != nil {
-		return fmt.Errorf("failed to install Debian package: %v", err)
+	rollback.Add("purge the agent package "+ByohAgentServiceName, PurgeAgentPackage)
+	if err = installAgentPackage(pm, packagePath, source.ArtifactDir != ""); err != nil {
+		return fmt.Errorf("failed to install agent package: %w", err)
 	}
 
-	utils.LogSuccess("Agent setup completed successfully")
+	utils.LogInfo("Agent setup completed successfully")
 	return nil
 }
 
+// PlanAgentSetup returns the actions SetupAgent would take to install the BYOH agent from source,
+// without taking them. It fails like SetupAgent would if a required package is neither installed
+// nor available in the repositories of the host.
+func PlanAgentSetup(byohDirPath string, source PackageSource) ([]string, error) {
+	pm, err := HostPackageManager()
+	if err != nil {
+		return nil, err
+	}
+	packagePath, err := source.agentPackagePath(pm)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []string
+	if source.ArtifactDir != "" {
+		_, filename := pm.AgentPackage()
+		files, err := filepath.Glob(filepath.Join(source.ArtifactDir, "*"+filepath.Ext(filename)))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range slices.DeleteFunc(files, func(file string) bool { return file == packagePath }) {
+			actions = append(actions, fmt.Sprintf("Install the package file %s", file))
+		}
+	} else {
+		actions = append(actions, "Refresh the package lists of the host")
+		for _, pkg := range requiredPackages {
+			if pkg.PullOnly && packagePath != "" {
+				continue
+			}
+			if pkg.CustomInstaller != nil {
+				if _, err := CommandRunner.LookPath(pkg.VerifyCommand); err != nil {
+					actions = append(actions, fmt.Sprintf("Download %s into %s", pkg.Name, ImgPkgPath))
+				}
+				continue
+			}
+			name := pm.PackageName(pkg)
+			if name == "" || pm.Installed(name) {
+				continue
+			}
+			if !pm.Available(name) {
+				return nil, fmt.Errorf("%w %s: it is not installed and not available in the repositories of the host", types.ErrPackageInstall, name)
+			}
+			actions = append(actions, fmt.Sprintf("Install the package %s", name))
+		}
+	}
+
+	pulled := packagePath == ""
+	if pulled {
+		_, filename := pm.AgentPackage()
+		image, err := source.agentImage(pm)
+		if err != nil {
+			return nil, err
+		}
+		packagePath = filepath.Join(byohDirPath, filename)
+		actions = append(actions, fmt.Sprintf("Pull the agent package %s into %s", image, byohDirPath))
+	}
+	switch {
+	case source.SkipVerify:
+	case source.Checksum != "":
+		actions = append(actions, fmt.Sprintf("Verify the SHA256 checksum %s of the agent package", source.Checksum))
+	case pulled:
+		actions = append(actions, fmt.Sprintf("Verify the SHA256 checksum of the agent package with the %s file pulled with it, if any", ChecksumFileExt))
+	}
+	if source.PublicKey != "" && !source.SkipVerify {
+		actions = append(actions, fmt.Sprintf("Verify the signature of the agent package with cosign and the public key %s", source.PublicKey))
+	}
+	actions = append(actions, fmt.Sprintf("Install the agent package %s, which starts the %s service", packagePath, ByohAgentServiceName))
+	return actions, nil
+}
+
 // PrepareAgentDirectory prepares the BYOH agent directory
 func PrepareAgentDirectory(byohDir string) error {
 	// Create byohDir if it doesn't exist
 	if err := os.MkdirAll(byohDir, DefaultDirPerms); err != nil {
-		return fmt.Errorf("failed to create BYOH directory %s: %v", byohDir, err)
+		return fmt.Errorf("failed to create BYOH directory %s: %w", byohDir, err)
 	}
 	return nil
 }
 
-var ensureRequiredPackages = func() error {
+// WriteTracingEnv writes the collector endpoint and the traceparent the agent continues the
+// onboarding trace with into the tracing file of byohDir, which the agent-after-install script
+// adds to the environment of the agent service. The file is removed if tracing is off.
+func WriteTracingEnv(byohDir, endpoint, traceParent string) error {
+	tracingFile := filepath.Join(byohDir, TracingEnvFilename)
+	if endpoint == "" {
+		if err := os.Remove(tracingFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", tracingFile, err)
+		}
+		return nil
+	}
+	env := fmt.Sprintf("%s=%s\n%s=%s\n", utils.OTLPEndpointEnv, endpoint, utils.TraceParentEnv, traceParent)
+	if err := os.WriteFile(tracingFile, []byte(env), DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tracingFile, err)
+	}
+	return nil
+}
 
-	// do apt-get update before proceeding with installing required packages
-	utils.LogSuccess("Updating apt packages...Might take few seconds")
+// AgentLabels returns the labels of the ByoHost of the agent as the comma-separated key=value pairs
+// of its --label flag: the region label, then labels in their order. A label of labels must be a
+// valid Kubernetes label and must not set the region.
+func AgentLabels(region string, labels []string) (string, error) {
+	pairs := []string{PcdKaapiRegionKey + "=" + region}
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		if !found {
+			return "", fmt.Errorf("invalid label %q, expected key=value", label)
+		}
+		if key == PcdKaapiRegionKey {
+			return "", fmt.Errorf("invalid label %q, the region is set with --region", label)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return "", fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return "", fmt.Errorf("invalid label value %q of %s: %s", value, key, strings.Join(errs, "; "))
+		}
+		pairs = append(pairs, label)
+	}
+	return strings.Join(pairs, ","), nil
+}
 
-	if ok, err := isAptUnlocked(); !ok {
+// WriteAgentTaints writes the taints of the node of the host, each key=value:effect or key:effect,
+// into the taints file of byohDir, which the agent-after-install script passes to the --taint flag
+// of the agent. The file is removed if there are none.
+func WriteAgentTaints(byohDir string, taints []string) error {
+	taintsFile := filepath.Join(byohDir, TaintsFilename)
+	spec, err := AgentTaints(taints)
+	if err != nil {
 		return err
 	}
+	if spec == "" {
+		if err := os.Remove(taintsFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", taintsFile, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(taintsFile, []byte(spec), DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to write %s: %w", taintsFile, err)
+	}
+	return nil
+}
 
-	// do apt-get update
-	if _, err := RunWithStdout("apt-get", "update"); err != nil {
-		return fmt.Errorf("failed to update apt packages: %v", err)
+// AgentTaints returns the taints as the comma-separated taints of the --taint flag of the agent,
+// it fails if a taint is not a valid taint of a Node
+func AgentTaints(taints []string) (string, error) {
+	parsed, err := nodetaint.Parse(strings.Join(taints, ","))
+	if err != nil {
+		return "", err
 	}
+	return nodetaint.Format(parsed), nil
+}
 
-	utils.LogInfo("Checking for required packages...")
+// addPurge adds the purge of the package name to rollback
+func addPurge(rollback *utils.Rollback, pm PackageManager, name string) {
+	rollback.Add("purge the package "+name, func() error {
+		if output, err := pm.Purge(name); err != nil {
+			return fmt.Errorf("%w\nOutput: %s", err, string(output))
+		}
+		return nil
+	})
+}
 
-	// Fix any broken package state first
-	output, err := exec.Command("apt-get", "--fix-broken", "install", "-y").CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to fix broken packages: %v\nOutput: %s", err, string(output))
+// ensureRequiredPackages installs the missing required packages from the repositories of the host,
+// pull installs the packages needed to pull the agent package too. It adds their removal to rollback.
+func ensureRequiredPackages(pm PackageManager, pull bool, rollback *utils.Rollback) error {
+	if err := pm.Refresh(); err != nil {
+		return err
 	}
 
+	utils.LogInfo("Checking for required packages...")
+
 	for _, pkg := range requiredPackages {
+		if pkg.PullOnly && !pull {
+			continue
+		}
 		if pkg.CustomInstaller != nil {
-			if _, err := exec.LookPath(pkg.VerifyCommand); err == nil {
+			if _, err := CommandRunner.LookPath(pkg.VerifyCommand); err == nil {
 				continue
 			}
 			utils.LogInfo("Installing %s...", pkg.Name)
 			if err := pkg.CustomInstaller(); err != nil {
-				return fmt.Errorf("failed to install %s: %v", pkg.Name, err)
+				return fmt.Errorf("%w %s: %w", types.ErrPackageInstall, pkg.Name, err)
+			}
+			if pkg.InstallPath != "" {
+				rollback.Add("remove "+pkg.InstallPath, func() error { return os.Remove(pkg.InstallPath) })
 			}
 			continue
 		}
 
-		if isPackageInstalled(pkg.PackageName) {
+		name := pm.PackageName(pkg)
+		if name == "" || pm.Installed(name) {
 			continue
 		}
 
 		utils.LogInfo("Installing %s...", pkg.Name)
-		output, err := exec.Command(pkg.InstallCommand, pkg.InstallArgs...).CombinedOutput()
+		output, err := pm.Install(name)
 		if err != nil {
-			return fmt.Errorf("failed to install %s: %v\nOutput: %s", pkg.Name, err, string(output))
+			return fmt.Errorf("%w %s: %w\nOutput: %s", types.ErrPackageInstall, pkg.Name, err, string(output))
 		}
-		utils.LogSuccess("Installed %s successfully", pkg.Name)
+		addPurge(rollback, pm, name)
+		utils.RecordStep(utils.HostChanged, "Installed package %s", name)
 	}
 
-	utils.LogSuccess("All required packages installed successfully")
+	utils.LogInfo("All required packages installed successfully")
 	return nil
 }
 
-var downloadDebianPackage = func(tempDir string) (string, error) {
-	utils.LogInfo("Downloading BYOH agent Debian package from %s", ByohAgentDebPackageURL)
+// installArtifacts installs the package files of dir, but the agent package agentPackagePath,
+// without reaching the repositories of the host, then checks the required packages are installed.
+// It adds the removal of the required packages it installs to rollback.
+func installArtifacts(pm PackageManager, dir, agentPackagePath string, rollback *utils.Rollback) error {
+	_, filename := pm.AgentPackage()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+filepath.Ext(filename)))
+	if err != nil {
+		return err
+	}
+	files = slices.DeleteFunc(files, func(file string) bool { return file == agentPackagePath })
 
-	imgpkgPath, _ := exec.LookPath("imgpkg")
+	var missing []string
+	for _, pkg := range requiredPackages {
+		if name := pm.PackageName(pkg); name != "" && !pkg.PullOnly && !pm.Installed(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(files) > 0 {
+		utils.LogInfo("Installing %d packages from %s...", len(files), dir)
+		output, err := pm.InstallFiles(true, files...)
+		for _, name := range missing {
+			// the files installed before a failure are purged too
+			if pm.Installed(name) {
+				addPurge(rollback, pm, name)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w from %s: %w\nOutput: %s", types.ErrPackageInstall, dir, err, string(output))
+		}
+		utils.RecordStep(utils.HostChanged, "Installed the %d packages of %s", len(files), dir)
+	}
 
-	// Use a buffer to capture the command output
-	var outputBuffer bytes.Buffer
-	pullCmd := exec.Command(imgpkgPath, "pull", "-i", ByohAgentDebPackageURL, "-o", tempDir)
-	pullCmd.Stdout = &outputBuffer
-	pullCmd.Stderr = &outputBuffer
+	for _, pkg := range requiredPackages {
+		if pkg.PullOnly {
+			continue
+		}
+		if name := pm.PackageName(pkg); name != "" && !pm.Installed(name) {
+			return fmt.Errorf("%w %s: it is not installed and its package is not in %s", types.ErrPackageInstall, name, dir)
+		}
+	}
+	return nil
+}
 
-	if err := pullCmd.Run(); err != nil {
-		output := outputBuffer.String()
-		return "", fmt.Errorf("failed to pull package: %v\nOutput: %s", err, output)
+func downloadAgentPackage(pm PackageManager, source PackageSource, tempDir string) (string, error) {
+	_, filename := pm.AgentPackage()
+	image, err := source.agentImage(pm)
+	if err != nil {
+		return "", err
 	}
+	utils.LogInfo("Downloading BYOH agent package from %s", image)
+
+	imgpkgPath, _ := CommandRunner.LookPath("imgpkg")
 
-	// Check if we've downloaded the Debian package file
-	debFilePath := filepath.Join(tempDir, ByohAgentDebPackageFilename)
-	if _, err := os.Stat(debFilePath); err != nil {
-		return "", fmt.Errorf("could not find downloaded Debian package in %s", tempDir)
+	err = utils.Retry(context.TODO(), utils.NetworkBackoff, "pull of "+image, func() error {
+		output, err := CommandRunner.CombinedOutput(context.TODO(), imgpkgPath, "pull", "-i", image, "-o", tempDir)
+		if err != nil {
+			return fmt.Errorf("failed to pull package: %w\nOutput: %s", err, string(output))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
-	utils.LogSuccess("Downloaded package to %s", debFilePath)
-	return debFilePath, nil
-}
+	// Check if we've downloaded the package file
+	packagePath := filepath.Join(tempDir, filename)
+	if _, err := os.Stat(packagePath); err != nil {
+		return "", fmt.Errorf("could not find downloaded package %s in %s", filename, tempDir)
+	}
 
-var installDebianPackage = func(debFilePath string) error {
-	dpkgPath, _ := exec.LookPath("dpkg")
+	utils.LogInfo("Downloaded package to %s", packagePath)
+	return packagePath, nil
+}
 
+func installAgentPackage(pm PackageManager, packagePath string, offline bool) error {
 	// Install the package
-	utils.LogInfo("Installing package %s", debFilePath)
+	utils.LogInfo("Installing package %s", packagePath)
 
-	// First, try a clean installation
-	cmd := exec.Command(dpkgPath, "-i", debFilePath)
-	output, err := cmd.CombinedOutput()
+	output, err := pm.InstallFiles(offline, packagePath)
 	outputStr := string(output)
 
 	if err != nil {
-		return fmt.Errorf("failed to install package: %v\nOutput: %s", err, outputStr)
+		return fmt.Errorf("%w: %w\nOutput: %s", types.ErrPackageInstall, err, outputStr)
 	}
 
-	utils.LogSuccess("Successfully installed Debian package %s", debFilePath)
+	utils.RecordStep(utils.HostChanged, "Installed the %s agent package from %s", ByohAgentServiceName, packagePath)
 	return nil
 }
 
-var PurgeDebianPackage = func() error {
-	dpkgPath, _ := exec.LookPath("dpkg")
+// PurgeAgentPackage purges the BYOH agent package from the host
+func PurgeAgentPackage() error {
+	pm, err := HostPackageManager()
+	if err != nil {
+		return err
+	}
 
 	// Purge the package
-	cmd := exec.Command(dpkgPath, "--purge", ByohAgentServiceName)
-	output, err := cmd.CombinedOutput()
+	output, err := pm.Purge(ByohAgentServiceName)
 	outputStr := string(output)
 
 	if err != nil {
-		return fmt.Errorf("failed to purge package: %v\nOutput: %s", err, outputStr)
+		return fmt.Errorf("failed to purge package: %w\nOutput: %s", err, outputStr)
 	}
 
-	utils.LogSuccess("Successfully purged Debian package pf9-byohost-agent")
+	utils.RecordStep(utils.HostChanged, "Purged the %s agent package", ByohAgentServiceName)
 	return nil
 }
 
 // RunWithStdout runs a command locally returning stdout and err
 func RunWithStdout(name string, args ...string) (string, error) {
 
-	cmd := execCommand(name, args...)
-	byt, err := cmd.Output()
+	byt, err := CommandRunner.Output(context.TODO(), name, args...)
 	stderr := ""
 	if exitError, ok := err.(*exec.ExitError); ok {
 		stderr = string(exitError.Stderr)
@@ -272,6 +709,6 @@ func isAptUnlocked() (bool, error) {
 		// lsof exits with code 1 if the file is not locked.
 		return true, nil
 	} else {
-		return false, fmt.Errorf("apt is locked %v", err)
+		return false, fmt.Errorf("apt is locked %w", err)
 	}
 }
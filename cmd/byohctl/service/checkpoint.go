@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+)
+
+// OnboardSettings identify an onboarding, only an onboarding of the same settings resumes from the
+// checkpoint of an interrupted one
+type OnboardSettings struct {
+	FQDN      string `json:"fqdn"`
+	Domain    string `json:"domain"`
+	Tenant    string `json:"tenant"`
+	Namespace string `json:"namespace,omitempty"`
+	Region    string `json:"region"`
+	HostName  string `json:"hostName,omitempty"`
+}
+
+// OnboardCheckpoint is the progress of an onboarding, saved into the checkpoint file of the BYOH
+// directory after each step, so that an onboarding that died midway, e.g. killed or with the host
+// rebooted, resumes from its last completed step when it is run again
+type OnboardCheckpoint struct {
+	Settings OnboardSettings `json:"settings"`
+	// Steps are the completed steps of the onboarding
+	Steps []string `json:"steps"`
+
+	path string
+}
+
+// LoadCheckpoint returns the checkpoint of the interrupted onboarding of settings in byohDir, a new
+// checkpoint if there is none or it is the checkpoint of other settings
+func LoadCheckpoint(byohDir string, settings OnboardSettings) (*OnboardCheckpoint, error) {
+	checkpoint := &OnboardCheckpoint{Settings: settings, path: filepath.Join(byohDir, CheckpointFilename)}
+	data, err := os.ReadFile(checkpoint.path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the onboarding checkpoint: %w", err)
+	}
+	var saved OnboardCheckpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		utils.LogWarn("Ignoring the invalid onboarding checkpoint %s: %v", checkpoint.path, err)
+		return checkpoint, nil
+	}
+	if saved.Settings != settings {
+		utils.LogDebug("Ignoring the onboarding checkpoint %s of other settings %+v", checkpoint.path, saved.Settings)
+		return checkpoint, nil
+	}
+	checkpoint.Steps = saved.Steps
+	return checkpoint, nil
+}
+
+// Resumed returns whether the checkpoint is the one of an interrupted onboarding
+func (c *OnboardCheckpoint) Resumed() bool {
+	return len(c.Steps) > 0
+}
+
+// Done returns whether the step was completed
+func (c *OnboardCheckpoint) Done(step string) bool {
+	return slices.Contains(c.Steps, step)
+}
+
+// Complete records the completion of step into the checkpoint file
+func (c *OnboardCheckpoint) Complete(step string) error {
+	if c.Done(step) {
+		return nil
+	}
+	c.Steps = append(c.Steps, step)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	// written to a temporary file then renamed, an onboarding dying meanwhile leaves the previous checkpoint
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to write the onboarding checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write the onboarding checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the checkpoint file once the onboarding is complete
+func (c *OnboardCheckpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the onboarding checkpoint: %w", err)
+	}
+	return nil
+}
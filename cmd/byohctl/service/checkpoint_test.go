@@ -0,0 +1,61 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOnboardCheckpoint(t *testing.T) {
+	byohDir := t.TempDir()
+	settings := OnboardSettings{FQDN: "pcd.example.com", Domain: "default", Tenant: "service", Region: "region-one"}
+
+	checkpoint, err := LoadCheckpoint(byohDir, settings)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned error: %v", err)
+	}
+	if checkpoint.Resumed() {
+		t.Errorf("Expected a new checkpoint, got steps %v", checkpoint.Steps)
+	}
+	for _, step := range []string{"save-kubeconfig", "write-agent-files", "save-kubeconfig"} {
+		if err := checkpoint.Complete(step); err != nil {
+			t.Fatalf("Complete returned error: %v", err)
+		}
+	}
+
+	// the onboarding of the same settings resumes after the completed steps
+	resumed, err := LoadCheckpoint(byohDir, settings)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint returned error: %v", err)
+	}
+	if !resumed.Resumed() || !reflect.DeepEqual(resumed.Steps, []string{"save-kubeconfig", "write-agent-files"}) {
+		t.Errorf("Expected the completed steps, got %v", resumed.Steps)
+	}
+	if !resumed.Done("write-agent-files") || resumed.Done("setup-agent") {
+		t.Errorf("Expected only the completed steps to be done, got %v", resumed.Steps)
+	}
+
+	// an onboarding of other settings starts over
+	other := settings
+	other.Region = "region-two"
+	if fresh, err := LoadCheckpoint(byohDir, other); err != nil || fresh.Resumed() {
+		t.Errorf("Expected a new checkpoint for other settings, got %v, %v", fresh, err)
+	}
+
+	// an invalid checkpoint is ignored
+	checkpointFile := filepath.Join(byohDir, CheckpointFilename)
+	if err := os.WriteFile(checkpointFile, []byte("{"), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", checkpointFile, err)
+	}
+	if fresh, err := LoadCheckpoint(byohDir, settings); err != nil || fresh.Resumed() {
+		t.Errorf("Expected a new checkpoint for an invalid one, got %v, %v", fresh, err)
+	}
+
+	if err := resumed.Remove(); err != nil {
+		t.Fatalf("Remove returned error: %v", err)
+	}
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the checkpoint to be removed, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
 )
 
 const (
@@ -12,16 +14,42 @@ const (
 	// DefaultFilePerms is the default file permission
 	DefaultFilePerms = 0644
 
+	// ByohAgentVersion is the version of the agent package pulled unless another one is set
+	ByohAgentVersion = "0.1.441"
+	// ByohAgentDebPackageRepository is the repository of the images of the agent package
+	ByohAgentDebPackageRepository = "quay.io/platform9/byoh-agent-deb"
 	// ByohAgentDebPackageURL is the URL to download the agent package
-	ByohAgentDebPackageURL = "quay.io/platform9/byoh-agent-deb:0.1.441"
+	ByohAgentDebPackageURL = ByohAgentDebPackageRepository + ":" + ByohAgentVersion
 	// ByohAgentDebPackageFilename is the filename of the agent package
 	ByohAgentDebPackageFilename = "pf9-byohost-agent.deb"
+	// ByohAgentRPMPackageFilename is the filename of the agent package of the RHEL family, no image
+	// of it is published yet
+	ByohAgentRPMPackageFilename = "pf9-byohost-agent.rpm"
+	// ChecksumFileExt is the extension of the SHA256 checksum file pulled with the agent package
+	ChecksumFileExt = ".sha256"
+	// SignatureFileExt is the extension of the cosign signature file of the agent package
+	SignatureFileExt = ".sig"
 	// ByohAgentServiceName is the name of the agent service
 	ByohAgentServiceName = "pf9-byohost-agent"
 	// ByohAgentLogPath is the path to the BYOH agent log file
 	ByohAgentLogPath = "/var/log/pf9/byoh/byoh-agent.log"
 	// ByohConfigDir is the directory for BYOH configuration
 	ByohConfigDir = ".byoh"
+	// TracingEnvFilename is the file of the BYOH configuration directory with the tracing
+	// environment of the agent service
+	TracingEnvFilename = "tracing"
+	// TaintsFilename is the file of the BYOH configuration directory with the taints of the node
+	// of the host, passed to the --taint flag of the agent
+	TaintsFilename = "taints"
+	// CheckpointFilename is the file of the BYOH configuration directory with the completed steps
+	// of an onboarding, an interrupted onboarding resumes from them
+	CheckpointFilename = "onboard-checkpoint.json"
+	// HostNameFilename is the file of the BYOH configuration directory with the --host-name of
+	// onboard, the name of the ByoHost of the host
+	HostNameFilename = "host-name"
+	// RegionFilename is the file of the BYOH configuration directory with the region and the labels
+	// of the ByoHost of the host, passed to the --label flag of the agent
+	RegionFilename = "region"
 
 	// ImgPkgVersion is the version of imgpkg to install
 	ImgPkgVersion = "v0.45.0"
@@ -32,12 +60,17 @@ const (
 
 	// Timeout for waiting for machineRef to be unset
 	WaitForMachineRefToBeUnsetTimeout = 5 * time.Minute
+	// HostLockTimeout is how long byohctl waits for another operation holding the host lock
+	HostLockTimeout = 10 * time.Minute
 
 	// Systemctl constants
 	Systemctl = "systemctl"
 
 	// pcd-kaapi region key
 	PcdKaapiRegionKey = "pcd-kaapi.pf9.io/region"
+	// PcdKaapiDomainKey and PcdKaapiTenantKey label the namespace of a tenant with its domain and tenant
+	PcdKaapiDomainKey = "pcd-kaapi.pf9.io/domain"
+	PcdKaapiTenantKey = "pcd-kaapi.pf9.io/tenant"
 )
 
 var (
@@ -46,6 +79,20 @@ var (
 
 	KubeconfigFilePath = filepath.Join(ByohDir, "config")
 
+	// WaitTimeout is how long decommission waits for the machine of the host to release it, and
+	// upgrade for a heartbeat of the upgraded agent, set by the --wait-timeout flag
+	WaitTimeout = WaitForMachineRefToBeUnsetTimeout
+
+	// HostLockPath is the lock of the host shared with the agent install and uninstall scripts
+	HostLockPath = hostlock.DefaultPath
+
+	// OSReleasePath is the os-release the distribution of the host is read from
+	OSReleasePath = "/etc/os-release"
+
+	// AgentServiceEnvPath is the environment file of the agent service the agent-after-install
+	// script writes from the BYOH configuration directory
+	AgentServiceEnvPath = "/etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf"
+
 	SystemctlServiceExists = []string{"list-unit-files", ByohAgentServiceName + ".service"}
 )
 
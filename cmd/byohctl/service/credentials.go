@@ -0,0 +1,149 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// credentialsKey is the description of the key of the credentials in the keyring
+	credentialsKey = "byohctl:credentials"
+	// keyPerm grants every permission on the key to its possessor and to its user, so that the
+	// sessions of the user that do not possess the keyring can read and replace it too; other
+	// users cannot even see it
+	keyPerm = 0x3f3f0000
+)
+
+// Credentials are the credentials of a user of the management plane stored by byohctl login, so
+// later commands can authenticate again without the password in a file or on the command line
+type Credentials struct {
+	URL         string `json:"url"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	ClientToken string `json:"clientToken"`
+	Domain      string `json:"domain"`
+	Tenant      string `json:"tenant"`
+	CACert      string `json:"caCert,omitempty"`
+}
+
+// Keyring stores secrets in the keyring of the OS, tests replace it with a fake
+type Keyring interface {
+	// Get returns the secret of key, types.ErrNoCredentials if the keyring has none
+	Get(key string) ([]byte, error)
+	// Set stores secret as the secret of key, replacing the previous one
+	Set(key string, secret []byte) error
+	// Delete removes the secret of key, types.ErrNoCredentials if the keyring has none
+	Delete(key string) error
+}
+
+// CredentialKeyring stores the credentials of byohctl login
+var CredentialKeyring Keyring = kernelKeyring{}
+
+// SaveCredentials stores creds in the keyring, replacing the credentials stored before
+func SaveCredentials(creds Credentials) error {
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	if err := CredentialKeyring.Set(credentialsKey, data); err != nil {
+		return fmt.Errorf("failed to store the credentials in the keyring: %w", err)
+	}
+	return nil
+}
+
+// LoadCredentials returns the credentials stored by SaveCredentials, types.ErrNoCredentials if
+// there are none
+func LoadCredentials() (*Credentials, error) {
+	data, err := CredentialKeyring.Get(credentialsKey)
+	if err != nil {
+		return nil, err
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse the credentials of the keyring: %w", err)
+	}
+	return &creds, nil
+}
+
+// DeleteCredentials removes the credentials stored by SaveCredentials, types.ErrNoCredentials if
+// there are none
+func DeleteCredentials() error {
+	return CredentialKeyring.Delete(credentialsKey)
+}
+
+// kernelKeyring stores the secrets in the persistent keyring of the user in the Linux kernel. The
+// secrets never reach the disk; the keyring outlives the sessions of the user but not a reboot,
+// and the kernel drops it after it is unused for /proc/sys/kernel/keys/persistent_keyring_expiry.
+type kernelKeyring struct{}
+
+// keyring returns the persistent keyring of the user, linked into the user keyring so that the
+// processes of the user possess it
+func (kernelKeyring) keyring() (int, error) {
+	ring, err := unix.KeyctlInt(unix.KEYCTL_GET_PERSISTENT, -1, unix.KEY_SPEC_USER_KEYRING, 0, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the persistent keyring: %w", err)
+	}
+	return ring, nil
+}
+
+// search returns the key of the secret of key in ring, types.ErrNoCredentials if there is none
+func (kernelKeyring) search(ring int, key string) (int, error) {
+	id, err := unix.KeyctlSearch(ring, "user", key, 0)
+	if errors.Is(err, unix.ENOKEY) || errors.Is(err, unix.EKEYEXPIRED) || errors.Is(err, unix.EKEYREVOKED) {
+		return 0, types.ErrNoCredentials
+	}
+	return id, err
+}
+
+func (k kernelKeyring) Get(key string) ([]byte, error) {
+	ring, err := k.keyring()
+	if err != nil {
+		return nil, err
+	}
+	id, err := k.search(ring, key)
+	if err != nil {
+		return nil, err
+	}
+	// a first read returns the size of the secret
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from the keyring: %w", key, err)
+	}
+	secret := make([]byte, size)
+	if _, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, secret, 0); err != nil {
+		return nil, fmt.Errorf("failed to read %s from the keyring: %w", key, err)
+	}
+	return secret, nil
+}
+
+func (k kernelKeyring) Set(key string, secret []byte) error {
+	ring, err := k.keyring()
+	if err != nil {
+		return err
+	}
+	// adding a key of the same description updates it
+	id, err := unix.AddKey("user", key, secret, ring)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to the keyring: %w", key, err)
+	}
+	return unix.KeyctlSetperm(id, keyPerm)
+}
+
+func (k kernelKeyring) Delete(key string) error {
+	ring, err := k.keyring()
+	if err != nil {
+		return err
+	}
+	id, err := k.search(ring, key)
+	if err != nil {
+		return err
+	}
+	if _, err := unix.KeyctlInt(unix.KEYCTL_UNLINK, id, ring, 0, 0); err != nil {
+		return fmt.Errorf("failed to remove %s from the keyring: %w", key, err)
+	}
+	return nil
+}
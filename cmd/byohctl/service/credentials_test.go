@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/internal/fakeplane"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+)
+
+func TestCredentials(t *testing.T) {
+	origKeyring := CredentialKeyring
+	CredentialKeyring = fakeplane.NewKeyring()
+	t.Cleanup(func() { CredentialKeyring = origKeyring })
+
+	if _, err := LoadCredentials(); !errors.Is(err, types.ErrNoCredentials) {
+		t.Fatalf("Expected no credentials, got: %v", err)
+	}
+
+	creds := Credentials{URL: "pf9.example.com", Username: "admin@example.com", Password: "secret",
+		ClientToken: "client-token", Domain: "default", Tenant: "service"}
+	if err := SaveCredentials(creds); err != nil {
+		t.Fatalf("Failed to save the credentials: %v", err)
+	}
+	loaded, err := LoadCredentials()
+	if err != nil {
+		t.Fatalf("Failed to load the credentials: %v", err)
+	}
+	if *loaded != creds {
+		t.Errorf("Expected credentials %+v, got %+v", creds, *loaded)
+	}
+
+	if err := DeleteCredentials(); err != nil {
+		t.Fatalf("Failed to delete the credentials: %v", err)
+	}
+	if err := DeleteCredentials(); !errors.Is(err, types.ErrNoCredentials) {
+		t.Errorf("Expected no credentials to delete, got: %v", err)
+	}
+}
+
+func TestKernelKeyring(t *testing.T) {
+	keyring := kernelKeyring{}
+	if _, err := keyring.keyring(); err != nil {
+		t.Skipf("The kernel keyring is not available: %v", err)
+	}
+	const key = "byohctl:test-kernel-keyring"
+	t.Cleanup(func() { _ = keyring.Delete(key) })
+
+	for _, secret := range []string{"first secret", "second, longer secret"} {
+		if err := keyring.Set(key, []byte(secret)); err != nil {
+			t.Fatalf("Failed to set the secret: %v", err)
+		}
+		got, err := keyring.Get(key)
+		if err != nil {
+			t.Fatalf("Failed to get the secret: %v", err)
+		}
+		if string(got) != secret {
+			t.Errorf("Expected secret '%s', got '%s'", secret, got)
+		}
+	}
+
+	if err := keyring.Delete(key); err != nil {
+		t.Fatalf("Failed to delete the secret: %v", err)
+	}
+	if _, err := keyring.Get(key); !errors.Is(err, types.ErrNoCredentials) {
+		t.Errorf("Expected no secret after the delete, got: %v", err)
+	}
+}
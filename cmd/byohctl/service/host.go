@@ -0,0 +1,181 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer"
+)
+
+const (
+	// EphemeralStoragePath is the filesystem of the host the kubelet and the container runtime store their data on
+	EphemeralStoragePath = "/var/lib"
+	// MinKubeadmCPU is the minimum number of CPUs kubeadm requires
+	MinKubeadmCPU = 2
+	// MinKubeadmMemoryMiB is the minimum amount of memory, in MiB, kubeadm requires
+	MinKubeadmMemoryMiB = 1700
+	// MinKernelVersion is the oldest kernel release the supported Kubernetes versions are supported on
+	MinKernelVersion = "4.19"
+	// MinRHELKernelVersion is the oldest kernel release of the hosts of the RHEL family, the 4.18
+	// kernel of RHEL 8 backports what Kubernetes requires of 4.19
+	MinRHELKernelVersion = "4.18"
+)
+
+// HostFacts are the properties of the host the requirements of a cluster are checked against
+type HostFacts struct {
+	// OSImage is the PRETTY_NAME of the os-release of the host, e.g. "Ubuntu 22.04.4 LTS"
+	OSImage string
+	// OSID is the ID of the os-release of the host, e.g. "ubuntu"
+	OSID string
+	// OSVersionID is the VERSION_ID of the os-release of the host, e.g. "22.04"
+	OSVersionID string
+	// Architecture is the architecture of the host, as named by GOARCH
+	Architecture string
+	// KernelVersion is the kernel release of the host, e.g. 5.15.0-91-generic
+	KernelVersion string
+	// CPUs is the number of CPUs of the host
+	CPUs int
+	// MemoryMiB is the total memory of the host in MiB
+	MemoryMiB int64
+	// DiskGiB is the size of the EphemeralStoragePath filesystem in GiB
+	DiskGiB int64
+}
+
+// HostRequirements are the requirements of a cluster on the hosts of its machines
+type HostRequirements struct {
+	// Cluster is the name of the cluster
+	Cluster string
+	// K8sVersion is the Kubernetes version of the cluster, e.g. v1.31.0
+	K8sVersion string
+	// MinCPU is the largest minimum number of CPUs of the machine templates of the cluster
+	MinCPU int32
+	// MinMemoryMiB is the largest minimum amount of memory of the machine templates of the cluster
+	MinMemoryMiB int64
+	// MinDiskGiB is the largest minimum amount of ephemeral storage of the machine templates of the cluster
+	MinDiskGiB int64
+	// BundlePaths are the paths of the pre-seeded bundles the installer configs of the cluster install from
+	BundlePaths []string
+}
+
+// GetHostFacts gathers the facts of the host
+func GetHostFacts() (*HostFacts, error) {
+	facts, err := readHostFacts(os.ReadFile)
+	if err != nil {
+		return nil, err
+	}
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(EphemeralStoragePath, &stat); err != nil {
+		return nil, fmt.Errorf("failed to get the size of %s: %w", EphemeralStoragePath, err)
+	}
+	facts.DiskGiB = int64(stat.Blocks) * int64(stat.Bsize) >> 30 //nolint: gosec, unconvert
+	return facts, nil
+}
+
+// readHostFacts reads the facts of the host, but the disk size, with readFile
+func readHostFacts(readFile func(string) ([]byte, error)) (*HostFacts, error) {
+	facts := &HostFacts{Architecture: runtime.GOARCH, CPUs: runtime.NumCPU()}
+
+	osRelease, err := readFile("/etc/os-release")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the os-release of the host: %w", err)
+	}
+	match := regexp.MustCompile(`(?m)^PRETTY_NAME=(.*)$`).FindSubmatch(osRelease)
+	if match == nil {
+		return nil, errors.New("PRETTY_NAME not found in the os-release of the host")
+	}
+	facts.OSImage = strings.Trim(string(match[1]), `"`)
+	facts.OSID = osReleaseValue(osRelease, "ID")
+	facts.OSVersionID = osReleaseValue(osRelease, "VERSION_ID")
+
+	kernel, err := readFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the kernel release of the host: %w", err)
+	}
+	facts.KernelVersion = strings.TrimSpace(string(kernel))
+
+	meminfo, err := readFile("/proc/meminfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the memory of the host: %w", err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(meminfo)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MemTotal value %q: %w", fields[1], err)
+		}
+		facts.MemoryMiB = kb >> 10
+	}
+	if facts.MemoryMiB == 0 {
+		return nil, errors.New("MemTotal not found in /proc/meminfo")
+	}
+	return facts, nil
+}
+
+// ValidateHost checks the host of facts against the requirements of a cluster, it returns
+// the requirements the host does not satisfy
+func ValidateHost(facts *HostFacts, requirements *HostRequirements) []error {
+	var errs []error
+	if err := installer.ValidateK8sVersion(facts.OSImage, facts.Architecture, requirements.K8sVersion); err != nil {
+		errs = append(errs, fmt.Errorf("os %s on %s: %w", facts.OSImage, facts.Architecture, err))
+	}
+	if minKernel := minKernelVersion(facts); !kernelAtLeast(facts.KernelVersion, minKernel) {
+		errs = append(errs, fmt.Errorf("kernel %s is older than %s", facts.KernelVersion, minKernel))
+	}
+	minCPU := max(int(requirements.MinCPU), MinKubeadmCPU)
+	if facts.CPUs < minCPU {
+		errs = append(errs, fmt.Errorf("%d CPUs, at least %d are required", facts.CPUs, minCPU))
+	}
+	minMemoryMiB := max(requirements.MinMemoryMiB, MinKubeadmMemoryMiB)
+	if facts.MemoryMiB < minMemoryMiB {
+		errs = append(errs, fmt.Errorf("%d MiB of memory, at least %d MiB are required", facts.MemoryMiB, minMemoryMiB))
+	}
+	if facts.DiskGiB < requirements.MinDiskGiB {
+		errs = append(errs, fmt.Errorf("%d GiB of disk on %s, at least %d GiB are required", facts.DiskGiB, EphemeralStoragePath, requirements.MinDiskGiB))
+	}
+	for _, bundlePath := range requirements.BundlePaths {
+		if _, err := os.Stat(bundlePath); err != nil {
+			errs = append(errs, fmt.Errorf("bundle %s is not pre-seeded: %w", bundlePath, err))
+		}
+	}
+	return errs
+}
+
+// minKernelVersion returns the oldest kernel release supported on the distribution of the host of facts
+func minKernelVersion(facts *HostFacts) string {
+	if family, _ := osFamilyOf(facts.OSID, facts.OSVersionID); family == RHELFamily {
+		return MinRHELKernelVersion
+	}
+	return MinKernelVersion
+}
+
+// kernelAtLeast reports whether the kernel release is at least the major.minor version minVersion
+func kernelAtLeast(release, minVersion string) bool {
+	major, minor, ok := parseKernelVersion(release)
+	if !ok {
+		return false
+	}
+	minMajor, minMinor, _ := parseKernelVersion(minVersion)
+	return major > minMajor || major == minMajor && minor >= minMinor
+}
+
+// parseKernelVersion returns the major and minor versions of a kernel release, e.g. 5 and 15 of 5.15.0-91-generic
+func parseKernelVersion(release string) (int, int, bool) {
+	match := regexp.MustCompile(`^(\d+)\.(\d+)`).FindStringSubmatch(release)
+	if match == nil {
+		return 0, 0, false
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, true
+}
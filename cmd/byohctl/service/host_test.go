@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHostFiles returns a readFile reading the files of a fake host
+func fakeHostFiles(files map[string]string) func(string) ([]byte, error) {
+	return func(name string) ([]byte, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("open %s: %w", name, os.ErrNotExist)
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestReadHostFacts(t *testing.T) {
+	facts, err := readHostFacts(fakeHostFiles(map[string]string{
+		"/etc/os-release":            "NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\nID=ubuntu\nVERSION_ID=\"22.04\"\n",
+		"/proc/sys/kernel/osrelease": "5.15.0-91-generic\n",
+		"/proc/meminfo":              "MemTotal:        8152656 kB\nMemFree:          612340 kB\n",
+	}))
+	if err != nil {
+		t.Fatalf("readHostFacts returned error: %v", err)
+	}
+	if facts.OSImage != "Ubuntu 22.04.4 LTS" {
+		t.Errorf("Expected os image %q, got %q", "Ubuntu 22.04.4 LTS", facts.OSImage)
+	}
+	if facts.OSID != "ubuntu" || facts.OSVersionID != "22.04" {
+		t.Errorf("Expected os ubuntu 22.04, got %s %s", facts.OSID, facts.OSVersionID)
+	}
+	if facts.KernelVersion != "5.15.0-91-generic" {
+		t.Errorf("Expected kernel %q, got %q", "5.15.0-91-generic", facts.KernelVersion)
+	}
+	if facts.MemoryMiB != 7961 {
+		t.Errorf("Expected 7961 MiB of memory, got %d", facts.MemoryMiB)
+	}
+
+	if _, err = readHostFacts(fakeHostFiles(map[string]string{})); err == nil {
+		t.Error("Expected an error for a host without os-release")
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar")
+	if err := os.WriteFile(bundlePath, []byte("bundle"), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write the bundle: %v", err)
+	}
+	facts := &HostFacts{
+		OSImage:       "Ubuntu 22.04.4 LTS",
+		Architecture:  "amd64",
+		KernelVersion: "5.15.0-91-generic",
+		CPUs:          4,
+		MemoryMiB:     8192,
+		DiskGiB:       100,
+	}
+	requirements := &HostRequirements{
+		Cluster:      "my-cluster",
+		K8sVersion:   "v1.31.2",
+		MinCPU:       4,
+		MinMemoryMiB: 4096,
+		MinDiskGiB:   50,
+		BundlePaths:  []string{bundlePath},
+	}
+	if errs := ValidateHost(facts, requirements); len(errs) != 0 {
+		t.Errorf("Expected the host to satisfy the requirements, got %v", errs)
+	}
+
+	facts = &HostFacts{
+		OSImage:       "Rocky Linux 9.3 (Blue Onyx)",
+		Architecture:  "amd64",
+		KernelVersion: "4.15.0-213-generic",
+		CPUs:          2,
+		MemoryMiB:     1024,
+		DiskGiB:       20,
+	}
+	requirements.BundlePaths = []string{filepath.Join(t.TempDir(), "missing.tar")}
+	errs := ValidateHost(facts, requirements)
+	expected := []string{
+		"os Rocky Linux 9.3 (Blue Onyx) on amd64",
+		"kernel 4.15.0-213-generic is older than 4.19",
+		"2 CPUs, at least 4 are required",
+		"1024 MiB of memory, at least 4096 MiB are required",
+		"20 GiB of disk on /var/lib, at least 50 GiB are required",
+		"bundle " + requirements.BundlePaths[0] + " is not pre-seeded",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(errs[i].Error(), prefix) {
+			t.Errorf("Expected error %d to start with %q, got %q", i, prefix, errs[i].Error())
+		}
+	}
+}
+
+func TestKernelAtLeast(t *testing.T) {
+	tests := []struct {
+		release  string
+		expected bool
+	}{
+		{"4.19.0", true},
+		{"5.4.0-150-generic", true},
+		{"6.1.0", true},
+		{"4.18.0-513.el8.x86_64", false},
+		{"3.10.0-1160.el7.x86_64", false},
+		{"unknown", false},
+	}
+	for _, tt := range tests {
+		if actual := kernelAtLeast(tt.release, MinKernelVersion); actual != tt.expected {
+			t.Errorf("kernelAtLeast(%q) = %v, expected %v", tt.release, actual, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// HostNameOverride is the name of the ByoHost of the host set with --host-name, it replaces the
+// name stored by onboard and the hostname of the host
+var HostNameOverride string
+
+// HostName returns the name of the ByoHost of the host: HostNameOverride, else the --host-name the
+// host was onboarded with, else the hostname of the host the agent registers by default
+func HostName() (string, error) {
+	if HostNameOverride != "" {
+		return HostNameOverride, nil
+	}
+	data, err := os.ReadFile(filepath.Join(ByohDir, HostNameFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read the host name: %w", err)
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name, nil
+	}
+	return os.Hostname()
+}
+
+// ValidateHostName fails if name cannot be the name of a ByoHost and of the node of the host
+func ValidateHostName(name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid host name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// WriteHostName writes the name of the ByoHost of the host into the host name file of byohDir,
+// which the agent-after-install script passes to the --host-name flag of the agent. The file is
+// removed if name is empty, the agent then registers with the hostname of the host.
+func WriteHostName(byohDir, name string) error {
+	hostNameFile := filepath.Join(byohDir, HostNameFilename)
+	if name == "" {
+		if err := os.Remove(hostNameFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", hostNameFile, err)
+		}
+		return nil
+	}
+	if err := ValidateHostName(name); err != nil {
+		return err
+	}
+	if err := os.WriteFile(hostNameFile, []byte(name), DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to write %s: %w", hostNameFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHostName(t *testing.T) {
+	origByohDir := ByohDir
+	ByohDir = t.TempDir()
+	t.Cleanup(func() {
+		ByohDir = origByohDir
+		HostNameOverride = ""
+	})
+
+	// the hostname of the host without --host-name
+	osHostName, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Failed to get the hostname: %v", err)
+	}
+	if name, err := HostName(); err != nil || name != osHostName {
+		t.Errorf("Expected host name %q, got %q, %v", osHostName, name, err)
+	}
+
+	// the --host-name of onboard
+	if err := WriteHostName(ByohDir, "stable-host"); err != nil {
+		t.Fatalf("WriteHostName returned error: %v", err)
+	}
+	if name, err := HostName(); err != nil || name != "stable-host" {
+		t.Errorf("Expected host name %q, got %q, %v", "stable-host", name, err)
+	}
+
+	// the --host-name of the command
+	HostNameOverride = "other-host"
+	if name, err := HostName(); err != nil || name != "other-host" {
+		t.Errorf("Expected host name %q, got %q, %v", "other-host", name, err)
+	}
+
+	// no --host-name at onboarding removes the name of a previous onboarding
+	if err := WriteHostName(ByohDir, ""); err != nil {
+		t.Fatalf("WriteHostName returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ByohDir, HostNameFilename)); !os.IsNotExist(err) {
+		t.Errorf("Expected host name file to be removed, got %v", err)
+	}
+
+	for _, name := range []string{"Upper-Case", "under_score", "-leading-dash"} {
+		if err := WriteHostName(ByohDir, name); err == nil {
+			t.Errorf("Expected WriteHostName to reject %q", name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
+)
+
+// LockHost takes the host lock for operation, e.g. "byohctl onboard", so that it does not
+// interleave with another byohctl invocation or the install and uninstall scripts of the agent.
+// It waits up to HostLockTimeout for the operation holding the lock. The caller releases the lock.
+func LockHost(operation string) (*hostlock.Lock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), HostLockTimeout)
+	defer cancel()
+	lock, err := hostlock.Acquire(ctx, HostLockPath, operation, func(holder string) {
+		utils.LogInfo("Waiting for %s to finish", holder)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the host: %w", err)
+	}
+	return lock, nil
+}
+
+// UnlockHost releases the host lock taken by LockHost, a failure is only logged
+func UnlockHost(lock *hostlock.Lock) {
+	if err := lock.Release(); err != nil {
+		utils.LogWarn("Failed to release the host lock: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
+)
+
+func TestLockHost(t *testing.T) {
+	origHostLockPath := HostLockPath
+	HostLockPath = filepath.Join(t.TempDir(), "host.lock")
+	t.Cleanup(func() { HostLockPath = origHostLockPath })
+
+	lock, err := LockHost("byohctl onboard")
+	if err != nil {
+		t.Fatalf("LockHost returned error: %v", err)
+	}
+	if holder := hostlock.Holder(HostLockPath); !strings.HasPrefix(holder, "byohctl onboard (pid ") {
+		t.Errorf("Expected the lock to be held by byohctl onboard, got %q", holder)
+	}
+	UnlockHost(lock)
+
+	// A released lock can be taken again
+	lock, err = LockHost("byohctl decommission")
+	if err != nil {
+		t.Fatalf("LockHost returned error on a released lock: %v", err)
+	}
+	UnlockHost(lock)
+}
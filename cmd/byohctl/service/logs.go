@@ -0,0 +1,141 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// byohctlLogTime matches the time of the lines and of the session headers of the debug log of
+	// byohctl, e.g. "[2006-01-02 15:04:05] [INFO] ..."
+	byohctlLogTime = regexp.MustCompile(`^(?:\[|===== BYOHCTL SESSION \w+ AT )(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`)
+	// klogLogTime matches the time of the lines of the agent log klog writes, e.g.
+	// "I1016 09:30:00.123456    1234 main.go:12] ...", which has no year
+	klogLogTime = regexp.MustCompile(`^[IWEF](\d{4} \d{2}:\d{2}:\d{2}\.\d{6})`)
+)
+
+// LogLineTime returns the time of line of the debug log of byohctl or of the agent log, false for
+// the lines without a time, e.g. the continuation of a multiline message. The lines of the agent
+// log are of the last year up to now.
+func LogLineTime(line string, now time.Time) (time.Time, bool) {
+	if match := byohctlLogTime.FindStringSubmatch(line); match != nil {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", match[1], time.Local)
+		return t, err == nil
+	}
+	if match := klogLogTime.FindStringSubmatch(line); match != nil {
+		t, err := time.ParseInLocation("2006 0102 15:04:05.000000", fmt.Sprintf("%d %s", now.Year(), match[1]), time.Local)
+		if err != nil {
+			return time.Time{}, false
+		}
+		// a line of December read in January
+		if t.After(now) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// TailLog returns the last lines of the log file path logged from since on, all of them if lines
+// is not positive, and the offset the log file ends at to follow it from. The lines without a time
+// go with the line before them.
+func TailLog(path string, lines int, since time.Time) ([]string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read the log: %w", err)
+	}
+	defer file.Close()
+
+	now := time.Now()
+	var tail []string
+	keep := since.IsZero()
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		// a partial last line is followed from its beginning
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read the log: %w", err)
+		}
+		offset += int64(len(line))
+		line = strings.TrimSuffix(line, "\n")
+		if t, ok := LogLineTime(line, now); ok {
+			keep = !t.Before(since)
+		}
+		if !keep {
+			continue
+		}
+		tail = append(tail, line)
+		if lines > 0 && len(tail) > lines {
+			tail = tail[1:]
+		}
+	}
+	return tail, offset, nil
+}
+
+// FollowLog calls write with each line appended to the log file path after offset, checking for
+// new lines every interval until ctx is done. A log file that is truncated, like the debug log of
+// byohctl by every command, or rotated is followed from its beginning.
+func FollowLog(ctx context.Context, path string, offset int64, interval time.Duration, write func(line string)) error {
+	partial := ""
+	for {
+		appended, err := readLogFrom(path, &offset)
+		if err != nil {
+			return err
+		}
+		if appended != "" {
+			lines := strings.Split(partial+appended, "\n")
+			partial = lines[len(lines)-1]
+			for _, line := range lines[:len(lines)-1] {
+				write(line)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// readLogFrom returns what was appended to the log file path after offset and moves offset to its
+// end, from its beginning if the file is now shorter than offset
+func readLogFrom(path string, offset *int64) (string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// the log is created once the agent starts, or rotated
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to follow the log: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to follow the log: %w", err)
+	}
+	if info.Size() < *offset {
+		*offset = 0
+	}
+	if info.Size() == *offset {
+		return "", nil
+	}
+	if _, err := file.Seek(*offset, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to follow the log: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to follow the log: %w", err)
+	}
+	*offset += int64(len(data))
+	return string(data), nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLogLineTime(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{"[2026-01-05 09:30:00] [INFO] Setting up BYOH agent", time.Date(2026, 1, 5, 9, 30, 0, 0, time.Local), true},
+		{"===== BYOHCTL SESSION STARTED AT 2026-01-05 09:29:59 =====", time.Date(2026, 1, 5, 9, 29, 59, 0, time.Local), true},
+		{"I0105 11:00:00.250000    1234 main.go:12] Registered the host", time.Date(2026, 1, 5, 11, 0, 0, 250000000, time.Local), true},
+		// a line of December read in January is of the last year
+		{"E1231 23:59:59.000000    1234 main.go:12] Heartbeat failed", time.Date(2025, 12, 31, 23, 59, 59, 0, time.Local), true},
+		{"	goroutine 1 [running]:", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := LogLineTime(tt.line, now)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("LogLineTime(%q) = %v, %v, want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTailLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "byoh-agent.log")
+	content := "I0105 09:00:00.000000    1 main.go:1] first\n" +
+		"I0105 10:00:00.000000    1 main.go:1] second\n" +
+		"E0105 11:00:00.000000    1 main.go:1] third\n" +
+		"	with a continuation\n" +
+		"I0105 12:00:00.000000    1 main.go:1] partial"
+	if err := os.WriteFile(path, []byte(content), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+
+	lines, offset, err := TailLog(path, 2, time.Time{})
+	if err != nil {
+		t.Fatalf("TailLog returned error: %v", err)
+	}
+	want := []string{"E0105 11:00:00.000000    1 main.go:1] third", "	with a continuation"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected the last complete lines %q, got %q", want, lines)
+	}
+	if offset != int64(len(content)-len("I0105 12:00:00.000000    1 main.go:1] partial")) {
+		t.Errorf("Expected the offset of the partial last line, got %d", offset)
+	}
+
+	second, _ := LogLineTime("I0105 10:00:00.000000    1 main.go:1] second", time.Now())
+	since := second.Add(-30 * time.Minute)
+	lines, _, err = TailLog(path, 0, since)
+	if err != nil {
+		t.Fatalf("TailLog returned error: %v", err)
+	}
+	if len(lines) != 3 || lines[0] != "I0105 10:00:00.000000    1 main.go:1] second" {
+		t.Errorf("Expected the lines since %s, got %q", since, lines)
+	}
+
+	if _, _, err := TailLog(filepath.Join(t.TempDir(), "missing.log"), 10, time.Time{}); err == nil {
+		t.Errorf("Expected an error for a missing log")
+	}
+}
+
+func TestFollowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "byoh-agent-debug.log")
+	if err := os.WriteFile(path, []byte("[2026-01-05 09:30:00] [INFO] old\n"), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	_, offset, err := TailLog(path, 0, time.Time{})
+	if err != nil {
+		t.Fatalf("TailLog returned error: %v", err)
+	}
+
+	var mu sync.Mutex
+	var followed []string
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- FollowLog(ctx, path, offset, time.Millisecond, func(line string) {
+			mu.Lock()
+			defer mu.Unlock()
+			followed = append(followed, line)
+		})
+	}()
+	waitFor := func(n int) []string {
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			lines := append([]string(nil), followed...)
+			mu.Unlock()
+			if len(lines) >= n {
+				return lines
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("Timed out waiting for %d followed lines, got %q", n, followed)
+		return nil
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, DefaultFilePerms)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	file.WriteString("[2026-01-05 09:31:00] [INFO] new\n[2026-01-05")
+	file.WriteString(" 09:32:00] [INFO] written in two parts\n")
+	file.Close()
+	want := []string{"[2026-01-05 09:31:00] [INFO] new", "[2026-01-05 09:32:00] [INFO] written in two parts"}
+	if lines := waitFor(2); !reflect.DeepEqual(lines, want) {
+		t.Errorf("Expected the appended lines %q, got %q", want, lines)
+	}
+
+	// a new session of byohctl truncates its debug log
+	if err := os.WriteFile(path, []byte("[2026-01-05 10:00:00] [INFO] truncated\n"), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if lines := waitFor(3); lines[2] != "[2026-01-05 10:00:00] [INFO] truncated" {
+		t.Errorf("Expected the truncated log to be followed from its beginning, got %q", lines)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("FollowLog returned error: %v", err)
+	}
+}
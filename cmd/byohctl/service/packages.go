@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+)
+
+// OSFamily is a family of Linux distributions sharing a package format
+type OSFamily string
+
+const (
+	// DebianFamily are the distributions installing .deb packages with apt, e.g. Ubuntu and Debian
+	DebianFamily OSFamily = "debian"
+	// RHELFamily are the distributions installing .rpm packages with dnf or yum, e.g. RHEL and Rocky Linux
+	RHELFamily OSFamily = "rhel"
+)
+
+// SupportedOS describes the distributions hosts can be onboarded on, for error messages
+const SupportedOS = "Ubuntu, Debian 11/12, RHEL 8/9 or Rocky Linux 8/9"
+
+// rhelIDs are the os-release IDs of the supported distributions of the RHEL family
+var rhelIDs = []string{"rhel", "rocky"}
+
+// debianVersions are the os-release VERSION_IDs of the supported Debian releases
+var debianVersions = []string{"11", "12"}
+
+// debPackageMatrix overrides the Debian package names of the required packages, by their Name, on
+// the releases they differ on. The releases are keyed by their os-release ID and VERSION_ID, e.g.
+// "debian 12"; an empty name skips the package on the release.
+var debPackageMatrix = map[string]map[string]string{
+	// unlike Ubuntu, Debian does not install iptables, kubeadm and kube-proxy need it
+	"debian 11": {"iptables": "iptables"},
+	"debian 12": {"iptables": "iptables"},
+}
+
+// osFamilyOf returns the family of the distribution with the os-release ID id and VERSION_ID
+// versionID, false if hosts can not be onboarded on it
+func osFamilyOf(id, versionID string) (OSFamily, bool) {
+	switch {
+	case id == "ubuntu":
+		return DebianFamily, true
+	case id == "debian" && slices.Contains(debianVersions, versionID):
+		return DebianFamily, true
+	case slices.Contains(rhelIDs, id):
+		return RHELFamily, true
+	}
+	return "", false
+}
+
+// HostOSFamily returns the family of the distribution of the host, it fails if hosts can not be onboarded on it
+func HostOSFamily() (OSFamily, error) {
+	family, _, err := hostOSRelease()
+	return family, err
+}
+
+// hostOSRelease returns the family and the release, e.g. "debian 12", of the distribution of the
+// host, it fails if hosts can not be onboarded on it
+func hostOSRelease() (OSFamily, string, error) {
+	osRelease, err := os.ReadFile(OSReleasePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read the os-release of the host: %w", err)
+	}
+	id, versionID := osReleaseValue(osRelease, "ID"), osReleaseValue(osRelease, "VERSION_ID")
+	family, ok := osFamilyOf(id, versionID)
+	if !ok {
+		return "", "", fmt.Errorf("%s is not supported, onboarding requires %s", osReleaseValue(osRelease, "PRETTY_NAME"), SupportedOS)
+	}
+	return family, id + " " + versionID, nil
+}
+
+// osReleaseValue returns the unquoted value of key in the os-release osRelease, empty if it is not set
+func osReleaseValue(osRelease []byte, key string) string {
+	match := regexp.MustCompile(`(?m)^` + key + `=(.*)$`).FindSubmatch(osRelease)
+	if match == nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(string(match[1])), `"'`)
+}
+
+// PackageManager installs and removes the packages of the host
+type PackageManager interface {
+	// Refresh makes the package manager ready to install packages, e.g. updates its package lists
+	Refresh() error
+	// PackageName returns the name pkg is installed with, empty if the host does not need it
+	PackageName(pkg Package) string
+	// Installed reports whether the package name is installed
+	Installed(name string) bool
+	// Version returns the installed version of the package name
+	Version(name string) (string, error)
+	// Available reports whether the package name can be installed from the repositories of the host,
+	// as far as the package lists already on the host know
+	Available(name string) bool
+	// Install installs the package name from the repositories of the host
+	Install(name string) ([]byte, error)
+	// InstallFiles installs the package files paths and their dependencies, offline does not
+	// reach the repositories of the host, the dependencies must be installed or among paths
+	InstallFiles(offline bool, paths ...string) ([]byte, error)
+	// Downgrade installs the package file path over a newer version of it, to roll back an upgrade
+	Downgrade(path string) ([]byte, error)
+	// Purge removes the package name and its configuration
+	Purge(name string) ([]byte, error)
+	// AgentPackage returns the image and the file name of the agent package, the image is empty if
+	// none is published
+	AgentPackage() (string, string)
+}
+
+// newPackageManager returns the package manager of the release of the distributions of family
+func newPackageManager(family OSFamily, release string) PackageManager {
+	if family == RHELFamily {
+		// RHEL 8 and later have dnf, yum is kept as an alias of it
+		command := "dnf"
+		if _, err := CommandRunner.LookPath(command); err != nil {
+			command = "yum"
+		}
+		return dnfPackageManager{command: command}
+	}
+	return aptPackageManager{release: release}
+}
+
+// HostPackageManager returns the package manager of the host, it fails if hosts can not be onboarded on its distribution
+func HostPackageManager() (PackageManager, error) {
+	family, release, err := hostOSRelease()
+	if err != nil {
+		return nil, err
+	}
+	return newPackageManager(family, release), nil
+}
+
+// aptPackageManager installs .deb packages with apt and dpkg
+type aptPackageManager struct {
+	// release is the os-release ID and VERSION_ID of the host, to look up in debPackageMatrix
+	release string
+}
+
+func (aptPackageManager) Refresh() error {
+	// do apt-get update before proceeding with installing required packages
+	utils.LogSuccess("Updating apt packages...Might take few seconds")
+
+	if ok, err := isAptUnlocked(); !ok {
+		return err
+	}
+
+	// do apt-get update
+	if _, err := RunWithStdout("apt-get", "update"); err != nil {
+		return fmt.Errorf("failed to update apt packages: %w", err)
+	}
+
+	// Fix any broken package state first
+	output, err := CommandRunner.CombinedOutput(context.TODO(), "apt-get", "--fix-broken", "install", "-y")
+	if err != nil {
+		return fmt.Errorf("failed to fix broken packages: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+func (m aptPackageManager) PackageName(pkg Package) string {
+	if name, ok := debPackageMatrix[m.release][pkg.Name]; ok {
+		return name
+	}
+	return pkg.PackageName
+}
+
+func (aptPackageManager) Installed(name string) bool {
+	output, err := CommandRunner.CombinedOutput(context.TODO(), "dpkg", "-l", name)
+	if err != nil {
+		return false
+	}
+	// dpkg -l output has "ii" at the start of the line for installed packages
+	return bytes.Contains(output, []byte("ii  "+name))
+}
+
+func (aptPackageManager) Version(name string) (string, error) {
+	output, err := CommandRunner.Output(context.TODO(), "dpkg-query", "-W", "-f=${Version}", name)
+	if err != nil {
+		return "", fmt.Errorf("%s is not installed: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (aptPackageManager) Available(name string) bool {
+	// apt-cache policy prints nothing for unknown packages and no candidate for packages no repository has
+	output, err := CommandRunner.Output(context.TODO(), "apt-cache", "policy", name)
+	return err == nil && bytes.Contains(output, []byte("Candidate:")) && !bytes.Contains(output, []byte("Candidate: (none)"))
+}
+
+func (aptPackageManager) Install(name string) ([]byte, error) {
+	return CommandRunner.CombinedOutput(context.TODO(), "apt-get", "install", "-y", name)
+}
+
+func (aptPackageManager) InstallFiles(_ bool, paths ...string) ([]byte, error) {
+	// dpkg never reaches the repositories, it installs the files of paths in one go so they can depend on each other
+	dpkgPath, _ := CommandRunner.LookPath("dpkg")
+	return CommandRunner.CombinedOutput(context.TODO(), dpkgPath, append([]string{"-i"}, paths...)...)
+}
+
+func (aptPackageManager) Downgrade(path string) ([]byte, error) {
+	// dpkg installs an older version over a newer one with a warning
+	dpkgPath, _ := CommandRunner.LookPath("dpkg")
+	return CommandRunner.CombinedOutput(context.TODO(), dpkgPath, "-i", path)
+}
+
+func (aptPackageManager) Purge(name string) ([]byte, error) {
+	dpkgPath, _ := CommandRunner.LookPath("dpkg")
+	return CommandRunner.CombinedOutput(context.TODO(), dpkgPath, "--purge", name)
+}
+
+func (aptPackageManager) AgentPackage() (string, string) {
+	return ByohAgentDebPackageURL, ByohAgentDebPackageFilename
+}
+
+// dnfPackageManager installs .rpm packages with dnf, or yum
+type dnfPackageManager struct {
+	command string
+}
+
+func (m dnfPackageManager) Refresh() error {
+	utils.LogSuccess("Updating %s metadata...Might take few seconds", m.command)
+	if output, err := CommandRunner.CombinedOutput(context.TODO(), m.command, "makecache"); err != nil {
+		return fmt.Errorf("failed to update %s metadata: %w\nOutput: %s", m.command, err, string(output))
+	}
+	return nil
+}
+
+func (dnfPackageManager) PackageName(pkg Package) string {
+	return pkg.RPMPackageName
+}
+
+func (dnfPackageManager) Installed(name string) bool {
+	// --whatprovides finds the package of both package names and file paths
+	_, err := CommandRunner.CombinedOutput(context.TODO(), "rpm", "-q", "--whatprovides", name)
+	return err == nil
+}
+
+func (dnfPackageManager) Version(name string) (string, error) {
+	output, err := CommandRunner.Output(context.TODO(), "rpm", "-q", "--qf", "%{VERSION}-%{RELEASE}", name)
+	if err != nil {
+		return "", fmt.Errorf("%s is not installed: %w", name, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (m dnfPackageManager) Available(name string) bool {
+	// like rpm --whatprovides, provides finds the package of both package names and file paths
+	_, err := CommandRunner.CombinedOutput(context.TODO(), m.command, "-q", "provides", name)
+	return err == nil
+}
+
+func (m dnfPackageManager) Install(name string) ([]byte, error) {
+	return CommandRunner.CombinedOutput(context.TODO(), m.command, "install", "-y", name)
+}
+
+func (m dnfPackageManager) InstallFiles(offline bool, paths ...string) ([]byte, error) {
+	args := []string{"install", "-y"}
+	if offline {
+		args = append(args, "--disablerepo=*")
+	}
+	return CommandRunner.CombinedOutput(context.TODO(), m.command, append(args, paths...)...)
+}
+
+func (dnfPackageManager) Downgrade(path string) ([]byte, error) {
+	// unlike dnf install, rpm replaces a newer version, the dependencies are installed already
+	return CommandRunner.CombinedOutput(context.TODO(), "rpm", "-U", "--oldpackage", "--replacepkgs", path)
+}
+
+func (m dnfPackageManager) Purge(name string) ([]byte, error) {
+	return CommandRunner.CombinedOutput(context.TODO(), m.command, "remove", "-y", name)
+}
+
+func (dnfPackageManager) AgentPackage() (string, string) {
+	// the release pipeline does not publish an image of the RPM package yet
+	return "", ByohAgentRPMPackageFilename
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// MinPreflightDiskGiB is the minimum size of the EphemeralStoragePath filesystem of a host to onboard
+	MinPreflightDiskGiB = 20
+	// PreflightDialTimeout is how long the connectivity check waits for the management plane
+	PreflightDialTimeout = 10 * time.Second
+)
+
+// ConflictingServices are the services that must not run on a host to onboard: the BYOH agent of
+// a previous onboarding, the host agent of a PCD hypervisor and a kubelet managed outside of BYOH
+var ConflictingServices = []string{ByohAgentServiceName, "pf9-hostagent", "kubelet"}
+
+// PreflightResult is the outcome of one preflight check of the host
+type PreflightResult struct {
+	// Name of the check, e.g. "os"
+	Name string
+	// Detail describes what the check found on the host
+	Detail string
+	// Err is the reason the check failed, nil if it passed
+	Err error
+}
+
+// Preflight checks whether a host can be onboarded, its checks are replaced by tests
+type Preflight struct {
+	// LookupHost resolves a host name, net.LookupHost by default
+	LookupHost func(host string) ([]string, error)
+	// Dial connects to an address, net.DialTimeout with PreflightDialTimeout by default
+	Dial func(network, address string) (net.Conn, error)
+}
+
+// Run runs every preflight check of the host against the management plane fqdn, in the order of the report
+func (p *Preflight) Run(facts *HostFacts, fqdn string) []PreflightResult {
+	host, port := fqdnHostPort(fqdn)
+	return []PreflightResult{
+		checkOS(facts),
+		checkResources(facts),
+		p.checkDNS(host),
+		p.checkConnectivity(net.JoinHostPort(host, port)),
+		checkConflictingServices(),
+	}
+}
+
+// PreflightFailed reports whether a check of results failed
+func PreflightFailed(results []PreflightResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOS checks the host runs a supported distribution on a recent enough kernel
+func checkOS(facts *HostFacts) PreflightResult {
+	result := PreflightResult{Name: "os", Detail: fmt.Sprintf("%s, kernel %s, %s", facts.OSImage, facts.KernelVersion, facts.Architecture)}
+	_, supported := osFamilyOf(facts.OSID, facts.OSVersionID)
+	minKernel := minKernelVersion(facts)
+	switch {
+	case !supported:
+		result.Err = fmt.Errorf("%s is not supported, onboarding requires %s", facts.OSImage, SupportedOS)
+	case !kernelAtLeast(facts.KernelVersion, minKernel):
+		result.Err = fmt.Errorf("kernel %s is older than %s", facts.KernelVersion, minKernel)
+	}
+	return result
+}
+
+// checkResources checks the host has the CPUs, memory and disk kubeadm requires
+func checkResources(facts *HostFacts) PreflightResult {
+	result := PreflightResult{Name: "resources", Detail: fmt.Sprintf("%d CPUs, %d MiB of memory, %d GiB of disk on %s", facts.CPUs, facts.MemoryMiB, facts.DiskGiB, EphemeralStoragePath)}
+	var missing []string
+	if facts.CPUs < MinKubeadmCPU {
+		missing = append(missing, fmt.Sprintf("at least %d CPUs", MinKubeadmCPU))
+	}
+	if facts.MemoryMiB < MinKubeadmMemoryMiB {
+		missing = append(missing, fmt.Sprintf("at least %d MiB of memory", MinKubeadmMemoryMiB))
+	}
+	if facts.DiskGiB < MinPreflightDiskGiB {
+		missing = append(missing, fmt.Sprintf("at least %d GiB of disk", MinPreflightDiskGiB))
+	}
+	if len(missing) > 0 {
+		result.Err = fmt.Errorf("%s are required", strings.Join(missing, ", "))
+	}
+	return result
+}
+
+// checkDNS checks the host name of the management plane resolves
+func (p *Preflight) checkDNS(host string) PreflightResult {
+	result := PreflightResult{Name: "dns", Detail: host}
+	lookupHost := p.LookupHost
+	if lookupHost == nil {
+		lookupHost = net.LookupHost
+	}
+	addrs, err := lookupHost(host)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to resolve %s: %w", host, err)
+		return result
+	}
+	result.Detail = fmt.Sprintf("%s resolves to %s", host, strings.Join(addrs, ", "))
+	return result
+}
+
+// checkConnectivity checks the host reaches the management plane over HTTPS
+func (p *Preflight) checkConnectivity(address string) PreflightResult {
+	result := PreflightResult{Name: "connectivity", Detail: address}
+	dial := p.Dial
+	if dial == nil {
+		dial = func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, PreflightDialTimeout)
+		}
+	}
+	conn, err := dial("tcp", address)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to connect to %s: %w", address, err)
+		return result
+	}
+	conn.Close()
+	return result
+}
+
+// checkConflictingServices checks none of the ConflictingServices is installed on the host
+func checkConflictingServices() PreflightResult {
+	result := PreflightResult{Name: "conflicting-agents", Detail: "none installed"}
+	var installed []string
+	for _, name := range ConflictingServices {
+		// systemctl fails if no unit file matches
+		out, err := RunWithStdout(Systemctl, "list-unit-files", name+".service")
+		if err == nil && strings.Contains(out, name+".service") {
+			installed = append(installed, name)
+		}
+	}
+	if len(installed) > 0 {
+		result.Detail = strings.Join(installed, ", ") + " installed"
+		result.Err = fmt.Errorf("%s must be removed before onboarding the host", strings.Join(installed, ", "))
+	}
+	return result
+}
+
+// fqdnHostPort returns the host name and the HTTPS port of the management plane fqdn, which may be given as a URL
+func fqdnHostPort(fqdn string) (string, string) {
+	host := strings.TrimPrefix(strings.TrimPrefix(fqdn, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		return h, port
+	}
+	return host, "443"
+}
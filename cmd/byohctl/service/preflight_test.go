@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	runner := useFakeHost(t)
+	facts := &HostFacts{
+		OSImage:       "Ubuntu 22.04.4 LTS",
+		OSID:          "ubuntu",
+		Architecture:  "amd64",
+		KernelVersion: "5.15.0-91-generic",
+		CPUs:          4,
+		MemoryMiB:     8192,
+		DiskGiB:       100,
+	}
+	var dialed string
+	preflight := &Preflight{
+		LookupHost: func(host string) ([]string, error) { return []string{"192.0.2.10"}, nil },
+		Dial: func(network, address string) (net.Conn, error) {
+			dialed = address
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		},
+	}
+
+	results := preflight.Run(facts, "https://your-fqdn.platform9.com/")
+	if PreflightFailed(results) {
+		t.Fatalf("Expected every check to pass, got %v", results)
+	}
+	if dialed != "your-fqdn.platform9.com:443" {
+		t.Errorf("Expected the management plane to be dialed on your-fqdn.platform9.com:443, got %q", dialed)
+	}
+	if !runner.Ran("systemctl list-unit-files kubelet.service") {
+		t.Errorf("Expected the kubelet service to be looked up, ran %v", runner.Commands())
+	}
+
+	facts = &HostFacts{
+		OSImage:       "Arch Linux",
+		OSID:          "arch",
+		Architecture:  "amd64",
+		KernelVersion: "6.7.4-arch1-1",
+		CPUs:          1,
+		MemoryMiB:     1024,
+		DiskGiB:       10,
+	}
+	preflight.LookupHost = func(host string) ([]string, error) { return nil, errors.New("no such host") }
+	preflight.Dial = func(network, address string) (net.Conn, error) { return nil, errors.New("connection refused") }
+	runner.Set("systemctl list-unit-files kubelet.service", "kubelet.service enabled enabled\n", nil)
+
+	results = preflight.Run(facts, "your-fqdn.platform9.com")
+	expected := map[string]string{
+		"os":                 "Arch Linux is not supported",
+		"resources":          "at least 2 CPUs, at least 1700 MiB of memory, at least 20 GiB of disk are required",
+		"dns":                "failed to resolve your-fqdn.platform9.com",
+		"connectivity":       "failed to connect to your-fqdn.platform9.com:443",
+		"conflicting-agents": "kubelet must be removed before onboarding the host",
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %v", len(expected), results)
+	}
+	for _, result := range results {
+		if result.Err == nil || !strings.HasPrefix(result.Err.Error(), expected[result.Name]) {
+			t.Errorf("Expected check %s to fail with %q, got %v", result.Name, expected[result.Name], result.Err)
+		}
+	}
+}
+
+func TestCheckOS(t *testing.T) {
+	tests := []struct {
+		facts HostFacts
+		err   string
+	}{
+		{facts: HostFacts{OSImage: "Rocky Linux 8.9 (Green Obsidian)", OSID: "rocky", KernelVersion: "4.18.0-513.5.1.el8_9.x86_64"}},
+		{facts: HostFacts{OSImage: "Red Hat Enterprise Linux 9.3 (Plow)", OSID: "rhel", KernelVersion: "5.14.0-362.8.1.el9_3.x86_64"}},
+		{facts: HostFacts{OSImage: "Ubuntu 18.04.6 LTS", OSID: "ubuntu", KernelVersion: "4.15.0-213-generic"}, err: "kernel 4.15.0-213-generic is older than 4.19"},
+		{facts: HostFacts{OSImage: "Debian GNU/Linux 12 (bookworm)", OSID: "debian", OSVersionID: "12", KernelVersion: "6.1.0-18-amd64"}},
+		{facts: HostFacts{OSImage: "Debian GNU/Linux 10 (buster)", OSID: "debian", OSVersionID: "10", KernelVersion: "4.19.0-26-amd64"}, err: "Debian GNU/Linux 10 (buster) is not supported"},
+	}
+	for _, tc := range tests {
+		result := checkOS(&tc.facts)
+		if tc.err == "" && result.Err != nil || tc.err != "" && (result.Err == nil || !strings.HasPrefix(result.Err.Error(), tc.err)) {
+			t.Errorf("checkOS(%s) = %v, expected %q", tc.facts.OSImage, result.Err, tc.err)
+		}
+	}
+}
+
+func TestFqdnHostPort(t *testing.T) {
+	tests := map[string]string{
+		"your-fqdn.platform9.com":                  "your-fqdn.platform9.com:443",
+		"https://your-fqdn.platform9.com":          "your-fqdn.platform9.com:443",
+		"https://your-fqdn.platform9.com:8443/x/y": "your-fqdn.platform9.com:8443",
+	}
+	for fqdn, expected := range tests {
+		if host, port := fqdnHostPort(fqdn); net.JoinHostPort(host, port) != expected {
+			t.Errorf("fqdnHostPort(%q) = %q, %q, expected %q", fqdn, host, port, expected)
+		}
+	}
+}
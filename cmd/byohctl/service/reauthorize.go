@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RegionAgentLabels returns the labels of the ByoHost of the host onboarded into byohDir with its
+// region label set to region, keeping the other labels it was onboarded with
+func RegionAgentLabels(byohDir, region string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(byohDir, RegionFilename))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the labels of the agent: %w", err)
+	}
+	var labels []string
+	for _, label := range strings.Split(strings.TrimSpace(string(data)), ",") {
+		if label == "" || strings.HasPrefix(label, PcdKaapiRegionKey+"=") {
+			continue
+		}
+		labels = append(labels, label)
+	}
+	return AgentLabels(region, labels)
+}
+
+// SetAgentServiceEnv sets the variables env in the environment file of the agent service, keeping
+// its other variables. The agent reads them once it is restarted.
+func SetAgentServiceEnv(env map[string]string) error {
+	data, err := os.ReadFile(AgentServiceEnvPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the environment of the agent service: %w", err)
+	}
+	set := map[string]bool{}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		key, _, _ := strings.Cut(line, "=")
+		if value, ok := env[key]; ok {
+			line = key + "=" + value
+			set[key] = true
+		}
+		lines = append(lines, line)
+	}
+	var missing []string
+	for key := range env {
+		if !set[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		lines = append(lines, key+"="+env[key])
+	}
+	if err := os.WriteFile(AgentServiceEnvPath, []byte(strings.Join(lines, "\n")+"\n"), DefaultFilePerms); err != nil {
+		return fmt.Errorf("failed to write the environment of the agent service: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegionAgentLabels(t *testing.T) {
+	byohDir := t.TempDir()
+	regionFile := filepath.Join(byohDir, RegionFilename)
+	if err := os.WriteFile(regionFile, []byte(PcdKaapiRegionKey+"=region-one,topology.kubernetes.io/zone=dc1,gpu=true"), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", regionFile, err)
+	}
+
+	labels, err := RegionAgentLabels(byohDir, "region-two")
+	if err != nil {
+		t.Fatalf("RegionAgentLabels returned error: %v", err)
+	}
+	if expected := PcdKaapiRegionKey + "=region-two,topology.kubernetes.io/zone=dc1,gpu=true"; labels != expected {
+		t.Errorf("Expected the labels %s, got %s", expected, labels)
+	}
+
+	if _, err := RegionAgentLabels(t.TempDir(), "region-two"); err == nil {
+		t.Errorf("Expected an error for a host without labels")
+	}
+}
+
+func TestSetAgentServiceEnv(t *testing.T) {
+	origAgentServiceEnvPath := AgentServiceEnvPath
+	AgentServiceEnvPath = filepath.Join(t.TempDir(), "pf9-byohost-agent.conf")
+	defer func() { AgentServiceEnvPath = origAgentServiceEnvPath }()
+	env := "NAMESPACE=tenant-one\nBOOTSTRAP_KUBECONFIG=/etc/pf9-byohost-agent.service.d/bootstrap-kubeconfig.yaml\nREGION=" + PcdKaapiRegionKey + "=region-one\n"
+	if err := os.WriteFile(AgentServiceEnvPath, []byte(env), DefaultFilePerms); err != nil {
+		t.Fatalf("Failed to write %s: %v", AgentServiceEnvPath, err)
+	}
+
+	if err := SetAgentServiceEnv(map[string]string{"NAMESPACE": "tenant-two", "REGION": PcdKaapiRegionKey + "=region-two", "HOST_NAME": "rack1-node3"}); err != nil {
+		t.Fatalf("SetAgentServiceEnv returned error: %v", err)
+	}
+	data, err := os.ReadFile(AgentServiceEnvPath)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", AgentServiceEnvPath, err)
+	}
+	expected := "NAMESPACE=tenant-two\nBOOTSTRAP_KUBECONFIG=/etc/pf9-byohost-agent.service.d/bootstrap-kubeconfig.yaml\nREGION=" + PcdKaapiRegionKey + "=region-two\nHOST_NAME=rack1-node3\n"
+	if string(data) != expected {
+		t.Errorf("Expected the environment\n%s\ngot\n%s", expected, data)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"strings"
+)
+
+// AgentServiceNotInstalled is the state of the agent service on a host it is not installed on
+const AgentServiceNotInstalled = "not installed"
+
+// AgentServiceState returns the state systemd reports for the agent service, e.g. active, failed
+// or activating, AgentServiceNotInstalled if the service is not installed
+func AgentServiceState() string {
+	out, err := RunWithStdout(Systemctl, SystemctlServiceExists...)
+	if err != nil || !strings.Contains(out, ByohAgentServiceName) {
+		return AgentServiceNotInstalled
+	}
+	// systemctl is-active exits non-zero for a service that is not active, but still prints its state
+	out, _ = RunWithStdout(Systemctl, "is-active", ByohAgentServiceName)
+	if state := strings.TrimSpace(out); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// InstalledAgentVersion returns the version of the agent package installed on the host
+func InstalledAgentVersion() (string, error) {
+	pm, err := HostPackageManager()
+	if err != nil {
+		return "", err
+	}
+	return pm.Version(ByohAgentServiceName)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/internal/fakeplane"
+)
+
+func TestAgentServiceState(t *testing.T) {
+	runner := useFakeHost(t)
+	if state := AgentServiceState(); state != AgentServiceNotInstalled {
+		t.Errorf("Expected the service not to be installed, got %q", state)
+	}
+
+	runner.Set("systemctl list-unit-files", "pf9-byohost-agent.service enabled enabled\n", nil)
+	runner.Set("systemctl is-active", "failed\n", errors.New("exit status 3"))
+	if state := AgentServiceState(); state != "failed" {
+		t.Errorf("Expected the failed state of the service, got %q", state)
+	}
+	runner.Set("systemctl is-active", "active\n", nil)
+	if state := AgentServiceState(); state != "active" {
+		t.Errorf("Expected the active state of the service, got %q", state)
+	}
+}
+
+func TestInstalledAgentVersion(t *testing.T) {
+	runner := useFakeHost(t)
+	runner.Set("dpkg-query -W", "0.1.500", nil)
+	if version, err := InstalledAgentVersion(); err != nil || version != "0.1.500" {
+		t.Errorf("Expected the version of the deb package, got %q, %v", version, err)
+	}
+
+	useOSRelease(t, fakeplane.RockyOSRelease)
+	runner.Set("rpm -q --qf", "", errors.New("exit status 1"))
+	if _, err := InstalledAgentVersion(); err == nil {
+		t.Errorf("Expected an error for an agent package that is not installed")
+	}
+	runner.Set("rpm -q --qf", "0.1.500-1", nil)
+	if version, err := InstalledAgentVersion(); err != nil || version != "0.1.500-1" {
+		t.Errorf("Expected the version of the rpm package, got %q, %v", version, err)
+	}
+}
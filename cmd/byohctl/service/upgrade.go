@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+)
+
+// UpgradeAgent upgrades the agent package installed on the host in place from source, keeping the
+// host onboarded, and returns the version installed. The agent package of pkgDir, which the
+// onboarding pulled, is the previous package unless previousPackage is set; rollback reinstalls it
+// and restarts the agent, so that a failed upgrade leaves the previous agent running.
+func UpgradeAgent(pkgDir string, source PackageSource, previousPackage string, rollback *utils.Rollback) (string, error) {
+	pm, err := HostPackageManager()
+	if err != nil {
+		return "", err
+	}
+	version, err := pm.Version(ByohAgentServiceName)
+	if err != nil {
+		return "", fmt.Errorf("%w: the agent package %s is not installed: %w", types.ErrNotOnboarded, ByohAgentServiceName, err)
+	}
+	utils.LogInfo("Upgrading the agent package %s %s", ByohAgentServiceName, version)
+
+	_, filename := pm.AgentPackage()
+	currentPackage := filepath.Join(pkgDir, filename)
+	if previousPackage == "" {
+		previousPackage = currentPackage
+	}
+	if _, err := os.Stat(previousPackage); err != nil {
+		return "", fmt.Errorf("could not find the agent package %s %s to roll back to, pass it with --previous-package-file: %w", ByohAgentServiceName, version, err)
+	}
+
+	packagePath, err := source.agentPackagePath(pm)
+	if err != nil {
+		return "", err
+	}
+	pulled := packagePath == ""
+	if pulled {
+		// the new package is pulled next to the previous one, not over it
+		pullDir := filepath.Join(pkgDir, "upgrade")
+		if err := os.MkdirAll(pullDir, DefaultDirPerms); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", pullDir, err)
+		}
+		defer os.RemoveAll(pullDir)
+		utils.LogInfo("Downloading agent package...")
+		if packagePath, err = downloadAgentPackage(pm, source, pullDir); err != nil {
+			return "", fmt.Errorf("%w agent package: %w", types.ErrDownload, err)
+		}
+	}
+	if err := source.verifyAgentPackage(packagePath, pulled); err != nil {
+		return "", err
+	}
+
+	// the previous package is read before it is replaced by the new one
+	previous, err := os.ReadFile(previousPackage)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the agent package %s: %w", previousPackage, err)
+	}
+	rollback.Add(fmt.Sprintf("reinstall the agent package %s %s", ByohAgentServiceName, version), func() error {
+		rollbackPackage := filepath.Join(pkgDir, "previous-"+filename)
+		if err := os.WriteFile(rollbackPackage, previous, DefaultFilePerms); err != nil {
+			return err
+		}
+		defer os.Remove(rollbackPackage)
+		if output, err := pm.Downgrade(rollbackPackage); err != nil {
+			return fmt.Errorf("%w\nOutput: %s", err, string(output))
+		}
+		return RestartAgentService()
+	})
+
+	if err := StopAgentService(); err != nil {
+		return "", err
+	}
+
+	// the new package replaces the previous one, so that the next upgrade can roll back to it
+	if err := rollback.RestoreFile(currentPackage); err != nil {
+		return "", err
+	}
+	if packagePath != currentPackage {
+		if err := copyFile(packagePath, currentPackage); err != nil {
+			return "", fmt.Errorf("failed to save the agent package to %s: %w", currentPackage, err)
+		}
+	}
+
+	if err := installAgentPackage(pm, currentPackage, source.ArtifactDir != ""); err != nil {
+		return "", fmt.Errorf("failed to install agent package: %w", err)
+	}
+	if err := RestartAgentService(); err != nil {
+		return "", err
+	}
+
+	upgraded, err := pm.Version(ByohAgentServiceName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the version of the agent package: %w", err)
+	}
+	utils.RecordStep(utils.HostChanged, "Upgraded the agent package %s from %s to %s", ByohAgentServiceName, version, upgraded)
+	return upgraded, nil
+}
+
+// StopAgentService stops the agent service
+func StopAgentService() error {
+	utils.LogInfo("Stopping the agent service %s", ByohAgentServiceName)
+	if output, err := CommandRunner.CombinedOutput(context.TODO(), Systemctl, "stop", ByohAgentServiceName+".service"); err != nil {
+		return fmt.Errorf("failed to stop the agent service: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// RestartAgentService reloads the unit files, which the agent package may change, and restarts
+// the agent service
+func RestartAgentService() error {
+	if output, err := CommandRunner.CombinedOutput(context.TODO(), Systemctl, "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w\nOutput: %s", err, string(output))
+	}
+	if output, err := CommandRunner.CombinedOutput(context.TODO(), Systemctl, "restart", ByohAgentServiceName+".service"); err != nil {
+		return fmt.Errorf("failed to restart the agent service: %w\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// copyFile copies the file src to dst
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, DefaultFilePerms)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/internal/fakeplane"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/types"
+	"github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl/utils"
+)
+
+// Test UpgradeAgent pulls the new agent package, installs it over the previous one and restarts the agent
+func TestUpgradeAgent(t *testing.T) {
+	runner := useFakeHost(t)
+	runner.Set("dpkg-query -W", "0.1.450", nil)
+	runner.On("dpkg -i", func(args []string) error {
+		runner.Set("dpkg-query -W", "0.1.500", nil)
+		return nil
+	})
+	pkgDir := t.TempDir()
+	currentPackage := writePackageFiles(t, pkgDir, ByohAgentDebPackageFilename)[0]
+
+	version, err := UpgradeAgent(pkgDir, PackageSource{Version: "0.1.500"}, "", nil)
+	if err != nil {
+		t.Fatalf("UpgradeAgent returned error: %v", err)
+	}
+	if version != "0.1.500" {
+		t.Errorf("Expected the upgraded version 0.1.500, got %s", version)
+	}
+	expected := []string{
+		"imgpkg pull -i " + ByohAgentDebPackageRepository + ":0.1.500 -o " + filepath.Join(pkgDir, "upgrade"),
+		"systemctl stop " + ByohAgentServiceName + ".service",
+		"dpkg -i " + currentPackage,
+		"systemctl daemon-reload",
+		"systemctl restart " + ByohAgentServiceName + ".service",
+	}
+	commands := strings.Join(runner.Commands(), "\n")
+	last := -1
+	for _, command := range expected {
+		i := strings.Index(commands, command)
+		if i <= last {
+			t.Fatalf("Expected the commands in the order\n%s\ngot\n%s", strings.Join(expected, "\n"), commands)
+		}
+		last = i
+	}
+	if content, err := os.ReadFile(currentPackage); err != nil || string(content) != fakeplane.FakePackage {
+		t.Errorf("Expected the new package to replace the previous one, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(pkgDir, "upgrade")); !os.IsNotExist(err) {
+		t.Errorf("Expected the pulled package to be removed, got %v", err)
+	}
+}
+
+// Test the rollback of a failed UpgradeAgent reinstalls the previous agent package and restarts the agent
+func TestUpgradeAgentRollback(t *testing.T) {
+	runner := useFakeHost(t)
+	runner.Set("dpkg-query -W", "0.1.450", nil)
+	pkgDir := t.TempDir()
+	currentPackage := writePackageFiles(t, pkgDir, ByohAgentDebPackageFilename)[0]
+	previous, _ := os.ReadFile(currentPackage)
+	runner.Set("dpkg -i "+currentPackage, "dpkg: error processing archive", fmt.Errorf("exit status 1"))
+	rollback := &utils.Rollback{}
+
+	if _, err := UpgradeAgent(pkgDir, PackageSource{}, "", rollback); !errors.Is(err, types.ErrPackageInstall) {
+		t.Fatalf("Expected the new agent package to fail to install, got: %v", err)
+	}
+	if err := rollback.Run(); err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	rollbackPackage := filepath.Join(pkgDir, "previous-"+ByohAgentDebPackageFilename)
+	if !runner.Ran("dpkg -i "+rollbackPackage) || !strings.HasSuffix(strings.Join(runner.Commands(), "\n"), "systemctl restart "+ByohAgentServiceName+".service") {
+		t.Errorf("Expected the previous package to be reinstalled and the agent restarted, got %v", runner.Commands())
+	}
+	if content, err := os.ReadFile(currentPackage); err != nil || string(content) != string(previous) {
+		t.Errorf("Expected the previous package to be restored, got %q, %v", content, err)
+	}
+	if _, err := os.Stat(rollbackPackage); !os.IsNotExist(err) {
+		t.Errorf("Expected the reinstalled package to be removed, got %v", err)
+	}
+}
+
+// Test UpgradeAgent fails before stopping the agent if it cannot roll back or the new package is not verified
+func TestUpgradeAgentErrors(t *testing.T) {
+	t.Run("not installed", func(t *testing.T) {
+		runner := useFakeHost(t)
+		runner.Set("dpkg-query -W", "", fmt.Errorf("exit status 1"))
+		if _, err := UpgradeAgent(t.TempDir(), PackageSource{}, "", nil); !errors.Is(err, types.ErrNotOnboarded) {
+			t.Errorf("Expected ErrNotOnboarded, got %v", err)
+		}
+	})
+	t.Run("no previous package", func(t *testing.T) {
+		runner := useFakeHost(t)
+		runner.Set("dpkg-query -W", "0.1.450", nil)
+		if _, err := UpgradeAgent(t.TempDir(), PackageSource{}, "", nil); err == nil || !strings.Contains(err.Error(), "--previous-package-file") {
+			t.Errorf("Expected the previous package to be missing, got %v", err)
+		}
+		if runner.Ran("systemctl stop") {
+			t.Errorf("Expected the agent not to be stopped")
+		}
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		runner := useFakeHost(t)
+		runner.Set("dpkg-query -W", "0.1.450", nil)
+		pkgDir := t.TempDir()
+		writePackageFiles(t, pkgDir, ByohAgentDebPackageFilename)
+		if _, err := UpgradeAgent(pkgDir, PackageSource{Checksum: strings.Repeat("0", 64)}, "", nil); !errors.Is(err, types.ErrVerify) {
+			t.Errorf("Expected ErrVerify, got %v", err)
+		}
+		if runner.Ran("systemctl stop") {
+			t.Errorf("Expected the agent not to be stopped")
+		}
+	})
+}
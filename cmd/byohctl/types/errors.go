@@ -0,0 +1,34 @@
+package types
+
+import "errors"
+
+// The errors byohctl fails with are wrapping one of these errors, callers classify the
+// failures with errors.Is instead of matching the messages
+var (
+	// ErrAuth is returned when the management plane rejects the credentials of the user
+	ErrAuth = errors.New("authentication failed")
+	// ErrRegionUnavailable is returned when the region to onboard the host to is not available for the tenant
+	ErrRegionUnavailable = errors.New("region is not available for the tenant")
+	// ErrPackageInstall is returned when the agent package or one of its required packages fails to install
+	ErrPackageInstall = errors.New("failed to install package")
+	// ErrNotOnboarded is returned when an operation requires the host to be onboarded and it is not
+	ErrNotOnboarded = errors.New("host is not onboarded")
+	// ErrHostNotAttached is returned when an operation requires the host to be attached to a cluster and it is not
+	ErrHostNotAttached = errors.New("host is not attached to a cluster")
+	// ErrCriticalWorkloads is returned when the host runs workloads that must not be disrupted and --force is not set
+	ErrCriticalWorkloads = errors.New("host runs critical workloads")
+	// ErrMFARequired is returned when the account of the user requires a second factor and no TOTP code was given
+	ErrMFARequired = errors.New("a second factor is required")
+	// ErrNoCredentials is returned when byohctl login has not stored the credentials of a user
+	ErrNoCredentials = errors.New("no credentials are stored, log in with byohctl login")
+	// ErrUsage is returned when the flags or the arguments of a command are invalid
+	ErrUsage = errors.New("invalid usage")
+	// ErrDownload is returned when the agent package or a tool byohctl needs fails to download
+	ErrDownload = errors.New("failed to download")
+	// ErrVerify is returned when the agent package does not match its checksum or its signature
+	ErrVerify = errors.New("failed to verify")
+	// ErrCancelled is returned when the user declined to continue an operation
+	ErrCancelled = errors.New("cancelled by the user")
+	// ErrHostAttached is returned when an operation requires the host not to be attached to a cluster and it is
+	ErrHostAttached = errors.New("host is attached to a cluster")
+)
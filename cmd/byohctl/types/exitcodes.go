@@ -0,0 +1,75 @@
+package types
+
+import "errors"
+
+// The exit codes of byohctl, orchestration tools branch on them instead of scraping the logs. A
+// code keeps its meaning across releases, a new category of failures gets a new code.
+const (
+	// ExitOK is the exit code of a command that succeeded
+	ExitOK = 0
+	// ExitFailure is the exit code of the failures of no other category
+	ExitFailure = 1
+	// ExitUsage is the exit code of invalid or missing flags, arguments and config files
+	ExitUsage = 2
+	// ExitAuth is the exit code of the credentials the management plane rejects, or of no credentials
+	ExitAuth = 3
+	// ExitRegionUnavailable is the exit code of a region that is not available for the tenant
+	ExitRegionUnavailable = 4
+	// ExitPreflight is the exit code of a host that fails its preflight checks or the requirements of a cluster
+	ExitPreflight = 5
+	// ExitDownload is the exit code of the agent package or a tool that fails to download
+	ExitDownload = 6
+	// ExitInstall is the exit code of the agent package or a required package that fails to install
+	ExitInstall = 7
+	// ExitAlreadyOnboarded is the exit code of onboarding a host that is already onboarded
+	ExitAlreadyOnboarded = 8
+	// ExitNotOnboarded is the exit code of an operation on a host that is not onboarded
+	ExitNotOnboarded = 9
+	// ExitHostNotAttached is the exit code of an operation on a host that is not attached to a cluster
+	ExitHostNotAttached = 10
+	// ExitCriticalWorkloads is the exit code of removing a host that runs critical workloads without --force
+	ExitCriticalWorkloads = 11
+	// ExitMFARequired is the exit code of an account with a second factor and no TOTP code to answer it
+	ExitMFARequired = 12
+	// ExitCancelled is the exit code of an operation the user declined to continue
+	ExitCancelled = 13
+	// ExitVerify is the exit code of an agent package that does not match its checksum or its signature
+	ExitVerify = 14
+	// ExitHostAttached is the exit code of moving a host that is attached to a cluster
+	ExitHostAttached = 15
+)
+
+// exitCodes are the exit codes of the errors, the first error a failure wraps decides its code
+var exitCodes = []struct {
+	err  error
+	code int
+}{
+	{ErrUsage, ExitUsage},
+	// the second factor is an authentication failure too
+	{ErrMFARequired, ExitMFARequired},
+	{ErrAuth, ExitAuth},
+	{ErrNoCredentials, ExitAuth},
+	{ErrRegionUnavailable, ExitRegionUnavailable},
+	// the download of imgpkg fails its installation too
+	{ErrDownload, ExitDownload},
+	{ErrPackageInstall, ExitInstall},
+	{ErrNotOnboarded, ExitNotOnboarded},
+	{ErrHostNotAttached, ExitHostNotAttached},
+	{ErrCriticalWorkloads, ExitCriticalWorkloads},
+	{ErrCancelled, ExitCancelled},
+	{ErrVerify, ExitVerify},
+	{ErrHostAttached, ExitHostAttached},
+}
+
+// ExitCode returns the exit code of a command failing with err, ExitOK if err is nil
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	for _, exitCode := range exitCodes {
+		if errors.Is(err, exitCode.err) {
+			return exitCode.code
+		}
+	}
+	return ExitFailure
+}
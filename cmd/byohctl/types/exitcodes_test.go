@@ -0,0 +1,38 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", err: nil, want: ExitOK},
+		{name: "uncategorized", err: errors.New("failed to lock the host"), want: ExitFailure},
+		{name: "usage", err: fmt.Errorf("%w: unknown flag: --foo", ErrUsage), want: ExitUsage},
+		{name: "auth", err: fmt.Errorf("failed to get token: %w", ErrAuth), want: ExitAuth},
+		{name: "second factor", err: fmt.Errorf("%w: %w for user admin", ErrAuth, ErrMFARequired), want: ExitMFARequired},
+		{name: "no credentials", err: ErrNoCredentials, want: ExitAuth},
+		{name: "region", err: fmt.Errorf("%w: region-two", ErrRegionUnavailable), want: ExitRegionUnavailable},
+		{name: "download of imgpkg", err: fmt.Errorf("%w imgpkg: %w", ErrPackageInstall, fmt.Errorf("%w imgpkg", ErrDownload)), want: ExitDownload},
+		{name: "install", err: fmt.Errorf("%w socat", ErrPackageInstall), want: ExitInstall},
+		{name: "not onboarded", err: fmt.Errorf("%w: no kubeconfig", ErrNotOnboarded), want: ExitNotOnboarded},
+		{name: "not attached", err: ErrHostNotAttached, want: ExitHostNotAttached},
+		{name: "critical workloads", err: ErrCriticalWorkloads, want: ExitCriticalWorkloads},
+		{name: "cancelled", err: fmt.Errorf("de-auth %w", ErrCancelled), want: ExitCancelled},
+		{name: "verify", err: fmt.Errorf("%w the agent package: checksum mismatch", ErrVerify), want: ExitVerify},
+		{name: "attached", err: fmt.Errorf("%w: machine md-0-abcde", ErrHostAttached), want: ExitHostAttached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package types
 
 type TokenResponse struct {
-    IDToken string `json:"id_token"`
+    IDToken     string `json:"id_token"`
+    AccessToken string `json:"access_token"`
+    // ExpiresIn is the lifetime of the tokens in seconds
+    ExpiresIn   int64  `json:"expires_in"`
 }
 
 type Secret struct {
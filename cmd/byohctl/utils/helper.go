@@ -2,13 +2,31 @@ package utils
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 )
 
+var (
+	// AssumeYes answers yes to the questions of AskBool without asking them, set by --yes
+	AssumeYes bool
+	// NonInteractive fails the questions of AskBool instead of asking them, set by --non-interactive
+	NonInteractive bool
+)
+
+// ErrNonInteractive is returned by AskBool for a question it cannot ask with NonInteractive
+var ErrNonInteractive = errors.New("confirmation required in non-interactive mode, rerun with --yes to confirm")
+
 // AskBool function asks for the user input
 // for a boolean input
 func AskBool(msg string, args ...interface{}) (bool, error) {
+	if AssumeYes {
+		LogInfo("%s: y (--yes)", fmt.Sprintf(msg, args...))
+		return true, nil
+	}
+	if NonInteractive {
+		return false, fmt.Errorf("%s: %w", fmt.Sprintf(msg, args...), ErrNonInteractive)
+	}
 	_, err := fmt.Fprintf(os.Stdout, fmt.Sprintf("%s: ", msg), args...)
 	if err != nil {
 		return false, fmt.Errorf("Unable to show options to user: %s", err.Error())
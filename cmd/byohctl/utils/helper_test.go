@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAskBoolUnattended(t *testing.T) {
+	defer func() { AssumeYes, NonInteractive = false, false }()
+
+	AssumeYes = true
+	if ok, err := AskBool("Do you want to continue with de-auth? (y/n)"); !ok || err != nil {
+		t.Errorf("Expected --yes to answer yes, got %v, %v", ok, err)
+	}
+
+	// --yes answers the questions --non-interactive would fail
+	NonInteractive = true
+	if ok, err := AskBool("Do you want to continue with de-auth? (y/n)"); !ok || err != nil {
+		t.Errorf("Expected --yes to answer yes in non-interactive mode, got %v, %v", ok, err)
+	}
+
+	AssumeYes = false
+	if ok, err := AskBool("Do you want to continue with de-auth? (y/n)"); ok || !errors.Is(err, ErrNonInteractive) {
+		t.Errorf("Expected a non-interactive question to fail, got %v, %v", ok, err)
+	}
+}
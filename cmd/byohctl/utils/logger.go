@@ -18,6 +18,9 @@ const (
 	LevelError   = "ERROR"
 )
 
+// DebugLogFilename is the name of the debug log of byohctl in its log directory
+const DebugLogFilename = "byoh-agent-debug.log"
+
 // Console output levels
 const (
 	ConsoleOutputAll      = "all"      // Show all log messages
@@ -33,6 +36,8 @@ var (
 
 	// File handle for logger
 	debugLogFile *os.File
+	// Path of the debug log file
+	debugLogPath string
 
 	// Console output configuration
 	consoleOutputEnabled = true
@@ -47,7 +52,7 @@ func InitLoggers(logDir string, debugEnabled bool) error {
 	}
 
 	// Define log file path - only use a single debug file
-	debugLogPath := filepath.Join(logDir, "byoh-agent-debug.log")
+	debugLogPath = filepath.Join(logDir, DebugLogFilename)
 	
 	// Always create a new log file when the command is run
 	// Open debug log file with truncate flag to overwrite any existing content
@@ -124,7 +129,7 @@ func shouldShowOnConsole(level string) bool {
 
 // LogDebug logs a debug message to the debug log file
 func LogDebug(format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := Redact(fmt.Sprintf(format, args...))
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, LevelDebug, message)
 
@@ -141,7 +146,7 @@ func LogDebug(format string, args ...interface{}) {
 
 // LogInfo logs an info message to the debug log file
 func LogInfo(format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := Redact(fmt.Sprintf(format, args...))
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, LevelInfo, message)
 
@@ -158,7 +163,7 @@ func LogInfo(format string, args ...interface{}) {
 
 // LogSuccess logs a success message to the debug log file
 func LogSuccess(format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := Redact(fmt.Sprintf(format, args...))
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, LevelSuccess, message)
 
@@ -175,7 +180,7 @@ func LogSuccess(format string, args ...interface{}) {
 
 // LogWarn logs a warning message to the debug log file
 func LogWarn(format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := Redact(fmt.Sprintf(format, args...))
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, LevelWarning, message)
 
@@ -192,7 +197,7 @@ func LogWarn(format string, args ...interface{}) {
 
 // LogError logs an error message to the debug log file
 func LogError(format string, args ...interface{}) {
-	message := fmt.Sprintf(format, args...)
+	message := Redact(fmt.Sprintf(format, args...))
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	logMessage := fmt.Sprintf("[%s] [%s] %s", timestamp, LevelError, message)
 
@@ -207,10 +212,12 @@ func LogError(format string, args ...interface{}) {
 	}
 }
 
-// LogErrorf logs an error message and returns an error with the same message
+// LogErrorf logs an error message and returns an error with the same message, both redacted.
+// Like fmt.Errorf, the error wraps the errors of the %w verbs of format.
 func LogErrorf(format string, args ...interface{}) error {
-	LogError(format, args...)
-	return fmt.Errorf(format, args...)
+	err := fmt.Errorf(format, args...)
+	LogError("%s", err.Error())
+	return RedactError(err)
 }
 
 // TrackTime logs the time taken for an operation
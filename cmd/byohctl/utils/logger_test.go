@@ -2,6 +2,7 @@
 package utils
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -185,3 +186,15 @@ func TestTimeTracking(t *testing.T) {
 		t.Errorf("Time tracking message not found in debug log")
 	}
 }
+
+func TestLogErrorfWrapsErrors(t *testing.T) {
+	sentinel := errors.New("authentication failed")
+	err := LogErrorf("%w with status %d: %s", sentinel, 401, `{"password":"hunter2"}`)
+
+	if !errors.Is(err, sentinel) {
+		t.Errorf("Expected the error to wrap %v, got %v", sentinel, err)
+	}
+	if strings.Contains(err.Error(), "hunter2") || !strings.HasPrefix(err.Error(), "authentication failed with status 401") {
+		t.Errorf("Expected a redacted error message, got %q", err.Error())
+	}
+}
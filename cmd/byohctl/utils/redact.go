@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"regexp"
+)
+
+// Redacted replaces the secrets masked by Redact
+const Redacted = "[REDACTED]"
+
+// the keys of the credentials, in JSON, YAML, form and query encodings
+const secretKeys = `[a-z_-]*token|password|passwd|client[_-]secret|client-key-data|client-certificate-data|certificate-authority-data`
+
+// redactions mask the secrets in log messages, in order
+var redactions = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// the data of a Kubernetes secret, e.g. the bootstrap kubeconfig
+	{regexp.MustCompile(`("data"\s*:\s*)\{[^{}]*\}`), `${1}{"` + Redacted + `"}`},
+	// "password": "..." in JSON bodies, e.g. the errors of dex
+	{regexp.MustCompile(`(?i)("(?:` + secretKeys + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`), `${1}"` + Redacted + `"`},
+	// token: ... in YAML, e.g. kubeconfigs
+	{regexp.MustCompile(`(?im)^(\s*(?:- )?(?:` + secretKeys + `)\s*:\s*)\S.*$`), `${1}` + Redacted},
+	// password=... in forms and query strings
+	{regexp.MustCompile(`(?i)\b((?:` + secretKeys + `)=)[^&\s"]+`), `${1}` + Redacted},
+	// Authorization: Bearer ...
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9\-._~+/]+=*`), `${1} ` + Redacted},
+	// JWTs anywhere else, e.g. the id tokens of dex
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Redacted},
+}
+
+// Redact masks the bearer tokens, passwords, client secrets and kubeconfig credentials in message,
+// the log messages and the errors of byohctl are redacted before they are written
+func Redact(message string) string {
+	for _, r := range redactions {
+		message = r.pattern.ReplaceAllString(message, r.replacement)
+	}
+	return message
+}
+
+// redactedError is an error whose message is redacted, it wraps the original error
+type redactedError struct {
+	message string
+	err     error
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError returns err with its message redacted, errors.Is and errors.As still see err
+func RedactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	return &redactedError{message: message, err: err}
+}
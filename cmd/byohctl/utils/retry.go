@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Backoff is how byohctl retries the network operations that fail, so that a flaky network does
+// not fail a whole onboarding
+type Backoff struct {
+	// Attempts is the number of attempts of an operation, 1 does not retry it
+	Attempts int
+	// BaseDelay is the delay before the first retry, doubled before each next retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+}
+
+// NetworkBackoff is the backoff of the requests to the management plane and of the downloads of
+// the agent package and its tools, set by the --retries, --retry-delay and --retry-max-delay flags
+var NetworkBackoff = Backoff{Attempts: 4, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// Delay returns the delay before the retry of an operation after its failed attempt attempt,
+// counted from 1
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := b.BaseDelay
+	for i := 1; i < attempt && delay < b.MaxDelay; i++ {
+		delay *= 2
+	}
+	if b.MaxDelay > 0 && delay > b.MaxDelay {
+		return b.MaxDelay
+	}
+	return delay
+}
+
+// permanentError is an error Retry does not retry
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as an error retrying the operation cannot fix, e.g. rejected credentials
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// Retry runs op, the operation what, until it succeeds, fails with a Permanent error, has run
+// b.Attempts times or ctx is done, and returns the error of its last attempt
+func Retry(ctx context.Context, b Backoff, what string, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		var permanent permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= b.Attempts || ctx.Err() != nil {
+			return err
+		}
+		delay := b.Delay(attempt)
+		LogDebug("%s failed, attempt %d of %d, retrying in %s: %v", what, attempt, b.Attempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Attempts: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := b.Delay(i + 1); got != want {
+			t.Errorf("Expected delay %s after attempt %d, got %s", want, i+1, got)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	b := Backoff{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	errFlaky := errors.New("connection reset by peer")
+
+	attempts := 0
+	err := Retry(context.Background(), b, "pull", func() error {
+		attempts++
+		if attempts < 3 {
+			return errFlaky
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success after 3 attempts, got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	err = Retry(context.Background(), b, "pull", func() error {
+		attempts++
+		return errFlaky
+	})
+	if !errors.Is(err, errFlaky) || attempts != 3 {
+		t.Errorf("Expected the error of the last of 3 attempts, got %v after %d", err, attempts)
+	}
+
+	// a permanent error is not retried and keeps its cause
+	attempts = 0
+	err = Retry(context.Background(), b, "login", func() error {
+		attempts++
+		return Permanent(errFlaky)
+	})
+	if !errors.Is(err, errFlaky) || attempts != 1 {
+		t.Errorf("Expected a single attempt for a permanent error, got %v after %d", err, attempts)
+	}
+
+	// a done context stops the retries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = Retry(ctx, Backoff{Attempts: 3, BaseDelay: time.Hour}, "pull", func() error {
+		attempts++
+		return errFlaky
+	})
+	if !errors.Is(err, errFlaky) || attempts != 1 {
+		t.Errorf("Expected a single attempt with a done context, got %v after %d", err, attempts)
+	}
+}
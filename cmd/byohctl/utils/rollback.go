@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Rollback undoes the completed steps of an operation that fails midway, so that the host is left
+// as it was before the operation. The steps add their undo as they complete, a nil Rollback
+// ignores them.
+type Rollback struct {
+	steps []rollbackStep
+}
+
+type rollbackStep struct {
+	description string
+	undo        func() error
+}
+
+// Add adds undo to undo a completed step, description says what it does, e.g. "remove /etc/foo"
+func (r *Rollback) Add(description string, undo func() error) {
+	if r == nil {
+		return
+	}
+	r.steps = append(r.steps, rollbackStep{description: description, undo: undo})
+}
+
+// RestoreFile adds the restore of the current content of the file path, or its removal if it does
+// not exist yet. It is called before the step that writes the file.
+func (r *Rollback) RestoreFile(path string) error {
+	if r == nil {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		r.Add("remove "+path, func() error {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	r.Add("restore "+path, func() error { return os.WriteFile(path, content, info.Mode().Perm()) })
+	return nil
+}
+
+// RemoveDir adds the removal of the directory path and of its content if it does not exist yet. It
+// is called before the step that creates the directory.
+func (r *Rollback) RemoveDir(path string) {
+	if r == nil {
+		return
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		r.Add("remove "+path, func() error { return os.RemoveAll(path) })
+	}
+}
+
+// Include adds the steps of other after those added so far, so that they are undone first, and
+// empties other
+func (r *Rollback) Include(other *Rollback) {
+	if r == nil || other == nil {
+		return
+	}
+	r.steps = append(r.steps, other.steps...)
+	other.steps = nil
+}
+
+// Run undoes the steps added so far, the last completed first. A step that fails to undo does not
+// stop the others, Run returns the errors of all of them.
+func (r *Rollback) Run() error {
+	if r == nil {
+		return nil
+	}
+	var errs []error
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			LogError("Failed to roll back: %s: %v", step.description, err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.description, err))
+			continue
+		}
+		RecordStep(HostChanged, "Rolled back: %s", step.description)
+	}
+	r.steps = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "region")
+	if err := os.WriteFile(existing, []byte("region-one"), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", existing, err)
+	}
+	created := filepath.Join(dir, "taints")
+	pkgDir := filepath.Join(dir, "packages")
+
+	var rollback Rollback
+	var undone []string
+	rollback.Add("first step", func() error {
+		undone = append(undone, "first step")
+		return nil
+	})
+	if err := rollback.RestoreFile(existing); err != nil {
+		t.Fatalf("RestoreFile returned error: %v", err)
+	}
+	if err := rollback.RestoreFile(created); err != nil {
+		t.Fatalf("RestoreFile returned error: %v", err)
+	}
+	rollback.RemoveDir(pkgDir)
+	rollback.RemoveDir(dir)
+	errUndo := errors.New("dpkg: error processing package")
+	rollback.Add("failing step", func() error { return errUndo })
+	rollback.Add("last step", func() error {
+		undone = append(undone, "last step")
+		return nil
+	})
+
+	// the steps change the host
+	for path, content := range map[string]string{existing: "region-two", created: "dedicated=gpu:NoSchedule"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(pkgDir, "downloads"), 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", pkgDir, err)
+	}
+
+	if err := rollback.Run(); !errors.Is(err, errUndo) {
+		t.Errorf("Expected the error of the failing step, got %v", err)
+	}
+	if !reflect.DeepEqual(undone, []string{"last step", "first step"}) {
+		t.Errorf("Expected the steps to be undone last first, got %v", undone)
+	}
+	content, err := os.ReadFile(existing)
+	if err != nil || string(content) != "region-one" {
+		t.Errorf("Expected %s to be restored, got %q, %v", existing, content, err)
+	}
+	if info, err := os.Stat(existing); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the mode of %s to be restored, got %v, %v", existing, info.Mode(), err)
+	}
+	for _, path := range []string{created, pkgDir} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("Expected the existing %s to be kept, got %v", dir, err)
+	}
+
+	// the steps are undone once, a nil rollback does nothing
+	if err := rollback.Run(); err != nil {
+		t.Errorf("Expected nothing left to roll back, got %v", err)
+	}
+	var none *Rollback
+	none.Add("ignored", func() error { return errUndo })
+	if err := none.Run(); err != nil {
+		t.Errorf("Expected a nil rollback to do nothing, got %v", err)
+	}
+}
+
+func TestRollbackInclude(t *testing.T) {
+	var undone []string
+	undo := func(step string) func() error {
+		return func() error {
+			undone = append(undone, step)
+			return nil
+		}
+	}
+	var rollback, step Rollback
+	rollback.Add("first step", undo("first step"))
+	step.Add("included step", undo("included step"))
+	rollback.Include(&step)
+	rollback.Add("last step", undo("last step"))
+
+	if err := step.Run(); err != nil || len(undone) != 0 {
+		t.Errorf("Expected the included steps to be moved, got %v, %v", undone, err)
+	}
+	if err := rollback.Run(); err != nil {
+		t.Errorf("Run returned error: %v", err)
+	}
+	if !reflect.DeepEqual(undone, []string{"last step", "included step", "first step"}) {
+		t.Errorf("Expected the included steps to be undone in order, got %v", undone)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// StepEventKind is what a step event of a command reports
+type StepEventKind int
+
+const (
+	// HostChanged is a change a step made to the host, e.g. a package installed
+	HostChanged StepEventKind = iota
+	// PlaneObject is an object of the management plane a step created, changed or deleted
+	PlaneObject
+	// LogLocation is where the logs of what a step set up are
+	LogLocation
+	// NextStep is a command the user may run next
+	NextStep
+)
+
+// summarySections are the titles of the sections of the summary, by the kind of their events
+var summarySections = []struct {
+	kind  StepEventKind
+	title string
+}{
+	{HostChanged, "Changed on this host"},
+	{PlaneObject, "Management plane"},
+	{LogLocation, "Logs"},
+	{NextStep, "Next steps"},
+}
+
+// StepEvent is reported by a step of a command, the summary of the command is generated from them
+type StepEvent struct {
+	Kind    StepEventKind
+	Message string
+}
+
+var (
+	stepEventsMu sync.Mutex
+	stepEvents   []StepEvent
+
+	// summaryOutput is where PrintSummary prints the summary
+	summaryOutput io.Writer = os.Stdout
+)
+
+// RecordStep records a step event for the summary of the command and logs it to the debug log
+func RecordStep(kind StepEventKind, format string, args ...interface{}) {
+	message := Redact(fmt.Sprintf(format, args...))
+	LogDebug("%s", message)
+
+	stepEventsMu.Lock()
+	defer stepEventsMu.Unlock()
+	stepEvents = append(stepEvents, StepEvent{Kind: kind, Message: message})
+}
+
+// StepEvents returns the step events recorded so far
+func StepEvents() []StepEvent {
+	stepEventsMu.Lock()
+	defer stepEventsMu.Unlock()
+	return append([]StepEvent(nil), stepEvents...)
+}
+
+// ResetSteps forgets the step events recorded so far
+func ResetSteps() {
+	stepEventsMu.Lock()
+	defer stepEventsMu.Unlock()
+	stepEvents = nil
+}
+
+// PrintSummary prints the summary block of the command generated from its step events, with the
+// debug log of byohctl, and whether it succeeded. Nothing is printed if the console output level
+// is none.
+func PrintSummary(command string, succeeded bool) {
+	if !consoleOutputEnabled || consoleOutputLevel == ConsoleOutputNone {
+		return
+	}
+	events := StepEvents()
+	if debugLogPath != "" {
+		events = append(events, StepEvent{Kind: LogLocation, Message: "byohctl debug log: " + debugLogPath})
+	}
+
+	status := "succeeded"
+	if !succeeded {
+		status = "failed"
+	}
+	fmt.Fprintf(summaryOutput, "\n===== byohctl %s %s =====\n", command, status)
+	for _, section := range summarySections {
+		var messages []string
+		for _, event := range events {
+			if event.Kind == section.kind {
+				messages = append(messages, event.Message)
+			}
+		}
+		if len(messages) == 0 {
+			continue
+		}
+		fmt.Fprintf(summaryOutput, "%s:\n", section.title)
+		for _, message := range messages {
+			fmt.Fprintf(summaryOutput, "  - %s\n", message)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintSummary(t *testing.T) {
+	var out bytes.Buffer
+	origOutput, origLogPath := summaryOutput, debugLogPath
+	origEnabled, origLevel := consoleOutputEnabled, consoleOutputLevel
+	summaryOutput, debugLogPath = &out, "/root/.byoh/byoh-agent-debug.log"
+	consoleOutputEnabled, consoleOutputLevel = true, ConsoleOutputMinimal
+	defer func() {
+		summaryOutput, debugLogPath = origOutput, origLogPath
+		consoleOutputEnabled, consoleOutputLevel = origEnabled, origLevel
+		ResetSteps()
+	}()
+
+	ResetSteps()
+	RecordStep(NextStep, "byohctl onboard to onboard the host again")
+	RecordStep(HostChanged, "Installed package %s", "socat")
+	RecordStep(PlaneObject, "Deleted the ByoHost of the host from namespace %s", "tenant-ns")
+	RecordStep(HostChanged, "Purged the %s agent package", "pf9-byohost-agent")
+	PrintSummary("decommission", true)
+
+	expected := `
+===== byohctl decommission succeeded =====
+Changed on this host:
+  - Installed package socat
+  - Purged the pf9-byohost-agent agent package
+Management plane:
+  - Deleted the ByoHost of the host from namespace tenant-ns
+Logs:
+  - byohctl debug log: /root/.byoh/byoh-agent-debug.log
+Next steps:
+  - byohctl onboard to onboard the host again
+`
+	if out.String() != expected {
+		t.Errorf("Expected summary\n%s\ngot\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	ResetSteps()
+	PrintSummary("preflight", false)
+	if expected := "\n===== byohctl preflight failed =====\nLogs:\n  - byohctl debug log: /root/.byoh/byoh-agent-debug.log\n"; out.String() != expected {
+		t.Errorf("Expected summary %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	consoleOutputLevel = ConsoleOutputNone
+	PrintSummary("preflight", true)
+	if out.Len() != 0 {
+		t.Errorf("Expected no summary without console output, got %q", out.String())
+	}
+}
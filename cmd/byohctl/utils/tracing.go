@@ -0,0 +1,160 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OTLPEndpointEnv is the environment variable with the endpoint of the OpenTelemetry collector
+	OTLPEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TraceParentEnv is the environment variable the agent reads the W3C traceparent of byohctl from
+	TraceParentEnv = "TRACEPARENT"
+	// HostNameKey is the span attribute with the name of the ByoHost, the spans of byohctl,
+	// the agent and the controllers for a host are correlated by it
+	HostNameKey = "byoh.host.name"
+
+	tracesPath    = "/v1/traces"
+	exportTimeout = 10 * time.Second
+)
+
+var (
+	tracingEndpoint string
+	tracingService  string
+	endedSpans      []*Span
+)
+
+// Span is a traced step of a command. The methods of a nil Span, returned when
+// tracing is off, do nothing.
+type Span struct {
+	name     string
+	traceID  string
+	spanID   string
+	parentID string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+}
+
+// InitTracing exports the spans of service to the OpenTelemetry collector at endpoint,
+// e.g. http://otel-collector:4318, once FlushTraces is called. An empty endpoint turns
+// tracing off.
+func InitTracing(service, endpoint string) {
+	tracingService = service
+	tracingEndpoint = strings.TrimSuffix(endpoint, "/")
+	endedSpans = nil
+}
+
+// StartSpan starts a span named name, child of parent unless parent is nil
+func StartSpan(name string, parent *Span) *Span {
+	if tracingEndpoint == "" {
+		return nil
+	}
+	span := &Span{name: name, spanID: randomHex(8), start: time.Now(), attrs: map[string]string{}}
+	if parent != nil {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+	return span
+}
+
+// SetAttribute sets the attribute key of the span to value
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// End ends the span, failed if err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	endedSpans = append(endedSpans, s)
+}
+
+// TraceParent returns the W3C traceparent of the span, or an empty string if tracing is off
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.traceID, s.spanID)
+}
+
+// FlushTraces sends the ended spans to the collector
+func FlushTraces() error {
+	if tracingEndpoint == "" || len(endedSpans) == 0 {
+		return nil
+	}
+	spans := make([]map[string]interface{}, 0, len(endedSpans))
+	for _, s := range endedSpans {
+		attrs := make([]map[string]interface{}, 0, len(s.attrs))
+		for key, value := range s.attrs {
+			attrs = append(attrs, map[string]interface{}{"key": key, "value": map[string]interface{}{"stringValue": value}})
+		}
+		span := map[string]interface{}{
+			"traceId":           s.traceID,
+			"spanId":            s.spanID,
+			"name":              s.name,
+			"kind":              1,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attrs,
+		}
+		if s.parentID != "" {
+			span["parentSpanId"] = s.parentID
+		}
+		if s.err != nil {
+			span["status"] = map[string]interface{}{"code": 2, "message": Redact(s.err.Error())}
+		}
+		spans = append(spans, span)
+	}
+	endedSpans = nil
+
+	// OTLP/HTTP JSON encoding, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{"attributes": []map[string]interface{}{
+				{"key": "service.name", "value": map[string]interface{}{"stringValue": tracingService}},
+			}},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]interface{}{"name": "github.com/platform9/cluster-api-provider-bringyourownhost/cmd/byohctl"},
+				"spans": spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: exportTimeout}
+	resp, err := client.Post(tracingEndpoint+tracesPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to export traces: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to export traces: collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestTracingOff(t *testing.T) {
+	InitTracing("byohctl", "")
+	span := StartSpan("byohctl.onboard", nil)
+	if span != nil {
+		t.Fatalf("Expected no span with tracing off, got %v", span)
+	}
+	span.SetAttribute(HostNameKey, "host")
+	span.End(nil)
+	if span.TraceParent() != "" {
+		t.Errorf("Expected no traceparent with tracing off, got %s", span.TraceParent())
+	}
+	if err := FlushTraces(); err != nil {
+		t.Errorf("FlushTraces returned error with tracing off: %v", err)
+	}
+}
+
+func TestFlushTraces(t *testing.T) {
+	type otlpSpan struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Status       struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+	}
+	var received []otlpSpan
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected traces to be sent to /v1/traces, got %s", r.URL.Path)
+		}
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode traces: %v", err)
+			return
+		}
+		received = req.ResourceSpans[0].ScopeSpans[0].Spans
+	}))
+	defer server.Close()
+
+	InitTracing("byohctl", server.URL+"/")
+	onboardSpan := StartSpan("byohctl.onboard", nil)
+	span := StartSpan("byohctl.authenticate", onboardSpan)
+	if !regexp.MustCompile("^00-[0-9a-f]{32}-[0-9a-f]{16}-01$").MatchString(span.TraceParent()) {
+		t.Errorf("Invalid traceparent %s", span.TraceParent())
+	}
+	span.End(errors.New("invalid credentials"))
+	onboardSpan.End(nil)
+	if err := FlushTraces(); err != nil {
+		t.Fatalf("FlushTraces returned error: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(received))
+	}
+	authenticate, onboard := received[0], received[1]
+	if authenticate.TraceID != onboard.TraceID || authenticate.ParentSpanID != onboard.SpanID {
+		t.Errorf("Expected byohctl.authenticate to be a child of byohctl.onboard, got %+v and %+v", authenticate, onboard)
+	}
+	if authenticate.Status.Code != 2 || authenticate.Status.Message != "invalid credentials" {
+		t.Errorf("Expected byohctl.authenticate to be failed, got %+v", authenticate.Status)
+	}
+	if onboard.ParentSpanID != "" {
+		t.Errorf("Expected byohctl.onboard to be a root span, got parent %s", onboard.ParentSpanID)
+	}
+}
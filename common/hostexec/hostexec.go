@@ -0,0 +1,114 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostexec runs the commands byohctl and the agent run on the host. Every command is
+// bounded by the timeout of its Exec and reported, redacted, to the hooks of the Exec, so both
+// time out and log their commands the same way.
+package hostexec
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Runner runs commands on the host, hostexectest.Fake replaces it in tests
+type Runner interface {
+	// Output runs the command and returns its standard output
+	Output(ctx context.Context, name string, args ...string) ([]byte, error)
+	// CombinedOutput runs the command and returns its standard output and standard error
+	CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error)
+	// Script runs script with bash, its output goes to the standard output and error of the process
+	Script(ctx context.Context, script string) error
+	// LookPath searches for the executable named name in the PATH
+	LookPath(name string) (string, error)
+}
+
+// Event is a command an Exec ran, reported to its hooks
+type Event struct {
+	// Command is the command line, redacted
+	Command string
+	// Duration is how long the command ran
+	Duration time.Duration
+	// Err is the error the command failed with, nil if it succeeded
+	Err error
+}
+
+// Hook is called with the event of every command an Exec ran
+type Hook func(Event)
+
+// Exec is the Runner running the commands on the host, its zero value runs them without a
+// timeout and without reporting them
+type Exec struct {
+	// Timeout bounds every command on top of the deadline of its context, zero does not bound them
+	Timeout time.Duration
+	// Redact masks the secrets of the command lines reported to the hooks, nil reports them as is
+	Redact func(string) string
+	// Hooks are called after every command
+	Hooks []Hook
+}
+
+var _ Runner = Exec{}
+
+func (e Exec) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	e.report(commandLine(name, args), start, err)
+	return output, err
+}
+
+func (e Exec) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	e.report(commandLine(name, args), start, err)
+	return output, err
+}
+
+func (e Exec) Script(ctx context.Context, script string) error {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	command := exec.CommandContext(ctx, "/bin/bash", "-c", script) // #nosec G204 -- scripts are admin-authored install/bootstrap content, not external/untrusted input
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	err := command.Run()
+	e.report(commandLine("bash", []string{"-c", script}), start, err)
+	return err
+}
+
+func (e Exec) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// withTimeout bounds ctx with the timeout of the Exec
+func (e Exec) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.Timeout)
+}
+
+// report reports the command line that ran since start to the hooks of the Exec
+func (e Exec) report(command string, start time.Time, err error) {
+	if len(e.Hooks) == 0 {
+		return
+	}
+	if e.Redact != nil {
+		command = e.Redact(command)
+	}
+	event := Event{Command: command, Duration: time.Since(start), Err: err}
+	for _, hook := range e.Hooks {
+		hook(event)
+	}
+}
+
+// commandLine returns the command line of the executable name with args
+func commandLine(name string, args []string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
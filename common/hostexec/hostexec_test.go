@@ -0,0 +1,107 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hostexec_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec/hostexectest"
+)
+
+func TestExecOutput(t *testing.T) {
+	var events []hostexec.Event
+	runner := hostexec.Exec{
+		Redact: func(command string) string { return strings.ReplaceAll(command, "secret", "***") },
+		Hooks:  []hostexec.Hook{func(event hostexec.Event) { events = append(events, event) }},
+	}
+
+	output, err := runner.Output(context.Background(), "echo", "-n", "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(output))
+
+	output, err = runner.CombinedOutput(context.Background(), "sh", "-c", "echo out; echo err >&2; exit 3")
+	assert.Error(t, err)
+	assert.Equal(t, "out\nerr\n", string(output))
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "echo -n ***", events[0].Command)
+	assert.NoError(t, events[0].Err)
+	assert.Equal(t, "sh -c echo out; echo err >&2; exit 3", events[1].Command)
+	assert.EqualError(t, events[1].Err, "exit status 3")
+}
+
+func TestExecScript(t *testing.T) {
+	var events []hostexec.Event
+	runner := hostexec.Exec{Hooks: []hostexec.Hook{func(event hostexec.Event) { events = append(events, event) }}}
+
+	require.NoError(t, runner.Script(context.Background(), "true"))
+	assert.EqualError(t, runner.Script(context.Background(), "exit 2"), "exit status 2")
+	require.Len(t, events, 2)
+	assert.Equal(t, "bash -c true", events[0].Command)
+}
+
+func TestExecTimeout(t *testing.T) {
+	runner := hostexec.Exec{Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := runner.Output(context.Background(), "sleep", "10")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// the deadline of the context bounds the command too
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, hostexec.Exec{}.Script(ctx, "sleep 10"))
+}
+
+func TestFake(t *testing.T) {
+	fake := hostexectest.NewFake()
+	fake.Set("systemctl", "", errors.New("exit status 1"))
+	fake.Set("systemctl is-active", "active\n", nil)
+	var pulled []string
+	fake.On("imgpkg pull", func(args []string) error {
+		pulled = args
+		return nil
+	})
+	fake.Missing("imgpkg")
+
+	output, err := fake.Output(context.Background(), "/usr/bin/systemctl", "is-active", "kubelet")
+	require.NoError(t, err)
+	assert.Equal(t, "active\n", string(output))
+	_, err = fake.CombinedOutput(context.Background(), "systemctl", "restart", "kubelet")
+	assert.EqualError(t, err, "exit status 1")
+	require.NoError(t, fake.Script(context.Background(), "imgpkg pull -i image"))
+	assert.Nil(t, pulled, "a script is not split into arguments")
+	_, err = fake.Output(context.Background(), "imgpkg", "pull", "-i", "image")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pull", "-i", "image"}, pulled)
+
+	path, err := fake.LookPath("apt-get")
+	require.NoError(t, err)
+	assert.Equal(t, "/usr/bin/apt-get", path)
+	_, err = fake.LookPath("imgpkg")
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = fake.Output(ctx, "apt-get", "update")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.True(t, fake.Ran("apt-get update"))
+	assert.Equal(t, []string{
+		"systemctl is-active kubelet",
+		"systemctl restart kubelet",
+		"bash -c imgpkg pull -i image",
+		"imgpkg pull -i image",
+		"apt-get update",
+	}, fake.Commands())
+}
@@ -0,0 +1,141 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostexectest provides a fake hostexec.Runner for tests
+package hostexectest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostexec"
+)
+
+// Fake is a fake host running commands. A command line succeeds without output unless a result
+// is set for it, and LookPath finds every executable in /usr/bin unless it is missing. The
+// command lines are recorded with the base name of their executable, scripts as bash -c <script>.
+type Fake struct {
+	mu       sync.Mutex
+	commands []string
+	results  map[string]result
+	effects  map[string]func(args []string) error
+	missing  map[string]bool
+}
+
+var _ hostexec.Runner = &Fake{}
+
+type result struct {
+	output string
+	err    error
+}
+
+// NewFake returns a fake host on which every command succeeds
+func NewFake() *Fake {
+	return &Fake{
+		results: map[string]result{},
+		effects: map[string]func(args []string) error{},
+		missing: map[string]bool{},
+	}
+}
+
+// Set makes the command lines starting with prefix output output and fail with err unless nil.
+// The longest prefix set for a command line applies.
+func (f *Fake) Set(prefix, output string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[prefix] = result{output: output, err: err}
+}
+
+// On runs effect with the arguments of the command lines starting with prefix, a command line
+// fails if its effect does
+func (f *Fake) On(prefix string, effect func(args []string) error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.effects[prefix] = effect
+}
+
+// Missing makes LookPath fail to find the executables names
+func (f *Fake) Missing(names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range names {
+		f.missing[name] = true
+	}
+}
+
+// Commands returns the command lines run so far
+func (f *Fake) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+// Ran reports whether a command line starting with prefix ran
+func (f *Fake) Ran(prefix string) bool {
+	for _, command := range f.Commands() {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Output runs the command line and returns its output
+func (f *Fake) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return f.run(ctx, name, args)
+}
+
+// CombinedOutput runs the command line and returns its output
+func (f *Fake) CombinedOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return f.run(ctx, name, args)
+}
+
+// Script runs the script as the command line bash -c <script>
+func (f *Fake) Script(ctx context.Context, script string) error {
+	_, err := f.run(ctx, "bash", []string{"-c", script})
+	return err
+}
+
+// LookPath returns /usr/bin/<name> unless name is missing
+func (f *Fake) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.missing[name] {
+		return "", fmt.Errorf("exec: %q: executable file not found in $PATH", name)
+	}
+	return filepath.Join("/usr/bin", name), nil
+}
+
+func (f *Fake) run(ctx context.Context, name string, args []string) ([]byte, error) {
+	command := strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+
+	f.mu.Lock()
+	f.commands = append(f.commands, command)
+	effect := f.effects[longestPrefix(command, f.effects)]
+	res := f.results[longestPrefix(command, f.results)]
+	f.mu.Unlock()
+
+	// like exec.CommandContext, a command does not start once its context is done
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if effect != nil {
+		if err := effect(args); err != nil {
+			return []byte(err.Error()), err
+		}
+	}
+	return []byte(res.output), res.err
+}
+
+func longestPrefix[V any](command string, prefixes map[string]V) string {
+	longest := ""
+	for prefix := range prefixes {
+		if strings.HasPrefix(command, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	return longest
+}
@@ -0,0 +1,437 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostfacts collects the facts of a host the agent reports in the status of its ByoHost,
+// and byohctl host facts prints: its OS, architecture, CPUs, memory, disks, network interfaces
+// and virtualization
+package hostfacts
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// EphemeralStoragePath is the filesystem the ephemeral-storage capacity of the host is
+	// computed from; kubelet keeps its root directory under it
+	EphemeralStoragePath = "/var/lib"
+	// sysClassNetPath is where the kernel exposes the network interfaces
+	sysClassNetPath = "/sys/class/net"
+	// procNetVLANPath is where the 8021q module exposes the vlan interfaces
+	procNetVLANPath = "/proc/net/vlan"
+	// procNetRoutePath is the IPv4 routing table of the kernel
+	procNetRoutePath = "/proc/net/route"
+	// sysBlockPath is where the kernel exposes the block devices
+	sysBlockPath = "/sys/block"
+	// sysDMIPath is where the kernel exposes the DMI table of the firmware
+	sysDMIPath = "/sys/class/dmi/id"
+	// sectorSize is the unit of the size of the block devices in sysfs
+	sectorSize = 512
+)
+
+// Link types of the network interfaces, as named by the NetworkLinkType of the ByoHost status
+const (
+	LinkTypePhysical = "physical"
+	LinkTypeBond     = "bond"
+	LinkTypeVLAN     = "vlan"
+	LinkTypeVirtual  = "virtual"
+)
+
+// NoVirtualization is the virtualization of a bare metal host
+const NoVirtualization = "none"
+
+var (
+	// devTypeRegex matches the device type in the uevent of a network interface
+	devTypeRegex = regexp.MustCompile(`(?m)^DEVTYPE=(\S+)$`)
+	// vlanIDRegex matches the vlan id in the procfs config of a vlan interface
+	vlanIDRegex = regexp.MustCompile(`VID:\s*(\d+)`)
+	// vlanDeviceRegex matches the parent interface in the procfs config of a vlan interface
+	vlanDeviceRegex = regexp.MustCompile(`(?m)^Device:\s*(\S+)`)
+	// prettyNameRegex matches the PRETTY_NAME of an os-release
+	prettyNameRegex = regexp.MustCompile("(PRETTY_NAME)=(.*)")
+	// hypervisorFlagRegex matches the hypervisor flag of the CPUs in /proc/cpuinfo
+	hypervisorFlagRegex = regexp.MustCompile(`(?m)^flags\s*:.*\bhypervisor\b`)
+
+	// machineArchitectures maps the machine names reported by uname to the GOARCH architectures
+	machineArchitectures = map[string]string{
+		"x86_64":  "amd64",
+		"aarch64": "arm64",
+		"arm64":   "arm64",
+	}
+
+	// dmiHypervisors maps the DMI vendors and products of the virtual machines to their hypervisor
+	dmiHypervisors = []struct{ match, hypervisor string }{
+		{"QEMU", "kvm"},
+		{"KVM", "kvm"},
+		{"VMware", "vmware"},
+		{"VirtualBox", "virtualbox"},
+		{"innotek", "virtualbox"},
+		{"Microsoft Corporation Virtual Machine", "hyperv"},
+		{"Xen", "xen"},
+		{"Amazon EC2", "amazon"},
+		{"Google Compute Engine", "google"},
+		{"OpenStack", "openstack"},
+		{"Parallels", "parallels"},
+	}
+)
+
+// ReadFile reads the file of a path, tests replace os.ReadFile with a fake file system
+type ReadFile func(string) ([]byte, error)
+
+// Facts are the facts of a host
+type Facts struct {
+	// OSName is the operating system of the host, as named by GOOS
+	OSName string `json:"osName"`
+	// OSImage is the PRETTY_NAME of the os-release of the host, e.g. "Ubuntu 22.04.4 LTS"
+	OSImage string `json:"osImage"`
+	// Architecture is the architecture of the host, as named by GOARCH
+	Architecture string `json:"architecture"`
+	// KernelVersion is the kernel release of the host, e.g. 5.15.0-91-generic
+	KernelVersion string `json:"kernelVersion"`
+	// CPUs is the number of CPUs of the host
+	CPUs int `json:"cpus"`
+	// MemoryBytes is the total memory of the host
+	MemoryBytes int64 `json:"memoryBytes"`
+	// EphemeralStorageBytes is the size of the EphemeralStoragePath filesystem
+	EphemeralStorageBytes int64 `json:"ephemeralStorageBytes"`
+	// Disks are the disks of the host
+	Disks []Disk `json:"disks"`
+	// Interfaces are the network interfaces of the host
+	Interfaces []Interface `json:"interfaces"`
+	// Virtualization is the hypervisor the host runs on, NoVirtualization on bare metal
+	Virtualization string `json:"virtualization"`
+}
+
+// Disk is a disk of the host
+type Disk struct {
+	Name       string `json:"name"`
+	SizeBytes  int64  `json:"sizeBytes"`
+	Model      string `json:"model,omitempty"`
+	Rotational bool   `json:"rotational"`
+}
+
+// Interface is a network interface of the host
+type Interface struct {
+	Name      string   `json:"name"`
+	MACAddr   string   `json:"macAddr"`
+	MTU       int32    `json:"mtu"`
+	Connected bool     `json:"connected"`
+	IPAddrs   []string `json:"ipAddrs,omitempty"`
+	// IsDefault is whether the default route of the host is through the interface
+	IsDefault bool `json:"isDefault"`
+	// Gateway is the default gateway, only set on the default interface
+	Gateway string `json:"gateway,omitempty"`
+	LinkInfo
+}
+
+// LinkInfo is the kind of link backing a network interface
+type LinkInfo struct {
+	// LinkType is one of the LinkType constants
+	LinkType string `json:"linkType"`
+	// BondMembers are the interfaces aggregated by a bond interface
+	BondMembers []string `json:"bondMembers,omitempty"`
+	// VLANID is the 802.1Q VLAN id of a vlan interface
+	VLANID int32 `json:"vlanID,omitempty"`
+	// ParentInterfaceName is the interface a vlan interface is built on
+	ParentInterfaceName string `json:"parentInterfaceName,omitempty"`
+}
+
+// DefaultRoute is the default IPv4 route of the host
+type DefaultRoute struct {
+	// IP is the address of the host on the interface of the route
+	IP net.IP
+	// Gateway is the address of the gateway of the route
+	Gateway string
+}
+
+// Collect collects the facts of the host, the interfaces are reported as default by route
+func Collect(route DefaultRoute) (*Facts, error) {
+	facts := &Facts{OSName: runtime.GOOS, CPUs: runtime.NumCPU()}
+
+	// the architecture falls back to the one of the binary if uname fails
+	facts.Architecture, _ = Architecture(UnameMachine)
+
+	var err error
+	if facts.OSImage, err = OSImage(os.ReadFile); err != nil {
+		return nil, fmt.Errorf("failed to get host operating system image: %w", err)
+	}
+	if kernel, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		facts.KernelVersion = strings.TrimSpace(string(kernel))
+	}
+	if facts.MemoryBytes, err = MemoryTotal(os.ReadFile); err != nil {
+		return nil, fmt.Errorf("failed to get host memory capacity: %w", err)
+	}
+	if facts.EphemeralStorageBytes, err = StorageCapacity(EphemeralStoragePath); err != nil {
+		return nil, err
+	}
+	if facts.Disks, err = Disks(os.ReadFile, os.ReadDir); err != nil {
+		return nil, err
+	}
+	if facts.Interfaces, err = Interfaces(os.ReadFile, route); err != nil {
+		return nil, err
+	}
+	facts.Virtualization = Virtualization(os.ReadFile)
+	return facts, nil
+}
+
+// OSImage returns the PRETTY_NAME of the os-release of the host, Unknown if it has none
+func OSImage(readFile ReadFile) (string, error) {
+	bytes, err := readFile("/etc/os-release")
+	if err != nil && os.IsNotExist(err) {
+		// /usr/lib/os-release in stateless systems like Clear Linux
+		bytes, err = readFile("/usr/lib/os-release")
+	}
+	if err != nil {
+		return "", fmt.Errorf("error opening file : %v", err)
+	}
+	line := prettyNameRegex.FindAllStringSubmatch(string(bytes), -1)
+	if len(line) > 0 {
+		return strings.Trim(line[0][2], "\""), nil
+	}
+	return "Unknown", nil
+}
+
+// Architecture returns the architecture of the host, as named by GOARCH, from the machine name of
+// its kernel: the binary may be emulated on a host of another architecture, and the bundle
+// installed on the host must match the host. It falls back to the architecture of the binary,
+// with the error of machine if it failed.
+func Architecture(machine func() (string, error)) (string, error) {
+	name, err := machine()
+	if err != nil {
+		return runtime.GOARCH, err
+	}
+	if arch, ok := machineArchitectures[name]; ok {
+		return arch, nil
+	}
+	return runtime.GOARCH, nil
+}
+
+// UnameMachine returns the machine name of the host kernel, as reported by uname -m
+func UnameMachine() (string, error) {
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return "", err
+	}
+	return unix.ByteSliceToString(uname.Machine[:]), nil
+}
+
+// MemoryTotal returns the total memory of the host in bytes from /proc/meminfo
+func MemoryTotal(readFile ReadFile) (int64, error) {
+	data, err := readFile("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("error opening file : %v", err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" { //nolint: mnd
+			continue
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal value %q: %v", fields[1], err)
+		}
+		return kb * 1024, nil //nolint: mnd
+	}
+	return 0, errors.New("MemTotal not found in /proc/meminfo")
+}
+
+// StorageCapacity returns the size in bytes of the filesystem of path
+func StorageCapacity(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to get ephemeral-storage capacity of %s: %w", path, err)
+	}
+	return int64(stat.Blocks) * int64(stat.Bsize), nil //nolint: gosec, unconvert
+}
+
+// Disks returns the disks of the host, the block devices backed by a device; partitions, loop,
+// ram and device mapper devices are left out
+func Disks(readFile ReadFile, readDir func(string) ([]os.DirEntry, error)) ([]Disk, error) {
+	entries, err := readDir(sysBlockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the block devices of the host: %w", err)
+	}
+	disks := []Disk{}
+	for _, entry := range entries {
+		devicePath := filepath.Join(sysBlockPath, entry.Name())
+		// only the block devices of a disk have a device entry in sysfs
+		if _, err := readFile(filepath.Join(devicePath, "device", "uevent")); err != nil {
+			continue
+		}
+		disk := Disk{Name: entry.Name()}
+		if size, err := readFile(filepath.Join(devicePath, "size")); err == nil {
+			if sectors, err := strconv.ParseInt(strings.TrimSpace(string(size)), 10, 64); err == nil {
+				disk.SizeBytes = sectors * sectorSize
+			}
+		}
+		if model, err := readFile(filepath.Join(devicePath, "device", "model")); err == nil {
+			disk.Model = strings.TrimSpace(string(model))
+		}
+		if rotational, err := readFile(filepath.Join(devicePath, "queue", "rotational")); err == nil {
+			disk.Rotational = strings.TrimSpace(string(rotational)) == "1"
+		}
+		disks = append(disks, disk)
+	}
+	return disks, nil
+}
+
+// Interfaces returns the network interfaces of the host, the interface with the address of route
+// is the default one
+func Interfaces(readFile ReadFile, route DefaultRoute) ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the network interfaces of the host: %w", err)
+	}
+
+	interfaces := make([]Interface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		hostIface := Interface{
+			Name:      iface.Name,
+			MACAddr:   iface.HardwareAddr.String(),
+			MTU:       int32(iface.MTU), //nolint: gosec
+			Connected: iface.Flags&net.FlagUp > 0,
+			LinkInfo:  ReadLinkInfo(readFile, iface.Name),
+		}
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+			if route.IP != nil && ip.Equal(route.IP) {
+				hostIface.IsDefault = true
+				hostIface.Gateway = route.Gateway
+			}
+			hostIface.IPAddrs = append(hostIface.IPAddrs, addr.String())
+		}
+		interfaces = append(interfaces, hostIface)
+	}
+	return interfaces, nil
+}
+
+// ReadLinkInfo returns the link type of the network interface name and, for bond and vlan
+// interfaces, the interfaces they are built on, as exposed by sysfs and procfs
+func ReadLinkInfo(readFile ReadFile, name string) LinkInfo {
+	ifacePath := filepath.Join(sysClassNetPath, name)
+
+	var devType string
+	if uevent, err := readFile(filepath.Join(ifacePath, "uevent")); err == nil {
+		if match := devTypeRegex.FindSubmatch(uevent); match != nil {
+			devType = string(match[1])
+		}
+	}
+
+	var info LinkInfo
+	switch devType {
+	case LinkTypeBond:
+		info.LinkType = LinkTypeBond
+		if members, err := readFile(filepath.Join(ifacePath, "bonding", "slaves")); err == nil {
+			info.BondMembers = strings.Fields(string(members))
+		}
+	case LinkTypeVLAN:
+		info.LinkType = LinkTypeVLAN
+		if config, err := readFile(filepath.Join(procNetVLANPath, name)); err == nil {
+			if match := vlanIDRegex.FindSubmatch(config); match != nil {
+				if id, err := strconv.ParseInt(string(match[1]), 10, 32); err == nil {
+					info.VLANID = int32(id)
+				}
+			}
+			if match := vlanDeviceRegex.FindSubmatch(config); match != nil {
+				info.ParentInterfaceName = string(match[1])
+			}
+		}
+	default:
+		// only interfaces backed by a device have a device entry in sysfs
+		if _, err := readFile(filepath.Join(ifacePath, "device", "uevent")); err == nil {
+			info.LinkType = LinkTypePhysical
+		} else {
+			info.LinkType = LinkTypeVirtual
+		}
+	}
+	return info
+}
+
+// ReadDefaultRoute returns the default IPv4 route of the host from the routing table of the
+// kernel, the address of the host is the first IPv4 address of the interface of the route
+func ReadDefaultRoute(readFile ReadFile, interfaceAddrs func(name string) ([]net.Addr, error)) (DefaultRoute, error) {
+	table, err := readFile(procNetRoutePath)
+	if err != nil {
+		return DefaultRoute{}, fmt.Errorf("failed to read the routing table of the host: %w", err)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(table)))
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags ..., the addresses are little endian hex
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" { //nolint: mnd
+			continue
+		}
+		gateway, err := hex.DecodeString(fields[2])
+		if err != nil || len(gateway) != net.IPv4len {
+			continue
+		}
+		route := DefaultRoute{Gateway: net.IPv4(gateway[3], gateway[2], gateway[1], gateway[0]).String()}
+		addrs, err := interfaceAddrs(fields[0])
+		if err != nil {
+			return DefaultRoute{}, fmt.Errorf("failed to get the addresses of interface %s: %w", fields[0], err)
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				route.IP = ipNet.IP
+				break
+			}
+		}
+		return route, nil
+	}
+	return DefaultRoute{}, errors.New("the host has no default route")
+}
+
+// InterfaceAddrs returns the addresses of the network interface name
+func InterfaceAddrs(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// Virtualization returns the hypervisor the host runs on from its DMI table and the CPU flags,
+// NoVirtualization on bare metal and unknown on a hypervisor it does not know
+func Virtualization(readFile ReadFile) string {
+	var dmi []string
+	for _, name := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor"} {
+		if value, err := readFile(filepath.Join(sysDMIPath, name)); err == nil {
+			dmi = append(dmi, strings.TrimSpace(string(value)))
+		}
+	}
+	// the vendor and the product are matched together, e.g. Microsoft Corporation Virtual Machine
+	dmiText := strings.Join(dmi, " ")
+	for _, hypervisor := range dmiHypervisors {
+		if strings.Contains(dmiText, hypervisor.match) {
+			return hypervisor.hypervisor
+		}
+	}
+	// the hypervisor flag is set by every hypervisor, e.g. on hosts without DMI like arm64 VMs
+	if cpuinfo, err := readFile("/proc/cpuinfo"); err == nil && hypervisorFlagRegex.Match(cpuinfo) {
+		return "unknown"
+	}
+	return NoVirtualization
+}
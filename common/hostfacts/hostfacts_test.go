@@ -0,0 +1,202 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hostfacts_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostfacts"
+)
+
+// fakeFS is a fake file system of absolute paths for the ReadFile of the facts
+type fakeFS map[string]string
+
+func (f fakeFS) ReadFile(name string) ([]byte, error) {
+	if content, ok := f[name]; ok {
+		return []byte(content), nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (f fakeFS) ReadDir(name string) ([]os.DirEntry, error) {
+	mapFS := fstest.MapFS{}
+	for path, content := range f {
+		mapFS[path[1:]] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fs.ReadDir(mapFS, name[1:])
+}
+
+func osRelease(prettyName string) string {
+	return fmt.Sprintf(`NAME="Ubuntu"
+VERSION="20.04.4 LTS (Focal Fossa)"
+ID=ubuntu
+PRETTY_NAME="%s"
+VERSION_ID="20.04"`, prettyName)
+}
+
+func TestOSImage(t *testing.T) {
+	osImage, err := hostfacts.OSImage(fakeFS{"/etc/os-release": osRelease("Ubuntu 20.04.4 LTS")}.ReadFile)
+	require.NoError(t, err)
+	assert.Equal(t, "Ubuntu 20.04.4 LTS", osImage)
+
+	// stateless systems like Clear Linux have /usr/lib/os-release only
+	osImage, err = hostfacts.OSImage(fakeFS{"/usr/lib/os-release": osRelease("Clear Linux Initramfs")}.ReadFile)
+	require.NoError(t, err)
+	assert.Equal(t, "Clear Linux Initramfs", osImage)
+
+	osImage, err = hostfacts.OSImage(fakeFS{"/etc/os-release": "some_file_without_PRETTY_NAME"}.ReadFile)
+	require.NoError(t, err)
+	assert.Equal(t, "Unknown", osImage)
+
+	_, err = hostfacts.OSImage(fakeFS{}.ReadFile)
+	assert.EqualError(t, err, "error opening file : file does not exist")
+}
+
+func TestArchitecture(t *testing.T) {
+	arch, err := hostfacts.Architecture(func() (string, error) { return "aarch64", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "arm64", arch)
+	arch, err = hostfacts.Architecture(func() (string, error) { return "x86_64", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "amd64", arch)
+
+	// the architecture of the binary is the fallback
+	arch, err = hostfacts.Architecture(func() (string, error) { return "", errors.New("uname failed") })
+	assert.Error(t, err)
+	assert.Equal(t, runtime.GOARCH, arch)
+	arch, err = hostfacts.Architecture(func() (string, error) { return "riscv64", nil })
+	require.NoError(t, err)
+	assert.Equal(t, runtime.GOARCH, arch)
+
+	machine, err := hostfacts.UnameMachine()
+	require.NoError(t, err)
+	assert.NotEmpty(t, machine)
+}
+
+func TestMemoryTotal(t *testing.T) {
+	memory, err := hostfacts.MemoryTotal(fakeFS{"/proc/meminfo": "MemTotal:        8144076 kB\nMemFree:         1021496 kB\n"}.ReadFile)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8144076*1024), memory)
+
+	_, err = hostfacts.MemoryTotal(fakeFS{"/proc/meminfo": "MemFree: 1021496 kB"}.ReadFile)
+	assert.Error(t, err)
+}
+
+func TestReadLinkInfo(t *testing.T) {
+	sysfs := fakeFS{
+		"/sys/class/net/eth0/uevent":          "INTERFACE=eth0\nIFINDEX=2\n",
+		"/sys/class/net/eth0/device/uevent":   "DRIVER=virtio_net\n",
+		"/sys/class/net/bond0/uevent":         "DEVTYPE=bond\nINTERFACE=bond0\n",
+		"/sys/class/net/bond0/bonding/slaves": "eth0 eth1\n",
+		"/sys/class/net/eth0.100/uevent":      "DEVTYPE=vlan\nINTERFACE=eth0.100\n",
+		"/proc/net/vlan/eth0.100": "eth0.100  VID: 100\t REF: 1\t REORDER_HDR: 1\n" +
+			"  total frames received            0\nDevice: eth0\nINGRESS priority mappings: 0:0\n",
+		"/sys/class/net/lo/uevent": "INTERFACE=lo\nIFINDEX=1\n",
+	}
+
+	assert.Equal(t, hostfacts.LinkInfo{LinkType: hostfacts.LinkTypePhysical}, hostfacts.ReadLinkInfo(sysfs.ReadFile, "eth0"))
+	assert.Equal(t, hostfacts.LinkInfo{LinkType: hostfacts.LinkTypeBond, BondMembers: []string{"eth0", "eth1"}},
+		hostfacts.ReadLinkInfo(sysfs.ReadFile, "bond0"))
+	assert.Equal(t, hostfacts.LinkInfo{LinkType: hostfacts.LinkTypeVLAN, VLANID: 100, ParentInterfaceName: "eth0"},
+		hostfacts.ReadLinkInfo(sysfs.ReadFile, "eth0.100"))
+	assert.Equal(t, hostfacts.LinkInfo{LinkType: hostfacts.LinkTypeVirtual}, hostfacts.ReadLinkInfo(sysfs.ReadFile, "lo"))
+}
+
+func TestDisks(t *testing.T) {
+	sysfs := fakeFS{
+		"/sys/block/sda/device/uevent":      "DEVTYPE=scsi_device\n",
+		"/sys/block/sda/device/model":       "Samsung SSD 870 \n",
+		"/sys/block/sda/size":               "1953525168\n",
+		"/sys/block/sda/queue/rotational":   "0\n",
+		"/sys/block/sdb/device/uevent":      "DEVTYPE=scsi_device\n",
+		"/sys/block/sdb/size":               "7814037168\n",
+		"/sys/block/sdb/queue/rotational":   "1\n",
+		"/sys/block/loop0/size":             "1024\n",
+		"/sys/block/loop0/queue/rotational": "0\n",
+	}
+
+	disks, err := hostfacts.Disks(sysfs.ReadFile, sysfs.ReadDir)
+	require.NoError(t, err)
+	assert.Equal(t, []hostfacts.Disk{
+		{Name: "sda", SizeBytes: 1953525168 * 512, Model: "Samsung SSD 870"},
+		{Name: "sdb", SizeBytes: 7814037168 * 512, Rotational: true},
+	}, disks)
+}
+
+func TestVirtualization(t *testing.T) {
+	for name, tc := range map[string]struct {
+		files    fakeFS
+		expected string
+	}{
+		"kvm": {
+			files:    fakeFS{"/sys/class/dmi/id/sys_vendor": "QEMU\n", "/sys/class/dmi/id/product_name": "Standard PC (Q35 + ICH9, 2009)\n"},
+			expected: "kvm",
+		},
+		"hyper-v": {
+			files:    fakeFS{"/sys/class/dmi/id/sys_vendor": "Microsoft Corporation\n", "/sys/class/dmi/id/product_name": "Virtual Machine\n"},
+			expected: "hyperv",
+		},
+		"unknown hypervisor": {
+			files:    fakeFS{"/proc/cpuinfo": "processor\t: 0\nflags\t\t: fpu vme hypervisor lahf_lm\n"},
+			expected: "unknown",
+		},
+		"bare metal": {
+			files: fakeFS{
+				"/sys/class/dmi/id/sys_vendor":   "Dell Inc.\n",
+				"/sys/class/dmi/id/product_name": "PowerEdge R640\n",
+				"/proc/cpuinfo":                  "processor\t: 0\nflags\t\t: fpu vme lahf_lm\n",
+			},
+			expected: hostfacts.NoVirtualization,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, hostfacts.Virtualization(tc.files.ReadFile))
+		})
+	}
+}
+
+func TestReadDefaultRoute(t *testing.T) {
+	routes := fakeFS{"/proc/net/route": "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\n" +
+		"eth0\t0001A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\n" +
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\n"}
+	addrs := func(name string) ([]net.Addr, error) {
+		assert.Equal(t, "eth0", name)
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+			&net.IPNet{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+
+	route, err := hostfacts.ReadDefaultRoute(routes.ReadFile, addrs)
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.1", route.Gateway)
+	assert.Equal(t, "192.168.1.10", route.IP.String())
+
+	_, err = hostfacts.ReadDefaultRoute(fakeFS{"/proc/net/route": "Iface\tDestination\tGateway\n"}.ReadFile, addrs)
+	assert.Error(t, err)
+}
+
+func TestInterfaces(t *testing.T) {
+	interfaces, err := hostfacts.Interfaces(fakeFS{}.ReadFile, hostfacts.DefaultRoute{IP: net.ParseIP("127.0.0.1"), Gateway: "127.0.0.254"})
+	require.NoError(t, err)
+	for _, iface := range interfaces {
+		if iface.Name == "lo" {
+			assert.True(t, iface.IsDefault)
+			assert.Equal(t, "127.0.0.254", iface.Gateway)
+			assert.Equal(t, hostfacts.LinkTypeVirtual, iface.LinkType)
+			return
+		}
+	}
+	t.Skip("the host has no loopback interface")
+}
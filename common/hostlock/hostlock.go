@@ -0,0 +1,93 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostlock implements the lock of a host shared by byohctl and the agent, so that the
+// onboarding, upgrade and decommission of the host and the install and uninstall scripts of the
+// agent do not interleave their dpkg and config writes
+package hostlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultPath is the lock file of the host. It is under /run, so a lock cannot outlive a reboot.
+const DefaultPath = "/run/byoh/host.lock"
+
+// pollInterval is how often a locked lock is tried again
+const pollInterval = 500 * time.Millisecond
+
+// ErrLocked is returned when the lock is held by another operation
+var ErrLocked = errors.New("the host is locked by another operation")
+
+// Lock is an exclusive lock of the host, an flock on the lock file
+type Lock struct {
+	file *os.File
+}
+
+// Acquire blocks until it holds the lock at path, or ctx is done. holder describes the operation
+// taking the lock, e.g. "byohctl onboard"; it is reported to the operations waiting for the lock.
+// waiting, if set, is called with the holder of the lock when the lock has to be waited for.
+func Acquire(ctx context.Context, path, holder string, waiting func(holder string)) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for notified := false; ; {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, err
+		}
+		if waiting != nil && !notified {
+			waiting(Holder(path))
+			notified = true
+		}
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, fmt.Errorf("%w, %s: %w", ErrLocked, Holder(path), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	// the holder is informational, failing to record it does not fail the lock
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(fmt.Sprintf("%s (pid %d)\n", holder, os.Getpid())), 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release releases the lock
+func (l *Lock) Release() error {
+	_ = l.file.Truncate(0)
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN); err != nil {
+		l.file.Close()
+		return err
+	}
+	return l.file.Close()
+}
+
+// Holder returns the operation holding the lock at path, as recorded by Acquire
+func Holder(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return "unknown operation"
+	}
+	return strings.TrimSpace(string(data))
+}
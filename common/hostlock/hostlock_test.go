@@ -0,0 +1,58 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hostlock_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostlock"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "byoh", "host.lock")
+	lock, err := hostlock.Acquire(context.Background(), path, "byohctl onboard", nil)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("byohctl onboard (pid %d)", os.Getpid()), hostlock.Holder(path))
+
+	// the flock is held per open file, so a second Acquire of the same process waits too
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var waitedFor string
+	_, err = hostlock.Acquire(ctx, path, "agent install", func(holder string) { waitedFor = holder })
+	assert.True(t, errors.Is(err, hostlock.ErrLocked))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Contains(t, err.Error(), "byohctl onboard")
+	assert.Contains(t, waitedFor, "byohctl onboard")
+
+	require.NoError(t, lock.Release())
+	assert.Equal(t, "unknown operation", hostlock.Holder(path))
+	lock, err = hostlock.Acquire(context.Background(), path, "agent install", nil)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
+
+func TestAcquireWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "host.lock")
+	lock, err := hostlock.Acquire(context.Background(), path, "byohctl decommission", nil)
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = lock.Release()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lock, err = hostlock.Acquire(ctx, path, "agent uninstall", nil)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release())
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hostoperation records the lifecycle operations of ByoHosts as ByoHostOperation objects
+package hostoperation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Operation is an operation on a ByoHost to be recorded once it ends
+type Operation struct {
+	Namespace  string
+	HostName   string
+	Type       infrastructurev1beta1.ByoHostOperationType
+	Initiator  string
+	MachineRef *corev1.ObjectReference
+	StartTime  time.Time
+}
+
+// New returns the ByoHostOperation of op ended now, failed if err is not nil
+func New(op Operation, err error) *infrastructurev1beta1.ByoHostOperation {
+	record := &infrastructurev1beta1.ByoHostOperation{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: strings.ToLower(op.HostName+"-"+string(op.Type)) + "-",
+			Namespace:    op.Namespace,
+		},
+		Spec: infrastructurev1beta1.ByoHostOperationSpec{
+			HostName:       op.HostName,
+			Operation:      op.Type,
+			Initiator:      op.Initiator,
+			MachineRef:     op.MachineRef,
+			StartTime:      metav1.NewTime(op.StartTime),
+			CompletionTime: metav1.Now(),
+			Outcome:        infrastructurev1beta1.ByoHostOperationSucceeded,
+		},
+	}
+	if len(validation.IsValidLabelValue(op.HostName)) == 0 {
+		record.Labels = map[string]string{infrastructurev1beta1.ByoHostOperationHostLabel: op.HostName}
+	}
+	if err != nil {
+		record.Spec.Outcome = infrastructurev1beta1.ByoHostOperationFailed
+		record.Spec.Message = err.Error()
+	}
+	return record
+}
+
+// Record creates the ByoHostOperation of op ended now, failed if err is not nil
+func Record(ctx context.Context, c client.Client, op Operation, err error) error {
+	return c.Create(ctx, New(op, err))
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hostoperation_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostoperation"
+)
+
+func TestNewSucceeded(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	machineRef := &corev1.ObjectReference{Kind: "ByoMachine", Namespace: "default", Name: "my-byomachine"}
+	record := hostoperation.New(hostoperation.Operation{
+		Namespace:  "default",
+		HostName:   "my-host",
+		Type:       infrastructurev1beta1.ByoHostOperationAttach,
+		Initiator:  "byomachine-controller",
+		MachineRef: machineRef,
+		StartTime:  start,
+	}, nil)
+
+	assert.Equal(t, "my-host-attach-", record.GenerateName)
+	assert.Equal(t, "default", record.Namespace)
+	assert.Equal(t, map[string]string{infrastructurev1beta1.ByoHostOperationHostLabel: "my-host"}, record.Labels)
+	assert.Equal(t, "my-host", record.Spec.HostName)
+	assert.Equal(t, infrastructurev1beta1.ByoHostOperationAttach, record.Spec.Operation)
+	assert.Equal(t, "byomachine-controller", record.Spec.Initiator)
+	assert.Equal(t, machineRef, record.Spec.MachineRef)
+	assert.True(t, record.Spec.StartTime.Time.Equal(start))
+	assert.False(t, record.Spec.CompletionTime.Before(&record.Spec.StartTime))
+	assert.Equal(t, infrastructurev1beta1.ByoHostOperationSucceeded, record.Spec.Outcome)
+	assert.Empty(t, record.Spec.Message)
+}
+
+func TestNewFailed(t *testing.T) {
+	record := hostoperation.New(hostoperation.Operation{
+		HostName: "my-host",
+		Type:     infrastructurev1beta1.ByoHostOperationDetach,
+	}, errors.New("byohosts.infrastructure.cluster.x-k8s.io \"my-host\" not found"))
+
+	assert.Equal(t, infrastructurev1beta1.ByoHostOperationFailed, record.Spec.Outcome)
+	assert.Equal(t, "byohosts.infrastructure.cluster.x-k8s.io \"my-host\" not found", record.Spec.Message)
+}
+
+func TestNewHostNameNotALabelValue(t *testing.T) {
+	hostName := strings.Repeat("a", 70)
+	record := hostoperation.New(hostoperation.Operation{
+		HostName: hostName,
+		Type:     infrastructurev1beta1.ByoHostOperationOnboard,
+	}, nil)
+
+	assert.Empty(t, record.Labels)
+	assert.Equal(t, hostName, record.Spec.HostName)
+}
+
+func TestRecord(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, infrastructurev1beta1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	require.NoError(t, hostoperation.Record(context.Background(), c, hostoperation.Operation{
+		Namespace: "default",
+		HostName:  "my-host",
+		Type:      infrastructurev1beta1.ByoHostOperationOnboard,
+		Initiator: "byoh:host:my-host",
+		StartTime: time.Now(),
+	}, nil))
+
+	records := &infrastructurev1beta1.ByoHostOperationList{}
+	require.NoError(t, c.List(context.Background(), records, client.InNamespace("default"),
+		client.MatchingLabels{infrastructurev1beta1.ByoHostOperationHostLabel: "my-host"}))
+	require.Len(t, records.Items, 1)
+	assert.Equal(t, infrastructurev1beta1.ByoHostOperationOnboard, records.Items[0].Spec.Operation)
+	assert.Equal(t, "byoh:host:my-host", records.Items[0].Spec.Initiator)
+}
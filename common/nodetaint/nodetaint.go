@@ -0,0 +1,90 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nodetaint parses the taints of the Node of a host declared at onboarding, shared by the
+// --taint flags of byohctl and the agent so both accept the same taints
+package nodetaint
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Parse parses the comma-separated taints of spec, each as key=value:effect or key:effect like
+// kubectl taint. An empty spec has no taints. Like the ByoHost webhook, it rejects two taints of
+// the same key and effect.
+func Parse(spec string) ([]corev1.Taint, error) {
+	var taints []corev1.Taint
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		taint, err := parseTaint(s)
+		if err != nil {
+			return nil, err
+		}
+		for i := range taints {
+			if taints[i].MatchTaint(&taint) {
+				return nil, fmt.Errorf("duplicate taint %s:%s", taint.Key, taint.Effect)
+			}
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
+// Format returns the taints in the form Parse parses
+func Format(taints []corev1.Taint) string {
+	specs := make([]string, 0, len(taints))
+	for _, taint := range taints {
+		specs = append(specs, taint.ToString())
+	}
+	return strings.Join(specs, ",")
+}
+
+// Merge returns taints with the taints of update, a taint of update replaces the value of the
+// taint of the same key and effect
+func Merge(taints, update []corev1.Taint) []corev1.Taint {
+	merged := append([]corev1.Taint(nil), taints...)
+	for i := range update {
+		found := false
+		for j := range merged {
+			if merged[j].MatchTaint(&update[i]) {
+				merged[j].Value = update[i].Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, update[i])
+		}
+	}
+	return merged
+}
+
+func parseTaint(s string) (corev1.Taint, error) {
+	keyValue, effect, found := strings.Cut(s, ":")
+	if !found {
+		return corev1.Taint{}, fmt.Errorf("invalid taint %q, expected key=value:effect", s)
+	}
+	key, value, _ := strings.Cut(keyValue, "=")
+	taint := corev1.Taint{Key: key, Value: value, Effect: corev1.TaintEffect(effect)}
+
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return corev1.Taint{}, fmt.Errorf("invalid taint key %q: %s", key, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+		return corev1.Taint{}, fmt.Errorf("invalid taint value %q of %s: %s", value, key, strings.Join(errs, "; "))
+	}
+	switch taint.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return corev1.Taint{}, fmt.Errorf("invalid taint effect %q of %s, expected %s, %s or %s", effect, key,
+			corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+	}
+	return taint, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nodetaint_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/nodetaint"
+)
+
+func TestParse(t *testing.T) {
+	taints, err := nodetaint.Parse("dedicated=gpu:NoSchedule, example.com/maintenance:NoExecute,")
+	require.NoError(t, err)
+	assert.Equal(t, []corev1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "example.com/maintenance", Effect: corev1.TaintEffectNoExecute},
+	}, taints)
+	assert.Equal(t, "dedicated=gpu:NoSchedule,example.com/maintenance:NoExecute", nodetaint.Format(taints))
+
+	taints, err = nodetaint.Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, taints)
+
+	for _, spec := range []string{"dedicated=gpu", "dedicated=gpu:Never", "-dedicated:NoSchedule", "dedicated=a b:NoSchedule", "dedicated=a:NoSchedule,dedicated=b:NoSchedule"} {
+		_, err := nodetaint.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	taints := []corev1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoExecute},
+	}
+	merged := nodetaint.Merge(taints, []corev1.Taint{
+		{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "maintenance", Effect: corev1.TaintEffectPreferNoSchedule},
+	})
+	assert.Equal(t, []corev1.Taint{
+		{Key: "dedicated", Value: "infra", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoExecute},
+		{Key: "maintenance", Effect: corev1.TaintEffectPreferNoSchedule},
+	}, merged)
+	assert.Equal(t, "gpu", taints[0].Value, "the taints merged into are not changed")
+}
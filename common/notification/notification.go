@@ -0,0 +1,100 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notification notifies the lifecycle events of ByoHosts, e.g. a host attached to a
+// machine, to the infra teams. WebhookNotifier posts them to an HTTP endpoint with a payload
+// Slack incoming webhooks accept.
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const sendTimeout = 10 * time.Second
+
+// EventType is a lifecycle event of a ByoHost
+type EventType string
+
+const (
+	// HostRegistered is the registration of a new host by its agent
+	HostRegistered EventType = "HostRegistered"
+	// HostAttached is the attachment of a host to a ByoMachine
+	HostAttached EventType = "HostAttached"
+	// HostDisconnected is the loss of the heartbeat of the agent of a host
+	HostDisconnected EventType = "HostDisconnected"
+	// HostDecommissioned is the removal of a host with byohctl
+	HostDecommissioned EventType = "HostDecommissioned"
+)
+
+// Event is a lifecycle event of a ByoHost
+type Event struct {
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	HostName  string    `json:"hostName"`
+	// Machine is the name of the ByoMachine the host is attached to, if any
+	Machine string `json:"machine,omitempty"`
+	// Message describes the event for humans
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Text returns the one line summary of the event
+func (e Event) Text() string {
+	text := fmt.Sprintf("[%s] ByoHost %s/%s", e.Type, e.Namespace, e.HostName)
+	if e.Machine != "" {
+		text += " (ByoMachine " + e.Machine + ")"
+	}
+	if e.Message != "" {
+		text += ": " + e.Message
+	}
+	return text
+}
+
+// Notifier notifies the lifecycle events of ByoHosts
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// payload is the body posted by WebhookNotifier, Slack shows its text and ignores the event
+type payload struct {
+	Text  string `json:"text"`
+	Event Event  `json:"event"`
+}
+
+// WebhookNotifier posts the events as JSON to the URL of a webhook
+type WebhookNotifier struct {
+	URL    string
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a WebhookNotifier posting the events to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, client: &http.Client{Timeout: sendTimeout}}
+}
+
+// Notify posts event to the webhook, it fails if the webhook does not answer with a 2xx status
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(payload{Text: event.Text(), Event: event})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post the %s event of %s: %w", event.Type, event.HostName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook answered the %s event of %s with %s", event.Type, event.HostName, resp.Status)
+	}
+	return nil
+}
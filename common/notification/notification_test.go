@@ -0,0 +1,69 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/notification"
+)
+
+func TestWebhookNotifierPostsSlackPayload(t *testing.T) {
+	var received struct {
+		Text  string             `json:"text"`
+		Event notification.Event `json:"event"`
+	}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	event := notification.Event{
+		Type:      notification.HostAttached,
+		Namespace: "default",
+		HostName:  "host-1",
+		Machine:   "cluster-md-0-abcde",
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.NoError(t, notification.NewWebhookNotifier(server.URL).Notify(context.Background(), event))
+
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, "[HostAttached] ByoHost default/host-1 (ByoMachine cluster-md-0-abcde)", received.Text)
+	assert.Equal(t, event, received.Event)
+}
+
+func TestWebhookNotifierFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := notification.NewWebhookNotifier(server.URL).Notify(context.Background(), notification.Event{
+		Type:     notification.HostDisconnected,
+		HostName: "host-1",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestEventText(t *testing.T) {
+	event := notification.Event{
+		Type:      notification.HostDisconnected,
+		Namespace: "default",
+		HostName:  "host-1",
+		Message:   "no heartbeat since 2026-01-02T03:04:05Z",
+	}
+	assert.Equal(t, "[HostDisconnected] ByoHost default/host-1: no heartbeat since 2026-01-02T03:04:05Z", event.Text())
+}
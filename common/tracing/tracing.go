@@ -0,0 +1,288 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tracing records spans of the onboarding and the provisioning of hosts and exports
+// them to an OpenTelemetry collector with the OTLP/HTTP JSON protocol. Nothing is recorded
+// unless Setup is given the endpoint of a collector.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// EndpointEnv is the environment variable with the endpoint of the collector, the
+	// variable the OpenTelemetry SDKs read it from
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TraceParentEnv is the environment variable a W3C traceparent is handed over to
+	// another process with, so that its spans join the trace of the caller
+	TraceParentEnv = "TRACEPARENT"
+	// HostNameKey is the attribute set to the name of the ByoHost a span is about, the
+	// spans of byohctl, the agent and the controllers for a host are correlated by it
+	HostNameKey = "byoh.host.name"
+	// MachineNameKey is the attribute set to the name of the ByoMachine a span is about
+	MachineNameKey = "byoh.machine.name"
+
+	tracesPath    = "/v1/traces"
+	exportTimeout = 10 * time.Second
+	// statusCodeError is the OTLP status code of a failed span
+	statusCodeError = 2
+	// spanKindInternal is the OTLP kind of all the spans recorded here
+	spanKindInternal = 1
+)
+
+var tracer atomic.Pointer[Tracer]
+
+// Attribute is a string attribute of a span
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// String returns the attribute key set to value
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer exports the spans of a service to a collector. The spans are sent once the
+// span that started the trace in the process ends.
+type Tracer struct {
+	service string
+	url     string
+	client  *http.Client
+
+	mu      sync.Mutex
+	pending []*Span
+	exports sync.WaitGroup
+}
+
+// Setup exports the spans started from now on as spans of service to the collector at
+// endpoint, e.g. http://otel-collector:4318. An empty endpoint turns tracing off.
+// The returned function sends the spans not exported yet and waits for the exports in flight.
+func Setup(service, endpoint string) func(context.Context) error {
+	if endpoint == "" {
+		tracer.Store(nil)
+		return func(context.Context) error { return nil }
+	}
+	t := &Tracer{
+		service: service,
+		url:     strings.TrimSuffix(endpoint, "/") + tracesPath,
+		client:  &http.Client{Timeout: exportTimeout},
+	}
+	tracer.Store(t)
+	return t.Shutdown
+}
+
+// Shutdown sends the spans not exported yet and waits for the exports in flight
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.exports.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.export(ctx)
+}
+
+// Span is an operation of a trace. The methods of a nil Span, returned when tracing
+// is off, do nothing.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	// local is set if the parent of the span is a span of this process
+	local bool
+	start time.Time
+	end   time.Time
+	attrs []Attribute
+	err   error
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// remote is a span of another process, as carried by a traceparent
+type remote struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// Start starts a span named name, child of the span of ctx or of the traceparent ctx was
+// given with ContextWithTraceParent, and returns a context carrying it
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	t := tracer.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{tracer: t, name: name, start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID, span.parentID, span.local = parent.traceID, parent.spanID, true
+	} else if parent, ok := ctx.Value(remoteKey{}).(remote); ok {
+		span.traceID, span.parentID = parent.traceID, parent.spanID
+	} else {
+		_, _ = rand.Read(span.traceID[:])
+	}
+	_, _ = rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes adds attrs to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetAttributes adds attrs to the span of ctx, if any
+func SetAttributes(ctx context.Context, attrs ...Attribute) {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	span.SetAttributes(attrs...)
+}
+
+// End ends the span, failed if err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+
+	t := s.tracer
+	t.mu.Lock()
+	t.pending = append(t.pending, s)
+	t.mu.Unlock()
+	if s.local {
+		return
+	}
+	t.exports.Add(1)
+	go func() {
+		defer t.exports.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		if err := t.export(ctx); err != nil {
+			klog.Errorf("error exporting spans to %s, err=%v", t.url, err)
+		}
+	}()
+}
+
+// TraceParent returns the W3C traceparent of the span of ctx, to be handed over to
+// another process, or an empty string if ctx has no span
+func TraceParent(ctx context.Context) string {
+	span, ok := ctx.Value(spanKey{}).(*Span)
+	if !ok || span == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(span.traceID[:]), hex.EncodeToString(span.spanID[:]))
+}
+
+// ContextWithTraceParent returns a context whose spans are children of the span of
+// another process identified by the W3C traceparent; ctx is returned as is if
+// traceParent is empty or invalid
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return ctx
+	}
+	var parent remote
+	if n, err := hex.Decode(parent.traceID[:], []byte(parts[1])); err != nil || n != len(parent.traceID) || parent.traceID == [16]byte{} {
+		return ctx
+	}
+	if n, err := hex.Decode(parent.spanID[:], []byte(parts[2])); err != nil || n != len(parent.spanID) || parent.spanID == [8]byte{} {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// export sends the ended spans to the collector
+func (t *Tracer) export(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mu.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(t.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// request returns the OTLP ExportTraceServiceRequest of spans in its JSON encoding, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+func (t *Tracer) request(spans []*Span) map[string]any {
+	otlpSpans := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		span := map[string]any{
+			"traceId":           hex.EncodeToString(s.traceID[:]),
+			"spanId":            hex.EncodeToString(s.spanID[:]),
+			"name":              s.name,
+			"kind":              spanKindInternal,
+			"startTimeUnixNano": strconv.FormatInt(s.start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attributes(s.attrs),
+		}
+		if s.parentID != [8]byte{} {
+			span["parentSpanId"] = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			span["status"] = map[string]any{"code": statusCodeError, "message": s.err.Error()}
+		}
+		otlpSpans = append(otlpSpans, span)
+	}
+	return map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": map[string]any{
+				"attributes": attributes([]Attribute{String("service.name", t.service)}),
+			},
+			"scopeSpans": []map[string]any{{
+				"scope": map[string]any{"name": "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost"},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+func attributes(attrs []Attribute) []map[string]any {
+	otlpAttrs := make([]map[string]any, 0, len(attrs))
+	for _, attr := range attrs {
+		otlpAttrs = append(otlpAttrs, map[string]any{"key": attr.Key, "value": map[string]any{"stringValue": attr.Value}})
+	}
+	return otlpAttrs
+}
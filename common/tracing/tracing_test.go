@@ -0,0 +1,129 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
+)
+
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Attributes   []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// collector returns the endpoint of a fake collector and the spans it received
+func collector(t *testing.T) (string, func() map[string]otlpSpan) {
+	var mu sync.Mutex
+	spans := make(map[string]otlpSpan)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					spans[span.Name] = span
+				}
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, func() map[string]otlpSpan {
+		mu.Lock()
+		defer mu.Unlock()
+		return spans
+	}
+}
+
+func TestTracingOff(t *testing.T) {
+	shutdown := tracing.Setup("test", "")
+	ctx, span := tracing.Start(context.Background(), "noop")
+	assert.Nil(t, span)
+	span.SetAttributes(tracing.String(tracing.HostNameKey, "host"))
+	span.End(nil)
+	assert.Empty(t, tracing.TraceParent(ctx))
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestSpansExported(t *testing.T) {
+	endpoint, received := collector(t)
+	shutdown := tracing.Setup("byoh-test", endpoint)
+
+	ctx, root := tracing.Start(context.Background(), "onboard", tracing.String(tracing.HostNameKey, "host-1"))
+	_, child := tracing.Start(ctx, "install")
+	child.End(errors.New("install script failed"))
+	root.End(nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	spans := received()
+	require.Len(t, spans, 2)
+	assert.Equal(t, spans["onboard"].TraceID, spans["install"].TraceID)
+	assert.Equal(t, spans["onboard"].SpanID, spans["install"].ParentSpanID)
+	assert.Empty(t, spans["onboard"].ParentSpanID)
+	require.Len(t, spans["onboard"].Attributes, 1)
+	assert.Equal(t, tracing.HostNameKey, spans["onboard"].Attributes[0].Key)
+	assert.Equal(t, "host-1", spans["onboard"].Attributes[0].Value.StringValue)
+	assert.Equal(t, 2, spans["install"].Status.Code)
+	assert.Equal(t, "install script failed", spans["install"].Status.Message)
+}
+
+func TestTraceParentPropagation(t *testing.T) {
+	endpoint, received := collector(t)
+	shutdown := tracing.Setup("byoh-test", endpoint)
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, span := tracing.Start(tracing.ContextWithTraceParent(context.Background(), traceParent), "bootstrap")
+	assert.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$", tracing.TraceParent(ctx))
+	span.End(nil)
+	require.NoError(t, shutdown(context.Background()))
+
+	spans := received()
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans["bootstrap"].TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", spans["bootstrap"].ParentSpanID)
+}
+
+func TestInvalidTraceParent(t *testing.T) {
+	for _, traceParent := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-not-hex-01",
+	} {
+		ctx := context.Background()
+		assert.Equal(t, ctx, tracing.ContextWithTraceParent(ctx, traceParent), traceParent)
+	}
+}
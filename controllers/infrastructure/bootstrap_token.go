@@ -0,0 +1,155 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/external"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultBootstrapTokenMinTTL is how long the bootstrap token of the bootstrap data of a machine must
+// still be valid for by default when a host is attached, for the host to install and join with it
+const DefaultBootstrapTokenMinTTL = 5 * time.Minute
+
+var (
+	// joinConfigurationRegexp matches the kubeadm JoinConfiguration of the bootstrap data of a joining machine
+	joinConfigurationRegexp = regexp.MustCompile(`(?m)^\s*kind:\s*JoinConfiguration\s*$`)
+	// discoveryTokenRegexp matches the bootstrap token the JoinConfiguration discovers the cluster with
+	discoveryTokenRegexp = regexp.MustCompile(`(?m)^\s*token:\s*["']?([a-z0-9]{6})\.[a-z0-9]{16}["']?\s*$`)
+)
+
+// bootstrapTokenID returns the id of the bootstrap token the kubeadm JoinConfiguration of bootstrapData
+// joins the cluster with. The bootstrap data of the first control plane machine, which initializes the
+// cluster, has none.
+func bootstrapTokenID(bootstrapData []byte) (string, bool) {
+	if !joinConfigurationRegexp.Match(bootstrapData) {
+		return "", false
+	}
+	match := discoveryTokenRegexp.FindSubmatch(bootstrapData)
+	if match == nil {
+		return "", false
+	}
+	return string(match[1]), true
+}
+
+// reconcileBootstrapToken checks, before a host is attached to the machine, that the bootstrap token of
+// its bootstrap data is still valid for BootstrapTokenMinTTL, so that the host is not handed bootstrap
+// data it is bound to fail to join with. If the token expires sooner its refresh is requested from the
+// bootstrap provider and the attach is requeued.
+func (r *ByoMachineReconciler) reconcileBootstrapToken(ctx context.Context, machineScope *byoMachineScope) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: machineScope.ByoMachine.Namespace, Name: *machineScope.Machine.Spec.Bootstrap.DataSecretName}
+	if err := r.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			// the agent reports the missing secret once the host is attached
+			logger.V(4).Info("Bootstrap data secret not found, not checking its bootstrap token", "secret", key.Name)
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	tokenID, ok := bootstrapTokenID(secret.Data["value"])
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	expiration, err := r.bootstrapTokenExpiration(ctx, machineScope, tokenID)
+	if err != nil {
+		logger.Error(err, "failed to get the expiration of the bootstrap token", "tokenID", tokenID)
+		return ctrl.Result{}, err
+	}
+	if expiration == nil || time.Until(*expiration) >= bootstrapTokenMinTTL(r.BootstrapTokenMinTTL) {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Bootstrap token expires too soon for a host to join with it, requesting its refresh", "tokenID", tokenID, "expiration", expiration)
+	if err = r.requestBootstrapTokenRefresh(ctx, machineScope); err != nil {
+		logger.Error(err, "failed to request the refresh of the bootstrap token", "tokenID", tokenID)
+		return ctrl.Result{}, err
+	}
+	message := fmt.Sprintf("bootstrap token %s expired", tokenID)
+	if !expiration.IsZero() {
+		message = fmt.Sprintf("bootstrap token %s expires at %s", tokenID, expiration.UTC().Format(time.RFC3339))
+	}
+	r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "BootstrapTokenExpiring", "Requested the refresh of the bootstrap data, %s", message)
+	conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.BootstrapTokenExpiringReason, clusterv1.ConditionSeverityInfo, "%s", message)
+	return ctrl.Result{RequeueAfter: RequeueForbyohost}, nil
+}
+
+// bootstrapTokenExpiration returns the expiration of the bootstrap token tokenID of the workload cluster,
+// nil if it does not expire, or the zero time if it does not exist anymore, e.g. removed once expired
+func (r *ByoMachineReconciler) bootstrapTokenExpiration(ctx context.Context, machineScope *byoMachineScope, tokenID string) (*time.Time, error) {
+	remoteClient, err := r.getRemoteClient(ctx, machineScope.ByoMachine)
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: metav1.NamespaceSystem, Name: bootstraputil.BootstrapTokenSecretName(tokenID)}
+	if err = remoteClient.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &time.Time{}, nil
+		}
+		return nil, err
+	}
+	value, ok := secret.Data[bootstrapapi.BootstrapTokenExpirationKey]
+	if !ok {
+		return nil, nil
+	}
+	expiration, err := time.Parse(time.RFC3339, string(value))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration of bootstrap token %s: %w", tokenID, err)
+	}
+	return &expiration, nil
+}
+
+// requestBootstrapTokenRefresh annotates the bootstrap config of the machine with the
+// BootstrapTokenRefreshAnnotation, so that the bootstrap provider reconciles it and refreshes
+// its bootstrap token. The kubeadm bootstrap provider extends the expiration of the tokens
+// of the machines that have not joined yet on every reconcile.
+func (r *ByoMachineReconciler) requestBootstrapTokenRefresh(ctx context.Context, machineScope *byoMachineScope) error {
+	configRef := machineScope.Machine.Spec.Bootstrap.ConfigRef
+	if configRef == nil {
+		log.FromContext(ctx).Info("Machine has no bootstrap config, cannot request the refresh of its bootstrap token")
+		return nil
+	}
+	config, err := external.Get(ctx, r.Client, configRef, machineScope.Machine.Namespace)
+	if err != nil {
+		return err
+	}
+	helper, err := patch.NewHelper(config, r.Client)
+	if err != nil {
+		return err
+	}
+	configAnnotations := config.GetAnnotations()
+	if configAnnotations == nil {
+		configAnnotations = map[string]string{}
+	}
+	configAnnotations[infrav1.BootstrapTokenRefreshAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	config.SetAnnotations(configAnnotations)
+	return helper.Patch(ctx, config)
+}
+
+// bootstrapTokenMinTTL returns minTTL, or DefaultBootstrapTokenMinTTL if it is not set
+func bootstrapTokenMinTTL(minTTL time.Duration) time.Duration {
+	if minTTL <= 0 {
+		return DefaultBootstrapTokenMinTTL
+	}
+	return minTTL
+}
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers
@@ -10,11 +11,19 @@ import (
 
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/bootstraptoken"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	"k8s.io/client-go/tools/record"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
 	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -23,21 +32,30 @@ import (
 // BootstrapKubeconfigReconciler reconciles a BootstrapKubeconfig object
 type BootstrapKubeconfigReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	// APIReader reads the bootstrap token secrets, which are not cached by the manager
+	APIReader client.Reader
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
 }
 
 const (
 	// ttl is the time to live for the generated bootstrap token
 	ttl = time.Minute * 30
+	// tokenStatusCheckInterval is how often a bootstrap token is checked for revocation
+	tokenStatusCheckInterval = time.Minute
 )
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=bootstrapkubeconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=bootstrapkubeconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=bootstrapkubeconfigs/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *BootstrapKubeconfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *BootstrapKubeconfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconcile request received")
 
@@ -51,59 +69,150 @@ func (r *BootstrapKubeconfigReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, err
 	}
 
+	helper, err := patch.NewHelper(bootstrapKubeconfig, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	defer func() {
+		if err := helper.Patch(ctx, bootstrapKubeconfig); err != nil && reterr == nil {
+			logger.Error(err, "failed to patch BootstrapKubeconfig")
+			reterr = err
+		}
+	}()
+
 	// There already is bootstrap-kubeconfig data associated with this object
 	// Do not create secrets again
 	if bootstrapKubeconfig.Status.BootstrapKubeconfigData != nil {
-		return ctrl.Result{}, nil
+		return r.reconcileTokenStatus(ctx, bootstrapKubeconfig)
+	}
+
+	// the data is generated again when it is cleared from the status, e.g. to replace
+	// an expired or revoked token
+	renewed := conditions.IsTrue(bootstrapKubeconfig, infrastructurev1beta1.TokenGenerated)
+	if renewed {
+		if err = r.deleteTokenSecret(ctx, bootstrapKubeconfig); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err = r.generateBootstrapKubeconfig(ctx, bootstrapKubeconfig); err != nil {
+		conditions.MarkFalse(bootstrapKubeconfig, infrastructurev1beta1.TokenGenerated, infrastructurev1beta1.TokenGenerationFailedReason, clusterv1.ConditionSeverityWarning, "%v", err)
+		r.Recorder.Eventf(bootstrapKubeconfig, corev1.EventTypeWarning, "BootstrapKubeconfigGenerationFailed", "Failed to generate bootstrap kubeconfig: %v", err)
+		return ctrl.Result{}, err
 	}
 
+	conditions.MarkTrue(bootstrapKubeconfig, infrastructurev1beta1.TokenGenerated)
+	conditions.MarkTrue(bootstrapKubeconfig, infrastructurev1beta1.TokenValid)
+	if renewed {
+		r.Recorder.Eventf(bootstrapKubeconfig, corev1.EventTypeNormal, "BootstrapKubeconfigRenewed", "Renewed bootstrap kubeconfig with token %s", bootstrapKubeconfig.Status.TokenSecretRef.Name)
+	} else {
+		r.Recorder.Eventf(bootstrapKubeconfig, corev1.EventTypeNormal, "BootstrapKubeconfigIssued", "Issued bootstrap kubeconfig with token %s", bootstrapKubeconfig.Status.TokenSecretRef.Name)
+	}
+
+	return ctrl.Result{RequeueAfter: tokenStatusCheckInterval}, nil
+}
+
+// generateBootstrapKubeconfig creates a bootstrap token secret and sets the bootstrap kubeconfig
+// authenticating with it, the token secret and its expiration in the status
+func (r *BootstrapKubeconfigReconciler) generateBootstrapKubeconfig(ctx context.Context, bootstrapKubeconfig *infrastructurev1beta1.BootstrapKubeconfig) error {
 	tokenStr, err := bootstraputil.GenerateBootstrapToken()
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
 	bootstrapKubeconfigSecret, err := bootstraptoken.GenerateSecretFromBootstrapToken(tokenStr, ttl)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
+	}
+	expiration, err := time.Parse(time.RFC3339, string(bootstrapKubeconfigSecret.Data[bootstrapapi.BootstrapTokenExpirationKey]))
+	if err != nil {
+		return err
 	}
 
 	// create secret
 	err = r.Create(ctx, bootstrapKubeconfigSecret)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
 	bootstrapKubeconfigData, err := bootstraptoken.GenerateBootstrapKubeconfigFromBootstrapToken(tokenStr, bootstrapKubeconfig)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
 	caData := bootstrapKubeconfigData.Clusters[infrastructurev1beta1.DefaultClusterName].CertificateAuthorityData
 	decodedCAData, err := b64.StdEncoding.DecodeString(string(caData))
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
 	bootstrapKubeconfigData.Clusters[infrastructurev1beta1.DefaultClusterName].CertificateAuthorityData = decodedCAData
 	runtimeEncodedBootstrapKubeConfig, err := runtime.Encode(clientcmdlatest.Codec, bootstrapKubeconfigData)
 	if err != nil {
-		return ctrl.Result{}, err
+		return err
 	}
 
-	helper, err := patch.NewHelper(bootstrapKubeconfig, r.Client)
+	bootstrapKubeconfigDataStr := string(runtimeEncodedBootstrapKubeConfig)
+	bootstrapKubeconfig.Status.BootstrapKubeconfigData = &bootstrapKubeconfigDataStr
+	bootstrapKubeconfig.Status.TokenSecretRef = &corev1.ObjectReference{
+		Kind:      "Secret",
+		Namespace: bootstrapKubeconfigSecret.Namespace,
+		Name:      bootstrapKubeconfigSecret.Name,
+	}
+	bootstrapKubeconfig.Status.TokenExpiration = &metav1.Time{Time: expiration}
+	return nil
+}
+
+// deleteTokenSecret deletes the secret of the bootstrap token being replaced, so that a renewed
+// kubeconfig does not leave a still valid token behind
+func (r *BootstrapKubeconfigReconciler) deleteTokenSecret(ctx context.Context, bootstrapKubeconfig *infrastructurev1beta1.BootstrapKubeconfig) error {
+	ref := bootstrapKubeconfig.Status.TokenSecretRef
+	if ref == nil {
+		return nil
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name}}
+	if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// reconcileTokenStatus marks the bootstrap token invalid once its expiration passed, or if its
+// secret got deleted before, and checks it again periodically until either happens
+func (r *BootstrapKubeconfigReconciler) reconcileTokenStatus(ctx context.Context, bootstrapKubeconfig *infrastructurev1beta1.BootstrapKubeconfig) (ctrl.Result, error) {
+	status := bootstrapKubeconfig.Status
+	if status.TokenSecretRef == nil || status.TokenExpiration == nil ||
+		conditions.IsFalse(bootstrapKubeconfig, infrastructurev1beta1.TokenValid) {
+		return ctrl.Result{}, nil
+	}
+
+	untilExpiration := time.Until(status.TokenExpiration.Time)
+	if untilExpiration <= 0 {
+		conditions.MarkFalse(bootstrapKubeconfig, infrastructurev1beta1.TokenValid, infrastructurev1beta1.TokenExpiredReason, clusterv1.ConditionSeverityInfo, "")
+		r.Recorder.Eventf(bootstrapKubeconfig, corev1.EventTypeNormal, "BootstrapTokenExpired", "Bootstrap token %s expired", status.TokenSecretRef.Name)
+		return ctrl.Result{}, nil
+	}
+
+	secret := &corev1.Secret{}
+	err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: status.TokenSecretRef.Namespace, Name: status.TokenSecretRef.Name}, secret)
 	if err != nil {
+		if apierrors.IsNotFound(err) {
+			conditions.MarkFalse(bootstrapKubeconfig, infrastructurev1beta1.TokenValid, infrastructurev1beta1.TokenRevokedReason, clusterv1.ConditionSeverityWarning, "")
+			r.Recorder.Eventf(bootstrapKubeconfig, corev1.EventTypeWarning, "BootstrapTokenRevoked", "Bootstrap token %s was deleted before it expired", status.TokenSecretRef.Name)
+			return ctrl.Result{}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
-	bootstrapKubeconfigDataStr := string(runtimeEncodedBootstrapKubeConfig)
-	bootstrapKubeconfig.Status.BootstrapKubeconfigData = &bootstrapKubeconfigDataStr
-
-	return ctrl.Result{}, helper.Patch(ctx, bootstrapKubeconfig)
+	if untilExpiration > tokenStatusCheckInterval {
+		return ctrl.Result{RequeueAfter: tokenStatusCheckInterval}, nil
+	}
+	return ctrl.Result{RequeueAfter: untilExpiration}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *BootstrapKubeconfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrastructurev1beta1.BootstrapKubeconfig{}).
+		WithEventFilter(predicates.ResourceHasFilterLabel(mgr.GetLogger(), r.WatchFilterValue)).
 		Complete(r)
 }
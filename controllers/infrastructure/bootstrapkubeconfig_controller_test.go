@@ -1,20 +1,27 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers_test
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	b64 "encoding/base64"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	eventutils "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/utils/events"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -42,6 +49,7 @@ var _ = Describe("Controllers/BoottrapKubeconfigController", func() {
 
 	Context("When BootstrapKubeconfig CRD is created", func() {
 		BeforeEach(func() {
+			eventutils.DrainEvents(recorder.Events)
 			var clientErr error
 			k8sClientUncached, clientErr = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 			Expect(clientErr).NotTo(HaveOccurred())
@@ -96,6 +104,120 @@ var _ = Describe("Controllers/BoottrapKubeconfigController", func() {
 
 		})
 
+		It("should mark the token generated and emit an event when the bootstrap kubeconfig is issued", func() {
+			res, err := bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: bootstrapKubeconfigLookupKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.RequeueAfter).To(BeNumerically(">", 0))
+
+			createdBootstrapKubeconfig := &infrav1.BootstrapKubeconfig{}
+			Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, createdBootstrapKubeconfig)).Should(Succeed())
+			Expect(conditions.IsTrue(createdBootstrapKubeconfig, infrav1.TokenGenerated)).To(BeTrue())
+			Expect(conditions.IsTrue(createdBootstrapKubeconfig, infrav1.TokenValid)).To(BeTrue())
+			Expect(createdBootstrapKubeconfig.Status.TokenSecretRef).NotTo(BeNil())
+			Expect(createdBootstrapKubeconfig.Status.TokenSecretRef.Namespace).To(Equal(metav1.NamespaceSystem))
+			Expect(createdBootstrapKubeconfig.Status.TokenExpiration).NotTo(BeNil())
+			Expect(createdBootstrapKubeconfig.Status.TokenExpiration.Time).To(BeTemporally("~", time.Now().Add(30*time.Minute), time.Minute))
+
+			tokenSecret := &corev1.Secret{}
+			Expect(k8sClientUncached.Get(ctx, types.NamespacedName{
+				Namespace: createdBootstrapKubeconfig.Status.TokenSecretRef.Namespace,
+				Name:      createdBootstrapKubeconfig.Status.TokenSecretRef.Name,
+			}, tokenSecret)).Should(Succeed())
+
+			events := eventutils.CollectEvents(recorder.Events)
+			Expect(events).Should(ConsistOf([]string{
+				fmt.Sprintf("Normal BootstrapKubeconfigIssued Issued bootstrap kubeconfig with token %s", createdBootstrapKubeconfig.Status.TokenSecretRef.Name),
+			}))
+		})
+
+		It("should mark the token revoked if its secret is deleted before it expires", func() {
+			_, err := bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: bootstrapKubeconfigLookupKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			createdBootstrapKubeconfig := &infrav1.BootstrapKubeconfig{}
+			Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, createdBootstrapKubeconfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(createdBootstrapKubeconfig, func(object client.Object) bool {
+				return object.(*infrav1.BootstrapKubeconfig).Status.TokenSecretRef != nil
+			})
+			Expect(k8sClientUncached.Delete(ctx, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Namespace: createdBootstrapKubeconfig.Status.TokenSecretRef.Namespace,
+				Name:      createdBootstrapKubeconfig.Status.TokenSecretRef.Name,
+			}})).Should(Succeed())
+
+			Eventually(func() bool {
+				_, err := bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: bootstrapKubeconfigLookupKey})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, createdBootstrapKubeconfig)).Should(Succeed())
+				return conditions.IsFalse(createdBootstrapKubeconfig, infrav1.TokenValid)
+			}).Should(BeTrue())
+			Expect(conditions.GetReason(createdBootstrapKubeconfig, infrav1.TokenValid)).To(Equal(infrav1.TokenRevokedReason))
+			Expect(eventutils.CollectEvents(recorder.Events)).Should(ContainElement(
+				fmt.Sprintf("Warning BootstrapTokenRevoked Bootstrap token %s was deleted before it expired", createdBootstrapKubeconfig.Status.TokenSecretRef.Name),
+			))
+		})
+
+		It("should mark the token expired once its expiration passed", func() {
+			helper, err := patch.NewHelper(bootstrapKubeConfig, k8sClientUncached)
+			Expect(err).NotTo(HaveOccurred())
+			bootstrapKubeConfig.Status.BootstrapKubeconfigData = &existingBootstrapKubeconfigData
+			bootstrapKubeConfig.Status.TokenSecretRef = &corev1.ObjectReference{Kind: "Secret", Namespace: metav1.NamespaceSystem, Name: "bootstrap-token-abcdef"}
+			bootstrapKubeConfig.Status.TokenExpiration = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+			Expect(helper.Patch(ctx, bootstrapKubeConfig)).NotTo(HaveOccurred())
+			WaitForObjectToBeUpdatedInCache(bootstrapKubeConfig, func(object client.Object) bool {
+				return object.(*infrav1.BootstrapKubeconfig).Status.TokenExpiration != nil
+			})
+
+			res, err := bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: bootstrapKubeconfigLookupKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(ctrl.Result{}))
+
+			updatedBootstrapKubeconfig := &infrav1.BootstrapKubeconfig{}
+			Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, updatedBootstrapKubeconfig)).Should(Succeed())
+			Expect(conditions.IsFalse(updatedBootstrapKubeconfig, infrav1.TokenValid)).To(BeTrue())
+			Expect(conditions.GetReason(updatedBootstrapKubeconfig, infrav1.TokenValid)).To(Equal(infrav1.TokenExpiredReason))
+			Expect(eventutils.CollectEvents(recorder.Events)).Should(ConsistOf([]string{
+				"Normal BootstrapTokenExpired Bootstrap token bootstrap-token-abcdef expired",
+			}))
+		})
+
+		It("should renew the bootstrap kubeconfig when its data is cleared", func() {
+			_, err := bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: bootstrapKubeconfigLookupKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			issuedBootstrapKubeconfig := &infrav1.BootstrapKubeconfig{}
+			Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, issuedBootstrapKubeconfig)).Should(Succeed())
+			issuedTokenSecretRef := issuedBootstrapKubeconfig.Status.TokenSecretRef
+			helper, err := patch.NewHelper(issuedBootstrapKubeconfig, k8sClientUncached)
+			Expect(err).NotTo(HaveOccurred())
+			issuedBootstrapKubeconfig.Status.BootstrapKubeconfigData = nil
+			Expect(helper.Patch(ctx, issuedBootstrapKubeconfig)).NotTo(HaveOccurred())
+			WaitForObjectToBeUpdatedInCache(issuedBootstrapKubeconfig, func(object client.Object) bool {
+				updated := object.(*infrav1.BootstrapKubeconfig)
+				return updated.Status.BootstrapKubeconfigData == nil && conditions.IsTrue(updated, infrav1.TokenGenerated)
+			})
+			eventutils.DrainEvents(recorder.Events)
+
+			_, err = bootstrapKubeconfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: bootstrapKubeconfigLookupKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			renewedBootstrapKubeconfig := &infrav1.BootstrapKubeconfig{}
+			Expect(k8sClientUncached.Get(ctx, bootstrapKubeconfigLookupKey, renewedBootstrapKubeconfig)).Should(Succeed())
+			Expect(renewedBootstrapKubeconfig.Status.BootstrapKubeconfigData).NotTo(BeNil())
+			Expect(renewedBootstrapKubeconfig.Status.TokenSecretRef.Name).NotTo(Equal(issuedTokenSecretRef.Name))
+			// the replaced token must not stay usable
+			err = k8sClientUncached.Get(ctx, types.NamespacedName{Namespace: issuedTokenSecretRef.Namespace, Name: issuedTokenSecretRef.Name}, &corev1.Secret{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			Expect(eventutils.CollectEvents(recorder.Events)).Should(ConsistOf([]string{
+				fmt.Sprintf("Normal BootstrapKubeconfigRenewed Renewed bootstrap kubeconfig with token %s", renewedBootstrapKubeconfig.Status.TokenSecretRef.Name),
+			}))
+		})
+
 		AfterEach(func() {
 			Expect(k8sClientUncached.Delete(ctx, bootstrapKubeConfig)).ToNot(HaveOccurred())
 		})
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers
@@ -11,10 +12,12 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
@@ -26,10 +29,11 @@ import (
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 )
 
 // DefaultAPIEndpointPort default port for the API endpoint
-const DefaultAPIEndpointPort int32 = 6443
+const DefaultAPIEndpointPort = infrav1.DefaultAPIEndpointPort
 
 var (
 	clusterControlledType     = &infrav1.ByoCluster{}
@@ -41,12 +45,15 @@ var (
 type ByoClusterReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byoclusters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byoclusters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byoclusters/finalizers,verbs=update
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohosts,verbs=get;list;watch
 
 // Reconcile handles the byo cluster reconciliations
 func (r *ByoClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
@@ -105,7 +112,7 @@ func (r *ByoClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Handle non-deleted clusters
-	return r.reconcileNormal(ctx, byoCluster)
+	return r.reconcileNormal(ctx, cluster, byoCluster)
 }
 
 func patchByoCluster(ctx context.Context, patchHelper *patch.Helper, byoCluster *infrav1.ByoCluster) error {
@@ -168,7 +175,7 @@ func (r ByoClusterReconciler) reconcileDelete(ctx context.Context, byoCluster *i
 	return ctrl.Result{}, nil
 }
 
-func (r ByoClusterReconciler) reconcileNormal(ctx context.Context, byoCluster *infrav1.ByoCluster) (reconcile.Result, error) {
+func (r ByoClusterReconciler) reconcileNormal(ctx context.Context, cluster *clusterv1.Cluster, byoCluster *infrav1.ByoCluster) (reconcile.Result, error) {
 	// If the ByoCluster doesn't have our finalizer, add it.
 	controllerutil.AddFinalizer(byoCluster, infrav1.ClusterFinalizer)
 
@@ -176,20 +183,87 @@ func (r ByoClusterReconciler) reconcileNormal(ctx context.Context, byoCluster *i
 		byoCluster.Spec.ControlPlaneEndpoint.Port = DefaultAPIEndpointPort
 	}
 
+	failureDomains, err := r.getFailureDomains(ctx, byoCluster.Namespace, cluster.Name)
+	if err != nil {
+		return reconcile.Result{}, errors.Wrapf(err,
+			"unable to compute failure domains of ByoCluster %s/%s", byoCluster.Namespace, byoCluster.Name)
+	}
+	byoCluster.Status.FailureDomains = failureDomains
+
 	byoCluster.Status.Ready = true
 
 	return reconcile.Result{}, nil
 }
 
+// getFailureDomains returns a failure domain for every zone the ByoHosts in the namespace
+// that can run machines of the cluster are labeled with, or nil if none of them is
+func (r ByoClusterReconciler) getFailureDomains(ctx context.Context, namespace, clusterName string) (clusterv1.FailureDomains, error) {
+	listOptions := []client.ListOption{client.InNamespace(namespace), client.HasLabels{infrav1.HostZoneLabel}}
+	if r.WatchFilterValue != "" {
+		listOptions = append(listOptions, client.MatchingLabels{clusterv1.WatchLabel: r.WatchFilterValue})
+	}
+
+	hostsList := &infrav1.ByoHostList{}
+	if err := r.List(ctx, hostsList, listOptions...); err != nil {
+		return nil, err
+	}
+
+	var failureDomains clusterv1.FailureDomains
+	for i := range hostsList.Items {
+		host := &hostsList.Items[i]
+		// a host attached to a machine of another cluster is not available to this one, the
+		// hosts attached to this cluster keep the zones of its machines listed
+		if hostCluster, ok := host.Labels[clusterv1.ClusterNameLabel]; ok && hostCluster != clusterName {
+			continue
+		}
+		if host.Status.MachineRef != nil && host.Labels[clusterv1.ClusterNameLabel] != clusterName {
+			continue
+		}
+		zone := host.Labels[infrav1.HostZoneLabel]
+		if zone == "" {
+			continue
+		}
+		if failureDomains == nil {
+			failureDomains = clusterv1.FailureDomains{}
+		}
+		failureDomains[zone] = clusterv1.FailureDomainSpec{ControlPlane: true}
+	}
+	return failureDomains, nil
+}
+
+// byoHostToByoClusters maps a ByoHost to the ByoClusters of its namespace, so that
+// their failure domains follow the zones of the hosts
+func (r *ByoClusterReconciler) byoHostToByoClusters(o client.Object) []reconcile.Request {
+	clustersList := &infrav1.ByoClusterList{}
+	if err := r.List(context.Background(), clustersList, client.InNamespace(o.GetNamespace())); err != nil {
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(clustersList.Items))
+	for i := range clustersList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&clustersList.Items[i]),
+		})
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ByoClusterReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		// Watch the controlled, infrastructure resource.
 		For(clusterControlledType).
+		WithEventFilter(predicates.ResourceHasFilterLabel(ctrl.LoggerFrom(ctx), r.WatchFilterValue)).
 		// Watch the CAPI resource that owns this infrastructure resource.
 		Watches(
 			&source.Kind{Type: &clusterv1.Cluster{}},
 			handler.EnqueueRequestsFromMapFunc(clusterutilv1.ClusterToInfrastructureMapFunc(ctx, infrav1.GroupVersion.WithKind(clusterControlledTypeGVK.Kind), mgr.GetClient(), &infrav1.ByoCluster{})),
 		).
+		// Watch the ByoHosts, whose zones are the failure domains of the cluster.
+		Watches(
+			&source.Kind{Type: &infrav1.ByoHost{}},
+			handler.EnqueueRequestsFromMapFunc(r.byoHostToByoClusters),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
 		Complete(r)
 }
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers_test
@@ -12,6 +13,7 @@ import (
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
 	controllers "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/controllers/infrastructure"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -134,4 +136,57 @@ var _ = Describe("Controllers/ByoclusterController", func() {
 		Expect(createdByoCluster.Spec.ControlPlaneEndpoint.Port).To(Equal(controllers.DefaultAPIEndpointPort))
 	})
 
+	It("should report the zones of the ByoHosts as failure domains", func() {
+		zoneAHost := builder.ByoHost(defaultNamespace, "byohost-zone-a").
+			WithLabels(map[string]string{infrastructurev1beta1.HostZoneLabel: "zone-a"}).
+			Build()
+		Expect(k8sClientUncached.Create(ctx, zoneAHost)).Should(Succeed())
+		zoneBHost := builder.ByoHost(defaultNamespace, "byohost-zone-b").
+			WithLabels(map[string]string{infrastructurev1beta1.HostZoneLabel: "zone-b"}).
+			Build()
+		Expect(k8sClientUncached.Create(ctx, zoneBHost)).Should(Succeed())
+		zoneCHost := builder.ByoHost(defaultNamespace, "byohost-zone-c").
+			WithLabels(map[string]string{
+				infrastructurev1beta1.HostZoneLabel: "zone-c",
+				clusterv1.ClusterNameLabel:          "another-cluster",
+			}).
+			Build()
+		Expect(k8sClientUncached.Create(ctx, zoneCHost)).Should(Succeed())
+		zoneDHost := builder.ByoHost(defaultNamespace, "byohost-zone-d").
+			WithLabels(map[string]string{infrastructurev1beta1.HostZoneLabel: "zone-d"}).
+			Build()
+		Expect(k8sClientUncached.Create(ctx, zoneDHost)).Should(Succeed())
+		zoneDHost.Status.MachineRef = &corev1.ObjectReference{Kind: "ByoMachine", Namespace: defaultNamespace, Name: "another-machine"}
+		Expect(k8sClientUncached.Status().Update(ctx, zoneDHost)).Should(Succeed())
+		WaitForObjectsToBePopulatedInCache(zoneAHost, zoneBHost, zoneCHost, zoneDHost)
+
+		cluster = builder.Cluster(defaultNamespace, "byocluster-failure-domains").
+			Build()
+		Expect(k8sClientUncached.Create(ctx, cluster)).Should(Succeed())
+		WaitForObjectsToBePopulatedInCache(cluster)
+
+		byoCluster = builder.ByoCluster(defaultNamespace, "byocluster-failure-domains").
+			WithOwnerCluster(cluster).
+			Build()
+		Expect(k8sClientUncached.Create(ctx, byoCluster)).Should(Succeed())
+		WaitForObjectsToBePopulatedInCache(byoCluster)
+
+		byoClusterLookupKey := types.NamespacedName{Name: byoCluster.Name, Namespace: byoCluster.Namespace}
+		_, err := byoClusterReconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: byoClusterLookupKey})
+		Expect(err).NotTo(HaveOccurred())
+
+		createdByoCluster := &infrastructurev1beta1.ByoCluster{}
+		err = k8sClientUncached.Get(ctx, byoClusterLookupKey, createdByoCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(createdByoCluster.Status.FailureDomains).To(HaveKeyWithValue("zone-a", clusterv1.FailureDomainSpec{ControlPlane: true}))
+		Expect(createdByoCluster.Status.FailureDomains).To(HaveKeyWithValue("zone-b", clusterv1.FailureDomainSpec{ControlPlane: true}))
+		Expect(createdByoCluster.Status.FailureDomains).NotTo(HaveKey("zone-c"))
+		Expect(createdByoCluster.Status.FailureDomains).NotTo(HaveKey("zone-d"))
+
+		for _, host := range []*infrastructurev1beta1.ByoHost{zoneAHost, zoneBHost, zoneCHost, zoneDHost} {
+			Expect(k8sClientUncached.Delete(ctx, host)).Should(Succeed())
+		}
+	})
+
 })
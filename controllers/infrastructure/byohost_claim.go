@@ -0,0 +1,70 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// HostClaimTTL is how long the claim of a host by a ByoMachine keeps the other ByoMachines from selecting
+// it. The claim is followed by the attach of the host in the same reconcile, the claims of the reconciles
+// that failed in between expire.
+const HostClaimTTL = time.Minute
+
+// errHostClaimLost is returned when the host was claimed or attached by another ByoMachine
+// between its selection and its claim
+var errHostClaimLost = errors.New("host claimed by another ByoMachine")
+
+// claimByoHost claims host for the ByoMachine before it is attached. The claim is a patch with an
+// optimistic lock on the resource version of the host the ByoMachine selected, so that it fails with
+// a conflict if the host changed since, e.g. because another ByoMachine reconciled concurrently claimed
+// it first. The host is updated to the claimed one, which the attach then patches.
+func (r *ByoMachineReconciler) claimByoHost(ctx context.Context, machineScope *byoMachineScope, host *infrav1.ByoHost) error {
+	uid := machineScope.ByoMachine.UID
+	if host.IsClaimedByOther(uid, time.Now(), HostClaimTTL) {
+		return errHostClaimLost
+	}
+	base := host.DeepCopy()
+	if host.Annotations == nil {
+		host.Annotations = make(map[string]string)
+	}
+	host.Annotations[infrav1.ClaimedByAnnotation] = string(uid)
+	host.Annotations[infrav1.ClaimedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Patch(ctx, host, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{})); err != nil {
+		return err
+	}
+
+	// verify the claim against the host as written, the selection works on the cached hosts
+	if host.Annotations[infrav1.ClaimedByAnnotation] != string(uid) || host.Status.MachineRef != nil ||
+		host.Labels[clusterv1.ClusterNameLabel] != "" {
+		return errHostClaimLost
+	}
+	log.FromContext(ctx).V(4).Info("Claimed ByoHost", "byohost", host.Name)
+	return nil
+}
+
+// releaseByoHostClaim drops the claim of host, once attached the MachineRef of the host keeps
+// the other ByoMachines from selecting it
+func releaseByoHostClaim(host *infrav1.ByoHost) {
+	delete(host.Annotations, infrav1.ClaimedByAnnotation)
+	delete(host.Annotations, infrav1.ClaimedAtAnnotation)
+}
+
+// filterUnclaimedByoHosts drops the hosts claimed by ByoMachines other than the one of machineScope
+func filterUnclaimedByoHosts(hosts []infrav1.ByoHost, machineScope *byoMachineScope, now time.Time) []infrav1.ByoHost {
+	unclaimed := make([]infrav1.ByoHost, 0, len(hosts))
+	for i := range hosts {
+		if !hosts[i].IsClaimedByOther(machineScope.ByoMachine.UID, now, HostClaimTTL) {
+			unclaimed = append(unclaimed, hosts[i])
+		}
+	}
+	return unclaimed
+}
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers
@@ -6,23 +7,41 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/notification"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 )
 
+// DefaultHeartbeatTimeout is how long the agent of a host may not report a heartbeat before
+// the host is considered disconnected by default
+const DefaultHeartbeatTimeout = 5 * time.Minute
+
 // ByoHostReconciler reconciles a ByoHost object
 type ByoHostReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+	// HeartbeatTimeout is how long the agent may not report a heartbeat before AgentConnected
+	// is marked false, defaults to DefaultHeartbeatTimeout. The HeartbeatTimeoutAnnotation of
+	// the hosts attached to a ByoCluster that sets its own timeout overrides it.
+	HeartbeatTimeout time.Duration
+	// Notifier is notified of the hosts marked disconnected, nothing is notified if nil
+	Notifier notification.Notifier
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohosts,verbs=get;list;watch;create;update;patch;delete
@@ -33,12 +52,23 @@ type ByoHostReconciler struct {
 
 func (r *ByoHostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
+	ctx, span := tracing.Start(ctx, "ByoHost.Reconcile", tracing.String(tracing.HostNameKey, req.Name))
+	defer func() {
+		span.End(reterr)
+	}()
 
 	byoHost := &infrastructurev1beta1.ByoHost{}
 	if err := r.Get(ctx, req.NamespacedName, byoHost); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	if byoHost.IsPaused() {
+		logger.Info("ByoHost is paused, not reconciling", "machineRef", byoHost.Status.MachineRef,
+			"lastHeartbeatTime", byoHost.Status.LastHeartbeatTime,
+			"heartbeatStale", byoHost.IsHeartbeatStale(time.Now(), byoHost.GetHeartbeatTimeout(heartbeatTimeout(r.HeartbeatTimeout))))
+		return ctrl.Result{}, nil
+	}
+
 	// Delete the uninstall secret once the agent has completed cleanup.
 	// The agent removes the cleanup annotation as its final step, so absence of
 	// the annotation combined with no machineRef means the host is fully cleaned up.
@@ -76,12 +106,94 @@ func (r *ByoHostReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_
 		logger.Info("cleared uninstallationSecret reference on ByoHost")
 	}
 
+	if err := r.reconcileQuarantine(ctx, byoHost); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return r.reconcileHeartbeat(ctx, byoHost)
+}
+
+// reconcileQuarantine lifts the quarantine of the host once an operator removed its QuarantinedLabel,
+// resetting its count of bootstrap failures
+func (r *ByoHostReconciler) reconcileQuarantine(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) error {
+	if _, quarantined := byoHost.Labels[infrastructurev1beta1.QuarantinedLabel]; quarantined ||
+		!conditions.Has(byoHost, infrastructurev1beta1.Quarantined) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("quarantine label removed, lifting the quarantine of the host")
+	helper, err := patch.NewHelper(byoHost, r.Client)
+	if err != nil {
+		return err
+	}
+	conditions.Delete(byoHost, infrastructurev1beta1.Quarantined)
+	delete(byoHost.Annotations, infrastructurev1beta1.BootstrapFailuresAnnotation)
+	return helper.Patch(ctx, byoHost)
+}
+
+// reconcileHeartbeat marks AgentConnected false once the heartbeat of the agent is stale, and
+// requeues the host for the time it would become stale otherwise
+func (r *ByoHostReconciler) reconcileHeartbeat(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost) (ctrl.Result, error) {
+	heartbeat := byoHost.Status.LastHeartbeatTime
+	if heartbeat == nil {
+		return ctrl.Result{}, nil
+	}
+	timeout := byoHost.GetHeartbeatTimeout(heartbeatTimeout(r.HeartbeatTimeout))
+	if !byoHost.IsHeartbeatStale(time.Now(), timeout) {
+		return ctrl.Result{RequeueAfter: time.Until(heartbeat.Add(timeout)) + time.Second}, nil
+	}
+	if conditions.IsFalse(byoHost, infrastructurev1beta1.AgentConnected) {
+		return ctrl.Result{}, nil
+	}
+
+	log.FromContext(ctx).Info("heartbeat of the agent is stale, marking the host disconnected", "lastHeartbeatTime", heartbeat)
+	helper, err := patch.NewHelper(byoHost, r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	message := "no heartbeat since " + heartbeat.UTC().Format(time.RFC3339)
+	conditions.MarkFalse(byoHost, infrastructurev1beta1.AgentConnected, infrastructurev1beta1.AgentHeartbeatStaleReason,
+		clusterv1.ConditionSeverityWarning, "%s", message)
+	if err := helper.Patch(ctx, byoHost); err != nil {
+		return ctrl.Result{}, err
+	}
+	r.notifyDisconnected(ctx, byoHost, message)
 	return ctrl.Result{}, nil
 }
 
+// notifyDisconnected notifies the Notifier the host was marked disconnected. The notification is
+// not retried, the host is marked disconnected only once until its agent reports a heartbeat again
+func (r *ByoHostReconciler) notifyDisconnected(ctx context.Context, byoHost *infrastructurev1beta1.ByoHost, message string) {
+	if r.Notifier == nil {
+		return
+	}
+	event := notification.Event{
+		Type:      notification.HostDisconnected,
+		Namespace: byoHost.Namespace,
+		HostName:  byoHost.Name,
+		Message:   message,
+		Time:      time.Now(),
+	}
+	if byoHost.Status.MachineRef != nil {
+		event.Machine = byoHost.Status.MachineRef.Name
+	}
+	if err := r.Notifier.Notify(ctx, event); err != nil {
+		log.FromContext(ctx).Error(err, "failed to notify the host is disconnected")
+	}
+}
+
+// heartbeatTimeout returns timeout, or DefaultHeartbeatTimeout if it is not set
+func heartbeatTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultHeartbeatTimeout
+	}
+	return timeout
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ByoHostReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrastructurev1beta1.ByoHost{}).
+		WithEventFilter(predicates.ResourceHasFilterLabel(mgr.GetLogger(), r.WatchFilterValue)).
 		Complete(r)
 }
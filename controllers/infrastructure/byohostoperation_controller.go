@@ -0,0 +1,119 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/notification"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/cluster-api/util/patch"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultHostOperationRetention is how long ByoHostOperation records are kept by default
+const DefaultHostOperationRetention = 90 * 24 * time.Hour
+
+// notificationMaxAge is how long after its completion an operation is still notified, the
+// operations completed before the notifications were turned on are not
+const notificationMaxAge = time.Hour
+
+// operationEvents are the lifecycle events notified for the succeeded operations
+var operationEvents = map[infrastructurev1beta1.ByoHostOperationType]notification.EventType{
+	infrastructurev1beta1.ByoHostOperationOnboard:      notification.HostRegistered,
+	infrastructurev1beta1.ByoHostOperationAttach:       notification.HostAttached,
+	infrastructurev1beta1.ByoHostOperationDecommission: notification.HostDecommissioned,
+}
+
+// ByoHostOperationReconciler notifies the lifecycle events of the ByoHostOperation records and
+// deletes the records older than the retention period
+type ByoHostOperationReconciler struct {
+	client.Client
+	// Retention is how long a record is kept after the operation completed, records are kept forever if zero
+	Retention time.Duration
+	// Notifier is notified of the registrations, attachments and decommissions of hosts, nothing is notified if nil
+	Notifier notification.Notifier
+}
+
+//+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohostoperations,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile notifies the lifecycle event of the ByoHostOperation and deletes it once its retention period is over
+func (r *ByoHostOperationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if r.Retention <= 0 && r.Notifier == nil {
+		return ctrl.Result{}, nil
+	}
+	logger := log.FromContext(ctx)
+
+	operation := &infrastructurev1beta1.ByoHostOperation{}
+	if err := r.Get(ctx, req.NamespacedName, operation); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if err := r.notify(ctx, operation); err != nil {
+		return ctrl.Result{}, err
+	}
+	if r.Retention <= 0 {
+		return ctrl.Result{}, nil
+	}
+
+	if remaining := time.Until(operation.Spec.CompletionTime.Add(r.Retention)); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+	logger.Info("Deleting expired ByoHostOperation", "byohost", operation.Spec.HostName, "operation", operation.Spec.Operation)
+	if err := r.Delete(ctx, operation); err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// notify notifies the lifecycle event of a recently succeeded operation once, the operation is
+// notified again if notifying or marking it notified fails
+func (r *ByoHostOperationReconciler) notify(ctx context.Context, operation *infrastructurev1beta1.ByoHostOperation) error {
+	eventType, ok := operationEvents[operation.Spec.Operation]
+	if r.Notifier == nil || !ok ||
+		operation.Spec.Outcome != infrastructurev1beta1.ByoHostOperationSucceeded ||
+		time.Since(operation.Spec.CompletionTime.Time) > notificationMaxAge {
+		return nil
+	}
+	if _, notified := operation.Annotations[infrastructurev1beta1.ByoHostOperationNotifiedAnnotation]; notified {
+		return nil
+	}
+
+	event := notification.Event{
+		Type:      eventType,
+		Namespace: operation.Namespace,
+		HostName:  operation.Spec.HostName,
+		Time:      operation.Spec.CompletionTime.Time,
+	}
+	if operation.Spec.MachineRef != nil {
+		event.Machine = operation.Spec.MachineRef.Name
+	}
+	if operation.Spec.Initiator != "" {
+		event.Message = "initiated by " + operation.Spec.Initiator
+	}
+	if err := r.Notifier.Notify(ctx, event); err != nil {
+		return err
+	}
+
+	helper, err := patch.NewHelper(operation, r.Client)
+	if err != nil {
+		return err
+	}
+	if operation.Annotations == nil {
+		operation.Annotations = map[string]string{}
+	}
+	operation.Annotations[infrastructurev1beta1.ByoHostOperationNotifiedAnnotation] = ""
+	return helper.Patch(ctx, operation)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ByoHostOperationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&infrastructurev1beta1.ByoHostOperation{}).
+		Complete(r)
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/notification"
+	controllers "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/controllers/infrastructure"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// recordingNotifier records the events it is notified of
+type recordingNotifier struct {
+	events []notification.Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event notification.Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+var _ = Describe("Controllers/ByoHostOperationController", func() {
+	var (
+		ctx       = context.Background()
+		lookupKey = types.NamespacedName{Name: "my-host-attach-abcde", Namespace: defaultNamespace}
+		c         client.Client
+	)
+
+	newOperation := func(completed time.Time) *infrav1.ByoHostOperation {
+		return &infrav1.ByoHostOperation{
+			ObjectMeta: metav1.ObjectMeta{Name: lookupKey.Name, Namespace: lookupKey.Namespace},
+			Spec: infrav1.ByoHostOperationSpec{
+				HostName:       defaultByoHostName,
+				Operation:      infrav1.ByoHostOperationAttach,
+				Initiator:      "byomachine-controller",
+				StartTime:      metav1.NewTime(completed.Add(-time.Second)),
+				CompletionTime: metav1.NewTime(completed),
+				Outcome:        infrav1.ByoHostOperationSucceeded,
+			},
+		}
+	}
+
+	It("should delete a record older than the retention", func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newOperation(time.Now().Add(-2 * time.Hour))).Build()
+		r := &controllers.ByoHostOperationReconciler{Client: c, Retention: time.Hour}
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+		err = c.Get(ctx, lookupKey, &infrav1.ByoHostOperation{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should requeue a record until the end of the retention", func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newOperation(time.Now().Add(-30 * time.Minute))).Build()
+		r := &controllers.ByoHostOperationReconciler{Client: c, Retention: time.Hour}
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 30*time.Minute, time.Minute))
+		Expect(c.Get(ctx, lookupKey, &infrav1.ByoHostOperation{})).To(Succeed())
+	})
+
+	It("should keep the records if the retention is zero", func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newOperation(time.Now().Add(-24 * time.Hour))).Build()
+		r := &controllers.ByoHostOperationReconciler{Client: c}
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(c.Get(ctx, lookupKey, &infrav1.ByoHostOperation{})).To(Succeed())
+	})
+
+	It("should notify a succeeded attachment once", func() {
+		operation := newOperation(time.Now())
+		operation.Spec.MachineRef = &corev1.ObjectReference{Kind: "ByoMachine", Name: "my-machine", Namespace: defaultNamespace}
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(operation).Build()
+		notifier := &recordingNotifier{}
+		r := &controllers.ByoHostOperationReconciler{Client: c, Notifier: notifier}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(notifier.events).To(HaveLen(1))
+		Expect(notifier.events[0].Type).To(Equal(notification.HostAttached))
+		Expect(notifier.events[0].HostName).To(Equal(defaultByoHostName))
+		Expect(notifier.events[0].Machine).To(Equal("my-machine"))
+		updated := &infrav1.ByoHostOperation{}
+		Expect(c.Get(ctx, lookupKey, updated)).To(Succeed())
+		Expect(updated.Annotations).To(HaveKey(infrav1.ByoHostOperationNotifiedAnnotation))
+	})
+
+	It("should notify the operation again if the notification failed", func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(newOperation(time.Now())).Build()
+		notifier := &recordingNotifier{err: errors.New("webhook unavailable")}
+		r := &controllers.ByoHostOperationReconciler{Client: c, Notifier: notifier}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).To(MatchError("webhook unavailable"))
+		updated := &infrav1.ByoHostOperation{}
+		Expect(c.Get(ctx, lookupKey, updated)).To(Succeed())
+		Expect(updated.Annotations).NotTo(HaveKey(infrav1.ByoHostOperationNotifiedAnnotation))
+	})
+
+	It("should not notify failed, detach or old operations", func() {
+		failed := newOperation(time.Now())
+		failed.Spec.Outcome = infrav1.ByoHostOperationFailed
+		detach := newOperation(time.Now())
+		detach.Name = "my-host-detach-abcde"
+		detach.Spec.Operation = infrav1.ByoHostOperationDetach
+		old := newOperation(time.Now().Add(-2 * time.Hour))
+		old.Name = "my-host-attach-fghij"
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(failed, detach, old).Build()
+		notifier := &recordingNotifier{}
+		r := &controllers.ByoHostOperationReconciler{Client: c, Notifier: notifier}
+
+		for _, operation := range []*infrav1.ByoHostOperation{failed, detach, old} {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(operation)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(notifier.events).To(BeEmpty())
+	})
+
+	It("should ignore a deleted record", func() {
+		c = fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+		r := &controllers.ByoHostOperationReconciler{Client: c, Retention: time.Hour}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: lookupKey})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
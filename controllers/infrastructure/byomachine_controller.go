@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers
@@ -7,13 +8,17 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -37,6 +42,10 @@ import (
 
 	"github.com/go-logr/logr"
 	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/hostoperation"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/nodetaint"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -53,6 +62,15 @@ const (
 	RequeueForbyohost = 10 * time.Second
 	// RequeueInstallerConfigTime requeue delay for installer config
 	RequeueInstallerConfigTime = 10 * time.Second
+
+	// DefaultQuarantineThreshold is the default number of consecutive bootstrap failures after which a host is quarantined
+	DefaultQuarantineThreshold = 3
+
+	// hostOperationInitiator is the initiator of the attach and detach ByoHostOperations
+	hostOperationInitiator = "byomachine-controller"
+
+	mebibyte = 1 << 20
+	gibibyte = 1 << 30
 )
 
 // ByoMachineReconciler reconciles a ByoMachine object
@@ -61,6 +79,18 @@ type ByoMachineReconciler struct {
 	Scheme   *runtime.Scheme
 	Tracker  *remote.ClusterCacheTracker
 	Recorder record.EventRecorder
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
+	// HeartbeatTimeout is how long the agent of a host may not report a heartbeat before the
+	// host is no longer selected, defaults to DefaultHeartbeatTimeout. The ByoCluster may
+	// override it, like the QuarantineThreshold, for its machines.
+	HeartbeatTimeout time.Duration
+	// QuarantineThreshold is the number of consecutive ByoMachines released from a host without it
+	// having bootstrapped their node after which the host is quarantined, hosts are never quarantined if zero
+	QuarantineThreshold int
+	// BootstrapTokenMinTTL is how long the bootstrap token of the bootstrap data of a machine must still be
+	// valid for when a host is attached to it, defaults to DefaultBootstrapTokenMinTTL
+	BootstrapTokenMinTTL time.Duration
 }
 
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byomachines,verbs=get;list;watch;create;update;patch;delete
@@ -68,9 +98,12 @@ type ByoMachineReconciler struct {
 //+kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byomachines/finalizers,verbs=update
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohosts,verbs=get;list;watch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohosts/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byohostadmissionpolicies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=*,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;machines,verbs=get;list;watch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines,verbs=patch
+// +kubebuilder:rbac:groups=bootstrap.cluster.x-k8s.io,resources=*,verbs=get;patch
 // +kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
@@ -89,6 +122,10 @@ type ByoMachineReconciler struct {
 func (r *ByoMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconcile request received")
+	ctx, span := tracing.Start(ctx, "ByoMachine.Reconcile", tracing.String(tracing.MachineNameKey, req.Name))
+	defer func() {
+		span.End(reterr)
+	}()
 
 	// Fetch the ByoMachine instance
 	byoMachine := &infrav1.ByoMachine{}
@@ -151,6 +188,7 @@ func (r *ByoMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	if refByoHost != nil {
 		logger = logger.WithValues("BYOHost", refByoHost.Name)
+		span.SetAttributes(tracing.String(tracing.HostNameKey, refByoHost.Name))
 	}
 
 	// Create the machine scope
@@ -222,7 +260,10 @@ func (r *ByoMachineReconciler) reconcileDelete(ctx context.Context, machineScope
 	if machineScope.ByoHost != nil {
 		// Add annotation to trigger host cleanup
 		logger.Info("Releasing ByoHost", "byohost", machineScope.ByoHost.Name)
-		if err := r.markHostForCleanup(ctx, machineScope); err != nil {
+		detachStart := time.Now()
+		err := r.markHostForCleanup(ctx, machineScope)
+		r.recordHostOperation(ctx, machineScope.ByoHost, machineScope.ByoMachine, infrav1.ByoHostOperationDetach, detachStart, err)
+		if err != nil {
 			return ctrl.Result{}, err
 		}
 		r.Recorder.Eventf(machineScope.ByoHost, corev1.EventTypeNormal, "ByoHostReleaseSucceeded", "ByoHost Released by %s", machineScope.ByoMachine.Name)
@@ -247,6 +288,11 @@ func (r *ByoMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 		}
 	}
 
+	if err := r.setNodeDrainTimeout(ctx, machineScope); err != nil {
+		logger.Error(err, "set node drain timeout of the machine failed")
+		return ctrl.Result{}, err
+	}
+
 	if machineScope.ByoMachine.Spec.InstallerRef != nil {
 		if err := r.createInstallerConfig(ctx, machineScope); err != nil {
 			logger.Error(err, "create installer config failed")
@@ -269,10 +315,14 @@ func (r *ByoMachineReconciler) reconcileNormal(ctx context.Context, machineScope
 	// If there is not yet an byoHost for this byoMachine,
 	// then pick one from the host capacity pool
 	if machineScope.ByoHost == nil {
+		if res, err := r.reconcileBootstrapToken(ctx, machineScope); err != nil || res.RequeueAfter > 0 {
+			return res, err
+		}
 		logger.Info("Attempting host reservation")
 		if res, err := r.attachByoHost(ctx, machineScope); err != nil {
 			return res, err
 		}
+		tracing.SetAttributes(ctx, tracing.String(tracing.HostNameKey, machineScope.ByoHost.Name))
 		conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.InstallationSecretNotAvailableReason, clusterv1.ConditionSeverityInfo, "")
 		r.Recorder.Eventf(machineScope.ByoHost, corev1.EventTypeNormal, "ByoHostAttachSucceeded", "Attached to ByoMachine %s", machineScope.ByoMachine.Name)
 		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeNormal, "ByoHostAttachSucceeded", "Attached ByoHost %s", machineScope.ByoHost.Name)
@@ -323,6 +373,17 @@ func (r *ByoMachineReconciler) updateNodeProviderID(ctx context.Context, machine
 		return ctrl.Result{}, err
 	}
 
+	if err = r.setNodeLabelsAndTaints(ctx, remoteClient, machineScope.ByoHost); err != nil {
+		logger.Error(err, "failed to set node labels and taints")
+		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "SetNodeLabelsAndTaintsFailed", "Failed to set labels and taints on Node %s", machineScope.ByoHost.Name)
+		return ctrl.Result{}, err
+	}
+
+	if err = r.setNodeRef(ctx, remoteClient, machineScope); err != nil {
+		logger.Error(err, "failed to get node")
+		return ctrl.Result{}, err
+	}
+
 	machineScope.ByoMachine.Spec.ProviderID = providerID
 	machineScope.ByoMachine.Status.Ready = true
 	conditions.MarkTrue(machineScope.ByoMachine, infrav1.BYOHostReady)
@@ -342,6 +403,7 @@ func (r *ByoMachineReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Ma
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(controlledType).
+		WithEventFilter(predicates.ResourceHasFilterLabel(logger, r.WatchFilterValue)).
 		Watches(
 			&source.Kind{Type: &infrav1.ByoHost{}},
 			handler.EnqueueRequestsFromMapFunc(ByoHostToByoMachineMapFunc(controlledTypeGVK)),
@@ -427,6 +489,80 @@ func (r *ByoMachineReconciler) setNodeProviderID(ctx context.Context, remoteClie
 	return node.Spec.ProviderID, helper.Patch(ctx, node)
 }
 
+// setNodeRef records the Node the attached host joined the workload cluster as, and whether it is
+// Ready, in the status of the ByoMachine. The owner Machine watches the Node, so that the ByoMachine
+// is reconciled again when the Node changes.
+func (r *ByoMachineReconciler) setNodeRef(ctx context.Context, remoteClient client.Client, machineScope *byoMachineScope) error {
+	node := &corev1.Node{}
+	key := client.ObjectKey{Name: machineScope.ByoHost.Name, Namespace: machineScope.ByoHost.Namespace}
+	if err := remoteClient.Get(ctx, key, node); err != nil {
+		return err
+	}
+	machineScope.ByoMachine.Status.NodeRef = &corev1.ObjectReference{
+		APIVersion: corev1.SchemeGroupVersion.String(),
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+	}
+	machineScope.ByoMachine.Status.NodeReady = false
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			machineScope.ByoMachine.Status.NodeReady = condition.Status == corev1.ConditionTrue
+		}
+	}
+	return nil
+}
+
+// setNodeLabelsAndTaints applies the node labels and taints requested on the
+// ByoHost spec, and the topology labels of the ByoHost, to the node using client
+// pointing to workload cluster
+func (r *ByoMachineReconciler) setNodeLabelsAndTaints(ctx context.Context, remoteClient client.Client, host *infrav1.ByoHost) error {
+	topologyLabels := getNodeTopologyLabels(host)
+	if len(host.Spec.NodeLabels) == 0 && len(host.Spec.NodeTaints) == 0 && len(topologyLabels) == 0 {
+		return nil
+	}
+
+	node := &corev1.Node{}
+	key := client.ObjectKey{Name: host.Name, Namespace: host.Namespace}
+	if err := remoteClient.Get(ctx, key, node); err != nil {
+		return err
+	}
+
+	helper, err := patch.NewHelper(node, remoteClient)
+	if err != nil {
+		return err
+	}
+
+	if node.Labels == nil {
+		node.Labels = make(map[string]string, len(host.Spec.NodeLabels))
+	}
+	for k, v := range host.Spec.NodeLabels {
+		node.Labels[k] = v
+	}
+	for k, v := range topologyLabels {
+		node.Labels[k] = v
+	}
+
+	node.Spec.Taints = nodetaint.Merge(node.Spec.Taints, host.Spec.NodeTaints)
+
+	return helper.Patch(ctx, node)
+}
+
+// getNodeTopologyLabels maps the zone, room and rack labels of the ByoHost to the
+// labels of its node. The zone is applied as the well-known topology.kubernetes.io/zone.
+func getNodeTopologyLabels(host *infrav1.ByoHost) map[string]string {
+	topologyLabels := make(map[string]string)
+	if zone := host.Labels[infrav1.HostZoneLabel]; zone != "" {
+		topologyLabels[corev1.LabelTopologyZone] = zone
+	}
+	for _, key := range []string{infrav1.HostRoomLabel, infrav1.HostRackLabel} {
+		if value := host.Labels[key]; value != "" {
+			topologyLabels[key] = value
+		}
+	}
+	return topologyLabels
+}
+
 func (r *ByoMachineReconciler) getRemoteClient(ctx context.Context, byoMachine *infrav1.ByoMachine) (client.Client, error) {
 	cluster, err := util.GetClusterFromMetadata(ctx, r.Client, byoMachine.ObjectMeta)
 	if err != nil {
@@ -453,11 +589,27 @@ func (r *ByoMachineReconciler) setPausedConditionForByoHost(ctx context.Context,
 		annotations.AddAnnotations(machineScope.ByoHost, desired)
 	} else {
 		delete(machineScope.ByoHost.Annotations, clusterv1.PausedAnnotation)
+		// clusterctl move does not move the status of the host and gives the ByoMachine a new UID,
+		// the attached ByoMachine label of the host is moved and restores the MachineRef
+		if ref := machineScope.ByoHost.Status.MachineRef; ref == nil || ref.UID != machineScope.ByoMachine.UID {
+			machineScope.ByoHost.Status.MachineRef = byoMachineRef(machineScope.ByoMachine)
+		}
 	}
 
 	return helper.Patch(ctx, machineScope.ByoHost)
 }
 
+// byoMachineRef returns the MachineRef of the ByoHost attached to byoMachine
+func byoMachineRef(byoMachine *infrav1.ByoMachine) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: byoMachine.APIVersion,
+		Kind:       byoMachine.Kind,
+		Namespace:  byoMachine.Namespace,
+		Name:       byoMachine.Name,
+		UID:        byoMachine.UID,
+	}
+}
+
 func (r *ByoMachineReconciler) getInstallerConfigAndHelper(ctx context.Context, machineScope *byoMachineScope) (*unstructured.Unstructured, *patch.Helper, ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
 	installerConfig, ready, err := r.getInstallerConfigAndStatus(ctx, machineScope)
@@ -493,6 +645,23 @@ func (r *ByoMachineReconciler) setInstallationSecretForByoHost(ctx context.Conte
 		return ctrl.Result{}, fmt.Errorf("failed to convert unstructured field, %s", err.Error())
 	}
 	machineScope.ByoHost.Spec.InstallationSecret = secretRef
+
+	// record the socket of the selected container runtime so that the agent
+	// can pass it to kubeadm when resetting the node
+	cri, found, err := unstructured.NestedString(installerConfig.Object, "spec", "cri")
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if found && cri != "" {
+		criSocket, err := installer.CRISocket(cri)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if machineScope.ByoHost.Annotations == nil {
+			machineScope.ByoHost.Annotations = make(map[string]string)
+		}
+		machineScope.ByoHost.Annotations[infrav1.CRISocketAnnotation] = criSocket
+	}
 	return ctrl.Result{}, helper.Patch(ctx, machineScope.ByoHost)
 }
 
@@ -530,56 +699,42 @@ func (r *ByoMachineReconciler) getInstallerConfigAndStatus(ctx context.Context,
 
 func (r *ByoMachineReconciler) attachByoHost(ctx context.Context, machineScope *byoMachineScope) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
-	var selector labels.Selector
-	var err error
 	if machineScope.ByoHost != nil {
 		return ctrl.Result{}, nil
 	}
 
-	hostsList := &infrav1.ByoHostList{}
-	// LabelSelector filter for byohosts
-	if machineScope.ByoMachine.Spec.Selector != nil {
-		selector, err = metav1.LabelSelectorAsSelector(machineScope.ByoMachine.Spec.Selector)
+	var host infrav1.ByoHost
+	if machineScope.ByoMachine.Spec.HostRef != nil {
+		pinnedHost, err := r.resolveHostRef(ctx, machineScope)
 		if err != nil {
-			logger.Error(err, "Label Selector as selector failed")
-			return ctrl.Result{}, err
+			return ctrl.Result{RequeueAfter: RequeueForbyohost}, err
 		}
+		host = *pinnedHost
 	} else {
-		selector = labels.NewSelector()
+		selectedHost, res, err := r.selectAvailableByoHost(ctx, machineScope)
+		if err != nil {
+			return res, err
+		}
+		host = *selectedHost
 	}
+	attachStart := time.Now()
 
-	byohostLabels, _ := labels.NewRequirement(clusterv1.ClusterNameLabel, selection.DoesNotExist, nil)
-	selector = selector.Add(*byohostLabels)
-
-	err = r.List(ctx, hostsList, &client.ListOptions{
-		LabelSelector: selector,
-		Namespace:     machineScope.ByoMachine.Namespace,
-	})
-	if err != nil {
-		logger.Error(err, "failed to list byohosts")
-		return ctrl.Result{RequeueAfter: RequeueForbyohost}, err
-	}
-	if len(hostsList.Items) == 0 {
-		logger.Info("No hosts found, waiting..")
-		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "ByoHostSelectionFailed", "No available ByoHost")
-		conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.BYOHostsUnavailableReason, clusterv1.ConditionSeverityInfo, "")
-		return ctrl.Result{RequeueAfter: RequeueForbyohost}, errors.New("no hosts found")
+	if err := r.claimByoHost(ctx, machineScope, &host); err != nil {
+		if errors.Is(err, errHostClaimLost) || apierrors.IsConflict(err) {
+			// another ByoMachine claimed the host concurrently, select again once the cache caught up
+			logger.Info("ByoHost was claimed by another ByoMachine, selecting again", "byohost", host.Name)
+			return ctrl.Result{RequeueAfter: time.Second}, nil
+		}
+		logger.Error(err, "failed to claim byohost", "byohost", host.Name)
+		return ctrl.Result{}, err
 	}
-	// TODO- Needs smarter logic
-	host := hostsList.Items[0]
 
 	byohostHelper, err := patch.NewHelper(&host, r.Client)
 	if err != nil {
 		logger.Error(err, "Creating patch helper failed")
 	}
 
-	host.Status.MachineRef = &corev1.ObjectReference{
-		APIVersion: machineScope.ByoMachine.APIVersion,
-		Kind:       machineScope.ByoMachine.Kind,
-		Namespace:  machineScope.ByoMachine.Namespace,
-		Name:       machineScope.ByoMachine.Name,
-		UID:        machineScope.ByoMachine.UID,
-	}
+	host.Status.MachineRef = byoMachineRef(machineScope.ByoMachine)
 	// Set the cluster Label
 	hostLabels := host.Labels
 	if hostLabels == nil {
@@ -600,18 +755,356 @@ func (r *ByoMachineReconciler) attachByoHost(ctx context.Context, machineScope *
 	}
 	host.Annotations[infrav1.EndPointIPAnnotation] = machineScope.Cluster.Spec.ControlPlaneEndpoint.Host
 	host.Annotations[infrav1.K8sVersionAnnotation] = strings.Split(*machineScope.Machine.Spec.Version, "+")[0]
-	host.Annotations[infrav1.BundleLookupBaseRegistryAnnotation] = machineScope.ByoCluster.Spec.BundleLookupBaseRegistry
+	host.Annotations[infrav1.BundleLookupBaseRegistryAnnotation] = machineScope.ByoCluster.Spec.GetBundleRegistry()
+	if timeout := machineScope.ByoCluster.Spec.GetHeartbeatTimeout(0); timeout > 0 {
+		host.Annotations[infrav1.HeartbeatTimeoutAnnotation] = timeout.String()
+	} else {
+		delete(host.Annotations, infrav1.HeartbeatTimeoutAnnotation)
+	}
+	if kubeletExtraArgs := machineScope.ByoMachine.Spec.KubeletExtraArgs; len(kubeletExtraArgs) > 0 {
+		encodedArgs, err := json.Marshal(kubeletExtraArgs)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to encode kubelet extra args: %w", err)
+		}
+		host.Annotations[infrav1.KubeletExtraArgsAnnotation] = string(encodedArgs)
+	}
+	releaseByoHostClaim(&host)
 
 	err = byohostHelper.Patch(ctx, &host)
+	r.recordHostOperation(ctx, &host, machineScope.ByoMachine, infrav1.ByoHostOperationAttach, attachStart, err)
 	if err != nil {
 		logger.Error(err, "failed to patch byohost")
 		return ctrl.Result{}, err
 	}
 	logger.Info("Successfully attached Byohost", "byohost", host.Name)
+	if machineScope.ByoMachine.Spec.HostRef != nil {
+		conditions.MarkTrue(machineScope.ByoMachine, infrav1.HostRefResolved)
+	}
 	machineScope.ByoHost = &host
 	return ctrl.Result{}, nil
 }
 
+// selectAvailableByoHost picks the host to attach among the free hosts matching the selector of the ByoMachine
+func (r *ByoMachineReconciler) selectAvailableByoHost(ctx context.Context, machineScope *byoMachineScope) (*infrav1.ByoHost, ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
+	var selector labels.Selector
+	var err error
+
+	hostsList := &infrav1.ByoHostList{}
+	// LabelSelector filter for byohosts
+	if machineScope.ByoMachine.Spec.Selector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(machineScope.ByoMachine.Spec.Selector)
+		if err != nil {
+			logger.Error(err, "Label Selector as selector failed")
+			return nil, ctrl.Result{}, err
+		}
+	} else {
+		selector = labels.NewSelector()
+	}
+
+	byohostLabels, _ := labels.NewRequirement(clusterv1.ClusterNameLabel, selection.DoesNotExist, nil)
+	selector = selector.Add(*byohostLabels)
+	notQuarantined, _ := labels.NewRequirement(infrav1.QuarantinedLabel, selection.DoesNotExist, nil)
+	selector = selector.Add(*notQuarantined)
+
+	// only claim the hosts this instance is responsible for
+	if r.WatchFilterValue != "" {
+		watchLabel, err := labels.NewRequirement(clusterv1.WatchLabel, selection.Equals, []string{r.WatchFilterValue})
+		if err != nil {
+			logger.Error(err, "invalid watch filter value", "watchFilterValue", r.WatchFilterValue)
+			return nil, ctrl.Result{}, err
+		}
+		selector = selector.Add(*watchLabel)
+	}
+
+	// the failure domains of the cluster are the zones of the hosts, restrict the
+	// selection to the zone chosen for the machine
+	if failureDomain := machineScope.Machine.Spec.FailureDomain; failureDomain != nil && *failureDomain != "" {
+		zoneLabel, err := labels.NewRequirement(infrav1.HostZoneLabel, selection.Equals, []string{*failureDomain})
+		if err != nil {
+			logger.Error(err, "invalid failure domain", "failureDomain", *failureDomain)
+			return nil, ctrl.Result{}, err
+		}
+		selector = selector.Add(*zoneLabel)
+	}
+
+	err = r.List(ctx, hostsList, &client.ListOptions{
+		LabelSelector: selector,
+		Namespace:     machineScope.ByoMachine.Namespace,
+	})
+	if err != nil {
+		logger.Error(err, "failed to list byohosts")
+		return nil, ctrl.Result{RequeueAfter: RequeueForbyohost}, err
+	}
+	hostsList.Items = filterSchedulableByoHosts(hostsList.Items, time.Now())
+	hostsList.Items = filterUnclaimedByoHosts(hostsList.Items, machineScope, time.Now())
+	hostsList.Items = filterConnectedByoHosts(hostsList.Items, time.Now(), r.heartbeatTimeout(machineScope))
+	if len(hostsList.Items) == 0 {
+		logger.Info("No hosts found, waiting..")
+		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "ByoHostSelectionFailed", "No available ByoHost")
+		conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.BYOHostsUnavailableReason, clusterv1.ConditionSeverityInfo, "")
+		return nil, ctrl.Result{RequeueAfter: RequeueForbyohost}, errors.New("no hosts found")
+	}
+	hostsList.Items = filterByoHostsByResources(hostsList.Items, &machineScope.ByoMachine.Spec)
+	if len(hostsList.Items) == 0 {
+		spec := machineScope.ByoMachine.Spec
+		message := fmt.Sprintf("no ByoHost satisfies minCPU=%d, minMemoryMiB=%d, minDiskGiB=%d", spec.MinCPU, spec.MinMemoryMiB, spec.MinDiskGiB)
+		logger.Info("No hosts satisfy the resource requirements, waiting..", "minCPU", spec.MinCPU, "minMemoryMiB", spec.MinMemoryMiB, "minDiskGiB", spec.MinDiskGiB)
+		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "ByoHostSelectionFailed", "No ByoHost satisfies the resource requirements")
+		conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.InsufficientHostResourcesReason, clusterv1.ConditionSeverityWarning, "%s", message)
+		return nil, ctrl.Result{RequeueAfter: RequeueForbyohost}, errors.New("no hosts satisfy the resource requirements")
+	}
+	hostsList.Items, err = r.filterAdmittedByoHosts(ctx, machineScope, hostsList.Items)
+	if err != nil {
+		logger.Error(err, "failed to evaluate the host admission policies")
+		return nil, ctrl.Result{}, err
+	}
+	if len(hostsList.Items) == 0 {
+		logger.Info("No hosts are admitted by the host admission policies of the cluster, waiting..")
+		r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "ByoHostSelectionFailed", "No ByoHost is admitted by the ByoHostAdmissionPolicies of the cluster")
+		conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.HostsNotAdmittedReason, clusterv1.ConditionSeverityWarning,
+			"no ByoHost is admitted by the ByoHostAdmissionPolicies of the cluster")
+		return nil, ctrl.Result{RequeueAfter: RequeueForbyohost}, errors.New("no hosts are admitted by the host admission policies")
+	}
+	host := selectByoHost(hostsList.Items)
+	return &host, ctrl.Result{}, nil
+}
+
+// resolveHostRef returns the host named by the hostRef of the ByoMachine if it can be attached. The
+// selector, the failure domain and the resource requirements of the machine are bypassed, the host
+// must still be free, schedulable, connected and admitted by the policies of the cluster.
+func (r *ByoMachineReconciler) resolveHostRef(ctx context.Context, machineScope *byoMachineScope) (*infrav1.ByoHost, error) {
+	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
+	hostRef := machineScope.ByoMachine.Spec.HostRef
+	host := &infrav1.ByoHost{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: machineScope.ByoMachine.Namespace, Name: hostRef.Name}, host)
+	if apierrors.IsNotFound(err) {
+		logger.Info("ByoHost of the host ref not found, waiting..", "byohost", hostRef.Name)
+		r.markHostRefUnresolved(machineScope, infrav1.HostRefNotFoundReason, fmt.Sprintf("ByoHost %s not found", hostRef.Name))
+		return nil, fmt.Errorf("byohost %s of the host ref not found", hostRef.Name)
+	}
+	if err != nil {
+		logger.Error(err, "failed to get the byohost of the host ref", "byohost", hostRef.Name)
+		return nil, err
+	}
+
+	now := time.Now()
+	// the QuarantinedLabel quarantines the host whatever its value, like the selector of attachByoHost
+	_, quarantined := host.Labels[infrav1.QuarantinedLabel]
+	var unavailable string
+	switch {
+	case host.Status.MachineRef != nil:
+		unavailable = fmt.Sprintf("attached to ByoMachine %s", host.Status.MachineRef.Name)
+	case host.Labels[clusterv1.ClusterNameLabel] != "":
+		unavailable = fmt.Sprintf("attached to cluster %s", host.Labels[clusterv1.ClusterNameLabel])
+	case quarantined:
+		unavailable = "quarantined"
+	case host.IsClaimedByOther(machineScope.ByoMachine.UID, now, HostClaimTTL):
+		unavailable = "claimed by another ByoMachine"
+	case host.IsPaused():
+		unavailable = "paused"
+	case !host.IsSchedulable(now):
+		unavailable = "unschedulable"
+	case conditions.IsFalse(host, infrav1.AgentConnected) || host.IsHeartbeatStale(now, r.heartbeatTimeout(machineScope)):
+		unavailable = "disconnected"
+	}
+	if unavailable != "" {
+		logger.Info("ByoHost of the host ref is unavailable, waiting..", "byohost", host.Name, "reason", unavailable)
+		r.markHostRefUnresolved(machineScope, infrav1.HostRefUnavailableReason, fmt.Sprintf("ByoHost %s is %s", host.Name, unavailable))
+		return nil, fmt.Errorf("byohost %s of the host ref is %s", host.Name, unavailable)
+	}
+
+	admitted, err := r.filterAdmittedByoHosts(ctx, machineScope, []infrav1.ByoHost{*host})
+	if err != nil {
+		logger.Error(err, "failed to evaluate the host admission policies")
+		return nil, err
+	}
+	if len(admitted) == 0 {
+		logger.Info("ByoHost of the host ref is not admitted by the host admission policies of the cluster, waiting..", "byohost", host.Name)
+		r.markHostRefUnresolved(machineScope, infrav1.HostRefNotAdmittedReason,
+			fmt.Sprintf("ByoHost %s is not admitted by the ByoHostAdmissionPolicies of the cluster", host.Name))
+		return nil, fmt.Errorf("byohost %s of the host ref is not admitted by the host admission policies", host.Name)
+	}
+	return host, nil
+}
+
+// heartbeatTimeout returns how long the agent of a host may not report a heartbeat before the host
+// is no longer selected for the machine, the heartbeat timeout of its ByoCluster if it sets one
+func (r *ByoMachineReconciler) heartbeatTimeout(machineScope *byoMachineScope) time.Duration {
+	return machineScope.ByoCluster.Spec.GetHeartbeatTimeout(heartbeatTimeout(r.HeartbeatTimeout))
+}
+
+// setNodeDrainTimeout sets the NodeDrainTimeout of the ByoCluster on the Machine, if the ByoCluster
+// sets one and the Machine does not
+func (r *ByoMachineReconciler) setNodeDrainTimeout(ctx context.Context, machineScope *byoMachineScope) error {
+	hostDefaults := machineScope.ByoCluster.Spec.HostDefaults
+	if hostDefaults == nil || hostDefaults.NodeDrainTimeout == nil || machineScope.Machine.Spec.NodeDrainTimeout != nil {
+		return nil
+	}
+	helper, err := patch.NewHelper(machineScope.Machine, r.Client)
+	if err != nil {
+		return err
+	}
+	machineScope.Machine.Spec.NodeDrainTimeout = hostDefaults.NodeDrainTimeout.DeepCopy()
+	log.FromContext(ctx).Info("Setting the node drain timeout of the ByoCluster on the machine", "nodeDrainTimeout", hostDefaults.NodeDrainTimeout.Duration)
+	return helper.Patch(ctx, machineScope.Machine)
+}
+
+// markHostRefUnresolved records why the host of the hostRef of the ByoMachine cannot be attached
+func (r *ByoMachineReconciler) markHostRefUnresolved(machineScope *byoMachineScope, reason, message string) {
+	r.Recorder.Eventf(machineScope.ByoMachine, corev1.EventTypeWarning, "ByoHostSelectionFailed", "%s", message)
+	conditions.MarkFalse(machineScope.ByoMachine, infrav1.HostRefResolved, reason, clusterv1.ConditionSeverityWarning, "%s", message)
+	conditions.MarkFalse(machineScope.ByoMachine, infrav1.BYOHostReady, infrav1.BYOHostsUnavailableReason, clusterv1.ConditionSeverityInfo, "%s", message)
+}
+
+// filterSchedulableByoHosts drops the hosts that are marked unschedulable, are
+// in their maintenance window or are paused
+func filterSchedulableByoHosts(hosts []infrav1.ByoHost, now time.Time) []infrav1.ByoHost {
+	schedulable := make([]infrav1.ByoHost, 0, len(hosts))
+	for i := range hosts {
+		if hosts[i].IsSchedulable(now) && !hosts[i].IsPaused() {
+			schedulable = append(schedulable, hosts[i])
+		}
+	}
+	return schedulable
+}
+
+// filterConnectedByoHosts drops the hosts whose agent is disconnected, either marked so by
+// the AgentConnected condition or not having reported a heartbeat for longer than timeout
+func filterConnectedByoHosts(hosts []infrav1.ByoHost, now time.Time, timeout time.Duration) []infrav1.ByoHost {
+	connected := make([]infrav1.ByoHost, 0, len(hosts))
+	for i := range hosts {
+		if conditions.IsFalse(&hosts[i], infrav1.AgentConnected) || hosts[i].IsHeartbeatStale(now, timeout) {
+			continue
+		}
+		connected = append(connected, hosts[i])
+	}
+	return connected
+}
+
+// filterByoHostsByResources drops the hosts whose reported capacity does not
+// meet the minimum cpu, memory and disk requirements of the ByoMachine. Hosts
+// that have not reported a capacity only fit machines without requirements.
+func filterByoHostsByResources(hosts []infrav1.ByoHost, spec *infrav1.ByoMachineSpec) []infrav1.ByoHost {
+	fitting := make([]infrav1.ByoHost, 0, len(hosts))
+	for i := range hosts {
+		if byoHostFitsResources(&hosts[i], spec) {
+			fitting = append(fitting, hosts[i])
+		}
+	}
+	return fitting
+}
+
+// byoHostFitsResources reports whether the capacity of the host satisfies the
+// resource requirements of the ByoMachine
+func byoHostFitsResources(host *infrav1.ByoHost, spec *infrav1.ByoMachineSpec) bool {
+	requirements := []struct {
+		name    corev1.ResourceName
+		minimum *resource.Quantity
+	}{
+		{corev1.ResourceCPU, resource.NewQuantity(int64(spec.MinCPU), resource.DecimalSI)},
+		{corev1.ResourceMemory, resource.NewQuantity(spec.MinMemoryMiB*mebibyte, resource.BinarySI)},
+		{corev1.ResourceEphemeralStorage, resource.NewQuantity(spec.MinDiskGiB*gibibyte, resource.BinarySI)},
+	}
+	for _, requirement := range requirements {
+		if requirement.minimum.IsZero() {
+			continue
+		}
+		capacity, ok := host.Status.Capacity[requirement.name]
+		if !ok || capacity.Cmp(*requirement.minimum) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// filterAdmittedByoHosts drops the hosts that are not admitted by all the ByoHostAdmissionPolicies
+// that apply to the cluster of the ByoMachine
+func (r *ByoMachineReconciler) filterAdmittedByoHosts(ctx context.Context, machineScope *byoMachineScope, hosts []infrav1.ByoHost) ([]infrav1.ByoHost, error) {
+	logger := log.FromContext(ctx)
+	policies := &infrav1.ByoHostAdmissionPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return nil, err
+	}
+	applying := make([]infrav1.ByoHostAdmissionPolicy, 0, len(policies.Items))
+	for i := range policies.Items {
+		applies, err := policies.Items[i].AppliesTo(machineScope.Cluster.Namespace, machineScope.Cluster.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cluster selector of ByoHostAdmissionPolicy %s: %w", policies.Items[i].Name, err)
+		}
+		if applies {
+			applying = append(applying, policies.Items[i])
+		}
+	}
+	if len(applying) == 0 {
+		return hosts, nil
+	}
+
+	admitted := make([]infrav1.ByoHost, 0, len(hosts))
+	for i := range hosts {
+		reason, err := byoHostAdmissionDenial(&hosts[i], applying)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			logger.V(4).Info("ByoHost is not admitted", "byohost", hosts[i].Name, "reason", reason)
+			continue
+		}
+		admitted = append(admitted, hosts[i])
+	}
+	return admitted, nil
+}
+
+// byoHostAdmissionDenial returns why the first of policies that does not admit host denies it,
+// or an empty string if all the policies admit it
+func byoHostAdmissionDenial(host *infrav1.ByoHost, policies []infrav1.ByoHostAdmissionPolicy) (string, error) {
+	for i := range policies {
+		reason, err := policies[i].Admits(host)
+		if err != nil {
+			return "", fmt.Errorf("invalid host selector of ByoHostAdmissionPolicy %s: %w", policies[i].Name, err)
+		}
+		if reason != "" {
+			return fmt.Sprintf("ByoHostAdmissionPolicy %s: %s", policies[i].Name, reason), nil
+		}
+	}
+	return "", nil
+}
+
+// selectByoHost picks the host to attach from a list of candidates. Hosts are
+// ordered by the HostPriorityLabel (highest first), then by their last heartbeat
+// (most recent first, hosts without one last) and then by name.
+func selectByoHost(hosts []infrav1.ByoHost) infrav1.ByoHost {
+	sort.SliceStable(hosts, func(i, j int) bool {
+		pi, pj := byoHostPriority(&hosts[i]), byoHostPriority(&hosts[j])
+		if pi != pj {
+			return pi > pj
+		}
+		hi, hj := hosts[i].Status.LastHeartbeatTime, hosts[j].Status.LastHeartbeatTime
+		switch {
+		case (hi == nil) != (hj == nil):
+			return hi != nil
+		case hi != nil && !hi.Equal(hj):
+			return hi.After(hj.Time)
+		}
+		return hosts[i].Name < hosts[j].Name
+	})
+	return hosts[0]
+}
+
+// byoHostPriority returns the selection priority of the host, defaulting to 0
+// when the HostPriorityLabel is absent or not an integer
+func byoHostPriority(host *infrav1.ByoHost) int {
+	value, ok := host.Labels[infrav1.HostPriorityLabel]
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
 // ByoHostToByoMachineMapFunc returns a handler.ToRequestsFunc that watches for
 // Machine events and returns reconciliation requests for an infrastructure provider object
 func ByoHostToByoMachineMapFunc(gvk schema.GroupVersionKind) handler.MapFunc {
@@ -643,6 +1136,29 @@ func ByoHostToByoMachineMapFunc(gvk schema.GroupVersionKind) handler.MapFunc {
 	}
 }
 
+// recordHostOperation records the operation of byoMachine on host as a ByoHostOperation. A failure
+// to record it is only logged, so that it does not hold up the machine.
+func (r *ByoMachineReconciler) recordHostOperation(ctx context.Context, host *infrav1.ByoHost, byoMachine *infrav1.ByoMachine,
+	operation infrav1.ByoHostOperationType, start time.Time, operationErr error) {
+	err := hostoperation.Record(ctx, r.Client, hostoperation.Operation{
+		Namespace: host.Namespace,
+		HostName:  host.Name,
+		Type:      operation,
+		Initiator: hostOperationInitiator,
+		MachineRef: &corev1.ObjectReference{
+			APIVersion: byoMachine.APIVersion,
+			Kind:       byoMachine.Kind,
+			Namespace:  byoMachine.Namespace,
+			Name:       byoMachine.Name,
+			UID:        byoMachine.UID,
+		},
+		StartTime: start,
+	}, operationErr)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed to record host operation", "byohost", host.Name, "operation", operation)
+	}
+}
+
 func (r *ByoMachineReconciler) markHostForCleanup(ctx context.Context, machineScope *byoMachineScope) error {
 	logger := log.FromContext(ctx).WithValues("cluster", machineScope.Cluster.Name)
 	helper, _ := patch.NewHelper(machineScope.ByoHost, r.Client)
@@ -651,6 +1167,10 @@ func (r *ByoMachineReconciler) markHostForCleanup(ctx context.Context, machineSc
 		machineScope.ByoHost.Annotations = map[string]string{}
 	}
 	machineScope.ByoHost.Annotations[infrav1.HostCleanupAnnotation] = ""
+	if _, ok := machineScope.ByoMachine.Annotations[infrav1.SkipUninstallAnnotation]; ok {
+		machineScope.ByoHost.Annotations[infrav1.SkipUninstallAnnotation] = ""
+	}
+	r.recordBootstrapOutcome(ctx, machineScope.ByoHost, machineScope.ByoCluster.Spec.GetQuarantineThreshold(r.QuarantineThreshold))
 
 	// Debug: Log the value and presence of the upgrade-in-progress annotation
 	upgradeInProgress, ok := machineScope.ByoMachine.Annotations["barista.platform9.io/upgrade-in-progress"]
@@ -668,6 +1188,36 @@ func (r *ByoMachineReconciler) markHostForCleanup(ctx context.Context, machineSc
 	return helper.Patch(ctx, machineScope.ByoHost)
 }
 
+// recordBootstrapOutcome counts the consecutive releases of host without it having bootstrapped the
+// node of its ByoMachine, and quarantines the host once the count reaches threshold, the quarantine
+// threshold of the ByoCluster of the machine
+func (r *ByoMachineReconciler) recordBootstrapOutcome(ctx context.Context, host *infrav1.ByoHost, threshold int) {
+	if conditions.IsTrue(host, infrav1.K8sNodeBootstrapSucceeded) {
+		delete(host.Annotations, infrav1.BootstrapFailuresAnnotation)
+		return
+	}
+	failures, _ := strconv.Atoi(host.Annotations[infrav1.BootstrapFailuresAnnotation])
+	failures++
+	host.Annotations[infrav1.BootstrapFailuresAnnotation] = strconv.Itoa(failures)
+	if threshold <= 0 || failures < threshold {
+		return
+	}
+
+	log.FromContext(ctx).Info("Quarantining ByoHost after repeated bootstrap failures", "byohost", host.Name, "failures", failures)
+	if host.Labels == nil {
+		host.Labels = map[string]string{}
+	}
+	host.Labels[infrav1.QuarantinedLabel] = "true"
+	conditions.Set(host, &clusterv1.Condition{
+		Type:   infrav1.Quarantined,
+		Status: corev1.ConditionTrue,
+		Reason: infrav1.RepeatedBootstrapFailuresReason,
+		Message: fmt.Sprintf("%d consecutive machines failed to bootstrap, remove the %s label to attach the host again",
+			failures, infrav1.QuarantinedLabel),
+	})
+	r.Recorder.Eventf(host, corev1.EventTypeWarning, "ByoHostQuarantined", "Quarantined after %d consecutive bootstrap failures", failures)
+}
+
 func (r *ByoMachineReconciler) getInstallerConfig(ctx context.Context, byoMachine *infrav1.ByoMachine) (*unstructured.Unstructured, error) {
 	installerConfig := &unstructured.Unstructured{}
 	gvk := byoMachine.Spec.InstallerRef.GroupVersionKind()
@@ -716,6 +1266,9 @@ func (r *ByoMachineReconciler) createInstallerConfig(ctx context.Context, machin
 			return err
 		} else {
 			installerConfig.SetName(machineScope.ByoMachine.Name)
+			if err = setInstallerConfigBundleRegistry(installerConfig, machineScope.ByoCluster.Spec.GetBundleRegistry()); err != nil {
+				return err
+			}
 			if err = r.Create(ctx, installerConfig); err != nil {
 				logger.Error(err, "failed to create installer config")
 				return err
@@ -728,6 +1281,19 @@ func (r *ByoMachineReconciler) createInstallerConfig(ctx context.Context, machin
 	return nil
 }
 
+// setInstallerConfigBundleRegistry fills in the bundle registry of a generated K8sInstallerConfig
+// from the ByoCluster, unless the installer config template already sets one
+func setInstallerConfigBundleRegistry(installerConfig *unstructured.Unstructured, registry string) error {
+	if registry == "" || installerConfig.GroupVersionKind().GroupKind() != infrav1.GroupVersion.WithKind("K8sInstallerConfig").GroupKind() {
+		return nil
+	}
+	current, _, err := unstructured.NestedString(installerConfig.Object, "spec", "bundleRegistry")
+	if err != nil || current != "" {
+		return err
+	}
+	return unstructured.SetNestedField(installerConfig.Object, registry, "spec", "bundleRegistry")
+}
+
 func generateSafeLabelValue(namespace, name string) string {
 	originalValue := namespace + "." + name
 
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers_test
@@ -7,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -15,6 +17,8 @@ import (
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
 	eventutils "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/utils/events"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
@@ -150,6 +154,28 @@ var _ = Describe("Controllers/ByomachineController", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			It("should record the node and its ready state in the byomachine status", func() {
+				node = builder.Node(defaultNamespace, byoHost.Name).
+					WithProviderID(fmt.Sprintf("%s%s/%s", controllers.ProviderIDPrefix, byoHost.Name, util.RandomString(controllers.ProviderIDSuffixLength))).
+					Build()
+				node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+				Expect(clientFake.Create(ctx, node)).Should(Succeed())
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdNode := &corev1.Node{}
+				Expect(clientFake.Get(ctx, types.NamespacedName{Name: byoHost.Name, Namespace: defaultNamespace}, createdNode)).To(Succeed())
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				Expect(createdByoMachine.Status.NodeRef).To(Equal(&corev1.ObjectReference{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       byoHost.Name,
+					UID:        createdNode.UID,
+				}))
+				Expect(createdByoMachine.Status.NodeReady).To(BeTrue())
+			})
+
 			It("should return error when node.Spec.ProviderID has stale value", func() {
 				node = builder.Node(defaultNamespace, byoHost.Name).
 					WithProviderID(fmt.Sprintf("%sanother-host/%s", controllers.ProviderIDPrefix, util.RandomString(controllers.ProviderIDSuffixLength))).
@@ -251,13 +277,17 @@ var _ = Describe("Controllers/ByomachineController", func() {
 
 				createdByoHostAnnotations := createdByoHost.GetAnnotations()
 				Expect(createdByoHostAnnotations[infrastructurev1beta1.K8sVersionAnnotation]).To(Equal(strings.Split(testClusterVersion, "+")[0]))
-				Expect(createdByoHostAnnotations[infrastructurev1beta1.BundleLookupBaseRegistryAnnotation]).To(Equal(byoCluster.Spec.BundleLookupBaseRegistry))
+				Expect(createdByoHostAnnotations[infrastructurev1beta1.BundleLookupBaseRegistryAnnotation]).To(Equal(byoCluster.Spec.GetBundleRegistry()))
 
 				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
 				err = k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(createdByoMachine.Spec.ProviderID).To(ContainSubstring(controllers.ProviderIDPrefix))
 				Expect(createdByoMachine.Status.Ready).To(BeTrue())
+				Expect(createdByoMachine.Status.NodeRef).ToNot(BeNil())
+				Expect(createdByoMachine.Status.NodeRef.Kind).To(Equal("Node"))
+				Expect(createdByoMachine.Status.NodeRef.Name).To(Equal(byoHost.Name))
+				Expect(createdByoMachine.Status.NodeReady).To(BeFalse())
 
 				actualCondition := conditions.Get(createdByoMachine, infrastructurev1beta1.BYOHostReady)
 				Expect(*actualCondition).To(conditions.MatchCondition(clusterv1.Condition{
@@ -278,6 +308,51 @@ var _ = Describe("Controllers/ByomachineController", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(node.Spec.ProviderID).To(ContainSubstring(controllers.ProviderIDPrefix))
+
+				// assert the attach is recorded
+				operations := &infrastructurev1beta1.ByoHostOperationList{}
+				Expect(k8sClientUncached.List(ctx, operations, client.InNamespace(defaultNamespace),
+					client.MatchingLabels{infrastructurev1beta1.ByoHostOperationHostLabel: createdByoHost.Name})).To(Succeed())
+				Expect(operations.Items).To(ContainElement(And(
+					HaveField("Spec.Operation", infrastructurev1beta1.ByoHostOperationAttach),
+					HaveField("Spec.Initiator", "byomachine-controller"),
+					HaveField("Spec.MachineRef.Name", byoMachine.Name),
+					HaveField("Spec.Outcome", infrastructurev1beta1.ByoHostOperationSucceeded),
+				)))
+			})
+
+			It("applies the host defaults of the ByoCluster to the host and the machine", func() {
+				ph, err := patch.NewHelper(byoCluster, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				byoCluster.Spec.HostDefaults = &infrastructurev1beta1.ByoClusterHostDefaults{
+					HeartbeatTimeout: &metav1.Duration{Duration: 2 * time.Minute},
+					NodeDrainTimeout: &metav1.Duration{Duration: 10 * time.Minute},
+				}
+				Expect(ph.Patch(ctx, byoCluster)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(byoCluster, func(object client.Object) bool {
+					return object.(*infrastructurev1beta1.ByoCluster).Spec.HostDefaults != nil
+				})
+				DeferCleanup(func() {
+					ph, err := patch.NewHelper(byoCluster, k8sClientUncached)
+					Expect(err).ShouldNot(HaveOccurred())
+					byoCluster.Spec.HostDefaults = nil
+					Expect(ph.Patch(ctx, byoCluster)).Should(Succeed())
+					WaitForObjectToBeUpdatedInCache(byoCluster, func(object client.Object) bool {
+						return object.(*infrastructurev1beta1.ByoCluster).Spec.HostDefaults == nil
+					})
+				})
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).To(Succeed())
+				Expect(createdByoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.HeartbeatTimeoutAnnotation, "2m0s"))
+				Expect(createdByoHost.GetHeartbeatTimeout(controllers.DefaultHeartbeatTimeout)).To(Equal(2 * time.Minute))
+
+				updatedMachine := &clusterv1.Machine{}
+				Expect(k8sClientUncached.Get(ctx, client.ObjectKeyFromObject(machine), updatedMachine)).To(Succeed())
+				Expect(updatedMachine.Spec.NodeDrainTimeout).To(Equal(&metav1.Duration{Duration: 10 * time.Minute}))
 			})
 
 			Context("When ByoMachine is attached to a host", func() {
@@ -345,6 +420,24 @@ var _ = Describe("Controllers/ByomachineController", func() {
 
 				})
 
+				It("should restore the MachineRef of the byohost that clusterctl move did not move", func() {
+					ph, err := patch.NewHelper(byoHost, k8sClientUncached)
+					Expect(err).ShouldNot(HaveOccurred())
+					byoHost.Status.MachineRef = nil
+					Expect(ph.Patch(ctx, byoHost, patch.WithStatusObservedGeneration{})).Should(Succeed())
+					WaitForObjectToBeUpdatedInCache(byoHost, func(object client.Object) bool {
+						return object.(*infrastructurev1beta1.ByoHost).Status.MachineRef == nil
+					})
+
+					_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+					Expect(err).ToNot(HaveOccurred())
+					createdByoHost := &infrastructurev1beta1.ByoHost{}
+					Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).To(Succeed())
+					Expect(createdByoHost.Status.MachineRef).NotTo(BeNil())
+					Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+					Expect(createdByoHost.Status.MachineRef.UID).To(Equal(byoMachine.UID))
+				})
+
 				It("should set host platform info from byohost to byomachine", func() {
 					ph, err := patch.NewHelper(byoHost, k8sClientUncached)
 					Expect(err).ShouldNot(HaveOccurred())
@@ -401,6 +494,78 @@ var _ = Describe("Controllers/ByomachineController", func() {
 						Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).NotTo(HaveOccurred())
 
 						Expect(createdByoHost.Annotations[infrastructurev1beta1.HostCleanupAnnotation]).Should(Equal(""))
+
+						operations := &infrastructurev1beta1.ByoHostOperationList{}
+						Expect(k8sClientUncached.List(ctx, operations, client.InNamespace(defaultNamespace),
+							client.MatchingLabels{infrastructurev1beta1.ByoHostOperationHostLabel: createdByoHost.Name})).To(Succeed())
+						Expect(operations.Items).To(ContainElement(And(
+							HaveField("Spec.Operation", infrastructurev1beta1.ByoHostOperationDetach),
+							HaveField("Spec.MachineRef.Name", byoMachine.Name),
+							HaveField("Spec.Outcome", infrastructurev1beta1.ByoHostOperationSucceeded),
+						)))
+					})
+
+					It("should quarantine the byohost once the bootstrap failures reach the threshold", func() {
+						reconciler.QuarantineThreshold = 1
+						defer func() { reconciler.QuarantineThreshold = 0 }()
+
+						_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+						Expect(err).NotTo(HaveOccurred())
+
+						createdByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).NotTo(HaveOccurred())
+						Expect(createdByoHost.Annotations).Should(HaveKeyWithValue(infrastructurev1beta1.BootstrapFailuresAnnotation, "1"))
+						Expect(createdByoHost.Labels).Should(HaveKeyWithValue(infrastructurev1beta1.QuarantinedLabel, "true"))
+						Expect(conditions.IsTrue(createdByoHost, infrastructurev1beta1.Quarantined)).To(BeTrue())
+						Expect(conditions.GetReason(createdByoHost, infrastructurev1beta1.Quarantined)).To(Equal(infrastructurev1beta1.RepeatedBootstrapFailuresReason))
+						Expect(eventutils.CollectEvents(recorder.Events)).Should(ContainElement(
+							"Warning ByoHostQuarantined Quarantined after 1 consecutive bootstrap failures"))
+					})
+
+					It("should quarantine the byohost with the quarantine threshold of the byocluster", func() {
+						ph, err := patch.NewHelper(byoCluster, k8sClientUncached)
+						Expect(err).ShouldNot(HaveOccurred())
+						threshold := int32(1)
+						byoCluster.Spec.HostDefaults = &infrastructurev1beta1.ByoClusterHostDefaults{QuarantineThreshold: &threshold}
+						Expect(ph.Patch(ctx, byoCluster)).Should(Succeed())
+						WaitForObjectToBeUpdatedInCache(byoCluster, func(object client.Object) bool {
+							return object.(*infrastructurev1beta1.ByoCluster).Spec.HostDefaults != nil
+						})
+						DeferCleanup(func() {
+							ph, err := patch.NewHelper(byoCluster, k8sClientUncached)
+							Expect(err).ShouldNot(HaveOccurred())
+							byoCluster.Spec.HostDefaults = nil
+							Expect(ph.Patch(ctx, byoCluster)).Should(Succeed())
+							WaitForObjectToBeUpdatedInCache(byoCluster, func(object client.Object) bool {
+								return object.(*infrastructurev1beta1.ByoCluster).Spec.HostDefaults == nil
+							})
+						})
+
+						_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+						Expect(err).NotTo(HaveOccurred())
+
+						createdByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).NotTo(HaveOccurred())
+						Expect(createdByoHost.Labels).Should(HaveKeyWithValue(infrastructurev1beta1.QuarantinedLabel, "true"))
+					})
+
+					It("should pass the skip-uninstall annotation of the byomachine on to the byohost", func() {
+						ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
+						Expect(err).ShouldNot(HaveOccurred())
+						annotations.AddAnnotations(byoMachine, map[string]string{infrastructurev1beta1.SkipUninstallAnnotation: ""})
+						Expect(ph.Patch(ctx, byoMachine)).Should(Succeed())
+						WaitForObjectToBeUpdatedInCache(byoMachine, func(object client.Object) bool {
+							_, ok := object.GetAnnotations()[infrastructurev1beta1.SkipUninstallAnnotation]
+							return ok
+						})
+
+						_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+						Expect(err).NotTo(HaveOccurred())
+
+						createdByoHost := &infrastructurev1beta1.ByoHost{}
+						Expect(k8sClientUncached.Get(ctx, byoHostLookupKey, createdByoHost)).NotTo(HaveOccurred())
+						Expect(createdByoHost.Annotations).Should(HaveKey(infrastructurev1beta1.HostCleanupAnnotation))
+						Expect(createdByoHost.Annotations).Should(HaveKey(infrastructurev1beta1.SkipUninstallAnnotation))
 					})
 
 					It("should delete the byomachine object", func() {
@@ -662,6 +827,136 @@ var _ = Describe("Controllers/ByomachineController", func() {
 			})
 		})
 
+		Context("When only BYO Hosts of another watch filter are available", func() {
+			BeforeEach(func() {
+				reconciler.WatchFilterValue = "shard-a"
+				byoHost = builder.ByoHost(defaultNamespace, "byohost-other-shard").
+					WithLabels(map[string]string{clusterv1.WatchLabel: "shard-b"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, byoHost)).Should(Succeed())
+
+				WaitForObjectsToBePopulatedInCache(byoHost)
+			})
+
+			AfterEach(func() {
+				reconciler.WatchFilterValue = ""
+				Expect(k8sClientUncached.Delete(ctx, byoHost)).ToNot(HaveOccurred())
+			})
+
+			It("should not claim the hosts of the other watch filter", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("no hosts found"))
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: byoHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).To(BeNil())
+			})
+		})
+
+		Context("When only unschedulable or paused BYO Hosts are available", func() {
+			var (
+				cordonedHost    *infrastructurev1beta1.ByoHost
+				maintenanceHost *infrastructurev1beta1.ByoHost
+				pausedHost      *infrastructurev1beta1.ByoHost
+			)
+
+			BeforeEach(func() {
+				cordonedHost = builder.ByoHost(defaultNamespace, "byohost-unschedulable").
+					WithSchedulable(false).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, cordonedHost)).Should(Succeed())
+				maintenanceHost = builder.ByoHost(defaultNamespace, "byohost-in-maintenance").
+					WithMaintenanceWindow(time.Now().Add(-time.Hour), time.Now().Add(time.Hour)).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, maintenanceHost)).Should(Succeed())
+				pausedHost = builder.ByoHost(defaultNamespace, "byohost-paused").Build()
+				pausedHost.Annotations = map[string]string{infrastructurev1beta1.HostPausedAnnotation: ""}
+				Expect(k8sClientUncached.Create(ctx, pausedHost)).Should(Succeed())
+
+				WaitForObjectsToBePopulatedInCache(cordonedHost, maintenanceHost, pausedHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, cordonedHost)).ToNot(HaveOccurred())
+				Expect(k8sClientUncached.Delete(ctx, maintenanceHost)).ToNot(HaveOccurred())
+				Expect(k8sClientUncached.Delete(ctx, pausedHost)).ToNot(HaveOccurred())
+			})
+
+			It("should mark BYOHostReady as False", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("no hosts found"))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				err = k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)
+				Expect(err).ToNot(HaveOccurred())
+
+				actualCondition := conditions.Get(createdByoMachine, infrastructurev1beta1.BYOHostReady)
+				Expect(*actualCondition).To(conditions.MatchCondition(clusterv1.Condition{
+					Type:     infrastructurev1beta1.BYOHostReady,
+					Status:   corev1.ConditionFalse,
+					Reason:   infrastructurev1beta1.BYOHostsUnavailableReason,
+					Severity: clusterv1.ConditionSeverityInfo,
+				}))
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: cordonedHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).To(BeNil())
+
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: pausedHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).To(BeNil())
+			})
+		})
+
+		Context("When BYO Hosts are claimed by other ByoMachines", func() {
+			var (
+				claimedHost      *infrastructurev1beta1.ByoHost
+				staleClaimedHost *infrastructurev1beta1.ByoHost
+			)
+
+			BeforeEach(func() {
+				claimedHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{infrastructurev1beta1.HostPriorityLabel: "10"}).
+					Build()
+				claimedHost.Annotations = map[string]string{
+					infrastructurev1beta1.ClaimedByAnnotation: "another-byomachine-uid",
+					infrastructurev1beta1.ClaimedAtAnnotation: time.Now().UTC().Format(time.RFC3339),
+				}
+				Expect(k8sClientUncached.Create(ctx, claimedHost)).Should(Succeed())
+				staleClaimedHost = builder.ByoHost(defaultNamespace, defaultByoHostName).Build()
+				staleClaimedHost.Annotations = map[string]string{
+					infrastructurev1beta1.ClaimedByAnnotation: "another-byomachine-uid",
+					infrastructurev1beta1.ClaimedAtAnnotation: time.Now().Add(-controllers.HostClaimTTL).UTC().Format(time.RFC3339),
+				}
+				Expect(k8sClientUncached.Create(ctx, staleClaimedHost)).Should(Succeed())
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, staleClaimedHost.Name).Build())).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(claimedHost, staleClaimedHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, claimedHost)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, staleClaimedHost)).Should(Succeed())
+			})
+
+			It("should only attach the host whose claim expired", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClientUncached.Get(ctx, client.ObjectKeyFromObject(claimedHost), createdByoHost)).To(Succeed())
+				Expect(createdByoHost.Status.MachineRef).To(BeNil())
+				Expect(createdByoHost.Annotations).To(HaveKeyWithValue(infrastructurev1beta1.ClaimedByAnnotation, "another-byomachine-uid"))
+
+				Expect(k8sClientUncached.Get(ctx, client.ObjectKeyFromObject(staleClaimedHost), createdByoHost)).To(Succeed())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+				Expect(createdByoHost.Annotations).ToNot(HaveKey(infrastructurev1beta1.ClaimedByAnnotation))
+				Expect(createdByoHost.Annotations).ToNot(HaveKey(infrastructurev1beta1.ClaimedAtAnnotation))
+			})
+		})
+
 		Context("When all ByoHost are attached", func() {
 			BeforeEach(func() {
 				byoHost = builder.ByoHost(defaultNamespace, "byohost-attached-different-cluster").
@@ -800,6 +1095,549 @@ var _ = Describe("Controllers/ByomachineController", func() {
 			})
 		})
 
+		Context("When a BYO Host with node labels and taints is available", func() {
+			BeforeEach(func() {
+				byoHost = builder.ByoHost(defaultNamespace, "host-with-node-labels-and-taints").
+					WithNodeLabels(map[string]string{"node.example.com/pool": "gpu"}).
+					WithNodeTaints([]corev1.Taint{{Key: "node.example.com/dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, byoHost)).Should(Succeed())
+
+				node = builder.Node(defaultNamespace, byoHost.Name).Build()
+				Expect(clientFake.Create(ctx, node)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(byoHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, byoHost)).ToNot(HaveOccurred())
+			})
+
+			It("applies the node labels and taints to the node", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				updatedNode := corev1.Node{}
+				err = clientFake.Get(ctx, types.NamespacedName{Name: byoHost.Name, Namespace: defaultNamespace}, &updatedNode)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(updatedNode.Labels).To(HaveKeyWithValue("node.example.com/pool", "gpu"))
+				Expect(updatedNode.Spec.Taints).To(ContainElement(corev1.Taint{Key: "node.example.com/dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}))
+			})
+		})
+
+		Context("When a BYO Host with topology labels is available", func() {
+			BeforeEach(func() {
+				byoHost = builder.ByoHost(defaultNamespace, "host-with-topology-labels").
+					WithLabels(map[string]string{
+						infrastructurev1beta1.HostZoneLabel: "zone-a",
+						infrastructurev1beta1.HostRoomLabel: "room-1",
+						infrastructurev1beta1.HostRackLabel: "rack-12",
+					}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, byoHost)).Should(Succeed())
+
+				node = builder.Node(defaultNamespace, byoHost.Name).Build()
+				Expect(clientFake.Create(ctx, node)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(byoHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, byoHost)).ToNot(HaveOccurred())
+			})
+
+			It("applies the topology labels to the node", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				updatedNode := corev1.Node{}
+				err = clientFake.Get(ctx, types.NamespacedName{Name: byoHost.Name, Namespace: defaultNamespace}, &updatedNode)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(updatedNode.Labels).To(HaveKeyWithValue(corev1.LabelTopologyZone, "zone-a"))
+				Expect(updatedNode.Labels).To(HaveKeyWithValue(infrastructurev1beta1.HostRoomLabel, "room-1"))
+				Expect(updatedNode.Labels).To(HaveKeyWithValue(infrastructurev1beta1.HostRackLabel, "rack-12"))
+			})
+		})
+
+		Context("When multiple BYO Hosts with different priorities are available", func() {
+			var (
+				lowPriorityHost  *infrastructurev1beta1.ByoHost
+				highPriorityHost *infrastructurev1beta1.ByoHost
+			)
+
+			BeforeEach(func() {
+				lowPriorityHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{infrastructurev1beta1.HostPriorityLabel: "1"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, lowPriorityHost)).Should(Succeed())
+				highPriorityHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{infrastructurev1beta1.HostPriorityLabel: "10"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, highPriorityHost)).Should(Succeed())
+
+				WaitForObjectsToBePopulatedInCache(lowPriorityHost, highPriorityHost)
+
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, lowPriorityHost.Name).Build())).Should(Succeed())
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, highPriorityHost.Name).Build())).Should(Succeed())
+			})
+
+			It("claims the host with the highest priority", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: highPriorityHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+
+				unclaimedByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: lowPriorityHost.Name, Namespace: defaultNamespace}, unclaimedByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(unclaimedByoHost.Status.MachineRef).To(BeNil())
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, lowPriorityHost)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, highPriorityHost)).Should(Succeed())
+			})
+		})
+
+		Context("When BYO Hosts do not satisfy the resource requirements", func() {
+			var (
+				smallHost *infrastructurev1beta1.ByoHost
+				largeHost *infrastructurev1beta1.ByoHost
+			)
+
+			setCapacity := func(host *infrastructurev1beta1.ByoHost, cpu, memory, disk string) {
+				ph, err := patch.NewHelper(host, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				host.Status.Capacity = corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse(cpu),
+					corev1.ResourceMemory:           resource.MustParse(memory),
+					corev1.ResourceEphemeralStorage: resource.MustParse(disk),
+				}
+				Expect(ph.Patch(ctx, host, patch.WithStatusObservedGeneration{})).Should(Succeed())
+			}
+
+			BeforeEach(func() {
+				smallHost = builder.ByoHost(defaultNamespace, "byohost-small").Build()
+				Expect(k8sClientUncached.Create(ctx, smallHost)).Should(Succeed())
+				setCapacity(smallHost, "2", "4Gi", "20Gi")
+				largeHost = builder.ByoHost(defaultNamespace, "byohost-large").Build()
+				Expect(k8sClientUncached.Create(ctx, largeHost)).Should(Succeed())
+				setCapacity(largeHost, "8", "32Gi", "200Gi")
+
+				byoMachine = builder.ByoMachine(defaultNamespace, "byomachine-with-resource-requirements").
+					WithClusterLabel(defaultClusterName).
+					WithOwnerMachine(machine).
+					WithResourceRequirements(16, 8192, 50).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, byoMachine)).Should(Succeed())
+
+				WaitForObjectsToBePopulatedInCache(smallHost, largeHost, byoMachine)
+				for _, host := range []*infrastructurev1beta1.ByoHost{smallHost, largeHost} {
+					WaitForObjectToBeUpdatedInCache(host, func(object client.Object) bool {
+						return object.(*infrastructurev1beta1.ByoHost).Status.Capacity != nil
+					})
+				}
+				byoMachineLookupKey = types.NamespacedName{Name: byoMachine.Name, Namespace: byoMachine.Namespace}
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, smallHost)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, largeHost)).Should(Succeed())
+			})
+
+			It("should mark BYOHostReady as False with InsufficientHostResources", func() {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("no hosts satisfy the resource requirements"))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				err = k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)
+				Expect(err).ToNot(HaveOccurred())
+
+				actualCondition := conditions.Get(createdByoMachine, infrastructurev1beta1.BYOHostReady)
+				Expect(*actualCondition).To(conditions.MatchCondition(clusterv1.Condition{
+					Type:     infrastructurev1beta1.BYOHostReady,
+					Status:   corev1.ConditionFalse,
+					Reason:   infrastructurev1beta1.InsufficientHostResourcesReason,
+					Severity: clusterv1.ConditionSeverityWarning,
+					Message:  "no ByoHost satisfies minCPU=16, minMemoryMiB=8192, minDiskGiB=50",
+				}))
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf([]string{
+					"Warning ByoHostSelectionFailed No ByoHost satisfies the resource requirements",
+				}))
+			})
+
+			It("claims the host that satisfies the resource requirements", func() {
+				ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				byoMachine.Spec.MinCPU = 4
+				Expect(ph.Patch(ctx, byoMachine)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(byoMachine, func(object client.Object) bool {
+					return object.(*infrastructurev1beta1.ByoMachine).Spec.MinCPU == 4
+				})
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, largeHost.Name).Build())).Should(Succeed())
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: largeHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+			})
+		})
+
+		Context("When BYO Hosts report heartbeats", func() {
+			var (
+				staleHost        *infrastructurev1beta1.ByoHost
+				disconnectedHost *infrastructurev1beta1.ByoHost
+				olderHost        *infrastructurev1beta1.ByoHost
+				recentHost       *infrastructurev1beta1.ByoHost
+			)
+
+			createHost := func(heartbeatAge time.Duration, connected bool) *infrastructurev1beta1.ByoHost {
+				host := builder.ByoHost(defaultNamespace, defaultByoHostName).Build()
+				Expect(k8sClientUncached.Create(ctx, host)).Should(Succeed())
+				ph, err := patch.NewHelper(host, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				heartbeat := metav1.NewTime(time.Now().Add(-heartbeatAge))
+				host.Status.LastHeartbeatTime = &heartbeat
+				if connected {
+					conditions.MarkTrue(host, infrastructurev1beta1.AgentConnected)
+				} else {
+					conditions.MarkFalse(host, infrastructurev1beta1.AgentConnected, infrastructurev1beta1.AgentHeartbeatStaleReason,
+						clusterv1.ConditionSeverityWarning, "")
+				}
+				Expect(ph.Patch(ctx, host, patch.WithStatusObservedGeneration{})).Should(Succeed())
+				return host
+			}
+
+			BeforeEach(func() {
+				staleHost = createHost(time.Hour, true)
+				disconnectedHost = createHost(0, false)
+				olderHost = createHost(2*time.Minute, true)
+				recentHost = createHost(time.Minute, true)
+				for _, host := range []*infrastructurev1beta1.ByoHost{staleHost, disconnectedHost, olderHost, recentHost} {
+					WaitForObjectToBeUpdatedInCache(host, func(object client.Object) bool {
+						return object.(*infrastructurev1beta1.ByoHost).Status.LastHeartbeatTime != nil
+					})
+				}
+			})
+
+			AfterEach(func() {
+				for _, host := range []*infrastructurev1beta1.ByoHost{staleHost, disconnectedHost, olderHost, recentHost} {
+					Expect(k8sClientUncached.Delete(ctx, host)).Should(Succeed())
+				}
+			})
+
+			It("claims the connected host with the most recent heartbeat", func() {
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, recentHost.Name).Build())).Should(Succeed())
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: recentHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+			})
+
+			It("does not claim hosts that are disconnected or whose heartbeat is stale", func() {
+				for _, host := range []*infrastructurev1beta1.ByoHost{olderHost, recentHost} {
+					Expect(k8sClientUncached.Delete(ctx, host)).Should(Succeed())
+				}
+				olderHost = createHost(time.Hour, false)
+				recentHost = createHost(2*time.Hour, true)
+				for _, host := range []*infrastructurev1beta1.ByoHost{olderHost, recentHost} {
+					WaitForObjectToBeUpdatedInCache(host, func(object client.Object) bool {
+						return object.(*infrastructurev1beta1.ByoHost).Status.LastHeartbeatTime != nil
+					})
+				}
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("no hosts found"))
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf([]string{warningNoAvailableByoHostEvent}))
+			})
+		})
+
+		Context("When ByoHostAdmissionPolicies apply to the cluster", func() {
+			var (
+				teamHost  *infrastructurev1beta1.ByoHost
+				otherHost *infrastructurev1beta1.ByoHost
+				policy    *infrastructurev1beta1.ByoHostAdmissionPolicy
+			)
+
+			BeforeEach(func() {
+				teamHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{"team": "team-a"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, teamHost)).Should(Succeed())
+				otherHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{infrastructurev1beta1.HostPriorityLabel: "10"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, otherHost)).Should(Succeed())
+
+				policy = &infrastructurev1beta1.ByoHostAdmissionPolicy{
+					ObjectMeta: metav1.ObjectMeta{GenerateName: "team-a-hosts-"},
+					Spec: infrastructurev1beta1.ByoHostAdmissionPolicySpec{
+						Namespaces:   []string{defaultNamespace},
+						HostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "team-a"}},
+					},
+				}
+				Expect(k8sClientUncached.Create(ctx, policy)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(teamHost, otherHost, policy)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, policy)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, teamHost)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, otherHost)).Should(Succeed())
+			})
+
+			It("claims a host admitted by the policies", func() {
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, teamHost.Name).Build())).Should(Succeed())
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: teamHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+
+				unclaimedByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: otherHost.Name, Namespace: defaultNamespace}, unclaimedByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(unclaimedByoHost.Status.MachineRef).To(BeNil())
+			})
+
+			It("should mark BYOHostReady as False with HostsNotAdmitted if no host is admitted", func() {
+				ph, err := patch.NewHelper(policy, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				policy.Spec.OSNames = []string{"Flatcar Container Linux"}
+				Expect(ph.Patch(ctx, policy)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(policy, func(object client.Object) bool {
+					return len(object.(*infrastructurev1beta1.ByoHostAdmissionPolicy).Spec.OSNames) > 0
+				})
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("no hosts are admitted by the host admission policies"))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				actualCondition := conditions.Get(createdByoMachine, infrastructurev1beta1.BYOHostReady)
+				Expect(*actualCondition).To(conditions.MatchCondition(clusterv1.Condition{
+					Type:     infrastructurev1beta1.BYOHostReady,
+					Status:   corev1.ConditionFalse,
+					Reason:   infrastructurev1beta1.HostsNotAdmittedReason,
+					Severity: clusterv1.ConditionSeverityWarning,
+					Message:  "no ByoHost is admitted by the ByoHostAdmissionPolicies of the cluster",
+				}))
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf([]string{
+					"Warning ByoHostSelectionFailed No ByoHost is admitted by the ByoHostAdmissionPolicies of the cluster",
+				}))
+			})
+		})
+
+		Context("When the ByoMachine is pinned to a host", func() {
+			var (
+				pinnedHost *infrastructurev1beta1.ByoHost
+				otherHost  *infrastructurev1beta1.ByoHost
+			)
+
+			BeforeEach(func() {
+				pinnedHost = builder.ByoHost(defaultNamespace, defaultByoHostName).Build()
+				Expect(k8sClientUncached.Create(ctx, pinnedHost)).Should(Succeed())
+				otherHost = builder.ByoHost(defaultNamespace, defaultByoHostName).
+					WithLabels(map[string]string{infrastructurev1beta1.HostPriorityLabel: "10"}).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, otherHost)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(pinnedHost, otherHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, pinnedHost)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, otherHost)).Should(Succeed())
+			})
+
+			createPinnedByoMachine := func(hostName string) {
+				byoMachine = builder.ByoMachine(defaultNamespace, "byomachine-with-host-ref").
+					WithClusterLabel(defaultClusterName).
+					WithOwnerMachine(machine).
+					WithHostRef(hostName).
+					Build()
+				Expect(k8sClientUncached.Create(ctx, byoMachine)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(byoMachine)
+				byoMachineLookupKey = types.NamespacedName{Name: byoMachine.Name, Namespace: byoMachine.Namespace}
+			}
+
+			It("claims the host of the host ref", func() {
+				createPinnedByoMachine(pinnedHost.Name)
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, pinnedHost.Name).Build())).Should(Succeed())
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: pinnedHost.Name, Namespace: defaultNamespace}, createdByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+
+				unclaimedByoHost := &infrastructurev1beta1.ByoHost{}
+				err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: otherHost.Name, Namespace: defaultNamespace}, unclaimedByoHost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(unclaimedByoHost.Status.MachineRef).To(BeNil())
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				Expect(conditions.IsTrue(createdByoMachine, infrastructurev1beta1.HostRefResolved)).To(BeTrue())
+			})
+
+			It("should mark HostRefResolved as False with HostRefNotFound if the host does not exist", func() {
+				createPinnedByoMachine("missing-host")
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError("byohost missing-host of the host ref not found"))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				actualCondition := conditions.Get(createdByoMachine, infrastructurev1beta1.HostRefResolved)
+				Expect(*actualCondition).To(conditions.MatchCondition(clusterv1.Condition{
+					Type:     infrastructurev1beta1.HostRefResolved,
+					Status:   corev1.ConditionFalse,
+					Reason:   infrastructurev1beta1.HostRefNotFoundReason,
+					Severity: clusterv1.ConditionSeverityWarning,
+					Message:  "ByoHost missing-host not found",
+				}))
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf([]string{
+					"Warning ByoHostSelectionFailed ByoHost missing-host not found",
+				}))
+			})
+
+			expectQuarantinedHostUnavailable := func(quarantineValue string) {
+				ph, err := patch.NewHelper(pinnedHost, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				pinnedHost.Labels = map[string]string{infrastructurev1beta1.QuarantinedLabel: quarantineValue}
+				Expect(ph.Patch(ctx, pinnedHost)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(pinnedHost, func(object client.Object) bool {
+					_, quarantined := object.GetLabels()[infrastructurev1beta1.QuarantinedLabel]
+					return quarantined
+				})
+				createPinnedByoMachine(pinnedHost.Name)
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).To(MatchError(fmt.Sprintf("byohost %s of the host ref is quarantined", pinnedHost.Name)))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				Expect(conditions.GetReason(createdByoMachine, infrastructurev1beta1.HostRefResolved)).To(Equal(infrastructurev1beta1.HostRefUnavailableReason))
+				eventutils.CollectEvents(recorder.Events)
+			}
+
+			It("should mark HostRefResolved as False with HostRefUnavailable if the host is quarantined", func() {
+				expectQuarantinedHostUnavailable("true")
+			})
+
+			It("should mark HostRefResolved as False with HostRefUnavailable if the host is quarantined with an empty label", func() {
+				expectQuarantinedHostUnavailable("")
+			})
+		})
+
+		Context("When the bootstrap data joins with a bootstrap token", func() {
+			var (
+				bootstrapSecret *corev1.Secret
+				tokenSecret     *corev1.Secret
+			)
+
+			createTokenSecret := func(expiration time.Time) {
+				tokenSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: metav1.NamespaceSystem},
+					Data: map[string][]byte{
+						"token-id":     []byte("abcdef"),
+						"token-secret": []byte("0123456789abcdef"),
+						"expiration":   []byte(expiration.UTC().Format(time.RFC3339)),
+					},
+				}
+				Expect(clientFake.Create(ctx, tokenSecret)).Should(Succeed())
+			}
+
+			BeforeEach(func() {
+				byoHost = builder.ByoHost(defaultNamespace, "host-for-bootstrap-token").Build()
+				Expect(k8sClientUncached.Create(ctx, byoHost)).Should(Succeed())
+				Expect(clientFake.Create(ctx, builder.Node(defaultNamespace, byoHost.Name).Build())).Should(Succeed())
+
+				bootstrapSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: fakeBootstrapSecret, Namespace: defaultNamespace},
+					StringData: map[string]string{"value": `write_files:
+- path: /run/kubeadm/kubeadm-join-config.yaml
+  content: |
+    apiVersion: kubeadm.k8s.io/v1beta3
+    kind: JoinConfiguration
+    discovery:
+      bootstrapToken:
+        apiServerEndpoint: 10.10.10.10:6443
+        token: abcdef.0123456789abcdef
+runcmd:
+- kubeadm join --config /run/kubeadm/kubeadm-join-config.yaml
+`},
+				}
+				Expect(k8sClientUncached.Create(ctx, bootstrapSecret)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(byoHost)
+			})
+
+			AfterEach(func() {
+				Expect(k8sClientUncached.Delete(ctx, bootstrapSecret)).Should(Succeed())
+				Expect(clientFake.Delete(ctx, tokenSecret)).Should(Succeed())
+				Expect(k8sClientUncached.Delete(ctx, byoHost)).Should(Succeed())
+			})
+
+			It("should not attach a host if the bootstrap token expires too soon", func() {
+				createTokenSecret(time.Now().Add(time.Minute))
+
+				result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(controllers.RequeueForbyohost))
+
+				createdByoMachine := &infrastructurev1beta1.ByoMachine{}
+				Expect(k8sClientUncached.Get(ctx, byoMachineLookupKey, createdByoMachine)).To(Succeed())
+				Expect(conditions.GetReason(createdByoMachine, infrastructurev1beta1.BYOHostReady)).To(Equal(infrastructurev1beta1.BootstrapTokenExpiringReason))
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClientUncached.Get(ctx, client.ObjectKeyFromObject(byoHost), createdByoHost)).To(Succeed())
+				Expect(createdByoHost.Status.MachineRef).To(BeNil())
+
+				events := eventutils.CollectEvents(recorder.Events)
+				Expect(events).Should(ConsistOf(HavePrefix("Warning BootstrapTokenExpiring Requested the refresh of the bootstrap data, bootstrap token abcdef expires at ")))
+			})
+
+			It("should attach a host if the bootstrap token is valid long enough", func() {
+				createTokenSecret(time.Now().Add(time.Hour))
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).ToNot(HaveOccurred())
+
+				createdByoHost := &infrastructurev1beta1.ByoHost{}
+				Expect(k8sClientUncached.Get(ctx, client.ObjectKeyFromObject(byoHost), createdByoHost)).To(Succeed())
+				Expect(createdByoHost.Status.MachineRef).ToNot(BeNil())
+				Expect(createdByoHost.Status.MachineRef.Name).To(Equal(byoMachine.Name))
+			})
+		})
+
 		Context("When installer config template exists", func() {
 			It("should create installer config from the template", func() {
 				ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
@@ -824,9 +1662,45 @@ var _ = Describe("Controllers/ByomachineController", func() {
 				err = k8sClientUncached.Get(ctx, byoMachineLookupKey, createdK8sInstallerConfig)
 				Expect(err).ShouldNot(HaveOccurred())
 
-				Expect(k8sInstallerConfigTemplate.Spec.Template.Spec).To(Equal(createdK8sInstallerConfig.Spec))
+				expectedSpec := k8sInstallerConfigTemplate.Spec.Template.Spec
+				expectedSpec.BundleRegistry = byoCluster.Spec.GetBundleRegistry()
+				Expect(expectedSpec).To(Equal(createdK8sInstallerConfig.Spec))
 				Expect(createdK8sInstallerConfig.GetAnnotations()[infrastructurev1beta1.K8sVersionAnnotation]).To(Equal(*machine.Spec.Version))
 			})
+
+			It("should keep the bundle registry set on the template", func() {
+				templateWithRegistry := builder.K8sInstallerConfigTemplate(defaultNamespace, "installer-template-with-registry-").
+					WithBundleRepo("cluster_api_provider_bringyourownhost").
+					WithBundleType("k8s").
+					WithBundleRegistry("quay.io/platform9").
+					Build()
+				Expect(k8sClientUncached.Create(ctx, templateWithRegistry)).Should(Succeed())
+				WaitForObjectsToBePopulatedInCache(templateWithRegistry)
+
+				ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				byoMachine.Spec.InstallerRef = &corev1.ObjectReference{
+					Kind:       k8sInstallerConfigTemplateKind,
+					Namespace:  templateWithRegistry.Namespace,
+					Name:       templateWithRegistry.Name,
+					UID:        templateWithRegistry.UID,
+					APIVersion: infrastructurev1beta1.GroupVersion.String(),
+				}
+				Expect(ph.Patch(ctx, byoMachine, patch.WithStatusObservedGeneration{})).Should(Succeed())
+
+				WaitForObjectToBeUpdatedInCache(byoMachine, func(object client.Object) bool {
+					return object.(*infrastructurev1beta1.ByoMachine).Spec.InstallerRef != nil
+				})
+
+				_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: byoMachineLookupKey})
+				Expect(err).Should(MatchError("no hosts found"))
+
+				createdK8sInstallerConfig := &infrastructurev1beta1.K8sInstallerConfig{}
+				err = k8sClientUncached.Get(ctx, byoMachineLookupKey, createdK8sInstallerConfig)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(createdK8sInstallerConfig.Spec.BundleRegistry).To(Equal("quay.io/platform9"))
+				Expect(createdK8sInstallerConfig.Spec.BundleRepoAddr()).To(Equal("quay.io/platform9/cluster_api_provider_bringyourownhost"))
+			})
 		})
 
 		Context("When installer config template resource does not exists", func() {
@@ -0,0 +1,62 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers
+
+import (
+	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UncachedObjects are the kinds the manager reads from the API server rather than the cache.
+// Only a few Secrets and ConfigMaps of the management cluster are ever read, caching all of
+// them would cost more memory than the reads.
+var UncachedObjects = []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}}
+
+// CacheOptions returns the options of the manager cache. The objects are cached without their
+// managed fields and the ByoHosts without the fields the manager does not read. If
+// watchFilterValue is set only the ByoHosts with the watch label are cached, as the other hosts
+// are never claimed by this manager.
+func CacheOptions(watchFilterValue string) cache.Options {
+	options := cache.Options{
+		DefaultTransform: StripManagedFields,
+		TransformByObject: cache.TransformByObject{
+			&infrav1.ByoHost{}: TransformByoHost,
+		},
+	}
+	if watchFilterValue != "" {
+		options.SelectorsByObject = cache.SelectorsByObject{
+			&infrav1.ByoHost{}: {Label: labels.SelectorFromSet(labels.Set{clusterv1.WatchLabel: watchFilterValue})},
+		}
+	}
+	return options
+}
+
+// StripManagedFields drops the managed fields of a cached object, the manager never reads them
+func StripManagedFields(obj interface{}) (interface{}, error) {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj, nil
+}
+
+// TransformByoHost drops the fields of a cached ByoHost the manager does not read: its managed
+// fields, the last applied configuration and onboarding durations annotations, and the network
+// interfaces reported by the agent. The manager only patches ByoHosts, so the dropped fields
+// are never written back.
+func TransformByoHost(obj interface{}) (interface{}, error) {
+	byoHost, ok := obj.(*infrav1.ByoHost)
+	if !ok {
+		return StripManagedFields(obj)
+	}
+	byoHost.ManagedFields = nil
+	delete(byoHost.Annotations, corev1.LastAppliedConfigAnnotation)
+	delete(byoHost.Annotations, infrav1.OnboardingDurationsAnnotation)
+	byoHost.Status.Network = nil
+	return byoHost, nil
+}
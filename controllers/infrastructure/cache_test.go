@@ -0,0 +1,68 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package controllers_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	controllers "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/controllers/infrastructure"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+var _ = Describe("Controllers/Cache", func() {
+	managedFields := []metav1.ManagedFieldsEntry{{Manager: "byoh-hostagent", Operation: metav1.ManagedFieldsOperationUpdate}}
+
+	It("should drop the fields the manager does not read from a ByoHost", func() {
+		byoHost := &infrav1.ByoHost{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:          defaultByoHostName,
+				ManagedFields: managedFields,
+				Annotations: map[string]string{
+					corev1.LastAppliedConfigAnnotation:    "{}",
+					infrav1.OnboardingDurationsAnnotation: `{"total":1}`,
+					infrav1.K8sVersionAnnotation:          "v1.26.2",
+				},
+			},
+			Status: infrav1.ByoHostStatus{
+				MachineRef:  &corev1.ObjectReference{Name: defaultByoMachineName},
+				HostDetails: infrav1.HostInfo{OSName: testOSNameLinux},
+				Network:     []infrav1.NetworkStatus{{NetworkInterfaceName: "eth0", IPAddrs: []string{"10.0.0.1/24"}}},
+			},
+		}
+
+		obj, err := controllers.TransformByoHost(byoHost)
+		Expect(err).NotTo(HaveOccurred())
+		transformed := obj.(*infrav1.ByoHost)
+		Expect(transformed.ManagedFields).To(BeNil())
+		Expect(transformed.Annotations).To(Equal(map[string]string{infrav1.K8sVersionAnnotation: "v1.26.2"}))
+		Expect(transformed.Status.Network).To(BeNil())
+		Expect(transformed.Status.MachineRef.Name).To(Equal(defaultByoMachineName))
+		Expect(transformed.Status.HostDetails.OSName).To(Equal(testOSNameLinux))
+	})
+
+	It("should drop the managed fields of the other objects", func() {
+		byoMachine := &infrav1.ByoMachine{ObjectMeta: metav1.ObjectMeta{Name: defaultByoMachineName, ManagedFields: managedFields}}
+
+		obj, err := controllers.StripManagedFields(byoMachine)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obj.(*infrav1.ByoMachine).ManagedFields).To(BeNil())
+		Expect(obj.(*infrav1.ByoMachine).Name).To(Equal(defaultByoMachineName))
+	})
+
+	It("should only cache the ByoHosts with the watch label if the watch filter is set", func() {
+		Expect(controllers.CacheOptions("").SelectorsByObject).To(BeEmpty())
+
+		selectors := controllers.CacheOptions("byoh-a").SelectorsByObject
+		Expect(selectors).To(HaveLen(1))
+		for obj, selector := range selectors {
+			Expect(obj).To(BeAssignableToTypeOf(&infrav1.ByoHost{}))
+			Expect(selector.Label.Matches(labels.Set{clusterv1.WatchLabel: "byoh-a"})).To(BeTrue())
+			Expect(selector.Label.Matches(labels.Set{clusterv1.WatchLabel: "byoh-b"})).To(BeFalse())
+		}
+	})
+})
@@ -1,27 +1,34 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	infrav1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
+	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -40,6 +47,8 @@ type K8sInstallerConfigReconciler struct {
 	// containerized hosts, they share Docker's kernel, and unloading them there breaks Docker's own
 	// bridge networking and hangs cluster deletion.
 	SkipKernelModuleCleanup bool
+	// WatchFilterValue is the label value used to filter events prior to reconciliation.
+	WatchFilterValue string
 }
 
 // k8sInstallerConfigScope defines a scope defined around a K8sInstallerConfig and its ByoMachine
@@ -57,12 +66,18 @@ type k8sInstallerConfigScope struct {
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byomachines,verbs=get;list;watch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=byomachines/status,verbs=get
 // +kubebuilder:rbac:groups="",resources=secrets;events,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups=controlplane.cluster.x-k8s.io,resources=*,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *K8sInstallerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, reterr error) {
 	logger := log.FromContext(ctx)
 	logger.Info("Reconcile request received")
+	ctx, span := tracing.Start(ctx, "K8sInstallerConfig.Reconcile")
+	defer func() {
+		span.End(reterr)
+	}()
 
 	// Fetch the K8sInstallerConfig instance
 	config := &infrav1.K8sInstallerConfig{}
@@ -88,6 +103,9 @@ func (r *K8sInstallerConfigReconciler) Reconcile(ctx context.Context, req ctrl.R
 		logger.Error(err, "failed to get Owner ByoMachine")
 		return ctrl.Result{}, err
 	}
+	if byoMachine != nil {
+		span.SetAttributes(tracing.String(tracing.MachineNameKey, byoMachine.Name))
+	}
 
 	helper, err := patch.NewHelper(config, r.Client)
 	if err != nil {
@@ -111,8 +129,15 @@ func (r *K8sInstallerConfigReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return r.reconcileDelete(ctx, scope)
 	}
 
+	// the dry run does not need a ByoMachine, and a failed one does not hold up the installation
+	if err := r.reconcileDryRun(ctx, scope); err != nil {
+		logger.Error(err, "failed to render installer scripts for dry run")
+		conditions.MarkFalse(config, infrav1.InstallerDryRunSucceeded, infrav1.InstallerDryRunFailedReason, clusterv1.ConditionSeverityWarning, "%v", err)
+	}
+
 	if byoMachine == nil {
 		logger.Info("Waiting for ByoMachine Controller to set OwnerRef on InstallerConfig")
+		conditions.MarkFalse(config, infrav1.SecretGenerated, infrav1.WaitingForOwnerByoMachineReason, clusterv1.ConditionSeverityInfo, "")
 		return ctrl.Result{}, nil
 	}
 	scope.ByoMachine = byoMachine
@@ -134,15 +159,23 @@ func (r *K8sInstallerConfigReconciler) Reconcile(ctx context.Context, req ctrl.R
 		return ctrl.Result{}, nil
 	}
 
+	// the generated secrets are never regenerated, flag them if the spec changed since;
+	// configs made ready before the observed generation was tracked are left alone
+	if config.Status.Ready && config.Status.ObservedGeneration != 0 && config.Generation != config.Status.ObservedGeneration {
+		conditions.MarkFalse(config, infrav1.InstallationSecretUpToDate, infrav1.SpecChangedAfterGenerationReason, clusterv1.ConditionSeverityWarning,
+			"the installation secret was generated from generation %d of the spec", config.Status.ObservedGeneration)
+	}
+
 	switch {
-	// waiting for ByoMachine to updating it's ByoHostReady condition to false for reason InstallationSecretNotAvailableReason
-	case conditions.GetReason(byoMachine, infrav1.BYOHostReady) != infrav1.InstallationSecretNotAvailableReason:
-		logger.Info("ByoMachine is not waiting for InstallationSecret", "reason", conditions.GetReason(byoMachine, infrav1.BYOHostReady))
-		return ctrl.Result{}, nil
 	// Status is ready means a config has been generated.
 	case config.Status.Ready:
 		logger.Info("K8sInstallerConfig is ready")
 		return ctrl.Result{}, nil
+	// waiting for ByoMachine to updating it's ByoHostReady condition to false for reason InstallationSecretNotAvailableReason
+	case conditions.GetReason(byoMachine, infrav1.BYOHostReady) != infrav1.InstallationSecretNotAvailableReason:
+		logger.Info("ByoMachine is not waiting for InstallationSecret", "reason", conditions.GetReason(byoMachine, infrav1.BYOHostReady))
+		conditions.MarkFalse(config, infrav1.SecretGenerated, infrav1.WaitingForInstallationRequestReason, clusterv1.ConditionSeverityInfo, "")
+		return ctrl.Result{}, nil
 	}
 
 	return r.reconcileNormal(ctx, scope)
@@ -153,21 +186,245 @@ func (r *K8sInstallerConfigReconciler) reconcileNormal(ctx context.Context, scop
 	logger.Info("Reconciling K8sInstallerConfig")
 
 	k8sVersion := scope.Config.GetAnnotations()[infrav1.K8sVersionAnnotation]
-	downloader := installer.NewBundleDownloader(scope.Config.Spec.BundleType, scope.Config.Spec.BundleRepo, "{{.BUNDLE_DOWNLOAD_PATH}}", logger)
-	installerObj, err := installer.NewInstaller(ctx, scope.ByoMachine.Status.HostInfo.OSImage, scope.ByoMachine.Status.HostInfo.Architecture, k8sVersion, downloader, r.SkipKernelModuleCleanup)
+	if k8sVersion != "" {
+		if err := r.validateK8sVersion(ctx, scope, k8sVersion); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	templates, err := r.getTemplates(ctx, scope)
+	if err != nil {
+		logger.Error(err, "failed to get the installer templates")
+		conditions.MarkFalse(scope.Config, infrav1.SecretGenerated, infrav1.TemplatesUnavailableReason, clusterv1.ConditionSeverityWarning, "%v", err)
+		return ctrl.Result{}, err
+	}
+	install, uninstall, err := r.renderScripts(ctx, scope, scope.ByoMachine.Status.HostInfo.OSImage, scope.ByoMachine.Status.HostInfo.Architecture, k8sVersion, templates)
 	if err != nil {
 		logger.Error(err, "failed to create installer instance", "osImage", scope.ByoMachine.Status.HostInfo.OSImage, "architecture", scope.ByoMachine.Status.HostInfo.Architecture, "k8sVersion", k8sVersion)
+		conditions.MarkFalse(scope.Config, infrav1.BundleResolved, infrav1.BundleResolutionFailedReason, clusterv1.ConditionSeverityError, "%v", err)
 		return ctrl.Result{}, err
 	}
+	conditions.MarkTrue(scope.Config, infrav1.BundleResolved)
 
 	// creating installation secret
-	if err := r.storeInstallationData(ctx, scope, installerObj.Install(), installerObj.Uninstall()); err != nil {
+	if err := r.storeInstallationData(ctx, scope, install, uninstall); err != nil {
+		conditions.MarkFalse(scope.Config, infrav1.SecretGenerated, infrav1.SecretGenerationFailedReason, clusterv1.ConditionSeverityWarning, "%v", err)
 		return ctrl.Result{}, err
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// validateK8sVersion checks that a bundle is available for k8sVersion on the host and that the
+// version skew policy lets a node of k8sVersion join the control plane of the cluster, so the
+// installation fails with a condition on the config instead of kubeadm join failing on the host
+func (r *K8sInstallerConfigReconciler) validateK8sVersion(ctx context.Context, scope *k8sInstallerConfigScope, k8sVersion string) error {
+	hostInfo := scope.ByoMachine.Status.HostInfo
+	if err := installer.ValidateK8sVersion(hostInfo.OSImage, hostInfo.Architecture, k8sVersion); err != nil {
+		scope.Logger.Error(err, "no bundle available", "osImage", hostInfo.OSImage, "architecture", hostInfo.Architecture, "k8sVersion", k8sVersion)
+		conditions.MarkFalse(scope.Config, infrav1.BundleResolved, infrav1.BundleResolutionFailedReason, clusterv1.ConditionSeverityError, "%v", err)
+		return err
+	}
+
+	controlPlaneVersion, err := r.getControlPlaneVersion(ctx, scope.Cluster)
+	if err != nil {
+		return err
+	}
+	// the version is unknown until the control plane provider sets it, there is nothing to check against yet
+	if controlPlaneVersion == "" {
+		return nil
+	}
+	if err := checkVersionSkew(k8sVersion, controlPlaneVersion); err != nil {
+		scope.Logger.Error(err, "unsupported version skew", "k8sVersion", k8sVersion, "controlPlaneVersion", controlPlaneVersion)
+		conditions.MarkFalse(scope.Config, infrav1.BundleResolved, infrav1.VersionSkewNotSupportedReason, clusterv1.ConditionSeverityError, "%v", err)
+		return err
+	}
+	return nil
+}
+
+// getControlPlaneVersion returns the desired Kubernetes version of the control plane of the cluster,
+// read from the topology of a ClusterClass based cluster or from the spec of the control plane object
+func (r *K8sInstallerConfigReconciler) getControlPlaneVersion(ctx context.Context, cluster *clusterv1.Cluster) (string, error) {
+	if cluster.Spec.Topology != nil && cluster.Spec.Topology.Version != "" {
+		return cluster.Spec.Topology.Version, nil
+	}
+	ref := cluster.Spec.ControlPlaneRef
+	if ref == nil {
+		return "", nil
+	}
+
+	controlPlane := &unstructured.Unstructured{}
+	controlPlane.SetAPIVersion(ref.APIVersion)
+	controlPlane.SetKind(ref.Kind)
+	key := client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+	if key.Namespace == "" {
+		key.Namespace = cluster.Namespace
+	}
+	if err := r.Client.Get(ctx, key, controlPlane); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get control plane %s %s", ref.Kind, key)
+	}
+	version, _, err := unstructured.NestedString(controlPlane.Object, "spec", "version")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the version of control plane %s %s", ref.Kind, key)
+	}
+	return version, nil
+}
+
+// checkVersionSkew returns an error if a kubelet of nodeVersion is not supported by a control plane
+// of controlPlaneVersion: the kubelet must not be newer than the kube-apiserver, and may be up to
+// three minor versions older, or two before Kubernetes 1.28
+func checkVersionSkew(nodeVersion, controlPlaneVersion string) error {
+	node, err := version.ParseGeneric(nodeVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the Kubernetes version %s", nodeVersion)
+	}
+	controlPlane, err := version.ParseGeneric(controlPlaneVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse the control plane version %s", controlPlaneVersion)
+	}
+
+	maxSkew := uint(3)
+	if controlPlane.LessThan(version.MustParseGeneric("1.28")) {
+		maxSkew = 2
+	}
+	switch {
+	case node.Major() != controlPlane.Major() || node.Minor() > controlPlane.Minor():
+		return fmt.Errorf("kubernetes version %s is newer than the control plane version %s", nodeVersion, controlPlaneVersion)
+	case controlPlane.Minor()-node.Minor() > maxSkew:
+		return fmt.Errorf("kubernetes version %s is more than %d minor versions older than the control plane version %s", nodeVersion, maxSkew, controlPlaneVersion)
+	}
+	return nil
+}
+
+// getTemplates returns the template overrides of the ConfigMap referenced by the config, if any
+func (r *K8sInstallerConfigReconciler) getTemplates(ctx context.Context, scope *k8sInstallerConfigScope) (installer.Templates, error) {
+	ref := scope.Config.Spec.TemplatesConfigMapRef
+	if ref == nil {
+		return installer.Templates{}, nil
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: scope.Config.Namespace, Name: ref.Name}, configMap); err != nil {
+		return installer.Templates{}, errors.Wrapf(err, "failed to get the templates ConfigMap %s", ref.Name)
+	}
+	templates, err := installer.NewTemplates(configMap.Data)
+	if err != nil {
+		return installer.Templates{}, errors.Wrapf(err, "invalid templates ConfigMap %s", ref.Name)
+	}
+	return templates, nil
+}
+
+// renderScripts renders the install and uninstall scripts of the config for the passed host OS and arch
+func (r *K8sInstallerConfigReconciler) renderScripts(ctx context.Context, scope *k8sInstallerConfigScope, osImage, arch, k8sVersion string, templates installer.Templates) (install, uninstall string, err error) {
+	downloader := installer.NewBundleDownloader(scope.Config.Spec.BundleType, scope.Config.Spec.BundleRepoAddr(), "{{.BUNDLE_DOWNLOAD_PATH}}", scope.Logger)
+	opts := installer.Options{
+		CRI:             scope.Config.Spec.CRI,
+		SwapPolicy:      scope.Config.Spec.SwapPolicy,
+		LocalBundlePath: scope.Config.Spec.BundlePath,
+		Proxy: installer.ProxyConfig{
+			HTTPProxy:  scope.Config.Spec.HTTPProxy,
+			HTTPSProxy: scope.Config.Spec.HTTPSProxy,
+			NoProxy:    scope.Config.Spec.NoProxy,
+		},
+		RegistryMirrors: make([]installer.RegistryMirror, 0, len(scope.Config.Spec.RegistryMirrors)),
+		RuntimeVersions: installer.RuntimeVersions{
+			Containerd: scope.Config.Spec.ContainerdVersion,
+			Runc:       scope.Config.Spec.RuncVersion,
+		},
+		Templates:               templates,
+		SkipKernelModuleCleanup: r.SkipKernelModuleCleanup,
+	}
+	for _, mirror := range scope.Config.Spec.RegistryMirrors {
+		opts.RegistryMirrors = append(opts.RegistryMirrors, installer.RegistryMirror{
+			Registry:  mirror.Registry,
+			Endpoints: mirror.Endpoints,
+			Insecure:  mirror.Insecure,
+		})
+	}
+	return installer.DryRun(ctx, osImage, arch, k8sVersion, downloader, opts)
+}
+
+// installerDryRun is the value of the InstallerDryRunAnnotation, it uses the field names of the HostInfo
+// of a ByoHost so that the host info of a host can be copied as is
+type installerDryRun struct {
+	OSImage      string `json:"osimage"`
+	Architecture string `json:"architecture"`
+	K8sVersion   string `json:"k8sversion,omitempty"`
+}
+
+// reconcileDryRun renders the scripts of the config for the host requested by the InstallerDryRunAnnotation
+// into the dry-run secret, and deletes the secret once the annotation is removed. Nothing is run, and the
+// installation and uninstallation secrets are left untouched.
+func (r *K8sInstallerConfigReconciler) reconcileDryRun(ctx context.Context, scope *k8sInstallerConfigScope) error {
+	value, ok := scope.Config.Annotations[infrav1.InstallerDryRunAnnotation]
+	if !ok {
+		return r.deleteDryRunSecret(ctx, scope)
+	}
+	logger := scope.Logger
+	logger.Info("rendering installer scripts for dry run")
+
+	dryRun := installerDryRun{}
+	if err := json.Unmarshal([]byte(value), &dryRun); err != nil {
+		return errors.Wrapf(err, "failed to decode the %s annotation", infrav1.InstallerDryRunAnnotation)
+	}
+	if dryRun.K8sVersion == "" {
+		dryRun.K8sVersion = scope.Config.Annotations[infrav1.K8sVersionAnnotation]
+	}
+	templates, err := r.getTemplates(ctx, scope)
+	if err != nil {
+		return err
+	}
+	install, uninstall, err := r.renderScripts(ctx, scope, dryRun.OSImage, dryRun.Architecture, dryRun.K8sVersion, templates)
+	if err != nil {
+		return errors.Wrapf(err, "failed to render installer scripts for %s/%s", dryRun.OSImage, dryRun.Architecture)
+	}
+
+	dryRunSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: dryRunSecretName(scope.Config), Namespace: scope.Config.Namespace}}
+	// the secret is only written when the rendered scripts differ from its content
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, dryRunSecret, func() error {
+		dryRunSecret.OwnerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: infrav1.GroupVersion.String(),
+				Kind:       scope.Config.Kind,
+				Name:       scope.Config.Name,
+				UID:        scope.Config.UID,
+				Controller: pointer.Bool(true),
+			},
+		}
+		dryRunSecret.Data = map[string][]byte{
+			"install":   []byte(install),
+			"uninstall": []byte(uninstall),
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to write dry-run secret for K8sInstallerConfig %s/%s", scope.Config.Namespace, scope.Config.Name)
+	}
+	conditions.MarkTrue(scope.Config, infrav1.InstallerDryRunSucceeded)
+	logger.Info("dry-run secret reconciled", "secret", dryRunSecret.Name, "operation", result, "osImage", dryRun.OSImage, "architecture", dryRun.Architecture)
+	return nil
+}
+
+// deleteDryRunSecret deletes the dry-run secret of a config whose InstallerDryRunAnnotation was removed
+func (r *K8sInstallerConfigReconciler) deleteDryRunSecret(ctx context.Context, scope *k8sInstallerConfigScope) error {
+	if conditions.Get(scope.Config, infrav1.InstallerDryRunSucceeded) == nil {
+		return nil
+	}
+	dryRunSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: dryRunSecretName(scope.Config), Namespace: scope.Config.Namespace}}
+	if err := r.Delete(ctx, dryRunSecret); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete dry-run secret for K8sInstallerConfig %s/%s", scope.Config.Namespace, scope.Config.Name)
+	}
+	conditions.Delete(scope.Config, infrav1.InstallerDryRunSucceeded)
+	scope.Logger.Info("dry-run secret deleted", "secret", dryRunSecret.Name)
+	return nil
+}
+
+// dryRunSecretName returns the name of the secret the scripts of a dry run are rendered into
+func dryRunSecretName(config *infrav1.K8sInstallerConfig) string {
+	return "byoh-dry-run-" + config.Name
+}
+
 // storeInstallationData creates a new secret with the install and unstall data passed in as input,
 // sets the reference in the configuration status and ready to true.
 func (r *K8sInstallerConfigReconciler) storeInstallationData(ctx context.Context, scope *k8sInstallerConfigScope, install, uninstall string) error {
@@ -222,8 +479,11 @@ func (r *K8sInstallerConfigReconciler) storeInstallationData(ctx context.Context
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "byoh-uninstall-" + scope.Config.Name,
 			Namespace: scope.Config.Namespace,
+			// the secret has no owner, so that the host can be cleaned up once the K8sInstallerConfig
+			// is deleted; clusterctl move relocates it with the move label instead
 			Labels: map[string]string{
-				clusterv1.ClusterNameLabel: scope.Cluster.Name,
+				clusterv1.ClusterNameLabel:       scope.Cluster.Name,
+				clusterctlv1.ClusterctlMoveLabel: "",
 			},
 		},
 		Data: map[string][]byte{
@@ -250,6 +510,9 @@ func (r *K8sInstallerConfigReconciler) storeInstallationData(ctx context.Context
 	logger.Info("uninstallation secret created", "secret", uninstallSecret.Name, "K8sInstallerConfig", scope.Config.Name)
 
 	scope.Config.Status.Ready = true
+	conditions.MarkTrue(scope.Config, infrav1.SecretGenerated)
+	conditions.MarkTrue(scope.Config, infrav1.InstallationSecretUpToDate)
+	scope.Config.Status.ObservedGeneration = scope.Config.Generation
 	logger.Info("created installation and uninstallation secrets")
 
 	// Status fields are set in-memory; the outer reconcile's defer-patch persists
@@ -263,6 +526,7 @@ func (r *K8sInstallerConfigReconciler) storeInstallationData(ctx context.Context
 func (r *K8sInstallerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1.K8sInstallerConfig{}).
+		WithEventFilter(predicates.ResourceHasFilterLabel(mgr.GetLogger(), r.WatchFilterValue)).
 		Watches(
 			&source.Kind{Type: &infrav1.ByoMachine{}},
 			handler.EnqueueRequestsFromMapFunc(r.ByoMachineToK8sInstallerConfigMapFunc),
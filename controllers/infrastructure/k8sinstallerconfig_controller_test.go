@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers_test
@@ -13,11 +14,13 @@ import (
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
 	eventutils "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/utils/events"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	clusterctlv1 "sigs.k8s.io/cluster-api/cmd/clusterctl/api/v1alpha3"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
@@ -184,6 +187,140 @@ var _ = Describe("Controllers/K8sInstallerConfigController", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
+	It("should mark SecretGenerated false when byomachine is not waiting for InstallationSecret", func() {
+		_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      k8sinstallerConfig.Name,
+				Namespace: k8sinstallerConfig.Namespace}})
+		Expect(err).NotTo(HaveOccurred())
+
+		updatedConfig := &infrav1.K8sInstallerConfig{}
+		Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+		Expect(*conditions.Get(updatedConfig, infrav1.SecretGenerated)).To(conditions.MatchCondition(clusterv1.Condition{
+			Type:     infrav1.SecretGenerated,
+			Status:   corev1.ConditionFalse,
+			Reason:   infrav1.WaitingForInstallationRequestReason,
+			Severity: clusterv1.ConditionSeverityInfo,
+		}))
+		// no installation secret was generated yet
+		Expect(updatedConfig.Status.ObservedGeneration).To(BeZero())
+	})
+
+	Context("When the installer dry-run annotation is set", func() {
+		var dryRunSecretLookupKey types.NamespacedName
+
+		BeforeEach(func() {
+			dryRunSecretLookupKey = types.NamespacedName{Name: "byoh-dry-run-" + k8sinstallerConfig.Name, Namespace: k8sinstallerConfig.Namespace}
+		})
+
+		It("should render the scripts into the dry-run secret without a host waiting for them", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Annotations = map[string]string{
+				infrav1.InstallerDryRunAnnotation: `{"osimage":"Ubuntu 22.04.1 LTS","architecture":"amd64","k8sversion":"v1.31.0"}`,
+			}
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.GetAnnotations()[infrav1.InstallerDryRunAnnotation] != ""
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			dryRunSecret := &corev1.Secret{}
+			Expect(k8sClientUncached.Get(ctx, dryRunSecretLookupKey, dryRunSecret)).Should(Succeed())
+			Expect(string(dryRunSecret.Data["install"])).To(ContainSubstring("byoh-bundle-ubuntu_22.04_x86-64_k8s:v1.31.0"))
+			Expect(dryRunSecret.Data).To(HaveKey("uninstall"))
+			Expect(dryRunSecret.OwnerReferences).To(HaveLen(1))
+			Expect(dryRunSecret.OwnerReferences[0].Name).To(Equal(k8sinstallerConfig.Name))
+
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(updatedConfig.Status.InstallationSecret).To(BeNil())
+			Expect(conditions.IsTrue(updatedConfig, infrav1.InstallerDryRunSucceeded)).To(BeTrue())
+
+			// the secret is not written again while the rendered scripts do not change
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+			unchangedSecret := &corev1.Secret{}
+			Expect(k8sClientUncached.Get(ctx, dryRunSecretLookupKey, unchangedSecret)).Should(Succeed())
+			Expect(unchangedSecret.ResourceVersion).To(Equal(dryRunSecret.ResourceVersion))
+		})
+
+		It("should delete the dry-run secret once the annotation is removed", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Annotations = map[string]string{
+				infrav1.InstallerDryRunAnnotation: `{"osimage":"Ubuntu 22.04.1 LTS","architecture":"amd64","k8sversion":"v1.31.0"}`,
+			}
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.GetAnnotations()[infrav1.InstallerDryRunAnnotation] != ""
+			})
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClientUncached.Get(ctx, dryRunSecretLookupKey, &corev1.Secret{})).Should(Succeed())
+
+			annotatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, annotatedConfig)).Should(Succeed())
+			ph, err = patch.NewHelper(annotatedConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			delete(annotatedConfig.Annotations, infrav1.InstallerDryRunAnnotation)
+			Expect(ph.Patch(ctx, annotatedConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(annotatedConfig, func(object client.Object) bool {
+				_, ok := object.GetAnnotations()[infrav1.InstallerDryRunAnnotation]
+				return !ok
+			})
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClientUncached.Get(ctx, dryRunSecretLookupKey, &corev1.Secret{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(conditions.Get(updatedConfig, infrav1.InstallerDryRunSucceeded)).To(BeNil())
+		})
+
+		It("should not fail the reconcile when the annotation is invalid", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Annotations = map[string]string{
+				infrav1.InstallerDryRunAnnotation: `{"osimage":"rhel","architecture":"amd64"}`,
+			}
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.GetAnnotations()[infrav1.InstallerDryRunAnnotation] != ""
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			dryRunSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, dryRunSecretLookupKey, dryRunSecret)
+			Expect(err).To(HaveOccurred())
+
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(conditions.IsFalse(updatedConfig, infrav1.InstallerDryRunSucceeded)).To(BeTrue())
+			Expect(conditions.GetReason(updatedConfig, infrav1.InstallerDryRunSucceeded)).To(Equal(infrav1.InstallerDryRunFailedReason))
+		})
+	})
+
 	Context("When ByoMachine wait for InstallerSecret", func() {
 
 		BeforeEach(func() {
@@ -251,6 +388,33 @@ var _ = Describe("Controllers/K8sInstallerConfigController", func() {
 			Expect(err).Should(MatchError("No k8s support for OS"))
 		})
 
+		It("should mark BundleResolved false if os distribution is not supported", func() {
+			ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			unsupportedOsDist := "unsupportedOsDist"
+			byoMachine.Status.HostInfo.OSImage = unsupportedOsDist
+			Expect(ph.Patch(ctx, byoMachine, patch.WithStatusObservedGeneration{})).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(byoMachine, func(object client.Object) bool {
+				return object.(*infrav1.ByoMachine).Status.HostInfo.OSImage == unsupportedOsDist
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).Should(HaveOccurred())
+
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(*conditions.Get(updatedConfig, infrav1.BundleResolved)).To(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrav1.BundleResolved,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrav1.BundleResolutionFailedReason,
+				Severity: clusterv1.ConditionSeverityError,
+				Message:  "No k8s support for OS",
+			}))
+		})
+
 		It("should throw error if architecture is not supported", func() {
 			ph, err := patch.NewHelper(byoMachine, k8sClientUncached)
 			Expect(err).ShouldNot(HaveOccurred())
@@ -305,6 +469,269 @@ var _ = Describe("Controllers/K8sInstallerConfigController", func() {
 			Expect(exists).To(BeTrue())
 		})
 
+		It("should render the proxy configuration into the install and uninstall scripts", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Spec.HTTPProxy = "http://proxy.example.com:3128"
+			k8sinstallerConfig.Spec.HTTPSProxy = "http://proxy.example.com:3128"
+			k8sinstallerConfig.Spec.NoProxy = "localhost,127.0.0.1"
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.(*infrav1.K8sInstallerConfig).Spec.HTTPProxy != ""
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			installSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, installerSecretLookupKey, installSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring(`export HTTPS_PROXY="http://proxy.example.com:3128"`))
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring("containerd.service.d/http-proxy.conf"))
+
+			uninstallSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, types.NamespacedName{Name: "byoh-uninstall-" + k8sinstallerConfig.Name, Namespace: k8sinstallerConfig.Namespace}, uninstallSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(uninstallSecret.Data["uninstall"])).To(ContainSubstring(`export NO_PROXY="localhost,127.0.0.1"`))
+		})
+
+		It("should copy the bundle from the bundle path instead of pulling it", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Spec.BundlePath = "/opt/byoh/bundle.tar"
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.(*infrav1.K8sInstallerConfig).Spec.BundlePath != ""
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			installSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, installerSecretLookupKey, installSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring(`LOCAL_BUNDLE_PATH="/opt/byoh/bundle.tar"`))
+			Expect(string(installSecret.Data["install"])).NotTo(ContainSubstring("imgpkg pull"))
+		})
+
+		It("should check the containerd and runc versions set on the config", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Spec.ContainerdVersion = "1.7.30"
+			k8sinstallerConfig.Spec.RuncVersion = "1.2.5"
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.(*infrav1.K8sInstallerConfig).Spec.RuncVersion != ""
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			installSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, installerSecretLookupKey, installSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring(`grep -qF " v1.7.30 "`))
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring(`grep -qx "runc version 1.2.5"`))
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring("containerd 1.7.30 is required"))
+		})
+
+		It("should keep swap enabled when the swap policy is keep", func() {
+			ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			k8sinstallerConfig.Spec.SwapPolicy = "keep"
+			Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+				return object.(*infrav1.K8sInstallerConfig).Spec.SwapPolicy == "keep"
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			installSecret := &corev1.Secret{}
+			err = k8sClientUncached.Get(ctx, installerSecretLookupKey, installSecret)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(installSecret.Data["install"])).To(ContainSubstring(`echo "keep" > /var/lib/byoh/swap-policy`))
+			Expect(string(installSecret.Data["install"])).NotTo(ContainSubstring("swapoff -a"))
+		})
+
+		Context("When the K8sInstallerConfig references a templates ConfigMap", func() {
+			var templatesConfigMap *corev1.ConfigMap
+
+			BeforeEach(func() {
+				templatesConfigMap = &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "byoh-installer-templates",
+						Namespace: defaultNamespace,
+					},
+					Data: map[string]string{
+						"pre-install":    "echo hardening {{.Arch}} host",
+						"post-uninstall": "rm -f /etc/site-agent.conf",
+					},
+				}
+
+				ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				k8sinstallerConfig.Spec.TemplatesConfigMapRef = &corev1.LocalObjectReference{Name: templatesConfigMap.Name}
+				Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+					return object.(*infrav1.K8sInstallerConfig).Spec.TemplatesConfigMapRef != nil
+				})
+			})
+
+			It("should render the hooks of the ConfigMap into the scripts", func() {
+				Expect(k8sClientUncached.Create(ctx, templatesConfigMap)).Should(Succeed())
+				DeferCleanup(func() {
+					Expect(k8sClientUncached.Delete(ctx, templatesConfigMap)).Should(Succeed())
+				})
+				WaitForObjectsToBePopulatedInCache(templatesConfigMap)
+
+				_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      k8sinstallerConfig.Name,
+						Namespace: k8sinstallerConfig.Namespace}})
+				Expect(err).NotTo(HaveOccurred())
+
+				installSecret := &corev1.Secret{}
+				Expect(k8sClientUncached.Get(ctx, installerSecretLookupKey, installSecret)).Should(Succeed())
+				Expect(string(installSecret.Data["install"])).To(ContainSubstring("echo hardening amd64 host"))
+				Expect(string(installSecret.Data["uninstall"])).To(ContainSubstring("rm -f /etc/site-agent.conf"))
+			})
+
+			It("should mark SecretGenerated false when the ConfigMap has an unknown key", func() {
+				templatesConfigMap.Data["preinstall"] = "echo typo"
+				Expect(k8sClientUncached.Create(ctx, templatesConfigMap)).Should(Succeed())
+				DeferCleanup(func() {
+					Expect(k8sClientUncached.Delete(ctx, templatesConfigMap)).Should(Succeed())
+				})
+				WaitForObjectsToBePopulatedInCache(templatesConfigMap)
+
+				_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      k8sinstallerConfig.Name,
+						Namespace: k8sinstallerConfig.Namespace}})
+				Expect(err).Should(HaveOccurred())
+
+				updatedConfig := &infrav1.K8sInstallerConfig{}
+				Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+				Expect(conditions.GetReason(updatedConfig, infrav1.SecretGenerated)).To(Equal(infrav1.TemplatesUnavailableReason))
+				Expect(conditions.GetMessage(updatedConfig, infrav1.SecretGenerated)).To(ContainSubstring("preinstall"))
+			})
+
+			It("should mark SecretGenerated false when the ConfigMap does not exist", func() {
+				_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      k8sinstallerConfig.Name,
+						Namespace: k8sinstallerConfig.Namespace}})
+				Expect(err).Should(HaveOccurred())
+
+				updatedConfig := &infrav1.K8sInstallerConfig{}
+				Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+				Expect(conditions.GetReason(updatedConfig, infrav1.SecretGenerated)).To(Equal(infrav1.TemplatesUnavailableReason))
+				Expect(k8sClientUncached.Get(ctx, installerSecretLookupKey, &corev1.Secret{})).ShouldNot(Succeed())
+			})
+		})
+
+		Context("When the K8sInstallerConfig has a Kubernetes version", func() {
+			setK8sVersion := func(k8sVersion string) {
+				ph, err := patch.NewHelper(k8sinstallerConfig, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				k8sinstallerConfig.Annotations = map[string]string{infrav1.K8sVersionAnnotation: k8sVersion}
+				Expect(ph.Patch(ctx, k8sinstallerConfig)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(k8sinstallerConfig, func(object client.Object) bool {
+					return object.GetAnnotations()[infrav1.K8sVersionAnnotation] == k8sVersion
+				})
+			}
+
+			setControlPlaneVersion := func(controlPlaneVersion string) {
+				ph, err := patch.NewHelper(capiCluster, k8sClientUncached)
+				Expect(err).ShouldNot(HaveOccurred())
+				capiCluster.Spec.Topology = &clusterv1.Topology{Class: "byoh-class", Version: controlPlaneVersion}
+				Expect(ph.Patch(ctx, capiCluster)).Should(Succeed())
+				WaitForObjectToBeUpdatedInCache(capiCluster, func(object client.Object) bool {
+					topology := object.(*clusterv1.Cluster).Spec.Topology
+					return topology != nil && topology.Version == controlPlaneVersion
+				})
+				DeferCleanup(func() {
+					ph, err := patch.NewHelper(capiCluster, k8sClientUncached)
+					Expect(err).ShouldNot(HaveOccurred())
+					capiCluster.Spec.Topology = nil
+					Expect(ph.Patch(ctx, capiCluster)).Should(Succeed())
+					WaitForObjectToBeUpdatedInCache(capiCluster, func(object client.Object) bool {
+						return object.(*clusterv1.Cluster).Spec.Topology == nil
+					})
+				})
+			}
+
+			reconcileAndGetBundleResolved := func() (*clusterv1.Condition, error) {
+				_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      k8sinstallerConfig.Name,
+						Namespace: k8sinstallerConfig.Namespace}})
+
+				updatedConfig := &infrav1.K8sInstallerConfig{}
+				Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+				return conditions.Get(updatedConfig, infrav1.BundleResolved), err
+			}
+
+			It("should create the secret when the version is within the skew of the control plane", func() {
+				setK8sVersion("v1.31.0")
+				setControlPlaneVersion("v1.32.1")
+
+				condition, err := reconcileAndGetBundleResolved()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+				Expect(k8sClientUncached.Get(ctx, installerSecretLookupKey, &corev1.Secret{})).Should(Succeed())
+			})
+
+			It("should mark BundleResolved false when no bundle is available for the version", func() {
+				setK8sVersion("v1.30.2")
+
+				condition, err := reconcileAndGetBundleResolved()
+				Expect(err).Should(HaveOccurred())
+				Expect(condition.Status).To(Equal(corev1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(infrav1.BundleResolutionFailedReason))
+				Expect(condition.Message).To(ContainSubstring("No bundle for k8s version v1.30.2"))
+			})
+
+			It("should mark BundleResolved false when the version is newer than the control plane", func() {
+				setK8sVersion("v1.31.2")
+				setControlPlaneVersion("v1.30.0")
+
+				condition, err := reconcileAndGetBundleResolved()
+				Expect(err).Should(HaveOccurred())
+				Expect(*condition).To(conditions.MatchCondition(clusterv1.Condition{
+					Type:     infrav1.BundleResolved,
+					Status:   corev1.ConditionFalse,
+					Reason:   infrav1.VersionSkewNotSupportedReason,
+					Severity: clusterv1.ConditionSeverityError,
+					Message:  "kubernetes version v1.31.2 is newer than the control plane version v1.30.0",
+				}))
+				Expect(k8sClientUncached.Get(ctx, installerSecretLookupKey, &corev1.Secret{})).ShouldNot(Succeed())
+			})
+
+			It("should mark BundleResolved false when the version is too old for the control plane", func() {
+				setK8sVersion("v1.31.0")
+				setControlPlaneVersion("v1.35.0")
+
+				condition, err := reconcileAndGetBundleResolved()
+				Expect(err).Should(HaveOccurred())
+				Expect(condition.Reason).To(Equal(infrav1.VersionSkewNotSupportedReason))
+				Expect(condition.Message).To(Equal("kubernetes version v1.31.0 is more than 3 minor versions older than the control plane version v1.35.0"))
+			})
+		})
+
 		It("should be add secret reference to K8sInstallerConfig", func() {
 			_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: types.NamespacedName{
@@ -377,6 +804,51 @@ var _ = Describe("Controllers/K8sInstallerConfigController", func() {
 			Expect(updatedConfig.Status.Ready).To(BeTrue())
 		})
 
+		It("should mark BundleResolved and SecretGenerated true after secret creation", func() {
+			_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(conditions.IsTrue(updatedConfig, infrav1.BundleResolved)).To(BeTrue())
+			Expect(conditions.IsTrue(updatedConfig, infrav1.SecretGenerated)).To(BeTrue())
+			Expect(conditions.IsTrue(updatedConfig, infrav1.InstallationSecretUpToDate)).To(BeTrue())
+			Expect(updatedConfig.Status.ObservedGeneration).To(Equal(updatedConfig.Generation))
+		})
+
+		It("should mark the installation secret out of date if the spec changes after secret creation", func() {
+			_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			updatedConfig := &infrav1.K8sInstallerConfig{}
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			ph, err := patch.NewHelper(updatedConfig, k8sClientUncached)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedConfig.Spec.HTTPProxy = "http://proxy.example.com:3128"
+			Expect(ph.Patch(ctx, updatedConfig)).Should(Succeed())
+			WaitForObjectToBeUpdatedInCache(updatedConfig, func(object client.Object) bool {
+				return object.(*infrav1.K8sInstallerConfig).Spec.HTTPProxy == "http://proxy.example.com:3128"
+			})
+
+			_, err = k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      k8sinstallerConfig.Name,
+					Namespace: k8sinstallerConfig.Namespace}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClientUncached.Get(ctx, k8sInstallerConfigLookupKey, updatedConfig)).Should(Succeed())
+			Expect(conditions.IsFalse(updatedConfig, infrav1.InstallationSecretUpToDate)).To(BeTrue())
+			Expect(conditions.GetReason(updatedConfig, infrav1.InstallationSecretUpToDate)).To(Equal(infrav1.SpecChangedAfterGenerationReason))
+			// the observed generation stays the one the secret was generated from
+			Expect(updatedConfig.Status.ObservedGeneration).To(BeNumerically("<", updatedConfig.Generation))
+		})
+
 		It("should create uninstall secret without owner reference", func() {
 			_, err := k8sInstallerConfigReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: types.NamespacedName{
@@ -394,6 +866,8 @@ var _ = Describe("Controllers/K8sInstallerConfigController", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(uninstallSecret.OwnerReferences).To(BeEmpty(),
 				"uninstall secret must have no owner so it is not GC'd when K8sInstallerConfig is deleted")
+			Expect(uninstallSecret.Labels).To(HaveKey(clusterctlv1.ClusterctlMoveLabel),
+				"uninstall secret must be moved by clusterctl move although it has no owner")
 		})
 
 		It("should create install secret with owner reference pointing to K8sInstallerConfig", func() {
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllers_test
@@ -159,7 +160,9 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	bootstrapKubeconfigReconciler = &controllers.BootstrapKubeconfigReconciler{
-		Client: k8sManager.GetClient(),
+		Client:    k8sManager.GetClient(),
+		APIReader: k8sManager.GetAPIReader(),
+		Recorder:  recorder,
 	}
 	err = bootstrapKubeconfigReconciler.SetupWithManager(k8sManager)
 	Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+# Copyright 2026 Platform9, Inc. All Rights Reserved.
+# SPDX-License-Identifier: Apache-2.0
+
+# Verifies that clusterctl move relocates every BYOH object of the management cluster of the
+# current kubeconfig context: the CRDs carry the contract and move labels, and the objects of the
+# clusters carry the cluster-name label and are owned by an object that is moved, or are labeled to
+# be moved themselves. Run it before clusterctl move; it lists the objects that would be left behind.
+#
+# Usage: hack/verify-clusterctl-move.sh [namespace]
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+NAMESPACE_ARGS=(--all-namespaces)
+if [[ $# -gt 0 ]]; then
+    NAMESPACE_ARGS=(--namespace "$1")
+fi
+
+CLUSTER_NAME_LABEL="cluster.x-k8s.io/cluster-name"
+MOVE_LABEL="clusterctl.cluster.x-k8s.io/move"
+MOVE_HIERARCHY_LABEL="clusterctl.cluster.x-k8s.io/move-hierarchy"
+ATTACHED_BYOMACHINE_LABEL="byoh.infrastructure.cluster.x-k8s.io/byomachine-name"
+failures=0
+
+fail() {
+    echo "FAIL  $*"
+    failures=$((failures + 1))
+}
+
+# every CRD of the provider is labeled with the contract it implements
+for crd in $(kubectl get crds -o name | grep '\.infrastructure\.cluster\.x-k8s\.io$' | grep -E '/(byo|k8sinstaller|bootstrapkubeconfig)'); do
+    if [[ -z "$(kubectl get "${crd}" -o jsonpath='{.metadata.labels.cluster\.x-k8s\.io/v1beta1}')" ]]; then
+        fail "${crd} has no cluster.x-k8s.io/v1beta1 contract label"
+    fi
+done
+
+# the objects that are not owned by the objects of a Cluster are moved by the labels of their CRD
+for kind in byohosts byohostoperations bootstrapkubeconfigs byohostadmissionpolicies; do
+    labels=$(kubectl get crd "${kind}.infrastructure.cluster.x-k8s.io" -o json | jq -r '.metadata.labels // {} | keys[]')
+    if ! grep -qxE "${MOVE_LABEL}|${MOVE_HIERARCHY_LABEL}" <<<"${labels}"; then
+        fail "crd/${kind}.infrastructure.cluster.x-k8s.io has neither the ${MOVE_LABEL} nor the ${MOVE_HIERARCHY_LABEL} label"
+    fi
+done
+
+# the ByoMachines and the K8sInstallerConfigs belong to a cluster and are owned by its objects
+for kind in byomachines k8sinstallerconfigs; do
+    while read -r object; do
+        fail "${object} has no cluster-name label or no owner"
+    done < <(kubectl get "${kind}" "${NAMESPACE_ARGS[@]}" -o json | jq -r --arg label "${CLUSTER_NAME_LABEL}" '
+        .items[]
+        | select((.metadata.labels[$label] // "") == "" or ((.metadata.ownerReferences // []) | length) == 0)
+        | "\(.kind) \(.metadata.namespace)/\(.metadata.name)"')
+done
+
+# the installation secrets are owned by their K8sInstallerConfig, the uninstallation secrets,
+# which outlive it, are labeled to be moved
+while read -r object; do
+    fail "${object} has no cluster-name label, or neither an owner nor the move label"
+done < <(kubectl get secrets "${NAMESPACE_ARGS[@]}" -o json | jq -r --arg label "${CLUSTER_NAME_LABEL}" --arg move "${MOVE_LABEL}" '
+    .items[]
+    | select(.metadata.name | startswith("byoh-install-") or startswith("byoh-uninstall-"))
+    | select((.metadata.labels[$label] // "") == ""
+        or (((.metadata.ownerReferences // []) | length) == 0 and ((.metadata.labels // {}) | has($move) | not)))
+    | "Secret \(.metadata.namespace)/\(.metadata.name)"')
+
+# the hosts attached to a machine keep the cluster they belong to
+while read -r object; do
+    fail "${object} is attached to a machine but has no cluster-name label"
+done < <(kubectl get byohosts "${NAMESPACE_ARGS[@]}" -o json | jq -r --arg label "${CLUSTER_NAME_LABEL}" --arg attached "${ATTACHED_BYOMACHINE_LABEL}" '
+    .items[]
+    | select((.metadata.labels[$attached] // "") != "")
+    | select((.metadata.labels[$label] // "") == "")
+    | "ByoHost \(.metadata.namespace)/\(.metadata.name)"')
+
+if [[ ${failures} -gt 0 ]]; then
+    echo "${failures} BYOH objects would not be moved by clusterctl move"
+    exit 1
+fi
+echo "Every BYOH object is moved by clusterctl move"
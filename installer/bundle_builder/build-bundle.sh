@@ -1,6 +1,7 @@
 #!/bin/bash
 
 # Copyright 2021 VMware, Inc. All Rights Reserved.
+# Copyright 2026 Platform9, Inc. All Rights Reserved.
 # SPDX-License-Identifier: Apache-2.0
 
 
@@ -16,14 +17,28 @@ ls -l $INGREDIENTS_PATH
 
 cd /bundle
 echo Strip version to well-known names
-# Mandatory
-cp $INGREDIENTS_PATH/*containerd* containerd.tar
-cp $INGREDIENTS_PATH/*kubeadm*.deb ./kubeadm.deb
-cp $INGREDIENTS_PATH/*kubelet*.deb ./kubelet.deb
-cp $INGREDIENTS_PATH/*kubectl*.deb ./kubectl.deb
-# Optional
-cp  $INGREDIENTS_PATH/*cri-tools*.deb cri-tools.deb > /dev/null | true
-cp  $INGREDIENTS_PATH/*kubernetes-cni*.deb kubernetes-cni.deb > /dev/null | true
+if ls $INGREDIENTS_PATH/*.raw > /dev/null 2>&1; then
+    # Immutable OSes (Flatcar), a systemd-sysext image with the k8s components
+    # replaces the debs and the OS ships its own containerd
+    cp $INGREDIENTS_PATH/*.raw kubernetes.raw
+else
+    # Mandatory
+    cp $INGREDIENTS_PATH/*containerd* containerd.tar
+    cp $INGREDIENTS_PATH/*kubeadm*.deb ./kubeadm.deb
+    cp $INGREDIENTS_PATH/*kubelet*.deb ./kubelet.deb
+    cp $INGREDIENTS_PATH/*kubectl*.deb ./kubectl.deb
+    # Optional
+    cp  $INGREDIENTS_PATH/*cri-tools*.deb cri-tools.deb > /dev/null | true
+    cp  $INGREDIENTS_PATH/*kubernetes-cni*.deb kubernetes-cni.deb > /dev/null | true
+    cp  $INGREDIENTS_PATH/runc.* runc > /dev/null | true
+fi
+# Optional, repack the cri-o static release so that it can be extracted under /
+if ls $INGREDIENTS_PATH/*cri-o* > /dev/null 2>&1; then
+    mkdir -p /tmp/cri-o-src /tmp/cri-o-root
+    tar -C /tmp/cri-o-src -xzf $INGREDIENTS_PATH/*cri-o*
+    (cd /tmp/cri-o-src/cri-o && DESTDIR=/tmp/cri-o-root ./install)
+    tar -C /tmp/cri-o-root -cvf cri-o.tar .
+fi
 
 echo Configuration $CONFIG_PATH
 ls -l $CONFIG_PATH
@@ -1,6 +1,7 @@
 #!/bin/bash
 
 # Copyright 2021 VMware, Inc. All Rights Reserved.
+# Copyright 2026 Platform9, Inc. All Rights Reserved.
 # SPDX-License-Identifier: Apache-2.0
 
 set -e
@@ -12,6 +13,16 @@ echo  Update the apt package index and install packages needed to use the Kubern
 echo Download containerd
 curl -LOJR https://github.com/containerd/containerd/releases/download/v${CONTAINERD_VERSION}/cri-containerd-cni-${CONTAINERD_VERSION}-linux-amd64.tar.gz 
 
+if [ -n "${RUNC_VERSION}" ]; then
+    echo Download runc
+    curl -LOJR https://github.com/opencontainers/runc/releases/download/v${RUNC_VERSION}/runc.${ARCH}
+fi
+
+if [ -n "${CRIO_VERSION}" ]; then
+    echo Download cri-o
+    curl -LOJR https://storage.googleapis.com/cri-o/artifacts/cri-o.${ARCH}.v${CRIO_VERSION}.tar.gz
+fi
+
 echo Download the Google Cloud public signing key
  curl -fsSLo /usr/share/keyrings/kubernetes-archive-keyring.gpg https://dl.k8s.io/apt/doc/apt-key.gpg
 
@@ -27,6 +38,12 @@ apt-get update
 chown -Rv _apt:root /bundle/
 chown -R _apt:root /ingredients
 mv cri-containerd-cni-${CONTAINERD_VERSION}-linux-amd64.tar.gz /ingredients/ 
+if [ -n "${RUNC_VERSION}" ]; then
+    mv runc.${ARCH} /ingredients/
+fi
+if [ -n "${CRIO_VERSION}" ]; then
+    mv cri-o.${ARCH}.v${CRIO_VERSION}.tar.gz /ingredients/
+fi
 cd /ingredients 
 apt-get download {kubelet,kubeadm,kubectl}:$ARCH=$KUBERNETES_VERSION
 apt-get download kubernetes-cni:$ARCH
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package installer
@@ -6,6 +7,7 @@ package installer
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer/internal/algo"
@@ -30,11 +32,27 @@ const (
 	BundleTypeK8s BundleType = "k8s"
 )
 
+const (
+	// CRIContainerd installs containerd as the container runtime
+	CRIContainerd = "containerd"
+	// CRICRIO installs CRI-O as the container runtime
+	CRICRIO = "cri-o"
+)
+
+const (
+	// SwapPolicyDisable turns off the swap of the host
+	SwapPolicyDisable = "disable"
+	// SwapPolicyKeep leaves the swap of the host enabled, the kubelet is started with failSwapOn set to false
+	SwapPolicyKeep = "keep"
+)
+
 const (
 	// ErrDetectOs error type when supported OS could not be detected
 	ErrDetectOs = Error("Error detecting OS")
 	// ErrOsK8sNotSupported error type when the OS is not supported by the k8s installer
 	ErrOsK8sNotSupported = Error("No k8s support for OS")
+	// ErrK8sVersionNotSupported error type when no bundle is available for the k8s version on the OS
+	ErrK8sVersionNotSupported = Error("No bundle for k8s version")
 	// ErrBundleDownload error type when the bundle download fails
 	ErrBundleDownload = Error("Error downloading bundle")
 	// ErrBundleExtract error type when the bundle extraction fails
@@ -45,38 +63,111 @@ const (
 	ErrBundleUninstall = Error("Error uninstalling bundle")
 	// ErrInstallerCreation error type when installer creation fails
 	ErrInstallerCreation = Error("Error creating installer")
+	// ErrCRINotSupported error type when the container runtime is not supported by the installer
+	ErrCRINotSupported = Error("No support for CRI")
+	// ErrSwapPolicyNotSupported error type when the swap policy is not supported by the installer
+	ErrSwapPolicyNotSupported = Error("No support for swap policy")
 )
 
+// criSockets maps a container runtime to the socket kubeadm uses to talk to it
+var criSockets = map[string]string{
+	CRIContainerd: "unix:///var/run/containerd/containerd.sock",
+	CRICRIO:       "unix:///var/run/crio/crio.sock",
+}
+
+// ProxyConfig holds the proxy settings used by the generated install and uninstall scripts
+type ProxyConfig = algo.ProxyConfig
+
+// RegistryMirror holds the mirror endpoints and TLS settings of an image registry
+type RegistryMirror = algo.RegistryMirror
+
+// Options holds the settings of the scripts NewInstaller generates. The zero value installs
+// containerd from the bundle pulled from the registry and disables the swap of the host.
+type Options struct {
+	// CRI is the container runtime to install, CRIContainerd or CRICRIO
+	CRI string
+	// SwapPolicy is SwapPolicyDisable or SwapPolicyKeep
+	SwapPolicy string
+	// LocalBundlePath is the path of a bundle pre-seeded on the host, used instead of pulling it
+	LocalBundlePath string
+	Proxy           ProxyConfig
+	RegistryMirrors []RegistryMirror
+	// RuntimeVersions override the containerd and runc versions pinned for the k8s version, the
+	// install script fails if the bundle does not ship the versions set
+	RuntimeVersions RuntimeVersions
+	// Templates override the embedded templates
+	Templates               Templates
+	SkipKernelModuleCleanup bool
+}
+
 // archOldNameMap keeps the mapping of architecture new name to old name mapping
 var archOldNameMap = map[string]string{
 	"amd64": "x86-64",
 }
 
-// NewInstaller will return a new installer
-func NewInstaller(ctx context.Context, osDist, arch, k8sVersion string, downloader *bundleDownloader, skipKernelModuleCleanup bool) (K8sInstaller, error) {
-	bundleArchName := arch
-	// replacing the arch name to old name to match with the bundle name
-	if _, exists := archOldNameMap[arch]; exists {
-		bundleArchName = archOldNameMap[arch]
+// CRISocket returns the socket of the passed container runtime, to be used as the
+// kubeadm cri-socket. An empty cri selects containerd.
+func CRISocket(cri string) (string, error) {
+	if cri == "" {
+		cri = CRIContainerd
+	}
+	socket, ok := criSockets[cri]
+	if !ok {
+		return "", ErrCRINotSupported
+	}
+	return socket, nil
+}
+
+// NewInstaller will return a new installer of the bundle of k8sVersion for the passed OS and arch
+func NewInstaller(ctx context.Context, osDist, arch, k8sVersion string, downloader *bundleDownloader, opts Options) (K8sInstaller, error) {
+	cri := opts.CRI
+	if cri == "" {
+		cri = CRIContainerd
+	}
+	criSocket, err := CRISocket(cri)
+	if err != nil {
+		return nil, err
+	}
+	var keepSwap bool
+	switch opts.SwapPolicy {
+	case "", SwapPolicyDisable:
+	case SwapPolicyKeep:
+		keepSwap = true
+	default:
+		return nil, ErrSwapPolicyNotSupported
 	}
-	// normalizing os image name and adding arch
-	osArch := strings.ReplaceAll(osDist, " ", "_") + "_" + bundleArchName
 
+	osArch := bundleOsArch(osDist, arch)
 	reg := GetSupportedRegistry()
 	if len(reg.ListK8s(osArch)) == 0 {
 		return nil, ErrOsK8sNotSupported
 	}
 	osbundle := reg.ResolveOsToOsBundle(osArch)
 	addrs := downloader.GetBundleAddr(osbundle, k8sVersion)
+	scriptOpts := algo.Options{
+		Arch:                    arch,
+		BundleAddrs:             addrs,
+		LocalBundlePath:         opts.LocalBundlePath,
+		CRI:                     cri,
+		CRISocket:               criSocket,
+		Proxy:                   opts.Proxy,
+		RegistryMirrors:         opts.RegistryMirrors,
+		RuntimeVersions:         resolveRuntimeVersions(k8sVersion, opts.RuntimeVersions),
+		Templates:               opts.Templates,
+		KeepSwap:                keepSwap,
+		SkipKernelModuleCleanup: opts.SkipKernelModuleCleanup,
+	}
 
 	// Use appropriate installer based on OS version
 	var installer K8sInstaller
-	var err error
 
-	if strings.Contains(osbundle, "Ubuntu_22.04") {
-		installer, err = algo.NewUbuntu22_04Installer(ctx, arch, addrs, skipKernelModuleCleanup)
-	} else {
-		installer, err = algo.NewUbuntu20_04Installer(ctx, arch, addrs, skipKernelModuleCleanup)
+	switch {
+	case strings.Contains(osbundle, "Flatcar"):
+		installer, err = algo.NewFlatcarInstaller(ctx, scriptOpts)
+	case strings.Contains(osbundle, "Ubuntu_22.04"):
+		installer, err = algo.NewUbuntu22_04Installer(ctx, scriptOpts)
+	default:
+		installer, err = algo.NewUbuntu20_04Installer(ctx, scriptOpts)
 	}
 
 	if err != nil {
@@ -84,3 +175,39 @@ func NewInstaller(ctx context.Context, osDist, arch, k8sVersion string, download
 	}
 	return installer, nil
 }
+
+// ValidateK8sVersion checks that the registry has a bundle for k8sVersion on the passed OS and arch
+func ValidateK8sVersion(osDist, arch, k8sVersion string) error {
+	reg := GetSupportedRegistry()
+	supported := reg.ListK8s(bundleOsArch(osDist, arch))
+	if len(supported) == 0 {
+		return ErrOsK8sNotSupported
+	}
+	for _, k8sFilter := range supported {
+		if matched, _ := regexp.MatchString("^"+k8sFilter+"$", k8sVersion); matched {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %s on %s, supported versions are %s", ErrK8sVersionNotSupported, k8sVersion, osDist, strings.Join(supported, ", "))
+}
+
+// bundleOsArch returns the name the registry uses for the OS and arch of a host
+func bundleOsArch(osDist, arch string) string {
+	bundleArchName := arch
+	// replacing the arch name to old name to match with the bundle name
+	if _, exists := archOldNameMap[arch]; exists {
+		bundleArchName = archOldNameMap[arch]
+	}
+	// normalizing os image name and adding arch
+	return strings.ReplaceAll(osDist, " ", "_") + "_" + bundleArchName
+}
+
+// DryRun renders the install and uninstall scripts NewInstaller would generate for the passed
+// OS, arch and bundle without running anything, so they can be reviewed before reaching a host
+func DryRun(ctx context.Context, osDist, arch, k8sVersion string, downloader *bundleDownloader, opts Options) (install, uninstall string, err error) {
+	k8sInstaller, err := NewInstaller(ctx, osDist, arch, k8sVersion, downloader, opts)
+	if err != nil {
+		return "", "", err
+	}
+	return k8sInstaller.Install(), k8sInstaller.Uninstall(), nil
+}
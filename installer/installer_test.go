@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package installer_test
@@ -28,23 +29,160 @@ var _ = Describe("Byohost Installer Tests", func() {
 
 	Context("When installer object is created for valid OS and arch", func() {
 		It("should create the object successfully", func() {
-			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, false)
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
 
+	Context("When installer object is created for Flatcar", func() {
+		It("should create the object successfully", func() {
+			os = "Flatcar Container Linux by Kinvolk 3510.2.1 (Oklo)"
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, "v1.31.0", downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(ContainSubstring("byoh-bundle-flatcar_x86-64_k8s:v1.31.0"))
+		})
+
+		It("should fail to create the object for cri-o", func() {
+			os = "Flatcar Container Linux by Kinvolk 3510.2.1 (Oklo)"
+			_, err := installer.NewInstaller(context.TODO(), os, arch, "v1.31.0", downloader, installer.Options{CRI: installer.CRICRIO})
+			Expect(err).To(MatchError(installer.ErrInstallerCreation))
+		})
+	})
+
+	Context("When installer object is created for arm64", func() {
+		It("should pull the arm64 bundle and imgpkg", func() {
+			arch = "arm64"
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, "v1.31.0", downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(ContainSubstring("byoh-bundle-ubuntu_20.04.1_arm64_k8s:v1.31.0"))
+			Expect(k8sInstaller.Install()).To(ContainSubstring("ARCH=arm64"))
+		})
+	})
+
 	Context("When installer object is created for invalid arch", func() {
 		It("should fail create the object", func() {
-			arch = "arm64"
-			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, false)
+			arch = "s390x"
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
+			Expect(err).To(MatchError(installer.ErrOsK8sNotSupported))
+		})
+	})
+
+	Context("When installer object is created for an unsupported CRI", func() {
+		It("should fail create the object", func() {
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{CRI: "docker"})
+			Expect(err).To(MatchError(installer.ErrCRINotSupported))
+		})
+	})
+
+	Context("When installer object is created for an unsupported swap policy", func() {
+		It("should fail create the object", func() {
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{SwapPolicy: "off"})
+			Expect(err).To(MatchError(installer.ErrSwapPolicyNotSupported))
+		})
+	})
+
+	Context("When the containerd and runc versions are pinned", func() {
+		It("should check the versions pinned for the k8s minor version", func() {
+			Expect(installer.PinnedRuntimeVersions("v1.29.3")).To(Equal(installer.RuntimeVersions{Containerd: "1.7.22", Runc: "1.1.14"}))
+
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, "v1.29.3", downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(ContainSubstring(`grep -qF " v1.7.22 "`))
+			Expect(k8sInstaller.Install()).To(ContainSubstring(`grep -qx "runc version 1.1.14"`))
+			Expect(k8sInstaller.Install()).To(ContainSubstring("Warning: containerd 1.7.22 is pinned"))
+			Expect(k8sInstaller.Install()).NotTo(ContainSubstring("is required"))
+		})
+
+		It("should let the passed versions override the pinned ones and require them", func() {
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, "v1.29.3", downloader, installer.Options{RuntimeVersions: installer.RuntimeVersions{Containerd: "1.7.30"}})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(ContainSubstring(`grep -qF " v1.7.30 "`))
+			Expect(k8sInstaller.Install()).To(ContainSubstring("containerd 1.7.30 is required"))
+			Expect(k8sInstaller.Install()).To(ContainSubstring(`grep -qx "runc version 1.1.14"`))
+			Expect(k8sInstaller.Install()).To(ContainSubstring("Warning: runc 1.1.14 is pinned"))
+		})
+
+		It("should not check the versions of a k8s version missing from the matrix", func() {
+			Expect(installer.PinnedRuntimeVersions(k8sversion)).To(BeZero())
+
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).NotTo(ContainSubstring("--version"))
+		})
+	})
+
+	Context("When the scripts are rendered for a dry run", func() {
+		It("should return the install and uninstall scripts of the installer", func() {
+			install, uninstall, err := installer.DryRun(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(install).To(Equal(k8sInstaller.Install()))
+			Expect(uninstall).To(Equal(k8sInstaller.Uninstall()))
+		})
+
+		It("should fail for an unsupported OS", func() {
+			_, _, err := installer.DryRun(context.TODO(), "rhel", arch, k8sversion, downloader, installer.Options{})
 			Expect(err).To(MatchError(installer.ErrOsK8sNotSupported))
 		})
 	})
 
+	Context("When the k8s version is validated against the bundles", func() {
+		It("should accept any patch version of a supported minor version", func() {
+			Expect(installer.ValidateK8sVersion(os, arch, "v1.31.4")).To(Succeed())
+		})
+
+		It("should fail for a k8s version without a bundle", func() {
+			err := installer.ValidateK8sVersion(os, arch, "v1.30.2")
+			Expect(err).To(MatchError(installer.ErrK8sVersionNotSupported))
+			Expect(err.Error()).To(ContainSubstring("v1.31.*"))
+		})
+
+		It("should fail for an unsupported OS", func() {
+			Expect(installer.ValidateK8sVersion("rhel", arch, "v1.31.4")).To(MatchError(installer.ErrOsK8sNotSupported))
+		})
+	})
+
+	Context("When the templates are overridden", func() {
+		It("should render the hooks into the scripts", func() {
+			templates, err := installer.NewTemplates(map[string]string{
+				installer.HookPreInstall:    "echo hardening {{.Arch}}",
+				installer.HookPostUninstall: "echo cleanup",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{Templates: templates})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(ContainSubstring("echo hardening amd64"))
+			Expect(k8sInstaller.Uninstall()).To(ContainSubstring("echo cleanup"))
+		})
+
+		It("should replace the install template", func() {
+			templates, err := installer.NewTemplates(map[string]string{installer.TemplateKeyInstall: "echo {{.BundleAddrs}}"})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			k8sInstaller, err := installer.NewInstaller(context.TODO(), os, arch, "v1.31.0", downloader, installer.Options{Templates: templates})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(k8sInstaller.Install()).To(Equal("echo repoAddr/byoh-bundle-ubuntu_20.04.1_x86-64_k8s:v1.31.0"))
+		})
+
+		It("should fail for an unknown template key", func() {
+			_, err := installer.NewTemplates(map[string]string{"pre-instal": "echo typo", installer.HookPostInstall: "echo ok"})
+			Expect(err).To(MatchError(installer.ErrTemplateNotSupported))
+			Expect(err.Error()).To(ContainSubstring("pre-instal"))
+		})
+
+		It("should fail to create the object for an invalid template", func() {
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{Templates: installer.Templates{Uninstall: "{{end}}"}})
+			Expect(err).To(MatchError(installer.ErrInstallerCreation))
+		})
+	})
+
 	Context("When installer object is created for invalid OS", func() {
 		It("should fail create the object", func() {
 			os = "rhel"
-			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, false)
+			_, err := installer.NewInstaller(context.TODO(), os, arch, k8sversion, downloader, installer.Options{})
 			Expect(err).To(MatchError(installer.ErrOsK8sNotSupported))
 		})
 	})
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package algo
@@ -7,8 +8,6 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
-	"html/template"
-	"strings"
 )
 
 const (
@@ -16,12 +15,69 @@ const (
 	ImgpkgVersion = "v0.36.4"
 )
 
+// RegistryMirror holds the mirror endpoints and TLS settings of an image registry,
+// rendered into the configuration of the container runtime
+type RegistryMirror struct {
+	Registry  string
+	Endpoints []string
+	Insecure  bool
+}
+
+// criServices maps a container runtime to the name of its systemd service
+var criServices = map[string]string{
+	"containerd": "containerd",
+	"cri-o":      "crio",
+}
+
 //go:embed ubuntu-templates/install.sh.tmpl
 var commonUbuntuInstallTemplate string
 
 //go:embed ubuntu-templates/uninstall.sh.tmpl
 var commonUbuntuUninstallTemplate string
 
+// ProxyConfig holds the proxy settings rendered into the install and uninstall
+// scripts and into the environment of the containerd service
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// RuntimeVersions holds the containerd and runc versions the install script checks the bundle
+// against, an empty version is not checked. The install script fails if the bundle ships another
+// version of a required one, it only warns otherwise.
+type RuntimeVersions struct {
+	Containerd         string
+	Runc               string
+	ContainerdRequired bool
+	RuncRequired       bool
+}
+
+// Options holds the settings the install and uninstall scripts are rendered with
+type Options struct {
+	// Arch is the architecture of the host, as used in the imgpkg release names
+	Arch string
+	// BundleAddrs is the address of the bundle the scripts pull
+	BundleAddrs string
+	// LocalBundlePath is the path of a bundle pre-seeded on the host, used instead of pulling BundleAddrs
+	LocalBundlePath string
+	// CRI is the container runtime to install and CRISocket the socket kubeadm talks to it on
+	CRI       string
+	CRISocket string
+	// Proxy is exported to the scripts and to the environment of the container runtime
+	Proxy ProxyConfig
+	// RegistryMirrors are configured in the container runtime
+	RegistryMirrors []RegistryMirror
+	// RuntimeVersions are checked against the container runtime of the bundle
+	RuntimeVersions RuntimeVersions
+	// Templates override the embedded templates
+	Templates Templates
+	// KeepSwap leaves the swap of the host enabled
+	KeepSwap bool
+	// SkipKernelModuleCleanup leaves the kernel modules loaded by the install script on uninstall
+	SkipKernelModuleCleanup bool
+}
+
 // BaseUbuntuInstaller provides common functionality for Ubuntu installers
 type BaseUbuntuInstaller struct {
 	install   string
@@ -39,7 +95,7 @@ func (s *BaseUbuntuInstaller) Uninstall() string {
 }
 
 // NewBaseUbuntuInstaller creates a new base Ubuntu installer
-func NewBaseUbuntuInstaller(ctx context.Context, arch, bundleAddrs, containerdConfig string, skipKernelModuleCleanup bool) (*BaseUbuntuInstaller, error) {
+func NewBaseUbuntuInstaller(ctx context.Context, opts Options) (*BaseUbuntuInstaller, error) {
 	// Validate embedded templates
 	if commonUbuntuInstallTemplate == "" {
 		return nil, fmt.Errorf("install template is empty - template file may be missing")
@@ -49,38 +105,34 @@ func NewBaseUbuntuInstaller(ctx context.Context, arch, bundleAddrs, containerdCo
 	}
 
 	data := map[string]any{
-		"BundleAddrs":             bundleAddrs,
-		"Arch":                    arch,
+		"BundleAddrs":             opts.BundleAddrs,
+		"LocalBundlePath":         opts.LocalBundlePath,
+		"Arch":                    opts.Arch,
 		"ImgpkgVersion":           ImgpkgVersion,
-		"ContainerdConfig":        containerdConfig,
 		"BundleDownloadPath":      "/var/lib/byoh/bundles",
-		"SkipKernelModuleCleanup": skipKernelModuleCleanup,
+		"SkipKernelModuleCleanup": opts.SkipKernelModuleCleanup,
+		"KeepSwap":                opts.KeepSwap,
+		"CRI":                     opts.CRI,
+		"CRISocket":               opts.CRISocket,
+		"CRIService":              criServices[opts.CRI],
+		"HTTPProxy":               opts.Proxy.HTTPProxy,
+		"HTTPSProxy":              opts.Proxy.HTTPSProxy,
+		"NoProxy":                 opts.Proxy.NoProxy,
+		"RegistryMirrors":         opts.RegistryMirrors,
+		"ContainerdVersion":       opts.RuntimeVersions.Containerd,
+		"RuncVersion":             opts.RuntimeVersions.Runc,
+		"ContainerdRequired":      opts.RuntimeVersions.ContainerdRequired,
+		"RuncRequired":            opts.RuntimeVersions.RuncRequired,
 	}
 
-	// Parse and validate templates
-	installTemplate, err := template.New("install").Parse(commonUbuntuInstallTemplate)
+	install, err := renderScript("install", commonUbuntuInstallTemplate, opts.Templates, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse install template: %v", err)
+		return nil, err
 	}
-
-	uninstallTemplate, err := template.New("uninstall").Parse(commonUbuntuUninstallTemplate)
+	uninstall, err := renderScript("uninstall", commonUbuntuUninstallTemplate, opts.Templates, data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse uninstall template: %v", err)
-	}
-
-	var install, uninstall string
-	var buf strings.Builder
-
-	if err := installTemplate.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("failed to execute install template: %v", err)
-	}
-	install = buf.String()
-
-	buf.Reset()
-	if err := uninstallTemplate.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("failed to execute uninstall template: %v", err)
+		return nil, err
 	}
-	uninstall = buf.String()
 
 	return &BaseUbuntuInstaller{
 		install:   install,
@@ -1,9 +1,11 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package algo_test
 
 import (
 	"context"
+	"os/exec"
 	"strings"
 	"testing"
 
@@ -33,7 +35,7 @@ func TestBaseUbuntuInstallerUninstallKernelModuleCleanup(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), "amd64", "test-bundle", "", tc.skipKernelModuleCleanup)
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", SkipKernelModuleCleanup: tc.skipKernelModuleCleanup})
 			require.NoError(t, err)
 
 			uninstallScript := installer.Uninstall()
@@ -43,3 +45,336 @@ func TestBaseUbuntuInstallerUninstallKernelModuleCleanup(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseUbuntuInstallerProxyConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		proxy     algo.ProxyConfig
+		wantProxy bool
+	}{
+		{
+			name:      "no proxy settings rendered when proxy is not configured",
+			proxy:     algo.ProxyConfig{},
+			wantProxy: false,
+		},
+		{
+			name: "proxy settings rendered when proxy is configured",
+			proxy: algo.ProxyConfig{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://proxy.example.com:3128",
+				NoProxy:    "localhost,127.0.0.1,10.0.0.0/8",
+			},
+			wantProxy: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Proxy: tc.proxy})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			uninstallScript := installer.Uninstall()
+
+			for _, script := range []string{installScript, uninstallScript} {
+				assert.Equal(t, tc.wantProxy, strings.Contains(script, `export HTTP_PROXY="http://proxy.example.com:3128"`))
+				assert.Equal(t, tc.wantProxy, strings.Contains(script, `export NO_PROXY="localhost,127.0.0.1,10.0.0.0/8"`))
+			}
+			assert.Equal(t, tc.wantProxy, strings.Contains(installScript, "containerd.service.d/http-proxy.conf"))
+			assert.Contains(t, uninstallScript, "rm -f /etc/systemd/system/containerd.service.d/http-proxy.conf")
+		})
+	}
+}
+
+func TestBaseUbuntuInstallerCRI(t *testing.T) {
+	testCases := []struct {
+		name              string
+		cri               string
+		criSocket         string
+		wantService       string
+		wantRuntimeTar    string
+		notWantRuntimeTar string
+	}{
+		{
+			name:              "containerd installed when containerd is selected",
+			cri:               "containerd",
+			criSocket:         "unix:///var/run/containerd/containerd.sock",
+			wantService:       "containerd",
+			wantRuntimeTar:    "containerd.tar",
+			notWantRuntimeTar: "cri-o.tar",
+		},
+		{
+			name:              "cri-o installed when cri-o is selected",
+			cri:               "cri-o",
+			criSocket:         "unix:///var/run/crio/crio.sock",
+			wantService:       "crio",
+			wantRuntimeTar:    "cri-o.tar",
+			notWantRuntimeTar: "containerd.tar",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: tc.cri, CRISocket: tc.criSocket})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			uninstallScript := installer.Uninstall()
+
+			for _, script := range []string{installScript, uninstallScript} {
+				assert.Contains(t, script, tc.wantRuntimeTar)
+				assert.NotContains(t, script, tc.notWantRuntimeTar)
+			}
+			assert.Contains(t, installScript, "systemctl enable "+tc.wantService)
+			assert.Contains(t, installScript, "runtime-endpoint: %s")
+			assert.Contains(t, installScript, tc.criSocket)
+			assert.Contains(t, uninstallScript, "systemctl stop "+tc.wantService)
+		})
+	}
+}
+
+func TestBaseUbuntuInstallerRegistryMirrors(t *testing.T) {
+	registryMirrors := []algo.RegistryMirror{
+		{
+			Registry:  "docker.io",
+			Endpoints: []string{"https://mirror.example.com:5000"},
+		},
+		{
+			Registry: "registry.local:5000",
+			Insecure: true,
+		},
+	}
+
+	testCases := []struct {
+		name            string
+		cri             string
+		registryMirrors []algo.RegistryMirror
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:            "no mirror configuration rendered when no mirrors are configured",
+			cri:             "containerd",
+			wantNotContains: []string{"configuring registry mirrors"},
+		},
+		{
+			name:            "containerd hosts.toml rendered for each registry",
+			cri:             "containerd",
+			registryMirrors: registryMirrors,
+			wantContains: []string{
+				`config_path = "/etc/containerd/certs.d"`,
+				`registry='docker.io'`,
+				`registry-1.docker.io > "/etc/containerd/certs.d/$registry/hosts.toml"`,
+				`'https://mirror.example.com:5000' >> "/etc/containerd/certs.d/$registry/hosts.toml"`,
+				`registry='registry.local:5000'`,
+				`skip_verify = true\n' "$registry" >> "/etc/containerd/certs.d/$registry/hosts.toml"`,
+			},
+		},
+		{
+			name: "mirror values quoted as single shell words",
+			cri:  "containerd",
+			registryMirrors: []algo.RegistryMirror{{
+				Registry:  "registry.local'; reboot; '",
+				Endpoints: []string{"https://mirror.example.com/$(reboot)"},
+			}},
+			wantContains: []string{
+				`registry='registry.local'\''; reboot; '\'''`,
+				`'https://mirror.example.com/$(reboot)' >> "/etc/containerd/certs.d/$registry/hosts.toml"`,
+			},
+		},
+		{
+			name:            "cri-o registries.conf rendered for each registry",
+			cri:             "cri-o",
+			registryMirrors: registryMirrors,
+			wantContains: []string{
+				`'docker.io' "false" >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf`,
+				`endpoint='https://mirror.example.com:5000'`,
+				`'registry.local:5000' "true" >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf`,
+			},
+			wantNotContains: []string{"/etc/containerd/certs.d"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: tc.cri, CRISocket: "unix:///var/run/test.sock", RegistryMirrors: tc.registryMirrors})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			for _, want := range tc.wantContains {
+				assert.Contains(t, installScript, want)
+			}
+			for _, notWant := range tc.wantNotContains {
+				assert.NotContains(t, installScript, notWant)
+			}
+		})
+	}
+}
+
+func TestBaseUbuntuInstallerLocalBundle(t *testing.T) {
+	testCases := []struct {
+		name            string
+		localBundlePath string
+		wantPull        bool
+	}{
+		{
+			name:     "bundle pulled with imgpkg when no local bundle path is set",
+			wantPull: true,
+		},
+		{
+			name:            "bundle copied from the local bundle path when set",
+			localBundlePath: "/opt/byoh/bundle.tar",
+			wantPull:        false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", LocalBundlePath: tc.localBundlePath, CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			assert.Equal(t, tc.wantPull, strings.Contains(installScript, `imgpkg pull -i "$BUNDLE_ADDR" -o "$BUNDLE_PATH"`))
+			assert.Equal(t, tc.wantPull, strings.Contains(installScript, "apt-get install"))
+			assert.Equal(t, !tc.wantPull, strings.Contains(installScript, `LOCAL_BUNDLE_PATH="/opt/byoh/bundle.tar"`))
+			assert.Contains(t, installer.Uninstall(), `rm -rf "$BUNDLE_PATH"`)
+		})
+	}
+}
+
+func TestBaseUbuntuInstallerCgroupDriver(t *testing.T) {
+	containerdInstaller, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+	require.NoError(t, err)
+	crioInstaller, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "cri-o", CRISocket: "unix:///var/run/crio/crio.sock"})
+	require.NoError(t, err)
+
+	for _, script := range []string{containerdInstaller.Install(), crioInstaller.Install()} {
+		assert.Contains(t, script, `if [ "$(stat -fc %T /sys/fs/cgroup)" = "cgroup2fs" ]; then`)
+		assert.Contains(t, script, `echo "$CGROUP_DRIVER" > /var/lib/byoh/cgroup-driver`)
+	}
+	assert.Contains(t, containerdInstaller.Install(), "sed -i 's/SystemdCgroup = false/SystemdCgroup = true/' /etc/containerd/config.toml")
+	assert.NotContains(t, containerdInstaller.Install(), "99-byoh-cgroup-manager.conf")
+	assert.Contains(t, crioInstaller.Install(), `cgroup_manager = "%s"`)
+	assert.Contains(t, crioInstaller.Uninstall(), "rm -f /etc/crio/crio.conf.d/99-byoh-cgroup-manager.conf")
+}
+
+func TestBaseUbuntuInstallerSwapPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		keepSwap bool
+	}{
+		{
+			name:     "swap disabled by default",
+			keepSwap: false,
+		},
+		{
+			name:     "swap kept enabled",
+			keepSwap: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", KeepSwap: tc.keepSwap})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			uninstallScript := installer.Uninstall()
+
+			if tc.keepSwap {
+				assert.Contains(t, installScript, `echo "keep" > /var/lib/byoh/swap-policy`)
+				assert.NotContains(t, installScript, "swapoff -a")
+				assert.NotContains(t, uninstallScript, "swapon -a")
+			} else {
+				assert.NotContains(t, installScript, "/var/lib/byoh/swap-policy")
+				assert.Contains(t, installScript, "swapoff -a")
+				assert.Contains(t, uninstallScript, "swapon -a")
+			}
+		})
+	}
+}
+
+func TestBaseUbuntuInstallerRuntimeVersions(t *testing.T) {
+	versions := algo.RuntimeVersions{Containerd: "1.7.22", Runc: "1.1.14"}
+
+	installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", RuntimeVersions: versions})
+	require.NoError(t, err)
+	installScript := installer.Install()
+	assert.Contains(t, installScript, `install -m 755 "$BUNDLE_PATH/runc" /usr/local/sbin/runc`)
+	assert.Contains(t, installScript, `containerd --version | grep -qF " v1.7.22 "`)
+	assert.Contains(t, installScript, `runc --version | grep -qx "runc version 1.1.14"`)
+	assert.Contains(t, installScript, "Warning: containerd 1.7.22 is pinned")
+	assert.NotContains(t, installScript, "exit 1")
+
+	versions.RuncRequired = true
+	installer, err = algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", RuntimeVersions: versions})
+	require.NoError(t, err)
+	installScript = installer.Install()
+	assert.Contains(t, installScript, "Warning: containerd 1.7.22 is pinned")
+	assert.Contains(t, installScript, `echo "runc 1.1.14 is required, the bundle ships $(runc --version | head -n 1)"`+"\n    exit 1")
+
+	installer, err = algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+	require.NoError(t, err)
+	assert.NotContains(t, installer.Install(), "--version")
+
+	installer, err = algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "cri-o", CRISocket: "unix:///var/run/crio/crio.sock", RuntimeVersions: versions})
+	require.NoError(t, err)
+	assert.NotContains(t, installer.Install(), "--version")
+}
+
+func TestBaseUbuntuInstallerPhaseMarkers(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cri       string
+		criSocket string
+	}{
+		{name: "containerd", cri: "containerd", criSocket: "unix:///var/run/containerd/containerd.sock"},
+		{name: "cri-o", cri: "cri-o", criSocket: "unix:///var/run/crio/crio.sock"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: tc.cri, CRISocket: tc.criSocket})
+			require.NoError(t, err)
+
+			installScript := installer.Install()
+			for _, phase := range []string{"bundle", "os-config", "packages", "container-runtime"} {
+				assert.Contains(t, installScript, "phase_done "+phase)
+				assert.Contains(t, installScript, "mark_phase_done "+phase)
+			}
+			// a partial download of a failed run is not reused
+			assert.Contains(t, installScript, `rm -rf "$BUNDLE_PATH" && mkdir -p "$BUNDLE_PATH"`)
+			assert.Contains(t, installer.Uninstall(), `rm -rf "$BUNDLE_PATH" /var/lib/byoh/state`)
+		})
+	}
+}
+
+func TestPhaseMarkerHelpers(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+	require.NoError(t, err)
+
+	// run the helpers of the script against a temporary state directory
+	installScript := installer.Install()
+	start := strings.Index(installScript, "STATE_PATH=")
+	end := strings.Index(installScript, "mark_phase_done() {")
+	require.True(t, start >= 0 && end > start)
+	end += strings.Index(installScript[end:], "\n")
+	helpers := strings.Replace(installScript[start:end], "/var/lib/byoh/state", t.TempDir()+"/state", 1)
+
+	script := "set -eu\nBUNDLE_ADDR=bundle:v1.31.0\n" + helpers + `
+phase_done packages && exit 1
+mark_phase_done packages
+phase_done packages
+phase_done os-config && exit 2
+BUNDLE_ADDR=bundle:v1.32.0
+phase_done packages && exit 3
+exit 0
+`
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package algo
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+)
+
+//go:embed flatcar-templates/install.sh.tmpl
+var flatcarInstallTemplate string
+
+//go:embed flatcar-templates/uninstall.sh.tmpl
+var flatcarUninstallTemplate string
+
+// FlatcarInstaller represent the installer implementation for Flatcar Container Linux.
+// The root filesystem is immutable, so instead of installing packages it merges the
+// systemd-sysext image of the bundle into /usr, or uses the k8s components pre-baked
+// in the OS image, and configures the containerd shipped with the OS, whose version
+// is not checked against the runtime versions.
+type FlatcarInstaller struct {
+	install   string
+	uninstall string
+}
+
+// NewFlatcarInstaller will return new FlatcarInstaller instance
+func NewFlatcarInstaller(ctx context.Context, opts Options) (*FlatcarInstaller, error) {
+	if opts.CRI != "containerd" {
+		return nil, fmt.Errorf("container runtime %s is not supported on Flatcar, only the containerd of the OS image is", opts.CRI)
+	}
+
+	data := map[string]any{
+		"BundleAddrs":             opts.BundleAddrs,
+		"LocalBundlePath":         opts.LocalBundlePath,
+		"Arch":                    opts.Arch,
+		"ImgpkgVersion":           ImgpkgVersion,
+		"BundleDownloadPath":      "/var/lib/byoh/bundles",
+		"SkipKernelModuleCleanup": opts.SkipKernelModuleCleanup,
+		"KeepSwap":                opts.KeepSwap,
+		"CRISocket":               opts.CRISocket,
+		"HTTPProxy":               opts.Proxy.HTTPProxy,
+		"HTTPSProxy":              opts.Proxy.HTTPSProxy,
+		"NoProxy":                 opts.Proxy.NoProxy,
+		"RegistryMirrors":         opts.RegistryMirrors,
+	}
+
+	install, err := renderScript("install", flatcarInstallTemplate, opts.Templates, data)
+	if err != nil {
+		return nil, err
+	}
+	uninstall, err := renderScript("uninstall", flatcarUninstallTemplate, opts.Templates, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FlatcarInstaller{
+		install:   install,
+		uninstall: uninstall,
+	}, nil
+}
+
+// Install will return k8s install script
+func (s *FlatcarInstaller) Install() string {
+	return s.install
+}
+
+// Uninstall will return k8s uninstall script
+func (s *FlatcarInstaller) Uninstall() string {
+	return s.uninstall
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package algo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer/internal/algo"
+)
+
+func TestFlatcarInstallerScripts(t *testing.T) {
+	installer, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+	require.NoError(t, err)
+
+	installScript := installer.Install()
+	uninstallScript := installer.Uninstall()
+
+	for _, script := range []string{installScript, uninstallScript} {
+		assert.NotContains(t, script, "apt-")
+		assert.NotContains(t, script, "dpkg")
+		assert.NotContains(t, script, "containerd.tar")
+		assert.NotContains(t, script, "/usr/local/bin")
+	}
+	assert.Contains(t, installScript, `cp "$BUNDLE_PATH/kubernetes.raw" /etc/extensions/kubernetes.raw`)
+	assert.Contains(t, installScript, "systemd-sysext refresh")
+	assert.Contains(t, installScript, `mv /tmp/imgpkg "$BIN_PATH/imgpkg"`)
+	assert.Contains(t, installScript, `echo "$CGROUP_DRIVER" > /var/lib/byoh/cgroup-driver`)
+	assert.Contains(t, installScript, `Environment="CONTAINERD_CONFIG=/etc/containerd/config.toml"`)
+	assert.Contains(t, uninstallScript, "rm -f /etc/extensions/kubernetes.raw /var/lib/byoh/sysext-installed")
+	assert.Contains(t, uninstallScript, "rm -f /etc/containerd/config.toml")
+	assert.NotContains(t, uninstallScript, "systemctl disable containerd")
+}
+
+func TestFlatcarInstallerRegistryMirrors(t *testing.T) {
+	registryMirrors := []algo.RegistryMirror{
+		{
+			Registry:  "docker.io",
+			Endpoints: []string{"https://mirror.example.com:5000"},
+		},
+	}
+
+	installer, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", RegistryMirrors: registryMirrors})
+	require.NoError(t, err)
+
+	installScript := installer.Install()
+	assert.Contains(t, installScript, `config_path = "/etc/containerd/certs.d"`)
+	assert.Contains(t, installScript, `'https://mirror.example.com:5000' >> "/etc/containerd/certs.d/$registry/hosts.toml"`)
+}
+
+func TestFlatcarInstallerCRI(t *testing.T) {
+	_, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "cri-o", CRISocket: "unix:///var/run/crio/crio.sock"})
+	assert.Error(t, err)
+}
+
+func TestFlatcarInstallerPhaseMarkers(t *testing.T) {
+	installer, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock"})
+	require.NoError(t, err)
+
+	installScript := installer.Install()
+	for _, phase := range []string{"bundle", "os-config", "k8s-components"} {
+		assert.Contains(t, installScript, "phase_done "+phase)
+		assert.Contains(t, installScript, "mark_phase_done "+phase)
+	}
+	assert.Contains(t, installer.Uninstall(), `rm -rf "$BUNDLE_PATH" /var/lib/byoh/state`)
+}
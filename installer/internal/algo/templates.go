@@ -0,0 +1,83 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package algo
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// HookPreInstall runs once the bundle is on the host, before the host is changed
+	HookPreInstall = "pre-install"
+	// HookPostInstall runs once the container runtime is started
+	HookPostInstall = "post-install"
+	// HookPreUninstall runs before the container runtime is stopped
+	HookPreUninstall = "pre-uninstall"
+	// HookPostUninstall runs once the bundle is removed from the host
+	HookPostUninstall = "post-uninstall"
+)
+
+// snippetsTemplate defines the blocks shared by the install and uninstall templates of all the OSes
+//
+//go:embed common-templates/snippets.sh.tmpl
+var snippetsTemplate string
+
+// Hooks lists the named snippets the install and uninstall templates render
+var Hooks = []string{HookPreInstall, HookPostInstall, HookPreUninstall, HookPostUninstall}
+
+// Templates holds the overrides of the embedded install and uninstall templates. Install and
+// Uninstall replace a template fully, Hooks maps a hook name to a snippet rendered at that point
+// of the scripts. All of them are text/template templates given the same data as the embedded ones,
+// can quote a value as a single shell word with shquote and render the shared snippets, e.g.
+// {{template "proxy-env" .}}.
+type Templates struct {
+	Install   string
+	Uninstall string
+	Hooks     map[string]string
+}
+
+// renderScript renders the named script template, text unless overridden, with the hooks of templates
+func renderScript(name, text string, templates Templates, data map[string]any) (string, error) {
+	switch {
+	case name == "install" && templates.Install != "":
+		text = templates.Install
+	case name == "uninstall" && templates.Uninstall != "":
+		text = templates.Uninstall
+	}
+
+	// a key missing from the data is a bug of the template, it must not render as an empty string
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"shquote": shellQuote}).Parse(snippetsTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the shared snippets: %v", err)
+	}
+	// parsed after the snippets, so that an override may redefine them
+	if _, err := tmpl.Parse(text); err != nil {
+		return "", fmt.Errorf("failed to parse %s template: %v", name, err)
+	}
+	for _, hook := range Hooks {
+		snippet, ok := templates.Hooks[hook]
+		// a full override may define the hooks itself, the empty default must not replace them
+		if !ok && tmpl.Lookup(hook) != nil {
+			continue
+		}
+		if _, err := tmpl.New(hook).Parse(snippet); err != nil {
+			return "", fmt.Errorf("failed to parse %s hook: %v", hook, err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute %s template: %v", name, err)
+	}
+	return buf.String(), nil
+}
+
+// shellQuote quotes s as a single shell word, so that a value rendered into a script is never
+// split, globbed or expanded
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
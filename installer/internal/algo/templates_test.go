@@ -0,0 +1,125 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package algo_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer/internal/algo"
+)
+
+func TestBaseUbuntuInstallerHooks(t *testing.T) {
+	templates := algo.Templates{Hooks: map[string]string{
+		algo.HookPreInstall:    "echo pre-install on {{.Arch}}",
+		algo.HookPostInstall:   "echo post-install",
+		algo.HookPreUninstall:  "echo pre-uninstall",
+		algo.HookPostUninstall: "echo post-uninstall",
+	}}
+
+	installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Templates: templates})
+	require.NoError(t, err)
+
+	installScript := installer.Install()
+	// the pre-install hook runs once the bundle is pulled, before the host is changed
+	preInstall := strings.Index(installScript, "echo pre-install on amd64")
+	require.NotEqual(t, -1, preInstall)
+	assert.Less(t, strings.Index(installScript, "imgpkg pull"), preInstall)
+	assert.Less(t, preInstall, strings.Index(installScript, "swapoff -a"))
+	// the post-install hook runs once the container runtime is started
+	postInstall := strings.Index(installScript, "echo post-install")
+	require.NotEqual(t, -1, postInstall)
+	assert.Less(t, strings.Index(installScript, "systemctl restart containerd"), postInstall)
+
+	uninstallScript := installer.Uninstall()
+	preUninstall := strings.Index(uninstallScript, "echo pre-uninstall")
+	require.NotEqual(t, -1, preUninstall)
+	assert.Less(t, preUninstall, strings.Index(uninstallScript, "systemctl stop containerd"))
+	postUninstall := strings.Index(uninstallScript, "echo post-uninstall")
+	require.NotEqual(t, -1, postUninstall)
+	assert.Less(t, strings.Index(uninstallScript, `rm -rf "$BUNDLE_PATH"`), postUninstall)
+}
+
+func TestBaseUbuntuInstallerTemplateOverride(t *testing.T) {
+	templates := algo.Templates{
+		Install: "set -euox pipefail\n{{template \"pre-install\" .}}\necho custom install of {{.BundleAddrs}}\n",
+		Hooks:   map[string]string{algo.HookPreInstall: "echo hardening"},
+	}
+
+	installer, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Templates: templates})
+	require.NoError(t, err)
+	assert.Equal(t, "set -euox pipefail\necho hardening\necho custom install of test-bundle\n", installer.Install())
+	// the uninstall template is not overridden
+	assert.Contains(t, installer.Uninstall(), "systemctl stop containerd")
+}
+
+func TestFlatcarInstallerTemplateOverrideDefinesHooks(t *testing.T) {
+	// a full override may define a hook itself, it is kept unless the hook is overridden too
+	templates := algo.Templates{
+		Uninstall: "{{define \"post-uninstall\"}}echo default cleanup{{end}}set -euox pipefail\n{{template \"post-uninstall\" .}}\n",
+	}
+
+	installer, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Templates: templates})
+	require.NoError(t, err)
+	assert.Equal(t, "set -euox pipefail\necho default cleanup\n", installer.Uninstall())
+
+	templates.Hooks = map[string]string{algo.HookPostUninstall: "echo site cleanup"}
+	installer, err = algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Templates: templates})
+	require.NoError(t, err)
+	assert.Equal(t, "set -euox pipefail\necho site cleanup\n", installer.Uninstall())
+}
+
+func TestFlatcarInstallerTemplateOverrideUsesSnippets(t *testing.T) {
+	// the snippets shared by the embedded templates are available to the overrides too
+	templates := algo.Templates{
+		Install: "set -euox pipefail\n{{template \"proxy-env\" .}}echo custom install\n",
+	}
+	proxy := algo.ProxyConfig{HTTPProxy: "http://proxy.example.com:3128"}
+
+	installer, err := algo.NewFlatcarInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Proxy: proxy, Templates: templates})
+	require.NoError(t, err)
+	assert.Contains(t, installer.Install(), `export HTTP_PROXY="http://proxy.example.com:3128"`)
+	assert.True(t, strings.HasSuffix(installer.Install(), "echo custom install\n"))
+}
+
+func TestInstallerTemplateOverrideErrors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		templates algo.Templates
+		errMsg    string
+	}{
+		{
+			name:      "invalid install template",
+			templates: algo.Templates{Install: "{{if .Arch}}"},
+			errMsg:    "failed to parse install template",
+		},
+		{
+			name:      "invalid hook",
+			templates: algo.Templates{Hooks: map[string]string{algo.HookPostInstall: "{{.Arch"}},
+			errMsg:    "failed to parse post-install hook",
+		},
+		{
+			name:      "missing key",
+			templates: algo.Templates{Hooks: map[string]string{algo.HookPreInstall: "echo {{.ArchName}}"}},
+			errMsg:    `map has no entry for key "ArchName"`,
+		},
+		{
+			name:      "undefined template",
+			templates: algo.Templates{Uninstall: "{{template \"site-cleanup\" .}}"},
+			errMsg:    "failed to execute uninstall template",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := algo.NewBaseUbuntuInstaller(context.Background(), algo.Options{Arch: "amd64", BundleAddrs: "test-bundle", CRI: "containerd", CRISocket: "unix:///var/run/containerd/containerd.sock", Templates: tc.templates})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+set -euox pipefail
+
+BUNDLE_DOWNLOAD_PATH=/var/lib/byoh/bundles
+BUNDLE_ADDR='projects.registry.vmware.com/cluster_api_provider_bringyourownhost/byoh-bundle-ubuntu_22.04_x86-64_k8s:v1.31.0'
+IMGPKG_VERSION=v0.36.4
+ARCH=amd64
+BUNDLE_PATH=$BUNDLE_DOWNLOAD_PATH/$BUNDLE_ADDR
+
+## phases completed for this bundle by a previous run of the script are skipped, so that a run
+## retried after a transient failure does not download and extract everything again
+STATE_PATH=/var/lib/byoh/state
+mkdir -p "$STATE_PATH"
+phase_done() { grep -qxF "$BUNDLE_ADDR" "$STATE_PATH/$1" 2>/dev/null; }
+mark_phase_done() { echo "$BUNDLE_ADDR" > "$STATE_PATH/$1"; }
+
+if phase_done bundle && [ -d "$BUNDLE_PATH" ]; then
+    echo "bundle already on the host"
+else
+
+    if ! command -v imgpkg >>/dev/null; then
+        echo "installing imgpkg"
+
+        if command -v wget >>/dev/null; then
+            dl_bin=(wget -nv -O-)
+        elif command -v curl >>/dev/null; then
+            dl_bin=(curl -s -L)
+        else
+            echo "installing curl"
+            apt-get install -y curl
+            dl_bin=(curl -s -L)
+        fi
+
+        "${dl_bin[@]}" "github.com/vmware-tanzu/carvel-imgpkg/releases/download/$IMGPKG_VERSION/imgpkg-linux-$ARCH" > /tmp/imgpkg
+        mv /tmp/imgpkg /usr/local/bin/imgpkg
+        chmod +x /usr/local/bin/imgpkg
+    fi
+
+    echo "downloading bundle"
+    rm -rf "$BUNDLE_PATH" && mkdir -p "$BUNDLE_PATH"
+    imgpkg pull -i "$BUNDLE_ADDR" -o "$BUNDLE_PATH"
+
+    mark_phase_done bundle
+fi
+
+
+## disable swap
+swapoff -a && sed -ri '/\sswap\s/s/^#?/#/' /etc/fstab
+
+## disable firewall, save current state so uninstall can restore it
+if command -v ufw >>/dev/null; then
+    mkdir -p /var/lib/byoh
+    if [ ! -f /var/lib/byoh/ufw-state ]; then
+        ufw status | grep -q "Status: active" && echo "active" > /var/lib/byoh/ufw-state || echo "inactive" > /var/lib/byoh/ufw-state
+    fi
+    ufw disable
+fi
+
+## load kernal modules
+modprobe overlay && modprobe br_netfilter
+
+## adding os configuration
+if ! phase_done os-config; then
+    tar -C / -xvf "$BUNDLE_PATH/conf.tar"
+    mark_phase_done os-config
+fi
+sysctl --system 
+
+## installing deb packages
+if ! phase_done packages; then
+    for pkg in cri-tools kubernetes-cni kubectl kubelet kubeadm; do
+        dpkg --install "$BUNDLE_PATH/$pkg.deb" && apt-mark hold "$pkg"
+    done
+    mark_phase_done packages
+fi
+## a host released without reset has its kubelet disabled
+systemctl enable kubelet
+
+## detecting the cgroup driver of the container runtime: systemd when it is the init system, as on
+## all cgroup v2 hosts, to match the kubelet configured by kubeadm, cgroupfs otherwise
+CGROUP_VERSION=1
+if [ "$(stat -fc %T /sys/fs/cgroup)" = "cgroup2fs" ]; then
+    CGROUP_VERSION=2
+fi
+if [ -d /run/systemd/system ]; then
+    CGROUP_DRIVER=systemd
+else
+    CGROUP_DRIVER=cgroupfs
+fi
+echo "using the $CGROUP_DRIVER cgroup driver on a cgroup v$CGROUP_VERSION host"
+mkdir -p /var/lib/byoh && echo "$CGROUP_DRIVER" > /var/lib/byoh/cgroup-driver
+
+## intalling containerd
+if ! phase_done container-runtime; then
+    tar -C / -xvf "$BUNDLE_PATH/containerd.tar"
+    ## the runc of the bundle, if any, replaces the one of the containerd release
+    if [ -f "$BUNDLE_PATH/runc" ]; then
+        install -m 755 "$BUNDLE_PATH/runc" /usr/local/sbin/runc
+    fi
+    mark_phase_done container-runtime
+fi
+## checking the containerd version pinned for the k8s version
+if ! containerd --version | grep -qF " v1.7.22 "; then
+    echo "Warning: containerd 1.7.22 is pinned for this Kubernetes version, the bundle ships $(containerd --version)"
+fi
+## checking the runc version pinned for the k8s version
+if ! runc --version | grep -qx "runc version 1.1.14"; then
+    echo "Warning: runc 1.1.14 is pinned for this Kubernetes version, the bundle ships $(runc --version | head -n 1)"
+fi
+mkdir -p /etc/containerd
+containerd config default > /etc/containerd/config.toml
+if [ "$CGROUP_DRIVER" = "systemd" ]; then
+    sed -i 's/SystemdCgroup = false/SystemdCgroup = true/' /etc/containerd/config.toml
+fi
+
+# remove cri as a disabled plugins from containerd config
+sed -i 's/^disabled_plugins = \["cri"\]/disabled_plugins = \[\]/' /etc/containerd/config.toml
+
+## pointing crictl to the container runtime socket
+printf 'runtime-endpoint: %s\nimage-endpoint: %s\n' "unix:///var/run/containerd/containerd.sock" "unix:///var/run/containerd/containerd.sock" > /etc/crictl.yaml
+
+
+## starting containerd service
+systemctl daemon-reload && systemctl enable containerd && systemctl restart containerd
+
+
+echo "Installation complete!"
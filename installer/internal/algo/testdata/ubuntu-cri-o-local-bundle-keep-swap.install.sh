@@ -0,0 +1,106 @@
+set -euox pipefail
+
+BUNDLE_DOWNLOAD_PATH=/var/lib/byoh/bundles
+BUNDLE_ADDR='projects.registry.vmware.com/cluster_api_provider_bringyourownhost/byoh-bundle-ubuntu_22.04_x86-64_k8s:v1.31.0'
+IMGPKG_VERSION=v0.36.4
+ARCH=amd64
+BUNDLE_PATH=$BUNDLE_DOWNLOAD_PATH/$BUNDLE_ADDR
+
+## phases completed for this bundle by a previous run of the script are skipped, so that a run
+## retried after a transient failure does not download and extract everything again
+STATE_PATH=/var/lib/byoh/state
+mkdir -p "$STATE_PATH"
+phase_done() { grep -qxF "$BUNDLE_ADDR" "$STATE_PATH/$1" 2>/dev/null; }
+mark_phase_done() { echo "$BUNDLE_ADDR" > "$STATE_PATH/$1"; }
+
+if phase_done bundle && [ -d "$BUNDLE_PATH" ]; then
+    echo "bundle already on the host"
+else
+
+    ## copying the pre-seeded bundle, a directory or a tarball of the bundle contents
+    LOCAL_BUNDLE_PATH="/opt/byoh/bundle.tar"
+    echo "copying bundle from $LOCAL_BUNDLE_PATH"
+    rm -rf "$BUNDLE_PATH" && mkdir -p "$BUNDLE_PATH"
+    if [ -d "$LOCAL_BUNDLE_PATH" ]; then
+        cp -r "$LOCAL_BUNDLE_PATH"/. "$BUNDLE_PATH"
+    else
+        tar -C "$BUNDLE_PATH" -xvf "$LOCAL_BUNDLE_PATH"
+    fi
+
+    mark_phase_done bundle
+fi
+
+
+## keep swap enabled, the agent starts the kubelet with failSwapOn set to false
+mkdir -p /var/lib/byoh && echo "keep" > /var/lib/byoh/swap-policy
+
+## disable firewall, save current state so uninstall can restore it
+if command -v ufw >>/dev/null; then
+    mkdir -p /var/lib/byoh
+    if [ ! -f /var/lib/byoh/ufw-state ]; then
+        ufw status | grep -q "Status: active" && echo "active" > /var/lib/byoh/ufw-state || echo "inactive" > /var/lib/byoh/ufw-state
+    fi
+    ufw disable
+fi
+
+## load kernal modules
+modprobe overlay && modprobe br_netfilter
+
+## adding os configuration
+if ! phase_done os-config; then
+    tar -C / -xvf "$BUNDLE_PATH/conf.tar"
+    mark_phase_done os-config
+fi
+sysctl --system 
+
+## installing deb packages
+if ! phase_done packages; then
+    for pkg in cri-tools kubernetes-cni kubectl kubelet kubeadm; do
+        dpkg --install "$BUNDLE_PATH/$pkg.deb" && apt-mark hold "$pkg"
+    done
+    mark_phase_done packages
+fi
+## a host released without reset has its kubelet disabled
+systemctl enable kubelet
+
+## detecting the cgroup driver of the container runtime: systemd when it is the init system, as on
+## all cgroup v2 hosts, to match the kubelet configured by kubeadm, cgroupfs otherwise
+CGROUP_VERSION=1
+if [ "$(stat -fc %T /sys/fs/cgroup)" = "cgroup2fs" ]; then
+    CGROUP_VERSION=2
+fi
+if [ -d /run/systemd/system ]; then
+    CGROUP_DRIVER=systemd
+else
+    CGROUP_DRIVER=cgroupfs
+fi
+echo "using the $CGROUP_DRIVER cgroup driver on a cgroup v$CGROUP_VERSION host"
+mkdir -p /var/lib/byoh && echo "$CGROUP_DRIVER" > /var/lib/byoh/cgroup-driver
+
+## installing cri-o
+if ! phase_done container-runtime; then
+    tar -C / -xvf "$BUNDLE_PATH/cri-o.tar"
+    mark_phase_done container-runtime
+fi
+mkdir -p /etc/crio/crio.conf.d
+printf '[crio.runtime]\ncgroup_manager = "%s"\nconmon_cgroup = "pod"\n' "$CGROUP_DRIVER" > /etc/crio/crio.conf.d/99-byoh-cgroup-manager.conf
+
+## configuring registry mirrors
+mkdir -p /etc/containers/registries.conf.d
+: > /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+printf '[[registry]]\nlocation = "%s"\ninsecure = %s\n' 'docker.io' "false" >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+endpoint='https://mirror.example.com:5000'
+printf '\n[[registry.mirror]]\nlocation = "%s"\ninsecure = %s\n' "${endpoint#*://}" "false" >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+printf '\n' >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+printf '[[registry]]\nlocation = "%s"\ninsecure = %s\n' 'registry.example.com:5000' "true" >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+printf '\n' >> /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+
+## pointing crictl to the container runtime socket
+printf 'runtime-endpoint: %s\nimage-endpoint: %s\n' "unix:///var/run/crio/crio.sock" "unix:///var/run/crio/crio.sock" > /etc/crictl.yaml
+
+
+## starting crio service
+systemctl daemon-reload && systemctl enable crio && systemctl restart crio
+
+
+echo "Installation complete!"
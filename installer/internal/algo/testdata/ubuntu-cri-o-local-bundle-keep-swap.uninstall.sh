@@ -0,0 +1,47 @@
+set -euox pipefail
+
+BUNDLE_DOWNLOAD_PATH=/var/lib/byoh/bundles
+BUNDLE_ADDR='projects.registry.vmware.com/cluster_api_provider_bringyourownhost/byoh-bundle-ubuntu_22.04_x86-64_k8s:v1.31.0'
+BUNDLE_PATH=$BUNDLE_DOWNLOAD_PATH/$BUNDLE_ADDR
+
+
+
+## disabling crio service
+systemctl stop crio && systemctl disable crio
+rm -f /etc/systemd/system/crio.service.d/http-proxy.conf && systemctl daemon-reload
+
+## removing container runtime configurations and cni plugins
+rm -rf /opt/cni/ && rm -rf /opt/containerd/ && rm -f /etc/crictl.yaml
+rm -rf /etc/containerd/certs.d && rm -f /etc/containers/registries.conf.d/99-byoh-mirrors.conf
+rm -f /etc/crio/crio.conf.d/99-byoh-cgroup-manager.conf
+if [ -f "$BUNDLE_PATH/cri-o.tar" ]; then
+  tar tf "$BUNDLE_PATH/cri-o.tar" | xargs -n 1 echo '/' | sed 's/ //g'  | grep -e '[^/]$' | xargs rm -f
+fi
+
+## removing deb packages
+for pkg in kubeadm kubelet kubectl kubernetes-cni cri-tools; do
+    dpkg -l "$pkg" &>/dev/null && dpkg --purge "$pkg" || echo "Package $pkg not installed"
+done
+
+## removing os configuration
+if [ -f "$BUNDLE_PATH/conf.tar" ]; then
+    tar tf "$BUNDLE_PATH/conf.tar" | xargs -n 1 echo '/' | sed 's/ //g' | grep -e "[^/]$" | xargs rm -f
+else
+    echo "Warning: conf.tar not found, skipping OS configuration removal"
+fi
+
+## remove kernel modules
+
+
+## restore firewall to its pre-install state
+if command -v ufw >>/dev/null; then
+    if [ -f /var/lib/byoh/ufw-state ] && grep -qx "active" /var/lib/byoh/ufw-state; then
+        ufw enable
+    fi
+    rm -f /var/lib/byoh/ufw-state
+fi
+
+
+
+rm -rf "$BUNDLE_PATH" /var/lib/byoh/state
+
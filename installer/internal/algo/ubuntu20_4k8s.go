@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package algo
@@ -13,8 +14,8 @@ type Ubuntu20_04Installer struct {
 }
 
 // NewUbuntu20_04Installer will return new Ubuntu20_04Installer instance
-func NewUbuntu20_04Installer(ctx context.Context, arch, bundleAddrs string, skipKernelModuleCleanup bool) (*Ubuntu20_04Installer, error) {
-	base, err := NewBaseUbuntuInstaller(ctx, arch, bundleAddrs, "", skipKernelModuleCleanup) // No special containerd config needed for 20.04
+func NewUbuntu20_04Installer(ctx context.Context, opts Options) (*Ubuntu20_04Installer, error) {
+	base, err := NewBaseUbuntuInstaller(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1,4 +1,5 @@
 // Copyright 2022 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package algo
@@ -7,19 +8,14 @@ import (
 	"context"
 )
 
-const (
-	// systemdCgroupConfig is the command to enable systemd cgroup in containerd for Ubuntu 22.04
-	systemdCgroupConfig = "sed -i s/SystemdCgroup\\ =\\ false/SystemdCgroup\\ =\\ true/ /etc/containerd/config.toml"
-)
-
 // Ubuntu22_04Installer represent the installer implementation for ubuntu22.04.* os distribution
 type Ubuntu22_04Installer struct {
 	*BaseUbuntuInstaller
 }
 
 // NewUbuntu22_04Installer will return new Ubuntu22_04Installer instance
-func NewUbuntu22_04Installer(ctx context.Context, arch, bundleAddrs string, skipKernelModuleCleanup bool) (*Ubuntu22_04Installer, error) {
-	base, err := NewBaseUbuntuInstaller(ctx, arch, bundleAddrs, systemdCgroupConfig, skipKernelModuleCleanup)
+func NewUbuntu22_04Installer(ctx context.Context, opts Options) (*Ubuntu22_04Installer, error) {
+	base, err := NewBaseUbuntuInstaller(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package installer
@@ -106,38 +107,52 @@ func (r *registry) ResolveOsToOsBundle(os string) string {
 	return ""
 }
 
-// GetSupportedRegistry returns a registry with installers for the supported OS and K8s
+// bundleArches are the architectures, as named in the bundles, the bundles are published for
+var bundleArches = []string{"x86-64", "arm64"}
+
+// GetSupportedRegistry returns a registry with installers for the supported OS, arch and K8s
 func GetSupportedRegistry() registry {
 	reg := newRegistry()
 
-	{
-		// Ubuntu
+	for _, arch := range bundleArches {
+		{
+			// Ubuntu
 
-		// BYOH Bundle Repository. Associate bundle with installer
-		linuxDistro20_04 := "Ubuntu_20.04.1_x86-64"
-		linuxDistro22_04 := "Ubuntu_22.04_x86-64"
+			// BYOH Bundle Repository. Associate bundle with installer
+			linuxDistro20_04 := "Ubuntu_20.04.1_" + arch
+			linuxDistro22_04 := "Ubuntu_22.04_" + arch
 
-		reg.AddBundleInstaller(linuxDistro20_04, "v1.31.*")
+			reg.AddBundleInstaller(linuxDistro20_04, "v1.31.*")
 
-		reg.AddBundleInstaller(linuxDistro22_04, "v1.31.*")
-		/*
-		 * PLACEHOLDER - ADD MORE K8S VERSIONS HERE
-		 */
+			reg.AddBundleInstaller(linuxDistro22_04, "v1.31.*")
+			/*
+			 * PLACEHOLDER - ADD MORE K8S VERSIONS HERE
+			 */
+
+			// Match concrete os version to repository os version
+			reg.AddOsFilter("Ubuntu_20.04.*_"+arch, linuxDistro20_04)
+			reg.AddOsFilter("Ubuntu_22.04.*_"+arch, linuxDistro22_04)
+			/*
+			 * PLACEHOLDER - POINT MORE DISTRO VERSIONS
+			 */
+		}
+
+		{
+			// Flatcar Container Linux, the bundle carries a systemd-sysext image instead of debs
+			linuxDistroFlatcar := "Flatcar_" + arch
 
-		// Match any patch version of the specified Major & Minor K8s version
-		reg.AddK8sFilter("v1.31.*")
+			reg.AddBundleInstaller(linuxDistroFlatcar, "v1.31.*")
+
+			reg.AddOsFilter("Flatcar_Container_Linux.*_"+arch, linuxDistroFlatcar)
+		}
 
-		// Match concrete os version to repository os version
-		reg.AddOsFilter("Ubuntu_20.04.*_x86-64", linuxDistro20_04)
-		reg.AddOsFilter("Ubuntu_22.04.*_x86-64", linuxDistro22_04)
 		/*
-		 * PLACEHOLDER - POINT MORE DISTRO VERSIONS
+		 * PLACEHOLDER - ADD MORE OS HERE
 		 */
 	}
 
-	/*
-	 * PLACEHOLDER - ADD MORE OS HERE
-	 */
+	// Match any patch version of the specified Major & Minor K8s version
+	reg.AddK8sFilter("v1.31.*")
 
 	return reg
 }
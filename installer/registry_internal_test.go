@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package installer
@@ -109,14 +110,19 @@ var _ = Describe("Byohost Installer Tests", func() {
 
 		It("Should match with the supported os and k8s versions", func() {
 			osFilters, osBundles := r.ListOS()
-			Expect(osFilters).To(ContainElements("Ubuntu_20.04.*_x86-64", "Ubuntu_22.04.*_x86-64"))
-			Expect(osFilters).To(HaveLen(2))
-			Expect(osBundles).To(ContainElements("Ubuntu_20.04.1_x86-64", "Ubuntu_22.04_x86-64"))
-			Expect(osBundles).To(HaveLen(2))
+			Expect(osFilters).To(ContainElements("Ubuntu_20.04.*_x86-64", "Ubuntu_22.04.*_x86-64", "Flatcar_Container_Linux.*_x86-64",
+				"Ubuntu_20.04.*_arm64", "Ubuntu_22.04.*_arm64", "Flatcar_Container_Linux.*_arm64"))
+			Expect(osFilters).To(HaveLen(6))
+			Expect(osBundles).To(ContainElements("Ubuntu_20.04.1_x86-64", "Ubuntu_22.04_x86-64", "Flatcar_x86-64",
+				"Ubuntu_20.04.1_arm64", "Ubuntu_22.04_arm64", "Flatcar_arm64"))
+			Expect(osBundles).To(HaveLen(6))
 
 			osBundleResult := r.ListK8s("Ubuntu_20.04.1_x86-64")
 			Expect(osBundleResult).To(ContainElements("v1.31.*"))
 			Expect(osBundleResult).To(HaveLen(1))
+
+			Expect(r.ResolveOsToOsBundle("Flatcar_Container_Linux_by_Kinvolk_3510.2.1_(Oklo)_x86-64")).To(Equal("Flatcar_x86-64"))
+			Expect(r.ResolveOsToOsBundle("Ubuntu_22.04.3_LTS_arm64")).To(Equal("Ubuntu_22.04_arm64"))
 		})
 	})
 })
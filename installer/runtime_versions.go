@@ -0,0 +1,56 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package installer
+
+import (
+	"strings"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer/internal/algo"
+)
+
+// RuntimeVersions holds the containerd and runc versions installed with containerd as the CRI
+type RuntimeVersions = algo.RuntimeVersions
+
+// runtimeVersionMatrix pins the containerd and runc versions known to work with a k8s minor
+// version. The install script warns if the bundle ships other versions, the bundles published
+// before the matrix existed do not all ship them.
+var runtimeVersionMatrix = map[string]RuntimeVersions{
+	"1.26": {Containerd: "1.6.26", Runc: "1.1.12"},
+	"1.27": {Containerd: "1.7.13", Runc: "1.1.12"},
+	"1.28": {Containerd: "1.7.13", Runc: "1.1.12"},
+	"1.29": {Containerd: "1.7.22", Runc: "1.1.14"},
+	"1.30": {Containerd: "1.7.22", Runc: "1.1.14"},
+	"1.31": {Containerd: "1.7.22", Runc: "1.1.14"},
+	"1.32": {Containerd: "1.7.26", Runc: "1.2.5"},
+}
+
+// PinnedRuntimeVersions returns the containerd and runc versions pinned for the minor version of
+// k8sVersion. Versions of k8s missing from the matrix are not pinned.
+func PinnedRuntimeVersions(k8sVersion string) RuntimeVersions {
+	return runtimeVersionMatrix[k8sMinorVersion(k8sVersion)]
+}
+
+// resolveRuntimeVersions returns the pinned runtime versions of k8sVersion, with the versions
+// set in overrides taking precedence. The versions set in overrides are required.
+func resolveRuntimeVersions(k8sVersion string, overrides RuntimeVersions) RuntimeVersions {
+	versions := PinnedRuntimeVersions(k8sVersion)
+	if overrides.Containerd != "" {
+		versions.Containerd = overrides.Containerd
+		versions.ContainerdRequired = true
+	}
+	if overrides.Runc != "" {
+		versions.Runc = overrides.Runc
+		versions.RuncRequired = true
+	}
+	return versions
+}
+
+// k8sMinorVersion returns the major and minor of a k8s version like v1.29.3, or an empty string
+func k8sMinorVersion(k8sVersion string) string {
+	parts := strings.SplitN(strings.TrimPrefix(k8sVersion, "v"), ".", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0] + "." + parts[1]
+}
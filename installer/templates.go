@@ -0,0 +1,60 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package installer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/installer/internal/algo"
+)
+
+// Templates holds the operator overrides of the install and uninstall templates
+type Templates = algo.Templates
+
+const (
+	// TemplateKeyInstall is the key of the template that replaces the install template
+	TemplateKeyInstall = "install"
+	// TemplateKeyUninstall is the key of the template that replaces the uninstall template
+	TemplateKeyUninstall = "uninstall"
+
+	// HookPreInstall is the key of the snippet run once the bundle is on the host, before the host is changed
+	HookPreInstall = algo.HookPreInstall
+	// HookPostInstall is the key of the snippet run once the container runtime is started
+	HookPostInstall = algo.HookPostInstall
+	// HookPreUninstall is the key of the snippet run before the container runtime is stopped
+	HookPreUninstall = algo.HookPreUninstall
+	// HookPostUninstall is the key of the snippet run once the bundle is removed from the host
+	HookPostUninstall = algo.HookPostUninstall
+)
+
+// ErrTemplateNotSupported error type when the templates have a key that is neither a template nor a hook
+const ErrTemplateNotSupported = Error("No support for template")
+
+// NewTemplates returns the templates held by data, e.g. the data of a ConfigMap, keyed by
+// TemplateKeyInstall, TemplateKeyUninstall and the hook names
+func NewTemplates(data map[string]string) (Templates, error) {
+	var templates Templates
+	var unknown []string
+	for key, text := range data {
+		switch key {
+		case TemplateKeyInstall:
+			templates.Install = text
+		case TemplateKeyUninstall:
+			templates.Uninstall = text
+		case HookPreInstall, HookPostInstall, HookPreUninstall, HookPostUninstall:
+			if templates.Hooks == nil {
+				templates.Hooks = make(map[string]string)
+			}
+			templates.Hooks[key] = text
+		default:
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return Templates{}, fmt.Errorf("%w %v", ErrTemplateNotSupported, unknown)
+	}
+	return templates, nil
+}
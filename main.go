@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package main
@@ -6,7 +7,10 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -15,32 +19,69 @@ import (
 	"k8s.io/klog/v2/klogr"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientset "k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	byohcontrollers "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/controllers/infrastructure"
 
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/notification"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/common/tracing"
 
 	//+kubebuilder:scaffold:imports
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/controllers/remote"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 )
 
+// notificationURLEnv is the environment variable the default of --notification-webhook-url is read from
+const notificationURLEnv = "BYOH_NOTIFICATION_WEBHOOK_URL"
+
 var (
 	scheme               = runtime.NewScheme()
 	setupLog             = ctrl.Log.WithName("setup")
 	metricsAddr          string
 	enableLeaderElection bool
 	probeAddr            string
+	watchFilterValue     string
+	otlpEndpoint         string
+	notificationURL      string
+
+	leaderElectionNamespace     string
+	leaderElectionLeaseDuration time.Duration
+	leaderElectionRenewDeadline time.Duration
+	leaderElectionRetryPeriod   time.Duration
+
+	hostOperationRetention  time.Duration
+	hostHeartbeatTimeout    time.Duration
+	hostQuarantineThreshold int
+	bootstrapTokenMinTTL    time.Duration
+
+	byoHostWebhookAllowedUsers        stringSliceFlag
+	byoHostWebhookAllowedUserPatterns stringSliceFlag
+	byoHostWebhookDenyUnknownUsers    bool
 )
 
+// stringSliceFlag is a flag.Value collecting the values of a flag that can be repeated
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func init() {
 	klog.InitFlags(nil)
 	// clear any discard loggers set by dependecies
@@ -58,7 +99,35 @@ func setFlags() {
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the lease used for leader election. Defaults to the namespace the manager runs in.")
+	flag.DurationVar(&leaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, //nolint: mnd
+		"Duration the non-leader replicas wait before forcing to acquire leadership. This is how long the controllers stop when the leader dies.")
+	flag.DurationVar(&leaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, //nolint: mnd
+		"Duration the leader retries refreshing its leadership before giving it up. Must be less than the lease duration.")
+	flag.DurationVar(&leaderElectionRetryPeriod, "leader-election-retry-period", 2*time.Second, //nolint: mnd
+		"Duration the replicas wait between two tries of acquiring or renewing the leadership. Must be less than the renew deadline.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&watchFilterValue, "watch-filter-value", "",
+		fmt.Sprintf("Label value that the controller watches to reconcile cluster-api and BYOH objects. Label key is always %s. If unspecified, the controller watches for all objects.", clusterv1.WatchLabel))
+	flag.Var(&byoHostWebhookAllowedUsers, "byohost-webhook-allowed-user",
+		"A username allowed to create and update any ByoHost, e.g. the manager service account. Can be repeated, replaces the default manager service accounts.")
+	flag.Var(&byoHostWebhookAllowedUserPatterns, "byohost-webhook-allowed-user-pattern",
+		"A regular expression matching the whole username of users allowed to create and update any ByoHost. Can be repeated, replaces the default email-like pattern.")
+	flag.BoolVar(&byoHostWebhookDenyUnknownUsers, "byohost-webhook-deny-unknown-users", false,
+		"Deny the ByoHost creates and updates of users that are neither allowed nor an agent identity byoh:host:<hostname>. Leave it off while agents register with the token kubeconfig of their tenant.")
+	flag.DurationVar(&hostOperationRetention, "byohost-operation-retention", byohcontrollers.DefaultHostOperationRetention,
+		"How long the ByoHostOperation audit records of the host lifecycle operations are kept. Records are kept forever if 0.")
+	flag.DurationVar(&hostHeartbeatTimeout, "byohost-heartbeat-timeout", byohcontrollers.DefaultHeartbeatTimeout,
+		"How long the agent of a host may not report a heartbeat before the host is marked disconnected and no longer attached to new machines.")
+	flag.IntVar(&hostQuarantineThreshold, "byohost-quarantine-threshold", byohcontrollers.DefaultQuarantineThreshold,
+		"Number of consecutive machines failing to bootstrap on a host after which the host is quarantined and no longer attached. Hosts are never quarantined if 0.")
+	flag.DurationVar(&bootstrapTokenMinTTL, "bootstrap-token-min-ttl", byohcontrollers.DefaultBootstrapTokenMinTTL,
+		"How long the bootstrap token of the bootstrap data of a machine must still be valid for when a host is attached to it. The refresh of tokens expiring sooner is requested from the bootstrap provider.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", os.Getenv(tracing.EndpointEnv),
+		"Endpoint of the OpenTelemetry collector to export the reconcile traces to with OTLP/HTTP, e.g. http://otel-collector:4318. Tracing is off if empty.")
+	flag.StringVar(&notificationURL, "notification-webhook-url", os.Getenv(notificationURLEnv),
+		"URL of the webhook, e.g. a Slack incoming webhook, the registrations, attachments, disconnections and decommissions of hosts are posted to. Nothing is posted if empty.")
 	flag.Parse()
 }
 
@@ -70,14 +139,33 @@ func setFlags() {
 func main() {
 	setFlags()
 	ctrl.SetLogger(klogr.New())
+	shutdownTracing := tracing.Setup("byoh-controller-manager", otlpEndpoint)
+
+	if err := validateLeaderElection(); err != nil {
+		setupLog.Error(err, "invalid leader election settings")
+		os.Exit(1)
+	}
+	if !enableLeaderElection {
+		setupLog.Info("leader election is disabled, run a single replica of the manager: replicas would claim the same hosts")
+	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "controller-leader-election-caph",
+		Scheme:                  scheme,
+		MetricsBindAddress:      metricsAddr,
+		Port:                    9443,
+		HealthProbeBindAddress:  probeAddr,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionID:        "controller-leader-election-caph",
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaseDuration:           &leaderElectionLeaseDuration,
+		RenewDeadline:           &leaderElectionRenewDeadline,
+		RetryPeriod:             &leaderElectionRetryPeriod,
+		// the leader gives up its lease on shutdown, e.g. on a rolling update, so that
+		// the other replicas take over without waiting for the lease to expire; the
+		// manager exits right after the controllers stop, so this is safe
+		LeaderElectionReleaseOnCancel: true,
+		NewCache:                      cache.BuilderWithOptions(byohcontrollers.CacheOptions(watchFilterValue)),
+		ClientDisableCacheFor:         byohcontrollers.UncachedObjects,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -85,7 +173,11 @@ func main() {
 	}
 
 	remoteLogger := ctrl.Log.WithName("remote").WithName("ClusterCacheTracker")
-	options := remote.ClusterCacheTrackerOptions{Log: &remoteLogger}
+	options := remote.ClusterCacheTrackerOptions{
+		Log: &remoteLogger,
+		// the bootstrap tokens of the workload clusters are read without caching all their secrets
+		ClientUncachedObjects: []client.Object{&corev1.Secret{}},
+	}
 	tracker, err := remote.NewClusterCacheTracker(mgr, options)
 	if err != nil {
 		setupLog.Error(err, "unable to create cluster cache tracker")
@@ -93,43 +185,66 @@ func main() {
 	}
 
 	if err = (&remote.ClusterCacheReconciler{
-		Client:  mgr.GetClient(),
-		Tracker: tracker,
+		Client:           mgr.GetClient(),
+		Tracker:          tracker,
+		WatchFilterValue: watchFilterValue,
 	}).SetupWithManager(context.TODO(), mgr, concurrency(0)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterCacheReconciler")
 		os.Exit(1)
 	}
 
+	var notifier notification.Notifier
+	if notificationURL != "" {
+		notifier = notification.NewWebhookNotifier(notificationURL)
+	}
+
 	if err = (&byohcontrollers.ByoMachineReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Tracker:  tracker,
-		Recorder: mgr.GetEventRecorderFor("byomachine-controller"),
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		Tracker:              tracker,
+		Recorder:             mgr.GetEventRecorderFor("byomachine-controller"),
+		WatchFilterValue:     watchFilterValue,
+		HeartbeatTimeout:     hostHeartbeatTimeout,
+		QuarantineThreshold:  hostQuarantineThreshold,
+		BootstrapTokenMinTTL: bootstrapTokenMinTTL,
 	}).SetupWithManager(context.TODO(), mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ByoMachine")
 		os.Exit(1)
 	}
 	if err = (&byohcontrollers.ByoHostReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		WatchFilterValue: watchFilterValue,
+		HeartbeatTimeout: hostHeartbeatTimeout,
+		Notifier:         notifier,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ByoHost")
 		os.Exit(1)
 	}
 	if err = (&byohcontrollers.ByoMachineTemplateReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		WatchFilterValue: watchFilterValue,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ByoMachineTemplate")
 		os.Exit(1)
 	}
 	if err = (&byohcontrollers.ByoClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		WatchFilterValue: watchFilterValue,
 	}).SetupWithManager(context.TODO(), mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ByoCluster")
 		os.Exit(1)
 	}
+	if err = (&byohcontrollers.ByoHostOperationReconciler{
+		Client:    mgr.GetClient(),
+		Retention: hostOperationRetention,
+		Notifier:  notifier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ByoHostOperation")
+		os.Exit(1)
+	}
 
 	// Set 'MANUAL_CSR_APPROVAL=enable' to disable ByoAdmission controller. Now CSRs should be approved manually.
 	if os.Getenv("MANUAL_CSR_APPROVAL") != "enable" {
@@ -154,18 +269,33 @@ func main() {
 		Client:                  mgr.GetClient(),
 		Scheme:                  mgr.GetScheme(),
 		SkipKernelModuleCleanup: skipKernelModuleCleanup,
+		WatchFilterValue:        watchFilterValue,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "K8sInstallerConfig")
 		os.Exit(1)
 	}
 
-	mgr.GetWebhookServer().Register("/validate-infrastructure-cluster-x-k8s-io-v1beta1-byohost", &webhook.Admission{Handler: &infrastructurev1beta1.ByoHostValidator{
-		Client: mgr.GetClient(),
-	}})
+	allowedUserPatterns, err := infrastructurev1beta1.CompileUserPatterns(byoHostWebhookAllowedUserPatterns)
+	if err != nil {
+		setupLog.Error(err, "unable to parse allowed user patterns", "webhook", "ByoHost")
+		os.Exit(1)
+	}
+	byoHostValidator := &infrastructurev1beta1.ByoHostValidator{
+		Client:           mgr.GetClient(),
+		AllowedUsers:     byoHostWebhookAllowedUsers,
+		DenyUnknownUsers: byoHostWebhookDenyUnknownUsers,
+	}
+	if len(byoHostWebhookAllowedUserPatterns) > 0 {
+		byoHostValidator.AllowedUserPatterns = allowedUserPatterns
+	}
+	mgr.GetWebhookServer().Register("/validate-infrastructure-cluster-x-k8s-io-v1beta1-byohost", &webhook.Admission{Handler: byoHostValidator})
 
 	if err = (&byohcontrollers.BootstrapKubeconfigReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:           mgr.GetClient(),
+		APIReader:        mgr.GetAPIReader(),
+		Scheme:           mgr.GetScheme(),
+		Recorder:         mgr.GetEventRecorderFor("bootstrapkubeconfig-controller"),
+		WatchFilterValue: watchFilterValue,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BootstrapKubeconfig")
 		os.Exit(1)
@@ -174,6 +304,30 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "BootstrapKubeconfig")
 		os.Exit(1)
 	}
+	if err = (&infrastructurev1beta1.ByoCluster{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ByoCluster")
+		os.Exit(1)
+	}
+	if err = (&infrastructurev1beta1.ByoClusterTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ByoClusterTemplate")
+		os.Exit(1)
+	}
+	if err = (&infrastructurev1beta1.ByoMachine{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ByoMachine")
+		os.Exit(1)
+	}
+	if err = (&infrastructurev1beta1.ByoMachineTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "ByoMachineTemplate")
+		os.Exit(1)
+	}
+	if err = (&infrastructurev1beta1.K8sInstallerConfig{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "K8sInstallerConfig")
+		os.Exit(1)
+	}
+	if err = (&infrastructurev1beta1.K8sInstallerConfigTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "K8sInstallerConfigTemplate")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -186,10 +340,30 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second) //nolint: mnd
+	defer cancel()
+	if tracingErr := shutdownTracing(ctx); tracingErr != nil {
+		setupLog.Error(tracingErr, "failed to export traces")
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		os.Exit(1) //nolint: gocritic
+	}
+}
+
+// validateLeaderElection checks that the leader renews its lease before the lease expires,
+// and retries renewing it before the renew deadline
+func validateLeaderElection() error {
+	if leaderElectionRenewDeadline >= leaderElectionLeaseDuration {
+		return fmt.Errorf("--leader-election-renew-deadline (%s) must be less than --leader-election-lease-duration (%s)",
+			leaderElectionRenewDeadline, leaderElectionLeaseDuration)
+	}
+	if leaderElectionRetryPeriod >= leaderElectionRenewDeadline {
+		return fmt.Errorf("--leader-election-retry-period (%s) must be less than --leader-election-renew-deadline (%s)",
+			leaderElectionRetryPeriod, leaderElectionRenewDeadline)
 	}
+	return nil
 }
 
 func concurrency(c int) controller.Options {
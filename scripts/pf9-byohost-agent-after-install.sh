@@ -30,6 +30,18 @@ export REGION=$(cat /root/.byoh/region)
 echo "NAMESPACE=$NAMESPACE" > /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf
 echo "BOOTSTRAP_KUBECONFIG=/etc/pf9-byohost-agent.service.d/bootstrap-kubeconfig.yaml" >> /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf 
 echo "REGION=$REGION" >> /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf 
+# the taints of the node, if byohctl onboard was given any
+if [ -f /root/.byoh/taints ]; then
+	echo "TAINTS=$(cat /root/.byoh/taints)" >> /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf
+fi
+# the name of the ByoHost, if byohctl onboard was given --host-name
+if [ -f /root/.byoh/host-name ]; then
+	echo "HOST_NAME=$(cat /root/.byoh/host-name)" >> /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf
+fi
+# byohctl hands over the OpenTelemetry collector and its onboarding trace to the agent
+if [ -f /root/.byoh/tracing ]; then
+	cat /root/.byoh/tracing >> /etc/pf9-byohost-agent.service.d/pf9-byohost-agent.conf
+fi
 
 systemctl daemon-reload
 systemctl enable pf9-byohost-agent.service
@@ -3,6 +3,13 @@
 # Exit immediately if a command fails
 set -e
 
+# dpkg runs the script of the old version with "upgrade" when byohctl upgrade installs a new one,
+# the host stays onboarded and byohctl restarts the agent
+if [ "$1" = "upgrade" ]; then
+    echo "Upgrading pf9-byoh-hostagent, the host is kept onboarded"
+    exit 0
+fi
+
 LOG_FILE="/var/log/pf9/byoh-agent-uninstall.log"
 
 echo "Starting uninstallation of pf9-byoh-hostagent..." | tee -a "$LOG_FILE"
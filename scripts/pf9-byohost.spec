@@ -89,6 +89,13 @@ systemctl start pf9-byohost-agent.service
 # Exit immediately if a command fails
 set -e
 
+# rpm runs the script of the old version with 1 when byohctl upgrade installs a new one, the host
+# stays onboarded and byohctl restarts the agent
+if [ "$1" -ge 1 ]; then
+    echo "Upgrading pf9-byoh-hostagent, the host is kept onboarded"
+    exit 0
+fi
+
 LOG_FILE="/var/log/pf9/byoh/byoh-agent-uninstall.log"
 
 echo "Starting uninstallation of pf9-byoh-hostagent..." | tee -a "$LOG_FILE"
@@ -1,4 +1,5 @@
 // Copyright 2021 VMware, Inc. All Rights Reserved.
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package builder
@@ -9,6 +10,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"time"
 
 	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
 	certv1 "k8s.io/api/certificates/v1"
@@ -24,6 +26,10 @@ type ByoMachineBuilder struct {
 	clusterLabel string
 	machine      *clusterv1.Machine
 	selector     map[string]string
+	hostRef      string
+	minCPU       int32
+	minMemoryMiB int64
+	minDiskGiB   int64
 }
 
 // ByoMachine returns a ByoMachineBuilder with the given name and namespace
@@ -52,6 +58,20 @@ func (b *ByoMachineBuilder) WithLabelSelector(selector map[string]string) *ByoMa
 	return b
 }
 
+// WithHostRef adds the passed name of the ByoHost the machine is pinned to to the ByoMachineBuilder
+func (b *ByoMachineBuilder) WithHostRef(hostName string) *ByoMachineBuilder {
+	b.hostRef = hostName
+	return b
+}
+
+// WithResourceRequirements adds the passed minimum host resources to the ByoMachineBuilder
+func (b *ByoMachineBuilder) WithResourceRequirements(minCPU int32, minMemoryMiB, minDiskGiB int64) *ByoMachineBuilder {
+	b.minCPU = minCPU
+	b.minMemoryMiB = minMemoryMiB
+	b.minDiskGiB = minDiskGiB
+	return b
+}
+
 // Build returns a ByoMachine with the attributes added to the ByoMachineBuilder
 func (b *ByoMachineBuilder) Build() *infrastructurev1beta1.ByoMachine {
 	byoMachine := &infrastructurev1beta1.ByoMachine{
@@ -63,7 +83,11 @@ func (b *ByoMachineBuilder) Build() *infrastructurev1beta1.ByoMachine {
 			GenerateName: b.name,
 			Namespace:    b.namespace,
 		},
-		Spec: infrastructurev1beta1.ByoMachineSpec{},
+		Spec: infrastructurev1beta1.ByoMachineSpec{
+			MinCPU:       b.minCPU,
+			MinMemoryMiB: b.minMemoryMiB,
+			MinDiskGiB:   b.minDiskGiB,
+		},
 	}
 	if b.machine != nil {
 		byoMachine.OwnerReferences = []metav1.OwnerReference{
@@ -83,15 +107,22 @@ func (b *ByoMachineBuilder) Build() *infrastructurev1beta1.ByoMachine {
 	if b.selector != nil {
 		byoMachine.Spec.Selector = &metav1.LabelSelector{MatchLabels: b.selector}
 	}
+	if b.hostRef != "" {
+		byoMachine.Spec.HostRef = &corev1.LocalObjectReference{Name: b.hostRef}
+	}
 
 	return byoMachine
 }
 
 // ByoHostBuilder holds the variables and objects required to build an infrastructurev1beta1.ByoHost
 type ByoHostBuilder struct {
-	namespace string
-	name      string
-	labels    map[string]string
+	namespace         string
+	name              string
+	labels            map[string]string
+	nodeLabels        map[string]string
+	nodeTaints        []corev1.Taint
+	schedulable       *bool
+	maintenanceWindow *infrastructurev1beta1.MaintenanceWindow
 }
 
 // ByoHost returns a ByoHostBuilder with the given name and namespace
@@ -108,6 +139,33 @@ func (b *ByoHostBuilder) WithLabels(labels map[string]string) *ByoHostBuilder {
 	return b
 }
 
+// WithNodeLabels adds the passed node labels to the ByoHostBuilder
+func (b *ByoHostBuilder) WithNodeLabels(nodeLabels map[string]string) *ByoHostBuilder {
+	b.nodeLabels = nodeLabels
+	return b
+}
+
+// WithNodeTaints adds the passed node taints to the ByoHostBuilder
+func (b *ByoHostBuilder) WithNodeTaints(nodeTaints []corev1.Taint) *ByoHostBuilder {
+	b.nodeTaints = nodeTaints
+	return b
+}
+
+// WithSchedulable sets the schedulable field of the ByoHostBuilder
+func (b *ByoHostBuilder) WithSchedulable(schedulable bool) *ByoHostBuilder {
+	b.schedulable = &schedulable
+	return b
+}
+
+// WithMaintenanceWindow adds the passed maintenance window to the ByoHostBuilder
+func (b *ByoHostBuilder) WithMaintenanceWindow(start, end time.Time) *ByoHostBuilder {
+	b.maintenanceWindow = &infrastructurev1beta1.MaintenanceWindow{
+		Start: metav1.NewTime(start),
+		End:   metav1.NewTime(end),
+	}
+	return b
+}
+
 // Build returns a ByoHost with the attributes added to the ByoHostBuilder
 func (b *ByoHostBuilder) Build() *infrastructurev1beta1.ByoHost {
 	byoHost := &infrastructurev1beta1.ByoHost{
@@ -119,7 +177,12 @@ func (b *ByoHostBuilder) Build() *infrastructurev1beta1.ByoHost {
 			GenerateName: b.name,
 			Namespace:    b.namespace,
 		},
-		Spec: infrastructurev1beta1.ByoHostSpec{},
+		Spec: infrastructurev1beta1.ByoHostSpec{
+			NodeLabels:        b.nodeLabels,
+			NodeTaints:        b.nodeTaints,
+			Schedulable:       b.schedulable,
+			MaintenanceWindow: b.maintenanceWindow,
+		},
 	}
 	if b.labels != nil {
 		byoHost.Labels = b.labels
@@ -139,11 +202,12 @@ type MachineBuilder struct {
 
 // ByoClusterBuilder holds the variables and objects required to build an infrastructurev1beta1.ByoCluster
 type ByoClusterBuilder struct {
-	namespace      string
-	name           string
-	bundleRegistry string
-	bundleTag      string
-	cluster        *clusterv1.Cluster
+	namespace          string
+	name               string
+	bundleBaseRegistry string
+	bundleRegistry     string
+	bundleTag          string
+	cluster            *clusterv1.Cluster
 }
 
 // ByoCluster returns a ByoClusterBuilder with the given name and namespace
@@ -162,6 +226,12 @@ func (c *ByoClusterBuilder) WithOwnerCluster(cluster *clusterv1.Cluster) *ByoClu
 
 // WithBundleBaseRegistry adds the passed registry value to the ByoClusterBuilder
 func (c *ByoClusterBuilder) WithBundleBaseRegistry(registry string) *ByoClusterBuilder {
+	c.bundleBaseRegistry = registry
+	return c
+}
+
+// WithBundleRegistry adds the passed bundleRegistry value to the ByoClusterBuilder
+func (c *ByoClusterBuilder) WithBundleRegistry(registry string) *ByoClusterBuilder {
 	c.bundleRegistry = registry
 	return c
 }
@@ -197,8 +267,12 @@ func (c *ByoClusterBuilder) Build() *infrastructurev1beta1.ByoCluster {
 		}
 	}
 
+	if c.bundleBaseRegistry != "" {
+		cluster.Spec.BundleLookupBaseRegistry = c.bundleBaseRegistry
+	}
+
 	if c.bundleRegistry != "" {
-		cluster.Spec.BundleLookupBaseRegistry = c.bundleRegistry
+		cluster.Spec.BundleRegistry = c.bundleRegistry
 	}
 
 	return cluster
@@ -478,13 +552,14 @@ func (csrb *CertificateSigningRequestBuilder) Build() (*certv1.CertificateSignin
 
 // K8sInstallerConfigBuilder holds the variables and objects required to build an infrastructurev1beta1.K8sInstallerConfig
 type K8sInstallerConfigBuilder struct {
-	namespace     string
-	name          string
-	generatedName string
-	clusterLabel  string
-	byomachine    *infrastructurev1beta1.ByoMachine
-	bundleType    string
-	bundleRepo    string
+	namespace      string
+	name           string
+	generatedName  string
+	clusterLabel   string
+	byomachine     *infrastructurev1beta1.ByoMachine
+	bundleType     string
+	bundleRepo     string
+	bundleRegistry string
 }
 
 // K8sInstallerConfig returns a K8sInstallerConfigBuilder with the given generated name and namespace
@@ -525,6 +600,12 @@ func (b *K8sInstallerConfigBuilder) WithBundleType(bundleType string) *K8sInstal
 	return b
 }
 
+// WithBundleRegistry adds the passed bundleRegistry to the K8sInstallerConfigBuilder
+func (b *K8sInstallerConfigBuilder) WithBundleRegistry(bundleRegistry string) *K8sInstallerConfigBuilder {
+	b.bundleRegistry = bundleRegistry
+	return b
+}
+
 // Build returns a K8sInstallerConfig with the attributes added to the K8sInstallerConfigBuilder
 func (b *K8sInstallerConfigBuilder) Build() *infrastructurev1beta1.K8sInstallerConfig {
 	k8sinstallerconfig := &infrastructurev1beta1.K8sInstallerConfig{
@@ -560,15 +641,19 @@ func (b *K8sInstallerConfigBuilder) Build() *infrastructurev1beta1.K8sInstallerC
 	if b.bundleType != "" {
 		k8sinstallerconfig.Spec.BundleType = b.bundleType
 	}
+	if b.bundleRegistry != "" {
+		k8sinstallerconfig.Spec.BundleRegistry = b.bundleRegistry
+	}
 	return k8sinstallerconfig
 }
 
 // K8sInstallerConfigTemplateBuilder holds the variables and objects required to build an infrastructurev1beta1.K8sInstallerConfigTemplate
 type K8sInstallerConfigTemplateBuilder struct {
-	namespace     string
-	generatedName string
-	bundleType    string
-	bundleRepo    string
+	namespace      string
+	generatedName  string
+	bundleType     string
+	bundleRepo     string
+	bundleRegistry string
 }
 
 // K8sInstallerConfigTemplate returns a K8sInstallerConfigTemplateBuilder with the given generated name and namespace
@@ -591,6 +676,12 @@ func (b *K8sInstallerConfigTemplateBuilder) WithBundleType(bundleType string) *K
 	return b
 }
 
+// WithBundleRegistry adds the passed bundleRegistry to the K8sInstallerConfigTemplateBuilder
+func (b *K8sInstallerConfigTemplateBuilder) WithBundleRegistry(bundleRegistry string) *K8sInstallerConfigTemplateBuilder {
+	b.bundleRegistry = bundleRegistry
+	return b
+}
+
 // Build returns a K8sInstallerConfigTemplate with the attributes added to the K8sInstallerConfigTemplateBuilder
 func (b *K8sInstallerConfigTemplateBuilder) Build() *infrastructurev1beta1.K8sInstallerConfigTemplate {
 	k8sinstallerconfigtemplate := &infrastructurev1beta1.K8sInstallerConfigTemplate{
@@ -610,6 +701,9 @@ func (b *K8sInstallerConfigTemplateBuilder) Build() *infrastructurev1beta1.K8sIn
 	if b.bundleType != "" {
 		k8sinstallerconfigtemplate.Spec.Template.Spec.BundleType = b.bundleType
 	}
+	if b.bundleRegistry != "" {
+		k8sinstallerconfigtemplate.Spec.Template.Spec.BundleRegistry = b.bundleRegistry
+	}
 	return k8sinstallerconfigtemplate
 }
 
@@ -0,0 +1,246 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package conformance
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	infrastructurev1beta1 "github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/apis/infrastructure/v1beta1"
+	"github.com/vmware-tanzu/cluster-api-provider-bringyourownhost/test/builder"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// crdProperty returns whether the v1beta1 schema of the CRD of kind has the property at path,
+// e.g. "spec.controlPlaneEndpoint.host"
+func crdProperty(kind, path string) bool {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+	name := strings.ToLower(kind) + "s." + infrastructurev1beta1.GroupVersion.Group
+	Expect(k8sClient.Get(ctx, client.ObjectKey{Name: name}, crd)).To(Succeed())
+
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, version := range versions {
+		version, ok := version.(map[string]interface{})
+		if !ok || version["name"] != infrastructurev1beta1.GroupVersion.Version {
+			continue
+		}
+		fields := []string{"schema", "openAPIV3Schema"}
+		for _, field := range strings.Split(path, ".") {
+			fields = append(fields, "properties", field)
+		}
+		_, found, _ := unstructured.NestedMap(version, fields...)
+		return found
+	}
+	return false
+}
+
+func createNamespace() string {
+	namespace := builder.Namespace("conformance-").Build()
+	Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+	return namespace.Name
+}
+
+func getByoCluster(byoCluster *infrastructurev1beta1.ByoCluster) func() *infrastructurev1beta1.ByoCluster {
+	return func() *infrastructurev1beta1.ByoCluster {
+		actual := &infrastructurev1beta1.ByoCluster{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoCluster), actual)).To(Succeed())
+		return actual
+	}
+}
+
+func getByoMachine(byoMachine *infrastructurev1beta1.ByoMachine) func() *infrastructurev1beta1.ByoMachine {
+	return func() *infrastructurev1beta1.ByoMachine {
+		actual := &infrastructurev1beta1.ByoMachine{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachine), actual)).To(Succeed())
+		return actual
+	}
+}
+
+func hasFinalizer(finalizer string) func(client.Object) bool {
+	return func(object client.Object) bool {
+		return controllerutil.ContainsFinalizer(object, finalizer)
+	}
+}
+
+var _ = Describe("Infrastructure provider contract", func() {
+	Context("When the CRDs are installed", func() {
+		It("Should serve the fields the contract requires on the ByoCluster", func() {
+			for _, path := range []string{
+				"spec.controlPlaneEndpoint.host",
+				"spec.controlPlaneEndpoint.port",
+				"status.ready",
+				"status.failureDomains",
+				"status.conditions",
+			} {
+				Expect(crdProperty("ByoCluster", path)).To(BeTrue(), "ByoCluster misses %s", path)
+			}
+		})
+
+		It("Should serve the fields the contract requires on the ByoMachine", func() {
+			for _, path := range []string{
+				"spec.providerID",
+				"status.ready",
+				"status.conditions",
+			} {
+				Expect(crdProperty("ByoMachine", path)).To(BeTrue(), "ByoMachine misses %s", path)
+			}
+		})
+
+		It("Should serve the template of the ByoMachineTemplate", func() {
+			Expect(crdProperty("ByoMachineTemplate", "spec.template.spec")).To(BeTrue())
+		})
+	})
+
+	Context("When a ByoCluster is reconciled", func() {
+		var (
+			namespace   string
+			capiCluster *clusterv1.Cluster
+			byoCluster  *infrastructurev1beta1.ByoCluster
+		)
+
+		BeforeEach(func() {
+			namespace = createNamespace()
+			capiCluster = builder.Cluster(namespace, "conformance-cluster").Build()
+			Expect(k8sClient.Create(ctx, capiCluster)).To(Succeed())
+		})
+
+		It("Should wait for the owner reference of the Cluster", func() {
+			byoCluster = builder.ByoCluster(namespace, "conformance-cluster").Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).To(Succeed())
+			Consistently(getByoCluster(byoCluster), pausedPeriod).ShouldNot(Satisfy(hasFinalizer(infrastructurev1beta1.ClusterFinalizer)))
+
+			patchedByoCluster := getByoCluster(byoCluster)()
+			patchedByoCluster.OwnerReferences = builder.ByoCluster(namespace, byoCluster.Name).WithOwnerCluster(capiCluster).Build().OwnerReferences
+			Expect(k8sClient.Update(ctx, patchedByoCluster)).To(Succeed())
+			Eventually(getByoCluster(byoCluster), contractTimeout).Should(Satisfy(hasFinalizer(infrastructurev1beta1.ClusterFinalizer)))
+		})
+
+		It("Should add its finalizer, default the endpoint port and mark the ByoCluster ready", func() {
+			byoCluster = builder.ByoCluster(namespace, "conformance-cluster").WithOwnerCluster(capiCluster).Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).To(Succeed())
+
+			Eventually(func() bool { return getByoCluster(byoCluster)().Status.Ready }, contractTimeout).Should(BeTrue())
+			actual := getByoCluster(byoCluster)()
+			Expect(controllerutil.ContainsFinalizer(actual, infrastructurev1beta1.ClusterFinalizer)).To(BeTrue())
+			Expect(actual.Spec.ControlPlaneEndpoint.Port).To(BeEquivalentTo(infrastructurev1beta1.DefaultAPIEndpointPort))
+			Expect(conditions.Has(actual, clusterv1.ReadyCondition)).To(BeTrue())
+		})
+
+		It("Should not reconcile the ByoCluster of a paused Cluster", func() {
+			pausedCluster := builder.Cluster(namespace, "paused-cluster").WithPausedField(true).Build()
+			Expect(k8sClient.Create(ctx, pausedCluster)).To(Succeed())
+			byoCluster = builder.ByoCluster(namespace, "paused-cluster").WithOwnerCluster(pausedCluster).Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).To(Succeed())
+
+			Consistently(getByoCluster(byoCluster), pausedPeriod).ShouldNot(Satisfy(hasFinalizer(infrastructurev1beta1.ClusterFinalizer)))
+			Expect(getByoCluster(byoCluster)().Status.Ready).To(BeFalse())
+		})
+
+		It("Should not reconcile a paused ByoCluster until it is resumed", func() {
+			byoCluster = builder.ByoCluster(namespace, "conformance-cluster").WithOwnerCluster(capiCluster).Build()
+			byoCluster.Annotations = map[string]string{clusterv1.PausedAnnotation: ""}
+			Expect(k8sClient.Create(ctx, byoCluster)).To(Succeed())
+			Consistently(getByoCluster(byoCluster), pausedPeriod).ShouldNot(Satisfy(hasFinalizer(infrastructurev1beta1.ClusterFinalizer)))
+
+			resumedByoCluster := getByoCluster(byoCluster)()
+			delete(resumedByoCluster.Annotations, clusterv1.PausedAnnotation)
+			Expect(k8sClient.Update(ctx, resumedByoCluster)).To(Succeed())
+			Eventually(func() bool { return getByoCluster(byoCluster)().Status.Ready }, contractTimeout).Should(BeTrue())
+		})
+
+		It("Should remove its finalizer once the ByoCluster is deleted", func() {
+			byoCluster = builder.ByoCluster(namespace, "conformance-cluster").WithOwnerCluster(capiCluster).Build()
+			Expect(k8sClient.Create(ctx, byoCluster)).To(Succeed())
+			Eventually(getByoCluster(byoCluster), contractTimeout).Should(Satisfy(hasFinalizer(infrastructurev1beta1.ClusterFinalizer)))
+
+			Expect(k8sClient.Delete(ctx, byoCluster)).To(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoCluster), &infrastructurev1beta1.ByoCluster{}))
+			}, contractTimeout).Should(BeTrue())
+		})
+	})
+
+	Context("When a ByoMachine is reconciled", func() {
+		var (
+			namespace   string
+			capiCluster *clusterv1.Cluster
+			machine     *clusterv1.Machine
+		)
+
+		BeforeEach(func() {
+			namespace = createNamespace()
+			byoCluster := builder.ByoCluster(namespace, "conformance-cluster").Build()
+			capiCluster = builder.Cluster(namespace, "conformance-cluster").WithInfrastructureRef(byoCluster).Build()
+			Expect(k8sClient.Create(ctx, capiCluster)).To(Succeed())
+			Expect(k8sClient.Create(ctx, builder.ByoCluster(namespace, byoCluster.Name).WithOwnerCluster(capiCluster).Build())).To(Succeed())
+			machine = builder.Machine(namespace, "conformance-machine-").WithClusterName(capiCluster.Name).Build()
+			Expect(k8sClient.Create(ctx, machine)).To(Succeed())
+		})
+
+		It("Should wait for the owner reference of the Machine", func() {
+			byoMachine := builder.ByoMachine(namespace, "conformance-byomachine-").WithClusterLabel(capiCluster.Name).Build()
+			Expect(k8sClient.Create(ctx, byoMachine)).To(Succeed())
+			Consistently(getByoMachine(byoMachine), pausedPeriod).ShouldNot(Satisfy(hasFinalizer(infrastructurev1beta1.MachineFinalizer)))
+
+			ownedByoMachine := getByoMachine(byoMachine)()
+			ownedByoMachine.OwnerReferences = builder.ByoMachine(namespace, "").WithOwnerMachine(machine).Build().OwnerReferences
+			Expect(k8sClient.Update(ctx, ownedByoMachine)).To(Succeed())
+			Eventually(getByoMachine(byoMachine), contractTimeout).Should(Satisfy(hasFinalizer(infrastructurev1beta1.MachineFinalizer)))
+		})
+
+		It("Should add its finalizer and wait for the infrastructure of the Cluster", func() {
+			byoMachine := builder.ByoMachine(namespace, "conformance-byomachine-").WithClusterLabel(capiCluster.Name).WithOwnerMachine(machine).Build()
+			Expect(k8sClient.Create(ctx, byoMachine)).To(Succeed())
+
+			Eventually(getByoMachine(byoMachine), contractTimeout).Should(Satisfy(hasFinalizer(infrastructurev1beta1.MachineFinalizer)))
+			Eventually(func() *clusterv1.Condition {
+				return conditions.Get(getByoMachine(byoMachine)(), infrastructurev1beta1.BYOHostReady)
+			}, contractTimeout).Should(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrastructurev1beta1.BYOHostReady,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrastructurev1beta1.WaitingForClusterInfrastructureReason,
+				Severity: clusterv1.ConditionSeverityInfo,
+			}))
+			actual := getByoMachine(byoMachine)()
+			Expect(actual.Status.Ready).To(BeFalse())
+			Expect(actual.Spec.ProviderID).To(BeEmpty())
+		})
+
+		It("Should mark a paused ByoMachine paused without reconciling it", func() {
+			byoMachine := builder.ByoMachine(namespace, "conformance-byomachine-").WithClusterLabel(capiCluster.Name).WithOwnerMachine(machine).Build()
+			byoMachine.Annotations = map[string]string{clusterv1.PausedAnnotation: ""}
+			Expect(k8sClient.Create(ctx, byoMachine)).To(Succeed())
+
+			Eventually(func() *clusterv1.Condition {
+				return conditions.Get(getByoMachine(byoMachine)(), infrastructurev1beta1.BYOHostReady)
+			}, contractTimeout).Should(conditions.MatchCondition(clusterv1.Condition{
+				Type:     infrastructurev1beta1.BYOHostReady,
+				Status:   corev1.ConditionFalse,
+				Reason:   infrastructurev1beta1.ClusterOrResourcePausedReason,
+				Severity: clusterv1.ConditionSeverityInfo,
+			}))
+			Expect(controllerutil.ContainsFinalizer(getByoMachine(byoMachine)(), infrastructurev1beta1.MachineFinalizer)).To(BeFalse())
+		})
+
+		It("Should remove its finalizer once the ByoMachine is deleted", func() {
+			byoMachine := builder.ByoMachine(namespace, "conformance-byomachine-").WithClusterLabel(capiCluster.Name).WithOwnerMachine(machine).Build()
+			Expect(k8sClient.Create(ctx, byoMachine)).To(Succeed())
+			Eventually(getByoMachine(byoMachine), contractTimeout).Should(Satisfy(hasFinalizer(infrastructurev1beta1.MachineFinalizer)))
+
+			Expect(k8sClient.Delete(ctx, byoMachine)).To(Succeed())
+			Eventually(func() bool {
+				return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(byoMachine), &infrastructurev1beta1.ByoMachine{}))
+			}, contractTimeout).Should(BeTrue())
+		})
+	})
+})
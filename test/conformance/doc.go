@@ -0,0 +1,8 @@
+// Copyright 2026 Platform9, Inc. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package conformance verifies, against an envtest API server, that the ByoCluster and ByoMachine
+// controllers keep the Cluster API infrastructure provider contract: the contract fields of the CRDs,
+// the ownership the controllers wait for, the finalizers, the paused handling and the status fields.
+// Forks run it with `make conformance-test` to check that they have not broken the CAPI integration.
+package conformance